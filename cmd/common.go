@@ -20,9 +20,11 @@ func Init() {
 	bootstrap.InitStreamLimit()
 	bootstrap.InitIndex()
 	bootstrap.InitUpgradePatch()
+	bootstrap.MigrateLegacySSOIDs()
 }
 
 func Release() {
+	bootstrap.FlushCreditsCounters()
 	db.Close()
 }
 
@@ -44,6 +44,9 @@ the address is defined in config file`,
 		bootstrap.InitOfflineDownloadTools()
 		bootstrap.LoadStorages()
 		bootstrap.InitTaskManager()
+		bootstrap.InitCreditsJobs()
+		bootstrap.InitMailJobs()
+		bootstrap.InitRegistrationJobs()
 		if !flags.Debug && !flags.Dev {
 			gin.SetMode(gin.ReleaseMode)
 		}
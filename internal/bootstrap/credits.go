@@ -0,0 +1,76 @@
+package bootstrap
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+var creditsCron *cron.Cron
+var ledgerAuditCron *cron.Cron
+
+// InitCreditsJobs starts the periodic jobs of the credits system
+func InitCreditsJobs() {
+	// exports are built in goroutines, so pending ones did not survive the restart
+	if err := op.FailStaleDataExports(time.Now()); err != nil {
+		utils.Log.Errorf("failed to fail stale data exports: %+v", err)
+	}
+	creditsCron = cron.NewCron(time.Minute)
+	creditsCron.Do(func() {
+		if err := op.ReleaseExpiredCreditHolds(); err != nil {
+			utils.Log.Errorf("failed to release expired credit holds: %+v", err)
+		}
+		if err := op.ExpireCreditLots(); err != nil {
+			utils.Log.Errorf("failed to expire credit lots: %+v", err)
+		}
+		if err := op.FlushTraffic(); err != nil {
+			utils.Log.Errorf("failed to flush traffic: %+v", err)
+		}
+		if err := op.BillTraffic(); err != nil {
+			utils.Log.Errorf("failed to bill traffic: %+v", err)
+		}
+		if err := op.FlushApiCalls(); err != nil {
+			utils.Log.Errorf("failed to flush api calls: %+v", err)
+		}
+		if err := op.BillApiCalls(); err != nil {
+			utils.Log.Errorf("failed to bill api calls: %+v", err)
+		}
+		if err := op.CleanApiUsages(); err != nil {
+			utils.Log.Errorf("failed to clean api usages: %+v", err)
+		}
+		if err := op.CleanDownloadQuotaUsages(); err != nil {
+			utils.Log.Errorf("failed to clean download quota usages: %+v", err)
+		}
+		if err := op.ReturnExpiredCreditGifts(); err != nil {
+			utils.Log.Errorf("failed to return expired credit gifts: %+v", err)
+		}
+		if err := op.GrantCreditAllowances(); err != nil {
+			utils.Log.Errorf("failed to grant credit allowances: %+v", err)
+		}
+		if err := op.CleanExpiredDataExports(); err != nil {
+			utils.Log.Errorf("failed to clean expired data exports: %+v", err)
+		}
+		if err := op.CleanExpiredDownloadTokens(); err != nil {
+			utils.Log.Errorf("failed to clean expired download tokens: %+v", err)
+		}
+	})
+	ledgerAuditCron = cron.NewCron(time.Hour)
+	ledgerAuditCron.Do(func() {
+		if _, err := op.AuditCreditLedger(); err != nil {
+			utils.Log.Errorf("failed to audit credit ledger: %+v", err)
+		}
+	})
+}
+
+// FlushCreditsCounters writes the traffic and api calls counted in memory to the database,
+// so the usage since the last periodic flush is not lost on shutdown
+func FlushCreditsCounters() {
+	if err := op.FlushTraffic(); err != nil {
+		utils.Log.Errorf("failed to flush traffic: %+v", err)
+	}
+	if err := op.FlushApiCalls(); err != nil {
+		utils.Log.Errorf("failed to flush api calls: %+v", err)
+	}
+}
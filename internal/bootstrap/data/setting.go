@@ -171,6 +171,7 @@ func InitialSettings() []model.SettingItem {
 		{Key: conf.IgnorePaths, Value: "", Type: conf.TypeText, Group: model.INDEX, Flag: model.PRIVATE, Help: `one path per line`},
 		{Key: conf.MaxIndexDepth, Value: "20", Type: conf.TypeNumber, Group: model.INDEX, Flag: model.PRIVATE, Help: `max depth of index`},
 		{Key: conf.IndexProgress, Value: "{}", Type: conf.TypeText, Group: model.SINGLE, Flag: model.PRIVATE},
+		{Key: conf.SSOIDMigrated, Value: "false", Type: conf.TypeBool, Group: model.SINGLE, Flag: model.PRIVATE},
 
 		// SSO settings
 		{Key: conf.SSOLoginEnabled, Value: "false", Type: conf.TypeBool, Group: model.SSO, Flag: model.PUBLIC},
@@ -187,6 +188,10 @@ func InitialSettings() []model.SettingItem {
 		{Key: conf.SSODefaultDir, Value: "/", Type: conf.TypeString, Group: model.SSO, Flag: model.PRIVATE},
 		{Key: conf.SSODefaultPermission, Value: "0", Type: conf.TypeNumber, Group: model.SSO, Flag: model.PRIVATE},
 		{Key: conf.SSOCompatibilityMode, Value: "false", Type: conf.TypeBool, Group: model.SSO, Flag: model.PUBLIC},
+		{Key: conf.SSOOIDCEmailKey, Value: "email", Type: conf.TypeString, Group: model.SSO, Flag: model.PRIVATE},
+		{Key: conf.SSOOIDCRoleKey, Value: "", Type: conf.TypeString, Group: model.SSO, Flag: model.PRIVATE, Help: "Claim holding the role or groups of the user, checked on every OIDC login. Leave empty to keep roles managed locally"},
+		{Key: conf.SSOOIDCAdminValues, Value: "", Type: conf.TypeString, Group: model.SSO, Flag: model.PRIVATE, Help: "Comma separated claim values that grant the admin role"},
+		{Key: conf.SSOOIDCRegister, Value: "false", Type: conf.TypeBool, Group: model.SSO, Flag: model.PRIVATE, Help: "When auto register is off, send new OIDC users through the registration pipeline instead of rejecting them"},
 
 		// ldap settings
 		{Key: conf.LdapLoginEnabled, Value: "false", Type: conf.TypeBool, Group: model.LDAP, Flag: model.PUBLIC},
@@ -198,11 +203,16 @@ func InitialSettings() []model.SettingItem {
 		{Key: conf.LdapDefaultDir, Value: "/", Type: conf.TypeString, Group: model.LDAP, Flag: model.PRIVATE},
 		{Key: conf.LdapDefaultPermission, Value: "0", Type: conf.TypeNumber, Group: model.LDAP, Flag: model.PRIVATE},
 		{Key: conf.LdapLoginTips, Value: "login with ldap", Type: conf.TypeString, Group: model.LDAP, Flag: model.PUBLIC},
+		{Key: conf.LdapUsernameAttribute, Value: "uid", Type: conf.TypeString, Group: model.LDAP, Flag: model.PRIVATE, Help: "Attribute used as the username when importing users, sAMAccountName for Active Directory"},
+		{Key: conf.LdapEmailAttribute, Value: "mail", Type: conf.TypeString, Group: model.LDAP, Flag: model.PRIVATE},
+		{Key: conf.LdapImportFilter, Value: "(objectClass=person)", Type: conf.TypeString, Group: model.LDAP, Flag: model.PRIVATE, Help: "Filter selecting the entries imported by the bulk import"},
+		{Key: conf.LdapAdminGroups, Value: "", Type: conf.TypeString, Group: model.LDAP, Flag: model.PRIVATE, Help: "Comma separated group DNs (memberOf values) whose members get the admin role. Leave empty to keep roles managed locally"},
 
 		// s3 settings
 		{Key: conf.S3AccessKeyId, Value: "", Type: conf.TypeString, Group: model.S3, Flag: model.PRIVATE},
 		{Key: conf.S3SecretAccessKey, Value: "", Type: conf.TypeString, Group: model.S3, Flag: model.PRIVATE},
 		{Key: conf.S3Buckets, Value: "[]", Type: conf.TypeString, Group: model.S3, Flag: model.PRIVATE},
+		{Key: conf.S3CreditsUser, Value: "", Type: conf.TypeString, Group: model.S3, Flag: model.PRIVATE, Help: "User the access key acts as when paid files are downloaded through the S3 gateway, the credits of this user are charged. Leave empty to charge the guest user"},
 
 		// ftp settings
 		{Key: conf.FTPPublicHost, Value: "127.0.0.1", Type: conf.TypeString, Group: model.FTP, Flag: model.PRIVATE},
@@ -231,6 +241,88 @@ func InitialSettings() []model.SettingItem {
 		{Key: conf.DefaultFileCredits, Value: "10", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Default credits required for file downloads"},
 		{Key: conf.CreditsPerMB, Value: "1", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Credits required per MB of file size"},
 		{Key: conf.MinCreditsForDownload, Value: "1", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Minimum credits required for any download"},
+		{Key: conf.CreditsHoldTimeout, Value: "30", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Minutes after which credits held for an unfinished download are released"},
+		{Key: conf.CreditsTransferFeePercent, Value: "0", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Fee charged to the sender of a credits transfer, in percent of the amount"},
+		{Key: conf.CreditsTransferDailyLimit, Value: "0", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Maximum credits a user can transfer per day, 0 means unlimited"},
+		{Key: conf.TrafficCreditsPerGB, Value: "0", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Credits charged per GB proxied to a logged-in user, 0 disables traffic billing"},
+		{Key: conf.FreeDailyDownloads, Value: "0", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Paid downloads per user per day that are free of charge. With 0 the count is unlimited and only the free GB below applies; the free quota is disabled when both are 0"},
+		{Key: conf.FreeDailyDownloadGB, Value: "0", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "GB of paid downloads per user per day that are free of charge, 0 means no limit on size. The free quota is disabled when both are 0"},
+		{Key: conf.CreditsPurchaseValidHours, Value: "24", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Hours during which a paid file can be downloaded again for free, 0 charges every download, -1 means forever"},
+		{Key: conf.DownloadTokenTTL, Value: "5", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Minutes a prepaid download token stays valid"},
+		{Key: conf.DownloadTokenMaxUses, Value: "1", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Downloads a prepaid download token can be used for, HEAD requests are not counted"},
+		{Key: conf.CreditsChargeWindow, Value: "60", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Minutes during which range requests, resumed and multi-threaded downloads of the same file by the same user are charged only once, 0 charges every request"},
+		{Key: conf.CreditsGiftExpireHours, Value: "72", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Hours a credits gift can be claimed before it is returned to the sender"},
+		{Key: conf.CreditsPricePer100, Value: "100", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Price of 100 credits in the minor currency unit (e.g. fen), used when buying credits outside of a credit package"},
+		{Key: conf.CreditsTaskCopy, Value: "0", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Credits charged for each copy task between storages, held when the task is submitted and returned if it fails, 0 means free"},
+		{Key: conf.CreditsTaskDecompress, Value: "0", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Credits charged for each archive decompress task, held when the task is submitted and returned if it fails, 0 means free"},
+		{Key: conf.CreditsWelcomeBalance, Value: "0", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Credits given to every new user when the account is created, by any means including registration, admin, SSO and LDAP"},
+		{Key: conf.CreditsWelcomeRequireEmail, Value: "false", Type: conf.TypeBool, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Only give the welcome credits to users with a verified email, that is approved registrations and SSO or LDAP accounts whose email is known, to deter bots"},
+		{Key: conf.CreditsPromoExpireDays, Value: "0", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Days welcome, referral, upload and offerwall reward credits stay valid, unused credits expire afterwards, 0 means they never expire"},
+		{Key: conf.CreditsTaskOfflineDownload, Value: "0", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Credits charged for each offline download task, held when the task is submitted and returned if it fails, 0 means free"},
+		{Key: conf.ApiFreeDailyCalls, Value: "1000", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Calls of each metered operation a user can make per day for free"},
+		{Key: conf.ApiCreditsPer1000List, Value: "0", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Credits charged per 1000 directory list calls beyond the daily free calls, 0 disables metering of list calls"},
+		{Key: conf.ApiCreditsPer1000Search, Value: "0", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Credits charged per 1000 search calls beyond the daily free calls, 0 disables metering of search calls"},
+		{Key: conf.ApiCreditsPer1000Propfind, Value: "0", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Credits charged per 1000 WebDAV PROPFIND requests beyond the daily free calls, 0 disables metering of PROPFIND"},
+		{Key: conf.ReferralRegisterReferrerCredits, Value: "0", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Credits given to the referrer when a referred registration is approved"},
+		{Key: conf.ReferralRegisterRefereeCredits, Value: "0", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Credits given to the referred user when the registration is approved"},
+		{Key: conf.ReferralPurchaseReferrerCredits, Value: "0", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Credits given to the referrer on the first purchase of a referred user"},
+		{Key: conf.ReferralPurchaseRefereeCredits, Value: "0", Type: conf.TypeNumber, Group: model.CREDITS, Flag: model.PRIVATE, Help: "Credits given to the referred user on the first purchase"},
+		{Key: conf.VipFreePaths, Value: "", Type: conf.TypeText, Group: model.CREDITS, Flag: model.PRIVATE, Help: "One path per line, VIP members download files under these paths without spending credits"},
+		{Key: conf.PaymentProviders, Value: "[]", Type: conf.TypeText, Group: model.CREDITS, Flag: model.PRIVATE, Help: `json array of {"name","driver","enabled","config"}, see /api/admin/credits/payment/drivers for the config schema of each driver`},
+
+		// mail settings
+		{Key: conf.SmtpHost, Value: "", Type: conf.TypeString, Group: model.MAIL, Flag: model.PRIVATE, Help: "SMTP server used to send verification codes, registration results and payment receipts, empty disables sending mail"},
+		{Key: conf.SmtpPort, Value: "465", Type: conf.TypeNumber, Group: model.MAIL, Flag: model.PRIVATE},
+		{Key: conf.SmtpUsername, Value: "", Type: conf.TypeString, Group: model.MAIL, Flag: model.PRIVATE},
+		{Key: conf.SmtpPassword, Value: "", Type: conf.TypeString, Group: model.MAIL, Flag: model.PRIVATE},
+		{Key: conf.SmtpEncryption, Value: "tls", Type: conf.TypeSelect, Options: "tls,starttls,none", Group: model.MAIL, Flag: model.PRIVATE},
+		{Key: conf.SmtpFrom, Value: "", Type: conf.TypeString, Group: model.MAIL, Flag: model.PRIVATE, Help: "Sender address of all providers, e.g. OpenList <noreply@example.com>, templates can be overridden by files in data/mail_templates"},
+		{Key: conf.MailProvider, Value: "smtp", Type: conf.TypeSelect, Options: "smtp,sendgrid,mailgun,ses,webhook", Group: model.MAIL, Flag: model.PRIVATE, Help: "Service used to send mail, smtp uses the smtp_* settings"},
+		{Key: conf.MailProviderConfig, Value: "{}", Type: conf.TypeText, Group: model.MAIL, Flag: model.PRIVATE, Help: `json config of the provider, sendgrid: {"api_key"}, mailgun: {"api_key","domain","region"}, ses: {"region","access_key_id","secret_access_key","configuration_set"}, webhook: {"url","secret","headers"}`},
+		{Key: conf.MailMaxAttempts, Value: "3", Type: conf.TypeNumber, Group: model.MAIL, Flag: model.PRIVATE, Help: "Attempts to send a mail before it is moved to the dead-letter list"},
+
+		// registration settings
+		{Key: conf.RegistrationMode, Value: model.RegistrationModeApproval, Type: conf.TypeSelect, Options: "closed,open,approval,invite_only", Group: model.REGISTRATION, Flag: model.PUBLIC, Help: "closed: no registration, open: accounts are created once the email is verified, approval: registrations are approved by an admin, invite_only: an invite code is also required"},
+		{Key: conf.InviteCodesPerUser, Value: "0", Type: conf.TypeNumber, Group: model.REGISTRATION, Flag: model.PRIVATE, Help: "Invite codes each user can create, 0 means only admins can create invite codes"},
+		{Key: conf.EmailAllowedDomains, Value: "", Type: conf.TypeText, Group: model.REGISTRATION, Flag: model.PRIVATE, Help: "Only emails of these domains and their subdomains can register, one per line or comma separated. Leave empty to allow every domain"},
+		{Key: conf.EmailBlockedDomains, Value: "", Type: conf.TypeText, Group: model.REGISTRATION, Flag: model.PRIVATE, Help: "Emails of these domains and their subdomains can't register, one per line or comma separated"},
+		{Key: conf.RegistrationRateLimitIP, Value: "20", Type: conf.TypeNumber, Group: model.REGISTRATION, Flag: model.PRIVATE, Help: "Requests each client ip can make to every registration and verification endpoint within the window, 0 means unlimited"},
+		{Key: conf.RegistrationRateLimitEmail, Value: "5", Type: conf.TypeNumber, Group: model.REGISTRATION, Flag: model.PRIVATE, Help: "Requests for each email address to every registration and verification endpoint within the window, 0 means unlimited"},
+		{Key: conf.RegistrationRateLimitWindow, Value: "60", Type: conf.TypeNumber, Group: model.REGISTRATION, Flag: model.PRIVATE, Help: "Length of the sliding window of the registration rate limits in minutes"},
+		{Key: conf.RegistrationSourceWindow, Value: "24", Type: conf.TypeNumber, Group: model.REGISTRATION, Flag: model.PRIVATE, Help: "Window in hours over which registrations from the same IPv4 /24 or IPv6 /64 subnet are counted"},
+		{Key: conf.RegistrationSourceApprovals, Value: "0", Type: conf.TypeNumber, Group: model.REGISTRATION, Flag: model.PRIVATE, Help: "Registrations from the same subnet that can be approved within the window, further ones wait for an admin to force the approval, 0 means unlimited"},
+		{Key: conf.RegistrationSourceSuspicious, Value: "3", Type: conf.TypeNumber, Group: model.REGISTRATION, Flag: model.PRIVATE, Help: "Registrations from the same subnet within the window from which pending registrations are flagged as suspicious, 0 disables the flag"},
+		{Key: conf.UsernamePattern, Value: "", Type: conf.TypeString, Group: model.REGISTRATION, Flag: model.PRIVATE, Help: "Regular expression new usernames must match, empty allows any characters"},
+		{Key: conf.UsernameMinLength, Value: "3", Type: conf.TypeNumber, Group: model.REGISTRATION, Flag: model.PUBLIC, Help: "Minimum length of new usernames in characters"},
+		{Key: conf.UsernameMaxLength, Value: "50", Type: conf.TypeNumber, Group: model.REGISTRATION, Flag: model.PUBLIC, Help: "Maximum length of new usernames in characters"},
+		{Key: conf.ReservedUsernames, Value: "admin\nroot\napi", Type: conf.TypeText, Group: model.REGISTRATION, Flag: model.PRIVATE, Help: "Usernames that can't be registered or given to new users, one per line or separated by commas, compared case-insensitively"},
+		{Key: conf.UsernameCaseInsensitive, Value: "true", Type: conf.TypeBool, Group: model.REGISTRATION, Flag: model.PRIVATE, Help: "Treat usernames that differ only in case as the same when checking that a new username is unique"},
+		{Key: conf.AccountRetentionDays, Value: "1825", Type: conf.TypeNumber, Group: model.REGISTRATION, Flag: model.PRIVATE, Help: "When a user deletes the account, payment orders of the last this many days and their credit transactions are kept as financial records, the rest is purged"},
+		{Key: conf.HomeFolderStorage, Value: "", Type: conf.TypeString, Group: model.REGISTRATION, Flag: model.PRIVATE, Help: "Mount path of the storage on which the base path of approved users is created as their home folder, base paths on other storages are left alone. Leave empty to not create home folders"},
+		{Key: conf.HomeFolderWritable, Value: "true", Type: conf.TypeBool, Group: model.REGISTRATION, Flag: model.PRIVATE, Help: "Add a meta to created home folders that lets their owner upload and manage files in them without the write permission"},
+		{Key: conf.VerifyRedirectURL, Value: "", Type: conf.TypeString, Group: model.REGISTRATION, Flag: model.PRIVATE, Help: "Frontend page the verification link in the email redirects to with the status and error code in the query, leave empty to show a page rendered by the server"},
+		{Key: conf.VerificationCodeCharset, Value: model.RedeemCharsetDigits, Type: conf.TypeSelect, Options: "digits,upper,unambiguous", Group: model.REGISTRATION, Flag: model.PRIVATE, Help: "Characters of the codes emailed for password reset, email change and account deletion, codes are compared case-insensitively. unambiguous leaves out characters such as 0/O and 1/I"},
+		{Key: conf.VerificationCodeLength, Value: "6", Type: conf.TypeNumber, Group: model.REGISTRATION, Flag: model.PRIVATE, Help: "Length of the emailed verification codes, from 4 to 16"},
+		{Key: conf.VerificationCodeTTL, Value: "10", Type: conf.TypeNumber, Group: model.REGISTRATION, Flag: model.PRIVATE, Help: "Minutes an emailed verification code stays valid"},
+		{Key: conf.VerificationCodeMaxActive, Value: "1", Type: conf.TypeNumber, Group: model.REGISTRATION, Flag: model.PRIVATE, Help: "Valid codes each email can have for the same purpose, issuing a new code invalidates the older ones beyond this number"},
+		{Key: conf.VerificationCodeMaxAttempts, Value: "5", Type: conf.TypeNumber, Group: model.REGISTRATION, Flag: model.PRIVATE, Help: "Wrong codes entered for an email after which its valid codes are locked and a new code must be requested, 0 means unlimited"},
+		{Key: conf.GravatarURL, Value: "https://www.gravatar.com/avatar/", Type: conf.TypeString, Group: model.REGISTRATION, Flag: model.PRIVATE, Help: "Gravatar compatible service used for users without an avatar, leave empty to not show an avatar for them"},
+		{Key: conf.RegistrationReminderBefore, Value: "2", Type: conf.TypeNumber, Group: model.REGISTRATION, Flag: model.PRIVATE, Help: "Hours before the verification link of a pending registration expires to email a reminder with a fresh link, 0 disables the reminder"},
+		{Key: conf.RegistrationEscalateAfter, Value: "48", Type: conf.TypeNumber, Group: model.REGISTRATION, Flag: model.PRIVATE, Help: "Hours a verified registration waits for approval before admins are notified about it, 0 disables the notification"},
+		{Key: conf.RequireTwoFactor, Value: "false", Type: conf.TypeBool, Group: model.REGISTRATION, Flag: model.PUBLIC, Help: "Require users to enable 2FA before sensitive operations such as creating payment orders and transferring credits"},
+
+		// admin notification settings
+		{Key: conf.NotifyAdminEmails, Value: "", Type: conf.TypeText, Group: model.NOTIFICATION, Flag: model.PRIVATE, Help: "Email addresses that receive admin notifications, one per line or separated by commas"},
+		{Key: conf.NotifyTelegramBotToken, Value: "", Type: conf.TypeString, Group: model.NOTIFICATION, Flag: model.PRIVATE, Help: "Token of the Telegram bot that sends admin notifications"},
+		{Key: conf.NotifyTelegramChatID, Value: "", Type: conf.TypeString, Group: model.NOTIFICATION, Flag: model.PRIVATE, Help: "Telegram chat that receives admin notifications"},
+		{Key: conf.NotifyWebhookURL, Value: "", Type: conf.TypeString, Group: model.NOTIFICATION, Flag: model.PRIVATE, Help: "URL that admin notifications are posted to as JSON"},
+		{Key: conf.NotifyWebhookSecret, Value: "", Type: conf.TypeString, Group: model.NOTIFICATION, Flag: model.PRIVATE, Help: "Secret used to sign the webhook body in the X-OpenList-Signature header"},
+		{Key: conf.NotifyOnRegistration, Value: "false", Type: conf.TypeBool, Group: model.NOTIFICATION, Flag: model.PRIVATE, Help: "Notify admins when a registration is waiting for approval"},
+		{Key: conf.NotifyOnPayment, Value: "false", Type: conf.TypeBool, Group: model.NOTIFICATION, Flag: model.PRIVATE, Help: "Notify admins when a payment order is completed"},
+		{Key: conf.NotifyPaymentMinAmount, Value: "0", Type: conf.TypeString, Group: model.NOTIFICATION, Flag: model.PRIVATE, Help: "Only notify about payment orders of at least this amount in the currency of the order, 0 notifies about every order"},
+		{Key: conf.RegistrationWebhookURLs, Value: "", Type: conf.TypeText, Group: model.NOTIFICATION, Flag: model.PRIVATE, Help: "URLs that registration lifecycle events are posted to as JSON, one per line or separated by commas"},
+		{Key: conf.RegistrationWebhookSecret, Value: "", Type: conf.TypeString, Group: model.NOTIFICATION, Flag: model.PRIVATE, Help: "Secret used to sign the registration webhook body in the X-OpenList-Signature header"},
+		{Key: conf.RegistrationWebhookEvents, Value: "", Type: conf.TypeString, Group: model.NOTIFICATION, Flag: model.PRIVATE, Help: "Registration events posted to the webhooks separated by commas, from registration.submitted, registration.verified, registration.approved and registration.rejected. Leave empty to post all of them"},
 	}
 	additionalSettingItems := tool.Tools.Items()
 	// 固定顺序
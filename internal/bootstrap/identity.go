@@ -0,0 +1,34 @@
+package bootstrap
+
+import (
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+// MigrateLegacySSOIDs links the sso ids bound before identities existed to the
+// configured sso platform once, later sso ids are not used to log in anymore
+func MigrateLegacySSOIDs() {
+	if setting.GetBool(conf.SSOIDMigrated) {
+		return
+	}
+	provider := strings.ToLower(setting.GetStr(conf.SSOLoginPlatform))
+	if provider == "" {
+		return
+	}
+	if err := op.MigrateLegacySSOIDs(provider); err != nil {
+		utils.Log.Errorf("failed to migrate legacy sso ids: %+v", err)
+		return
+	}
+	item, err := op.GetSettingItemByKey(conf.SSOIDMigrated)
+	if err == nil {
+		item.Value = "true"
+		err = op.SaveSettingItem(item)
+	}
+	if err != nil {
+		utils.Log.Errorf("failed to save the sso id migration: %+v", err)
+	}
+}
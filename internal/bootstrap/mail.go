@@ -0,0 +1,21 @@
+package bootstrap
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+var mailCron *cron.Cron
+
+// InitMailJobs starts retrying failed mail deliveries
+func InitMailJobs() {
+	mailCron = cron.NewCron(time.Minute)
+	mailCron.Do(func() {
+		if err := op.RetryMailDeliveries(); err != nil {
+			utils.Log.Errorf("failed to retry mail deliveries: %+v", err)
+		}
+	})
+}
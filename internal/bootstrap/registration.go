@@ -0,0 +1,25 @@
+package bootstrap
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+var registrationCron *cron.Cron
+
+// InitRegistrationJobs starts reminding pending registrations before their links expire
+// and notifying admins of registrations that wait too long for approval
+func InitRegistrationJobs() {
+	registrationCron = cron.NewCron(10 * time.Minute)
+	registrationCron.Do(func() {
+		if err := op.SendRegistrationReminders(); err != nil {
+			utils.Log.Errorf("failed to send registration reminders: %+v", err)
+		}
+		if err := op.NotifyWaitingRegistrations(); err != nil {
+			utils.Log.Errorf("failed to notify admins of waiting registrations: %+v", err)
+		}
+	})
+}
@@ -130,6 +130,7 @@ type Config struct {
 	FTP                   FTP         `json:"ftp" envPrefix:"FTP_"`
 	SFTP                  SFTP        `json:"sftp" envPrefix:"SFTP_"`
 	LastLaunchedVersion   string      `json:"last_launched_version"`
+	NodeID                int64       `json:"node_id" env:"NODE_ID"`
 }
 
 func DefaultConfig(dataDir string) *Config {
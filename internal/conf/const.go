@@ -51,6 +51,96 @@ const (
 	DefaultFileCredits      = "default_file_credits"
 	CreditsPerMB           = "credits_per_mb"
 	MinCreditsForDownload  = "min_credits_for_download"
+	CreditsHoldTimeout     = "credits_hold_timeout"
+	CreditsTransferFeePercent = "credits_transfer_fee_percent"
+	CreditsTransferDailyLimit = "credits_transfer_daily_limit"
+	TrafficCreditsPerGB       = "traffic_credits_per_gb"
+	FreeDailyDownloads        = "free_daily_downloads"
+	FreeDailyDownloadGB       = "free_daily_download_gb"
+	CreditsPurchaseValidHours = "credits_purchase_valid_hours"
+	CreditsChargeWindow       = "credits_charge_window"
+	DownloadTokenTTL          = "download_token_ttl"
+	DownloadTokenMaxUses      = "download_token_max_uses"
+	CreditsGiftExpireHours    = "credits_gift_expire_hours"
+	CreditsPricePer100        = "credits_price_per_100"
+	CreditsTaskCopy            = "credits_task_copy"
+	CreditsTaskDecompress      = "credits_task_decompress"
+	CreditsTaskOfflineDownload = "credits_task_offline_download"
+	CreditsWelcomeBalance      = "credits_welcome_balance"
+	CreditsWelcomeRequireEmail = "credits_welcome_require_email"
+	CreditsPromoExpireDays     = "credits_promo_expire_days"
+	ApiFreeDailyCalls          = "api_free_daily_calls"
+	ApiCreditsPer1000List      = "api_credits_per_1000_list"
+	ApiCreditsPer1000Search    = "api_credits_per_1000_search"
+	ApiCreditsPer1000Propfind  = "api_credits_per_1000_propfind"
+
+	// referral
+	ReferralRegisterReferrerCredits = "referral_register_referrer_credits"
+	ReferralRegisterRefereeCredits  = "referral_register_referee_credits"
+	ReferralPurchaseReferrerCredits = "referral_purchase_referrer_credits"
+	ReferralPurchaseRefereeCredits  = "referral_purchase_referee_credits"
+
+	// vip
+	VipFreePaths = "vip_free_paths"
+
+	// payment
+	PaymentProviders = "payment_providers"
+
+	// mail
+	SmtpHost           = "smtp_host"
+	SmtpPort           = "smtp_port"
+	SmtpUsername       = "smtp_username"
+	SmtpPassword       = "smtp_password"
+	SmtpEncryption     = "smtp_encryption"
+	SmtpFrom           = "smtp_from"
+	MailProvider       = "mail_provider"
+	MailProviderConfig = "mail_provider_config"
+	MailMaxAttempts    = "mail_max_attempts"
+
+	// registration
+	RegistrationMode   = "registration_mode"
+	InviteCodesPerUser = "invite_codes_per_user"
+	RequireTwoFactor   = "require_two_factor"
+	EmailAllowedDomains = "email_allowed_domains"
+	EmailBlockedDomains = "email_blocked_domains"
+	RegistrationRateLimitIP      = "registration_rate_limit_ip"
+	RegistrationRateLimitEmail   = "registration_rate_limit_email"
+	RegistrationRateLimitWindow  = "registration_rate_limit_window"
+	RegistrationSourceWindow     = "registration_source_window"
+	RegistrationSourceApprovals  = "registration_source_approvals"
+	RegistrationSourceSuspicious = "registration_source_suspicious"
+	UsernamePattern              = "username_pattern"
+	UsernameMinLength            = "username_min_length"
+	UsernameMaxLength            = "username_max_length"
+	ReservedUsernames            = "reserved_usernames"
+	UsernameCaseInsensitive      = "username_case_insensitive"
+	AccountRetentionDays         = "account_retention_days"
+	HomeFolderStorage            = "home_folder_storage"
+	HomeFolderWritable           = "home_folder_writable"
+	VerifyRedirectURL            = "verify_redirect_url"
+	VerificationCodeCharset      = "verification_code_charset"
+	VerificationCodeLength       = "verification_code_length"
+	VerificationCodeTTL          = "verification_code_ttl"
+	VerificationCodeMaxActive    = "verification_code_max_active"
+	VerificationCodeMaxAttempts  = "verification_code_max_attempts"
+	GravatarURL                  = "gravatar_url"
+	RegistrationReminderBefore   = "registration_reminder_before"
+	RegistrationEscalateAfter    = "registration_escalate_after"
+
+	// admin notification
+	NotifyAdminEmails      = "notify_admin_emails"
+	NotifyTelegramBotToken = "notify_telegram_bot_token"
+	NotifyTelegramChatID   = "notify_telegram_chat_id"
+	NotifyWebhookURL       = "notify_webhook_url"
+	NotifyWebhookSecret    = "notify_webhook_secret"
+	NotifyOnRegistration   = "notify_on_registration"
+	NotifyOnPayment        = "notify_on_payment"
+	NotifyPaymentMinAmount = "notify_payment_min_amount"
+
+	// registration webhook
+	RegistrationWebhookURLs   = "registration_webhook_urls"
+	RegistrationWebhookSecret = "registration_webhook_secret"
+	RegistrationWebhookEvents = "registration_webhook_events"
 
 	// index
 	SearchIndex     = "search_index"
@@ -87,6 +177,7 @@ const (
 	// single
 	Token         = "token"
 	IndexProgress = "index_progress"
+	SSOIDMigrated = "sso_id_migrated"
 
 	// SSO
 	SSOClientId          = "sso_client_id"
@@ -103,6 +194,10 @@ const (
 	SSODefaultDir        = "sso_default_dir"
 	SSODefaultPermission = "sso_default_permission"
 	SSOCompatibilityMode = "sso_compatibility_mode"
+	SSOOIDCEmailKey      = "sso_oidc_email_key"
+	SSOOIDCRoleKey       = "sso_oidc_role_key"
+	SSOOIDCAdminValues   = "sso_oidc_admin_values"
+	SSOOIDCRegister      = "sso_oidc_register"
 
 	// ldap
 	LdapLoginEnabled      = "ldap_login_enabled"
@@ -114,11 +209,16 @@ const (
 	LdapDefaultPermission = "ldap_default_permission"
 	LdapDefaultDir        = "ldap_default_dir"
 	LdapLoginTips         = "ldap_login_tips"
+	LdapUsernameAttribute = "ldap_username_attribute"
+	LdapEmailAttribute    = "ldap_email_attribute"
+	LdapImportFilter      = "ldap_import_filter"
+	LdapAdminGroups       = "ldap_admin_groups"
 
 	// s3
 	S3Buckets         = "s3_buckets"
 	S3AccessKeyId     = "s3_access_key_id"
 	S3SecretAccessKey = "s3_secret_access_key"
+	S3CreditsUser     = "s3_credits_user"
 
 	// qbittorrent
 	QbittorrentUrl      = "qbittorrent_url"
@@ -173,4 +273,5 @@ const (
 	RequestHeaderKey
 	UserAgentKey
 	PathKey
+	DownloadTokenKey
 )
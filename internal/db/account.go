@@ -0,0 +1,92 @@
+package db
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// PurgeUserAccount 在同一事务中删除用户的个人数据并保存已匿名化的用户，username 和 email 为匿名化前的用户名和邮箱。创建时间晚于 retainSince 的支付订单
+// 及其积分交易作为财务记录保留（仅关联匿名化后的用户），其余积分数据、下载和用量记录、推荐关系、注册记录、邮箱更换记录和登录凭证全部删除；
+// 用户的分享随之删除不能再购买，涉及其他用户的礼物清除留言，待领取的礼物立即过期以退还赠送方
+func PurgeUserAccount(user *model.User, username, email string, retainSince time.Time) error {
+	return errors.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		var retained []string
+		if err := tx.Model(&model.PaymentOrder{}).
+			Where("user_id = ? AND created_at >= ?", user.ID, retainSince).
+			Pluck("order_no", &retained).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id = ? AND created_at < ?", user.ID, retainSince).
+			Delete(&model.PaymentOrder{}).Error; err != nil {
+			return err
+		}
+		transactions := tx.Unscoped().Where("user_id = ?", user.ID)
+		if len(retained) > 0 {
+			transactions = transactions.Where("source_id NOT IN ?", retained)
+		}
+		if err := transactions.Delete(&model.CreditTransaction{}).Error; err != nil {
+			return err
+		}
+		for _, m := range []any{
+			&model.UserCredits{}, &model.CreditLot{}, &model.CreditHold{}, &model.EmailChange{},
+			&model.WebAuthnCredential{}, &model.OtpBackupCode{}, &model.UserIdentity{},
+			&model.DownloadPurchase{}, &model.DownloadQuotaUsage{}, &model.DownloadToken{}, &model.ApiUsage{},
+			&model.TrafficAccount{}, &model.RedeemCodeUsage{}, &model.ExternalReward{}, &model.ReferralCode{},
+			&model.UserPricingGroup{}, &model.CreditAllowanceGrant{}, &model.FileCreditsExemption{},
+			&model.TermsAcceptance{},
+		} {
+			if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(m).Error; err != nil {
+				return err
+			}
+		}
+		// 分享软删除，已支付的访客订单仍可按原路径签发下载令牌
+		if err := tx.Where("user_id = ?", user.ID).Delete(&model.FileShare{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("referrer_id = ? OR referee_id = ?", user.ID, user.ID).
+			Delete(&model.Referral{}).Error; err != nil {
+			return err
+		}
+		// 保留内容标识，同一文件不能再次领取上传奖励
+		if err := tx.Model(&model.UploadReward{}).Where("user_id = ?", user.ID).
+			Update("path", "").Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.CreditGift{}).Where("status = ? AND recipient_id = ?", model.CreditGiftPending, user.ID).
+			Update("expires_at", time.Now()).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.CreditGift{}).Where("sender_id = ? OR recipient_id = ?", user.ID, user.ID).
+			Update("message", "").Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&model.SSHPublicKey{}).Error; err != nil {
+			return err
+		}
+		registrations := tx.Unscoped().Where("username = ?", username)
+		if email != "" {
+			registrations = registrations.Or("email = ?", email)
+			if err := tx.Unscoped().Where("email = ?", email).Delete(&model.VerificationCode{}).Error; err != nil {
+				return err
+			}
+		}
+		var registrationIDs []uint
+		if err := registrations.Model(&model.UserRegistration{}).Pluck("id", &registrationIDs).Error; err != nil {
+			return err
+		}
+		if len(registrationIDs) > 0 {
+			if err := tx.Unscoped().Where("registration_id IN ?", registrationIDs).
+				Delete(&model.TermsAcceptance{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("id IN ?", registrationIDs).
+				Delete(&model.UserRegistration{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(user).Error
+	}))
+}
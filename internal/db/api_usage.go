@@ -0,0 +1,53 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AddApiUsage 累加用户某天某类接口的调用次数
+func AddApiUsage(userID uint, day, operation string, calls int64) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "day"}, {Name: "operation"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"calls": gorm.Expr("calls + ?", calls)}),
+	}).Create(&model.ApiUsage{UserID: userID, Day: day, Operation: operation, Calls: calls}).Error
+}
+
+// GetApiUsages 获取用户某天的接口调用次数
+func GetApiUsages(userID uint, day string) ([]model.ApiUsage, error) {
+	var usages []model.ApiUsage
+	err := db.Where("user_id = ? AND day = ?", userID, day).Order("operation").Find(&usages).Error
+	return usages, err
+}
+
+// GetApiUsagesOverFree 获取 since 之后调用次数超过 free 次的记录
+func GetApiUsagesOverFree(since string, free int64) ([]model.ApiUsage, error) {
+	var usages []model.ApiUsage
+	err := db.Where("day >= ? AND calls > ?", since, free).Find(&usages).Error
+	return usages, err
+}
+
+// BillApiUsage 在同一个事务中扣除积分并累加已计费积分，
+// 已计费积分与 usage 中的不一致时说明已被其他进程计费，不做处理
+func BillApiUsage(usage *model.ApiUsage, credits int64, transaction *model.CreditTransaction) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.ApiUsage{}).
+			Where("user_id = ? AND day = ? AND operation = ? AND billed_credits = ?",
+				usage.UserID, usage.Day, usage.Operation, usage.BilledCredits).
+			Update("billed_credits", gorm.Expr("billed_credits + ?", credits))
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		userCredits, err := lockUserCredits(tx, usage.UserID)
+		if err != nil {
+			return err
+		}
+		return applyCreditChange(tx, userCredits, transaction)
+	})
+}
+
+// CleanApiUsages 清理指定日期之前的接口调用记录
+func CleanApiUsages(before string) error {
+	return db.Where("day < ?", before).Delete(&model.ApiUsage{}).Error
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"gorm.io/gorm"
+)
+
+// GetCoupons 获取优惠券列表
+func GetCoupons(page, pageSize int) ([]model.Coupon, int64, error) {
+	var coupons []model.Coupon
+	var total int64
+
+	query := db.Model(&model.Coupon{})
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&coupons).Error
+	return coupons, total, err
+}
+
+// GetCouponByCode 根据优惠码获取优惠券
+func GetCouponByCode(code string) (*model.Coupon, error) {
+	var coupon model.Coupon
+	err := db.Where("code = ?", code).First(&coupon).Error
+	return &coupon, err
+}
+
+// SaveCoupon 创建或更新优惠券
+func SaveCoupon(coupon *model.Coupon) error {
+	return db.Save(coupon).Error
+}
+
+// DeleteCoupon 删除优惠券
+func DeleteCoupon(id uint) error {
+	return db.Delete(&model.Coupon{}, id).Error
+}
+
+// CountCouponOrders 统计使用了优惠码的已支付订单和未过期的待支付订单，userID 为0时统计所有用户
+func CountCouponOrders(code string, userID uint) (int64, error) {
+	return countCouponOrders(db, code, userID)
+}
+
+func countCouponOrders(tx *gorm.DB, code string, userID uint) (int64, error) {
+	var count int64
+	query := tx.Model(&model.PaymentOrder{}).Where("coupon_code = ?", code).
+		Where("status = ? OR (status = ? AND expires_at > ?)", model.PaymentOrderPaid, model.PaymentOrderPending, time.Now())
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	err := query.Count(&count).Error
+	return count, err
+}
+
+// CreateCouponOrder 创建使用优惠券的支付订单。先更新优惠券行将其锁住，同一优惠券的下单串行执行，
+// 之后统计的使用次数是准确的；超过总次数上限时返回 errs.CouponExhausted，超过每个用户的上限时返回 errs.CouponUserLimit
+func CreateCouponOrder(order *model.PaymentOrder, coupon *model.Coupon) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Coupon{}).Where("id = ?", coupon.ID).Update("updated_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errs.CouponNotFound
+		}
+		if coupon.MaxUses > 0 {
+			used, err := countCouponOrders(tx, coupon.Code, 0)
+			if err != nil {
+				return err
+			}
+			if used >= int64(coupon.MaxUses) {
+				return errs.CouponExhausted
+			}
+		}
+		if coupon.PerUserLimit > 0 {
+			used, err := countCouponOrders(tx, coupon.Code, order.UserID)
+			if err != nil {
+				return err
+			}
+			if used >= int64(coupon.PerUserLimit) {
+				return errs.CouponUserLimit
+			}
+		}
+		return tx.Create(order).Error
+	})
+}
+
+// IncreaseCouponUsage 订单支付成功后累加优惠券使用次数
+func IncreaseCouponUsage(code string) error {
+	return db.Model(&model.Coupon{}).Where("code = ?", code).
+		Update("used_count", gorm.Expr("used_count + ?", 1)).Error
+}
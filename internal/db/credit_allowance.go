@@ -0,0 +1,69 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"gorm.io/gorm"
+)
+
+// GetCreditAllowances 获取积分津贴列表
+func GetCreditAllowances(enabledOnly bool) ([]model.CreditAllowance, error) {
+	var allowances []model.CreditAllowance
+	query := db.Model(&model.CreditAllowance{})
+	if enabledOnly {
+		query = query.Where("enabled = ?", true)
+	}
+	err := query.Order("id ASC").Find(&allowances).Error
+	return allowances, err
+}
+
+// SaveCreditAllowance 创建或更新积分津贴
+func SaveCreditAllowance(allowance *model.CreditAllowance) error {
+	return db.Save(allowance).Error
+}
+
+// DeleteCreditAllowance 删除积分津贴
+func DeleteCreditAllowance(id uint) error {
+	return db.Delete(&model.CreditAllowance{}, id).Error
+}
+
+// GetUngrantedAllowanceUsers 获取符合津贴角色且在该周期内尚未发放的启用用户ID，积分账户被冻结的用户除外
+func GetUngrantedAllowanceUsers(allowance *model.CreditAllowance, period string, limit int) ([]uint, error) {
+	var userIDs []uint
+	granted := db.Model(&model.CreditAllowanceGrant{}).Select("user_id").
+		Where("allowance_id = ? AND period = ?", allowance.ID, period)
+	frozen := db.Model(&model.UserCredits{}).Select("user_id").Where("frozen = ?", true)
+	err := db.Model(&model.User{}).
+		Where(columnName("role")+" = ? AND disabled = ?", allowance.Role, false).
+		Where("id NOT IN (?)", granted).
+		Where("id NOT IN (?)", frozen).
+		Order("id ASC").Limit(limit).Pluck("id", &userIDs).Error
+	return userIDs, err
+}
+
+// GrantCreditAllowance 在同一个事务中记录津贴发放并增加用户积分，
+// 已发放过时返回 false，不重复增加积分
+func GrantCreditAllowance(grant *model.CreditAllowanceGrant, transaction *model.CreditTransaction) (bool, error) {
+	granted := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		credits, err := lockUserCredits(tx, grant.UserID)
+		if err != nil {
+			return err
+		}
+		var count int64
+		err = tx.Model(&model.CreditAllowanceGrant{}).
+			Where("allowance_id = ? AND user_id = ? AND period = ?", grant.AllowanceID, grant.UserID, grant.Period).
+			Count(&count).Error
+		if err != nil || count > 0 {
+			return err
+		}
+		if err = tx.Create(grant).Error; err != nil {
+			return err
+		}
+		if err = applyCreditChange(tx, credits, transaction); err != nil {
+			return err
+		}
+		granted = true
+		return nil
+	})
+	return granted, err
+}
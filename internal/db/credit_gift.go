@@ -0,0 +1,80 @@
+package db
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"gorm.io/gorm"
+)
+
+// CreateCreditGift 在同一个事务中扣除赠送方积分并创建礼物记录
+func CreateCreditGift(gift *model.CreditGift, transaction *model.CreditTransaction) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		credits, err := lockUserCredits(tx, gift.SenderID)
+		if err != nil {
+			return err
+		}
+		if err = applyCreditChange(tx, credits, transaction); err != nil {
+			return err
+		}
+		gift.Status = model.CreditGiftPending
+		return tx.Create(gift).Error
+	})
+}
+
+// GetCreditGiftByNo 根据礼物编号获取礼物
+func GetCreditGiftByNo(giftNo string) (*model.CreditGift, error) {
+	var gift model.CreditGift
+	err := db.Where("gift_no = ?", giftNo).First(&gift).Error
+	return &gift, err
+}
+
+// GetCreditGifts 获取用户赠送（sent 为 true）或收到的礼物
+func GetCreditGifts(userID uint, sent bool, page, pageSize int) ([]model.CreditGift, int64, error) {
+	var gifts []model.CreditGift
+	var total int64
+
+	query := db.Model(&model.CreditGift{})
+	if sent {
+		query = query.Where("sender_id = ?", userID)
+	} else {
+		query = query.Where("recipient_id = ?", userID)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&gifts).Error
+	return gifts, total, err
+}
+
+// GetExpiredCreditGifts 获取已过期但仍未领取的礼物
+func GetExpiredCreditGifts(now time.Time) ([]model.CreditGift, error) {
+	var gifts []model.CreditGift
+	err := db.Where("status = ? AND expires_at < ?", model.CreditGiftPending, now).Find(&gifts).Error
+	return gifts, err
+}
+
+// FinishCreditGift 将待领取的礼物切换到领取或退还状态，并为接收方或赠送方增加积分。
+// 礼物不处于待领取状态时返回 errs.CreditGiftNotPending，保证一份礼物只会被领取或退还一次
+func FinishCreditGift(giftID uint, status string, transaction *model.CreditTransaction) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&model.CreditGift{}).
+			Where("id = ? AND status = ?", giftID, model.CreditGiftPending).
+			Updates(map[string]any{"status": status, "finished_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errs.CreditGiftNotPending
+		}
+		credits, err := lockUserCredits(tx, transaction.UserID)
+		if err != nil {
+			return err
+		}
+		return applyCreditChange(tx, credits, transaction)
+	})
+}
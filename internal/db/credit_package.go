@@ -0,0 +1,33 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+)
+
+// GetCreditPackages 获取积分充值套餐列表
+func GetCreditPackages(enabledOnly bool) ([]model.CreditPackage, error) {
+	var packages []model.CreditPackage
+	query := db.Model(&model.CreditPackage{})
+	if enabledOnly {
+		query = query.Where("enabled = ?", true)
+	}
+	err := query.Order("credits ASC").Find(&packages).Error
+	return packages, err
+}
+
+// GetCreditPackageByID 根据ID获取积分充值套餐
+func GetCreditPackageByID(id uint) (*model.CreditPackage, error) {
+	var pkg model.CreditPackage
+	err := db.First(&pkg, id).Error
+	return &pkg, err
+}
+
+// SaveCreditPackage 创建或更新积分充值套餐
+func SaveCreditPackage(pkg *model.CreditPackage) error {
+	return db.Save(pkg).Error
+}
+
+// DeleteCreditPackage 删除积分充值套餐
+func DeleteCreditPackage(id uint) error {
+	return db.Delete(&model.CreditPackage{}, id).Error
+}
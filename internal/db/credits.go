@@ -1,9 +1,15 @@
 package db
 
 import (
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CreateUserCredits 创建用户积分账户
@@ -18,9 +24,390 @@ func GetUserCreditsByUserID(userID uint) (*model.UserCredits, error) {
 	return &credits, err
 }
 
-// UpdateUserCredits 更新用户积分账户
+// UpdateUserCredits 更新用户积分账户，仅当版本号未变化时才会写入，
+// 否则返回 errs.CreditsVersionConflict，成功后 credits.Version 递增
 func UpdateUserCredits(credits *model.UserCredits) error {
-	return db.Save(credits).Error
+	return updateUserCredits(db, credits)
+}
+
+func updateUserCredits(tx *gorm.DB, credits *model.UserCredits) error {
+	result := tx.Model(&model.UserCredits{}).
+		Where("id = ? AND version = ?", credits.ID, credits.Version).
+		Updates(map[string]interface{}{
+			"balance":     credits.Balance,
+			"total_earn":  credits.TotalEarn,
+			"total_spent": credits.TotalSpent,
+			"held":        credits.Held,
+			"bonus":       credits.Bonus,
+			"version":     credits.Version + 1,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errs.CreditsVersionConflict
+	}
+	credits.Version++
+	return nil
+}
+
+// deductUserCredits 以单条带余额条件的 UPDATE 扣除积分或冻结积分，
+// 可用积分（balance - held）不足 spent + held 时没有行被更新，返回 errs.InsufficientCredits，
+// 不依赖事务内先读后比较的结果。扣除时优先使用赠送积分，purchasedOnly 为 true 时只能使用
+// 未被冻结的购买积分（冻结的积分在扣除时同样优先使用赠送积分）。
+// 成功后重新读取账户以刷新 credits，并返回扣除的赠送积分数量
+func deductUserCredits(tx *gorm.DB, credits *model.UserCredits, spent, held int64, purchasedOnly bool) (int64, error) {
+	query := tx.Model(&model.UserCredits{}).Where("id = ? AND balance - held >= ?", credits.ID, spent+held)
+	updates := map[string]interface{}{
+		"balance":     gorm.Expr("balance - ?", spent),
+		"total_spent": gorm.Expr("total_spent + ?", spent),
+		"held":        gorm.Expr("held + ?", held),
+		"version":     gorm.Expr("version + 1"),
+	}
+	if purchasedOnly {
+		query = query.Where("balance - bonus - CASE WHEN held > bonus THEN held - bonus ELSE 0 END >= ?", spent+held)
+	} else if spent > 0 {
+		updates["bonus"] = gorm.Expr("CASE WHEN bonus > ? THEN bonus - ? ELSE 0 END", spent, spent)
+	}
+	bonus := credits.Bonus
+	result := query.Updates(updates)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		if purchasedOnly {
+			return 0, errors.WithMessage(errs.InsufficientCredits, "only purchased credits can be used")
+		}
+		return 0, errs.InsufficientCredits
+	}
+	if err := tx.First(credits, credits.ID).Error; err != nil {
+		return 0, err
+	}
+	return bonus - credits.Bonus, nil
+}
+
+// ChangeUserCredits 在同一个事务中变更用户积分余额并写入交易记录。
+// 积分账户行在事务内加锁（SELECT ... FOR UPDATE），并通过版本号做乐观锁校验，
+// 对于不支持行锁的 SQLite，并发修改会返回 errs.CreditsVersionConflict，由调用方重试。
+// 扣减通过 deductUserCredits 的条件更新完成，可用积分不足返回 errs.InsufficientCredits，
+// 交易记录的 Balance 为变更后余额。
+func ChangeUserCredits(transaction *model.CreditTransaction) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		credits, err := lockUserCredits(tx, transaction.UserID)
+		if err != nil {
+			return err
+		}
+		return applyCreditChange(tx, credits, transaction)
+	})
+}
+
+// applyCreditChange 在事务内按交易记录变更已加锁的积分账户，并维护积分批次。
+// 交易的 Bonus 按 transaction.Purchased 计算：获得的积分计入对应的钱包，扣除的积分按 deductUserCredits 的规则分摊
+func applyCreditChange(tx *gorm.DB, credits *model.UserCredits, transaction *model.CreditTransaction) error {
+	if credits.Frozen {
+		return errs.CreditsFrozen
+	}
+	amount := transaction.Amount
+	if amount < 0 {
+		bonus, err := deductUserCredits(tx, credits, -amount, 0, transaction.Purchased)
+		if err != nil {
+			return err
+		}
+		transaction.Bonus = -bonus
+	} else {
+		transaction.Bonus = amount
+		if transaction.Purchased {
+			transaction.Bonus = 0
+		}
+		credits.Balance += amount
+		credits.Bonus += transaction.Bonus
+		credits.TotalEarn += amount
+		if err := updateUserCredits(tx, credits); err != nil {
+			return err
+		}
+	}
+	transaction.Balance = credits.Balance
+	if err := tx.Create(transaction).Error; err != nil {
+		return err
+	}
+	if amount > 0 {
+		return tx.Create(&model.CreditLot{
+			UserID:        transaction.UserID,
+			TransactionID: transaction.ID,
+			Amount:        amount,
+			Remaining:     amount,
+			Source:        transaction.Source,
+			Bonus:         !transaction.Purchased,
+			ExpiresAt:     transaction.ExpiresAt,
+		}).Error
+	}
+	return consumeCreditLots(tx, transaction.UserID, -amount, -transaction.Bonus)
+}
+
+// TransferUserCredits 在同一个事务中完成积分转账：扣除转出方的转账积分和手续费，增加接收方积分。
+// dailyLimit 大于 0 时，转出方自 since 起的转出总额（不含手续费）超过限额返回 errs.TransferLimitExceeded。
+// 两个账户按用户ID顺序加锁，避免并发互转时死锁。
+func TransferUserCredits(out, fee, in *model.CreditTransaction, dailyLimit int64, since time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		first, second := out.UserID, in.UserID
+		if first > second {
+			first, second = second, first
+		}
+		accounts := make(map[uint]*model.UserCredits, 2)
+		for _, userID := range []uint{first, second} {
+			credits, err := lockUserCredits(tx, userID)
+			if err != nil {
+				return err
+			}
+			accounts[userID] = credits
+		}
+		if dailyLimit > 0 {
+			var transferred int64
+			err := tx.Model(&model.CreditTransaction{}).
+				Where("user_id = ? AND type = ? AND created_at >= ?", out.UserID, out.Type, since).
+				Select("COALESCE(SUM(-amount), 0)").Scan(&transferred).Error
+			if err != nil {
+				return err
+			}
+			if transferred-out.Amount > dailyLimit {
+				return errs.TransferLimitExceeded
+			}
+		}
+		sender := accounts[out.UserID]
+		if err := applyCreditChange(tx, sender, out); err != nil {
+			return err
+		}
+		if fee.Amount < 0 {
+			if err := applyCreditChange(tx, sender, fee); err != nil {
+				return err
+			}
+		}
+		return applyCreditChange(tx, accounts[in.UserID], in)
+	})
+}
+
+// consumeCreditLots 按获得先后顺序从未过期的积分批次中扣减，其中 bonus 部分从赠送积分批次扣减，
+// 其余从购买积分批次扣减，批次不足的部分来自未记录批次的历史余额，不做处理
+func consumeCreditLots(tx *gorm.DB, userID uint, amount, bonus int64) error {
+	if err := consumeWalletLots(tx, userID, bonus, true); err != nil {
+		return err
+	}
+	return consumeWalletLots(tx, userID, amount-bonus, false)
+}
+
+func consumeWalletLots(tx *gorm.DB, userID uint, amount int64, bonus bool) error {
+	if amount <= 0 {
+		return nil
+	}
+	var lots []model.CreditLot
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND bonus = ? AND remaining > 0", userID, bonus).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Order("id ASC").Find(&lots).Error
+	if err != nil {
+		return err
+	}
+	for _, lot := range lots {
+		if amount <= 0 {
+			break
+		}
+		used := min(lot.Remaining, amount)
+		err = tx.Model(&model.CreditLot{}).Where("id = ?", lot.ID).
+			Update("remaining", lot.Remaining-used).Error
+		if err != nil {
+			return err
+		}
+		amount -= used
+	}
+	return nil
+}
+
+// GetActiveCreditLots 获取用户未用完且未过期的积分批次
+func GetActiveCreditLots(userID uint) ([]model.CreditLot, error) {
+	var lots []model.CreditLot
+	err := db.Where("user_id = ? AND remaining > 0", userID).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Order("id ASC").Find(&lots).Error
+	return lots, err
+}
+
+// GetExpiredCreditLots 获取已过期但仍有剩余积分的批次
+func GetExpiredCreditLots(now time.Time) ([]model.CreditLot, error) {
+	var lots []model.CreditLot
+	err := db.Where("remaining > 0 AND expires_at IS NOT NULL AND expires_at <= ?", now).Find(&lots).Error
+	return lots, err
+}
+
+// ExpireCreditLot 作废过期批次的剩余积分并写入 expire 交易记录，
+// 作废数量不超过用户当前可用积分，避免影响已冻结的积分
+func ExpireCreditLot(lotID uint, transaction *model.CreditTransaction) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var lot model.CreditLot
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&lot, lotID).Error
+		if err != nil {
+			return err
+		}
+		if lot.Remaining <= 0 {
+			return nil
+		}
+		credits, err := lockUserCredits(tx, lot.UserID)
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&model.CreditLot{}).Where("id = ? AND remaining = ?", lot.ID, lot.Remaining).
+			Update("remaining", 0).Error
+		if err != nil {
+			return err
+		}
+		amount := max(min(lot.Remaining, credits.Available()), 0)
+		if amount == 0 {
+			return nil
+		}
+		// 作废的积分优先从批次所属的钱包扣除
+		bonus := max(amount-credits.Purchased(), 0)
+		if lot.Bonus {
+			bonus = min(amount, credits.Bonus)
+		}
+		credits.Balance -= amount
+		credits.Bonus -= bonus
+		if err = updateUserCredits(tx, credits); err != nil {
+			return err
+		}
+		transaction.UserID = lot.UserID
+		transaction.Amount = -amount
+		transaction.Bonus = -bonus
+		transaction.Balance = credits.Balance
+		transaction.SourceID = strconv.FormatUint(uint64(lot.ID), 10)
+		return tx.Create(transaction).Error
+	})
+}
+
+// lockUserCredits 在事务内加锁读取积分账户，不存在时创建
+func lockUserCredits(tx *gorm.DB, userID uint) (*model.UserCredits, error) {
+	var credits model.UserCredits
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userID).First(&credits).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		credits = model.UserCredits{UserID: userID}
+		err = tx.Create(&credits).Error
+	}
+	return &credits, err
+}
+
+// SetUserCreditsFrozen 冻结或解冻用户的积分账户，账户不存在时创建
+func SetUserCreditsFrozen(userID uint, frozen bool, reason string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		credits, err := lockUserCredits(tx, userID)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&model.UserCredits{}).Where("id = ?", credits.ID).
+			Updates(map[string]interface{}{
+				"frozen":        frozen,
+				"frozen_reason": reason,
+				"version":       gorm.Expr("version + 1"),
+			}).Error
+	})
+}
+
+// CreateCreditHold 冻结用户积分并创建预扣记录，可用积分不足时返回 errs.InsufficientCredits
+func CreateCreditHold(hold *model.CreditHold) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		credits, err := lockUserCredits(tx, hold.UserID)
+		if err != nil {
+			return err
+		}
+		if credits.Frozen {
+			return errs.CreditsFrozen
+		}
+		if _, err = deductUserCredits(tx, credits, 0, hold.Amount, false); err != nil {
+			return err
+		}
+		hold.Status = model.CreditHoldHeld
+		return tx.Create(hold).Error
+	})
+}
+
+// GetCreditHoldByID 根据ID获取预扣记录
+func GetCreditHoldByID(id uint) (*model.CreditHold, error) {
+	var hold model.CreditHold
+	err := db.First(&hold, id).Error
+	return &hold, err
+}
+
+// GetExpiredCreditHolds 获取已超时但仍处于冻结状态的预扣记录
+func GetExpiredCreditHolds(now time.Time) ([]model.CreditHold, error) {
+	var holds []model.CreditHold
+	err := db.Where("status = ? AND expires_at < ?", model.CreditHoldHeld, now).Find(&holds).Error
+	return holds, err
+}
+
+// CaptureCreditHold 扣除预扣的积分并写入交易记录，预扣记录不处于冻结状态时返回 errs.CreditHoldNotActive
+func CaptureCreditHold(holdID uint, transaction *model.CreditTransaction) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		hold, err := finishCreditHold(tx, holdID, model.CreditHoldCaptured)
+		if err != nil {
+			return err
+		}
+		credits, err := lockUserCredits(tx, hold.UserID)
+		if err != nil {
+			return err
+		}
+		if credits.Frozen {
+			return errs.CreditsFrozen
+		}
+		bonus := min(credits.Bonus, hold.Amount)
+		credits.Held -= hold.Amount
+		credits.Balance -= hold.Amount
+		credits.Bonus -= bonus
+		credits.TotalSpent += hold.Amount
+		if err = updateUserCredits(tx, credits); err != nil {
+			return err
+		}
+		transaction.UserID = hold.UserID
+		transaction.Amount = -hold.Amount
+		transaction.Bonus = -bonus
+		transaction.Balance = credits.Balance
+		if err = tx.Create(transaction).Error; err != nil {
+			return err
+		}
+		return consumeCreditLots(tx, hold.UserID, hold.Amount, bonus)
+	})
+}
+
+// ReleaseCreditHold 释放预扣的积分，预扣记录不处于冻结状态时返回 errs.CreditHoldNotActive
+func ReleaseCreditHold(holdID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		hold, err := finishCreditHold(tx, holdID, model.CreditHoldReleased)
+		if err != nil {
+			return err
+		}
+		credits, err := lockUserCredits(tx, hold.UserID)
+		if err != nil {
+			return err
+		}
+		credits.Held -= hold.Amount
+		return updateUserCredits(tx, credits)
+	})
+}
+
+// finishCreditHold 将冻结状态的预扣记录切换到最终状态，保证一笔预扣只会被扣除或释放一次
+func finishCreditHold(tx *gorm.DB, holdID uint, status string) (*model.CreditHold, error) {
+	var hold model.CreditHold
+	if err := tx.First(&hold, holdID).Error; err != nil {
+		return nil, err
+	}
+	result := tx.Model(&model.CreditHold{}).
+		Where("id = ? AND status = ?", holdID, model.CreditHoldHeld).
+		Update("status", status)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errs.CreditHoldNotActive
+	}
+	hold.Status = status
+	return &hold, nil
 }
 
 // CreateCreditTransaction 创建积分交易记录
@@ -28,12 +415,18 @@ func CreateCreditTransaction(transaction *model.CreditTransaction) error {
 	return db.Create(transaction).Error
 }
 
-// GetCreditTransactionsByUserID 获取用户积分交易记录
-func GetCreditTransactionsByUserID(userID uint, page, pageSize int) ([]model.CreditTransaction, int64, error) {
+// GetCreditTransactionsByUserID 按筛选条件获取用户积分交易记录
+func GetCreditTransactionsByUserID(userID uint, filter model.CreditTransactionFilter, page, pageSize int) ([]model.CreditTransaction, int64, error) {
+	filter.UserID = userID
+	return GetCreditTransactions(filter, page, pageSize)
+}
+
+// GetCreditTransactions 按筛选条件获取积分交易记录，UserID 为0时查询所有用户
+func GetCreditTransactions(filter model.CreditTransactionFilter, page, pageSize int) ([]model.CreditTransaction, int64, error) {
 	var transactions []model.CreditTransaction
 	var total int64
 	
-	query := db.Model(&model.CreditTransaction{}).Where("user_id = ?", userID)
+	query := filterCreditTransactions(db.Model(&model.CreditTransaction{}), filter)
 	err := query.Count(&total).Error
 	if err != nil {
 		return nil, 0, err
@@ -44,6 +437,63 @@ func GetCreditTransactionsByUserID(userID uint, page, pageSize int) ([]model.Cre
 	return transactions, total, err
 }
 
+// GetCreditTransactionsBySource 获取指定来源的积分交易记录（所有用户）
+func GetCreditTransactionsBySource(source string, page, pageSize int) ([]model.CreditTransaction, int64, error) {
+	var transactions []model.CreditTransaction
+	var total int64
+
+	query := db.Model(&model.CreditTransaction{}).Where("source = ?", source)
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err = query.Preload("User").Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&transactions).Error
+	return transactions, total, err
+}
+
+// filterCreditTransactions 按筛选条件过滤积分交易记录
+func filterCreditTransactions(query *gorm.DB, filter model.CreditTransactionFilter) *gorm.DB {
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.Source != "" {
+		query = query.Where("source = ?", filter.Source)
+	}
+	if filter.Start != nil {
+		query = query.Where("created_at >= ?", *filter.Start)
+	}
+	if filter.End != nil {
+		query = query.Where("created_at < ?", *filter.End)
+	}
+	switch filter.Sign {
+	case model.AmountPositive:
+		query = query.Where("amount > 0")
+	case model.AmountNegative:
+		query = query.Where("amount < 0")
+	}
+	if filter.Path != "" {
+		query = query.Where("path = ?", filter.Path)
+	}
+	if filter.Keyword != "" {
+		query = query.Where("description LIKE ?", "%"+filter.Keyword+"%")
+	}
+	return query
+}
+
+// EachCreditTransactions 按ID顺序分批遍历符合条件的积分交易记录
+func EachCreditTransactions(filter model.CreditTransactionFilter, batchSize int, fn func([]model.CreditTransaction) error) error {
+	var batch []model.CreditTransaction
+	query := filterCreditTransactions(db.Model(&model.CreditTransaction{}), filter)
+	return query.FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
+
 // CreateFileCreditsConfig 创建文件积分配置
 func CreateFileCreditsConfig(config *model.FileCreditsConfig) error {
 	return db.Create(config).Error
@@ -56,6 +506,36 @@ func GetFileCreditsConfigByPath(path string) (*model.FileCreditsConfig, error) {
 	return &config, err
 }
 
+// GetFileCreditsConfigByID 根据ID获取积分配置，包括已停用的配置
+func GetFileCreditsConfigByID(id uint) (*model.FileCreditsConfig, error) {
+	var config model.FileCreditsConfig
+	err := db.First(&config, id).Error
+	return &config, err
+}
+
+// FindFileCreditsConfig 根据路径获取积分配置，包括已停用的配置
+func FindFileCreditsConfig(path string) (*model.FileCreditsConfig, error) {
+	var config model.FileCreditsConfig
+	err := db.Where("path = ?", path).First(&config).Error
+	return &config, err
+}
+
+// SaveFileCreditsConfig 按路径创建或覆盖积分配置
+func SaveFileCreditsConfig(config *model.FileCreditsConfig) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		return saveFileCreditsConfig(tx, config)
+	})
+}
+
+// SetFileCreditsConfigEnabled 启用或停用积分配置
+func SetFileCreditsConfigEnabled(id uint, enabled bool) error {
+	result := db.Model(&model.FileCreditsConfig{}).Where("id = ?", id).Update("enabled", enabled)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
 // GetFileCreditsConfigs 获取文件积分配置列表
 func GetFileCreditsConfigs(page, pageSize int) ([]model.FileCreditsConfig, int64, error) {
 	var configs []model.FileCreditsConfig
@@ -72,9 +552,9 @@ func GetFileCreditsConfigs(page, pageSize int) ([]model.FileCreditsConfig, int64
 	return configs, total, err
 }
 
-// UpdateFileCreditsConfig 更新文件积分配置
+// UpdateFileCreditsConfig 更新文件积分配置，包括值为 false 的布尔字段
 func UpdateFileCreditsConfig(config *model.FileCreditsConfig) error {
-	return db.Save(config).Error
+	return db.Select("*").Omit("created_at").Updates(config).Error
 }
 
 // DeleteFileCreditsConfig 删除文件积分配置
@@ -82,13 +562,148 @@ func DeleteFileCreditsConfig(id uint) error {
 	return db.Delete(&model.FileCreditsConfig{}, id).Error
 }
 
-// GetInheritableCreditsConfig 获取可继承的积分配置
-func GetInheritableCreditsConfig(path string) (*model.FileCreditsConfig, error) {
-	var config model.FileCreditsConfig
-	// 查找最匹配的父级配置
-	err := db.Where("? LIKE CONCAT(path, '%') AND is_folder = true AND inheritable = true AND enabled = true", path).
-		Order("LENGTH(path) DESC").First(&config).Error
-	return &config, err
+// SaveFileCreditsConfigs 在同一个事务中创建或覆盖多个路径的积分配置
+func SaveFileCreditsConfigs(configs []*model.FileCreditsConfig) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, config := range configs {
+			if err := saveFileCreditsConfig(tx, config); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// saveFileCreditsConfig 按路径创建或覆盖积分配置，已删除的同路径配置会被恢复，避免触发路径的唯一索引
+func saveFileCreditsConfig(tx *gorm.DB, config *model.FileCreditsConfig) error {
+	var existing model.FileCreditsConfig
+	err := tx.Unscoped().Where("path = ?", config.Path).First(&existing).Error
+	if err == nil {
+		config.ID = existing.ID
+		config.CreatedAt = existing.CreatedAt
+		// 恢复已删除的配置时不保留原来的豁免名单
+		if existing.DeletedAt.Valid {
+			if err = tx.Where("config_id = ?", existing.ID).Delete(&model.FileCreditsExemption{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Save(config).Error
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err = tx.Create(config).Error; err != nil {
+		return err
+	}
+	// 有默认值的布尔字段为 false 时创建会被忽略，需要单独更新
+	return tx.Model(config).Select("inheritable", "enabled").Updates(config).Error
+}
+
+// DeleteFileCreditsConfigsByPaths 删除指定路径的积分配置，返回删除的数量
+func DeleteFileCreditsConfigsByPaths(paths []string) (int64, error) {
+	result := db.Where("path IN ?", paths).Delete(&model.FileCreditsConfig{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteFileCreditsConfigTree 删除 root 及其下所有路径的积分配置，返回删除的数量
+func DeleteFileCreditsConfigTree(root string) (int64, error) {
+	configs, err := findFileCreditsConfigTree(db, root)
+	if err != nil || len(configs) == 0 {
+		return 0, err
+	}
+	ids := make([]uint, 0, len(configs))
+	for _, config := range configs {
+		ids = append(ids, config.ID)
+	}
+	result := db.Delete(&model.FileCreditsConfig{}, ids)
+	return result.RowsAffected, result.Error
+}
+
+// MoveFileCreditsConfigTree 将 src 及其下所有路径的积分配置移动到 dst，返回移动的数量。
+// 目标路径上已有的配置（包括已删除的）会被移除，与文件被覆盖的行为一致
+func MoveFileCreditsConfigTree(src, dst string) (int64, error) {
+	var count int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		configs, err := findFileCreditsConfigTree(tx, src)
+		if err != nil {
+			return err
+		}
+		for _, config := range configs {
+			newPath := dst + strings.TrimPrefix(config.Path, src)
+			err = tx.Unscoped().Where("path = ? AND id <> ?", newPath, config.ID).Delete(&model.FileCreditsConfig{}).Error
+			if err != nil {
+				return err
+			}
+			err = tx.Model(&model.FileCreditsConfig{}).Where("id = ?", config.ID).
+				Updates(map[string]any{"path": newPath, "orphaned": false}).Error
+			if err != nil {
+				return err
+			}
+		}
+		count = int64(len(configs))
+		return nil
+	})
+	return count, err
+}
+
+// MarkFileCreditsConfigTreeOrphaned 将 root 及其下所有路径的积分配置标记为孤立，返回标记的数量
+func MarkFileCreditsConfigTreeOrphaned(root string) (int64, error) {
+	configs, err := findFileCreditsConfigTree(db, root)
+	if err != nil || len(configs) == 0 {
+		return 0, err
+	}
+	ids := make([]uint, 0, len(configs))
+	for _, config := range configs {
+		ids = append(ids, config.ID)
+	}
+	result := db.Model(&model.FileCreditsConfig{}).Where("id IN ?", ids).Update("orphaned", true)
+	return result.RowsAffected, result.Error
+}
+
+// GetOrphanedFileCreditsConfigs 获取所有被标记为孤立的积分配置
+func GetOrphanedFileCreditsConfigs() ([]model.FileCreditsConfig, error) {
+	var configs []model.FileCreditsConfig
+	err := db.Where("orphaned = ?", true).Order("path ASC").Find(&configs).Error
+	return configs, err
+}
+
+// DeleteOrphanedFileCreditsConfigs 删除所有被标记为孤立的积分配置，返回删除的数量
+func DeleteOrphanedFileCreditsConfigs() (int64, error) {
+	result := db.Where("orphaned = ?", true).Delete(&model.FileCreditsConfig{})
+	return result.RowsAffected, result.Error
+}
+
+// EachFileCreditsConfigs 分批遍历所有积分配置
+func EachFileCreditsConfigs(batchSize int, fn func([]model.FileCreditsConfig) error) error {
+	var batch []model.FileCreditsConfig
+	return db.Model(&model.FileCreditsConfig{}).FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
+
+// findFileCreditsConfigTree 获取 root 及其下所有路径的积分配置。
+// 路径中的 _ 和 % 会被 LIKE 当作通配符，先按 LIKE 粗筛再按前缀精确过滤
+func findFileCreditsConfigTree(tx *gorm.DB, root string) ([]model.FileCreditsConfig, error) {
+	prefix := strings.TrimSuffix(root, "/") + "/"
+	var candidates []model.FileCreditsConfig
+	err := tx.Where("path = ? OR path LIKE ?", root, prefix+"%").Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+	configs := candidates[:0]
+	for _, config := range candidates {
+		if config.Path == root || strings.HasPrefix(config.Path, prefix) {
+			configs = append(configs, config)
+		}
+	}
+	return configs, nil
+}
+
+// GetInheritableFolderConfigs 获取所有启用且可被子路径继承的文件夹积分配置
+func GetInheritableFolderConfigs() ([]model.FileCreditsConfig, error) {
+	var configs []model.FileCreditsConfig
+	err := db.Where("is_folder = ? AND inheritable = ? AND enabled = ?", true, true, true).Find(&configs).Error
+	return configs, err
 }
 
 // CreateRedeemCode 创建兑换码
@@ -129,22 +744,54 @@ func CreateRedeemCodeUsage(usage *model.RedeemCodeUsage) error {
 	return db.Create(usage).Error
 }
 
-// GetRedeemCodeUsages 获取兑换码使用记录
-func GetRedeemCodeUsages(redeemCodeID uint, page, pageSize int) ([]model.RedeemCodeUsage, int64, error) {
+// CountUserRedeemCodeUsages 统计用户使用某个兑换码的次数
+func CountUserRedeemCodeUsages(redeemCodeID, userID uint) (int64, error) {
+	var count int64
+	err := db.Model(&model.RedeemCodeUsage{}).Where("redeem_code_id = ? AND user_id = ?", redeemCodeID, userID).Count(&count).Error
+	return count, err
+}
+
+// GetRedeemCodeUsages 按筛选条件分页获取兑换码使用记录
+func GetRedeemCodeUsages(filter model.RedeemCodeUsageFilter, page, pageSize int) ([]model.RedeemCodeUsage, int64, error) {
 	var usages []model.RedeemCodeUsage
 	var total int64
 	
-	query := db.Model(&model.RedeemCodeUsage{}).Where("redeem_code_id = ?", redeemCodeID)
+	query := db.Model(&model.RedeemCodeUsage{})
+	if filter.RedeemCodeID != 0 {
+		query = query.Where("redeem_code_id = ?", filter.RedeemCodeID)
+	}
+	if filter.Code != "" || filter.BatchNo != "" {
+		codes := filterRedeemCodes(db.Unscoped().Model(&model.RedeemCode{}).Select("id"),
+			model.RedeemCodeFilter{Code: filter.Code, BatchNo: filter.BatchNo})
+		query = query.Where("redeem_code_id IN (?)", codes)
+	}
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Start != nil {
+		query = query.Where("used_at >= ?", *filter.Start)
+	}
+	if filter.End != nil {
+		query = query.Where("used_at < ?", *filter.End)
+	}
 	err := query.Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 	
 	offset := (page - 1) * pageSize
-	err = query.Preload("User").Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&usages).Error
+	err = query.Preload("User").Preload("RedeemCode", func(tx *gorm.DB) *gorm.DB { return tx.Unscoped() }).
+		Order("used_at DESC").Offset(offset).Limit(pageSize).Find(&usages).Error
 	return usages, total, err
 }
 
+// FindRedeemCode 根据兑换码获取记录，包括已停用的兑换码
+func FindRedeemCode(code string) (*model.RedeemCode, error) {
+	var redeemCode model.RedeemCode
+	err := db.Where("code = ?", code).First(&redeemCode).Error
+	return &redeemCode, err
+}
+
 // CreatePaymentOrder 创建支付订单
 func CreatePaymentOrder(order *model.PaymentOrder) error {
 	return db.Create(order).Error
@@ -178,6 +825,55 @@ func UpdatePaymentOrder(order *model.PaymentOrder) error {
 	return db.Save(order).Error
 }
 
+// PayPaymentOrder 在同一个事务中将待支付的订单标记为已支付并入账：transaction 不为空时增加积分，
+// months 大于0时延长会员有效期。订单已不是待支付状态时返回 false 且不做任何变更，避免重复入账
+func PayPaymentOrder(order *model.PaymentOrder, transaction *model.CreditTransaction, months int) (bool, error) {
+	var paid bool
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.PaymentOrder{}).
+			Where("id = ? AND status = ?", order.ID, model.PaymentOrderPending).
+			Updates(map[string]interface{}{
+				"status":       model.PaymentOrderPaid,
+				"payment_data": order.PaymentData,
+				"paid_at":      order.PaidAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return nil
+		}
+		if transaction != nil {
+			credits, err := lockUserCredits(tx, transaction.UserID)
+			if err != nil {
+				return err
+			}
+			if err = applyCreditChange(tx, credits, transaction); err != nil {
+				return err
+			}
+		}
+		if months > 0 {
+			_, err := extendUserVipTx(tx, order.UserID, func(start time.Time) time.Time {
+				return start.AddDate(0, months, 0)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		paid = true
+		return nil
+	})
+	return paid, err
+}
+
+// CancelPaymentOrder 取消待支付的订单，订单已不是待支付状态时返回 false
+func CancelPaymentOrder(orderID uint) (bool, error) {
+	result := db.Model(&model.PaymentOrder{}).
+		Where("id = ? AND status = ?", orderID, model.PaymentOrderPending).
+		Update("status", model.PaymentOrderCancelled)
+	return result.RowsAffected == 1, result.Error
+}
+
 // CleanExpiredPaymentOrders 清理过期的支付订单
 func CleanExpiredPaymentOrders() error {
 	return db.Where("expires_at < ? AND status = 'pending'", time.Now()).Update("status", "expired").Error
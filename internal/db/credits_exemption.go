@@ -0,0 +1,37 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"gorm.io/gorm"
+)
+
+// GetFileCreditsExemptions 获取文件积分配置的豁免名单
+func GetFileCreditsExemptions(configID uint) ([]model.FileCreditsExemption, error) {
+	var exemptions []model.FileCreditsExemption
+	err := db.Where("config_id = ?", configID).Order("id ASC").Find(&exemptions).Error
+	return exemptions, err
+}
+
+// SetFileCreditsExemptions 替换文件积分配置的豁免名单
+func SetFileCreditsExemptions(configID uint, exemptions []model.FileCreditsExemption) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("config_id = ?", configID).Delete(&model.FileCreditsExemption{}).Error; err != nil {
+			return err
+		}
+		if len(exemptions) == 0 {
+			return nil
+		}
+		return tx.Create(&exemptions).Error
+	})
+}
+
+// IsFileCreditsExempt 检查用户本人或其所在的定价组是否在文件积分配置的豁免名单中
+func IsFileCreditsExempt(configID, userID uint) (bool, error) {
+	var count int64
+	groups := db.Model(&model.UserPricingGroup{}).Select("group_id").Where("user_id = ?", userID)
+	err := db.Model(&model.FileCreditsExemption{}).
+		Where("config_id = ?", configID).
+		Where(db.Where("user_id = ?", userID).Or("group_id > 0 AND group_id IN (?)", groups)).
+		Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetCreditLedgerSums 按用户汇总交易记录的积分变动
+func GetCreditLedgerSums() (map[uint]int64, error) {
+	var rows []struct {
+		UserID uint
+		Total  int64
+	}
+	err := db.Model(&model.CreditTransaction{}).
+		Select("user_id, COALESCE(SUM(amount), 0) AS total").
+		Group("user_id").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	sums := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		sums[row.UserID] = row.Total
+	}
+	return sums, nil
+}
+
+// GetAllUserCreditBalances 获取所有积分账户的余额
+func GetAllUserCreditBalances() ([]model.UserCredits, error) {
+	var accounts []model.UserCredits
+	err := db.Select("id", "user_id", "balance").Find(&accounts).Error
+	return accounts, err
+}
+
+// VerifyUserCreditLedger 在事务内锁定积分账户并按交易记录重新计算余额，
+// repair 为 true 且不一致时将账户余额修正为交易记录的合计
+func VerifyUserCreditLedger(userID uint, repair bool) (balance, expected int64, err error) {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		credits, err := lockUserCredits(tx, userID)
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&model.CreditTransaction{}).Where("user_id = ?", userID).
+			Select("COALESCE(SUM(amount), 0)").Scan(&expected).Error
+		if err != nil {
+			return err
+		}
+		balance = credits.Balance
+		if !repair || balance == expected {
+			return nil
+		}
+		credits.Balance = expected
+		credits.Bonus = max(min(credits.Bonus, expected), 0)
+		return updateUserCredits(tx, credits)
+	})
+	return balance, expected, err
+}
+
+// SaveCreditLedgerIssue 记录账本不一致，已有记录时刷新余额并重新标记为未修复
+func SaveCreditLedgerIssue(issue *model.CreditLedgerIssue) error {
+	return db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"balance":     issue.Balance,
+			"expected":    issue.Expected,
+			"resolved":    false,
+			"resolved_by": 0,
+			"resolved_at": nil,
+			"updated_at":  time.Now(),
+		}),
+	}).Create(issue).Error
+}
+
+// DeleteUnresolvedCreditLedgerIssues 删除未修复但已不再不一致的记录
+func DeleteUnresolvedCreditLedgerIssues(keepUserIDs []uint) error {
+	query := db.Where("resolved = ?", false)
+	if len(keepUserIDs) > 0 {
+		query = query.Where("user_id NOT IN ?", keepUserIDs)
+	}
+	return query.Delete(&model.CreditLedgerIssue{}).Error
+}
+
+// GetCreditLedgerIssues 获取账本不一致记录
+func GetCreditLedgerIssues(resolved bool, page, pageSize int) ([]model.CreditLedgerIssue, int64, error) {
+	var issues []model.CreditLedgerIssue
+	var total int64
+
+	query := db.Model(&model.CreditLedgerIssue{}).Where("resolved = ?", resolved)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Order("updated_at DESC").Offset(offset).Limit(pageSize).Find(&issues).Error
+	return issues, total, err
+}
+
+// ResolveCreditLedgerIssue 将用户的账本不一致记录标记为已修复
+func ResolveCreditLedgerIssue(userID, adminID uint) error {
+	now := time.Now()
+	return db.Model(&model.CreditLedgerIssue{}).Where("user_id = ?", userID).
+		Updates(map[string]interface{}{"resolved": true, "resolved_by": adminID, "resolved_at": &now}).Error
+}
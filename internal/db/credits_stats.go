@@ -0,0 +1,96 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+)
+
+// GetCreditRanking 按统计期内的消费或获得积分、或当前余额对用户排序，since 为空时统计全部记录
+func GetCreditRanking(by string, since *time.Time, limit int) ([]model.CreditRankEntry, error) {
+	var entries []model.CreditRankEntry
+	var err error
+	if by == model.RankByBalance {
+		err = db.Model(&model.UserCredits{}).
+			Select("user_id, balance AS value").Where("balance > 0").
+			Order("value DESC").Limit(limit).Scan(&entries).Error
+	} else {
+		value := "SUM(CASE WHEN amount > 0 THEN amount ELSE 0 END)"
+		if by == model.RankBySpend {
+			value = "SUM(CASE WHEN amount < 0 THEN -amount ELSE 0 END)"
+		}
+		query := db.Model(&model.CreditTransaction{}).Select("user_id, " + value + " AS value")
+		if since != nil {
+			query = query.Where("created_at >= ?", *since)
+		}
+		err = query.Group("user_id").Having(value + " > 0").
+			Order("value DESC").Limit(limit).Scan(&entries).Error
+	}
+	if err != nil || len(entries) == 0 {
+		return entries, err
+	}
+
+	ids := make([]uint, len(entries))
+	for i, e := range entries {
+		ids[i] = e.UserID
+	}
+	var users []model.User
+	if err = db.Select("id", "username").Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[uint]string, len(users))
+	for _, u := range users {
+		names[u.ID] = u.Username
+	}
+	for i := range entries {
+		entries[i].Username = names[entries[i].UserID]
+	}
+	return entries, nil
+}
+
+// SumCreditTransactionsByPeriod 按 starts 划分的周期在数据库中分组汇总积分收支，starts 按时间升序，
+// 每个周期从其开始时间到下一个周期开始，userID 为0时统计全站，返回与 starts 一一对应的汇总
+func SumCreditTransactionsByPeriod(userID uint, starts []time.Time) ([]model.CreditStatsBucket, error) {
+	buckets := make([]model.CreditStatsBucket, len(starts))
+	if len(starts) == 0 {
+		return buckets, nil
+	}
+	var bucket strings.Builder
+	args := make([]interface{}, 0, len(starts))
+	bucket.WriteString("CASE")
+	for i := len(starts) - 1; i > 0; i-- {
+		bucket.WriteString(fmt.Sprintf(" WHEN created_at >= ? THEN %d", i))
+		args = append(args, starts[i])
+	}
+	bucket.WriteString(" ELSE 0 END")
+
+	var rows []struct {
+		Bucket int
+		Earn   int64
+		Spend  int64
+		Count  int64
+	}
+	query := db.Model(&model.CreditTransaction{}).
+		Select(bucket.String()+" AS bucket, "+
+			"SUM(CASE WHEN amount > 0 THEN amount ELSE 0 END) AS earn, "+
+			"SUM(CASE WHEN amount < 0 THEN -amount ELSE 0 END) AS spend, "+
+			"COUNT(*) AS count", args...).
+		Where("created_at >= ?", starts[0])
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	if err := query.Group("bucket").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if row.Bucket < 0 || row.Bucket >= len(buckets) {
+			continue
+		}
+		buckets[row.Bucket].Earn = row.Earn
+		buckets[row.Bucket].Spend = row.Spend
+		buckets[row.Bucket].Count = row.Count
+	}
+	return buckets, nil
+}
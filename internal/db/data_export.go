@@ -0,0 +1,94 @@
+package db
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+// CreateDataExport 创建数据导出记录
+func CreateDataExport(export *model.DataExport) error {
+	return errors.WithStack(db.Create(export).Error)
+}
+
+// UpdateDataExport 保存数据导出记录
+func UpdateDataExport(export *model.DataExport) error {
+	return errors.WithStack(db.Save(export).Error)
+}
+
+// GetLatestDataExport 获取用户最近一次的数据导出
+func GetLatestDataExport(userID uint) (*model.DataExport, error) {
+	var export model.DataExport
+	if err := db.Where("user_id = ?", userID).Order("id DESC").First(&export).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &export, nil
+}
+
+// GetDataExportByToken 根据下载令牌获取数据导出
+func GetDataExportByToken(token string) (*model.DataExport, error) {
+	var export model.DataExport
+	if err := db.Where("token = ?", token).First(&export).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &export, nil
+}
+
+// GetExpiredDataExports 获取已到期的数据导出
+func GetExpiredDataExports(now time.Time) ([]model.DataExport, error) {
+	var exports []model.DataExport
+	err := db.Where("expires_at < ?", now).Find(&exports).Error
+	return exports, errors.WithStack(err)
+}
+
+// GetPendingDataExports 获取创建早于 before 仍在生成中的数据导出
+func GetPendingDataExports(before time.Time) ([]model.DataExport, error) {
+	var exports []model.DataExport
+	err := db.Where("status = ? AND created_at < ?", model.DataExportPending, before).Find(&exports).Error
+	return exports, errors.WithStack(err)
+}
+
+// DeleteDataExport 删除数据导出记录
+func DeleteDataExport(id uint) error {
+	return errors.WithStack(db.Delete(&model.DataExport{}, id).Error)
+}
+
+// GetUserRegistrations 获取用户名或邮箱与用户相关的所有注册申请
+func GetUserRegistrations(username, email string) ([]model.UserRegistration, error) {
+	var registrations []model.UserRegistration
+	query := db.Where("username = ?", username)
+	if email != "" {
+		query = query.Or("email = ?", email)
+	}
+	err := query.Order("id").Find(&registrations).Error
+	return registrations, errors.WithStack(err)
+}
+
+// GetAllPaymentOrdersByUserID 获取用户的所有支付订单
+func GetAllPaymentOrdersByUserID(userID uint) ([]model.PaymentOrder, error) {
+	var orders []model.PaymentOrder
+	err := db.Where("user_id = ?", userID).Order("id").Find(&orders).Error
+	return orders, errors.WithStack(err)
+}
+
+// GetRedeemCodeUsagesByUserID 获取用户的所有兑换码使用记录
+func GetRedeemCodeUsagesByUserID(userID uint) ([]model.RedeemCodeUsage, error) {
+	var usages []model.RedeemCodeUsage
+	err := db.Preload("RedeemCode").Where("user_id = ?", userID).Order("id").Find(&usages).Error
+	return usages, errors.WithStack(err)
+}
+
+// GetDownloadPurchasesByUserID 获取用户的所有付费下载记录
+func GetDownloadPurchasesByUserID(userID uint) ([]model.DownloadPurchase, error) {
+	var purchases []model.DownloadPurchase
+	err := db.Where("user_id = ?", userID).Order("id").Find(&purchases).Error
+	return purchases, errors.WithStack(err)
+}
+
+// GetAllTermsAcceptancesByUserID 获取用户的所有同意条款记录
+func GetAllTermsAcceptancesByUserID(userID uint) ([]model.TermsAcceptance, error) {
+	var acceptances []model.TermsAcceptance
+	err := db.Where("user_id = ?", userID).Order("id").Find(&acceptances).Error
+	return acceptances, errors.WithStack(err)
+}
@@ -18,12 +18,25 @@ func Init(d *gorm.DB) {
 		// 用户注册相关模型
 		new(model.UserRegistration), new(model.VerificationCode),
 		// 积分系统相关模型
-		new(model.UserCredits), new(model.CreditTransaction), new(model.FileCreditsConfig),
+		new(model.UserCredits), new(model.CreditTransaction), new(model.CreditLot), new(model.CreditHold),
+		new(model.FileCreditsConfig),
 		new(model.RedeemCode), new(model.RedeemCodeUsage), new(model.PaymentOrder),
+		new(model.ReferralCode), new(model.Referral), new(model.UploadEarnRule), new(model.UploadReward),
+		new(model.SubscriptionPlan), new(model.PricingGroup), new(model.UserPricingGroup),
+		new(model.TrafficAccount), new(model.DownloadQuotaUsage),
+		new(model.DownloadPurchase), new(model.PricingRule),
+		new(model.CreditLedgerIssue), new(model.Coupon), new(model.CreditGift),
+		new(model.FileCreditsExemption), new(model.Promotion),
+		new(model.RewardSource), new(model.ExternalReward), new(model.CreditPackage),
+		new(model.CreditAllowance), new(model.CreditAllowanceGrant), new(model.ApiUsage), new(model.RedeemBatch), new(model.RedeemCampaign), new(model.RedeemCodeRevocation), new(model.MailDelivery), new(model.InviteCode), new(model.Invitation),
+		new(model.WebAuthnCredential), new(model.OtpBackupCode), new(model.EmailChange), new(model.TermsDocument), new(model.TermsAcceptance), new(model.DataExport), new(model.ProvisioningTemplate), new(model.UserIdentity), new(model.DownloadToken), new(model.FileShare),
 	)
 	if err != nil {
 		log.Fatalf("failed migrate database: %s", err.Error())
 	}
+	if err = migrateUserAuthn(); err != nil {
+		log.Fatalf("failed migrate passkeys: %+v", err)
+	}
 }
 
 func AutoMigrate(dst ...interface{}) error {
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetDownloadPurchase 获取用户对某个文件的购买记录
+func GetDownloadPurchase(userID uint, path string) (*model.DownloadPurchase, error) {
+	var purchase model.DownloadPurchase
+	err := db.Where("user_id = ? AND path = ?", userID, path).First(&purchase).Error
+	return &purchase, err
+}
+
+// SaveDownloadPurchase 记录付费下载，已有记录时刷新付费时间和积分
+func SaveDownloadPurchase(purchase *model.DownloadPurchase) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "path"}},
+		DoUpdates: clause.AssignmentColumns([]string{"credits", "size", "storage", "purchased_at", "updated_at"}),
+	}).Create(purchase).Error
+}
+
+// GetDownloadPurchases 分页获取用户的付费下载记录，按最近付费时间倒序
+func GetDownloadPurchases(userID uint, page, pageSize int) ([]model.DownloadPurchase, int64, error) {
+	var purchases []model.DownloadPurchase
+	var total int64
+	query := db.Model(&model.DownloadPurchase{}).Where("user_id = ?", userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("purchased_at DESC").Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&purchases).Error
+	return purchases, total, err
+}
+
+// unlockDownload 在事务内永久解锁用户对文件的下载，已有购买记录时标记为已解锁
+func unlockDownload(tx *gorm.DB, purchase *model.DownloadPurchase) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "path"}},
+		DoUpdates: clause.AssignmentColumns([]string{"unlocked", "updated_at"}),
+	}).Create(purchase).Error
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetDownloadQuotaUsage 获取用户某天的免费额度使用量，没有记录时返回空使用量
+func GetDownloadQuotaUsage(userID uint, day string) (*model.DownloadQuotaUsage, error) {
+	usage := model.DownloadQuotaUsage{UserID: userID, Day: day}
+	err := db.Where("user_id = ? AND day = ?", userID, day).Limit(1).Find(&usage).Error
+	return &usage, err
+}
+
+// ConsumeDownloadQuota 在额度范围内累加一次下载，额度不足时返回 false
+func ConsumeDownloadQuota(userID uint, day string, size int64, quota model.DownloadQuota) (bool, error) {
+	var consumed bool
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.DownloadQuotaUsage{UserID: userID, Day: day}).Error
+		if err != nil {
+			return err
+		}
+		query := tx.Model(&model.DownloadQuotaUsage{}).Where("user_id = ? AND day = ?", userID, day)
+		if quota.Downloads > 0 {
+			query = query.Where("downloads < ?", quota.Downloads)
+		}
+		if quota.Bytes > 0 {
+			query = query.Where("bytes + ? <= ?", size, quota.Bytes)
+		}
+		result := query.Updates(map[string]interface{}{
+			"downloads": gorm.Expr("downloads + ?", 1),
+			"bytes":     gorm.Expr("bytes + ?", size),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		consumed = result.RowsAffected > 0
+		return nil
+	})
+	return consumed, err
+}
+
+// RefundDownloadQuota 退还一次下载使用的额度
+func RefundDownloadQuota(userID uint, day string, size int64) error {
+	return db.Model(&model.DownloadQuotaUsage{}).
+		Where("user_id = ? AND day = ? AND downloads > 0", userID, day).
+		Updates(map[string]interface{}{
+			"downloads": gorm.Expr("downloads - ?", 1),
+			"bytes":     gorm.Expr("CASE WHEN bytes > ? THEN bytes - ? ELSE 0 END", size, size),
+		}).Error
+}
+
+// CleanDownloadQuotaUsages 清理指定日期之前的额度使用记录
+func CleanDownloadQuotaUsages(before string) error {
+	return db.Where("day < ?", before).Delete(&model.DownloadQuotaUsage{}).Error
+}
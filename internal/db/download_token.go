@@ -0,0 +1,58 @@
+package db
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// CreateDownloadToken 创建下载令牌
+func CreateDownloadToken(token *model.DownloadToken) error {
+	return errors.WithStack(db.Create(token).Error)
+}
+
+// GetDownloadToken 根据令牌获取下载令牌
+func GetDownloadToken(token string) (*model.DownloadToken, error) {
+	var t model.DownloadToken
+	if err := db.Where("token = ?", token).First(&t).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &t, nil
+}
+
+// UseDownloadToken 仅当令牌未撤销、未过期且未用完时使用一次，返回 false 表示令牌不可用
+func UseDownloadToken(id uint, now time.Time) (bool, error) {
+	res := db.Model(&model.DownloadToken{}).
+		Where("id = ? AND revoked = ? AND uses < max_uses AND expires_at > ?", id, false, now).
+		Update("uses", gorm.Expr("uses + 1"))
+	return res.RowsAffected == 1, errors.WithStack(res.Error)
+}
+
+// GetDownloadTokensByUserID 获取用户未过期的下载令牌
+func GetDownloadTokensByUserID(userID uint, now time.Time) ([]model.DownloadToken, error) {
+	var tokens []model.DownloadToken
+	err := db.Where("user_id = ? AND expires_at > ?", userID, now).Order("id DESC").Find(&tokens).Error
+	return tokens, errors.WithStack(err)
+}
+
+// RevokeDownloadToken 撤销用户的下载令牌，返回 false 表示令牌不存在
+func RevokeDownloadToken(userID, id uint) (bool, error) {
+	res := db.Model(&model.DownloadToken{}).Where("id = ? AND user_id = ?", id, userID).Update("revoked", true)
+	return res.RowsAffected == 1, errors.WithStack(res.Error)
+}
+
+// DeleteExpiredDownloadTokens 删除已过期的下载令牌
+func DeleteExpiredDownloadTokens(now time.Time) error {
+	return errors.WithStack(db.Where("expires_at < ?", now).Delete(&model.DownloadToken{}).Error)
+}
+
+// GetDownloadTokenByID 根据ID获取下载令牌
+func GetDownloadTokenByID(id uint) (*model.DownloadToken, error) {
+	var t model.DownloadToken
+	if err := db.First(&t, id).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &t, nil
+}
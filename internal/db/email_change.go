@@ -0,0 +1,32 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// ChangeUserEmail 在同一事务中保存注册记录的新邮箱并写入审计记录
+func ChangeUserEmail(registration *model.UserRegistration, change *model.EmailChange) error {
+	return errors.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(registration).Error; err != nil {
+			return err
+		}
+		return tx.Create(change).Error
+	}))
+}
+
+// GetEmailChanges 分页获取邮箱更换记录，userID 为 0 时返回全部
+func GetEmailChanges(userID uint, page, pageSize int) ([]model.EmailChange, int64, error) {
+	var changes []model.EmailChange
+	var total int64
+	query := db.Model(&model.EmailChange{})
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, errors.WithStack(err)
+	}
+	err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&changes).Error
+	return changes, total, errors.WithStack(err)
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+// CreateFileShare 创建分享链接
+func CreateFileShare(share *model.FileShare) error {
+	return errors.WithStack(db.Create(share).Error)
+}
+
+// GetFileShareByToken 根据分享令牌获取分享链接，withDeleted 为 true 时包括已删除的分享链接
+func GetFileShareByToken(token string, withDeleted bool) (*model.FileShare, error) {
+	var share model.FileShare
+	tx := db
+	if withDeleted {
+		tx = tx.Unscoped()
+	}
+	if err := tx.Where("token = ?", token).First(&share).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &share, nil
+}
+
+// GetFileSharesByUserID 获取用户创建的分享链接
+func GetFileSharesByUserID(userID uint) ([]model.FileShare, error) {
+	var shares []model.FileShare
+	err := db.Where("user_id = ?", userID).Order("id DESC").Find(&shares).Error
+	return shares, errors.WithStack(err)
+}
+
+// DeleteFileShare 删除用户的分享链接，返回 false 表示分享链接不存在
+func DeleteFileShare(userID, id uint) (bool, error) {
+	res := db.Where("id = ? AND user_id = ?", id, userID).Delete(&model.FileShare{})
+	return res.RowsAffected == 1, errors.WithStack(res.Error)
+}
+
+// GetPaymentOrderByClaimToken 根据领取令牌获取访客订单
+func GetPaymentOrderByClaimToken(claimToken string) (*model.PaymentOrder, error) {
+	var order model.PaymentOrder
+	if err := db.Where("claim_token = ?", claimToken).First(&order).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &order, nil
+}
+
+// SetPaymentOrderDownloadToken 仅当访客订单尚未关联下载令牌时关联，返回 false 表示已被并发请求关联
+func SetPaymentOrderDownloadToken(orderID, tokenID uint) (bool, error) {
+	res := db.Model(&model.PaymentOrder{}).
+		Where("id = ? AND download_token_id = ?", orderID, 0).
+		Update("download_token_id", tokenID)
+	return res.RowsAffected == 1, errors.WithStack(res.Error)
+}
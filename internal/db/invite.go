@@ -0,0 +1,61 @@
+package db
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"gorm.io/gorm"
+)
+
+// CreateInviteCodes 批量创建邀请码
+func CreateInviteCodes(codes []model.InviteCode) error {
+	return db.CreateInBatches(codes, 100).Error
+}
+
+// GetInviteCodes 分页获取邀请码，createdBy 为 0 时返回所有人创建的邀请码
+func GetInviteCodes(createdBy uint, page, pageSize int) ([]model.InviteCode, int64, error) {
+	var codes []model.InviteCode
+	var total int64
+	query := db.Model(&model.InviteCode{})
+	if createdBy != 0 {
+		query = query.Where("created_by = ?", createdBy)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Preload("Creator").Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&codes).Error
+	return codes, total, err
+}
+
+// GetInviteCodeByCode 根据邀请码获取记录
+func GetInviteCodeByCode(code string) (*model.InviteCode, error) {
+	var inviteCode model.InviteCode
+	err := db.Where("code = ?", code).First(&inviteCode).Error
+	return &inviteCode, err
+}
+
+// CountInviteCodesByCreator 统计用户创建的邀请码数量
+func CountInviteCodesByCreator(userID uint) (int64, error) {
+	var count int64
+	err := db.Model(&model.InviteCode{}).Where("created_by = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// UseInviteCode 通过条件更新占用邀请码的一次使用次数，邀请码已停用、过期或用完时返回 false
+func UseInviteCode(id uint) (bool, error) {
+	result := db.Model(&model.InviteCode{}).
+		Where("id = ? AND enabled = ? AND used_count < max_uses", id, true).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Update("used_count", gorm.Expr("used_count + 1"))
+	return result.RowsAffected > 0, result.Error
+}
+
+// SetInviteCodeEnabled 启用或停用邀请码
+func SetInviteCodeEnabled(id uint, enabled bool) error {
+	return db.Model(&model.InviteCode{}).Where("id = ?", id).Update("enabled", enabled).Error
+}
+
+// CreateInvitation 记录邀请关系
+func CreateInvitation(invitation *model.Invitation) error {
+	return db.Create(invitation).Error
+}
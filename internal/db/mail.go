@@ -0,0 +1,47 @@
+package db
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+)
+
+// CreateMailDelivery 创建邮件发送记录
+func CreateMailDelivery(delivery *model.MailDelivery) error {
+	return db.Create(delivery).Error
+}
+
+// UpdateMailDelivery 更新邮件发送记录
+func UpdateMailDelivery(delivery *model.MailDelivery) error {
+	return db.Save(delivery).Error
+}
+
+// GetMailDelivery 根据ID获取邮件发送记录
+func GetMailDelivery(id uint) (*model.MailDelivery, error) {
+	var delivery model.MailDelivery
+	err := db.First(&delivery, id).Error
+	return &delivery, err
+}
+
+// GetMailDeliveries 分页获取邮件发送记录，status 为空时返回全部
+func GetMailDeliveries(status string, page, pageSize int) ([]model.MailDelivery, int64, error) {
+	var deliveries []model.MailDelivery
+	var total int64
+	query := db.Model(&model.MailDelivery{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&deliveries).Error
+	return deliveries, total, err
+}
+
+// GetDueMailDeliveries 获取到达重试时间的发送失败记录
+func GetDueMailDeliveries(now time.Time, limit int) ([]model.MailDelivery, error) {
+	var deliveries []model.MailDelivery
+	err := db.Where("status = ? AND next_retry_at <= ?", model.MailFailed, now).
+		Order("next_retry_at ASC").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
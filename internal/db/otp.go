@@ -0,0 +1,40 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// ReplaceOtpBackupCodes drops the backup codes of a user and stores new ones
+func ReplaceOtpBackupCodes(userID uint, codeHashes []string) error {
+	return errors.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.OtpBackupCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]model.OtpBackupCode, 0, len(codeHashes))
+		for _, hash := range codeHashes {
+			codes = append(codes, model.OtpBackupCode{UserID: userID, CodeHash: hash})
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	}))
+}
+
+// UseOtpBackupCode marks an unused backup code of a user as used, it reports
+// false if there is no such code
+func UseOtpBackupCode(userID uint, codeHash string) (bool, error) {
+	res := db.Model(&model.OtpBackupCode{}).
+		Where("user_id = ? AND code_hash = ? AND used = ?", userID, codeHash, false).
+		Update("used", true)
+	return res.RowsAffected == 1, errors.WithStack(res.Error)
+}
+
+// CountOtpBackupCodes returns the number of unused backup codes of a user
+func CountOtpBackupCodes(userID uint) (int64, error) {
+	var count int64
+	err := db.Model(&model.OtpBackupCode{}).Where("user_id = ? AND used = ?", userID, false).Count(&count).Error
+	return count, errors.WithStack(err)
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+)
+
+// GetPricingGroups 获取定价组列表
+func GetPricingGroups() ([]model.PricingGroup, error) {
+	var groups []model.PricingGroup
+	err := db.Order("id ASC").Find(&groups).Error
+	return groups, err
+}
+
+// GetPricingGroupByID 根据ID获取定价组
+func GetPricingGroupByID(id uint) (*model.PricingGroup, error) {
+	var group model.PricingGroup
+	err := db.First(&group, id).Error
+	return &group, err
+}
+
+// SavePricingGroup 创建或更新定价组
+func SavePricingGroup(group *model.PricingGroup) error {
+	return db.Save(group).Error
+}
+
+// DeletePricingGroup 删除定价组及其成员关系
+func DeletePricingGroup(id uint) error {
+	if err := db.Where("group_id = ?", id).Delete(&model.UserPricingGroup{}).Error; err != nil {
+		return err
+	}
+	return db.Delete(&model.PricingGroup{}, id).Error
+}
+
+// GetUserPricingGroup 获取用户所属的定价组
+func GetUserPricingGroup(userID uint) (*model.PricingGroup, error) {
+	var group model.PricingGroup
+	err := db.Joins("JOIN x_user_pricing_groups ON x_user_pricing_groups.group_id = x_pricing_groups.id").
+		Where("x_user_pricing_groups.user_id = ?", userID).First(&group).Error
+	return &group, err
+}
+
+// SetUserPricingGroup 设置用户所属的定价组
+func SetUserPricingGroup(userID, groupID uint) error {
+	return db.Save(&model.UserPricingGroup{UserID: userID, GroupID: groupID}).Error
+}
+
+// RemoveUserPricingGroup 将用户移出定价组
+func RemoveUserPricingGroup(userID uint) error {
+	return db.Where("user_id = ?", userID).Delete(&model.UserPricingGroup{}).Error
+}
@@ -0,0 +1,26 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+)
+
+// GetPricingRules 获取路径计价规则，按优先级排序
+func GetPricingRules(enabledOnly bool) ([]model.PricingRule, error) {
+	var rules []model.PricingRule
+	query := db.Order("priority ASC, id ASC")
+	if enabledOnly {
+		query = query.Where("enabled = ?", true)
+	}
+	err := query.Find(&rules).Error
+	return rules, err
+}
+
+// SavePricingRule 创建或更新路径计价规则
+func SavePricingRule(rule *model.PricingRule) error {
+	return db.Save(rule).Error
+}
+
+// DeletePricingRule 删除路径计价规则
+func DeletePricingRule(id uint) error {
+	return db.Delete(&model.PricingRule{}, id).Error
+}
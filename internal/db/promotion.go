@@ -0,0 +1,26 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+)
+
+// GetPromotions 获取促销活动
+func GetPromotions(enabledOnly bool) ([]model.Promotion, error) {
+	var promotions []model.Promotion
+	query := db.Order("id DESC")
+	if enabledOnly {
+		query = query.Where("enabled = ?", true)
+	}
+	err := query.Find(&promotions).Error
+	return promotions, err
+}
+
+// SavePromotion 创建或更新促销活动
+func SavePromotion(promotion *model.Promotion) error {
+	return db.Save(promotion).Error
+}
+
+// DeletePromotion 删除促销活动
+func DeletePromotion(id uint) error {
+	return db.Delete(&model.Promotion{}, id).Error
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// GetProvisioningTemplates 获取所有用户模板
+func GetProvisioningTemplates() ([]model.ProvisioningTemplate, error) {
+	var templates []model.ProvisioningTemplate
+	err := db.Order("id ASC").Find(&templates).Error
+	return templates, errors.WithStack(err)
+}
+
+// GetProvisioningTemplateByID 根据ID获取用户模板
+func GetProvisioningTemplateByID(id uint) (*model.ProvisioningTemplate, error) {
+	var template model.ProvisioningTemplate
+	if err := db.First(&template, id).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &template, nil
+}
+
+// GetDefaultProvisioningTemplate 获取默认的用户模板
+func GetDefaultProvisioningTemplate() (*model.ProvisioningTemplate, error) {
+	var template model.ProvisioningTemplate
+	if err := db.Where("is_default = ?", true).First(&template).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &template, nil
+}
+
+// SaveProvisioningTemplate 创建或更新用户模板，模板设为默认时取消其他模板的默认状态
+func SaveProvisioningTemplate(template *model.ProvisioningTemplate) error {
+	return errors.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(template).Error; err != nil {
+			return err
+		}
+		if !template.IsDefault {
+			return nil
+		}
+		return tx.Model(&model.ProvisioningTemplate{}).
+			Where("id <> ? AND is_default = ?", template.ID, true).
+			Update("is_default", false).Error
+	}))
+}
+
+// DeleteProvisioningTemplate 删除用户模板
+func DeleteProvisioningTemplate(id uint) error {
+	return errors.WithStack(db.Delete(&model.ProvisioningTemplate{}, id).Error)
+}
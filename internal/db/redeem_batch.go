@@ -0,0 +1,233 @@
+package db
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"gorm.io/gorm"
+)
+
+// CreateRedeemBatch 在同一个事务中创建兑换码批次及其兑换码
+func CreateRedeemBatch(batch *model.RedeemBatch, codes []model.RedeemCode) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(batch).Error; err != nil {
+			return err
+		}
+		return tx.CreateInBatches(codes, 100).Error
+	})
+}
+
+// GetRedeemBatches 分页获取兑换码批次，按创建时间倒序
+func GetRedeemBatches(page, pageSize int) ([]model.RedeemBatch, int64, error) {
+	var batches []model.RedeemBatch
+	var total int64
+	query := db.Model(&model.RedeemBatch{})
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&batches).Error
+	return batches, total, err
+}
+
+// GetRedeemBatch 根据批次号获取兑换码批次
+func GetRedeemBatch(batchNo string) (*model.RedeemBatch, error) {
+	var batch model.RedeemBatch
+	err := db.Where("batch_no = ?", batchNo).First(&batch).Error
+	return &batch, err
+}
+
+// GetRedeemCodesByBatch 获取批次中的所有兑换码
+func GetRedeemCodesByBatch(batchNo string) ([]model.RedeemCode, error) {
+	var codes []model.RedeemCode
+	err := db.Where("batch_no = ?", batchNo).Order("id").Find(&codes).Error
+	return codes, err
+}
+
+// GetExistingRedeemCodes 获取 codes 中已存在的兑换码，包括已删除的
+func GetExistingRedeemCodes(codes []string) ([]string, error) {
+	var existing []string
+	err := db.Unscoped().Model(&model.RedeemCode{}).Where("code IN ?", codes).Pluck("code", &existing).Error
+	return existing, err
+}
+
+// GetRedeemCampaigns 获取全部兑换码活动
+func GetRedeemCampaigns() ([]model.RedeemCampaign, error) {
+	var campaigns []model.RedeemCampaign
+	err := db.Order("id DESC").Find(&campaigns).Error
+	return campaigns, err
+}
+
+// GetRedeemCampaign 根据ID获取兑换码活动
+func GetRedeemCampaign(id uint) (*model.RedeemCampaign, error) {
+	var campaign model.RedeemCampaign
+	err := db.First(&campaign, id).Error
+	return &campaign, err
+}
+
+// SaveRedeemCampaign 创建或更新兑换码活动
+func SaveRedeemCampaign(campaign *model.RedeemCampaign) error {
+	return db.Save(campaign).Error
+}
+
+// DeleteRedeemCampaign 删除兑换码活动
+func DeleteRedeemCampaign(id uint) error {
+	return db.Delete(&model.RedeemCampaign{}, id).Error
+}
+
+// CountRedeemCampaignBatches 统计活动下的批次数
+func CountRedeemCampaignBatches(campaignID uint) (int64, error) {
+	var count int64
+	err := db.Model(&model.RedeemBatch{}).Where("campaign_id = ?", campaignID).Count(&count).Error
+	return count, err
+}
+
+// GetRedeemCampaignCommitted 获取活动下所有兑换码用完时发放的积分总数
+func GetRedeemCampaignCommitted(campaignID uint) (int64, error) {
+	var committed int64
+	err := db.Model(&model.RedeemBatch{}).Where("campaign_id = ?", campaignID).
+		Select("COALESCE(SUM(count * max_uses * credits), 0)").Scan(&committed).Error
+	return committed, err
+}
+
+// GetRedeemCampaignStats 统计活动下所有批次的兑换码数量、兑换次数、已发放积分和兑换用户数
+func GetRedeemCampaignStats(campaignID uint) (*model.RedeemCampaignStats, error) {
+	var batchStats struct{ Batches, Committed int64 }
+	var codeStats struct{ Codes, Capacity, Used int64 }
+	var usageStats struct{ CreditsIssued, UniqueUsers int64 }
+	batches := db.Model(&model.RedeemBatch{}).Select("batch_no").Where("campaign_id = ?", campaignID)
+	codes := db.Model(&model.RedeemCode{}).Select("id").Where("batch_no IN (?)", batches)
+	err := db.Model(&model.RedeemBatch{}).Where("campaign_id = ?", campaignID).
+		Select("COUNT(*) AS batches, COALESCE(SUM(count * max_uses * credits), 0) AS committed").
+		Scan(&batchStats).Error
+	if err != nil {
+		return nil, err
+	}
+	err = db.Model(&model.RedeemCode{}).Where("batch_no IN (?)", batches).
+		Select("COUNT(*) AS codes, COALESCE(SUM(max_uses), 0) AS capacity, COALESCE(SUM(used_count), 0) AS used").
+		Scan(&codeStats).Error
+	if err != nil {
+		return nil, err
+	}
+	err = db.Model(&model.RedeemCodeUsage{}).Where("redeem_code_id IN (?)", codes).
+		Select("COALESCE(SUM(credits), 0) AS credits_issued, COUNT(DISTINCT user_id) AS unique_users").
+		Scan(&usageStats).Error
+	if err != nil {
+		return nil, err
+	}
+	return &model.RedeemCampaignStats{
+		CampaignID:    campaignID,
+		Batches:       batchStats.Batches,
+		Committed:     batchStats.Committed,
+		Codes:         codeStats.Codes,
+		Capacity:      codeStats.Capacity,
+		Used:          codeStats.Used,
+		CreditsIssued: usageStats.CreditsIssued,
+		UniqueUsers:   usageStats.UniqueUsers,
+	}, nil
+}
+
+// filterRedeemCodes 按筛选条件构造兑换码查询
+func filterRedeemCodes(query *gorm.DB, filter model.RedeemCodeFilter) *gorm.DB {
+	if filter.Code != "" {
+		query = query.Where("code = ?", filter.Code)
+	}
+	if filter.BatchNo != "" {
+		query = query.Where("batch_no = ?", filter.BatchNo)
+	}
+	if filter.CampaignID != 0 {
+		query = query.Where("batch_no IN (?)",
+			db.Model(&model.RedeemBatch{}).Select("batch_no").Where("campaign_id = ?", filter.CampaignID))
+	}
+	if filter.Start != nil {
+		query = query.Where("created_at >= ?", *filter.Start)
+	}
+	if filter.End != nil {
+		query = query.Where("created_at < ?", *filter.End)
+	}
+	if filter.Unused {
+		query = query.Where("used_count = 0")
+	}
+	return query
+}
+
+// RevokeRedeemCodes 在同一个事务中停用符合条件的兑换码并写入作废记录，revocation.Count 为实际停用的数量
+func RevokeRedeemCodes(filter model.RedeemCodeFilter, revocation *model.RedeemCodeRevocation) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := filterRedeemCodes(tx.Model(&model.RedeemCode{}), filter).
+			Where("enabled = ?", true).Update("enabled", false)
+		if result.Error != nil {
+			return result.Error
+		}
+		revocation.Count = result.RowsAffected
+		return tx.Create(revocation).Error
+	})
+}
+
+// GetRedeemCodeRevocations 分页获取兑换码作废记录
+func GetRedeemCodeRevocations(page, pageSize int) ([]model.RedeemCodeRevocation, int64, error) {
+	var revocations []model.RedeemCodeRevocation
+	var total int64
+	query := db.Model(&model.RedeemCodeRevocation{})
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&revocations).Error
+	return revocations, total, err
+}
+
+// RedeemCodeReward 兑换时在同一个事务中发放的奖励，按兑换码的奖励类型只设置其中一项
+type RedeemCodeReward struct {
+	Transaction *model.CreditTransaction // 积分
+	VipDays     int                      // 会员天数
+	Download    *model.DownloadPurchase  // 解锁的文件
+}
+
+// RedeemCode 在同一个事务中占用兑换码的一次使用次数、写入使用记录并发放奖励。
+// 使用次数通过 used_count < max_uses 的条件更新占用，兑换码已停用、过期或用完时返回 errs.RedeemCodeUnavailable；
+// 条件更新会锁住兑换码行，同一兑换码的兑换串行执行，因此之后统计的用户使用次数是准确的，
+// 超过 MaxUsesPerUser 时返回 errs.RedeemCodeUserLimit
+func RedeemCode(redeemCode *model.RedeemCode, usage *model.RedeemCodeUsage, reward RedeemCodeReward) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.RedeemCode{}).
+			Where("id = ? AND enabled = ? AND used_count < max_uses", redeemCode.ID, true).
+			Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+			Update("used_count", gorm.Expr("used_count + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errs.RedeemCodeUnavailable
+		}
+		if redeemCode.MaxUsesPerUser > 0 {
+			var used int64
+			err := tx.Model(&model.RedeemCodeUsage{}).
+				Where("redeem_code_id = ? AND user_id = ?", redeemCode.ID, usage.UserID).Count(&used).Error
+			if err != nil {
+				return err
+			}
+			if used >= int64(redeemCode.MaxUsesPerUser) {
+				return errs.RedeemCodeUserLimit
+			}
+		}
+		if err := tx.Create(usage).Error; err != nil {
+			return err
+		}
+		switch {
+		case reward.Transaction != nil:
+			credits, err := lockUserCredits(tx, usage.UserID)
+			if err != nil {
+				return err
+			}
+			return applyCreditChange(tx, credits, reward.Transaction)
+		case reward.VipDays > 0:
+			_, err := extendUserVipTx(tx, usage.UserID, func(start time.Time) time.Time {
+				return start.AddDate(0, 0, reward.VipDays)
+			})
+			return err
+		case reward.Download != nil:
+			return unlockDownload(tx, reward.Download)
+		}
+		return nil
+	})
+}
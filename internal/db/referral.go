@@ -0,0 +1,102 @@
+package db
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"gorm.io/gorm"
+)
+
+// CreateReferralCode 创建推荐码
+func CreateReferralCode(code *model.ReferralCode) error {
+	return db.Create(code).Error
+}
+
+// GetReferralCodeByUserID 获取用户的推荐码
+func GetReferralCodeByUserID(userID uint) (*model.ReferralCode, error) {
+	var code model.ReferralCode
+	err := db.Where("user_id = ?", userID).First(&code).Error
+	return &code, err
+}
+
+// GetReferralCodeByCode 根据推荐码获取记录
+func GetReferralCodeByCode(code string) (*model.ReferralCode, error) {
+	var referralCode model.ReferralCode
+	err := db.Where("code = ?", code).First(&referralCode).Error
+	return &referralCode, err
+}
+
+// CreateReferral 创建推荐关系
+func CreateReferral(referral *model.Referral) error {
+	return db.Create(referral).Error
+}
+
+// GetReferralByRefereeID 获取被推荐用户的推荐关系
+func GetReferralByRefereeID(refereeID uint) (*model.Referral, error) {
+	var referral model.Referral
+	err := db.Where("referee_id = ?", refereeID).First(&referral).Error
+	return &referral, err
+}
+
+// MarkReferralFirstPurchase 记录被推荐用户的首次购买时间，已记录过时返回 false
+func MarkReferralFirstPurchase(referralID uint, at time.Time) (bool, error) {
+	result := db.Model(&model.Referral{}).
+		Where("id = ? AND first_purchase_at IS NULL", referralID).
+		Update("first_purchase_at", at)
+	return result.RowsAffected > 0, result.Error
+}
+
+// AddReferralRewards 累加推荐奖励积分
+func AddReferralRewards(referralID uint, referrerCredits, refereeCredits int64) error {
+	return db.Model(&model.Referral{}).Where("id = ?", referralID).Updates(map[string]interface{}{
+		"referrer_credits": gorm.Expr("referrer_credits + ?", referrerCredits),
+		"referee_credits":  gorm.Expr("referee_credits + ?", refereeCredits),
+	}).Error
+}
+
+// GetReferralsByReferrerID 获取用户推荐的用户列表，只返回被推荐用户的用户名和注册时间
+func GetReferralsByReferrerID(referrerID uint, page, pageSize int) ([]model.ReferralItem, int64, error) {
+	var referrals []model.Referral
+	var total int64
+
+	query := db.Model(&model.Referral{}).Where("referrer_id = ?", referrerID)
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err = query.Select("referee_id", "created_at").Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&referrals).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]uint, 0, len(referrals))
+	for _, r := range referrals {
+		ids = append(ids, r.RefereeID)
+	}
+	var users []model.User
+	if len(ids) > 0 {
+		if err = db.Select("id", "username").Where("id IN ?", ids).Find(&users).Error; err != nil {
+			return nil, 0, err
+		}
+	}
+	usernames := make(map[uint]string, len(users))
+	for _, u := range users {
+		usernames[u.ID] = u.Username
+	}
+	items := make([]model.ReferralItem, 0, len(referrals))
+	for _, r := range referrals {
+		items = append(items, model.ReferralItem{Username: usernames[r.RefereeID], CreatedAt: r.CreatedAt})
+	}
+	return items, total, nil
+}
+
+// GetReferralStats 统计用户的推荐数据
+func GetReferralStats(referrerID uint) (*model.ReferralStats, error) {
+	var stats model.ReferralStats
+	err := db.Model(&model.Referral{}).Where("referrer_id = ?", referrerID).
+		Select("COUNT(*) AS invited, COUNT(first_purchase_at) AS purchased, COALESCE(SUM(referrer_credits), 0) AS credits_earned").
+		Scan(&stats).Error
+	return &stats, err
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"gorm.io/gorm"
+)
+
+// GetRewardSources 获取外部奖励来源
+func GetRewardSources() ([]model.RewardSource, error) {
+	var sources []model.RewardSource
+	err := db.Order("id ASC").Find(&sources).Error
+	return sources, err
+}
+
+// GetRewardSourceByName 根据名称获取外部奖励来源
+func GetRewardSourceByName(name string) (*model.RewardSource, error) {
+	var source model.RewardSource
+	err := db.Where("name = ?", name).First(&source).Error
+	return &source, err
+}
+
+// SaveRewardSource 创建或更新外部奖励来源
+func SaveRewardSource(source *model.RewardSource) error {
+	return db.Save(source).Error
+}
+
+// DeleteRewardSource 删除外部奖励来源
+func DeleteRewardSource(id uint) error {
+	return db.Delete(&model.RewardSource{}, id).Error
+}
+
+// GrantExternalReward 在同一个事务中检查重放和每日上限、记录外部奖励并为用户增加积分。
+// 外部交易ID或随机数已使用过时返回 errs.RewardReplayed，超出上限时返回 errs.RewardCapExceeded
+func GrantExternalReward(source *model.RewardSource, reward *model.ExternalReward, transaction *model.CreditTransaction, since time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		credits, err := lockUserCredits(tx, reward.UserID)
+		if err != nil {
+			return err
+		}
+		var used int64
+		err = tx.Model(&model.ExternalReward{}).
+			Where("source_id = ? AND (external_id = ? OR nonce = ?)", source.ID, reward.ExternalID, reward.Nonce).
+			Count(&used).Error
+		if err != nil {
+			return err
+		}
+		if used > 0 {
+			return errs.RewardReplayed
+		}
+		if source.UserDailyCap > 0 {
+			if err = checkExternalRewardCap(tx, source.UserDailyCap, reward.Amount, since,
+				"source_id = ? AND user_id = ?", source.ID, reward.UserID); err != nil {
+				return err
+			}
+		}
+		if source.DailyCap > 0 {
+			if err = checkExternalRewardCap(tx, source.DailyCap, reward.Amount, since, "source_id = ?", source.ID); err != nil {
+				return err
+			}
+		}
+		if err = tx.Create(reward).Error; err != nil {
+			return err
+		}
+		return applyCreditChange(tx, credits, transaction)
+	})
+}
+
+// checkExternalRewardCap 检查自 since 起已发放的积分加上本次是否超过上限
+func checkExternalRewardCap(tx *gorm.DB, limit, amount int64, since time.Time, query string, args ...any) error {
+	var granted int64
+	err := tx.Model(&model.ExternalReward{}).Where(query, args...).Where("created_at >= ?", since).
+		Select("COALESCE(SUM(amount), 0)").Scan(&granted).Error
+	if err != nil {
+		return err
+	}
+	if granted+amount > limit {
+		return errs.RewardCapExceeded
+	}
+	return nil
+}
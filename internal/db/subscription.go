@@ -0,0 +1,76 @@
+package db
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetSubscriptionPlans 获取会员套餐列表
+func GetSubscriptionPlans(enabledOnly bool) ([]model.SubscriptionPlan, error) {
+	var plans []model.SubscriptionPlan
+	query := db.Model(&model.SubscriptionPlan{})
+	if enabledOnly {
+		query = query.Where("enabled = ?", true)
+	}
+	err := query.Order("months ASC").Find(&plans).Error
+	return plans, err
+}
+
+// GetSubscriptionPlanByID 根据ID获取会员套餐
+func GetSubscriptionPlanByID(id uint) (*model.SubscriptionPlan, error) {
+	var plan model.SubscriptionPlan
+	err := db.First(&plan, id).Error
+	return &plan, err
+}
+
+// SaveSubscriptionPlan 创建或更新会员套餐
+func SaveSubscriptionPlan(plan *model.SubscriptionPlan) error {
+	return db.Save(plan).Error
+}
+
+// DeleteSubscriptionPlan 删除会员套餐
+func DeleteSubscriptionPlan(id uint) error {
+	return db.Delete(&model.SubscriptionPlan{}, id).Error
+}
+
+// ExtendUserVip 延长用户的会员有效期，未过期时在原到期时间上叠加，返回新的到期时间
+func ExtendUserVip(userID uint, months int) (time.Time, error) {
+	return extendUserVip(userID, func(start time.Time) time.Time {
+		return start.AddDate(0, months, 0)
+	})
+}
+
+// ExtendUserVipDays 按天延长用户的会员有效期，规则与 ExtendUserVip 相同
+func ExtendUserVipDays(userID uint, days int) (time.Time, error) {
+	return extendUserVip(userID, func(start time.Time) time.Time {
+		return start.AddDate(0, 0, days)
+	})
+}
+
+func extendUserVip(userID uint, extend func(start time.Time) time.Time) (time.Time, error) {
+	var expiresAt time.Time
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		expiresAt, err = extendUserVipTx(tx, userID, extend)
+		return err
+	})
+	return expiresAt, err
+}
+
+// extendUserVipTx 在事务内加锁读取用户并延长会员有效期
+func extendUserVipTx(tx *gorm.DB, userID uint, extend func(start time.Time) time.Time) (time.Time, error) {
+	var user model.User
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error
+	if err != nil {
+		return time.Time{}, err
+	}
+	start := time.Now()
+	if user.VipExpiresAt != nil && user.VipExpiresAt.After(start) {
+		start = *user.VipExpiresAt
+	}
+	expiresAt := extend(start)
+	return expiresAt, tx.Model(&model.User{}).Where("id = ?", userID).Update("vip_expires_at", expiresAt).Error
+}
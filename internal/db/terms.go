@@ -0,0 +1,83 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+// CreateTermsDocument 发布一个条款版本
+func CreateTermsDocument(doc *model.TermsDocument) error {
+	return errors.WithStack(db.Create(doc).Error)
+}
+
+// GetLatestTermsDocument 获取某类条款最新发布的版本
+func GetLatestTermsDocument(kind string) (*model.TermsDocument, error) {
+	var doc model.TermsDocument
+	if err := db.Where("kind = ?", kind).Order("id DESC").First(&doc).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &doc, nil
+}
+
+// GetTermsDocumentByVersion 根据类型和版本号获取条款
+func GetTermsDocumentByVersion(kind, version string) (*model.TermsDocument, error) {
+	var doc model.TermsDocument
+	if err := db.Where("kind = ? AND version = ?", kind, version).First(&doc).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &doc, nil
+}
+
+// GetTermsDocuments 分页获取条款的所有版本，kind 为空时返回全部类型
+func GetTermsDocuments(kind string, page, pageSize int) ([]model.TermsDocument, int64, error) {
+	var docs []model.TermsDocument
+	var total int64
+	query := db.Model(&model.TermsDocument{})
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, errors.WithStack(err)
+	}
+	err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&docs).Error
+	return docs, total, errors.WithStack(err)
+}
+
+// CreateTermsAcceptances 保存同意条款的记录
+func CreateTermsAcceptances(acceptances []model.TermsAcceptance) error {
+	if len(acceptances) == 0 {
+		return nil
+	}
+	return errors.WithStack(db.Create(&acceptances).Error)
+}
+
+// HasAcceptedTerms 检查用户是否同意过某类条款的指定版本
+func HasAcceptedTerms(userID uint, kind, version string) (bool, error) {
+	var count int64
+	err := db.Model(&model.TermsAcceptance{}).
+		Where("user_id = ? AND kind = ? AND version = ?", userID, kind, version).
+		Count(&count).Error
+	return count > 0, errors.WithStack(err)
+}
+
+// BindRegistrationTermsAcceptances 将注册时同意条款的记录关联到注册申请创建的用户
+func BindRegistrationTermsAcceptances(registrationID, userID uint) error {
+	return errors.WithStack(db.Model(&model.TermsAcceptance{}).
+		Where("registration_id = ? AND user_id = 0", registrationID).
+		Update("user_id", userID).Error)
+}
+
+// GetTermsAcceptances 分页获取用户同意条款的记录，userID 为 0 时返回全部
+func GetTermsAcceptances(userID uint, page, pageSize int) ([]model.TermsAcceptance, int64, error) {
+	var acceptances []model.TermsAcceptance
+	var total int64
+	query := db.Model(&model.TermsAcceptance{})
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, errors.WithStack(err)
+	}
+	err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&acceptances).Error
+	return acceptances, total, errors.WithStack(err)
+}
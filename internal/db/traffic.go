@@ -0,0 +1,52 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AddTraffic 累加用户的代理流量
+func AddTraffic(userID uint, bytes int64) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"total_bytes": gorm.Expr("total_bytes + ?", bytes)}),
+	}).Create(&model.TrafficAccount{UserID: userID, TotalBytes: bytes}).Error
+}
+
+// GetTrafficAccount 获取用户的流量账户
+func GetTrafficAccount(userID uint) (*model.TrafficAccount, error) {
+	var account model.TrafficAccount
+	err := db.Where("user_id = ?", userID).First(&account).Error
+	return &account, err
+}
+
+// GetUnbilledTrafficAccounts 获取未计费流量不少于 minBytes 的流量账户
+func GetUnbilledTrafficAccounts(minBytes int64) ([]model.TrafficAccount, error) {
+	var accounts []model.TrafficAccount
+	err := db.Where("total_bytes - billed_bytes >= ?", minBytes).Find(&accounts).Error
+	return accounts, err
+}
+
+// BillTraffic 在同一个事务中为 bytes 字节的流量扣除积分并记为已计费
+func BillTraffic(userID uint, bytes int64, transaction *model.CreditTransaction) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var account model.TrafficAccount
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&account).Error
+		if err != nil {
+			return err
+		}
+		if account.Unbilled() < bytes {
+			return nil
+		}
+		credits, err := lockUserCredits(tx, userID)
+		if err != nil {
+			return err
+		}
+		if err = applyCreditChange(tx, credits, transaction); err != nil {
+			return err
+		}
+		return tx.Model(&model.TrafficAccount{}).Where("user_id = ?", userID).
+			Update("billed_bytes", gorm.Expr("billed_bytes + ?", bytes)).Error
+	})
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"gorm.io/gorm"
+)
+
+// GetUploadEarnRules 获取所有上传奖励规则
+func GetUploadEarnRules() ([]model.UploadEarnRule, error) {
+	var rules []model.UploadEarnRule
+	err := db.Order("path ASC").Find(&rules).Error
+	return rules, err
+}
+
+// GetEnabledUploadEarnRules 获取启用的上传奖励规则
+func GetEnabledUploadEarnRules() ([]model.UploadEarnRule, error) {
+	var rules []model.UploadEarnRule
+	err := db.Where("enabled = ?", true).Find(&rules).Error
+	return rules, err
+}
+
+// SaveUploadEarnRule 创建或更新上传奖励规则
+func SaveUploadEarnRule(rule *model.UploadEarnRule) error {
+	return db.Save(rule).Error
+}
+
+// DeleteUploadEarnRule 删除上传奖励规则
+func DeleteUploadEarnRule(id uint) error {
+	return db.Delete(&model.UploadEarnRule{}, id).Error
+}
+
+// CreateUploadReward 记录上传奖励并发放积分，内容或路径已奖励过时返回 false
+func CreateUploadReward(reward *model.UploadReward, transaction *model.CreditTransaction) (bool, error) {
+	created := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&model.UploadReward{}).
+			Where("content_key = ? OR path = ?", reward.ContentKey, reward.Path).
+			Count(&count).Error
+		if err != nil || count > 0 {
+			return err
+		}
+		if err = tx.Create(reward).Error; err != nil {
+			return err
+		}
+		credits, err := lockUserCredits(tx, reward.UserID)
+		if err != nil {
+			return err
+		}
+		if err = applyCreditChange(tx, credits, transaction); err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	return created, err
+}
@@ -1,11 +1,7 @@
 package db
 
 import (
-	"encoding/base64"
-
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
-	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
-	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/pkg/errors"
 )
 
@@ -17,6 +13,13 @@ func GetUserByRole(role int) (*model.User, error) {
 	return &user, nil
 }
 
+// CountUsersByRole 统计指定角色的用户数
+func CountUsersByRole(role int) (int64, error) {
+	var count int64
+	err := db.Model(&model.User{}).Where("role = ?", role).Count(&count).Error
+	return count, errors.WithStack(err)
+}
+
 func GetUserByName(username string) (*model.User, error) {
 	user := model.User{Username: username}
 	if err := db.Where(user).First(&user).Error; err != nil {
@@ -25,6 +28,26 @@ func GetUserByName(username string) (*model.User, error) {
 	return &user, nil
 }
 
+// UsernameTaken 检查用户名是否已被 excludeID 以外的用户或尚未完成的注册申请使用，
+// caseInsensitive 为 true 时忽略大小写
+func UsernameTaken(username string, excludeID uint, caseInsensitive bool) (bool, error) {
+	cond := "username = ?"
+	if caseInsensitive {
+		cond = "LOWER(username) = LOWER(?)"
+	}
+	var count int64
+	if err := db.Model(&model.User{}).Where(cond, username).Where("id <> ?", excludeID).Count(&count).Error; err != nil {
+		return false, errors.WithStack(err)
+	}
+	if count > 0 {
+		return true, nil
+	}
+	err := db.Model(&model.UserRegistration{}).Where(cond, username).
+		Where("status IN ?", []int{model.RegistrationPending, model.RegistrationVerified}).
+		Count(&count).Error
+	return count > 0, errors.WithStack(err)
+}
+
 func GetUserBySSOID(ssoID string) (*model.User, error) {
 	user := model.User{SsoID: ssoID}
 	if err := db.Where(user).First(&user).Error; err != nil {
@@ -33,6 +56,13 @@ func GetUserBySSOID(ssoID string) (*model.User, error) {
 	return &user, nil
 }
 
+// GetUsersWithSSOID 获取绑定了旧的 sso_id 的用户
+func GetUsersWithSSOID() ([]model.User, error) {
+	var users []model.User
+	err := db.Where("sso_id <> ''").Find(&users).Error
+	return users, errors.WithStack(err)
+}
+
 func GetUserById(id uint) (*model.User, error) {
 	var u model.User
 	if err := db.First(&u, id).Error; err != nil {
@@ -63,40 +93,3 @@ func GetUsers(pageIndex, pageSize int) (users []model.User, count int64, err err
 func DeleteUserById(id uint) error {
 	return errors.WithStack(db.Delete(&model.User{}, id).Error)
 }
-
-func UpdateAuthn(userID uint, authn string) error {
-	return db.Model(&model.User{ID: userID}).Update("authn", authn).Error
-}
-
-func RegisterAuthn(u *model.User, credential *webauthn.Credential) error {
-	if u == nil {
-		return errors.New("user is nil")
-	}
-	exists := u.WebAuthnCredentials()
-	if credential != nil {
-		exists = append(exists, *credential)
-	}
-	res, err := utils.Json.Marshal(exists)
-	if err != nil {
-		return err
-	}
-	return UpdateAuthn(u.ID, string(res))
-}
-
-func RemoveAuthn(u *model.User, id string) error {
-	exists := u.WebAuthnCredentials()
-	for i := 0; i < len(exists); i++ {
-		idEncoded := base64.StdEncoding.EncodeToString(exists[i].ID)
-		if idEncoded == id {
-			exists[len(exists)-1], exists[i] = exists[i], exists[len(exists)-1]
-			exists = exists[:len(exists)-1]
-			break
-		}
-	}
-
-	res, err := utils.Json.Marshal(exists)
-	if err != nil {
-		return err
-	}
-	return UpdateAuthn(u.ID, string(res))
-}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+// CreateUserIdentity 创建外部身份关联
+func CreateUserIdentity(identity *model.UserIdentity) error {
+	return errors.WithStack(db.Create(identity).Error)
+}
+
+// GetUserIdentity 根据提供方和身份标识获取关联
+func GetUserIdentity(provider, subject string) (*model.UserIdentity, error) {
+	var identity model.UserIdentity
+	if err := db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &identity, nil
+}
+
+// GetUserIdentities 获取用户关联的所有外部身份
+func GetUserIdentities(userID uint) ([]model.UserIdentity, error) {
+	var identities []model.UserIdentity
+	err := db.Where("user_id = ?", userID).Order("id").Find(&identities).Error
+	return identities, errors.WithStack(err)
+}
+
+// GetIdentityUserIDsByEmail 获取外部身份邮箱为 email 的用户
+func GetIdentityUserIDsByEmail(email string) ([]uint, error) {
+	var userIDs []uint
+	err := db.Model(&model.UserIdentity{}).Where("email = ?", email).Distinct().Pluck("user_id", &userIDs).Error
+	return userIDs, errors.WithStack(err)
+}
+
+// DeleteUserIdentity 删除用户的一个外部身份关联，返回是否删除了记录
+func DeleteUserIdentity(userID, id uint) (bool, error) {
+	result := db.Where("id = ? AND user_id = ?", id, userID).Delete(&model.UserIdentity{})
+	return result.RowsAffected > 0, errors.WithStack(result.Error)
+}
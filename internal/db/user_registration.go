@@ -1,9 +1,11 @@
 package db
 
 import (
+	"strings"
 	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"gorm.io/gorm"
 )
 
 // CreateUserRegistration 创建用户注册记录
@@ -18,10 +20,32 @@ func GetUserRegistrationByToken(token string) (*model.UserRegistration, error) {
 	return &registration, err
 }
 
-// GetUserRegistrationByEmail 根据邮箱获取注册记录
+// GetUserRegistrationByID 根据 ID 获取注册记录
+func GetUserRegistrationByID(id uint) (*model.UserRegistration, error) {
+	var registration model.UserRegistration
+	err := db.First(&registration, id).Error
+	return &registration, err
+}
+
+// TransitionUserRegistration 仅当注册记录仍处于 from 状态时保存其新状态和审核信息，
+// 返回 false 表示状态已被其他请求修改
+func TransitionUserRegistration(registration *model.UserRegistration, from int) (bool, error) {
+	res := db.Model(&model.UserRegistration{}).
+		Where("id = ? AND status = ?", registration.ID, from).
+		Updates(map[string]interface{}{
+			"status":      registration.Status,
+			"reviewed_by": registration.ReviewedBy,
+			"reviewed_at": registration.ReviewedAt,
+			"verified_at": registration.VerifiedAt,
+			"updated_at":  time.Now(),
+		})
+	return res.RowsAffected == 1, res.Error
+}
+
+// GetUserRegistrationByEmail 根据邮箱获取注册记录，不区分大小写
 func GetUserRegistrationByEmail(email string) (*model.UserRegistration, error) {
 	var registration model.UserRegistration
-	err := db.Where("email = ?", email).First(&registration).Error
+	err := db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&registration).Error
 	return &registration, err
 }
 
@@ -60,6 +84,44 @@ func GetVerificationCode(email, codeType string) (*model.VerificationCode, error
 	return &code, err
 }
 
+// GetActiveVerificationCodes 获取邮箱未使用且未过期的验证码，最新的在前
+func GetActiveVerificationCodes(email, codeType string) ([]model.VerificationCode, error) {
+	var codes []model.VerificationCode
+	err := db.Where("email = ? AND type = ? AND used = false AND expires_at > ?", email, codeType, time.Now()).
+		Order("created_at DESC, id DESC").Find(&codes).Error
+	return codes, err
+}
+
+// InvalidateVerificationCodes 将邮箱较早的有效验证码标记为已使用，只保留最新的 keep 个
+func InvalidateVerificationCodes(email, codeType string, keep int) error {
+	codes, err := GetActiveVerificationCodes(email, codeType)
+	if err != nil || len(codes) <= keep {
+		return err
+	}
+	ids := make([]uint, 0, len(codes)-keep)
+	for _, code := range codes[keep:] {
+		ids = append(ids, code.ID)
+	}
+	return db.Model(&model.VerificationCode{}).Where("id IN ?", ids).Update("used", true).Error
+}
+
+// RecordVerificationCodeFailure 为邮箱的有效验证码记录一次校验失败，失败次数达到 maxAttempts 的验证码标记为已使用，
+// 返回是否有验证码因此被锁定；maxAttempts 为 0 时只记录次数
+func RecordVerificationCodeFailure(email, codeType string, maxAttempts int) (bool, error) {
+	active := db.Model(&model.VerificationCode{}).
+		Where("email = ? AND type = ? AND used = false AND expires_at > ?", email, codeType, time.Now())
+	if err := active.Update("attempts", gorm.Expr("attempts + 1")).Error; err != nil {
+		return false, err
+	}
+	if maxAttempts <= 0 {
+		return false, nil
+	}
+	result := db.Model(&model.VerificationCode{}).
+		Where("email = ? AND type = ? AND used = false AND attempts >= ?", email, codeType, maxAttempts).
+		Update("used", true)
+	return result.RowsAffected > 0, result.Error
+}
+
 // UpdateVerificationCode 更新验证码记录
 func UpdateVerificationCode(code *model.VerificationCode) error {
 	return db.Save(code).Error
@@ -70,12 +132,12 @@ func CleanExpiredVerificationCodes() error {
 	return db.Where("expires_at < ?", time.Now()).Delete(&model.VerificationCode{}).Error
 }
 
-// GetPendingRegistrations 获取待处理的注册申请
+// GetPendingRegistrations 获取已验证邮箱、等待管理员审核的注册申请
 func GetPendingRegistrations(page, pageSize int) ([]model.UserRegistration, int64, error) {
 	var registrations []model.UserRegistration
 	var total int64
 	
-	query := db.Model(&model.UserRegistration{}).Where("status = 0")
+	query := db.Model(&model.UserRegistration{}).Where("status = ?", model.RegistrationVerified)
 	err := query.Count(&total).Error
 	if err != nil {
 		return nil, 0, err
@@ -84,4 +146,59 @@ func GetPendingRegistrations(page, pageSize int) ([]model.UserRegistration, int6
 	offset := (page - 1) * pageSize
 	err = query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&registrations).Error
 	return registrations, total, err
-}
\ No newline at end of file
+}
+
+// GetRegistrationsBySource 获取 since 之后从同一来源网段提交的注册申请
+func GetRegistrationsBySource(source string, since time.Time) ([]model.UserRegistration, error) {
+	var registrations []model.UserRegistration
+	err := db.Where("source = ? AND created_at >= ?", source, since).Order("created_at").Find(&registrations).Error
+	return registrations, err
+}
+
+// CountApprovedRegistrationsBySource 统计 since 之后批准的来自同一来源网段的注册申请数
+func CountApprovedRegistrationsBySource(source string, since time.Time) (int64, error) {
+	var count int64
+	err := db.Model(&model.UserRegistration{}).
+		Where("source = ? AND status = ? AND reviewed_at >= ?", source, model.RegistrationRegistered, since).
+		Count(&count).Error
+	return count, err
+}
+
+// GetRegistrationsToRemind 获取令牌在 before 之前过期、尚未发送过提醒邮件的待验证注册申请，第三方登录发起的申请无需验证邮箱
+func GetRegistrationsToRemind(before time.Time) ([]model.UserRegistration, error) {
+	var registrations []model.UserRegistration
+	err := db.Where("status = ? AND sso_id = '' AND reminded_at IS NULL AND expires_at > ? AND expires_at <= ?",
+		model.RegistrationPending, time.Now(), before).Order("expires_at").Find(&registrations).Error
+	return registrations, err
+}
+
+// GetStaleVerifiedRegistrations 获取在 before 之前验证邮箱、仍在等待审核且尚未通知管理员的注册申请，
+// 没有验证时间的旧记录按更新时间计算
+func GetStaleVerifiedRegistrations(before time.Time) ([]model.UserRegistration, error) {
+	var registrations []model.UserRegistration
+	err := db.Where("status = ? AND escalated_at IS NULL AND COALESCE(verified_at, updated_at) <= ?",
+		model.RegistrationVerified, before).Order("id").Find(&registrations).Error
+	return registrations, err
+}
+
+// MarkRegistrationsEscalated 记录已就等待审核超时通知管理员的注册申请
+func MarkRegistrationsEscalated(ids []uint, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return db.Model(&model.UserRegistration{}).Where("id IN ?", ids).Update("escalated_at", at).Error
+}
+
+// GetUserRegistrationByEnrollToken 根据通行密钥登记令牌获取已完成注册的记录
+func GetUserRegistrationByEnrollToken(token string) (*model.UserRegistration, error) {
+	var registration model.UserRegistration
+	err := db.Where("enroll_token = ? AND status = ?", token, model.RegistrationRegistered).First(&registration).Error
+	return &registration, err
+}
+
+// ClearRegistrationEnrollToken 使通行密钥登记令牌失效，令牌已失效时返回 false
+func ClearRegistrationEnrollToken(token string) (bool, error) {
+	result := db.Model(&model.UserRegistration{}).Where("enroll_token = ?", token).
+		Updates(map[string]any{"enroll_token": "", "enroll_expires_at": nil})
+	return result.RowsAffected > 0, result.Error
+}
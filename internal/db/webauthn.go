@@ -0,0 +1,71 @@
+package db
+
+import (
+	"encoding/base64"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// GetWebAuthnCredentials returns the passkeys of a user
+func GetWebAuthnCredentials(userID uint) ([]model.WebAuthnCredential, error) {
+	var credentials []model.WebAuthnCredential
+	err := db.Where("user_id = ?", userID).Order("id").Find(&credentials).Error
+	return credentials, errors.WithStack(err)
+}
+
+// CountWebAuthnCredentials returns the number of passkeys of a user
+func CountWebAuthnCredentials(userID uint) (int64, error) {
+	var count int64
+	err := db.Model(&model.WebAuthnCredential{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, errors.WithStack(err)
+}
+
+// CreateWebAuthnCredential stores a passkey of a user
+func CreateWebAuthnCredential(userID uint, credential *webauthn.Credential) error {
+	return createWebAuthnCredential(db, userID, credential)
+}
+
+func createWebAuthnCredential(tx *gorm.DB, userID uint, credential *webauthn.Credential) error {
+	data, err := utils.Json.Marshal(credential)
+	if err != nil {
+		return err
+	}
+	return errors.WithStack(tx.Create(&model.WebAuthnCredential{
+		UserID:       userID,
+		CredentialID: base64.StdEncoding.EncodeToString(credential.ID),
+		Data:         string(data),
+	}).Error)
+}
+
+// DeleteWebAuthnCredential removes a passkey of a user by its base64 encoded credential id
+func DeleteWebAuthnCredential(userID uint, credentialID string) error {
+	return errors.WithStack(db.Where("user_id = ? AND credential_id = ?", userID, credentialID).
+		Delete(&model.WebAuthnCredential{}).Error)
+}
+
+// migrateUserAuthn moves the passkeys kept in the authn column of users into the credentials table
+func migrateUserAuthn() error {
+	var users []model.User
+	if err := db.Where("authn <> '' AND authn <> '[]'").Find(&users).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	for i := range users {
+		user := &users[i]
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for _, credential := range user.WebAuthnCredentials() {
+				if err := createWebAuthnCredential(tx, user.ID, &credential); err != nil {
+					return err
+				}
+			}
+			return tx.Model(&model.User{ID: user.ID}).Update("authn", "[]").Error
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed migrate passkeys of user %s", user.Username)
+		}
+	}
+	return nil
+}
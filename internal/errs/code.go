@@ -0,0 +1,27 @@
+package errs
+
+import "errors"
+
+// CodedError is an error with a stable machine readable code, so that clients
+// can tell errors apart without parsing messages and the server can localize them
+type CodedError struct {
+	Code string
+	msg  string
+}
+
+func NewCoded(code, msg string) *CodedError {
+	return &CodedError{Code: code, msg: msg}
+}
+
+func (e *CodedError) Error() string {
+	return e.msg
+}
+
+// Code returns the code of the first CodedError in the chain of err, or "" if there is none
+func Code(err error) string {
+	var coded *CodedError
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+	return ""
+}
@@ -0,0 +1,48 @@
+package errs
+
+var (
+	InsufficientCredits    = NewCoded("insufficient_credits", "insufficient credits")
+	CreditsVersionConflict = NewCoded("credits_conflict", "credits account was modified concurrently")
+	CreditHoldNotActive    = NewCoded("credit_hold_not_active", "credit hold is already captured or released")
+	TransferLimitExceeded  = NewCoded("transfer_limit_exceeded", "daily credits transfer limit exceeded")
+	CreditGiftNotPending   = NewCoded("credit_gift_not_pending", "credit gift is already claimed or returned")
+	RewardReplayed         = NewCoded("reward_replayed", "reward transaction or nonce has already been used")
+	RewardCapExceeded      = NewCoded("reward_cap_exceeded", "reward cap exceeded")
+	InvalidRewardSign      = NewCoded("invalid_reward_sign", "invalid reward callback signature")
+	CreditsFrozen          = NewCoded("credits_frozen", "credits account is frozen")
+	InvalidDownloadToken   = NewCoded("invalid_download_token", "download token is invalid, used up or expired")
+
+	InvalidCreditsAmount = NewCoded("invalid_credits_amount", "credits amount must be greater than 0")
+	TransferToSelf       = NewCoded("transfer_to_self", "cannot send credits to yourself")
+	RecipientNotFound    = NewCoded("recipient_not_found", "recipient not found")
+	RecipientUnavailable = NewCoded("recipient_unavailable", "recipient is unavailable")
+	CreditHoldNotFound   = NewCoded("credit_hold_not_found", "credit hold not found")
+	CreditGiftNotFound   = NewCoded("credit_gift_not_found", "credit gift not found")
+	CreditGiftExpired    = NewCoded("credit_gift_expired", "credit gift has expired")
+
+	RedeemCodeNotFound    = NewCoded("redeem_code_not_found", "redeem code not found")
+	RedeemCodeUnavailable = NewCoded("redeem_code_unavailable", "redeem code is already used or expired")
+	RedeemCodeUserLimit   = NewCoded("redeem_code_user_limit", "you have reached the usage limit of this redeem code")
+	InvalidReferralCode   = NewCoded("invalid_referral_code", "invalid referral code")
+	SelfReferral          = NewCoded("self_referral", "cannot use your own referral code")
+
+	PaymentOrderNotFound      = NewCoded("payment_order_not_found", "payment order not found")
+	PaymentOrderInvalidStatus = NewCoded("payment_order_invalid_status", "payment order is not pending")
+	PaymentOrderExpired       = NewCoded("payment_order_expired", "payment order has expired")
+	CreditPackageNotFound     = NewCoded("credit_package_not_found", "credit package not found")
+	CreditPackageUnavailable  = NewCoded("credit_package_unavailable", "credit package is no longer available")
+	VipPlanNotFound           = NewCoded("vip_plan_not_found", "subscription plan not found")
+	VipPlanUnavailable        = NewCoded("vip_plan_unavailable", "subscription plan is no longer available")
+
+	CouponRequired      = NewCoded("coupon_required", "coupon code is required")
+	CouponNotFound      = NewCoded("coupon_not_found", "coupon not found")
+	CouponInactive      = NewCoded("coupon_inactive", "coupon is not active or has expired")
+	CouponMinAmount     = NewCoded("coupon_min_amount", "order amount does not reach the coupon minimum")
+	CouponNotApplicable = NewCoded("coupon_not_applicable", "coupon does not apply to this order")
+	CouponTooLarge      = NewCoded("coupon_too_large", "order amount after discount must be greater than 0")
+	CouponExhausted     = NewCoded("coupon_exhausted", "coupon has reached its usage limit")
+	CouponUserLimit     = NewCoded("coupon_user_limit", "you have reached the usage limit of this coupon")
+
+	ShareNotFound = NewCoded("share_not_found", "share link not found or expired")
+	ShareFileFree = NewCoded("share_file_free", "the shared file is free to download")
+)
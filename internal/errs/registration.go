@@ -0,0 +1,28 @@
+package errs
+
+var (
+	RegistrationClosed         = NewCoded("registration_closed", "registration is closed")
+	InviteCodeRequired         = NewCoded("invite_code_required", "an invite code is required to register")
+	InvalidInviteCode          = NewCoded("invalid_invite_code", "invite code is invalid, used up or expired")
+	InviteCodeQuota            = NewCoded("invite_code_quota", "you have reached the number of invite codes you can create")
+	RegistrationPending        = NewCoded("registration_pending", "your registration is waiting for approval")
+	EmailDomainNotAllowed      = NewCoded("email_domain_not_allowed", "registration is limited to emails of specific domains")
+	EmailDomainBlocked         = NewCoded("email_domain_blocked", "emails of this domain can't be used to register")
+	VerificationResendCooldown = NewCoded("verification_resend_cooldown", "the verification email was sent recently, try again later")
+	VerificationResendLimit    = NewCoded("verification_resend_limit", "the verification email can't be sent again, register anew after the registration expires")
+	InvalidRegistrationStatus  = NewCoded("invalid_registration_status", "the registration can't be processed in its current status")
+	RegistrationSourceLimited  = NewCoded("registration_source_limited", "too many registrations from the same network were approved recently")
+	UsernameInvalid            = NewCoded("username_invalid", "the username contains characters that are not allowed")
+	UsernameLength             = NewCoded("username_length", "the username is too short or too long")
+	UsernameReserved           = NewCoded("username_reserved", "this username is reserved")
+	UsernameTaken              = NewCoded("username_taken", "this username is already taken")
+	InvalidVerificationLink    = NewCoded("invalid_verification_link", "the verification link is invalid or has been replaced by a newer one")
+	VerificationLinkExpired    = NewCoded("verification_link_expired", "the verification link has expired, register again")
+	InvalidVerificationCode    = NewCoded("invalid_verification_code", "the verification code is wrong or expired")
+	VerificationCodeLocked     = NewCoded("verification_code_locked", "too many wrong verification codes, request a new code")
+	IdentityLinked             = NewCoded("identity_linked", "this identity is already linked to another account")
+	IdentityNotFound           = NewCoded("identity_not_found", "the linked identity doesn't exist")
+	InvalidIdentityLinkToken   = NewCoded("invalid_identity_link_token", "the sign in with the provider has expired, sign in again to link it")
+	LastLoginMethod            = NewCoded("last_login_method", "the only way to sign in can't be unlinked, set a password first")
+	DirectoryAccountProtected  = NewCoded("directory_account_protected", "an admin account with this name exists and can't be taken over by the directory user")
+)
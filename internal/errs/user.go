@@ -8,3 +8,19 @@ var (
 	WrongPassword      = errors.New("password is incorrect")
 	DeleteAdminOrGuest = errors.New("cannot delete admin or guest")
 )
+
+var (
+	TwoFactorRequired      = NewCoded("two_factor_required", "two factor authentication must be enabled for this operation")
+	InvalidTwoFactorCode   = NewCoded("invalid_two_factor_code", "a valid 2FA code or backup code is required for this operation")
+	PasswordResetLimited   = NewCoded("password_reset_limited", "too many password reset attempts, try again later")
+	InvalidResetCode       = NewCoded("invalid_reset_code", "the reset code is wrong, used or expired")
+	InvalidResetToken      = NewCoded("invalid_reset_token", "the reset link is invalid or expired, request a new code")
+	EmailChangeLimited     = NewCoded("email_change_limited", "an email change was requested recently, try again later")
+	NoPendingEmailChange   = NewCoded("no_pending_email_change", "there is no pending email change, request a new one")
+	InvalidEmailChangeCode = NewCoded("invalid_email_change_code", "the verification codes are wrong or expired")
+	AccountDeletionLimited = NewCoded("account_deletion_limited", "too many account deletion attempts, try again later")
+	InvalidDeletionCode    = NewCoded("invalid_deletion_code", "the password or the verification code is wrong")
+	DataExportLimited      = NewCoded("data_export_limited", "a data export was requested recently, try again later")
+	InvalidExportToken     = NewCoded("invalid_export_token", "the export link is invalid or expired, request a new export")
+	TermsNotAccepted       = NewCoded("terms_not_accepted", "the current terms of service and privacy policy must be accepted")
+)
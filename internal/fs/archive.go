@@ -54,6 +54,14 @@ func (t *ArchiveDownloadTask) Run() error {
 	return nil
 }
 
+func (t *ArchiveDownloadTask) OnSucceeded() {
+	op.SettleTaskCredits(t.CreditHoldID, true)
+}
+
+func (t *ArchiveDownloadTask) OnFailed() {
+	op.SettleTaskCredits(t.CreditHoldID, false)
+}
+
 func (t *ArchiveDownloadTask) RunWithoutPushUploadTask() (*ArchiveContentUploadTask, error) {
 	srcObj, tool, ss, err := op.GetArchiveToolAndStream(t.Ctx(), t.SrcStorage, t.SrcActualPath, model.LinkArgs{})
 	if err != nil {
@@ -395,6 +403,10 @@ func archiveDecompress(ctx context.Context, srcObjPath, dstDirPath string, args
 	} else {
 		tsk.Creator, _ = ctx.Value(conf.UserKey).(*model.User)
 		tsk.ApiUrl = common.GetApiUrl(ctx)
+		tsk.CreditHoldID, err = op.HoldTaskCredits(tsk.Creator, op.TaskDecompress, "decompress "+srcObjPath)
+		if err != nil {
+			return nil, err
+		}
 		ArchiveDownloadTaskManager.Add(tsk)
 		return tsk, nil
 	}
@@ -64,10 +64,12 @@ func (t *FileTransferTask) Run() error {
 }
 
 func (t *FileTransferTask) OnSucceeded() {
+	op.SettleTaskCredits(t.CreditHoldID, true)
 	task_group.TransferCoordinator.Done(t.groupID, true)
 }
 
 func (t *FileTransferTask) OnFailed() {
+	op.SettleTaskCredits(t.CreditHoldID, false)
 	task_group.TransferCoordinator.Done(t.groupID, false)
 }
 
@@ -104,6 +106,9 @@ func transfer(ctx context.Context, taskType taskType, srcObjPath, dstDirPath str
 		} else {
 			err = op.Move(ctx, srcStorage, srcObjActualPath, dstDirActualPath, lazyCache...)
 			if !errors.Is(err, errs.NotImplement) && !errors.Is(err, errs.NotSupport) {
+				if err == nil {
+					moveCreditsConfigs(srcObjPath, stdpath.Join(dstDirPath, stdpath.Base(srcObjPath)))
+				}
 				return nil, err
 			}
 		}
@@ -149,6 +154,10 @@ func transfer(ctx context.Context, taskType taskType, srcObjPath, dstDirPath str
 	t.ApiUrl = common.GetApiUrl(ctx)
 	t.groupID = dstDirPath
 	if taskType == copy {
+		t.CreditHoldID, err = op.HoldTaskCredits(t.Creator, op.TaskCopy, t.GetName())
+		if err != nil {
+			return nil, err
+		}
 		task_group.TransferCoordinator.AddTask(dstDirPath, nil)
 		CopyTaskManager.Add(t)
 	} else {
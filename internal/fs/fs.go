@@ -3,6 +3,7 @@ package fs
 import (
 	"context"
 	"io"
+	stdpath "path"
 
 	log "github.com/sirupsen/logrus"
 
@@ -86,6 +87,8 @@ func Rename(ctx context.Context, srcPath, dstName string, lazyCache ...bool) err
 	err := rename(ctx, srcPath, dstName, lazyCache...)
 	if err != nil {
 		log.Errorf("failed rename %s to %s: %+v", srcPath, dstName, err)
+	} else {
+		moveCreditsConfigs(srcPath, stdpath.Join(stdpath.Dir(srcPath), dstName))
 	}
 	return err
 }
@@ -94,6 +97,8 @@ func Remove(ctx context.Context, path string) error {
 	err := remove(ctx, path)
 	if err != nil {
 		log.Errorf("failed remove %s: %+v", path, err)
+	} else if _, err := op.OrphanFileCreditsConfigs(path); err != nil {
+		log.Errorf("failed orphan credits configs of %s: %+v", path, err)
 	}
 	return err
 }
@@ -188,3 +193,10 @@ func PutURL(ctx context.Context, path, dstName, urlStr string) error {
 	}
 	return op.PutURL(ctx, storage, dstDirActualPath, dstName, urlStr)
 }
+
+// moveCreditsConfigs keeps the credits configs attached to a moved or renamed path, failures are only logged
+func moveCreditsConfigs(srcPath, dstPath string) {
+	if _, err := op.MoveFileCreditsConfigs(srcPath, dstPath); err != nil {
+		log.Errorf("failed move credits configs from %s to %s: %+v", srcPath, dstPath, err)
+	}
+}
@@ -0,0 +1,52 @@
+package fs
+
+import (
+	"context"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	op.RegisterUserApprovedHook(func(user *model.User) {
+		if err := CreateHomeFolder(context.Background(), user); err != nil {
+			log.Errorf("failed create home folder of user %s: %+v", user.Username, err)
+		}
+	})
+}
+
+// CreateHomeFolder creates the base path of the user on the storage set by home_folder_storage,
+// and makes it writable by a meta if home_folder_writable is on
+func CreateHomeFolder(ctx context.Context, user *model.User) error {
+	mountPath := setting.GetStr(conf.HomeFolderStorage)
+	if mountPath == "" || utils.PathEqual(user.BasePath, "/") {
+		return nil
+	}
+	storage, _, err := op.GetStorageAndActualPath(user.BasePath)
+	if err != nil {
+		return errors.WithMessage(err, "failed get storage")
+	}
+	if !utils.PathEqual(utils.GetActualMountPath(storage.GetStorage().MountPath), mountPath) {
+		log.Debugf("skip home folder %s, it's not on storage %s", user.BasePath, mountPath)
+		return nil
+	}
+	if err = MakeDir(ctx, user.BasePath); err != nil {
+		return err
+	}
+	if !setting.GetBool(conf.HomeFolderWritable) {
+		return nil
+	}
+	// keep the meta an admin may have set up for the path
+	if _, err = op.GetMetaByPath(user.BasePath); err == nil {
+		return nil
+	} else if !errors.Is(err, errs.MetaNotFound) {
+		return errors.WithMessage(err, "failed get meta")
+	}
+	return op.CreateMeta(&model.Meta{Path: user.BasePath, Write: true, WSub: true})
+}
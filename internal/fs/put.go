@@ -13,10 +13,13 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
 	"github.com/OpenListTeam/OpenList/v4/internal/task"
 	"github.com/OpenListTeam/OpenList/v4/internal/task_group"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/tache"
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 )
 
 type UploadTask struct {
@@ -24,6 +27,7 @@ type UploadTask struct {
 	storage          driver.Driver
 	dstDirActualPath string
 	file             model.FileStreamer
+	hash             string // sha256 hashed on the server for upload rewards, empty when no reward applies
 }
 
 func (t *UploadTask) GetName() string {
@@ -42,7 +46,11 @@ func (t *UploadTask) Run() error {
 }
 
 func (t *UploadTask) OnSucceeded() {
-	task_group.TransferCoordinator.Done(stdpath.Join(t.storage.GetStorage().MountPath, t.dstDirActualPath), true)
+	dstDirPath := stdpath.Join(t.storage.GetStorage().MountPath, t.dstDirActualPath)
+	task_group.TransferCoordinator.Done(dstDirPath, true)
+	if t.hash != "" {
+		rewardUpload(t.Creator, stdpath.Join(dstDirPath, t.file.GetName()), t.file.GetSize(), t.hash)
+	}
 }
 
 func (t *UploadTask) OnFailed() {
@@ -68,7 +76,14 @@ func putAsTask(ctx context.Context, dstDirPath string, file model.FileStreamer)
 	if storage.Config().NoUpload {
 		return nil, errors.WithStack(errs.UploadNotSupported)
 	}
-	if file.NeedStore() {
+	taskCreator, _ := ctx.Value(conf.UserKey).(*model.User) // taskCreator is nil when convert failed
+	var hash string
+	if op.UploadRewardApplies(taskCreator, stdpath.Join(dstDirPath, file.GetName())) {
+		// upload rewards are keyed on the content, hash it here as the hash sent by the client can't be trusted
+		if _, hash, err = stream.CacheFullAndHash(file, nil, utils.SHA256); err != nil {
+			return nil, errors.Wrapf(err, "failed to create temp file")
+		}
+	} else if file.NeedStore() {
 		_, err := file.CacheFullAndWriter(nil, nil)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to create temp file")
@@ -76,7 +91,6 @@ func putAsTask(ctx context.Context, dstDirPath string, file model.FileStreamer)
 		//file.SetReader(tempFile)
 		//file.SetTmpFile(tempFile)
 	}
-	taskCreator, _ := ctx.Value(conf.UserKey).(*model.User) // taskCreator is nil when convert failed
 	t := &UploadTask{
 		TaskExtension: task.TaskExtension{
 			Creator: taskCreator,
@@ -85,6 +99,7 @@ func putAsTask(ctx context.Context, dstDirPath string, file model.FileStreamer)
 		storage:          storage,
 		dstDirActualPath: dstDirActualPath,
 		file:             file,
+		hash:             hash,
 	}
 	t.SetTotalBytes(file.GetSize())
 	task_group.TransferCoordinator.AddTask(dstDirPath, nil)
@@ -103,5 +118,29 @@ func putDirectly(ctx context.Context, dstDirPath string, file model.FileStreamer
 		_ = file.Close()
 		return errors.WithStack(errs.UploadNotSupported)
 	}
-	return op.Put(ctx, storage, dstDirActualPath, file, nil, lazyCache...)
+	user, _ := ctx.Value(conf.UserKey).(*model.User)
+	path := stdpath.Join(dstDirPath, file.GetName())
+	var hash string
+	if op.UploadRewardApplies(user, path) {
+		// upload rewards are keyed on the content, hash it here as the hash sent by the client can't be trusted
+		if _, hash, err = stream.CacheFullAndHash(file, nil, utils.SHA256); err != nil {
+			_ = file.Close()
+			return errors.WithMessage(err, "failed to cache the file")
+		}
+	}
+	err = op.Put(ctx, storage, dstDirActualPath, file, nil, lazyCache...)
+	if err == nil && hash != "" {
+		rewardUpload(user, path, file.GetSize(), hash)
+	}
+	return err
+}
+
+// rewardUpload grants upload earn credits, failures never affect the upload itself
+func rewardUpload(user *model.User, path string, size int64, hash string) {
+	credits, err := op.RewardUpload(user, path, size, hash)
+	if err != nil {
+		log.Errorf("failed to reward upload %s: %+v", path, err)
+	} else if credits > 0 {
+		log.Debugf("user %s earned %d credits for uploading %s", user.Username, credits, path)
+	}
 }
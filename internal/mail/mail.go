@@ -0,0 +1,182 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Encryption modes of the connection to the SMTP server
+const (
+	EncryptionNone     = "none"
+	EncryptionStartTLS = "starttls"
+	EncryptionTLS      = "tls"
+)
+
+const dialTimeout = 10 * time.Second
+
+// Config is the SMTP server used to deliver mail
+type Config struct {
+	Host       string
+	Port       int
+	Username   string
+	Password   string
+	Encryption string // none, starttls or tls
+	From       string // sender address, may include a display name
+}
+
+// Message is a single mail, at least one of Text and HTML should be set
+type Message struct {
+	From    string // sender address, may include a display name
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Send delivers the message through the SMTP server, the sender of the
+// message defaults to cfg.From
+func Send(cfg Config, msg Message) error {
+	if msg.From == "" {
+		msg.From = cfg.From
+	}
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return errors.Wrapf(err, "invalid sender address: %s", msg.From)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return errors.Wrapf(err, "invalid recipient address: %s", msg.To)
+	}
+	body, err := buildMessage(from, to, msg)
+	if err != nil {
+		return err
+	}
+
+	c, err := dial(cfg)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to smtp server")
+	}
+	defer c.Close()
+	if cfg.Username != "" {
+		if err = c.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return errors.Wrap(err, "smtp auth failed")
+		}
+	}
+	if err = c.Mail(from.Address); err != nil {
+		return errors.Wrap(err, "smtp MAIL FROM failed")
+	}
+	if err = c.Rcpt(to.Address); err != nil {
+		return errors.Wrap(err, "smtp RCPT TO failed")
+	}
+	w, err := c.Data()
+	if err != nil {
+		return errors.Wrap(err, "smtp DATA failed")
+	}
+	if _, err = w.Write(body); err != nil {
+		return errors.Wrap(err, "failed to write mail body")
+	}
+	if err = w.Close(); err != nil {
+		return errors.Wrap(err, "failed to send mail")
+	}
+	return c.Quit()
+}
+
+func dial(cfg Config) (*smtp.Client, error) {
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	tlsConfig := &tls.Config{ServerName: cfg.Host}
+	if cfg.Encryption == EncryptionTLS {
+		conn, err := tls.DialWithDialer(&net.Dialer{Timeout: dialTimeout}, "tcp", addr, tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+		return smtp.NewClient(conn, cfg.Host)
+	}
+	conn, err := net.DialTimeout("tcp", addr, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if cfg.Encryption == EncryptionStartTLS {
+		if err = c.StartTLS(tlsConfig); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// buildMessage encodes the message as MIME, with a multipart/alternative body
+// when both the text and html versions are present
+func buildMessage(from, to *mail.Address, msg Message) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+	header("From", from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+
+	if msg.Text == "" || msg.HTML == "" {
+		contentType, content := "text/plain", msg.Text
+		if msg.HTML != "" {
+			contentType, content = "text/html", msg.HTML
+		}
+		header("Content-Type", contentType+"; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, content); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	w := multipart.NewWriter(&buf)
+	header("Content-Type", "multipart/alternative; boundary="+w.Boundary())
+	buf.WriteString("\r\n")
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain", msg.Text},
+		{"text/html", msg.HTML},
+	} {
+		pw, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err = writeQuotedPrintable(pw, part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, content string) error {
+	qw := quotedprintable.NewWriter(w)
+	if _, err := qw.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qw.Close()
+}
@@ -0,0 +1,63 @@
+package mail
+
+import (
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/cmd/flags"
+)
+
+func TestRender(t *testing.T) {
+	msg, err := Render(TemplateVerificationCode, map[string]any{
+		"SiteTitle": "OpenList",
+		"Code":      "123456",
+		"ExpiresAt": time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("failed to render template: %+v", err)
+	}
+	if msg.Subject != "[OpenList] Your verification code" {
+		t.Errorf("unexpected subject: %q", msg.Subject)
+	}
+	if !strings.Contains(msg.Text, "123456") || !strings.Contains(msg.HTML, "123456") {
+		t.Errorf("expected the code in both bodies, got %q and %q", msg.Text, msg.HTML)
+	}
+
+	dataDir := flags.DataDir
+	flags.DataDir = t.TempDir()
+	defer func() { flags.DataDir = dataDir }()
+	dir := filepath.Join(flags.DataDir, TemplateDirName)
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	override := `{{define "subject"}}Code for {{.SiteTitle}}{{end}}Code: {{.Code}}`
+	if err = os.WriteFile(filepath.Join(dir, TemplateVerificationCode+".txt"), []byte(override), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	msg, err = Render(TemplateVerificationCode, map[string]any{"SiteTitle": "OpenList", "Code": "654321", "ExpiresAt": time.Now()})
+	if err != nil {
+		t.Fatalf("failed to render overridden template: %+v", err)
+	}
+	if msg.Subject != "Code for OpenList" || msg.Text != "Code: 654321" || !strings.Contains(msg.HTML, "654321") {
+		t.Errorf("expected the overridden text template, got %+v", msg)
+	}
+}
+
+func TestBuildMessage(t *testing.T) {
+	from := &mail.Address{Name: "OpenList", Address: "noreply@example.com"}
+	to := &mail.Address{Address: "user@example.com"}
+	body, err := buildMessage(from, to, Message{Subject: "验证码", Text: "text body", HTML: "<p>html body</p>"})
+	if err != nil {
+		t.Fatalf("failed to build message: %+v", err)
+	}
+	s := string(body)
+	for _, want := range []string{"Subject: =?utf-8?q?", "multipart/alternative", "text/plain", "text/html", "text body", "<p>html body</p>"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("expected %q in message:\n%s", want, s)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type mailgunConfig struct {
+	ApiKey string `json:"api_key"`
+	Domain string `json:"domain"`
+	Region string `json:"region"` // us (default) or eu
+}
+
+type mailgunProvider struct {
+	config mailgunConfig
+}
+
+func init() {
+	RegisterProviderDriver("mailgun", func(config []byte) (Provider, error) {
+		p := &mailgunProvider{}
+		if err := decodeConfig(config, &p.config); err != nil {
+			return nil, err
+		}
+		if p.config.ApiKey == "" || p.config.Domain == "" {
+			return nil, errors.New("mailgun api_key and domain are required")
+		}
+		return p, nil
+	})
+}
+
+func (p *mailgunProvider) endpoint() string {
+	host := "api.mailgun.net"
+	if p.config.Region == "eu" {
+		host = "api.eu.mailgun.net"
+	}
+	return "https://" + host + "/v3/" + url.PathEscape(p.config.Domain) + "/messages"
+}
+
+func (p *mailgunProvider) Send(ctx context.Context, msg Message) (string, error) {
+	form := url.Values{
+		"from":    {msg.From},
+		"to":      {msg.To},
+		"subject": {msg.Subject},
+	}
+	if msg.Text != "" {
+		form.Set("text", msg.Text)
+	}
+	if msg.HTML != "" {
+		form.Set("html", msg.HTML)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth("api", p.config.ApiKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to call mailgun")
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if err = checkResponse(resp, body); err != nil {
+		return "", err
+	}
+	var result struct {
+		ID string `json:"id"`
+	}
+	_ = json.Unmarshal(body, &result)
+	return result.ID, nil
+}
@@ -0,0 +1,87 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Provider delivers rendered messages, it is implemented by every mail driver
+type Provider interface {
+	// Send delivers the message and returns the id the provider assigned to it, if any
+	Send(ctx context.Context, msg Message) (string, error)
+}
+
+// ProviderSMTP is the built-in driver configured by the smtp_* settings
+const ProviderSMTP = "smtp"
+
+var providerDrivers = map[string]func(config []byte) (Provider, error){}
+
+// RegisterProviderDriver registers a mail provider driver created from a JSON config,
+// it should be called in init()
+func RegisterProviderDriver(name string, newProvider func(config []byte) (Provider, error)) {
+	if _, ok := providerDrivers[name]; ok {
+		panic("mail provider driver registered twice: " + name)
+	}
+	providerDrivers[name] = newProvider
+}
+
+// GetProviderDriverNames returns the names of all registered drivers, including smtp
+func GetProviderDriverNames() []string {
+	names := []string{ProviderSMTP}
+	for name := range providerDrivers {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// NewProvider creates a provider of the named driver from its JSON config
+func NewProvider(name string, config string) (Provider, error) {
+	newProvider, ok := providerDrivers[name]
+	if !ok {
+		return nil, errors.Errorf("no mail provider driver named: %s", name)
+	}
+	if config == "" {
+		config = "{}"
+	}
+	return newProvider([]byte(config))
+}
+
+// decodeConfig unmarshals a driver config
+func decodeConfig(config []byte, v any) error {
+	if err := json.Unmarshal(config, v); err != nil {
+		return errors.Wrap(err, "invalid mail provider config")
+	}
+	return nil
+}
+
+type smtpProvider struct {
+	cfg Config
+}
+
+// NewSMTPProvider creates a provider sending through an SMTP server
+func NewSMTPProvider(cfg Config) Provider {
+	return &smtpProvider{cfg: cfg}
+}
+
+func (p *smtpProvider) Send(_ context.Context, msg Message) (string, error) {
+	return "", Send(p.cfg, msg)
+}
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// checkResponse turns a non 2xx response of a mail API into an error
+func checkResponse(resp *http.Response, body []byte) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	if len(body) > 512 {
+		body = body[:512]
+	}
+	return errors.Errorf("mail api responded %s: %s", resp.Status, body)
+}
@@ -0,0 +1,48 @@
+package mail
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookProvider(t *testing.T) {
+	var received map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write(body)
+		if r.Header.Get(WebhookSignatureHeader) != hex.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.Unmarshal(body, &received)
+		_, _ = w.Write([]byte(`{"id":"msg-1"}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewProvider("webhook", `{"url":"`+server.URL+`","secret":"secret"}`)
+	if err != nil {
+		t.Fatalf("failed to create webhook provider: %+v", err)
+	}
+	id, err := provider.Send(context.Background(), Message{From: "noreply@example.com", To: "user@example.com", Subject: "hi", Text: "hello"})
+	if err != nil {
+		t.Fatalf("failed to send: %+v", err)
+	}
+	if id != "msg-1" || received["to"] != "user@example.com" || received["text"] != "hello" {
+		t.Errorf("unexpected delivery, id %q, payload %v", id, received)
+	}
+
+	if _, err = NewProvider("webhook", `{}`); err == nil {
+		t.Errorf("expected a webhook without url to be rejected")
+	}
+	if _, err = NewProvider("carrier_pigeon", `{}`); err == nil {
+		t.Errorf("expected an unknown driver to be rejected")
+	}
+}
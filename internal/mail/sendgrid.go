@@ -0,0 +1,80 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/mail"
+
+	"github.com/pkg/errors"
+)
+
+const sendGridEndpoint = "https://api.sendgrid.com/v3/mail/send"
+
+type sendGridConfig struct {
+	ApiKey   string `json:"api_key"`
+	Endpoint string `json:"endpoint"` // defaults to the public v3 API
+}
+
+type sendGridProvider struct {
+	config sendGridConfig
+}
+
+func init() {
+	RegisterProviderDriver("sendgrid", func(config []byte) (Provider, error) {
+		p := &sendGridProvider{config: sendGridConfig{Endpoint: sendGridEndpoint}}
+		if err := decodeConfig(config, &p.config); err != nil {
+			return nil, err
+		}
+		if p.config.ApiKey == "" {
+			return nil, errors.New("sendgrid api_key is required")
+		}
+		return p, nil
+	})
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+func (p *sendGridProvider) Send(ctx context.Context, msg Message) (string, error) {
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid sender address: %s", msg.From)
+	}
+	var content []map[string]string
+	if msg.Text != "" {
+		content = append(content, map[string]string{"type": "text/plain", "value": msg.Text})
+	}
+	if msg.HTML != "" {
+		content = append(content, map[string]string{"type": "text/html", "value": msg.HTML})
+	}
+	payload, err := json.Marshal(map[string]any{
+		"personalizations": []map[string]any{{"to": []sendGridAddress{{Email: msg.To}}}},
+		"from":             sendGridAddress{Email: from.Address, Name: from.Name},
+		"subject":          msg.Subject,
+		"content":          content,
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.Endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.config.ApiKey)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to call sendgrid")
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if err = checkResponse(resp, body); err != nil {
+		return "", err
+	}
+	return resp.Header.Get("X-Message-Id"), nil
+}
@@ -0,0 +1,71 @@
+package mail
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sesv2"
+	"github.com/pkg/errors"
+)
+
+type sesConfig struct {
+	Region           string `json:"region"`
+	AccessKeyID      string `json:"access_key_id"` // empty uses the default credential chain
+	SecretAccessKey  string `json:"secret_access_key"`
+	ConfigurationSet string `json:"configuration_set"` // optional, used for delivery events
+}
+
+type sesProvider struct {
+	config sesConfig
+	client *sesv2.SESV2
+}
+
+func init() {
+	RegisterProviderDriver("ses", func(config []byte) (Provider, error) {
+		p := &sesProvider{}
+		if err := decodeConfig(config, &p.config); err != nil {
+			return nil, err
+		}
+		if p.config.Region == "" {
+			return nil, errors.New("ses region is required")
+		}
+		cfg := aws.NewConfig().WithRegion(p.config.Region)
+		if p.config.AccessKeyID != "" {
+			cfg = cfg.WithCredentials(credentials.NewStaticCredentials(p.config.AccessKeyID, p.config.SecretAccessKey, ""))
+		}
+		sess, err := session.NewSession(cfg)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create aws session")
+		}
+		p.client = sesv2.New(sess)
+		return p, nil
+	})
+}
+
+func (p *sesProvider) Send(ctx context.Context, msg Message) (string, error) {
+	body := &sesv2.Body{}
+	if msg.Text != "" {
+		body.Text = &sesv2.Content{Charset: aws.String("UTF-8"), Data: aws.String(msg.Text)}
+	}
+	if msg.HTML != "" {
+		body.Html = &sesv2.Content{Charset: aws.String("UTF-8"), Data: aws.String(msg.HTML)}
+	}
+	input := &sesv2.SendEmailInput{
+		FromEmailAddress: aws.String(msg.From),
+		Destination:      &sesv2.Destination{ToAddresses: []*string{aws.String(msg.To)}},
+		Content: &sesv2.EmailContent{Simple: &sesv2.Message{
+			Subject: &sesv2.Content{Charset: aws.String("UTF-8"), Data: aws.String(msg.Subject)},
+			Body:    body,
+		}},
+	}
+	if p.config.ConfigurationSet != "" {
+		input.ConfigurationSetName = aws.String(p.config.ConfigurationSet)
+	}
+	output, err := p.client.SendEmailWithContext(ctx, input)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to send mail with ses")
+	}
+	return aws.StringValue(output.MessageId), nil
+}
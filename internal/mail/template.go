@@ -0,0 +1,92 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/OpenListTeam/OpenList/v4/cmd/flags"
+	"github.com/pkg/errors"
+)
+
+// Names of the built-in templates. Each template is a pair of files, <name>.txt
+// and <name>.html; the text file also defines the "subject" template.
+const (
+	TemplateVerification         = "verification"
+	TemplateVerificationCode     = "verification_code"
+	TemplateVerificationReminder = "verification_reminder"
+	TemplateRegistrationApproved = "registration_approved"
+	TemplateRegistrationRejected = "registration_rejected"
+	TemplatePaymentReceipt       = "payment_receipt"
+	TemplatePasswordReset        = "password_reset"
+	TemplateAdminNotification    = "admin_notification"
+	TemplateAccountDeletion      = "account_deletion"
+	TemplateDataExport           = "data_export"
+)
+
+// TemplateDirName is the directory under the data directory where a file with
+// the same name as a built-in template overrides it
+const TemplateDirName = "mail_templates"
+
+//go:embed templates
+var builtinTemplates embed.FS
+
+// Render renders the named template into a message without recipient
+func Render(name string, data any) (Message, error) {
+	var msg Message
+	text, err := readTemplate(name + ".txt")
+	if err != nil {
+		return msg, err
+	}
+	textTmpl, err := texttemplate.New(name).Parse(text)
+	if err != nil {
+		return msg, errors.Wrapf(err, "failed to parse mail template %s.txt", name)
+	}
+	var buf bytes.Buffer
+	if textTmpl.Lookup("subject") != nil {
+		if err = textTmpl.ExecuteTemplate(&buf, "subject", data); err != nil {
+			return msg, errors.Wrapf(err, "failed to render subject of mail template %s", name)
+		}
+		msg.Subject = strings.TrimSpace(buf.String())
+		buf.Reset()
+	}
+	if err = textTmpl.Execute(&buf, data); err != nil {
+		return msg, errors.Wrapf(err, "failed to render mail template %s.txt", name)
+	}
+	msg.Text = strings.TrimSpace(buf.String())
+
+	html, err := readTemplate(name + ".html")
+	if err != nil {
+		return msg, err
+	}
+	htmlTmpl, err := htmltemplate.New(name).Parse(html)
+	if err != nil {
+		return msg, errors.Wrapf(err, "failed to parse mail template %s.html", name)
+	}
+	buf.Reset()
+	if err = htmlTmpl.Execute(&buf, data); err != nil {
+		return msg, errors.Wrapf(err, "failed to render mail template %s.html", name)
+	}
+	msg.HTML = buf.String()
+	return msg, nil
+}
+
+// readTemplate reads a template file from the override directory, falling back to the built-in one
+func readTemplate(file string) (string, error) {
+	b, err := os.ReadFile(filepath.Join(flags.DataDir, TemplateDirName, file))
+	if err == nil {
+		return string(b), nil
+	}
+	if !os.IsNotExist(err) {
+		return "", errors.Wrapf(err, "failed to read mail template %s", file)
+	}
+	b, err = builtinTemplates.ReadFile("templates/" + file)
+	if err != nil {
+		return "", errors.Errorf("no mail template named %s", file)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,82 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// WebhookSignatureHeader carries the hex HMAC-SHA256 of the request body when a secret is configured
+const WebhookSignatureHeader = "X-OpenList-Signature"
+
+type webhookConfig struct {
+	URL     string            `json:"url"`
+	Secret  string            `json:"secret"`
+	Headers map[string]string `json:"headers"`
+}
+
+// webhookProvider posts the rendered message as JSON to a URL, so that any
+// service can deliver it. The response may contain {"id": "..."}.
+type webhookProvider struct {
+	config webhookConfig
+}
+
+func init() {
+	RegisterProviderDriver("webhook", func(config []byte) (Provider, error) {
+		p := &webhookProvider{}
+		if err := decodeConfig(config, &p.config); err != nil {
+			return nil, err
+		}
+		if p.config.URL == "" {
+			return nil, errors.New("webhook url is required")
+		}
+		return p, nil
+	})
+}
+
+func (p *webhookProvider) Send(ctx context.Context, msg Message) (string, error) {
+	payload, err := json.Marshal(map[string]string{
+		"from":    msg.From,
+		"to":      msg.To,
+		"subject": msg.Subject,
+		"text":    msg.Text,
+		"html":    msg.HTML,
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.URL, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range p.config.Headers {
+		req.Header.Set(k, v)
+	}
+	if p.config.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(p.config.Secret))
+		mac.Write(payload)
+		req.Header.Set(WebhookSignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to call mail webhook")
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if err = checkResponse(resp, body); err != nil {
+		return "", err
+	}
+	var result struct {
+		ID string `json:"id"`
+	}
+	_ = json.Unmarshal(body, &result)
+	return result.ID, nil
+}
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// ApiUsage 用户每日各类接口的调用次数，超出免费次数的部分按次计费
+type ApiUsage struct {
+	UserID        uint      `json:"user_id" gorm:"primaryKey"`           // 用户ID
+	Day           string    `json:"day" gorm:"primaryKey;size:10"`       // 日期，格式 2006-01-02
+	Operation     string    `json:"operation" gorm:"primaryKey;size:16"` // 操作类型: list, search, propfind
+	Calls         int64     `json:"calls"`                               // 当日调用次数
+	BilledCredits int64     `json:"billed_credits"`                      // 当日已扣除的积分
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func (ApiUsage) TableName() string {
+	return "x_api_usages"
+}
+
+// Unbilled 按每千次 pricePer1000 积分计算超出 free 次免费调用后尚未扣除的积分，不足1积分的部分留到下次
+func (u *ApiUsage) Unbilled(free, pricePer1000 int64) int64 {
+	over := u.Calls - free
+	if over <= 0 || pricePer1000 <= 0 {
+		return 0
+	}
+	charge := over*pricePer1000/1000 - u.BilledCredits
+	if charge < 0 {
+		return 0
+	}
+	return charge
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 优惠券类型
+const (
+	CouponPercent = "percent" // 按比例折扣，Value 为减免的百分比
+	CouponFixed   = "fixed"   // 固定金额减免，Value 为减免金额（最小货币单位）
+)
+
+// Coupon 支付订单优惠券，下单时抵扣订单金额，与兑换积分的兑换码相互独立
+type Coupon struct {
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	Code         string         `json:"code" gorm:"uniqueIndex;not null"` // 优惠码
+	Type         string         `json:"type" gorm:"not null"`             // 类型: percent, fixed
+	Value        int64          `json:"value" gorm:"not null"`            // 减免百分比或减免金额（最小货币单位）
+	Currency     string         `json:"currency"`                         // 固定金额减免的货币类型
+	MinAmount    int64          `json:"min_amount"`                       // 最低订单金额（最小货币单位），0 表示不限
+	MaxUses      int            `json:"max_uses"`                         // 总使用次数上限，0 表示不限
+	PerUserLimit int            `json:"per_user_limit"`                   // 每个用户的使用次数上限，0 表示不限
+	UsedCount    int            `json:"used_count"`                       // 已支付订单的使用次数
+	StartsAt     *time.Time     `json:"starts_at"`                        // 生效时间，为空表示立即生效
+	ExpiresAt    *time.Time     `json:"expires_at"`                       // 过期时间，为空表示永不过期
+	Enabled      bool           `json:"enabled"`                          // 是否启用
+	Description  string         `json:"description"`                      // 描述
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (Coupon) TableName() string {
+	return "x_coupons"
+}
+
+// InValidity 检查优惠券在 now 时刻是否启用且处于有效期内
+func (c *Coupon) InValidity(now time.Time) bool {
+	if !c.Enabled {
+		return false
+	}
+	if c.StartsAt != nil && now.Before(*c.StartsAt) {
+		return false
+	}
+	return c.ExpiresAt == nil || now.Before(*c.ExpiresAt)
+}
+
+// Discount 计算订单金额可减免的金额（最小货币单位），不超过订单金额，
+// 货币不匹配或未达到最低金额时返回0
+func (c *Coupon) Discount(amount Money) int64 {
+	if amount.Amount < c.MinAmount {
+		return 0
+	}
+	var discount int64
+	switch c.Type {
+	case CouponPercent:
+		discount = amount.Amount * c.Value / 100
+	case CouponFixed:
+		if c.Currency != "" && !strings.EqualFold(c.Currency, amount.Currency) {
+			return 0
+		}
+		discount = c.Value
+	}
+	return max(min(discount, amount.Amount), 0)
+}
@@ -0,0 +1,22 @@
+package model
+
+import "testing"
+
+func TestCouponDiscount(t *testing.T) {
+	cases := []struct {
+		coupon Coupon
+		amount Money
+		want   int64
+	}{
+		{Coupon{Type: CouponPercent, Value: 20}, Money{Amount: 1000, Currency: "CNY"}, 200},
+		{Coupon{Type: CouponPercent, Value: 20, MinAmount: 2000}, Money{Amount: 1000, Currency: "CNY"}, 0},
+		{Coupon{Type: CouponFixed, Value: 300, Currency: "CNY"}, Money{Amount: 1000, Currency: "cny"}, 300},
+		{Coupon{Type: CouponFixed, Value: 300, Currency: "USD"}, Money{Amount: 1000, Currency: "CNY"}, 0},
+		{Coupon{Type: CouponFixed, Value: 3000}, Money{Amount: 1000, Currency: "CNY"}, 1000},
+	}
+	for i, c := range cases {
+		if got := c.coupon.Discount(c.amount); got != c.want {
+			t.Errorf("case %d: discount %d, want %d", i, got, c.want)
+		}
+	}
+}
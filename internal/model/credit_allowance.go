@@ -0,0 +1,63 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 积分津贴的发放周期
+const (
+	AllowanceMonthly = "monthly"
+	AllowanceWeekly  = "weekly"
+)
+
+// CreditAllowance 按角色定期发放的积分津贴，每个周期开始时为该角色的用户发放一次
+type CreditAllowance struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Name        string         `json:"name" gorm:"not null"`        // 名称
+	Role        int            `json:"role" gorm:"index;not null"`  // 发放对象的用户角色
+	Amount      int64          `json:"amount" gorm:"not null"`      // 每个周期发放的积分
+	Period      string         `json:"period" gorm:"not null"`      // 发放周期: monthly, weekly
+	Stacking    bool           `json:"stacking"`                    // 未用完的津贴是否累积，不累积时在周期结束时作废
+	Enabled     bool           `json:"enabled" gorm:"default:true"` // 是否启用
+	Description string         `json:"description"`                 // 描述
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (CreditAllowance) TableName() string {
+	return "x_credit_allowances"
+}
+
+// CreditAllowanceGrant 积分津贴的发放记录，同一津贴在同一周期内对每个用户只发放一次
+type CreditAllowanceGrant struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	AllowanceID uint      `json:"allowance_id" gorm:"uniqueIndex:idx_allowance_grant,priority:1;not null"`
+	UserID      uint      `json:"user_id" gorm:"uniqueIndex:idx_allowance_grant,priority:2;not null"`
+	Period      string    `json:"period" gorm:"uniqueIndex:idx_allowance_grant,priority:3;not null"` // 周期标识，如 2024-01、2024-W01
+	Amount      int64     `json:"amount" gorm:"not null"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (CreditAllowanceGrant) TableName() string {
+	return "x_credit_allowance_grants"
+}
+
+// PeriodAt 返回 t 所在周期的标识和起止时间，周按 ISO 8601 从周一开始
+func (a *CreditAllowance) PeriodAt(t time.Time) (key string, start, end time.Time, err error) {
+	switch a.Period {
+	case AllowanceMonthly:
+		start = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+		return start.Format("2006-01"), start, start.AddDate(0, 1, 0), nil
+	case AllowanceWeekly:
+		offset := (int(t.Weekday()) + 6) % 7
+		start = time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week), start, start.AddDate(0, 0, 7), nil
+	default:
+		return "", start, end, fmt.Errorf("unknown allowance period: %s", a.Period)
+	}
+}
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCreditAllowancePeriodAt(t *testing.T) {
+	now := time.Date(2025, 1, 1, 15, 0, 0, 0, time.UTC) // Wednesday
+	for _, c := range []struct {
+		period string
+		key    string
+		start  time.Time
+	}{
+		{AllowanceMonthly, "2025-01", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{AllowanceWeekly, "2025-W01", time.Date(2024, 12, 30, 0, 0, 0, 0, time.UTC)},
+	} {
+		a := CreditAllowance{Period: c.period}
+		key, start, _, err := a.PeriodAt(now)
+		if err != nil {
+			t.Fatalf("failed to get period: %+v", err)
+		}
+		if key != c.key || !start.Equal(c.start) {
+			t.Errorf("%s: got %s from %s, want %s from %s", c.period, key, start, c.key, c.start)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 积分礼物状态
+const (
+	CreditGiftPending  = "pending"
+	CreditGiftClaimed  = "claimed"
+	CreditGiftReturned = "returned"
+)
+
+// CreditGift 积分礼物，赠送时从赠送方扣除积分，接收方领取后到账，过期未领取的积分退还赠送方
+type CreditGift struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	GiftNo      string         `json:"gift_no" gorm:"uniqueIndex;not null"` // 礼物编号
+	SenderID    uint           `json:"sender_id" gorm:"index;not null"`     // 赠送方用户ID
+	RecipientID uint           `json:"recipient_id" gorm:"index;not null"`  // 接收方用户ID
+	Amount      int64          `json:"amount" gorm:"not null"`              // 积分数量
+	Message     string         `json:"message"`                             // 留言
+	Status      string         `json:"status" gorm:"index;not null"`        // 状态: pending, claimed, returned
+	ExpiresAt   time.Time      `json:"expires_at" gorm:"index"`             // 领取截止时间
+	FinishedAt  *time.Time     `json:"finished_at"`                         // 领取或退还时间
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (CreditGift) TableName() string {
+	return "x_credit_gifts"
+}
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CreditPackage 积分充值套餐，价格覆盖全局的积分单价设置
+type CreditPackage struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Name        string         `json:"name" gorm:"not null"`    // 套餐名称
+	Credits     int64          `json:"credits" gorm:"not null"` // 到账积分
+	Money                      // 套餐价格（amount 为最小货币单位）及货币类型
+	Enabled     bool           `json:"enabled" gorm:"default:true"` // 是否可购买
+	Description string         `json:"description"`                 // 描述
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (CreditPackage) TableName() string {
+	return "x_credit_packages"
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 
 	"gorm.io/gorm"
@@ -13,6 +14,11 @@ type UserCredits struct {
 	Balance   int64          `json:"balance" gorm:"default:0"` // 积分余额
 	TotalEarn int64          `json:"total_earn" gorm:"default:0"` // 累计获得积分
 	TotalSpent int64         `json:"total_spent" gorm:"default:0"` // 累计消费积分
+	Held      int64          `json:"held" gorm:"default:0"` // 下载中预扣（冻结）的积分
+	Bonus     int64          `json:"bonus" gorm:"not null;default:0"` // 余额中赠送（促销）积分的部分，其余为购买积分
+	Frozen    bool           `json:"frozen" gorm:"not null;default:false"` // 是否被管理员冻结，冻结期间不能获得或扣除积分
+	FrozenReason string      `json:"frozen_reason"` // 冻结原因
+	Version   int64          `json:"version" gorm:"not null;default:0"` // 乐观锁版本号，每次更新递增
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
@@ -22,28 +28,80 @@ type UserCredits struct {
 // CreditTransaction 积分交易记录
 type CreditTransaction struct {
 	ID          uint           `json:"id" gorm:"primaryKey"`
-	UserID      uint           `json:"user_id" gorm:"index;not null"` // 用户ID
-	Type        string         `json:"type" gorm:"not null"` // 交易类型: earn, spend, refund
+	UserID      uint           `json:"user_id" gorm:"index;index:idx_credit_tx_user_created,priority:1;index:idx_credit_tx_user_type,priority:1;index:idx_credit_tx_user_source,priority:1;not null"` // 用户ID
+	Type        string         `json:"type" gorm:"index:idx_credit_tx_user_type,priority:2;not null"` // 交易类型: earn, spend, refund, expire
 	Amount      int64          `json:"amount" gorm:"not null"` // 积分数量（正数为获得，负数为消费）
 	Balance     int64          `json:"balance" gorm:"not null"` // 交易后余额
-	Source      string         `json:"source" gorm:"not null"` // 来源: purchase, redeem_code, download, admin
+	Source      string         `json:"source" gorm:"index:idx_credit_tx_user_source,priority:2;not null"` // 来源: purchase, redeem_code, download, admin
 	SourceID    string         `json:"source_id"` // 来源ID（如订单ID、兑换码ID等）
 	Description string         `json:"description"` // 交易描述
-	Metadata    string         `json:"metadata" gorm:"type:text"` // 额外元数据（JSON格式）
-	CreatedAt   time.Time      `json:"created_at"`
+	Metadata    string         `json:"metadata" gorm:"type:text"` // 额外元数据（JSON格式），通过 SetMetadata/GetMetadata 读写
+	Path        string         `json:"path,omitempty" gorm:"index"` // 关联的文件路径，由 SetMetadata 从元数据同步，用于查询
+	Bonus       int64          `json:"bonus" gorm:"not null;default:0"` // Amount 中赠送积分的部分，其余为购买积分
+	Purchased   bool           `json:"-" gorm:"-"` // 获得的积分计入购买积分，扣除时只能使用购买积分；否则获得的积分计入赠送积分，扣除时优先使用赠送积分
+	ExpiresAt   *time.Time     `json:"expires_at,omitempty"` // 获得的积分的过期时间（为空则永不过期）
+	CreatedAt   time.Time      `json:"created_at" gorm:"index:idx_credit_tx_user_created,priority:2"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
 	User        *User          `json:"user,omitempty" gorm:"foreignKey:UserID"`
 }
 
+// CreditLot 积分批次，每笔获得的积分记为一个批次，消费时按获得先后扣减，过期后剩余部分作废
+type CreditLot struct {
+	ID            uint           `json:"id" gorm:"primaryKey"`
+	UserID        uint           `json:"user_id" gorm:"index;not null"` // 用户ID
+	TransactionID uint           `json:"transaction_id" gorm:"index"` // 对应的获得积分交易ID
+	Amount        int64          `json:"amount" gorm:"not null"` // 获得的积分数量
+	Remaining     int64          `json:"remaining" gorm:"not null"` // 剩余未消费的积分
+	Source        string         `json:"source"` // 来源
+	Bonus         bool           `json:"bonus"` // 是否为赠送积分
+	ExpiresAt     *time.Time     `json:"expires_at" gorm:"index"` // 过期时间（为空则永不过期）
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// CreditHold 积分预扣记录，下载开始时冻结积分，传输成功后扣除，失败或超时后释放
+type CreditHold struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	UserID      uint           `json:"user_id" gorm:"index;not null"` // 用户ID
+	Amount      int64          `json:"amount" gorm:"not null"` // 冻结的积分数量
+	Status      string         `json:"status" gorm:"index;not null"` // 状态: held, captured, released
+	Source      string         `json:"source"` // 来源: download
+	SourceID    string         `json:"source_id"` // 来源ID（如文件路径）
+	Description string         `json:"description"` // 描述
+	FileSize    int64          `json:"file_size"` // 冻结时的文件大小，未知时为0，扣除后记录到已购文件
+	ExpiresAt   time.Time      `json:"expires_at" gorm:"index"` // 超时时间，超时未扣除的冻结会被自动释放
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// 文件积分计价方式
+const (
+	PricingFlat  = "flat"
+	PricingPerMB = "per_mb"
+	PricingPerGB = "per_gb"
+)
+
+// 积分预扣状态
+const (
+	CreditHoldHeld     = "held"
+	CreditHoldCaptured = "captured"
+	CreditHoldReleased = "released"
+)
+
 // FileCreditsConfig 文件积分配置
 type FileCreditsConfig struct {
 	ID          uint           `json:"id" gorm:"primaryKey"`
 	Path        string         `json:"path" gorm:"uniqueIndex;not null"` // 文件或文件夹路径
 	IsFolder    bool           `json:"is_folder" gorm:"default:false"` // 是否为文件夹配置
-	Credits     int64          `json:"credits" gorm:"not null"` // 所需积分
+	Credits     int64          `json:"credits" gorm:"not null"` // 所需积分，按大小计价时为每MB或每GB的积分
+	PreviewCredits int64       `json:"preview_credits"` // 在线预览/串流所需积分，计价方式同下载，0 表示预览免费
+	PricingMode string         `json:"pricing_mode" gorm:"default:'flat'"` // 计价方式: flat, per_mb, per_gb
 	Inheritable bool           `json:"inheritable" gorm:"default:true"` // 子文件是否继承此配置
 	Enabled     bool           `json:"enabled" gorm:"default:true"` // 是否启用
+	Orphaned    bool           `json:"orphaned" gorm:"index"` // 路径对应的文件已被删除
 	CreatedBy   uint           `json:"created_by" gorm:"not null"` // 创建者ID
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
@@ -55,9 +113,13 @@ type FileCreditsConfig struct {
 type RedeemCode struct {
 	ID          uint           `json:"id" gorm:"primaryKey"`
 	Code        string         `json:"code" gorm:"uniqueIndex;not null"` // 兑换码
+	BatchNo     string         `json:"batch_no" gorm:"index"` // 生成批次号
 	Credits     int64          `json:"credits" gorm:"not null"` // 积分数量
 	MaxUses     int            `json:"max_uses" gorm:"default:1"` // 最大使用次数
 	UsedCount   int            `json:"used_count" gorm:"default:0"` // 已使用次数
+	MaxUsesPerUser int         `json:"max_uses_per_user"` // 每个用户的最大使用次数，0 表示不限制
+	RewardType    string       `json:"reward_type" gorm:"size:16;default:credits"` // 奖励类型: credits, vip_days, file_unlock
+	RewardPayload string       `json:"reward_payload"` // 奖励内容: vip_days 为天数，file_unlock 为文件路径
 	Enabled     bool           `json:"enabled" gorm:"default:true"` // 是否启用
 	ExpiresAt   *time.Time     `json:"expires_at"` // 过期时间（可为空）
 	CreatedBy   uint           `json:"created_by" gorm:"not null"` // 创建者ID
@@ -88,19 +150,33 @@ type PaymentOrder struct {
 	OrderNo       string         `json:"order_no" gorm:"uniqueIndex;not null"` // 订单号
 	UserID        uint           `json:"user_id" gorm:"index;not null"` // 用户ID
 	Credits       int64          `json:"credits" gorm:"not null"` // 购买积分数量
-	Amount        int64          `json:"amount" gorm:"not null"` // 支付金额（分）
-	Currency      string         `json:"currency" gorm:"default:'CNY'"` // 货币类型
+	PlanID        uint           `json:"plan_id,omitempty" gorm:"index"` // 购买的会员套餐ID，为0表示购买积分
+	CouponCode    string         `json:"coupon_code,omitempty" gorm:"index"` // 使用的优惠码
+	Discount      int64          `json:"discount,omitempty"` // 优惠减免的金额（最小货币单位），Money 为减免后的实付金额
+	Money                        // 支付金额（amount 为最小货币单位）及货币类型
 	PaymentMethod string         `json:"payment_method"` // 支付方式
 	Status        string         `json:"status" gorm:"default:'pending'"` // 订单状态: pending, paid, failed, cancelled
 	PaidAt        *time.Time     `json:"paid_at"` // 支付时间
 	ExpiresAt     time.Time      `json:"expires_at"` // 订单过期时间
 	PaymentData   string         `json:"payment_data" gorm:"type:text"` // 支付相关数据（JSON格式）
+	ShareToken    string         `json:"share_token,omitempty" gorm:"index"` // 访客通过分享链接购买单次下载时的分享令牌，此时 UserID 为访客用户
+	ClaimToken    string         `json:"-" gorm:"index"` // 访客凭此令牌查询订单并领取下载令牌
+	DownloadTokenID uint         `json:"-"` // 访客订单支付后签发的下载令牌ID
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
 	User          *User          `json:"user,omitempty" gorm:"foreignKey:UserID"`
 }
 
+// 支付订单状态
+const (
+	PaymentOrderPending   = "pending"
+	PaymentOrderPaid      = "paid"
+	PaymentOrderFailed    = "failed"
+	PaymentOrderCancelled = "cancelled"
+	PaymentOrderExpired   = "expired"
+)
+
 // TableName 设置表名
 func (UserCredits) TableName() string {
 	return "x_user_credits"
@@ -110,6 +186,14 @@ func (CreditTransaction) TableName() string {
 	return "x_credit_transactions"
 }
 
+func (CreditLot) TableName() string {
+	return "x_credit_lots"
+}
+
+func (CreditHold) TableName() string {
+	return "x_credit_holds"
+}
+
 func (FileCreditsConfig) TableName() string {
 	return "x_file_credits_configs"
 }
@@ -126,6 +210,65 @@ func (PaymentOrder) TableName() string {
 	return "x_payment_orders"
 }
 
+// Available 可用积分，即余额减去冻结中的积分
+func (uc *UserCredits) Available() int64 {
+	return uc.Balance - uc.Held
+}
+
+// Purchased 返回余额中购买积分的部分
+func (uc *UserCredits) Purchased() int64 {
+	return uc.Balance - uc.Bonus
+}
+
+// MarshalJSON 在输出中同时给出购买积分和赠送积分两个余额
+func (uc UserCredits) MarshalJSON() ([]byte, error) {
+	type userCredits UserCredits
+	return json.Marshal(struct {
+		userCredits
+		Purchased int64 `json:"purchased"`
+	}{userCredits(uc), uc.Purchased()})
+}
+
+// 文件计费的访问方式
+const (
+	CreditsActionDownload = "download" // 完整下载
+	CreditsActionPreview  = "preview"  // 在线预览/串流
+)
+
+// Cost 按计价方式计算指定大小的文件所需积分，按大小计价时不足1MB或1GB的部分按1计
+func (fc *FileCreditsConfig) Cost(size int64) int64 {
+	return fc.cost(fc.Credits, size)
+}
+
+// CostFor 计算指定访问方式下文件所需积分
+func (fc *FileCreditsConfig) CostFor(action string, size int64) int64 {
+	if action == CreditsActionPreview {
+		return fc.cost(fc.PreviewCredits, size)
+	}
+	return fc.cost(fc.Credits, size)
+}
+
+func (fc *FileCreditsConfig) cost(credits int64, size int64) int64 {
+	var unit int64
+	switch fc.PricingMode {
+	case PricingPerMB:
+		unit = 1024 * 1024
+	case PricingPerGB:
+		unit = 1024 * 1024 * 1024
+	default:
+		return credits
+	}
+	if size <= 0 {
+		return 0
+	}
+	return (size + unit - 1) / unit * credits
+}
+
+// IsSizeBased 是否按文件大小计价
+func (fc *FileCreditsConfig) IsSizeBased() bool {
+	return fc.PricingMode == PricingPerMB || fc.PricingMode == PricingPerGB
+}
+
 // IsExpired 检查兑换码是否过期
 func (rc *RedeemCode) IsExpired() bool {
 	if rc.ExpiresAt == nil {
@@ -134,6 +277,13 @@ func (rc *RedeemCode) IsExpired() bool {
 	return time.Now().After(*rc.ExpiresAt)
 }
 
+// 兑换码奖励类型
+const (
+	RedeemRewardCredits    = "credits"     // 积分，数量为 Credits
+	RedeemRewardVipDays    = "vip_days"    // 会员天数，RewardPayload 为天数
+	RedeemRewardFileUnlock = "file_unlock" // 永久免费下载指定文件，RewardPayload 为文件路径
+)
+
 // CanUse 检查兑换码是否可用
 func (rc *RedeemCode) CanUse() bool {
 	return rc.Enabled && !rc.IsExpired() && rc.UsedCount < rc.MaxUses
@@ -146,5 +296,23 @@ func (po *PaymentOrder) IsExpired() bool {
 
 // IsPaid 检查订单是否已支付
 func (po *PaymentOrder) IsPaid() bool {
-	return po.Status == "paid"
-}
\ No newline at end of file
+	return po.Status == PaymentOrderPaid
+}
+
+// 积分交易记录按金额正负筛选
+const (
+	AmountPositive = "positive" // 获得
+	AmountNegative = "negative" // 消费
+)
+
+// CreditTransactionFilter 积分交易记录筛选条件，零值表示不限
+type CreditTransactionFilter struct {
+	UserID  uint       `json:"user_id"`
+	Type    string     `json:"type"`
+	Source  string     `json:"source"`
+	Start   *time.Time `json:"start"`   // 起始时间（含）
+	End     *time.Time `json:"end"`     // 结束时间（不含）
+	Sign    string     `json:"sign"`    // 金额正负: positive, negative
+	Keyword string     `json:"keyword"` // 按描述模糊匹配
+	Path    string     `json:"path"`    // 关联的文件路径
+}
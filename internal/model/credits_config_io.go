@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+// CreditsConfigDocumentVersion 积分配置导出文档的格式版本
+const CreditsConfigDocumentVersion = 1
+
+// 导入积分配置时与已有配置冲突的处理方式
+const (
+	ImportConflictSkip      = "skip"      // 保留已有配置
+	ImportConflictOverwrite = "overwrite" // 覆盖已有配置
+	ImportConflictFail      = "fail"      // 存在冲突时不导入任何配置
+)
+
+// CreditsConfigEntry 导出文档中的一条文件积分配置，不包含实例相关的ID和创建者
+type CreditsConfigEntry struct {
+	Path           string `json:"path"`
+	IsFolder       bool   `json:"is_folder"`
+	Credits        int64  `json:"credits"`
+	PreviewCredits int64  `json:"preview_credits"`
+	PricingMode    string `json:"pricing_mode"`
+	Inheritable    bool   `json:"inheritable"`
+	Enabled        bool   `json:"enabled"`
+}
+
+// CreditsConfigDocument 文件积分配置导出文档
+type CreditsConfigDocument struct {
+	Version    int                  `json:"version"`
+	ExportedAt time.Time            `json:"exported_at"`
+	Configs    []CreditsConfigEntry `json:"configs"`
+}
+
+// CreditsConfigImportResult 积分配置导入结果，DryRun 为 true 时只统计不写入
+type CreditsConfigImportResult struct {
+	DryRun    bool     `json:"dry_run"`
+	Created   int      `json:"created"`
+	Updated   int      `json:"updated"`
+	Unchanged int      `json:"unchanged"`
+	Skipped   int      `json:"skipped"`
+	Conflicts []string `json:"conflicts"` // 与已有配置不同的路径
+}
+
+// ToEntry 转换为导出文档中的配置
+func (c *FileCreditsConfig) ToEntry() CreditsConfigEntry {
+	return CreditsConfigEntry{
+		Path:           c.Path,
+		IsFolder:       c.IsFolder,
+		Credits:        c.Credits,
+		PreviewCredits: c.PreviewCredits,
+		PricingMode:    c.PricingMode,
+		Inheritable:    c.Inheritable,
+		Enabled:        c.Enabled,
+	}
+}
@@ -0,0 +1,64 @@
+package model
+
+import "strings"
+
+// CreditsConfigTree 按路径分段组织的文件夹积分配置前缀树，用于查找路径最近的可继承父目录配置，
+// 查找的复杂度只与路径深度有关。树在创建后只读，可以被多个协程同时使用
+type CreditsConfigTree struct {
+	root creditsConfigNode
+}
+
+type creditsConfigNode struct {
+	children map[string]*creditsConfigNode
+	config   *FileCreditsConfig
+}
+
+// NewCreditsConfigTree 使用文件夹积分配置创建前缀树
+func NewCreditsConfigTree(configs []FileCreditsConfig) *CreditsConfigTree {
+	tree := &CreditsConfigTree{}
+	for i := range configs {
+		node := &tree.root
+		for _, segment := range splitConfigPath(configs[i].Path) {
+			child, ok := node.children[segment]
+			if !ok {
+				if node.children == nil {
+					node.children = make(map[string]*creditsConfigNode)
+				}
+				child = &creditsConfigNode{}
+				node.children[segment] = child
+			}
+			node = child
+		}
+		node.config = &configs[i]
+	}
+	return tree
+}
+
+// Longest 返回路径本身或其最近的父目录上的配置副本，没有时返回 nil
+func (t *CreditsConfigTree) Longest(path string) *FileCreditsConfig {
+	node := &t.root
+	found := node.config
+	for _, segment := range splitConfigPath(path) {
+		child, ok := node.children[segment]
+		if !ok {
+			break
+		}
+		node = child
+		if node.config != nil {
+			found = node.config
+		}
+	}
+	if found == nil {
+		return nil
+	}
+	config := *found
+	return &config
+}
+
+func splitConfigPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
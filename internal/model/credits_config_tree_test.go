@@ -0,0 +1,31 @@
+package model
+
+import "testing"
+
+func TestCreditsConfigTreeLongest(t *testing.T) {
+	tree := NewCreditsConfigTree([]FileCreditsConfig{
+		{Path: "/", Credits: 1},
+		{Path: "/movies", Credits: 2},
+		{Path: "/movies/hd", Credits: 3},
+	})
+	cases := []struct {
+		path string
+		want int64
+	}{
+		{"/readme.md", 1},
+		{"/movies/a.mkv", 2},
+		{"/movies/hd/b/c.mkv", 3},
+		{"/movies_old/a.mkv", 1},
+		{"/movies/hdr/a.mkv", 2},
+		{"/movies/hd", 3},
+	}
+	for _, c := range cases {
+		config := tree.Longest(c.path)
+		if config == nil || config.Credits != c.want {
+			t.Errorf("%s: got %+v, want credits %d", c.path, config, c.want)
+		}
+	}
+	if config := NewCreditsConfigTree(nil).Longest("/a"); config != nil {
+		t.Errorf("expected no config in an empty tree, got %+v", config)
+	}
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// FileCreditsExemption 文件积分配置的豁免名单，名单中的用户或定价组成员下载、预览该配置覆盖的文件时免费。
+// UserID 和 GroupID 只设置其中一个
+type FileCreditsExemption struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ConfigID  uint      `json:"config_id" gorm:"index;not null"` // 文件积分配置ID
+	UserID    uint      `json:"user_id" gorm:"index"`            // 豁免的用户ID
+	GroupID   uint      `json:"group_id" gorm:"index"`           // 豁免的定价组ID
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (FileCreditsExemption) TableName() string {
+	return "x_file_credits_exemptions"
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// CreditLedgerIssue 积分账户余额与交易记录不一致的记录，由账本校验任务生成
+type CreditLedgerIssue struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"uniqueIndex;not null"` // 用户ID
+	Balance    int64      `json:"balance"`                             // 账户记录的余额
+	Expected   int64      `json:"expected"`                            // 按交易记录重新计算的余额
+	Resolved   bool       `json:"resolved" gorm:"index"`               // 是否已修复
+	ResolvedBy uint       `json:"resolved_by"`                         // 修复的管理员ID
+	ResolvedAt *time.Time `json:"resolved_at"`                         // 修复时间
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func (CreditLedgerIssue) TableName() string {
+	return "x_credit_ledger_issues"
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// TransactionMetadata 积分交易记录的结构化元数据，以 JSON 保存在 CreditTransaction.Metadata 中
+type TransactionMetadata struct {
+	ClientIP  string            `json:"client_ip,omitempty"`  // 客户端IP
+	UserAgent string            `json:"user_agent,omitempty"` // 客户端 User-Agent
+	FilePath  string            `json:"file_path,omitempty"`  // 关联的文件路径
+	FileSize  int64             `json:"file_size,omitempty"`  // 文件大小（字节）
+	OrderNo   string            `json:"order_no,omitempty"`   // 关联的订单号
+	HoldID    uint              `json:"hold_id,omitempty"`    // 关联的积分冻结ID
+	AdminID   uint              `json:"admin_id,omitempty"`   // 操作的管理员ID
+	Reason    string            `json:"reason,omitempty"`     // 操作原因
+	Extra     map[string]string `json:"extra,omitempty"`      // 其他信息
+}
+
+// SetMetadata 序列化元数据，并把文件路径同步到可查询的 Path 字段
+func (t *CreditTransaction) SetMetadata(meta *TransactionMetadata) error {
+	if meta == nil {
+		t.Metadata = ""
+		return nil
+	}
+	data, err := utils.Json.MarshalToString(meta)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	t.Metadata = data
+	if meta.FilePath != "" {
+		t.Path = meta.FilePath
+	}
+	return nil
+}
+
+// GetMetadata 解析元数据，没有元数据时返回空结构
+func (t *CreditTransaction) GetMetadata() (*TransactionMetadata, error) {
+	meta := &TransactionMetadata{}
+	if t.Metadata == "" {
+		return meta, nil
+	}
+	if err := utils.Json.UnmarshalFromString(t.Metadata, meta); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return meta, nil
+}
@@ -0,0 +1,30 @@
+package model
+
+// 积分统计的时间粒度
+const (
+	StatsPeriodDay   = "day"
+	StatsPeriodWeek  = "week"
+	StatsPeriodMonth = "month"
+)
+
+// CreditStatsBucket 一个统计周期内的积分收支汇总
+type CreditStatsBucket struct {
+	Start string `json:"start"` // 周期开始日期，格式 2006-01-02，周从周一开始
+	Earn  int64  `json:"earn"`  // 获得的积分
+	Spend int64  `json:"spend"` // 消费的积分（正数）
+	Count int64  `json:"count"` // 交易笔数
+}
+
+// 积分排行的排序依据
+const (
+	RankBySpend   = "spend"
+	RankByEarn    = "earn"
+	RankByBalance = "balance"
+)
+
+// CreditRankEntry 积分排行中的一项
+type CreditRankEntry struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	Value    int64  `json:"value"` // 统计期内的消费、获得积分或当前余额
+}
@@ -0,0 +1,47 @@
+package model
+
+import "testing"
+
+func TestFileCreditsConfigCost(t *testing.T) {
+	const mb = 1024 * 1024
+	cases := []struct {
+		mode string
+		size int64
+		want int64
+	}{
+		{PricingFlat, 10 * mb, 3},
+		{PricingPerMB, 10 * mb, 30},
+		{PricingPerMB, 10*mb + 1, 33},
+		{PricingPerGB, 10 * mb, 3},
+		{PricingPerGB, 0, 0},
+	}
+	for _, c := range cases {
+		cfg := FileCreditsConfig{Credits: 3, PricingMode: c.mode}
+		if got := cfg.Cost(c.size); got != c.want {
+			t.Errorf("%s cost of %d bytes: got %d, want %d", c.mode, c.size, got, c.want)
+		}
+		cfg.PreviewCredits = 1
+		if got := cfg.CostFor(CreditsActionPreview, c.size); got*3 != c.want {
+			t.Errorf("%s preview cost of %d bytes: got %d, want %d", c.mode, c.size, got, c.want/3)
+		}
+	}
+}
+
+func TestTransactionMetadata(t *testing.T) {
+	tx := CreditTransaction{}
+	meta, err := tx.GetMetadata()
+	if err != nil || meta.FilePath != "" {
+		t.Fatalf("expected empty metadata, got %+v, %v", meta, err)
+	}
+	err = tx.SetMetadata(&TransactionMetadata{ClientIP: "1.2.3.4", FilePath: "/a/b.zip", FileSize: 42})
+	if err != nil {
+		t.Fatalf("failed to set metadata: %+v", err)
+	}
+	if tx.Path != "/a/b.zip" {
+		t.Errorf("expected path to be synced, got %q", tx.Path)
+	}
+	meta, err = tx.GetMetadata()
+	if err != nil || meta.ClientIP != "1.2.3.4" || meta.FileSize != 42 {
+		t.Errorf("unexpected metadata: %+v, %v", meta, err)
+	}
+}
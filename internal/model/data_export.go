@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// DataExport 用户请求导出的个人数据压缩包
+type DataExport struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	UserID      uint       `json:"user_id" gorm:"index"`
+	Token       string     `json:"-" gorm:"uniqueIndex"` // 下载令牌，同时作为压缩包的文件名
+	Status      string     `json:"status" gorm:"index"`  // 导出状态: pending, ready, failed
+	Size        int64      `json:"size"`                 // 压缩包大小（字节）
+	Error       string     `json:"error,omitempty"`      // 导出失败的原因
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   time.Time  `json:"expires_at" gorm:"index"` // 到期后压缩包被删除
+}
+
+// TableName 设置表名
+func (DataExport) TableName() string {
+	return "x_data_exports"
+}
+
+// 数据导出状态
+const (
+	DataExportPending = "pending"
+	DataExportReady   = "ready"
+	DataExportFailed  = "failed"
+)
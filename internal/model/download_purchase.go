@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// DownloadPurchase 用户已付费下载的文件，有效期内重复下载不再扣积分
+type DownloadPurchase struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	UserID      uint      `json:"user_id" gorm:"uniqueIndex:idx_download_purchase;not null"` // 用户ID
+	Path        string    `json:"path" gorm:"uniqueIndex:idx_download_purchase;not null"`    // 文件路径
+	Credits     int64     `json:"credits"`                                                   // 最近一次支付的积分
+	Size        int64     `json:"size"`                                                      // 付费时的文件大小
+	Storage     string    `json:"storage"`                                                   // 付费时文件所在存储的挂载路径
+	PurchasedAt time.Time `json:"purchased_at" gorm:"index"`                                 // 最近一次付费时间
+	Unlocked    bool      `json:"unlocked"`                                                  // 通过兑换码永久解锁
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (DownloadPurchase) TableName() string {
+	return "x_download_purchases"
+}
+
+// ValidAt 检查购买在 t 时刻是否仍有效，window 小于0表示永久有效，永久解锁的文件不受 window 限制
+func (p *DownloadPurchase) ValidAt(t time.Time, window time.Duration) bool {
+	if p.Unlocked || window < 0 {
+		return true
+	}
+	return t.Before(p.PurchasedAt.Add(window))
+}
@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// DownloadQuota 每日免费下载额度，0 表示该项不限
+type DownloadQuota struct {
+	Downloads int64 `json:"downloads"` // 每日免费下载次数
+	Bytes     int64 `json:"bytes"`     // 每日免费下载流量（字节）
+}
+
+// DownloadQuotaUsage 用户每日免费额度使用量，按天滚动计数
+type DownloadQuotaUsage struct {
+	UserID    uint      `json:"user_id" gorm:"primaryKey"`     // 用户ID
+	Day       string    `json:"day" gorm:"primaryKey;size:10"` // 日期，格式 2006-01-02
+	Downloads int64     `json:"downloads"`                     // 已使用的下载次数
+	Bytes     int64     `json:"bytes"`                         // 已使用的下载流量（字节）
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (DownloadQuotaUsage) TableName() string {
+	return "x_download_quota_usages"
+}
+
+// Enabled 是否设置了免费额度
+func (q DownloadQuota) Enabled() bool {
+	return q.Downloads > 0 || q.Bytes > 0
+}
+
+// Allows 检查剩余额度是否足够下载 size 字节的文件
+func (q DownloadQuota) Allows(usage *DownloadQuotaUsage, size int64) bool {
+	if !q.Enabled() {
+		return false
+	}
+	if q.Downloads > 0 && usage.Downloads >= q.Downloads {
+		return false
+	}
+	if q.Bytes > 0 && usage.Bytes+size > q.Bytes {
+		return false
+	}
+	return true
+}
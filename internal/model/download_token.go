@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// DownloadToken 预先扣费的一次性下载令牌，下载链接凭令牌访问文件，不再检查积分
+type DownloadToken struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"index"`
+	Token     string    `json:"-" gorm:"uniqueIndex"`
+	Path      string    `json:"path"`
+	Credits   int64     `json:"credits"`  // 签发时扣除的积分
+	MaxUses   int       `json:"max_uses"` // 可使用的次数
+	Uses      int       `json:"uses"`     // 已使用的次数
+	Revoked   bool      `json:"revoked"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 设置表名
+func (DownloadToken) TableName() string {
+	return "x_download_tokens"
+}
+
+// Usable 检查令牌在 t 时刻是否仍可使用
+func (t *DownloadToken) Usable(at time.Time) bool {
+	return !t.Revoked && t.Uses < t.MaxUses && at.Before(t.ExpiresAt)
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// EmailChange 用户更换邮箱的审计记录
+type EmailChange struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"index"`
+	OldEmail  string    `json:"old_email"` // 原邮箱，账户此前没有邮箱时为空
+	NewEmail  string    `json:"new_email"`
+	IP        string    `json:"ip"` // 发起更换的客户端地址
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 设置表名
+func (EmailChange) TableName() string {
+	return "x_email_changes"
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// FileShare 文件分享链接，访客凭分享令牌查看文件，付费文件可由访客单独购买一次下载
+type FileShare struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	UserID    uint           `json:"user_id" gorm:"index"` // 创建分享的用户
+	Token     string         `json:"token" gorm:"uniqueIndex"`
+	Path      string         `json:"path"`
+	ExpiresAt *time.Time     `json:"expires_at"` // 为空表示不过期
+	CreatedAt time.Time      `json:"created_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"` // 删除后仍保留，已支付的访客订单按原路径签发下载令牌
+}
+
+// TableName 设置表名
+func (FileShare) TableName() string {
+	return "x_file_shares"
+}
+
+// IsExpired 检查分享链接是否已过期
+func (s *FileShare) IsExpired() bool {
+	return s.ExpiresAt != nil && time.Now().After(*s.ExpiresAt)
+}
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// InviteCode 邀请码，邀请注册模式下注册时必须填写
+type InviteCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	Code      string     `json:"code" gorm:"uniqueIndex;not null"` // 邀请码
+	CreatedBy uint       `json:"created_by" gorm:"index"`          // 创建者，即邀请人
+	MaxUses   int        `json:"max_uses" gorm:"default:1"`        // 最大使用次数
+	UsedCount int        `json:"used_count" gorm:"default:0"`      // 已使用次数
+	Enabled   bool       `json:"enabled" gorm:"default:true"`      // 是否启用
+	Note      string     `json:"note"`                             // 备注
+	ExpiresAt *time.Time `json:"expires_at"`                       // 过期时间（可为空）
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Creator   *User      `json:"creator,omitempty" gorm:"foreignKey:CreatedBy"`
+}
+
+// Invitation 邀请关系，注册通过后记录邀请人和被邀请的用户
+type Invitation struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	InviteCodeID uint      `json:"invite_code_id" gorm:"index"`            // 使用的邀请码
+	InviterID    uint      `json:"inviter_id" gorm:"index"`                // 邀请人
+	InviteeID    uint      `json:"invitee_id" gorm:"uniqueIndex;not null"` // 被邀请的用户
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (InviteCode) TableName() string {
+	return "x_invite_codes"
+}
+
+func (Invitation) TableName() string {
+	return "x_invitations"
+}
+
+// CanUse 检查邀请码是否启用、未过期且未用完
+func (c *InviteCode) CanUse() bool {
+	return c.Enabled && c.UsedCount < c.MaxUses && (c.ExpiresAt == nil || time.Now().Before(*c.ExpiresAt))
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// 邮件投递状态
+const (
+	MailPending = "pending" // 等待发送
+	MailSent    = "sent"    // 已交给邮件服务
+	MailFailed  = "failed"  // 发送失败，等待重试
+	MailDead    = "dead"    // 重试次数用完，进入死信列表
+)
+
+// MailDelivery 邮件发送记录，保存渲染后的邮件以便重试
+type MailDelivery struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	Provider    string     `json:"provider"`                    // 发送时使用的邮件服务
+	Template    string     `json:"template" gorm:"index"`       // 邮件模板
+	Sender      string     `json:"sender"`                      // 发件人
+	Recipient   string     `json:"recipient" gorm:"index"`      // 收件人
+	Subject     string     `json:"subject"`                     // 主题
+	Text        string     `json:"-" gorm:"type:text"`          // 纯文本正文
+	HTML        string     `json:"-" gorm:"type:text"`          // HTML 正文
+	Status      string     `json:"status" gorm:"index"`         // 投递状态
+	MessageID   string     `json:"message_id"`                  // 邮件服务返回的消息ID
+	Attempts    int        `json:"attempts"`                    // 已尝试次数
+	LastError   string     `json:"last_error" gorm:"type:text"` // 最近一次失败原因
+	NextRetryAt *time.Time `json:"next_retry_at" gorm:"index"`  // 下次重试时间
+	SentAt      *time.Time `json:"sent_at"`                     // 发送成功时间
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (MailDelivery) TableName() string {
+	return "x_mail_deliveries"
+}
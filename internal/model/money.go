@@ -0,0 +1,92 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const DefaultCurrency = "CNY"
+
+// currencyExponents lists currencies whose minor unit is not 1/100
+var currencyExponents = map[string]int{
+	"JPY": 0,
+	"KRW": 0,
+}
+
+// Money 金额，Amount 始终为最小货币单位（如人民币的分），避免元/分换算错误
+type Money struct {
+	Amount   int64  `json:"amount" gorm:"not null"`        // 金额（最小货币单位）
+	Currency string `json:"currency" gorm:"default:'CNY'"` // 货币类型
+}
+
+// NewMoney creates a Money from an amount in minor units
+func NewMoney(minor int64, currency string) Money {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	return Money{Amount: minor, Currency: strings.ToUpper(currency)}
+}
+
+// ParseMoney parses a decimal amount in major units such as "12.34" returned
+// by payment gateways. It rejects amounts with more decimals than the currency allows.
+func ParseMoney(major string, currency string) (Money, error) {
+	m := NewMoney(0, currency)
+	major = strings.TrimSpace(major)
+	if major == "" {
+		return m, errors.New("empty amount")
+	}
+	neg := strings.HasPrefix(major, "-")
+	major = strings.TrimPrefix(major, "-")
+	intPart, fracPart, _ := strings.Cut(major, ".")
+	exp := m.exponent()
+	if len(fracPart) > exp {
+		return m, errors.Errorf("invalid amount %s for %s", major, m.Currency)
+	}
+	fracPart += strings.Repeat("0", exp-len(fracPart))
+	v, err := strconv.ParseInt(intPart+fracPart, 10, 64)
+	if err != nil {
+		return m, errors.Wrapf(err, "invalid amount %s", major)
+	}
+	if neg {
+		v = -v
+	}
+	m.Amount = v
+	return m, nil
+}
+
+func (m Money) exponent() int {
+	if e, ok := currencyExponents[m.Currency]; ok {
+		return e
+	}
+	return 2
+}
+
+// Minor returns the amount in minor units
+func (m Money) Minor() int64 {
+	return m.Amount
+}
+
+// MajorString formats the amount in major units, e.g. 1234 CNY -> "12.34"
+func (m Money) MajorString() string {
+	exp := m.exponent()
+	if exp == 0 {
+		return strconv.FormatInt(m.Amount, 10)
+	}
+	unit := int64(math.Pow10(exp))
+	sign := ""
+	v := m.Amount
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%0*d", sign, v/unit, exp, v%unit)
+}
+
+// Equal reports whether two amounts are identical, including the currency
+func (m Money) Equal(o Money) bool {
+	return m.Amount == o.Amount && strings.EqualFold(m.Currency, o.Currency)
+}
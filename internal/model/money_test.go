@@ -0,0 +1,52 @@
+package model
+
+import "testing"
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in       string
+		currency string
+		want     int64
+		isErr    bool
+	}{
+		{in: "12.34", currency: "CNY", want: 1234},
+		{in: "0.01", currency: "CNY", want: 1},
+		{in: "5", currency: "CNY", want: 500},
+		{in: "5.5", currency: "CNY", want: 550},
+		{in: "-1.20", currency: "CNY", want: -120},
+		{in: "300", currency: "JPY", want: 300},
+		{in: "1.234", currency: "CNY", isErr: true},
+		{in: "1.5", currency: "JPY", isErr: true},
+		{in: "", currency: "CNY", isErr: true},
+		{in: "abc", currency: "CNY", isErr: true},
+	}
+	for _, tt := range tests {
+		m, err := ParseMoney(tt.in, tt.currency)
+		if tt.isErr {
+			if err == nil {
+				t.Errorf("ParseMoney(%q) expected error", tt.in)
+			}
+			continue
+		}
+		if err != nil || m.Minor() != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, %v; want %d", tt.in, m.Minor(), err, tt.want)
+		}
+	}
+}
+
+func TestMoneyMajorString(t *testing.T) {
+	tests := map[string]Money{
+		"12.34": NewMoney(1234, "CNY"),
+		"0.05":  NewMoney(5, "cny"),
+		"-1.20": NewMoney(-120, "CNY"),
+		"300":   NewMoney(300, "JPY"),
+	}
+	for want, m := range tests {
+		if got := m.MajorString(); got != want {
+			t.Errorf("MajorString() = %s; want %s", got, want)
+		}
+	}
+	if !NewMoney(100, "cny").Equal(NewMoney(100, "CNY")) || NewMoney(100, "CNY").Equal(NewMoney(100, "USD")) {
+		t.Error("Equal should compare amount and currency")
+	}
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// OtpBackupCode is a one time code that can replace a TOTP code when the
+// authenticator is not at hand
+type OtpBackupCode struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"index"`
+	CodeHash  string    `json:"-"`
+	Used      bool      `json:"used"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (OtpBackupCode) TableName() string {
+	return "x_otp_backup_codes"
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PricingGroup 定价组，按组对下载所需积分打折、加价或免除
+type PricingGroup struct {
+	ID                 uint           `json:"id" gorm:"primaryKey"`
+	Name               string         `json:"name" gorm:"uniqueIndex;not null"` // 组名，如 free, member, partner
+	Multiplier         float64        `json:"multiplier" gorm:"default:1"`      // 价格倍率
+	Exempt             bool           `json:"exempt"`                           // 是否免除下载积分
+	FreeDailyDownloads int64          `json:"free_daily_downloads"`             // 每日免费下载次数，0 表示使用全局设置
+	FreeDailyGB        int64          `json:"free_daily_gb"`                    // 每日免费下载流量（GB），0 表示使用全局设置
+	Description        string         `json:"description"`                      // 描述
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// UserPricingGroup 用户所属的定价组
+type UserPricingGroup struct {
+	UserID    uint      `json:"user_id" gorm:"primaryKey"`      // 用户ID
+	GroupID   uint      `json:"group_id" gorm:"index;not null"` // 定价组ID
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (PricingGroup) TableName() string {
+	return "x_pricing_groups"
+}
+
+func (UserPricingGroup) TableName() string {
+	return "x_user_pricing_groups"
+}
+
+// Apply 计算定价组下的实际价格，按倍率向上取整
+func (g *PricingGroup) Apply(credits int64) int64 {
+	if g.Exempt {
+		return 0
+	}
+	return int64(math.Ceil(float64(credits) * g.Multiplier))
+}
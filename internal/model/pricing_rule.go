@@ -0,0 +1,122 @@
+package model
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// 路径计价规则的匹配方式
+const (
+	PricingRuleGlob  = "glob"  // 通配符，* 匹配单级路径，** 匹配任意多级路径
+	PricingRuleRegex = "regex" // 正则表达式
+)
+
+// PricingRule 路径模式计价规则，按优先级从小到大依次匹配，如 /movies/**/*.mkv = 50
+type PricingRule struct {
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	Pattern        string         `json:"pattern" gorm:"not null"`            // 路径模式
+	MatchType      string         `json:"match_type" gorm:"default:'glob'"`   // 匹配方式: glob, regex
+	Priority       int            `json:"priority" gorm:"index"`              // 优先级，数值越小越先匹配
+	Credits        int64          `json:"credits" gorm:"not null"`            // 所需积分，按大小计价时为每MB或每GB的积分
+	PreviewCredits int64          `json:"preview_credits"`                    // 在线预览/串流所需积分，0 表示预览免费
+	PricingMode    string         `json:"pricing_mode" gorm:"default:'flat'"` // 计价方式: flat, per_mb, per_gb
+	Enabled        bool           `json:"enabled"`                            // 是否启用
+	Description    string         `json:"description"`                        // 描述
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
+
+	re *regexp.Regexp
+}
+
+func (PricingRule) TableName() string {
+	return "x_pricing_rules"
+}
+
+// Compile 编译路径模式，匹配前必须调用
+func (r *PricingRule) Compile() error {
+	expr := r.Pattern
+	switch r.MatchType {
+	case PricingRuleRegex:
+	case PricingRuleGlob, "":
+		expr = globToRegexp(r.Pattern)
+	default:
+		return errors.Errorf("invalid match type: %s", r.MatchType)
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	r.re = re
+	return nil
+}
+
+// Match 检查路径是否匹配规则
+func (r *PricingRule) Match(path string) bool {
+	return r.re != nil && r.re.MatchString(path)
+}
+
+// ToConfig 转换为等价的文件积分配置，用于计算费用
+func (r *PricingRule) ToConfig() *FileCreditsConfig {
+	return &FileCreditsConfig{
+		Path:           r.Pattern,
+		Credits:        r.Credits,
+		PreviewCredits: r.PreviewCredits,
+		PricingMode:    r.PricingMode,
+		Enabled:        true,
+	}
+}
+
+// globToRegexp 将通配符转换为正则表达式，** 匹配任意多级路径（包括零级），* 和 ? 不匹配 /
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '*' && i+1 < len(glob) && glob[i+1] == '*':
+			i++
+			if i+1 < len(glob) && glob[i+1] == '/' {
+				// **/ 匹配零级或多级目录
+				i++
+				sb.WriteString("(?:.*/)?")
+			} else {
+				sb.WriteString(".*")
+			}
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	sb.WriteString("$")
+	return sb.String()
+}
+
+// 计价试算结果的配置来源
+const (
+	PricingSourceNone    = "none"    // 没有配置，免费
+	PricingSourceConfig  = "config"  // 精确路径配置
+	PricingSourceRule    = "rule"    // 路径计价规则
+	PricingSourceInherit = "inherit" // 继承父目录配置
+)
+
+// PricingDryRun 文件计价试算结果
+type PricingDryRun struct {
+	Path        string `json:"path"`
+	Source      string `json:"source"`                 // 配置来源: none, config, rule, inherit
+	ConfigID    uint   `json:"config_id,omitempty"`    // 命中的文件积分配置ID
+	RuleID      uint   `json:"rule_id,omitempty"`      // 命中的计价规则ID
+	Pattern     string `json:"pattern,omitempty"`      // 命中的配置路径或规则模式
+	PricingMode string `json:"pricing_mode,omitempty"` // 计价方式
+	Credits     int64  `json:"credits"`                // 配置的积分
+	Size        int64  `json:"size"`                   // 计价使用的文件大小，小于0表示未获取
+	Cost        int64  `json:"cost"`                   // 下载基础价格
+	PreviewCost int64  `json:"preview_cost"`           // 预览基础价格
+}
@@ -0,0 +1,30 @@
+package model
+
+import "testing"
+
+func TestPricingRuleMatch(t *testing.T) {
+	cases := []struct {
+		matchType string
+		pattern   string
+		path      string
+		want      bool
+	}{
+		{PricingRuleGlob, "/movies/**/*.mkv", "/movies/a.mkv", true},
+		{PricingRuleGlob, "/movies/**/*.mkv", "/movies/2024/hd/a.mkv", true},
+		{PricingRuleGlob, "/movies/**/*.mkv", "/movies/a.mp4", false},
+		{PricingRuleGlob, "/movies/*.mkv", "/movies/2024/a.mkv", false},
+		{PricingRuleGlob, "/电影/?.mkv", "/电影/a.mkv", true},
+		{PricingRuleGlob, "/docs/**", "/docs/a/b.pdf", true},
+		{PricingRuleRegex, `^/music/.*\.(flac|ape)$`, "/music/x/y.flac", true},
+		{PricingRuleRegex, `^/music/.*\.(flac|ape)$`, "/music/x/y.mp3", false},
+	}
+	for _, c := range cases {
+		rule := PricingRule{Pattern: c.pattern, MatchType: c.matchType}
+		if err := rule.Compile(); err != nil {
+			t.Fatalf("failed to compile %s: %+v", c.pattern, err)
+		}
+		if got := rule.Match(c.path); got != c.want {
+			t.Errorf("%s match %s: got %v, want %v", c.pattern, c.path, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package model
+
+// UserProfile 用户资料，AvatarURL 为实际显示的头像，未设置头像时为 gravatar 地址
+type UserProfile struct {
+	Nickname  string `json:"nickname"`
+	Avatar    string `json:"avatar"`
+	Bio       string `json:"bio"`
+	AvatarURL string `json:"avatar_url"`
+}
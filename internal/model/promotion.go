@@ -0,0 +1,112 @@
+package model
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// MaxPromotionDuration 周期促销每次持续的最长时间
+const MaxPromotionDuration = 7 * 24 * 60
+
+// Promotion 促销活动，活动期间指定路径下的文件下载、预览打折或免费。
+// 在 StartsAt 和 EndsAt 之间生效，设置了 Cron 时只在每次触发后的 Duration 分钟内生效
+type Promotion struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Name        string         `json:"name" gorm:"not null"`     // 活动名称
+	Paths       string         `json:"paths" gorm:"type:text"`   // 参与活动的路径，每行一个，包括其下所有文件
+	Discount    int            `json:"discount" gorm:"not null"` // 折扣百分比，100 表示免费
+	StartsAt    *time.Time     `json:"starts_at"`                // 开始时间，为空表示立即开始
+	EndsAt      *time.Time     `json:"ends_at"`                  // 结束时间，为空表示不结束
+	Cron        string         `json:"cron"`                     // 周期触发的 cron 表达式（分 时 日 月 周），为空表示整个时间段内生效
+	Duration    int            `json:"duration"`                 // 每次触发后持续的分钟数
+	Enabled     bool           `json:"enabled"`                  // 是否启用
+	Description string         `json:"description"`              // 描述
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+
+	schedule *cron.Schedule
+	paths    []string
+}
+
+func (Promotion) TableName() string {
+	return "x_promotions"
+}
+
+// Compile 校验并解析活动的路径和 cron 表达式
+func (p *Promotion) Compile() error {
+	if p.Discount <= 0 || p.Discount > 100 {
+		return errors.New("折扣百分比必须在1到100之间")
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return errors.New("结束时间必须晚于开始时间")
+	}
+	p.paths = p.paths[:0]
+	for _, path := range strings.Split(p.Paths, "\n") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		if !strings.HasPrefix(path, "/") {
+			return errors.Errorf("路径必须以 / 开头: %s", path)
+		}
+		if path != "/" {
+			path = strings.TrimSuffix(path, "/")
+		}
+		p.paths = append(p.paths, path)
+	}
+	if len(p.paths) == 0 {
+		return errors.New("活动路径不能为空")
+	}
+	p.schedule = nil
+	if p.Cron != "" {
+		schedule, err := cron.ParseSchedule(p.Cron)
+		if err != nil {
+			return errors.WithMessage(err, "无效的 cron 表达式")
+		}
+		if p.Duration <= 0 || p.Duration > MaxPromotionDuration {
+			return errors.Errorf("持续时间必须在1到%d分钟之间", MaxPromotionDuration)
+		}
+		p.schedule = schedule
+	}
+	return nil
+}
+
+// MatchPath 检查路径是否参与活动，需要先调用 Compile
+func (p *Promotion) MatchPath(path string) bool {
+	for _, prefix := range p.paths {
+		if prefix == "/" || path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ActiveAt 检查活动在 now 时刻是否生效，需要先调用 Compile
+func (p *Promotion) ActiveAt(now time.Time) bool {
+	if !p.Enabled {
+		return false
+	}
+	if p.StartsAt != nil && now.Before(*p.StartsAt) {
+		return false
+	}
+	if p.EndsAt != nil && !now.Before(*p.EndsAt) {
+		return false
+	}
+	if p.schedule == nil {
+		return true
+	}
+	// 触发后持续 Duration 分钟，即 [触发时间, 触发时间+Duration)
+	_, ok := p.schedule.Prev(now, time.Duration(p.Duration-1)*time.Minute)
+	return ok
+}
+
+// Apply 计算打折后所需的积分，向上取整
+func (p *Promotion) Apply(credits int64) int64 {
+	return int64(math.Ceil(float64(credits) * float64(100-p.Discount) / 100))
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPromotionActiveAt(t *testing.T) {
+	at := func(s string) time.Time {
+		v, _ := time.Parse("2006-01-02 15:04", s)
+		return v
+	}
+	start, end := at("2025-01-01 00:00"), at("2025-02-01 00:00")
+	p := Promotion{Paths: "/movies/\n/music", Discount: 50, StartsAt: &start, EndsAt: &end, Cron: "0 20 * * 5", Duration: 120, Enabled: true}
+	if err := p.Compile(); err != nil {
+		t.Fatalf("failed to compile promotion: %+v", err)
+	}
+	cases := []struct {
+		at   string
+		want bool
+	}{
+		{"2025-01-03 20:00", true},
+		{"2025-01-03 21:59", true},
+		{"2025-01-03 22:00", false},
+		{"2025-01-04 20:30", false},
+		{"2025-02-07 20:30", false},
+	}
+	for _, c := range cases {
+		if got := p.ActiveAt(at(c.at)); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.at, got, c.want)
+		}
+	}
+	if !p.MatchPath("/movies/a.mkv") || p.MatchPath("/movies2/a.mkv") {
+		t.Errorf("unexpected path matching")
+	}
+	if got := p.Apply(5); got != 3 {
+		t.Errorf("expected 3 credits after discount, got %d", got)
+	}
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// ProvisioningTemplate 注册申请批准后创建用户时使用的模板，IsDefault 的模板用于所有批准的申请
+type ProvisioningTemplate struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	Name           string    `json:"name" gorm:"uniqueIndex;not null"`
+	Role           int       `json:"role"`             // 用户角色，只能是 GENERAL 或 ADMIN
+	BasePath       string    `json:"base_path"`        // 基础路径，{username} 替换为用户名，如 /home/{username}
+	Permission     int32     `json:"permission"`       // 权限位，含义同 User.Permission
+	PricingGroupID uint      `json:"pricing_group_id"` // 新用户加入的定价组，定价组决定价格倍率和每日免费额度，0 表示不加入
+	WelcomeCredits int64     `json:"welcome_credits"`  // 批准时额外发放的积分，在 credits_welcome_balance 之外
+	IsDefault      bool      `json:"is_default" gorm:"index"`
+	Description    string    `json:"description"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (ProvisioningTemplate) TableName() string {
+	return "x_provisioning_templates"
+}
+
+// UserBasePath 返回模板为用户生成的基础路径，未设置时为根目录
+func (t *ProvisioningTemplate) UserBasePath(username string) string {
+	if t.BasePath == "" {
+		return "/"
+	}
+	return strings.ReplaceAll(t.BasePath, "{username}", username)
+}
@@ -0,0 +1,182 @@
+package model
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// RedeemBatch 兑换码生成批次，记录每次批量生成的参数，便于之后重新导出
+type RedeemBatch struct {
+	ID             uint             `json:"id" gorm:"primaryKey"`
+	BatchNo        string           `json:"batch_no" gorm:"uniqueIndex;size:64;not null"`  // 批次号
+	CampaignID     uint             `json:"campaign_id" gorm:"index"`                      // 所属活动ID，0 表示不属于任何活动
+	Count          int              `json:"count"`                                         // 生成数量
+	Credits        int64            `json:"credits"`                                       // 每个兑换码的积分
+	RewardType     string           `json:"reward_type" gorm:"size:16"`                    // 奖励类型，为空时为积分
+	RewardPayload  string           `json:"reward_payload"`                                // 奖励内容
+	MaxUses        int              `json:"max_uses"`                                      // 每个兑换码的最大使用次数
+	MaxUsesPerUser int              `json:"max_uses_per_user"`                             // 每个用户对同一兑换码的最大使用次数，0 表示不限制
+	ExpiresAt      *time.Time       `json:"expires_at"`                                    // 过期时间（可为空）
+	Description    string           `json:"description"`                                   // 描述
+	Format         RedeemCodeFormat `json:"format" gorm:"embedded;embeddedPrefix:format_"` // 兑换码格式
+	CreatedBy      uint             `json:"created_by"`                                    // 创建者ID
+	CreatedAt      time.Time        `json:"created_at"`
+}
+
+func (RedeemBatch) TableName() string {
+	return "x_redeem_batches"
+}
+
+// Potential 批次中所有兑换码用完时发放的积分总数，非积分奖励为0
+func (b *RedeemBatch) Potential() int64 {
+	if b.RewardType != RedeemRewardCredits {
+		return 0
+	}
+	return int64(b.Count) * int64(b.MaxUses) * b.Credits
+}
+
+// RedeemCampaign 兑换码活动，将多个生成批次归入同一渠道统计，并限制可发放的积分总数
+type RedeemCampaign struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"uniqueIndex;size:100;not null"` // 活动名称
+	Channel     string    `json:"channel" gorm:"size:100"`                   // 发放渠道
+	Budget      int64     `json:"budget"`                                    // 可发放的积分总数，0 表示不限制
+	Description string    `json:"description"`                               // 描述
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (RedeemCampaign) TableName() string {
+	return "x_redeem_campaigns"
+}
+
+// RedeemCampaignStats 兑换码活动的兑换统计
+type RedeemCampaignStats struct {
+	CampaignID     uint    `json:"campaign_id"`
+	Batches        int64   `json:"batches"`         // 批次数
+	Codes          int64   `json:"codes"`           // 兑换码数量
+	Capacity       int64   `json:"capacity"`        // 可兑换的总次数
+	Used           int64   `json:"used"`            // 已兑换次数
+	RedemptionRate float64 `json:"redemption_rate"` // 兑换率，已兑换次数 / 可兑换的总次数
+	Committed      int64   `json:"committed"`       // 所有兑换码用完时发放的积分总数，受预算限制
+	CreditsIssued  int64   `json:"credits_issued"`  // 已发放的积分
+	UniqueUsers    int64   `json:"unique_users"`    // 兑换过的用户数
+}
+
+// 兑换码字符集
+const (
+	RedeemCharsetAlnum       = "alnum"       // 大小写字母和数字
+	RedeemCharsetUpper       = "upper"       // 大写字母和数字
+	RedeemCharsetUnambiguous = "unambiguous" // 去除 0/O/1/I/L 等易混淆字符的大写字母和数字
+	RedeemCharsetDigits      = "digits"      // 纯数字
+)
+
+var redeemCharsets = map[string]string{
+	RedeemCharsetAlnum:       "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789",
+	RedeemCharsetUpper:       "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789",
+	RedeemCharsetUnambiguous: "ABCDEFGHJKMNPQRSTUVWXYZ23456789",
+	RedeemCharsetDigits:      "0123456789",
+}
+
+// RedeemCodeFormat 兑换码格式，生成的兑换码为 前缀 + 随机部分，随机部分可按固定长度分组并以 - 连接
+type RedeemCodeFormat struct {
+	Prefix    string `json:"prefix" gorm:"size:16"`  // 前缀
+	Length    int    `json:"length"`                 // 随机部分长度，不含前缀和分隔符
+	Charset   string `json:"charset" gorm:"size:16"` // 字符集
+	GroupSize int    `json:"group_size"`             // 每组字符数，0 表示不分组
+}
+
+// DefaultRedeemCodeFormat 未指定格式时使用的兑换码格式
+var DefaultRedeemCodeFormat = RedeemCodeFormat{Prefix: "OL", Length: 12, Charset: RedeemCharsetAlnum}
+
+// Alphabet 字符集包含的字符，未知字符集返回空字符串
+func (f RedeemCodeFormat) Alphabet() string {
+	return redeemCharsets[f.Charset]
+}
+
+// EntropyBits 随机部分的熵（位），用于判断兑换码是否难以被猜中
+func (f RedeemCodeFormat) EntropyBits() float64 {
+	alphabet := f.Alphabet()
+	if alphabet == "" {
+		return 0
+	}
+	return float64(f.Length) * math.Log2(float64(len(alphabet)))
+}
+
+// Format 将随机部分按格式加上前缀和分组
+func (f RedeemCodeFormat) Format(random string) string {
+	if f.GroupSize <= 0 || f.GroupSize >= len(random) {
+		return f.Prefix + random
+	}
+	var sb strings.Builder
+	sb.WriteString(f.Prefix)
+	for i := 0; i < len(random); i += f.GroupSize {
+		if i > 0 {
+			sb.WriteByte('-')
+		}
+		sb.WriteString(random[i:min(i+f.GroupSize, len(random))])
+	}
+	return sb.String()
+}
+
+// 兑换码作废范围
+const (
+	RevokeScopeCode   = "code"   // 单个兑换码
+	RevokeScopeBatch  = "batch"  // 整个批次
+	RevokeScopeFilter = "filter" // 符合条件的所有未使用兑换码
+)
+
+// RedeemCodeFilter 兑换码筛选条件，零值字段不参与筛选
+type RedeemCodeFilter struct {
+	Code       string     `json:"code"`
+	BatchNo    string     `json:"batch_no"`
+	CampaignID uint       `json:"campaign_id"`
+	Start      *time.Time `json:"start"`  // 生成时间起始（含）
+	End        *time.Time `json:"end"`    // 生成时间结束（不含）
+	Unused     bool       `json:"unused"` // 只筛选未使用过的兑换码
+}
+
+// RedeemCodeRevocation 兑换码作废记录
+type RedeemCodeRevocation struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	AdminID   uint      `json:"admin_id" gorm:"index"` // 操作的管理员ID
+	Scope     string    `json:"scope" gorm:"size:16"`  // 作废范围: code, batch, filter
+	Target    string    `json:"target"`                // 兑换码、批次号或 JSON 格式的筛选条件
+	Reason    string    `json:"reason"`                // 作废原因
+	Count     int64     `json:"count"`                 // 作废的兑换码数量
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (RedeemCodeRevocation) TableName() string {
+	return "x_redeem_code_revocations"
+}
+
+// RedeemCodeInfo 兑换前展示给用户确认的兑换码信息
+type RedeemCodeInfo struct {
+	Code          string     `json:"code"`
+	Credits       int64      `json:"credits"`        // 可获得的积分
+	RewardType    string     `json:"reward_type"`    // 奖励类型
+	RewardPayload string     `json:"reward_payload"` // 奖励内容
+	ExpiresAt     *time.Time `json:"expires_at"`     // 过期时间
+	RemainingUses int        `json:"remaining_uses"` // 剩余可兑换次数
+	Available     bool       `json:"available"`      // 当前用户能否兑换
+}
+
+// RedeemCodeUsageFilter 兑换码使用记录筛选条件，零值字段不参与筛选
+type RedeemCodeUsageFilter struct {
+	RedeemCodeID uint       `json:"redeem_code_id"`
+	Code         string     `json:"code"`
+	BatchNo      string     `json:"batch_no"`
+	UserID       uint       `json:"user_id"`
+	Start        *time.Time `json:"start"` // 使用时间起始（含）
+	End          *time.Time `json:"end"`   // 使用时间结束（不含）
+}
+
+// RedeemCodeDetail 兑换码详情，供排查兑换失败的问题
+type RedeemCodeDetail struct {
+	*RedeemCode
+	RemainingUses int               `json:"remaining_uses"` // 剩余可兑换次数
+	Expired       bool              `json:"expired"`        // 是否已过期
+	Usages        []RedeemCodeUsage `json:"usages"`         // 最近的使用记录
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ReferralCode 用户的推荐码
+type ReferralCode struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex;not null"` // 用户ID
+	Code      string    `json:"code" gorm:"uniqueIndex;not null"`    // 推荐码
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Referral 推荐关系台账，每个被推荐用户一条记录
+type Referral struct {
+	ID              uint           `json:"id" gorm:"primaryKey"`
+	ReferrerID      uint           `json:"referrer_id" gorm:"index;not null"`      // 推荐人ID
+	RefereeID       uint           `json:"referee_id" gorm:"uniqueIndex;not null"` // 被推荐人ID
+	Code            string         `json:"code"`                                   // 注册时使用的推荐码
+	ReferrerCredits int64          `json:"referrer_credits"`                       // 推荐人累计获得的奖励积分
+	RefereeCredits  int64          `json:"referee_credits"`                        // 被推荐人累计获得的奖励积分
+	FirstPurchaseAt *time.Time     `json:"first_purchase_at"`                      // 被推荐人首次购买时间
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
+	Referee         *User          `json:"referee,omitempty" gorm:"foreignKey:RefereeID"`
+}
+
+// ReferralItem 推荐列表中的被推荐用户，只包含用户名和注册时间
+type ReferralItem struct {
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ReferralStats 用户推荐统计
+type ReferralStats struct {
+	Code          string `json:"code"`           // 推荐码
+	Invited       int64  `json:"invited"`        // 成功推荐的用户数
+	Purchased     int64  `json:"purchased"`      // 已完成首次购买的被推荐用户数
+	CreditsEarned int64  `json:"credits_earned"` // 通过推荐获得的积分
+}
+
+func (ReferralCode) TableName() string {
+	return "x_referral_codes"
+}
+
+func (Referral) TableName() string {
+	return "x_referrals"
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RewardSource 外部积分奖励来源（如广告、任务墙平台），平台通过签名的服务端回调为用户发放积分
+type RewardSource struct {
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	Name         string         `json:"name" gorm:"uniqueIndex;not null"` // 来源名称，用于回调地址
+	Secret       string         `json:"secret" gorm:"not null"`           // 回调签名密钥
+	MaxAmount    int64          `json:"max_amount"`                       // 单次回调的最大积分，0 表示不限
+	UserDailyCap int64          `json:"user_daily_cap"`                   // 每个用户每天从该来源获得的积分上限，0 表示不限
+	DailyCap     int64          `json:"daily_cap"`                        // 该来源每天发放的积分总上限，0 表示不限
+	Enabled      bool           `json:"enabled"`                          // 是否启用
+	Description  string         `json:"description"`                      // 描述
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (RewardSource) TableName() string {
+	return "x_reward_sources"
+}
+
+// ExternalReward 外部来源发放的积分记录，外部交易ID和随机数在同一来源内唯一，防止回调被重放
+type ExternalReward struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	SourceID   uint      `json:"source_id" gorm:"uniqueIndex:idx_external_reward_tx,priority:1;uniqueIndex:idx_external_reward_nonce,priority:1;index:idx_external_reward_user,priority:1;not null"`
+	ExternalID string    `json:"external_id" gorm:"uniqueIndex:idx_external_reward_tx,priority:2;not null"` // 外部平台的交易ID
+	Nonce      string    `json:"nonce" gorm:"uniqueIndex:idx_external_reward_nonce,priority:2;not null"`    // 回调随机数
+	UserID     uint      `json:"user_id" gorm:"index:idx_external_reward_user,priority:2;not null"`
+	Amount     int64     `json:"amount" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+}
+
+func (ExternalReward) TableName() string {
+	return "x_external_rewards"
+}
+
+// RewardCallbackData 回调签名的原文，签名方式与 sign.HMACSign 相同
+func RewardCallbackData(source string, userID uint, amount int64, externalID, nonce string) string {
+	return fmt.Sprintf("%s:%d:%d:%s:%s", source, userID, amount, externalID, nonce)
+}
@@ -14,6 +14,9 @@ const (
 	FTP
 	TRAFFIC
 	CREDITS
+	MAIL
+	REGISTRATION
+	NOTIFICATION
 )
 
 const (
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SubscriptionPlan VIP会员套餐
+type SubscriptionPlan struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Name        string         `json:"name" gorm:"not null"`   // 套餐名称
+	Months      int            `json:"months" gorm:"not null"` // 有效月数，如月度为1，年度为12
+	Money                      // 套餐价格（amount 为最小货币单位）及货币类型
+	Enabled     bool           `json:"enabled" gorm:"default:true"` // 是否可购买
+	Description string         `json:"description"`                 // 描述
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (SubscriptionPlan) TableName() string {
+	return "x_subscription_plans"
+}
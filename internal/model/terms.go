@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// 条款文档类型
+const (
+	TermsOfService = "terms_of_service" // 服务条款
+	PrivacyPolicy  = "privacy_policy"   // 隐私政策
+)
+
+// TermsKinds 所有条款文档类型
+var TermsKinds = []string{TermsOfService, PrivacyPolicy}
+
+// TermsDocument 服务条款或隐私政策的一个版本，发布后不可修改，每种类型最新发布的版本为当前版本
+type TermsDocument struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Kind      string    `json:"kind" gorm:"uniqueIndex:idx_terms_kind_version;size:32;not null"`    // 文档类型
+	Version   string    `json:"version" gorm:"uniqueIndex:idx_terms_kind_version;size:32;not null"` // 版本号
+	Title     string    `json:"title"`
+	Content   string    `json:"content" gorm:"type:text"`
+	CreatedBy uint      `json:"created_by"` // 发布的管理员
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TermsAcceptance 用户同意某个版本条款的记录。注册时同意的记录在申请批准后关联到用户
+type TermsAcceptance struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	UserID         uint      `json:"user_id" gorm:"index:idx_terms_acceptance_user"` // 注册申请尚未批准时为 0
+	RegistrationID uint      `json:"registration_id" gorm:"index"`                   // 注册时同意的注册申请
+	Kind           string    `json:"kind" gorm:"index:idx_terms_acceptance_user;size:32"`
+	Version        string    `json:"version" gorm:"size:32"`
+	IP             string    `json:"ip"` // 同意时的客户端地址
+	AcceptedAt     time.Time `json:"accepted_at"`
+}
+
+func (TermsDocument) TableName() string {
+	return "x_terms_documents"
+}
+
+func (TermsAcceptance) TableName() string {
+	return "x_terms_acceptances"
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// TrafficAccount 用户代理下载流量账户，按已计费字节数分批转换为积分扣费
+type TrafficAccount struct {
+	UserID      uint      `json:"user_id" gorm:"primaryKey"` // 用户ID
+	TotalBytes  int64     `json:"total_bytes"`               // 累计代理流量（字节）
+	BilledBytes int64     `json:"billed_bytes"`              // 已计费的流量（字节）
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (TrafficAccount) TableName() string {
+	return "x_traffic_accounts"
+}
+
+// Unbilled 未计费的流量
+func (t *TrafficAccount) Unbilled() int64 {
+	return t.TotalBytes - t.BilledBytes
+}
@@ -0,0 +1,63 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 上传奖励计算方式
+const (
+	UploadRewardPerFile = "per_file"
+	UploadRewardPerMB   = "per_mb"
+)
+
+// UploadEarnRule 上传奖励规则，用户上传文件到 Path 下时获得积分
+type UploadEarnRule struct {
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	Path       string         `json:"path" gorm:"uniqueIndex;not null"` // 生效的目录
+	Mode       string         `json:"mode" gorm:"not null"`             // 计算方式: per_file, per_mb
+	Credits    int64          `json:"credits" gorm:"not null"`          // 每个文件或每MB奖励的积分
+	MaxCredits int64          `json:"max_credits"`                      // 单个文件最多奖励的积分，0 表示不限制
+	MinSize    int64          `json:"min_size"`                         // 获得奖励的最小文件大小（字节）
+	Enabled    bool           `json:"enabled" gorm:"default:true"`      // 是否启用
+	CreatedBy  uint           `json:"created_by"`                       // 创建者ID
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// UploadReward 上传奖励记录，用于去重，同一内容或同一路径只奖励一次
+type UploadReward struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	UserID     uint      `json:"user_id" gorm:"index;not null"`           // 用户ID
+	RuleID     uint      `json:"rule_id" gorm:"index"`                    // 命中的规则ID
+	Path       string    `json:"path" gorm:"index;not null"`              // 上传的文件路径
+	Size       int64     `json:"size"`                                    // 文件大小
+	ContentKey string    `json:"content_key" gorm:"uniqueIndex;not null"` // 内容标识：服务端计算的文件哈希和大小
+	Credits    int64     `json:"credits"`                                 // 奖励的积分
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (UploadEarnRule) TableName() string {
+	return "x_upload_earn_rules"
+}
+
+func (UploadReward) TableName() string {
+	return "x_upload_rewards"
+}
+
+// Calculate 计算文件可获得的积分
+func (r *UploadEarnRule) Calculate(size int64) int64 {
+	if !r.Enabled || size < r.MinSize {
+		return 0
+	}
+	credits := r.Credits
+	if r.Mode == UploadRewardPerMB {
+		credits = size * r.Credits / (1024 * 1024)
+	}
+	if r.MaxCredits > 0 && credits > r.MaxCredits {
+		credits = r.MaxCredits
+	}
+	return credits
+}
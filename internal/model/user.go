@@ -58,6 +58,12 @@ type User struct {
 	OtpSecret  string `json:"-"`
 	SsoID      string `json:"sso_id"` // unique by sso platform
 	Authn      string `gorm:"type:text" json:"-"`
+	// VIP membership expiry, nil or past means not a VIP member
+	VipExpiresAt *time.Time `json:"vip_expires_at"`
+	// profile edited by the user, an empty avatar falls back to gravatar
+	Nickname string `json:"nickname" gorm:"size:64"`
+	Avatar   string `json:"avatar"`
+	Bio      string `json:"bio" gorm:"size:500"`
 }
 
 func (u *User) IsGuest() bool {
@@ -68,6 +74,10 @@ func (u *User) IsAdmin() bool {
 	return u.Role == ADMIN
 }
 
+func (u *User) IsVip() bool {
+	return u.VipExpiresAt != nil && u.VipExpiresAt.After(time.Now())
+}
+
 func (u *User) ValidateRawPassword(password string) error {
 	return u.ValidatePwdStaticHash(StaticHash(password))
 }
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// 外部身份的提供方，第三方登录平台使用平台名，如 Github
+const (
+	IdentityProviderOIDC = "oidc"
+	IdentityProviderLDAP = "ldap"
+)
+
+// UserIdentity 关联到用户的外部身份，同一提供方的同一身份只能关联一个用户
+type UserIdentity struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	UserID      uint      `json:"user_id" gorm:"index;not null"`
+	Provider    string    `json:"provider" gorm:"uniqueIndex:idx_identity_subject;size:32;not null"`
+	Subject     string    `json:"subject" gorm:"uniqueIndex:idx_identity_subject;size:255;not null"` // 提供方的用户标识，LDAP 为 DN
+	Email       string    `json:"email"`                                                             // 提供方返回的邮箱
+	Provisioned bool      `json:"provisioned" gorm:"not null;default:false"`                         // 账户由该身份登录时创建，角色由提供方管理
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TableName 设置表名
+func (UserIdentity) TableName() string {
+	return "x_user_identities"
+}
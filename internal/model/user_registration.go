@@ -16,6 +16,24 @@ type UserRegistration struct {
 	Salt      string         `json:"-" gorm:"not null"` // 密码盐值
 	Status    int            `json:"status" gorm:"default:0"` // 0: 待验证, 1: 已验证, 2: 已注册, -1: 已拒绝
 	Token     string         `json:"-" gorm:"uniqueIndex"` // 验证令牌
+	EnrollToken     string     `json:"-" gorm:"index"` // 仅通行密钥的账户登记首个通行密钥的一次性令牌，注册完成时签发
+	EnrollExpiresAt *time.Time `json:"-"`              // 登记令牌的过期时间
+	ReferralCode string      `json:"referral_code"` // 注册时填写的推荐码
+	InviteCode   string      `json:"invite_code"` // 注册时使用的邀请码
+	Reason       string      `json:"reason" gorm:"size:500"` // 申请理由，供管理员审核时参考
+	Nickname     string      `json:"nickname" gorm:"size:64"` // 昵称，批准后写入用户资料
+	Avatar       string      `json:"avatar"` // 头像地址，为空时使用 gravatar
+	Bio          string      `json:"bio" gorm:"size:500"` // 个人简介
+	SsoID        string      `json:"sso_id"` // 通过第三方登录发起注册时的外部身份
+	ReviewedBy   uint        `json:"reviewed_by"` // 批准或拒绝申请的管理员，0 表示未经人工审核
+	ReviewedAt   *time.Time  `json:"reviewed_at"` // 审核时间
+	ResendCount  int         `json:"resend_count"` // 重新发送验证邮件的次数
+	SentAt       *time.Time  `json:"sent_at"` // 最近一次发送验证邮件的时间
+	RemindedAt   *time.Time  `json:"reminded_at"` // 令牌过期前发送提醒邮件的时间，每个申请只提醒一次
+	VerifiedAt   *time.Time  `json:"verified_at"` // 验证邮箱的时间
+	EscalatedAt  *time.Time  `json:"escalated_at"` // 等待审核超时后通知管理员的时间
+	IP           string      `json:"ip" gorm:"size:64"` // 提交注册申请的客户端 IP
+	Source       string      `json:"source" gorm:"index;size:64"` // 来源网段，IPv4 为 /24，IPv6 为 /64
 	ExpiresAt time.Time      `json:"expires_at"` // 令牌过期时间
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
@@ -29,12 +47,73 @@ type VerificationCode struct {
 	Code      string         `json:"-" gorm:"not null"` // 验证码
 	Type      string         `json:"type" gorm:"not null"` // 验证码类型: register, reset_password
 	Used      bool           `json:"used" gorm:"default:false"` // 是否已使用
+	Attempts  int            `json:"attempts" gorm:"default:0"` // 校验失败次数，达到上限后验证码被锁定
 	ExpiresAt time.Time      `json:"expires_at"` // 过期时间
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// 注册状态
+const (
+	RegistrationRejected   = -1 // 已拒绝
+	RegistrationPending    = 0  // 待验证邮箱
+	RegistrationVerified   = 1  // 已验证，等待审核
+	RegistrationRegistered = 2  // 已注册
+)
+
+// registrationTransitions 注册状态允许的转换，已注册和已拒绝为终态
+var registrationTransitions = map[int][]int{
+	RegistrationPending:  {RegistrationVerified, RegistrationRejected},
+	RegistrationVerified: {RegistrationRegistered, RegistrationRejected},
+}
+
+// PendingRegistration 待审核的注册申请及其来源的可疑标记
+type PendingRegistration struct {
+	UserRegistration
+	SourceCount int      `json:"source_count"` // 窗口期内同一来源网段提交的注册申请数
+	Flags       []string `json:"flags"`        // 可疑标记
+}
+
+// RegistrationReviewResult 批量审核中单个注册申请的处理结果
+type RegistrationReviewResult struct {
+	ID     uint   `json:"id"`
+	Status string `json:"status"`            // 处理结果
+	UserID uint   `json:"user_id,omitempty"` // 批准后创建的用户
+	Code   string `json:"code,omitempty"`    // 失败原因的错误码
+	Error  string `json:"error,omitempty"`   // 失败原因
+}
+
+// 批量审核的处理结果
+const (
+	ReviewResultApproved = "approved"
+	ReviewResultRejected = "rejected"
+	ReviewResultFailed   = "failed"
+	ReviewResultSkipped  = "skipped" // 整批处理时因其他申请校验失败而未处理
+)
+
+// 注册申请的可疑标记
+const (
+	RegistrationFlagSameSource          = "same_source"          // 同一来源网段在窗口期内提交了大量注册申请
+	RegistrationFlagSequentialUsernames = "sequential_usernames" // 同一来源网段存在仅数字后缀不同的用户名
+)
+
+// 注册模式
+const (
+	RegistrationModeClosed     = "closed"      // 不接受注册
+	RegistrationModeOpen       = "open"        // 验证邮箱后自动通过
+	RegistrationModeApproval   = "approval"    // 验证邮箱后由管理员审核
+	RegistrationModeInviteOnly = "invite_only" // 同 approval，且必须填写邀请码
+)
+
+// 验证码类型
+const (
+	VerificationCodeRegister      = "register"
+	VerificationCodeResetPassword = "reset_password"
+	VerificationCodeChangeEmail   = "change_email"
+	VerificationCodeDeleteAccount = "delete_account"
+)
+
 // TableName 设置表名
 func (UserRegistration) TableName() string {
 	return "x_user_registrations"
@@ -58,4 +137,14 @@ func (vc *VerificationCode) IsExpired() bool {
 // CanUse 检查验证码是否可用
 func (vc *VerificationCode) CanUse() bool {
 	return !vc.Used && !vc.IsExpired()
-}
\ No newline at end of file
+}
+
+// CanTransitionTo 检查注册记录能否从当前状态转换到目标状态
+func (ur *UserRegistration) CanTransitionTo(status int) bool {
+	for _, to := range registrationTransitions[ur.Status] {
+		if to == status {
+			return true
+		}
+	}
+	return false
+}
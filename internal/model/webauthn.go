@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// WebAuthnCredential is a passkey registered by a user
+type WebAuthnCredential struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	UserID       uint      `json:"user_id" gorm:"index"`
+	CredentialID string    `json:"credential_id" gorm:"uniqueIndex;size:255"` // base64 encoded credential id
+	Data         string    `json:"-" gorm:"type:text"`                        // webauthn.Credential encoded as JSON
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (WebAuthnCredential) TableName() string {
+	return "x_webauthn_credentials"
+}
+
+// AuthnUser is a user together with the passkeys stored in the credentials table
+type AuthnUser struct {
+	*User
+	Credentials []webauthn.Credential
+}
+
+func (u *AuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.Credentials
+}
@@ -0,0 +1,94 @@
+// Package notify delivers short operational notifications to chat services
+// and webhooks. Email notifications go through the mail package instead.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the webhook body when a secret is configured
+const SignatureHeader = "X-OpenList-Signature"
+
+// TelegramAPI is the base URL of the Telegram bot API, it is a variable so tests can replace it
+var TelegramAPI = "https://api.telegram.org"
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// Message is a notification about an event
+type Message struct {
+	Event  string            `json:"event"`
+	Title  string            `json:"title"`
+	Text   string            `json:"text"`
+	Fields map[string]string `json:"fields,omitempty"`
+	Time   time.Time         `json:"time"`
+}
+
+// SendTelegram sends the message to a chat through a Telegram bot
+func SendTelegram(ctx context.Context, token, chatID string, msg Message) error {
+	payload, err := json.Marshal(map[string]string{
+		"chat_id": chatID,
+		"text":    msg.Title + "\n\n" + msg.Text,
+	})
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/bot%s/sendMessage", TelegramAPI, token)
+	return post(ctx, url, payload, nil)
+}
+
+// SendWebhook posts the message as JSON to the URL, signing the body when secret is not empty
+func SendWebhook(ctx context.Context, url, secret string, msg Message) error {
+	return PostJSON(ctx, url, secret, msg)
+}
+
+// PostJSON posts v as JSON to the URL, signing the body when secret is not empty
+func PostJSON(ctx context.Context, url, secret string, v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var headers map[string]string
+	if secret != "" {
+		headers = map[string]string{SignatureHeader: Sign(secret, payload)}
+	}
+	return post(ctx, url, payload, headers)
+}
+
+// Sign returns the hex HMAC-SHA256 of the payload that is sent in SignatureHeader
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func post(ctx context.Context, url string, payload []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to send notification")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("notification endpoint returned %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
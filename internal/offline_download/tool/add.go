@@ -144,6 +144,10 @@ func AddURL(ctx context.Context, args *AddURLArgs) (task.TaskExtensionInfo, erro
 		Toolname:     args.Tool,
 		tool:         tool,
 	}
+	t.CreditHoldID, err = op.HoldTaskCredits(taskCreator, op.TaskOfflineDownload, t.GetName())
+	if err != nil {
+		return nil, err
+	}
 	DownloadTaskManager.Add(t)
 	return t, nil
 }
@@ -210,6 +210,14 @@ func (t *DownloadTask) Transfer() error {
 	return transferStd(t.Ctx(), t.TempDir, t.DstDirPath, t.DeletePolicy)
 }
 
+func (t *DownloadTask) OnSucceeded() {
+	op.SettleTaskCredits(t.CreditHoldID, true)
+}
+
+func (t *DownloadTask) OnFailed() {
+	op.SettleTaskCredits(t.CreditHoldID, false)
+}
+
 func (t *DownloadTask) GetName() string {
 	return fmt.Sprintf("download %s to (%s)", t.Url, t.DstDirPath)
 }
@@ -0,0 +1,102 @@
+package op
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/mail"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
+	"github.com/OpenListTeam/go-cache"
+	"github.com/pkg/errors"
+)
+
+const (
+	accountDeletionInterval    = time.Minute // 两次请求注销验证码的最短间隔
+	accountDeletionMaxAttempts = 5           // 密码或验证码允许的错误次数，超过后验证码作废
+)
+
+var (
+	accountDeletionRequests = cache.NewMemCache[time.Time]()
+	accountDeletionAttempts = cache.NewMemCache[int]()
+)
+
+// RequestAccountDeletion 向用户的注册邮箱发送注销账户的验证码，管理员和游客不能注销
+func RequestAccountDeletion(user *model.User) error {
+	if user.IsAdmin() || user.IsGuest() {
+		return errors.New("管理员和游客账户不能注销")
+	}
+	email := userEmail(user)
+	if email == "" {
+		return errors.New("账户没有邮箱，无法发送验证码，请联系管理员注销")
+	}
+	key := strconv.FormatUint(uint64(user.ID), 10)
+	if last, ok := accountDeletionRequests.Get(key); ok && time.Since(last) < accountDeletionInterval {
+		return errs.AccountDeletionLimited
+	}
+	accountDeletionRequests.Set(key, time.Now(), cache.WithEx[time.Time](accountDeletionInterval))
+
+	code, err := CreateVerificationCode(email, model.VerificationCodeDeleteAccount)
+	if err != nil {
+		return err
+	}
+	accountDeletionAttempts.Del(key)
+	return SendMail(email, mail.TemplateAccountDeletion, map[string]any{
+		"Username":  user.Username,
+		"Code":      code.Code,
+		"ExpiresAt": code.ExpiresAt,
+	})
+}
+
+// DeleteAccount 校验密码和邮箱验证码后注销账户：用户被禁用并匿名化，所有登录凭证和会话失效，
+// 注册记录和积分数据被删除，account_retention_days 内的支付订单作为财务记录保留。
+// 仅使用通行密钥登录的账户没有密码，只校验验证码
+func DeleteAccount(user *model.User, password, code string) error {
+	if user.IsAdmin() || user.IsGuest() {
+		return errors.New("管理员和游客账户不能注销")
+	}
+	key := strconv.FormatUint(uint64(user.ID), 10)
+	attempts, _ := accountDeletionAttempts.Get(key)
+	if attempts >= accountDeletionMaxAttempts {
+		return errs.AccountDeletionLimited
+	}
+	email := userEmail(user)
+	verificationCode, err := findVerificationCode(email, model.VerificationCodeDeleteAccount, code)
+	if err != nil || (user.PwdHash != "" && user.ValidateRawPassword(password) != nil) {
+		accountDeletionAttempts.Set(key, attempts+1, cache.WithEx[int](time.Hour))
+		return errs.InvalidDeletionCode
+	}
+	accountDeletionAttempts.Del(key)
+	verificationCode.Used = true
+	if err = db.UpdateVerificationCode(verificationCode); err != nil {
+		return errors.Wrap(err, "更新验证码状态失败")
+	}
+
+	username := user.Username
+	deleted := *user
+	deleted.Username = "deleted_" + key
+	deleted.Password = ""
+	deleted.PwdHash = ""
+	deleted.Salt = random.String(16)
+	deleted.PwdTS = time.Now().Unix() // 使已签发的令牌失效
+	deleted.OtpSecret = ""
+	deleted.SsoID = ""
+	deleted.Authn = "[]"
+	deleted.Permission = 0
+	deleted.Disabled = true
+	deleted.Nickname, deleted.Avatar, deleted.Bio = "", "", ""
+	removeUserAvatar(user.ID)
+	removeDataExports(user.ID)
+	retainSince := time.Now().AddDate(0, 0, -int(getCreditsSettingInt(conf.AccountRetentionDays, 1825)))
+	if err := db.PurgeUserAccount(&deleted, username, email, retainSince); err != nil {
+		return errors.Wrap(err, "注销账户失败")
+	}
+	userCache.Del(username)
+	*user = deleted
+	utils.Log.Infof("user %d (%s) deleted the account", user.ID, username)
+	return nil
+}
@@ -0,0 +1,71 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/pkg/errors"
+)
+
+func TestDeleteAccount(t *testing.T) {
+	user := (&model.User{Username: "deletion_user"}).SetPassword("password")
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if err := op.RecordSSORegistration(user, "deletion@example.com"); err != nil {
+		t.Fatalf("failed to record registration: %+v", err)
+	}
+	if err := op.AddCredits(user.ID, 100, "test", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	share, err := op.CreateFileShare(user.ID, "/deletion/file.zip", 0)
+	if err != nil {
+		t.Fatalf("failed to create share: %+v", err)
+	}
+	referral, err := op.GetReferralCode(user.ID)
+	if err != nil {
+		t.Fatalf("failed to get referral code: %+v", err)
+	}
+
+	// sending fails without a mail provider, the code is stored anyway
+	_ = op.RequestAccountDeletion(user)
+	if err := op.RequestAccountDeletion(user); !errors.Is(err, errs.AccountDeletionLimited) {
+		t.Errorf("expected a second request to be rate limited, got %v", err)
+	}
+	code, err := db.GetVerificationCode("deletion@example.com", model.VerificationCodeDeleteAccount)
+	if err != nil {
+		t.Fatalf("expected a deletion code: %+v", err)
+	}
+	if err = op.DeleteAccount(user, "password", "wrong"); !errors.Is(err, errs.InvalidDeletionCode) {
+		t.Errorf("expected a wrong code to be rejected, got %v", err)
+	}
+	if err = op.DeleteAccount(user, "wrong", code.Code); !errors.Is(err, errs.InvalidDeletionCode) {
+		t.Errorf("expected a wrong password to be rejected, got %v", err)
+	}
+	if err = op.DeleteAccount(user, "password", code.Code); err != nil {
+		t.Fatalf("failed to delete account: %+v", err)
+	}
+
+	deleted, err := op.GetUserById(user.ID)
+	if err != nil {
+		t.Fatalf("failed to get deleted user: %+v", err)
+	}
+	if !deleted.Disabled || deleted.Username == "deletion_user" || deleted.PwdHash != "" {
+		t.Errorf("expected the user to be disabled and anonymized, got %+v", deleted)
+	}
+	if _, err = db.GetUserRegistrationByEmail("deletion@example.com"); err == nil {
+		t.Errorf("expected the registration to be purged")
+	}
+	if _, err = db.GetUserCreditsByUserID(user.ID); err == nil {
+		t.Errorf("expected the credits to be purged")
+	}
+	if _, err = op.GetFileShare(share.Token); !errors.Is(err, errs.ShareNotFound) {
+		t.Errorf("expected the shares to be removed, got %v", err)
+	}
+	if err = op.CheckReferralCode(referral.Code); err == nil {
+		t.Errorf("expected the referral code to be purged")
+	}
+}
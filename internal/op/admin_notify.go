@@ -0,0 +1,154 @@
+package op
+
+import (
+	"context"
+	stderrors "errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/mail"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/notify"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// 管理员通知的事件类型
+const (
+	AdminEventRegistrationPending = "registration_pending"
+	AdminEventPaymentCompleted    = "payment_completed"
+	AdminEventTest                = "test"
+)
+
+// notifySendTimeout 单个渠道发送管理员通知的超时时间
+const notifySendTimeout = 30 * time.Second
+
+// notifyRegistrationPending 注册申请进入待审核队列时通知管理员
+func notifyRegistrationPending(registration *model.UserRegistration) {
+	if !getCreditsSettingBool(conf.NotifyOnRegistration) {
+		return
+	}
+	fields := map[string]string{
+		"Username": registration.Username,
+		"Email":    registration.Email,
+	}
+	if registration.InviteCode != "" {
+		fields["Invite code"] = registration.InviteCode
+	}
+	if registration.SsoID != "" {
+		fields["SSO ID"] = registration.SsoID
+	}
+	msg := notify.Message{
+		Event:  AdminEventRegistrationPending,
+		Title:  "New registration waiting for approval",
+		Text:   registration.Username + " has verified the email address and is waiting for approval.",
+		Fields: fields,
+		Time:   time.Now(),
+	}
+	go func() {
+		logNotifyError(deliverAdminNotification(msg))
+	}()
+}
+
+// notifyPaymentCompleted 支付订单完成且金额不低于 notify_payment_min_amount 时通知管理员，
+// 最低金额按订单的货币计算
+func notifyPaymentCompleted(order *model.PaymentOrder) {
+	if !getCreditsSettingBool(conf.NotifyOnPayment) {
+		return
+	}
+	if minAmount := strings.TrimSpace(getSettingStr(conf.NotifyPaymentMinAmount)); minAmount != "" {
+		threshold, err := model.ParseMoney(minAmount, order.Currency)
+		if err != nil {
+			utils.Log.Warnf("invalid %s: %+v", conf.NotifyPaymentMinAmount, err)
+		} else if order.Amount < threshold.Amount {
+			return
+		}
+	}
+	fields := map[string]string{
+		"Order":   order.OrderNo,
+		"User ID": strconv.FormatUint(uint64(order.UserID), 10),
+		"Amount":  order.MajorString() + " " + order.Currency,
+		"Credits": strconv.FormatInt(order.Credits, 10),
+		"Method":  order.PaymentMethod,
+	}
+	if user, err := GetUserById(order.UserID); err == nil {
+		fields["User"] = user.Username
+	}
+	msg := notify.Message{
+		Event:  AdminEventPaymentCompleted,
+		Title:  "Payment order completed",
+		Text:   "Payment order " + order.OrderNo + " of " + order.MajorString() + " " + order.Currency + " has been paid.",
+		Fields: fields,
+		Time:   time.Now(),
+	}
+	go func() {
+		logNotifyError(deliverAdminNotification(msg))
+	}()
+}
+
+// SendTestAdminNotification 向所有已配置的渠道发送一条测试通知，不受事件开关影响，返回各渠道的发送错误
+func SendTestAdminNotification() error {
+	return deliverAdminNotification(notify.Message{
+		Event: AdminEventTest,
+		Title: "Test notification",
+		Text:  "Admin notifications are configured correctly.",
+		Time:  time.Now(),
+	})
+}
+
+// deliverAdminNotification 通过邮件、Telegram 和 Webhook 中已配置的渠道发送管理员通知，
+// 一个渠道失败不影响其他渠道
+func deliverAdminNotification(msg notify.Message) error {
+	var errs []error
+	for _, to := range adminNotifyEmails() {
+		if err := SendMail(to, mail.TemplateAdminNotification, map[string]any{
+			"Title":  msg.Title,
+			"Text":   msg.Text,
+			"Fields": msg.Fields,
+		}); err != nil {
+			errs = append(errs, errors.Wrapf(err, "发送通知邮件到 %s 失败", to))
+		}
+	}
+	token, chatID := getSettingStr(conf.NotifyTelegramBotToken), getSettingStr(conf.NotifyTelegramChatID)
+	if token != "" && chatID != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), notifySendTimeout)
+		if err := notify.SendTelegram(ctx, token, chatID, msg); err != nil {
+			errs = append(errs, errors.Wrap(err, "发送 Telegram 通知失败"))
+		}
+		cancel()
+	}
+	if url := strings.TrimSpace(getSettingStr(conf.NotifyWebhookURL)); url != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), notifySendTimeout)
+		if err := notify.SendWebhook(ctx, url, getSettingStr(conf.NotifyWebhookSecret), msg); err != nil {
+			errs = append(errs, errors.Wrap(err, "发送 Webhook 通知失败"))
+		}
+		cancel()
+	}
+	return stderrors.Join(errs...)
+}
+
+// adminNotifyEmails 返回接收管理员通知的邮箱列表
+func adminNotifyEmails() []string {
+	return splitSettingList(getSettingStr(conf.NotifyAdminEmails))
+}
+
+// splitSettingList 按逗号、空格和换行拆分设置项中的列表
+func splitSettingList(value string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r' || r == ' '
+	}) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func logNotifyError(err error) {
+	if err != nil {
+		utils.Log.Errorf("failed to notify admins: %+v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package op_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/notify"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestSendTestAdminNotification(t *testing.T) {
+	var received notify.Message
+	var signature string
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(notify.SignatureHeader)
+		_ = json.Unmarshal(body, &received)
+	}))
+	defer server.Close()
+
+	save := func(key, value string) {
+		if err := op.SaveSettingItem(&model.SettingItem{Key: key, Value: value, Type: conf.TypeString}); err != nil {
+			t.Fatalf("failed to save setting: %+v", err)
+		}
+	}
+	save(conf.NotifyWebhookURL, server.URL)
+	save(conf.NotifyWebhookSecret, "secret")
+	defer save(conf.NotifyWebhookURL, "")
+
+	if err := op.SendTestAdminNotification(); err != nil {
+		t.Fatalf("failed to send test notification: %+v", err)
+	}
+	if received.Event != op.AdminEventTest {
+		t.Errorf("expected event %s, got %q", op.AdminEventTest, received.Event)
+	}
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	if signature != hex.EncodeToString(mac.Sum(nil)) {
+		t.Errorf("webhook body is not signed with the secret")
+	}
+
+	save(conf.NotifyWebhookURL, server.URL+"/missing\x00")
+	if err := op.SendTestAdminNotification(); err == nil {
+		t.Errorf("expected an error for an invalid webhook url")
+	}
+}
@@ -0,0 +1,131 @@
+package op
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// 计量的接口操作
+const (
+	ApiList     = "list"
+	ApiSearch   = "search"
+	ApiPropfind = "propfind"
+)
+
+var apiPriceSettings = map[string]string{
+	ApiList:     conf.ApiCreditsPer1000List,
+	ApiSearch:   conf.ApiCreditsPer1000Search,
+	ApiPropfind: conf.ApiCreditsPer1000Propfind,
+}
+
+type apiCallKey struct {
+	userID    uint
+	operation string
+}
+
+var (
+	apiCallsMu      sync.Mutex
+	pendingApiCalls = map[apiCallKey]int64{}
+)
+
+func apiPrice(operation string) int64 {
+	return getCreditsSettingInt(apiPriceSettings[operation], 0)
+}
+
+// RecordApiCall 记录用户的一次接口调用，先在内存中累加，由 FlushApiCalls 定期写入数据库，
+// 该操作未设置价格时不记录，游客和管理员不计量
+func RecordApiCall(user *model.User, operation string) {
+	if user == nil || user.IsGuest() || user.IsAdmin() || apiPrice(operation) <= 0 {
+		return
+	}
+	apiCallsMu.Lock()
+	pendingApiCalls[apiCallKey{user.ID, operation}]++
+	apiCallsMu.Unlock()
+}
+
+// FlushApiCalls 将内存中累计的接口调用次数写入当日的调用记录
+func FlushApiCalls() error {
+	apiCallsMu.Lock()
+	pending := pendingApiCalls
+	pendingApiCalls = map[apiCallKey]int64{}
+	apiCallsMu.Unlock()
+
+	day := quotaDay(time.Now())
+	var errList []error
+	for key, calls := range pending {
+		if err := db.AddApiUsage(key.userID, day, key.operation, calls); err != nil {
+			// 写入失败的次数放回，下次重试
+			apiCallsMu.Lock()
+			pendingApiCalls[key] += calls
+			apiCallsMu.Unlock()
+			errList = append(errList, err)
+		}
+	}
+	if len(errList) > 0 {
+		return errors.Wrapf(errList[0], "写入接口调用次数失败 %d 条", len(errList))
+	}
+	return nil
+}
+
+// BillApiCalls 将超出每日免费次数的接口调用按每千次价格转换为积分扣费，
+// 每条调用记录每次只生成一条交易记录，不足1积分的部分留到下次
+func BillApiCalls() error {
+	free := getCreditsSettingInt(conf.ApiFreeDailyCalls, 0)
+	// 前一天的记录在零点后仍可能有最后一批调用写入
+	usages, err := db.GetApiUsagesOverFree(quotaDay(time.Now().AddDate(0, 0, -1)), free)
+	if err != nil {
+		return errors.Wrap(err, "获取接口调用记录失败")
+	}
+	for i := range usages {
+		usage := &usages[i]
+		credits := usage.Unbilled(free, apiPrice(usage.Operation))
+		if credits <= 0 {
+			continue
+		}
+		err = retryOnCreditsConflict(func() error {
+			return db.BillApiUsage(usage, credits, &model.CreditTransaction{
+				UserID:      usage.UserID,
+				Amount:      -credits,
+				Type:        "spend",
+				Source:      "api",
+				SourceID:    usage.Operation,
+				Description: fmt.Sprintf("接口调用计费: %s %s", usage.Operation, usage.Day),
+			})
+		})
+		if errors.Is(err, errs.InsufficientCredits) || errors.Is(err, errs.CreditsFrozen) {
+			// 积分不足时保留未计费次数，等待充值后再扣
+			utils.Log.Warnf("failed to bill %d credits of %s calls of user %d: %v", credits, usage.Operation, usage.UserID, err)
+			continue
+		}
+		if err != nil {
+			return errors.Wrap(err, "接口调用计费失败")
+		}
+	}
+	return nil
+}
+
+// GetApiUsages 获取用户当日各类接口的调用次数
+func GetApiUsages(userID uint) ([]model.ApiUsage, error) {
+	usages, err := db.GetApiUsages(userID, quotaDay(time.Now()))
+	if err != nil {
+		return nil, errors.Wrap(err, "获取接口调用记录失败")
+	}
+	return usages, nil
+}
+
+// CleanApiUsages 清理过期的接口调用记录
+func CleanApiUsages() error {
+	before := quotaDay(time.Now().AddDate(0, 0, -quotaRetentionDays))
+	if err := db.CleanApiUsages(before); err != nil {
+		return errors.Wrap(err, "清理接口调用记录失败")
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestBillApiCalls(t *testing.T) {
+	user := &model.User{Username: "api_usage_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	settings := map[string]string{conf.ApiFreeDailyCalls: "10", conf.ApiCreditsPer1000List: "500"}
+	for key, value := range settings {
+		if err := op.SaveSettingItem(&model.SettingItem{Key: key, Value: value, Type: conf.TypeNumber}); err != nil {
+			t.Fatalf("failed to save setting: %+v", err)
+		}
+	}
+	defer op.SaveSettingItem(&model.SettingItem{Key: conf.ApiFreeDailyCalls, Value: "1000", Type: conf.TypeNumber})
+	defer op.SaveSettingItem(&model.SettingItem{Key: conf.ApiCreditsPer1000List, Value: "0", Type: conf.TypeNumber})
+	if err := op.AddCredits(user.ID, 100, "api usage test", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	// 10 free calls, 5 paid list calls bill 2 credits, search is not priced
+	for i := 0; i < 15; i++ {
+		op.RecordApiCall(user, op.ApiList)
+		op.RecordApiCall(user, op.ApiSearch)
+	}
+	if err := op.FlushApiCalls(); err != nil {
+		t.Fatalf("failed to flush api calls: %+v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := op.BillApiCalls(); err != nil {
+			t.Fatalf("failed to bill api calls: %+v", err)
+		}
+	}
+	credits, err := op.GetUserCredits(user.ID)
+	if err != nil {
+		t.Fatalf("failed to get credits: %+v", err)
+	}
+	if credits.Balance != 98 {
+		t.Errorf("expected balance 98, got %d", credits.Balance)
+	}
+	usages, err := op.GetApiUsages(user.ID)
+	if err != nil {
+		t.Fatalf("failed to get api usages: %+v", err)
+	}
+	if len(usages) != 1 || usages[0].Calls != 15 || usages[0].BilledCredits != 2 {
+		t.Errorf("unexpected api usages: %+v", usages)
+	}
+}
@@ -0,0 +1,98 @@
+package op
+
+import (
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// ListCoupons 获取优惠券列表
+func ListCoupons(page, pageSize int) ([]model.Coupon, int64, error) {
+	coupons, total, err := db.GetCoupons(page, pageSize)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "获取优惠券失败")
+	}
+	return coupons, total, nil
+}
+
+// SaveCoupon 创建或更新优惠券
+func SaveCoupon(coupon *model.Coupon) error {
+	coupon.Code = strings.ToUpper(strings.TrimSpace(coupon.Code))
+	if coupon.Code == "" {
+		return errs.CouponRequired
+	}
+	switch coupon.Type {
+	case model.CouponPercent:
+		if coupon.Value <= 0 || coupon.Value > 100 {
+			return errors.New("折扣百分比必须在1到100之间")
+		}
+	case model.CouponFixed:
+		if coupon.Value <= 0 {
+			return errors.New("减免金额必须大于0")
+		}
+		if coupon.Currency != "" {
+			coupon.Currency = strings.ToUpper(coupon.Currency)
+		}
+	default:
+		return errors.Errorf("无效的优惠券类型: %s", coupon.Type)
+	}
+	if coupon.MinAmount < 0 || coupon.MaxUses < 0 || coupon.PerUserLimit < 0 {
+		return errors.New("使用限制不能为负数")
+	}
+	if coupon.StartsAt != nil && coupon.ExpiresAt != nil && !coupon.ExpiresAt.After(*coupon.StartsAt) {
+		return errors.New("过期时间必须晚于生效时间")
+	}
+	if err := db.SaveCoupon(coupon); err != nil {
+		return errors.Wrap(err, "保存优惠券失败")
+	}
+	return nil
+}
+
+// DeleteCoupon 删除优惠券
+func DeleteCoupon(id uint) error {
+	if err := db.DeleteCoupon(id); err != nil {
+		return errors.Wrap(err, "删除优惠券失败")
+	}
+	return nil
+}
+
+// applyCoupon 校验优惠码并计算订单可减免的金额，使用次数在创建订单时由 db.CreateCouponOrder 校验
+func applyCoupon(code string, amount model.Money) (*model.Coupon, int64, error) {
+	coupon, err := db.GetCouponByCode(strings.ToUpper(strings.TrimSpace(code)))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, 0, errs.CouponNotFound
+		}
+		return nil, 0, errors.Wrap(err, "获取优惠券失败")
+	}
+	if !coupon.InValidity(time.Now()) {
+		return nil, 0, errs.CouponInactive
+	}
+	if amount.Amount < coupon.MinAmount {
+		return nil, 0, errs.CouponMinAmount
+	}
+	discount := coupon.Discount(amount)
+	if discount <= 0 {
+		return nil, 0, errs.CouponNotApplicable
+	}
+	if discount >= amount.Amount {
+		return nil, 0, errs.CouponTooLarge
+	}
+	return coupon, discount, nil
+}
+
+// recordCouponUsage 订单支付成功后累加优惠券使用次数，失败只打印日志
+func recordCouponUsage(order *model.PaymentOrder) {
+	if order.CouponCode == "" {
+		return
+	}
+	if err := db.IncreaseCouponUsage(order.CouponCode); err != nil {
+		utils.Log.Errorf("failed to record usage of coupon %s for order %s: %+v", order.CouponCode, order.OrderNo, err)
+	}
+}
@@ -0,0 +1,106 @@
+package op
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+const allowanceGrantBatchSize = 100
+
+// ListCreditAllowances 获取积分津贴列表
+func ListCreditAllowances() ([]model.CreditAllowance, error) {
+	allowances, err := db.GetCreditAllowances(false)
+	if err != nil {
+		return nil, errors.Wrap(err, "获取积分津贴失败")
+	}
+	return allowances, nil
+}
+
+// SaveCreditAllowance 创建或更新积分津贴
+func SaveCreditAllowance(allowance *model.CreditAllowance) error {
+	if allowance.Amount <= 0 {
+		return errors.New("津贴积分必须大于0")
+	}
+	if allowance.Role == model.GUEST {
+		return errors.New("不能为游客发放津贴")
+	}
+	if _, _, _, err := allowance.PeriodAt(time.Now()); err != nil {
+		return errors.WithMessage(err, "发放周期无效")
+	}
+	if err := db.SaveCreditAllowance(allowance); err != nil {
+		return errors.Wrap(err, "保存积分津贴失败")
+	}
+	return nil
+}
+
+// DeleteCreditAllowance 删除积分津贴，已发放的积分不受影响
+func DeleteCreditAllowance(id uint) error {
+	if err := db.DeleteCreditAllowance(id); err != nil {
+		return errors.Wrap(err, "删除积分津贴失败")
+	}
+	return nil
+}
+
+// GrantCreditAllowances 为符合条件且本周期尚未领取的用户发放积分津贴。
+// 不累积的津贴在周期结束时过期，由 ExpireCreditLots 作废剩余部分
+func GrantCreditAllowances() error {
+	if !getCreditsSettingBool(conf.CreditsEnabled) {
+		return nil
+	}
+	allowances, err := db.GetCreditAllowances(true)
+	if err != nil {
+		return errors.Wrap(err, "获取积分津贴失败")
+	}
+	now := time.Now()
+	for i := range allowances {
+		if err = grantCreditAllowance(&allowances[i], now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func grantCreditAllowance(allowance *model.CreditAllowance, now time.Time) error {
+	period, _, end, err := allowance.PeriodAt(now)
+	if err != nil {
+		return err
+	}
+	for {
+		userIDs, err := db.GetUngrantedAllowanceUsers(allowance, period, allowanceGrantBatchSize)
+		if err != nil {
+			return errors.Wrap(err, "获取待发放津贴的用户失败")
+		}
+		if len(userIDs) == 0 {
+			return nil
+		}
+		for _, userID := range userIDs {
+			err = retryOnCreditsConflict(func() error {
+				transaction := &model.CreditTransaction{
+					UserID:      userID,
+					Amount:      allowance.Amount,
+					Type:        "earn",
+					Source:      "allowance",
+					SourceID:    period,
+					Description: allowance.Name,
+				}
+				if !allowance.Stacking {
+					transaction.ExpiresAt = &end
+				}
+				_, err := db.GrantCreditAllowance(&model.CreditAllowanceGrant{
+					AllowanceID: allowance.ID,
+					UserID:      userID,
+					Period:      period,
+					Amount:      allowance.Amount,
+				}, transaction)
+				return err
+			})
+			if err != nil {
+				return errors.Wrapf(err, "发放积分津贴 %d 给用户 %d 失败", allowance.ID, userID)
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package op
+
+import (
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// SendCreditGift 从赠送方扣除积分生成一份只能由指定用户领取的礼物，recipient 可以是用户名或注册邮箱。
+// 礼物在 credits_gift_expire_hours 小时内未领取时退还赠送方
+func SendCreditGift(senderID uint, recipient string, amount int64, message string) (*model.CreditGift, error) {
+	if amount <= 0 {
+		return nil, errs.InvalidCreditsAmount
+	}
+	to, err := findGiftRecipient(recipient)
+	if err != nil {
+		return nil, err
+	}
+	if to.ID == senderID {
+		return nil, errs.TransferToSelf
+	}
+	if to.IsGuest() || to.Disabled {
+		return nil, errs.RecipientUnavailable
+	}
+
+	hours := getCreditsSettingInt(conf.CreditsGiftExpireHours, 72)
+	if hours <= 0 {
+		hours = 72
+	}
+	gift := &model.CreditGift{
+		GiftNo:      generateGiftNo(),
+		SenderID:    senderID,
+		RecipientID: to.ID,
+		Amount:      amount,
+		Message:     message,
+		ExpiresAt:   time.Now().Add(time.Duration(hours) * time.Hour),
+	}
+	err = retryOnCreditsConflict(func() error {
+		return db.CreateCreditGift(gift, &model.CreditTransaction{
+			UserID:      senderID,
+			Amount:      -amount,
+			Type:        "gift_out",
+			Source:      "gift",
+			SourceID:    gift.GiftNo,
+			Description: message,
+			Purchased:   true,
+		})
+	})
+	if err != nil {
+		if errors.Is(err, errs.InsufficientCredits) {
+			return nil, err
+		}
+		return nil, errors.Wrap(err, "赠送积分失败")
+	}
+	return gift, nil
+}
+
+// findGiftRecipient 根据用户名或已完成注册的邮箱查找礼物接收方
+func findGiftRecipient(recipient string) (*model.User, error) {
+	recipient = strings.TrimSpace(recipient)
+	if strings.Contains(recipient, "@") {
+		registration, err := db.GetUserRegistrationByEmail(normalizeEmail(recipient))
+		if err != nil || registration.Status != model.RegistrationRegistered {
+			return nil, errs.RecipientNotFound
+		}
+		recipient = registration.Username
+	}
+	user, err := GetUserByName(recipient)
+	if err != nil {
+		return nil, errs.RecipientNotFound
+	}
+	return user, nil
+}
+
+// ClaimCreditGift 接收方领取礼物
+func ClaimCreditGift(userID uint, giftNo string) (*model.CreditGift, error) {
+	gift, err := db.GetCreditGiftByNo(giftNo)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.CreditGiftNotFound
+		}
+		return nil, errors.Wrap(err, "获取礼物失败")
+	}
+	if gift.RecipientID != userID {
+		return nil, errs.CreditGiftNotFound
+	}
+	if gift.Status != model.CreditGiftPending {
+		return nil, errs.CreditGiftNotPending
+	}
+	if time.Now().After(gift.ExpiresAt) {
+		return nil, errs.CreditGiftExpired
+	}
+	err = retryOnCreditsConflict(func() error {
+		return db.FinishCreditGift(gift.ID, model.CreditGiftClaimed, &model.CreditTransaction{
+			UserID:      userID,
+			Amount:      gift.Amount,
+			Type:        "gift_in",
+			Source:      "gift",
+			SourceID:    gift.GiftNo,
+			Description: gift.Message,
+			Purchased:   true,
+		})
+	})
+	if err != nil {
+		if errors.Is(err, errs.CreditGiftNotPending) {
+			return nil, err
+		}
+		return nil, errors.Wrap(err, "领取礼物失败")
+	}
+	gift.Status = model.CreditGiftClaimed
+	return gift, nil
+}
+
+// ListCreditGifts 获取用户赠送（sent 为 true）或收到的礼物
+func ListCreditGifts(userID uint, sent bool, page, pageSize int) ([]model.CreditGift, int64, error) {
+	gifts, total, err := db.GetCreditGifts(userID, sent, page, pageSize)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "获取礼物列表失败")
+	}
+	return gifts, total, nil
+}
+
+// ReturnExpiredCreditGifts 将过期未领取的礼物积分退还赠送方
+func ReturnExpiredCreditGifts() error {
+	gifts, err := db.GetExpiredCreditGifts(time.Now())
+	if err != nil {
+		return errors.Wrap(err, "获取过期礼物失败")
+	}
+	for _, gift := range gifts {
+		err = retryOnCreditsConflict(func() error {
+			return db.FinishCreditGift(gift.ID, model.CreditGiftReturned, &model.CreditTransaction{
+				UserID:      gift.SenderID,
+				Amount:      gift.Amount,
+				Type:        "refund",
+				Source:      "gift",
+				SourceID:    gift.GiftNo,
+				Description: "礼物过期未领取，积分退还",
+				Purchased:   true,
+			})
+		})
+		// gifts of frozen accounts are returned once the account is unfrozen
+		if err != nil && !errors.Is(err, errs.CreditGiftNotPending) && !errors.Is(err, errs.CreditsFrozen) {
+			return errors.Wrapf(err, "退还礼物 %s 失败", gift.GiftNo)
+		}
+	}
+	return nil
+}
+
+// generateGiftNo 生成礼物编号
+func generateGiftNo() string {
+	return "GF" + utils.NextSnowflakeString()
+}
@@ -0,0 +1,82 @@
+package op
+
+import (
+	"math"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// ListCreditPackages 获取积分充值套餐列表
+func ListCreditPackages(enabledOnly bool) ([]model.CreditPackage, error) {
+	packages, err := db.GetCreditPackages(enabledOnly)
+	if err != nil {
+		return nil, errors.Wrap(err, "获取充值套餐失败")
+	}
+	return packages, nil
+}
+
+// SaveCreditPackage 创建或更新积分充值套餐
+func SaveCreditPackage(pkg *model.CreditPackage) error {
+	if pkg.Credits <= 0 {
+		return errors.New("套餐积分必须大于0")
+	}
+	if pkg.Money.Amount <= 0 {
+		return errors.New("套餐价格必须大于0")
+	}
+	pkg.Money = model.NewMoney(pkg.Money.Amount, pkg.Money.Currency)
+	if err := db.SaveCreditPackage(pkg); err != nil {
+		return errors.Wrap(err, "保存充值套餐失败")
+	}
+	return nil
+}
+
+// DeleteCreditPackage 删除积分充值套餐
+func DeleteCreditPackage(id uint) error {
+	if err := db.DeleteCreditPackage(id); err != nil {
+		return errors.Wrap(err, "删除充值套餐失败")
+	}
+	return nil
+}
+
+// CreditsPrice 按积分单价设置计算购买指定积分的金额，不足一个最小货币单位的部分向上取整
+func CreditsPrice(credits int64) (model.Money, error) {
+	if credits <= 0 {
+		return model.Money{}, errs.InvalidCreditsAmount
+	}
+	per100 := getCreditsSettingInt(conf.CreditsPricePer100, 100)
+	if per100 <= 0 {
+		return model.Money{}, errors.New("积分单价设置无效")
+	}
+	if credits > (math.MaxInt64-99)/per100 {
+		return model.Money{}, errors.New("积分数量过大")
+	}
+	return model.NewMoney((credits*per100+99)/100, model.DefaultCurrency), nil
+}
+
+// CreateCreditsOrder 创建购买积分的支付订单。packageID 不为 0 时按套餐的积分和价格下单，
+// 否则按积分单价计算 credits 的金额
+func CreateCreditsOrder(userID uint, credits int64, packageID uint, paymentMethod, couponCode string) (*model.PaymentOrder, error) {
+	if packageID == 0 {
+		amount, err := CreditsPrice(credits)
+		if err != nil {
+			return nil, err
+		}
+		return CreatePaymentOrder(userID, amount, credits, paymentMethod, couponCode)
+	}
+	pkg, err := db.GetCreditPackageByID(packageID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.CreditPackageNotFound
+		}
+		return nil, errors.Wrap(err, "获取充值套餐失败")
+	}
+	if !pkg.Enabled {
+		return nil, errs.CreditPackageUnavailable
+	}
+	return CreatePaymentOrder(userID, pkg.Money, pkg.Credits, paymentMethod, couponCode)
+}
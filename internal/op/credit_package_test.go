@@ -0,0 +1,112 @@
+package op_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/pkg/errors"
+)
+
+func TestCreateCreditsOrder(t *testing.T) {
+	user := &model.User{Username: "credits_buyer", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	err := op.SaveSettingItem(&model.SettingItem{Key: conf.CreditsPricePer100, Value: "50", Type: conf.TypeNumber})
+	if err != nil {
+		t.Fatalf("failed to save setting: %+v", err)
+	}
+	defer op.SaveSettingItem(&model.SettingItem{Key: conf.CreditsPricePer100, Value: "100", Type: conf.TypeNumber})
+
+	order, err := op.CreateCreditsOrder(user.ID, 201, 0, "alipay", "")
+	if err != nil {
+		t.Fatalf("failed to create order: %+v", err)
+	}
+	if order.Money.Amount != 101 || order.Credits != 201 {
+		t.Errorf("unexpected order by rate: %d credits for %d", order.Credits, order.Money.Amount)
+	}
+
+	pkg := &model.CreditPackage{Name: "bundle", Credits: 1000, Money: model.NewMoney(300, ""), Enabled: true}
+	if err = op.SaveCreditPackage(pkg); err != nil {
+		t.Fatalf("failed to save package: %+v", err)
+	}
+	order, err = op.CreateCreditsOrder(user.ID, 1, pkg.ID, "alipay", "")
+	if err != nil {
+		t.Fatalf("failed to create order: %+v", err)
+	}
+	if order.Money.Amount != 300 || order.Credits != 1000 {
+		t.Errorf("unexpected order by package: %d credits for %d", order.Credits, order.Money.Amount)
+	}
+}
+
+func TestCompletePaymentOrderOnce(t *testing.T) {
+	user := &model.User{Username: "credits_double_pay", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	order, err := op.CreateCreditsOrder(user.ID, 500, 0, "alipay", "")
+	if err != nil {
+		t.Fatalf("failed to create order: %+v", err)
+	}
+	before, _ := op.GetUserCredits(user.ID)
+
+	var wg sync.WaitGroup
+	var completed int32
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if op.CompletePaymentOrder(order.OrderNo, "tx_once", order.Money, time.Now()) == nil {
+				atomic.AddInt32(&completed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if completed != 1 {
+		t.Errorf("order should be completed exactly once, got %d", completed)
+	}
+	credits, _ := op.GetUserCredits(user.ID)
+	if credits.Balance != before.Balance+500 {
+		t.Errorf("credits should be granted once, balance %d -> %d", before.Balance, credits.Balance)
+	}
+	if err = op.CancelPaymentOrder(order.OrderNo, user.ID); err == nil {
+		t.Error("paid order should not be cancelled")
+	}
+}
+
+func TestCouponUsageLimit(t *testing.T) {
+	user := &model.User{Username: "coupon_buyer", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	coupon := &model.Coupon{Code: "once", Type: model.CouponPercent, Value: 10, MaxUses: 2, PerUserLimit: 1, Enabled: true}
+	if err := op.SaveCoupon(coupon); err != nil {
+		t.Fatalf("failed to save coupon: %+v", err)
+	}
+
+	var wg sync.WaitGroup
+	var created int32
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := op.CreateCreditsOrder(user.ID, 1000, 0, "alipay", "ONCE"); err == nil {
+				atomic.AddInt32(&created, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if created != 1 {
+		t.Errorf("the per user limit should allow one order, got %d", created)
+	}
+	_, err := op.CreateCreditsOrder(user.ID, 1000, 0, "alipay", "ONCE")
+	if !errors.Is(err, errs.CouponUserLimit) {
+		t.Errorf("expected the per user limit to be reached, got %v", err)
+	}
+}
@@ -1,16 +1,25 @@
 package op
 
 import (
+	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
-	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
+	"github.com/OpenListTeam/OpenList/v4/internal/payment"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
 )
 
+// creditsUpdateRetries 积分更新遇到并发冲突时的最大尝试次数
+const creditsUpdateRetries = 5
+
 // CreateUserCredits 创建用户积分账户
 func CreateUserCredits(userID uint) (*model.UserCredits, error) {
 	// 检查是否已存在积分账户
@@ -31,6 +40,42 @@ func CreateUserCredits(userID uint) (*model.UserCredits, error) {
 	return credits, nil
 }
 
+// provisionUserCredits 用户创建后立即开通积分账户，并按设置项 credits_welcome_balance 发放新用户积分；
+// 开启 credits_welcome_require_email 时新用户积分改在邮箱验证后由 grantWelcomeCredits 发放。
+// 失败只记录日志，积分账户会在首次访问时补建
+func provisionUserCredits(user *model.User) {
+	if user.IsGuest() {
+		return
+	}
+	if _, err := CreateUserCredits(user.ID); err != nil {
+		utils.Log.Errorf("failed to create credits account for user %d: %+v", user.ID, err)
+		return
+	}
+	if !getCreditsSettingBool(conf.CreditsWelcomeRequireEmail) {
+		grantWelcomeCredits(user)
+	}
+}
+
+// grantWelcomeCredits 向新用户发放 credits_welcome_balance 设置的新用户积分，管理员不发放
+func grantWelcomeCredits(user *model.User) {
+	if user.IsAdmin() || user.IsGuest() {
+		return
+	}
+	if welcome := getCreditsSettingInt(conf.CreditsWelcomeBalance, 0); welcome > 0 {
+		if err := grantPromoCredits(user.ID, welcome, "welcome"); err != nil {
+			utils.Log.Errorf("failed to grant welcome credits to user %d: %+v", user.ID, err)
+		}
+	}
+}
+
+// grantVerifiedWelcomeCredits 用户邮箱验证后发放新用户积分，仅在开启 credits_welcome_require_email 时生效，
+// 否则新用户积分已在创建账户时发放
+func grantVerifiedWelcomeCredits(user *model.User) {
+	if getCreditsSettingBool(conf.CreditsWelcomeRequireEmail) {
+		grantWelcomeCredits(user)
+	}
+}
+
 // GetUserCredits 获取用户积分
 func GetUserCredits(userID uint) (*model.UserCredits, error) {
 	credits, err := db.GetUserCreditsByUserID(userID)
@@ -44,97 +89,548 @@ func GetUserCredits(userID uint) (*model.UserCredits, error) {
 	return credits, nil
 }
 
-// AddCredits 增加用户积分
+// AddCredits 增加用户的赠送积分
 func AddCredits(userID uint, amount int64, reason, orderID string) error {
-	credits, err := GetUserCredits(userID)
+	return addCredits(userID, amount, reason, orderID, nil, false)
+}
+
+// AddExpiringCredits 增加有有效期的积分（如促销积分），过期后未使用的部分由 ExpireCreditLots 作废
+func AddExpiringCredits(userID uint, amount int64, reason, orderID string, expiresAt time.Time) error {
+	return addCredits(userID, amount, reason, orderID, &expiresAt, false)
+}
+
+// promoCreditsExpiresAt 按 credits_promo_expire_days 计算新发放的促销积分的过期时间，不过期时返回 nil
+func promoCreditsExpiresAt() *time.Time {
+	days := getCreditsSettingInt(conf.CreditsPromoExpireDays, 0)
+	if days <= 0 {
+		return nil
+	}
+	expiresAt := time.Now().AddDate(0, 0, int(days))
+	return &expiresAt
+}
+
+// grantPromoCredits 发放新用户积分等促销积分，设置了有效期时按有效期发放
+func grantPromoCredits(userID uint, amount int64, reason string) error {
+	if expiresAt := promoCreditsExpiresAt(); expiresAt != nil {
+		return AddExpiringCredits(userID, amount, reason, "", *expiresAt)
+	}
+	return AddCredits(userID, amount, reason, "")
+}
+
+// AddPurchasedCredits 增加用户的购买积分，只有购买积分可以转账和赠送
+func AddPurchasedCredits(userID uint, amount int64, reason, orderID string) error {
+	return addCredits(userID, amount, reason, orderID, nil, true)
+}
+
+func addCredits(userID uint, amount int64, reason, orderID string, expiresAt *time.Time, purchased bool) error {
+	transaction, err := earnTransaction(userID, amount, reason, orderID, expiresAt, purchased)
 	if err != nil {
 		return err
 	}
 
-	// 更新积分
-	credits.Balance += amount
-	credits.TotalEarn += amount
-	err = db.UpdateUserCredits(credits)
+	err = changeUserCredits(transaction)
 	if err != nil {
 		return errors.Wrap(err, "更新用户积分失败")
 	}
 
-	// 记录交易
+	return nil
+}
+
+// earnTransaction 构造获得积分的交易记录
+func earnTransaction(userID uint, amount int64, reason, orderID string, expiresAt *time.Time, purchased bool) (*model.CreditTransaction, error) {
+	if amount <= 0 {
+		return nil, errs.InvalidCreditsAmount
+	}
+
 	transaction := &model.CreditTransaction{
 		UserID:      userID,
 		Amount:      amount,
 		Type:        "earn",
 		Source:      reason,
 		SourceID:    orderID,
-		Balance:     credits.Balance,
 		Description: reason,
+		ExpiresAt:   expiresAt,
+		Purchased:   purchased,
+	}
+	if orderID != "" {
+		if err := transaction.SetMetadata(&model.TransactionMetadata{OrderNo: orderID}); err != nil {
+			return nil, err
+		}
 	}
+	return transaction, nil
+}
+
+// DeductCredits 扣除用户积分
+func DeductCredits(userID uint, amount int64, reason, fileID string) error {
+	return deductCredits(userID, amount, "download", reason, fileID, nil)
+}
 
-	err = db.CreateCreditTransaction(transaction)
+// deductCredits 按指定来源扣除用户积分，fileID 会作为文件路径记录到交易元数据中
+func deductCredits(userID uint, amount int64, source, reason, fileID string, meta *model.TransactionMetadata) error {
+	if amount <= 0 {
+		return errs.InvalidCreditsAmount
+	}
+
+	transaction := &model.CreditTransaction{
+		UserID:      userID,
+		Amount:      -amount,
+		Type:        "spend",
+		Source:      source,
+		SourceID:    fileID,
+		Description: reason,
+	}
+	if meta == nil {
+		meta = &model.TransactionMetadata{}
+	}
+	if meta.FilePath == "" {
+		meta.FilePath = fileID
+	}
+	if err := transaction.SetMetadata(meta); err != nil {
+		return err
+	}
+
+	err := changeUserCredits(transaction)
 	if err != nil {
-		return errors.Wrap(err, "记录积分交易失败")
+		if errors.Is(err, errs.InsufficientCredits) {
+			return err
+		}
+		return errors.Wrap(err, "更新用户积分失败")
 	}
 
 	return nil
 }
 
-// DeductCredits 扣除用户积分
-func DeductCredits(userID uint, amount int64, reason, fileID string) error {
-	credits, err := GetUserCredits(userID)
+// changeUserCredits 变更积分，遇到乐观锁冲突时重试
+func changeUserCredits(transaction *model.CreditTransaction) error {
+	return retryOnCreditsConflict(func() error {
+		transaction.ID = 0
+		return db.ChangeUserCredits(transaction)
+	})
+}
+
+// retryOnCreditsConflict 执行积分账户更新，遇到乐观锁冲突时重试
+func retryOnCreditsConflict(f func() error) error {
+	var err error
+	for i := 0; i < creditsUpdateRetries; i++ {
+		err = f()
+		if !errors.Is(err, errs.CreditsVersionConflict) {
+			return err
+		}
+		time.Sleep(time.Duration(i+1) * 10 * time.Millisecond)
+	}
+	return err
+}
+
+// GetCreditLots 获取用户未用完且未过期的积分批次
+func GetCreditLots(userID uint) ([]model.CreditLot, error) {
+	lots, err := db.GetActiveCreditLots(userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "获取积分批次失败")
+	}
+	return lots, nil
+}
+
+// ExpireCreditLots 作废所有已过期批次的剩余积分
+func ExpireCreditLots() error {
+	lots, err := db.GetExpiredCreditLots(time.Now())
+	if err != nil {
+		return errors.Wrap(err, "获取过期积分批次失败")
+	}
+	for _, lot := range lots {
+		err = retryOnCreditsConflict(func() error {
+			return db.ExpireCreditLot(lot.ID, &model.CreditTransaction{
+				Type:        "expire",
+				Source:      "expire",
+				Description: "积分过期",
+			})
+		})
+		if err != nil {
+			// 单个批次失败不影响其他批次，下次任务重试
+			utils.Log.Errorf("failed to expire credit lot %d of user %d: %+v", lot.ID, lot.UserID, err)
+		}
+	}
+	return nil
+}
+
+// HoldCredits 冻结用户积分，超过 ttl 仍未扣除的冻结会被 ReleaseExpiredCreditHolds 释放
+func HoldCredits(userID uint, amount int64, reason, fileID string, ttl time.Duration) (*model.CreditHold, error) {
+	return holdCredits(userID, amount, "download", reason, fileID, ttl)
+}
+
+func holdCredits(userID uint, amount int64, source, reason, sourceID string, ttl time.Duration) (*model.CreditHold, error) {
+	if amount <= 0 {
+		return nil, errs.InvalidCreditsAmount
+	}
+
+	return createCreditHold(&model.CreditHold{
+		UserID:      userID,
+		Amount:      amount,
+		Source:      source,
+		SourceID:    sourceID,
+		Description: reason,
+		ExpiresAt:   time.Now().Add(ttl),
+	})
+}
+
+// createCreditHold 保存冻结记录并冻结积分
+func createCreditHold(hold *model.CreditHold) (*model.CreditHold, error) {
+	err := retryOnCreditsConflict(func() error {
+		hold.ID = 0
+		return db.CreateCreditHold(hold)
+	})
 	if err != nil {
+		if errors.Is(err, errs.InsufficientCredits) {
+			return nil, err
+		}
+		return nil, errors.Wrap(err, "冻结积分失败")
+	}
+
+	return hold, nil
+}
+
+// GetCreditHold 获取积分预扣记录
+func GetCreditHold(holdID uint) (*model.CreditHold, error) {
+	hold, err := db.GetCreditHoldByID(holdID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.CreditHoldNotFound
+		}
+		return nil, errors.Wrap(err, "获取预扣记录失败")
+	}
+	return hold, nil
+}
+
+// CaptureCreditHold 下载成功后扣除冻结的积分
+func CaptureCreditHold(holdID uint) error {
+	hold, err := GetCreditHold(holdID)
+	if err != nil {
+		return err
+	}
+
+	transaction := &model.CreditTransaction{
+		Type:        "spend",
+		Source:      hold.Source,
+		SourceID:    hold.SourceID,
+		Description: hold.Description,
+	}
+	meta := &model.TransactionMetadata{HoldID: hold.ID}
+	if hold.Source == "download" || hold.Source == "archive" || hold.Source == "preview" {
+		meta.FilePath = hold.SourceID
+	}
+	if err = transaction.SetMetadata(meta); err != nil {
 		return err
 	}
 
-	if credits.Balance < amount {
-		return errors.New("积分不足")
+	err = retryOnCreditsConflict(func() error {
+		transaction.ID = 0
+		return db.CaptureCreditHold(holdID, transaction)
+	})
+	if err != nil {
+		if errors.Is(err, errs.CreditHoldNotActive) {
+			return err
+		}
+		return errors.Wrap(err, "扣除冻结积分失败")
 	}
 
-	// 更新积分
-	credits.Balance -= amount
-	credits.TotalSpent += amount
-	err = db.UpdateUserCredits(credits)
+	if hold.Source == "download" {
+		recordDownloadPurchase(hold.UserID, hold.SourceID, hold.Amount, hold.FileSize)
+	}
+
+	return nil
+}
+
+// ReleaseCreditHold 下载失败或超时后释放冻结的积分
+func ReleaseCreditHold(holdID uint) error {
+	err := retryOnCreditsConflict(func() error {
+		return db.ReleaseCreditHold(holdID)
+	})
 	if err != nil {
-		return errors.Wrap(err, "更新用户积分失败")
+		if errors.Is(err, errs.CreditHoldNotActive) {
+			return err
+		}
+		return errors.Wrap(err, "释放冻结积分失败")
+	}
+	return nil
+}
+
+// ReleaseExpiredCreditHolds 释放所有超时的积分冻结
+func ReleaseExpiredCreditHolds() error {
+	holds, err := db.GetExpiredCreditHolds(time.Now())
+	if err != nil {
+		return errors.Wrap(err, "获取超时预扣记录失败")
+	}
+	for _, hold := range holds {
+		err = ReleaseCreditHold(hold.ID)
+		if err != nil && !errors.Is(err, errs.CreditHoldNotActive) {
+			return err
+		}
+	}
+	return nil
+}
+
+// TransferCredits 用户之间转账积分，手续费比例和每日限额由设置项
+// credits_transfer_fee_percent、credits_transfer_daily_limit 控制，手续费由转出方额外承担
+func TransferCredits(fromUserID, toUserID uint, amount int64, note string) error {
+	if amount <= 0 {
+		return errs.InvalidCreditsAmount
+	}
+	if fromUserID == toUserID {
+		return errs.TransferToSelf
+	}
+	to, err := GetUserById(toUserID)
+	if err != nil {
+		return errs.RecipientNotFound
+	}
+	if to.IsGuest() || to.Disabled {
+		return errs.RecipientUnavailable
+	}
+
+	fee := amount * getCreditsSettingInt(conf.CreditsTransferFeePercent, 0) / 100
+	dailyLimit := getCreditsSettingInt(conf.CreditsTransferDailyLimit, 0)
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	sourceID := generateTransferNo()
+
+	var out, feeTx, in *model.CreditTransaction
+	err = retryOnCreditsConflict(func() error {
+		out = &model.CreditTransaction{
+			UserID:      fromUserID,
+			Amount:      -amount,
+			Type:        "transfer_out",
+			Source:      "transfer",
+			SourceID:    sourceID,
+			Purchased:   true,
+			Description: note,
+		}
+		feeTx = &model.CreditTransaction{
+			UserID:      fromUserID,
+			Amount:      -fee,
+			Type:        "fee",
+			Source:      "transfer",
+			SourceID:    sourceID,
+			Purchased:   true,
+			Description: "转账手续费",
+		}
+		in = &model.CreditTransaction{
+			UserID:      toUserID,
+			Amount:      amount,
+			Type:        "transfer_in",
+			Source:      "transfer",
+			SourceID:    sourceID,
+			Purchased:   true,
+			Description: note,
+		}
+		return db.TransferUserCredits(out, feeTx, in, dailyLimit, today)
+	})
+	if err != nil {
+		if errors.Is(err, errs.InsufficientCredits) || errors.Is(err, errs.TransferLimitExceeded) {
+			return err
+		}
+		return errors.Wrap(err, "积分转账失败")
+	}
+
+	return nil
+}
+
+// AdjustCredits 管理员为用户增加（amount > 0）或扣除（amount < 0）积分，
+// 必须填写原因，操作管理员ID和原因记录在交易的 Metadata 中
+func AdjustCredits(adminID, userID uint, amount int64, reason string) error {
+	if amount == 0 {
+		return errors.New("积分数量不能为0")
+	}
+	if strings.TrimSpace(reason) == "" {
+		return errors.New("必须填写调整原因")
+	}
+	if _, err := GetUserById(userID); err != nil {
+		return errors.WithMessage(err, "用户不存在")
 	}
 
-	// 记录交易
 	transaction := &model.CreditTransaction{
 		UserID:      userID,
-		Amount:      -amount,
-		Type:        "spend",
-		Source:      "download",
-		SourceID:    fileID,
-		Balance:     credits.Balance,
+		Amount:      amount,
+		Type:        "earn",
+		Source:      "admin",
 		Description: reason,
 	}
+	if amount < 0 {
+		transaction.Type = "spend"
+	}
+	err := transaction.SetMetadata(&model.TransactionMetadata{AdminID: adminID, Reason: reason})
+	if err != nil {
+		return err
+	}
 
-	err = db.CreateCreditTransaction(transaction)
+	err = changeUserCredits(transaction)
 	if err != nil {
-		return errors.Wrap(err, "记录积分交易失败")
+		if errors.Is(err, errs.InsufficientCredits) {
+			return err
+		}
+		return errors.Wrap(err, "调整用户积分失败")
 	}
 
 	return nil
 }
 
-// GetCreditTransactions 获取用户积分交易记录
-func GetCreditTransactions(userID uint, page, pageSize int) ([]model.CreditTransaction, int64, error) {
-	return db.GetCreditTransactionsByUserID(userID, page, pageSize)
+// FreezeUserCredits 管理员冻结或解冻用户的积分账户，冻结期间获得或扣除积分都会返回 errs.CreditsFrozen，
+// 冻结时必须填写原因
+func FreezeUserCredits(adminID, userID uint, frozen bool, reason string) error {
+	if frozen && strings.TrimSpace(reason) == "" {
+		return errors.New("必须填写冻结原因")
+	}
+	if _, err := GetUserById(userID); err != nil {
+		return errors.WithMessage(err, "用户不存在")
+	}
+	if !frozen {
+		reason = ""
+	}
+	if err := db.SetUserCreditsFrozen(userID, frozen, reason); err != nil {
+		return errors.Wrap(err, "更新积分账户冻结状态失败")
+	}
+	utils.Log.Infof("admin %d set credits frozen=%v for user %d: %s", adminID, frozen, userID, reason)
+	return nil
 }
 
-// SetFileCreditsConfig 设置文件积分配置
-func SetFileCreditsConfig(path string, credits int64, isFolder bool, createdBy uint) error {
-	config := &model.FileCreditsConfig{
-		Path:      path,
-		Credits:   credits,
-		IsFolder:  isFolder,
-		CreatedBy: createdBy,
+// ListAllCreditTransactions 按筛选条件获取所有用户的积分交易记录
+func ListAllCreditTransactions(filter model.CreditTransactionFilter, page, pageSize int) ([]model.CreditTransaction, int64, error) {
+	transactions, total, err := db.GetCreditTransactions(filter, page, pageSize)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "获取积分交易记录失败")
 	}
+	return transactions, total, nil
+}
 
-	err := db.CreateFileCreditsConfig(config)
+// ListCreditAdjustments 获取管理员积分调整记录
+func ListCreditAdjustments(page, pageSize int) ([]model.CreditTransaction, int64, error) {
+	return db.GetCreditTransactionsBySource("admin", page, pageSize)
+}
+
+// getCreditsSettingInt 读取积分相关的整数设置项，不存在或格式错误时返回默认值
+func getCreditsSettingInt(key string, defaultValue int64) int64 {
+	item, err := GetSettingItemByKey(key)
+	if err != nil {
+		return defaultValue
+	}
+	v, err := strconv.ParseInt(item.Value, 10, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return v
+}
+
+// getCreditsSettingBool 读取积分相关的布尔设置项，不存在时返回 false
+func getCreditsSettingBool(key string) bool {
+	item, err := GetSettingItemByKey(key)
 	if err != nil {
+		return false
+	}
+	return item.Value == "true" || item.Value == "1"
+}
+
+// GetCreditTransactions 获取用户积分交易记录
+func GetCreditTransactions(userID uint, filter model.CreditTransactionFilter, page, pageSize int) ([]model.CreditTransaction, int64, error) {
+	return db.GetCreditTransactionsByUserID(userID, filter, page, pageSize)
+}
+
+// ExportCreditTransactions 分批导出符合条件的积分交易记录
+func ExportCreditTransactions(filter model.CreditTransactionFilter, fn func([]model.CreditTransaction) error) error {
+	if err := db.EachCreditTransactions(filter, 500, fn); err != nil {
+		return errors.Wrap(err, "导出积分交易记录失败")
+	}
+	return nil
+}
+
+// SetFileCreditsConfig 设置文件积分配置，配置启用且可继承，路径已有配置时覆盖
+func SetFileCreditsConfig(path string, credits, previewCredits int64, pricingMode string, isFolder bool, createdBy uint) error {
+	return SaveFileCreditsConfig(&model.FileCreditsConfig{
+		Path:           path,
+		Credits:        credits,
+		PreviewCredits: previewCredits,
+		PricingMode:    pricingMode,
+		IsFolder:       isFolder,
+		Inheritable:    true,
+		Enabled:        true,
+		CreatedBy:      createdBy,
+	})
+}
+
+// SaveFileCreditsConfig 按路径创建或覆盖文件积分配置
+func SaveFileCreditsConfig(config *model.FileCreditsConfig) error {
+	if err := validateFileCreditsConfig(config); err != nil {
+		return err
+	}
+	if err := db.SaveFileCreditsConfig(config); err != nil {
 		return errors.Wrap(err, "设置文件积分配置失败")
 	}
+	invalidateCreditsConfigTree()
+	return nil
+}
 
+// UpdateFileCreditsConfig 按ID更新文件积分配置，可以修改路径
+func UpdateFileCreditsConfig(config *model.FileCreditsConfig) error {
+	if err := validateFileCreditsConfig(config); err != nil {
+		return err
+	}
+	old, err := db.GetFileCreditsConfigByID(config.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("文件积分配置不存在")
+		}
+		return errors.Wrap(err, "获取文件积分配置失败")
+	}
+	if config.Path != old.Path {
+		if _, err = db.FindFileCreditsConfig(config.Path); err == nil {
+			return errors.Errorf("路径 %s 已存在积分配置", config.Path)
+		}
+	}
+	config.CreatedBy = old.CreatedBy
+	if err = db.UpdateFileCreditsConfig(config); err != nil {
+		return errors.Wrap(err, "更新文件积分配置失败")
+	}
+	invalidateCreditsConfigTree()
+	return nil
+}
+
+// validateFileCreditsConfig 校验并规范化文件积分配置
+func validateFileCreditsConfig(config *model.FileCreditsConfig) error {
+	if config.Path == "" {
+		return errors.New("路径不能为空")
+	}
+	config.Path = utils.FixAndCleanPath(config.Path)
+	if config.PricingMode == "" {
+		config.PricingMode = model.PricingFlat
+	}
+	if config.PricingMode != model.PricingFlat && config.PricingMode != model.PricingPerMB && config.PricingMode != model.PricingPerGB {
+		return errors.Errorf("无效的计价方式: %s", config.PricingMode)
+	}
+	if config.Credits < 0 || config.PreviewCredits < 0 {
+		return errors.New("积分不能为负数")
+	}
+	return nil
+}
+
+// FindFileCreditsConfig 获取路径上直接设置的积分配置，包括已停用的配置，不继承父目录配置
+func FindFileCreditsConfig(path string) (*model.FileCreditsConfig, error) {
+	return db.FindFileCreditsConfig(utils.FixAndCleanPath(path))
+}
+
+// ListFileCreditsConfigs 获取文件积分配置列表
+func ListFileCreditsConfigs(page, pageSize int) ([]model.FileCreditsConfig, int64, error) {
+	configs, total, err := db.GetFileCreditsConfigs(page, pageSize)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "获取文件积分配置失败")
+	}
+	return configs, total, nil
+}
+
+// SetFileCreditsConfigEnabled 启用或停用文件积分配置
+func SetFileCreditsConfigEnabled(configID uint, enabled bool) error {
+	if err := db.SetFileCreditsConfigEnabled(configID, enabled); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("文件积分配置不存在")
+		}
+		return errors.Wrap(err, "更新文件积分配置失败")
+	}
+	invalidateCreditsConfigTree()
 	return nil
 }
 
@@ -144,7 +640,7 @@ func GetFileCreditsConfig(path string) (*model.FileCreditsConfig, error) {
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			// 如果没有找到配置，尝试继承父目录配置
-			return db.GetInheritableCreditsConfig(path)
+			return getInheritableCreditsConfig(path)
 		}
 		return nil, errors.Wrap(err, "获取文件积分配置失败")
 	}
@@ -157,91 +653,187 @@ func DeleteFileCreditsConfig(configID uint) error {
 	if err != nil {
 		return errors.Wrap(err, "删除文件积分配置失败")
 	}
+	invalidateCreditsConfigTree()
 	return nil
 }
 
-// GenerateRedeemCodes 批量生成兑换码
-func GenerateRedeemCodes(count int, credits int64, description string, createdBy uint, expiresAt *time.Time) ([]string, error) {
-	codes := make([]string, 0, count)
-
-	for i := 0; i < count; i++ {
-		code := generateRedeemCode()
-		codes = append(codes, code)
-
-		redeemCode := &model.RedeemCode{
-			Code:        code,
-			Credits:     credits,
-			Description: description,
-			CreatedBy:   createdBy,
-			ExpiresAt:   expiresAt,
+// GenerateRedeemCodes 按批次参数批量生成兑换码，同一批生成的兑换码共享一个批次号，批次记录保存后可重新导出；
+// 未指定格式时使用默认格式，未指定最大使用次数时每个兑换码只能使用一次
+func GenerateRedeemCodes(batch *model.RedeemBatch) ([]string, error) {
+	if batch.Format == (model.RedeemCodeFormat{}) {
+		batch.Format = model.DefaultRedeemCodeFormat
+	}
+	if batch.MaxUses <= 0 {
+		batch.MaxUses = 1
+	}
+	if batch.MaxUsesPerUser < 0 {
+		return nil, errors.New("每个用户的使用次数限制不能为负数")
+	}
+	if batch.ExpiresAt != nil && !batch.ExpiresAt.After(time.Now()) {
+		return nil, errors.New("兑换码过期时间必须晚于当前时间")
+	}
+	if err := normalizeRedeemReward(batch); err != nil {
+		return nil, err
+	}
+	if err := validateRedeemCodeFormat(batch.Format, batch.Count); err != nil {
+		return nil, err
+	}
+	if batch.CampaignID != 0 {
+		if err := checkRedeemCampaignBudget(batch); err != nil {
+			return nil, err
 		}
+	}
+	batch.BatchNo = generateRedeemBatchNo()
+	codes, err := generateUniqueRedeemCodes(batch.Format, batch.Count)
+	if err != nil {
+		return nil, err
+	}
+	redeemCodes := make([]model.RedeemCode, 0, batch.Count)
+	for _, code := range codes {
+		redeemCodes = append(redeemCodes, model.RedeemCode{
+			Code:           code,
+			BatchNo:        batch.BatchNo,
+			Credits:        batch.Credits,
+			RewardType:     batch.RewardType,
+			RewardPayload:  batch.RewardPayload,
+			MaxUses:        batch.MaxUses,
+			MaxUsesPerUser: batch.MaxUsesPerUser,
+			Enabled:        true,
+			Description:    batch.Description,
+			CreatedBy:      batch.CreatedBy,
+			ExpiresAt:      batch.ExpiresAt,
+		})
+	}
 
-		err := db.CreateRedeemCode(redeemCode)
-		if err != nil {
-			return nil, errors.Wrap(err, "创建兑换码失败")
-		}
+	if err := db.CreateRedeemBatch(batch, redeemCodes); err != nil {
+		return nil, errors.Wrap(err, "创建兑换码失败")
 	}
 
 	return codes, nil
 }
 
-// RedeemCode 兑换积分码
-func RedeemCode(userID uint, code string) error {
-	redeemCode, err := db.GetRedeemCodeByCode(code)
+// GetRedeemCodeInfo 获取兑换码的面值、有效期和当前用户能否兑换，不会使用兑换码
+func GetRedeemCodeInfo(userID uint, code string) (*model.RedeemCodeInfo, error) {
+	redeemCode, err := GetRedeemCode(code)
+	if err != nil {
+		return nil, err
+	}
+	info := &model.RedeemCodeInfo{
+		Code:          redeemCode.Code,
+		Credits:       redeemCode.Credits,
+		RewardType:    redeemCode.RewardType,
+		RewardPayload: redeemCode.RewardPayload,
+		ExpiresAt:     redeemCode.ExpiresAt,
+		RemainingUses: max(redeemCode.MaxUses-redeemCode.UsedCount, 0),
+	}
+	err = checkRedeemCodeUsable(userID, redeemCode)
+	if err != nil && !errors.Is(err, errs.RedeemCodeUnavailable) && !errors.Is(err, errs.RedeemCodeUserLimit) {
+		return nil, err
+	}
+	info.Available = err == nil
+	return info, nil
+}
+
+// GetRedeemCode 获取启用的兑换码，忽略首尾空白
+func GetRedeemCode(code string) (*model.RedeemCode, error) {
+	redeemCode, err := db.GetRedeemCodeByCode(strings.TrimSpace(code))
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("兑换码不存在")
+			return nil, errs.RedeemCodeNotFound
 		}
-		return errors.Wrap(err, "获取兑换码失败")
+		return nil, errors.Wrap(err, "获取兑换码失败")
 	}
+	return redeemCode, nil
+}
 
+// checkRedeemCodeUsable 检查兑换码是否可用，以及用户是否已达到该兑换码的使用次数上限
+func checkRedeemCodeUsable(userID uint, redeemCode *model.RedeemCode) error {
 	if !redeemCode.CanUse() {
-		return errors.New("兑换码已使用或已过期")
+		return errs.RedeemCodeUnavailable
 	}
-
-	// 更新兑换码使用次数
-	redeemCode.UsedCount++
-	err = db.UpdateRedeemCode(redeemCode)
-	if err != nil {
-		return errors.Wrap(err, "更新兑换码状态失败")
+	if redeemCode.MaxUsesPerUser > 0 {
+		used, err := db.CountUserRedeemCodeUsages(redeemCode.ID, userID)
+		if err != nil {
+			return errors.Wrap(err, "获取兑换码使用记录失败")
+		}
+		if used >= int64(redeemCode.MaxUsesPerUser) {
+			return errs.RedeemCodeUserLimit
+		}
 	}
+	return nil
+}
 
-	// 记录使用记录
-	usage := &model.RedeemCodeUsage{
-		UserID:       userID,
-		RedeemCodeID: redeemCode.ID,
-		Credits:      redeemCode.Credits,
-		UsedAt:       time.Now(),
+// RedeemCode 使用兑换码，按兑换码的奖励类型发放积分、会员时长或解锁文件
+func RedeemCode(userID uint, code string) error {
+	redeemCode, err := GetRedeemCode(code)
+	if err != nil {
+		return err
 	}
-	err = db.CreateRedeemCodeUsage(usage)
+	reward, err := redeemCodeReward(userID, redeemCode)
 	if err != nil {
-		return errors.Wrap(err, "记录兑换码使用失败")
+		return err
 	}
 
-	// 增加用户积分
-	err = AddCredits(userID, redeemCode.Credits, fmt.Sprintf("兑换码: %s", code), "")
+	// 占用使用次数、记录使用和发放奖励在同一个事务中完成，并发兑换同一兑换码时不会超出使用次数
+	err = retryOnCreditsConflict(func() error {
+		if reward.Transaction != nil {
+			reward.Transaction.ID = 0
+		}
+		return db.RedeemCode(redeemCode, &model.RedeemCodeUsage{
+			UserID:       userID,
+			RedeemCodeID: redeemCode.ID,
+			Credits:      redeemCode.Credits,
+			UsedAt:       time.Now(),
+		}, reward)
+	})
 	if err != nil {
-		return errors.Wrap(err, "增加积分失败")
+		if errors.Is(err, errs.RedeemCodeUnavailable) || errors.Is(err, errs.RedeemCodeUserLimit) ||
+			errors.Is(err, errs.CreditsFrozen) {
+			return err
+		}
+		return errors.Wrap(err, "兑换失败")
+	}
+	if reward.VipDays > 0 {
+		if user, err := db.GetUserById(userID); err == nil {
+			userCache.Del(user.Username)
+		}
 	}
-
 	return nil
 }
 
-// CreatePaymentOrder 创建支付订单
-func CreatePaymentOrder(userID uint, amount int64, credits int64, paymentMethod string) (*model.PaymentOrder, error) {
+// CreatePaymentOrder 创建支付订单，couponCode 不为空时按优惠券减免订单金额
+func CreatePaymentOrder(userID uint, amount model.Money, credits int64, paymentMethod, couponCode string) (*model.PaymentOrder, error) {
 	orderNo := generateOrderID()
 
 	order := &model.PaymentOrder{
 		OrderNo:       orderNo,
 		UserID:        userID,
-		Amount:        amount,
+		Money:         amount,
 		Credits:       credits,
 		PaymentMethod: paymentMethod,
-		Status:        "pending",
+		Status:        model.PaymentOrderPending,
 		ExpiresAt:     time.Now().Add(30 * time.Minute), // 30分钟过期
 	}
 
-	err := db.CreatePaymentOrder(order)
+	if couponCode == "" {
+		if err := db.CreatePaymentOrder(order); err != nil {
+			return nil, errors.Wrap(err, "创建支付订单失败")
+		}
+		return order, nil
+	}
+
+	coupon, discount, err := applyCoupon(couponCode, amount)
+	if err != nil {
+		return nil, err
+	}
+	order.CouponCode = coupon.Code
+	order.Discount = discount
+	order.Money.Amount -= discount
+	// 在同一个事务中校验使用次数并创建订单，避免并发下单超出限制
+	err = db.CreateCouponOrder(order, coupon)
+	if errors.Is(err, errs.CouponExhausted) || errors.Is(err, errs.CouponUserLimit) || errors.Is(err, errs.CouponNotFound) {
+		return nil, err
+	}
 	if err != nil {
 		return nil, errors.Wrap(err, "创建支付订单失败")
 	}
@@ -264,40 +856,119 @@ func ListPaymentOrders(userID uint, page, pageSize int) ([]model.PaymentOrder, i
 	return db.GetPaymentOrdersByUserID(userID, page, pageSize)
 }
 
-// CompletePaymentOrder 完成支付订单
-func CompletePaymentOrder(orderNo string, transactionID string, amount float64, paidAt time.Time) error {
+// CompletePaymentOrder 完成支付订单，amount 为支付渠道确认的实付金额，须与订单金额一致
+func CompletePaymentOrder(orderNo string, transactionID string, amount model.Money, paidAt time.Time) error {
 	order, err := db.GetPaymentOrderByOrderNo(orderNo)
 	if err != nil {
 		return errors.Wrap(err, "获取支付订单失败")
 	}
 
-	if order.Status != "pending" {
-		return errors.New("订单状态异常")
+	if order.Status != model.PaymentOrderPending {
+		return errs.PaymentOrderInvalidStatus
 	}
 
 	if order.IsExpired() {
-		return errors.New("订单已过期")
+		return errs.PaymentOrderExpired
+	}
+
+	if !amount.Equal(order.Money) {
+		return errors.Errorf("支付金额不一致: 订单 %s, 实付 %s", order.Money.MajorString(), amount.MajorString())
 	}
 
-	// 更新订单状态
-	order.Status = "completed"
+	// 在同一个事务中更新订单状态并入账，并发的支付通知、同步返回和主动查询只有一个能完成订单
 	order.PaymentData = fmt.Sprintf(`{"transaction_id":"%s"}`, transactionID)
 	order.PaidAt = &paidAt
-
-	err = db.UpdatePaymentOrder(order)
+	var transaction *model.CreditTransaction
+	var months int
+	switch {
+	case order.ShareToken != "":
+		// 访客订单不入账，支付后签发下载令牌
+	case order.PlanID != 0:
+		// 开通或续费会员
+		plan, err := db.GetSubscriptionPlanByID(order.PlanID)
+		if err != nil {
+			return errors.Wrap(err, "获取会员套餐失败")
+		}
+		months = plan.Months
+	default:
+		// 增加用户积分
+		transaction, err = earnTransaction(order.UserID, order.Credits, fmt.Sprintf("购买积分: %s", orderNo), orderNo, nil, true)
+		if err != nil {
+			return err
+		}
+	}
+	var paid bool
+	err = retryOnCreditsConflict(func() error {
+		if transaction != nil {
+			transaction.ID = 0
+		}
+		paid, err = db.PayPaymentOrder(order, transaction, months)
+		return err
+	})
 	if err != nil {
-		return errors.Wrap(err, "更新支付订单失败")
+		return errors.Wrap(err, "完成支付订单失败")
+	}
+	if !paid {
+		return errs.PaymentOrderInvalidStatus
+	}
+	order.Status = model.PaymentOrderPaid
+	if months > 0 {
+		if user, err := db.GetUserById(order.UserID); err == nil {
+			userCache.Del(user.Username)
+		}
 	}
 
-	// 增加用户积分
-	err = AddCredits(order.UserID, order.Credits, fmt.Sprintf("购买积分: %s", orderNo), orderNo)
-	if err != nil {
-		return errors.Wrap(err, "增加积分失败")
+	if order.ShareToken != "" {
+		// 访客购买分享文件的下载，签发下载令牌，失败时访客领取时补签
+		if _, err = fulfillShareOrder(order); err != nil {
+			utils.Log.Errorf("failed to issue download token for share order %s: %+v", orderNo, err)
+		}
+		notifyPaymentCompleted(order)
+		return nil
 	}
 
+	recordCouponUsage(order)
+	logMailError(sendPaymentReceipt(order))
+	notifyPaymentCompleted(order)
+
+	// 被推荐用户首次购买奖励
+	logReferralError(RewardReferralFirstPurchase(order.UserID))
+
 	return nil
 }
 
+// SyncPaymentOrder 主动向支付渠道查询订单状态，确认已支付后入账。
+// 用于同步返回（return_url）场景：异步通知可能尚未到达，且不能信任客户端带回的参数。
+func SyncPaymentOrder(orderNo string) (*model.PaymentOrder, error) {
+	order, err := db.GetPaymentOrderByOrderNo(orderNo)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.PaymentOrderNotFound
+		}
+		return nil, errors.Wrap(err, "获取支付订单失败")
+	}
+	if order.Status != model.PaymentOrderPending {
+		return order, nil
+	}
+
+	verification, err := payment.GetPaymentManager().QueryPayment(order.PaymentMethod, orderNo)
+	if err != nil {
+		return nil, errors.Wrap(err, "查询支付状态失败")
+	}
+	if !verification.Success {
+		return order, nil
+	}
+	err = CompletePaymentOrder(orderNo, verification.TransactionID, verification.Amount, verification.PaidAt)
+	if err != nil {
+		// 异步通知可能已抢先完成订单
+		if latest, e := db.GetPaymentOrderByOrderNo(orderNo); e == nil && latest.IsPaid() {
+			return latest, nil
+		}
+		return nil, err
+	}
+	return db.GetPaymentOrderByOrderNo(orderNo)
+}
+
 // CancelPaymentOrder 取消支付订单
 func CancelPaymentOrder(orderNo string, userID uint) error {
 	order, err := db.GetPaymentOrderByOrderNo(orderNo)
@@ -305,15 +976,18 @@ func CancelPaymentOrder(orderNo string, userID uint) error {
 		return errors.Wrap(err, "获取支付订单失败")
 	}
 
-	if order.Status != "pending" {
-		return errors.New("订单状态异常")
+	if order.Status != model.PaymentOrderPending {
+		return errs.PaymentOrderInvalidStatus
 	}
 
-	order.Status = "cancelled"
-	err = db.UpdatePaymentOrder(order)
+	// 订单可能同时被支付，只取消仍待支付的订单
+	cancelled, err := db.CancelPaymentOrder(order.ID)
 	if err != nil {
 		return errors.Wrap(err, "更新支付订单失败")
 	}
+	if !cancelled {
+		return errs.PaymentOrderInvalidStatus
+	}
 
 	return nil
 }
@@ -323,60 +997,297 @@ func CleanExpiredPaymentOrders() error {
 	return db.CleanExpiredPaymentOrders()
 }
 
-// generateRedeemCode 生成兑换码
-func generateRedeemCode() string {
-	return "OL" + random.String(12)
+// generateOrderID 生成订单ID（基于雪花算法，多实例部署时按节点ID区分）
+func generateOrderID() string {
+	return "OL" + utils.NextSnowflakeString()
 }
 
-// generateOrderID 生成订单ID
-func generateOrderID() string {
-	return fmt.Sprintf("OL%d%s", time.Now().Unix(), random.String(8))
+// generateTransferNo 生成转账流水号
+func generateTransferNo() string {
+	return "TF" + utils.NextSnowflakeString()
 }
 
-// CheckFileDownloadPermission 检查文件下载权限和积分
+// generateRedeemBatchNo 生成兑换码批次号
+func generateRedeemBatchNo() string {
+	return "RB" + utils.NextSnowflakeString()
+}
+
+// CheckFileDownloadPermission 检查文件下载权限和积分，使用每日免费额度时所需积分为0
 func CheckFileDownloadPermission(userID uint, filePath string) (bool, int64, error) {
-	// 获取文件积分配置
-	config, err := GetFileCreditsConfig(filePath)
+	return CheckFileAccessPermission(userID, filePath, model.CreditsActionDownload)
+}
+
+// CheckFilePreviewPermission 检查文件在线预览权限和积分
+func CheckFilePreviewPermission(userID uint, filePath string) (bool, int64, error) {
+	return CheckFileAccessPermission(userID, filePath, model.CreditsActionPreview)
+}
+
+// CheckFileAccessPermission 按访问方式（下载或预览）检查文件权限和积分
+func CheckFileAccessPermission(userID uint, filePath string, action string) (bool, int64, error) {
+	return CheckSizedFileAccessPermission(userID, filePath, action, -1)
+}
+
+// CheckSizedFileAccessPermission 与 CheckFileAccessPermission 相同，size 为调用方已知的文件大小，
+// 批量检查目录中的文件时可避免逐个获取文件大小，小于0时按需获取
+func CheckSizedFileAccessPermission(userID uint, filePath string, action string, size int64) (bool, int64, error) {
+	check, err := checkFileCharge(userID, filePath, action, size)
 	if err != nil {
-		// 如果没有配置，默认免费
+		return false, check.required, err
+	}
+	if check.quota != nil {
 		return true, 0, nil
 	}
+	return check.allowed, check.required, nil
+}
 
-	if config.Credits <= 0 {
-		// 免费文件
-		return true, 0, nil
+// EstimateFileCredits 估算用户下载或预览文件所需积分，与实际扣费使用相同的计价逻辑，但不使用每日免费额度，
+// 用于目录下载前汇总费用；size 小于0时按需获取文件大小
+func EstimateFileCredits(userID uint, filePath string, action string, size int64) (int64, error) {
+	required, _, err := fileCharge(userID, filePath, action, size)
+	return required, err
+}
+
+// downloadCheck 文件下载或预览的计费检查结果
+type downloadCheck struct {
+	allowed  bool
+	required int64                // 所需积分
+	size     int64                // 文件大小，小于等于0表示未获取
+	quota    *model.DownloadQuota // 不为 nil 时本次下载可使用每日免费额度
+}
+
+// checkFileCharge 计算下载或预览文件所需积分，免费额度足够时不检查积分余额。
+// size 为已知的文件大小，小于0时按大小计价的文件会获取文件大小
+func checkFileCharge(userID uint, filePath string, action string, size int64) (downloadCheck, error) {
+	required, size, err := fileCharge(userID, filePath, action, size)
+	if err != nil {
+		return downloadCheck{required: required}, err
+	}
+	if required <= 0 {
+		return downloadCheck{allowed: true}, nil
+	}
+
+	// 每日免费额度内的下载不扣积分
+	if action == model.CreditsActionDownload {
+		quota, size, err := checkDownloadQuota(userID, filePath, size)
+		if err != nil {
+			return downloadCheck{required: required}, err
+		}
+		if quota != nil {
+			return downloadCheck{allowed: true, required: required, size: size, quota: quota}, nil
+		}
 	}
 
 	// 检查用户积分
 	userCredits, err := GetUserCredits(userID)
 	if err != nil {
-		return false, config.Credits, err
+		return downloadCheck{required: required}, err
+	}
+
+	return downloadCheck{allowed: userCredits.Available() >= required, required: required, size: size}, nil
+}
+
+// fileCharge 按积分配置、计价规则、豁免名单、会员、已购记录、定价组和促销计算用户访问文件所需积分，
+// 不考虑每日免费额度和积分余额，同时返回按需获取的文件大小
+func fileCharge(userID uint, filePath string, action string, size int64) (int64, int64, error) {
+	// 获取文件积分配置
+	config, _, err := ResolveFileCreditsConfig(filePath)
+	if err != nil {
+		// 如果没有配置，默认免费
+		return 0, size, nil
+	}
+
+	cost := config.CostFor(action, 0)
+	if config.IsSizeBased() {
+		// 按文件大小计价
+		if size < 0 {
+			size, err = getFileSize(filePath)
+			if err != nil {
+				return 0, size, errors.WithMessage(err, "获取文件大小失败")
+			}
+		}
+		cost = config.CostFor(action, size)
+	}
+
+	if cost <= 0 {
+		// 免费文件
+		return 0, size, nil
+	}
+
+	// 豁免名单中的用户和定价组成员免费
+	exempt, err := isFileCreditsExempt(config, userID)
+	if err != nil {
+		return cost, size, err
+	}
+	if exempt {
+		return 0, size, nil
+	}
+
+	// 会员在指定目录下免积分下载
+	if IsVipFreePath(filePath) {
+		if user, err := GetUserById(userID); err == nil && user.IsVip() {
+			return 0, size, nil
+		}
+	}
+
+	// 有效期内已付费下载过的文件不再扣积分
+	purchased, err := HasValidDownloadPurchase(userID, filePath)
+	if err != nil {
+		return cost, size, err
+	}
+	if purchased {
+		return 0, size, nil
+	}
+
+	// 按用户所属定价组调整价格
+	required, err := ApplyPricingGroup(userID, cost)
+	if err != nil {
+		return cost, size, err
+	}
+	// 促销活动期间打折或免费
+	if required, err = ApplyPromotion(filePath, required); err != nil {
+		return cost, size, err
 	}
+	return required, size, nil
+}
 
-	if userCredits.Balance < config.Credits {
-		return false, config.Credits, nil
+// metadata 补充文件大小后返回交易元数据
+func (check downloadCheck) metadata(meta *model.TransactionMetadata) *model.TransactionMetadata {
+	if meta == nil {
+		meta = &model.TransactionMetadata{}
+	}
+	if check.size > 0 {
+		meta.FileSize = check.size
 	}
+	return meta
+}
 
-	return true, config.Credits, nil
+// useDownloadQuota 尝试使用每日免费额度，返回非 nil 表示本次下载无需扣积分，下载失败时凭返回值退还额度；
+// 额度被并发的下载用完时返回 nil，按正常价格扣费
+func useDownloadQuota(userID uint, check downloadCheck) (*quotaUse, error) {
+	if check.quota == nil {
+		return nil, nil
+	}
+	return consumeDownloadQuota(userID, check.size, check.quota)
 }
 
-// ProcessFileDownload 处理文件下载（扣除积分）
-func ProcessFileDownload(userID uint, filePath string) error {
-	canDownload, requiredCredits, err := CheckFileDownloadPermission(userID, filePath)
+// getFileSize 获取文件大小
+func getFileSize(filePath string) (int64, error) {
+	storage, actualPath, err := GetStorageAndActualPath(filePath)
 	if err != nil {
-		return err
+		return 0, err
+	}
+	obj, err := Get(context.Background(), storage, actualPath)
+	if err != nil {
+		return 0, err
+	}
+	return obj.GetSize(), nil
+}
+
+// ProcessFileDownload 处理文件下载（扣除积分），meta 为可选的客户端信息，记录到交易元数据中
+func ProcessFileDownload(userID uint, filePath string, meta *model.TransactionMetadata) error {
+	_, err := processFileDownload(userID, filePath, meta)
+	return err
+}
+
+// processFileDownload 与 ProcessFileDownload 相同，返回扣除的积分
+func processFileDownload(userID uint, filePath string, meta *model.TransactionMetadata) (int64, error) {
+	check, err := checkFileCharge(userID, filePath, model.CreditsActionDownload, -1)
+	if err != nil {
+		return 0, err
 	}
 
-	if !canDownload {
-		return errors.New("积分不足")
+	if !check.allowed {
+		return 0, errs.InsufficientCredits
 	}
 
-	if requiredCredits > 0 {
-		err = DeductCredits(userID, requiredCredits, fmt.Sprintf("下载文件: %s", filePath), filePath)
+	if used, err := useDownloadQuota(userID, check); err != nil || used != nil {
+		return 0, err
+	}
+
+	if check.required > 0 {
+		err = deductCredits(userID, check.required, "download", fmt.Sprintf("下载文件: %s", filePath), filePath, check.metadata(meta))
 		if err != nil {
-			return err
+			return 0, err
 		}
+		recordDownloadPurchase(userID, filePath, check.required, check.size)
+	}
+
+	return check.required, nil
+}
+
+// ProcessFilePreview 处理文件在线预览（按预览价格扣除积分），meta 为可选的客户端信息
+func ProcessFilePreview(userID uint, filePath string, meta *model.TransactionMetadata) error {
+	check, err := checkFileCharge(userID, filePath, model.CreditsActionPreview, -1)
+	if err != nil {
+		return err
+	}
+
+	if !check.allowed {
+		return errs.InsufficientCredits
+	}
+
+	if check.required > 0 {
+		return deductCredits(userID, check.required, "preview", fmt.Sprintf("预览文件: %s", filePath), filePath, check.metadata(meta))
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// holdFilePreview 按文件的预览价格冻结积分，免费时返回 nil
+func holdFilePreview(userID uint, filePath string, ttl time.Duration) (*model.CreditHold, error) {
+	check, err := checkFileCharge(userID, filePath, model.CreditsActionPreview, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	if !check.allowed {
+		return nil, errs.InsufficientCredits
+	}
+
+	if check.required <= 0 {
+		return nil, nil
+	}
+
+	return createCreditHold(&model.CreditHold{
+		UserID:      userID,
+		Amount:      check.required,
+		Source:      "preview",
+		SourceID:    filePath,
+		Description: fmt.Sprintf("预览文件: %s", filePath),
+		FileSize:    max(check.size, 0),
+		ExpiresAt:   time.Now().Add(ttl),
+	})
+}
+
+// holdFileCharge 按文件的下载价格冻结积分，size 为计价使用的大小，小于0时按需获取文件大小；
+// source 为 download 的冻结扣除后记录为已购文件。使用每日免费额度时返回额度的使用记录，
+// 免费文件两者都返回 nil
+func holdFileCharge(userID uint, filePath string, size int64, source, reason string, ttl time.Duration) (*model.CreditHold, *quotaUse, error) {
+	check, err := checkFileCharge(userID, filePath, model.CreditsActionDownload, size)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !check.allowed {
+		return nil, nil, errs.InsufficientCredits
+	}
+
+	if used, err := useDownloadQuota(userID, check); err != nil || used != nil {
+		return nil, used, err
+	}
+
+	if check.required <= 0 {
+		return nil, nil, nil
+	}
+
+	hold, err := createCreditHold(&model.CreditHold{
+		UserID:      userID,
+		Amount:      check.required,
+		Source:      source,
+		SourceID:    filePath,
+		Description: reason,
+		FileSize:    max(check.size, 0),
+		ExpiresAt:   time.Now().Add(ttl),
+	})
+	return hold, nil, err
+}
@@ -0,0 +1,66 @@
+package op
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// MaxBulkCreditsConfigs 单次批量设置积分配置的最大路径数
+const MaxBulkCreditsConfigs = 10000
+
+// BulkSetFileCreditsConfig 在一个事务中为多个路径设置相同的积分配置，已有配置的路径会被覆盖，
+// paths 的值表示该路径是否为文件夹，返回设置的路径数
+func BulkSetFileCreditsConfig(paths map[string]bool, template model.FileCreditsConfig) (int, error) {
+	if len(paths) == 0 {
+		return 0, errors.New("路径不能为空")
+	}
+	if len(paths) > MaxBulkCreditsConfigs {
+		return 0, errors.Errorf("单次最多设置 %d 个路径", MaxBulkCreditsConfigs)
+	}
+	template.Path = "/"
+	if err := validateFileCreditsConfig(&template); err != nil {
+		return 0, err
+	}
+
+	configs := make([]*model.FileCreditsConfig, 0, len(paths))
+	for path, isFolder := range paths {
+		config := template
+		config.Path = utils.FixAndCleanPath(path)
+		config.IsFolder = isFolder
+		configs = append(configs, &config)
+	}
+	if err := db.SaveFileCreditsConfigs(configs); err != nil {
+		return 0, errors.Wrap(err, "批量设置文件积分配置失败")
+	}
+	invalidateCreditsConfigTree()
+	return len(configs), nil
+}
+
+// ClearFileCreditsConfigs 删除指定路径的积分配置，返回删除的数量
+func ClearFileCreditsConfigs(paths []string) (int64, error) {
+	if len(paths) == 0 {
+		return 0, errors.New("路径不能为空")
+	}
+	cleaned := make([]string, 0, len(paths))
+	for _, path := range paths {
+		cleaned = append(cleaned, utils.FixAndCleanPath(path))
+	}
+	count, err := db.DeleteFileCreditsConfigsByPaths(cleaned)
+	if err != nil {
+		return 0, errors.Wrap(err, "批量删除文件积分配置失败")
+	}
+	invalidateCreditsConfigTree()
+	return count, nil
+}
+
+// ClearFileCreditsConfigTree 删除 root 及其下所有路径的积分配置，返回删除的数量
+func ClearFileCreditsConfigTree(root string) (int64, error) {
+	count, err := db.DeleteFileCreditsConfigTree(utils.FixAndCleanPath(root))
+	if err != nil {
+		return 0, errors.Wrap(err, "删除目录积分配置失败")
+	}
+	invalidateCreditsConfigTree()
+	return count, nil
+}
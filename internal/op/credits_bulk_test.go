@@ -0,0 +1,138 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestBulkFileCreditsConfig(t *testing.T) {
+	if err := op.SetFileCreditsConfig("/bulk_a/one.zip", 1, 0, model.PricingFlat, false, 1); err != nil {
+		t.Fatalf("failed to set file config: %+v", err)
+	}
+	paths := map[string]bool{"/bulk_a/one.zip": false, "/bulk_a/two.zip": false, "/bulkXa/three.zip": false}
+	count, err := op.BulkSetFileCreditsConfig(paths, model.FileCreditsConfig{Credits: 7, Enabled: true})
+	if err != nil || count != 3 {
+		t.Fatalf("failed to bulk set configs: %d, %+v", count, err)
+	}
+	config, err := op.GetFileCreditsConfig("/bulk_a/one.zip")
+	if err != nil || config.Credits != 7 || config.Inheritable {
+		t.Fatalf("expected overwritten config, got %+v, %+v", config, err)
+	}
+
+	cleared, err := op.ClearFileCreditsConfigTree("/bulk_a")
+	if err != nil || cleared != 2 {
+		t.Fatalf("expected 2 cleared configs, got %d, %+v", cleared, err)
+	}
+	if _, err = op.GetFileCreditsConfig("/bulkXa/three.zip"); err != nil {
+		t.Errorf("config outside the tree should be kept: %+v", err)
+	}
+}
+
+func TestSaveFileCreditsConfig(t *testing.T) {
+	for _, credits := range []int64{3, 5} {
+		if err := op.SetFileCreditsConfig("/upsert/file.zip", credits, 0, model.PricingFlat, false, 1); err != nil {
+			t.Fatalf("failed to set file config: %+v", err)
+		}
+	}
+	config, err := op.FindFileCreditsConfig("/upsert/file.zip")
+	if err != nil || config.Credits != 5 {
+		t.Fatalf("expected upserted config, got %+v, %+v", config, err)
+	}
+	if err = op.SetFileCreditsConfigEnabled(config.ID, false); err != nil {
+		t.Fatalf("failed to disable config: %+v", err)
+	}
+	if allowed, required, _ := op.CheckFileDownloadPermission(1, "/upsert/file.zip"); !allowed || required != 0 {
+		t.Errorf("disabled config should not charge, got %v, %d", allowed, required)
+	}
+	config.Path = "/upsert/renamed.zip"
+	config.Enabled = true
+	if err = op.UpdateFileCreditsConfig(config); err != nil {
+		t.Fatalf("failed to update config: %+v", err)
+	}
+	if _, err = op.FindFileCreditsConfig("/upsert/file.zip"); err == nil {
+		t.Errorf("expected old path to be gone after update")
+	}
+	if updated, err := op.FindFileCreditsConfig("/upsert/renamed.zip"); err != nil || !updated.Enabled {
+		t.Errorf("expected enabled config at new path, got %+v, %+v", updated, err)
+	}
+}
+
+func TestMoveFileCreditsConfigs(t *testing.T) {
+	for _, path := range []string{"/sync/dir", "/sync/dir/a.zip", "/sync/dir_b/b.zip"} {
+		if err := op.SetFileCreditsConfig(path, 4, 0, model.PricingFlat, path == "/sync/dir", 1); err != nil {
+			t.Fatalf("failed to set file config: %+v", err)
+		}
+	}
+	count, err := op.MoveFileCreditsConfigs("/sync/dir", "/sync/moved")
+	if err != nil || count != 2 {
+		t.Fatalf("expected 2 moved configs, got %d, %+v", count, err)
+	}
+	if _, err = op.FindFileCreditsConfig("/sync/moved/a.zip"); err != nil {
+		t.Errorf("expected config at moved path: %+v", err)
+	}
+	if _, err = op.FindFileCreditsConfig("/sync/dir_b/b.zip"); err != nil {
+		t.Errorf("config of sibling path should be kept: %+v", err)
+	}
+
+	if count, err = op.OrphanFileCreditsConfigs("/sync/moved"); err != nil || count != 2 {
+		t.Fatalf("expected 2 orphaned configs, got %d, %+v", count, err)
+	}
+	if count, err = op.CleanOrphanedFileCreditsConfigs(); err != nil || count != 2 {
+		t.Fatalf("expected 2 cleaned configs, got %d, %+v", count, err)
+	}
+	if _, err = op.FindFileCreditsConfig("/sync/moved/a.zip"); err == nil {
+		t.Errorf("expected orphaned config to be cleaned")
+	}
+}
+
+func TestImportFileCreditsConfigs(t *testing.T) {
+	if err := op.SetFileCreditsConfig("/io/existing.zip", 2, 0, model.PricingFlat, false, 1); err != nil {
+		t.Fatalf("failed to set file config: %+v", err)
+	}
+	doc := &model.CreditsConfigDocument{
+		Version: model.CreditsConfigDocumentVersion,
+		Configs: []model.CreditsConfigEntry{
+			{Path: "/io/existing.zip", Credits: 9, PricingMode: model.PricingFlat, Inheritable: true, Enabled: true},
+			{Path: "/io/new.zip", Credits: 1, PricingMode: model.PricingFlat, Enabled: true},
+		},
+	}
+	result, err := op.ImportFileCreditsConfigs(doc, model.ImportConflictFail, false, 1)
+	if err == nil || len(result.Conflicts) != 1 {
+		t.Fatalf("expected conflict failure, got %+v, %v", result, err)
+	}
+	if _, err = op.FindFileCreditsConfig("/io/new.zip"); err == nil {
+		t.Errorf("nothing should be imported when failing on conflicts")
+	}
+	result, err = op.ImportFileCreditsConfigs(doc, model.ImportConflictOverwrite, true, 1)
+	if err != nil || result.Created != 1 || result.Updated != 1 {
+		t.Fatalf("unexpected dry run result %+v, %+v", result, err)
+	}
+	if _, err = op.ImportFileCreditsConfigs(doc, model.ImportConflictOverwrite, false, 1); err != nil {
+		t.Fatalf("failed to import configs: %+v", err)
+	}
+	config, err := op.FindFileCreditsConfig("/io/existing.zip")
+	if err != nil || config.Credits != 9 {
+		t.Errorf("expected overwritten config, got %+v, %+v", config, err)
+	}
+}
+
+func TestInheritableCreditsConfig(t *testing.T) {
+	if err := op.SetFileCreditsConfig("/inherit/paid", 6, 0, model.PricingFlat, true, 1); err != nil {
+		t.Fatalf("failed to set folder config: %+v", err)
+	}
+	config, err := op.GetFileCreditsConfig("/inherit/paid/sub/file.zip")
+	if err != nil || config.Credits != 6 {
+		t.Fatalf("expected inherited config, got %+v, %+v", config, err)
+	}
+	if _, err = op.GetFileCreditsConfig("/inherit/paid_free/file.zip"); err == nil {
+		t.Errorf("sibling folder with the same prefix should not inherit")
+	}
+	if err = op.SetFileCreditsConfigEnabled(config.ID, false); err != nil {
+		t.Fatalf("failed to disable config: %+v", err)
+	}
+	if _, err = op.GetFileCreditsConfig("/inherit/paid/sub/file.zip"); err == nil {
+		t.Errorf("disabled folder config should not be inherited")
+	}
+}
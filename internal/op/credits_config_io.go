@@ -0,0 +1,99 @@
+package op
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// ExportFileCreditsConfigs 导出所有文件积分配置
+func ExportFileCreditsConfigs() (*model.CreditsConfigDocument, error) {
+	doc := &model.CreditsConfigDocument{
+		Version:    model.CreditsConfigDocumentVersion,
+		ExportedAt: time.Now(),
+		Configs:    []model.CreditsConfigEntry{},
+	}
+	err := db.EachFileCreditsConfigs(500, func(configs []model.FileCreditsConfig) error {
+		for _, config := range configs {
+			doc.Configs = append(doc.Configs, config.ToEntry())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "导出文件积分配置失败")
+	}
+	return doc, nil
+}
+
+// ImportFileCreditsConfigs 导入文件积分配置，strategy 决定与已有配置冲突时的处理方式，
+// 所有配置在一个事务中写入，dryRun 为 true 时只返回导入结果不写入
+func ImportFileCreditsConfigs(doc *model.CreditsConfigDocument, strategy string, dryRun bool, createdBy uint) (*model.CreditsConfigImportResult, error) {
+	if doc.Version != model.CreditsConfigDocumentVersion {
+		return nil, errors.Errorf("不支持的配置文档版本: %d", doc.Version)
+	}
+	if strategy == "" {
+		strategy = model.ImportConflictFail
+	}
+	if strategy != model.ImportConflictSkip && strategy != model.ImportConflictOverwrite && strategy != model.ImportConflictFail {
+		return nil, errors.Errorf("无效的冲突处理方式: %s", strategy)
+	}
+
+	result := &model.CreditsConfigImportResult{DryRun: dryRun, Conflicts: []string{}}
+	seen := make(map[string]struct{}, len(doc.Configs))
+	var configs []*model.FileCreditsConfig
+	for _, entry := range doc.Configs {
+		config := &model.FileCreditsConfig{
+			Path:           entry.Path,
+			IsFolder:       entry.IsFolder,
+			Credits:        entry.Credits,
+			PreviewCredits: entry.PreviewCredits,
+			PricingMode:    entry.PricingMode,
+			Inheritable:    entry.Inheritable,
+			Enabled:        entry.Enabled,
+			CreatedBy:      createdBy,
+		}
+		if err := validateFileCreditsConfig(config); err != nil {
+			return nil, errors.WithMessagef(err, "配置 %s 无效", entry.Path)
+		}
+		if _, ok := seen[config.Path]; ok {
+			return nil, errors.Errorf("路径 %s 重复", config.Path)
+		}
+		seen[config.Path] = struct{}{}
+
+		existing, err := db.FindFileCreditsConfig(config.Path)
+		if err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.Wrap(err, "获取文件积分配置失败")
+			}
+			result.Created++
+			configs = append(configs, config)
+			continue
+		}
+		if existing.ToEntry() == config.ToEntry() {
+			result.Unchanged++
+			continue
+		}
+		result.Conflicts = append(result.Conflicts, config.Path)
+		if strategy == model.ImportConflictOverwrite {
+			result.Updated++
+			configs = append(configs, config)
+		} else {
+			result.Skipped++
+		}
+	}
+
+	if strategy == model.ImportConflictFail && len(result.Conflicts) > 0 {
+		return result, errors.Errorf("%d 个路径与已有配置冲突", len(result.Conflicts))
+	}
+	if dryRun || len(configs) == 0 {
+		return result, nil
+	}
+	if err := db.SaveFileCreditsConfigs(configs); err != nil {
+		return nil, errors.Wrap(err, "导入文件积分配置失败")
+	}
+	invalidateCreditsConfigTree()
+	return result, nil
+}
@@ -0,0 +1,80 @@
+package op
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// MoveFileCreditsConfigs 文件或目录被移动、重命名后，将原路径及其子路径上的积分配置迁移到新路径
+func MoveFileCreditsConfigs(srcPath, dstPath string) (int64, error) {
+	srcPath, dstPath = utils.FixAndCleanPath(srcPath), utils.FixAndCleanPath(dstPath)
+	if srcPath == dstPath || srcPath == "/" {
+		return 0, nil
+	}
+	count, err := db.MoveFileCreditsConfigTree(srcPath, dstPath)
+	if err != nil {
+		return 0, errors.Wrap(err, "迁移文件积分配置失败")
+	}
+	if count > 0 {
+		invalidateCreditsConfigTree()
+	}
+	return count, nil
+}
+
+// OrphanFileCreditsConfigs 文件或目录被删除后，将其路径及子路径上的积分配置标记为孤立，由管理员确认后清理
+func OrphanFileCreditsConfigs(path string) (int64, error) {
+	count, err := db.MarkFileCreditsConfigTreeOrphaned(utils.FixAndCleanPath(path))
+	if err != nil {
+		return 0, errors.Wrap(err, "标记孤立积分配置失败")
+	}
+	return count, nil
+}
+
+// ListOrphanedFileCreditsConfigs 获取被标记为孤立的积分配置
+func ListOrphanedFileCreditsConfigs() ([]model.FileCreditsConfig, error) {
+	configs, err := db.GetOrphanedFileCreditsConfigs()
+	if err != nil {
+		return nil, errors.Wrap(err, "获取孤立积分配置失败")
+	}
+	return configs, nil
+}
+
+// ScanFileCreditsConfigOrphans 逐个检查积分配置的路径，exists 返回 false 的配置被标记为孤立，返回检查的数量
+func ScanFileCreditsConfigOrphans(exists func(path string) (bool, error)) (int, error) {
+	checked := 0
+	var orphans []string
+	err := db.EachFileCreditsConfigs(100, func(configs []model.FileCreditsConfig) error {
+		for _, config := range configs {
+			ok, err := exists(config.Path)
+			if err != nil {
+				return err
+			}
+			checked++
+			if !ok && !config.Orphaned {
+				orphans = append(orphans, config.Path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return checked, errors.Wrap(err, "检查积分配置失败")
+	}
+	for _, path := range orphans {
+		if _, err = db.MarkFileCreditsConfigTreeOrphaned(path); err != nil {
+			return checked, errors.Wrap(err, "标记孤立积分配置失败")
+		}
+	}
+	return checked, nil
+}
+
+// CleanOrphanedFileCreditsConfigs 删除所有被标记为孤立的积分配置，返回删除的数量
+func CleanOrphanedFileCreditsConfigs() (int64, error) {
+	count, err := db.DeleteOrphanedFileCreditsConfigs()
+	if err != nil {
+		return 0, errors.Wrap(err, "清理孤立积分配置失败")
+	}
+	invalidateCreditsConfigTree()
+	return count, nil
+}
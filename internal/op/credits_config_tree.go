@@ -0,0 +1,59 @@
+package op
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/singleflight"
+	"github.com/OpenListTeam/go-cache"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const creditsConfigTreeCacheKey = "credits_config_tree"
+
+var creditsConfigTreeCache = cache.NewMemCache(cache.WithShards[*model.CreditsConfigTree](1))
+var creditsConfigTreeG singleflight.Group[*model.CreditsConfigTree]
+
+// creditsConfigTreeVersion 每次配置变化时递增，避免变化前开始构建的旧树在变化后被写入缓存
+var creditsConfigTreeVersion atomic.Uint64
+
+// invalidateCreditsConfigTree 文件积分配置变化后丢弃缓存的前缀树，下次查找时重新构建
+func invalidateCreditsConfigTree() {
+	creditsConfigTreeVersion.Add(1)
+	creditsConfigTreeCache.Del(creditsConfigTreeCacheKey)
+}
+
+// getCreditsConfigTree 获取可继承文件夹配置的前缀树
+func getCreditsConfigTree() (*model.CreditsConfigTree, error) {
+	if tree, ok := creditsConfigTreeCache.Get(creditsConfigTreeCacheKey); ok {
+		return tree, nil
+	}
+	tree, err, _ := creditsConfigTreeG.Do(creditsConfigTreeCacheKey, func() (*model.CreditsConfigTree, error) {
+		version := creditsConfigTreeVersion.Load()
+		configs, err := db.GetInheritableFolderConfigs()
+		if err != nil {
+			return nil, errors.Wrap(err, "获取文件夹积分配置失败")
+		}
+		tree := model.NewCreditsConfigTree(configs)
+		if creditsConfigTreeVersion.Load() == version {
+			creditsConfigTreeCache.Set(creditsConfigTreeCacheKey, tree, cache.WithEx[*model.CreditsConfigTree](time.Hour))
+		}
+		return tree, nil
+	})
+	return tree, err
+}
+
+// getInheritableCreditsConfig 获取路径最近的可继承文件夹配置，没有时返回 gorm.ErrRecordNotFound
+func getInheritableCreditsConfig(path string) (*model.FileCreditsConfig, error) {
+	tree, err := getCreditsConfigTree()
+	if err != nil {
+		return nil, err
+	}
+	if config := tree.Longest(path); config != nil {
+		return config, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
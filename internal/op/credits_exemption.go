@@ -0,0 +1,63 @@
+package op
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// ListFileCreditsExemptions 获取文件积分配置的豁免名单
+func ListFileCreditsExemptions(configID uint) ([]model.FileCreditsExemption, error) {
+	exemptions, err := db.GetFileCreditsExemptions(configID)
+	if err != nil {
+		return nil, errors.Wrap(err, "获取豁免名单失败")
+	}
+	return exemptions, nil
+}
+
+// SetFileCreditsExemptions 替换文件积分配置的豁免名单，名单中的用户和定价组成员访问该配置覆盖的文件时免费
+func SetFileCreditsExemptions(configID uint, userIDs, groupIDs []uint) error {
+	if _, err := db.GetFileCreditsConfigByID(configID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("文件积分配置不存在")
+		}
+		return errors.Wrap(err, "获取文件积分配置失败")
+	}
+	var exemptions []model.FileCreditsExemption
+	seen := make(map[model.FileCreditsExemption]struct{})
+	add := func(exemption model.FileCreditsExemption) {
+		if _, ok := seen[exemption]; !ok {
+			seen[exemption] = struct{}{}
+			exemptions = append(exemptions, exemption)
+		}
+	}
+	for _, userID := range userIDs {
+		if _, err := GetUserById(userID); err != nil {
+			return errors.WithMessagef(err, "用户 %d 不存在", userID)
+		}
+		add(model.FileCreditsExemption{ConfigID: configID, UserID: userID})
+	}
+	for _, groupID := range groupIDs {
+		if _, err := db.GetPricingGroupByID(groupID); err != nil {
+			return errors.Errorf("定价组 %d 不存在", groupID)
+		}
+		add(model.FileCreditsExemption{ConfigID: configID, GroupID: groupID})
+	}
+	if err := db.SetFileCreditsExemptions(configID, exemptions); err != nil {
+		return errors.Wrap(err, "设置豁免名单失败")
+	}
+	return nil
+}
+
+// isFileCreditsExempt 检查用户是否在文件积分配置的豁免名单中，计价规则生成的配置没有豁免名单
+func isFileCreditsExempt(config *model.FileCreditsConfig, userID uint) (bool, error) {
+	if config.ID == 0 {
+		return false, nil
+	}
+	exempt, err := db.IsFileCreditsExempt(config.ID, userID)
+	if err != nil {
+		return false, errors.Wrap(err, "获取豁免名单失败")
+	}
+	return exempt, nil
+}
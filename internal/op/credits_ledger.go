@@ -0,0 +1,79 @@
+package op
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// AuditCreditLedger 按交易记录重新计算所有积分账户的余额，记录不一致的账户并返回其数量。
+// 初筛不一致的账户会在加锁后再次校验，避免把正在进行的积分变动误判为不一致
+func AuditCreditLedger() (int, error) {
+	sums, err := db.GetCreditLedgerSums()
+	if err != nil {
+		return 0, errors.Wrap(err, "汇总交易记录失败")
+	}
+	accounts, err := db.GetAllUserCreditBalances()
+	if err != nil {
+		return 0, errors.Wrap(err, "获取积分账户失败")
+	}
+
+	var inconsistent []uint
+	for _, account := range accounts {
+		if account.Balance == sums[account.UserID] {
+			continue
+		}
+		balance, expected, err := db.VerifyUserCreditLedger(account.UserID, false)
+		if err != nil {
+			return 0, errors.Wrap(err, "校验积分账户失败")
+		}
+		if balance == expected {
+			continue
+		}
+		err = db.SaveCreditLedgerIssue(&model.CreditLedgerIssue{
+			UserID:   account.UserID,
+			Balance:  balance,
+			Expected: expected,
+		})
+		if err != nil {
+			return 0, errors.Wrap(err, "记录账本不一致失败")
+		}
+		utils.Log.Warnf("credit ledger of user %d is inconsistent: balance %d, transactions sum %d", account.UserID, balance, expected)
+		inconsistent = append(inconsistent, account.UserID)
+	}
+
+	if err = db.DeleteUnresolvedCreditLedgerIssues(inconsistent); err != nil {
+		return 0, errors.Wrap(err, "清理账本不一致记录失败")
+	}
+	return len(inconsistent), nil
+}
+
+// ListCreditLedgerIssues 获取账本不一致记录
+func ListCreditLedgerIssues(resolved bool, page, pageSize int) ([]model.CreditLedgerIssue, int64, error) {
+	issues, total, err := db.GetCreditLedgerIssues(resolved, page, pageSize)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "获取账本不一致记录失败")
+	}
+	return issues, total, nil
+}
+
+// RepairCreditLedger 将用户积分余额修正为交易记录的合计，并标记不一致记录为已修复
+func RepairCreditLedger(adminID, userID uint) error {
+	var balance, expected int64
+	err := retryOnCreditsConflict(func() error {
+		var err error
+		balance, expected, err = db.VerifyUserCreditLedger(userID, true)
+		return err
+	})
+	if err != nil {
+		return errors.Wrap(err, "修复积分账户失败")
+	}
+	if balance != expected {
+		utils.Log.Infof("admin %d repaired credit balance of user %d from %d to %d", adminID, userID, balance, expected)
+	}
+	if err = db.ResolveCreditLedgerIssue(userID, adminID); err != nil {
+		return errors.Wrap(err, "更新账本不一致记录失败")
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package op
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+// GetCreditRanking 获取最近 days 天消费或获得积分最多的用户，按余额排序时忽略 days；days 为0时统计全部记录
+func GetCreditRanking(by string, days, limit int) ([]model.CreditRankEntry, error) {
+	if by != model.RankBySpend && by != model.RankByEarn && by != model.RankByBalance {
+		return nil, errors.Errorf("无效的排序依据: %s", by)
+	}
+	var since *time.Time
+	if days > 0 {
+		t := periodStart(time.Now(), model.StatsPeriodDay).AddDate(0, 0, 1-days)
+		since = &t
+	}
+	entries, err := db.GetCreditRanking(by, since, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "获取积分排行失败")
+	}
+	return entries, nil
+}
@@ -0,0 +1,61 @@
+package op
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+// GetCreditStats 按日、周或月汇总最近 count 个周期的积分收支，userID 为0时统计全站
+func GetCreditStats(userID uint, period string, count int) ([]model.CreditStatsBucket, error) {
+	if count <= 0 {
+		return nil, errors.New("统计周期数必须大于0")
+	}
+	if period != model.StatsPeriodDay && period != model.StatsPeriodWeek && period != model.StatsPeriodMonth {
+		return nil, errors.Errorf("无效的统计粒度: %s", period)
+	}
+
+	starts := make([]time.Time, count)
+	start := periodStart(time.Now(), period)
+	for i := count - 1; i >= 0; i-- {
+		starts[i] = start
+		start = previousPeriod(start, period)
+	}
+
+	buckets, err := db.SumCreditTransactionsByPeriod(userID, starts)
+	if err != nil {
+		return nil, errors.Wrap(err, "统计积分收支失败")
+	}
+	for i := range buckets {
+		buckets[i].Start = starts[i].Format(time.DateOnly)
+	}
+	return buckets, nil
+}
+
+// periodStart 返回 t 所在统计周期的开始时间（本地时间零点）
+func periodStart(t time.Time, period string) time.Time {
+	t = t.In(time.Local)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+	switch period {
+	case model.StatsPeriodWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case model.StatsPeriodMonth:
+		return day.AddDate(0, 0, 1-day.Day())
+	default:
+		return day
+	}
+}
+
+// previousPeriod 返回上一个统计周期的开始时间
+func previousPeriod(start time.Time, period string) time.Time {
+	switch period {
+	case model.StatsPeriodWeek:
+		return start.AddDate(0, 0, -7)
+	case model.StatsPeriodMonth:
+		return start.AddDate(0, -1, 0)
+	default:
+		return start.AddDate(0, 0, -1)
+	}
+}
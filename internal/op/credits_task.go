@@ -0,0 +1,65 @@
+package op
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// 按次计费的服务端任务类型
+const (
+	TaskCopy            = "copy"
+	TaskDecompress      = "decompress"
+	TaskOfflineDownload = "offline_download"
+)
+
+var taskCreditsSettings = map[string]string{
+	TaskCopy:            conf.CreditsTaskCopy,
+	TaskDecompress:      conf.CreditsTaskDecompress,
+	TaskOfflineDownload: conf.CreditsTaskOfflineDownload,
+}
+
+// taskCreditHoldTTL 任务冻结积分的超时时间，任务可能长时间排队，超时后冻结由 ReleaseExpiredCreditHolds 释放
+const taskCreditHoldTTL = 7 * 24 * time.Hour
+
+// HoldTaskCredits 提交任务时按任务类型对应的设置项冻结用户积分，返回预扣记录ID。
+// 未启用积分系统、任务免费或由管理员、系统提交时返回 0，可用积分不足时返回 errs.InsufficientCredits
+func HoldTaskCredits(user *model.User, taskType, name string) (uint, error) {
+	if user == nil || user.IsAdmin() || !getCreditsSettingBool(conf.CreditsEnabled) {
+		return 0, nil
+	}
+	key, ok := taskCreditsSettings[taskType]
+	if !ok {
+		return 0, errors.Errorf("未知的任务类型: %s", taskType)
+	}
+	cost := getCreditsSettingInt(key, 0)
+	if cost <= 0 {
+		return 0, nil
+	}
+	hold, err := holdCredits(user.ID, cost, "task", name, taskType, taskCreditHoldTTL)
+	if err != nil {
+		return 0, err
+	}
+	return hold.ID, nil
+}
+
+// SettleTaskCredits 任务结束时结算冻结的积分：成功时扣除，失败或取消时释放。
+// 同一笔冻结只会结算一次，重试后再次结束的任务不会重复扣除
+func SettleTaskCredits(holdID uint, succeeded bool) {
+	if holdID == 0 {
+		return
+	}
+	var err error
+	if succeeded {
+		err = CaptureCreditHold(holdID)
+	} else {
+		err = ReleaseCreditHold(holdID)
+	}
+	if err != nil && !errors.Is(err, errs.CreditHoldNotActive) {
+		utils.Log.Errorf("failed to settle task credit hold %d: %+v", holdID, err)
+	}
+}
@@ -0,0 +1,679 @@
+package op_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/sign"
+	"github.com/pkg/errors"
+)
+
+func TestDeductCreditsConcurrent(t *testing.T) {
+	const userID, initial, price = 1001, 100, 30
+	if err := op.AddCredits(userID, initial, "test", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := op.DeductCredits(userID, price, "test", "/file"); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	credits, err := op.GetUserCredits(userID)
+	if err != nil {
+		t.Fatalf("failed to get credits: %+v", err)
+	}
+	if credits.Balance < 0 {
+		t.Fatalf("balance went negative: %d", credits.Balance)
+	}
+	if credits.Balance != initial-int64(succeeded)*price || credits.TotalSpent != int64(succeeded)*price {
+		t.Errorf("lost update: balance %d, spent %d after %d deductions", credits.Balance, credits.TotalSpent, succeeded)
+	}
+	if err := op.DeductCredits(userID, initial, "test", "/file"); !errors.Is(err, errs.InsufficientCredits) {
+		t.Errorf("expected insufficient credits, got %v", err)
+	}
+}
+
+func TestUpdateUserCreditsVersionConflict(t *testing.T) {
+	const userID = 1002
+	if err := op.AddCredits(userID, 10, "test", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	a, _ := op.GetUserCredits(userID)
+	b, _ := op.GetUserCredits(userID)
+	a.Balance += 5
+	if err := db.UpdateUserCredits(a); err != nil {
+		t.Fatalf("failed to update credits: %+v", err)
+	}
+	b.Balance += 7
+	if err := db.UpdateUserCredits(b); !errors.Is(err, errs.CreditsVersionConflict) {
+		t.Errorf("stale update should conflict, got %v", err)
+	}
+}
+
+func TestCreditHold(t *testing.T) {
+	const userID = 1003
+	if err := op.AddCredits(userID, 50, "test", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	captured, err := op.HoldCredits(userID, 30, "test", "/a", time.Minute)
+	if err != nil {
+		t.Fatalf("failed to hold credits: %+v", err)
+	}
+	if _, err = op.HoldCredits(userID, 30, "test", "/b", time.Minute); !errors.Is(err, errs.InsufficientCredits) {
+		t.Errorf("held credits should not be available, got %v", err)
+	}
+	expired, err := op.HoldCredits(userID, 20, "test", "/c", -time.Minute)
+	if err != nil {
+		t.Fatalf("failed to hold credits: %+v", err)
+	}
+	if err = op.CaptureCreditHold(captured.ID); err != nil {
+		t.Fatalf("failed to capture hold: %+v", err)
+	}
+	if err = op.CaptureCreditHold(captured.ID); !errors.Is(err, errs.CreditHoldNotActive) {
+		t.Errorf("hold should only be captured once, got %v", err)
+	}
+	if err = op.ReleaseExpiredCreditHolds(); err != nil {
+		t.Fatalf("failed to release expired holds: %+v", err)
+	}
+	if err = op.CaptureCreditHold(expired.ID); !errors.Is(err, errs.CreditHoldNotActive) {
+		t.Errorf("expired hold should be released, got %v", err)
+	}
+	credits, _ := op.GetUserCredits(userID)
+	if credits.Balance != 20 || credits.Held != 0 || credits.TotalSpent != 30 {
+		t.Errorf("unexpected account state: balance %d, held %d, spent %d", credits.Balance, credits.Held, credits.TotalSpent)
+	}
+}
+
+func TestExpireCreditLots(t *testing.T) {
+	const userID = 1004
+	if err := op.AddCredits(userID, 10, "test", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	if err := op.AddExpiringCredits(userID, 20, "promo", "", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	// spends the permanent lot first, then 5 of the promo lot
+	if err := op.DeductCredits(userID, 15, "test", "/a"); err != nil {
+		t.Fatalf("failed to deduct credits: %+v", err)
+	}
+	lots, _ := op.GetCreditLots(userID)
+	if len(lots) != 1 || lots[0].Remaining != 15 {
+		t.Fatalf("unexpected lots after spending: %+v", lots)
+	}
+	db.GetDb().Model(&model.CreditLot{}).Where("id = ?", lots[0].ID).Update("expires_at", time.Now().Add(-time.Minute))
+	if err := op.ExpireCreditLots(); err != nil {
+		t.Fatalf("failed to expire lots: %+v", err)
+	}
+	credits, _ := op.GetUserCredits(userID)
+	if credits.Balance != 0 {
+		t.Errorf("expired credits should be removed, balance %d", credits.Balance)
+	}
+}
+
+func TestTransferCredits(t *testing.T) {
+	from := &model.User{Username: "transfer_from", Role: model.GENERAL}
+	to := &model.User{Username: "transfer_to", Role: model.GENERAL}
+	for _, u := range []*model.User{from, to} {
+		if err := op.CreateUser(u); err != nil {
+			t.Fatalf("failed to create user: %+v", err)
+		}
+	}
+	if err := op.AddPurchasedCredits(from.ID, 100, "test", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	if err := op.TransferCredits(from.ID, to.ID, 60, "gift"); err != nil {
+		t.Fatalf("failed to transfer credits: %+v", err)
+	}
+	if err := op.TransferCredits(from.ID, to.ID, 60, "gift"); !errors.Is(err, errs.InsufficientCredits) {
+		t.Errorf("expected insufficient credits, got %v", err)
+	}
+	a, _ := op.GetUserCredits(from.ID)
+	b, _ := op.GetUserCredits(to.ID)
+	if a.Balance != 40 || b.Balance != 60 {
+		t.Errorf("unexpected balances after transfer: %d, %d", a.Balance, b.Balance)
+	}
+}
+
+func TestBonusCredits(t *testing.T) {
+	user := &model.User{Username: "bonus_user", Role: model.GENERAL}
+	to := &model.User{Username: "bonus_to", Role: model.GENERAL}
+	for _, u := range []*model.User{user, to} {
+		if err := op.CreateUser(u); err != nil {
+			t.Fatalf("failed to create user: %+v", err)
+		}
+	}
+	if err := op.AddPurchasedCredits(user.ID, 50, "test", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	if err := op.AddCredits(user.ID, 30, "promo", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	if err := op.TransferCredits(user.ID, to.ID, 60, "gift"); !errors.Is(err, errs.InsufficientCredits) {
+		t.Errorf("expected bonus credits not to be transferable, got %v", err)
+	}
+	// bonus credits are spent first
+	if err := op.DeductCredits(user.ID, 40, "download", "/bonus.zip"); err != nil {
+		t.Fatalf("failed to deduct credits: %+v", err)
+	}
+	credits, _ := op.GetUserCredits(user.ID)
+	if credits.Bonus != 0 || credits.Purchased() != 40 {
+		t.Errorf("unexpected wallets after spending: bonus %d, purchased %d", credits.Bonus, credits.Purchased())
+	}
+	if err := op.TransferCredits(user.ID, to.ID, 40, "gift"); err != nil {
+		t.Fatalf("failed to transfer purchased credits: %+v", err)
+	}
+}
+
+func TestCheckSizedFileAccessPermission(t *testing.T) {
+	user := &model.User{Username: "sized_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if err := op.SetFileCreditsConfig("/sized/big.iso", 2, 0, model.PricingPerMB, false, 1); err != nil {
+		t.Fatalf("failed to set file config: %+v", err)
+	}
+	allowed, required, err := op.CheckSizedFileAccessPermission(user.ID, "/sized/big.iso", model.CreditsActionDownload, 3*1024*1024)
+	if err != nil {
+		t.Fatalf("failed to check permission: %+v", err)
+	}
+	if allowed || required != 6 {
+		t.Errorf("expected 6 required credits and not allowed, got %d, %v", required, allowed)
+	}
+}
+
+func TestCreditGift(t *testing.T) {
+	sender := &model.User{Username: "gift_sender", Role: model.GENERAL}
+	recipient := &model.User{Username: "gift_recipient", Role: model.GENERAL}
+	for _, u := range []*model.User{sender, recipient} {
+		if err := op.CreateUser(u); err != nil {
+			t.Fatalf("failed to create user: %+v", err)
+		}
+	}
+	if err := op.AddPurchasedCredits(sender.ID, 50, "test", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	gift, err := op.SendCreditGift(sender.ID, recipient.Username, 30, "happy birthday")
+	if err != nil {
+		t.Fatalf("failed to send gift: %+v", err)
+	}
+	if _, err = op.ClaimCreditGift(sender.ID, gift.GiftNo); err == nil {
+		t.Errorf("expected sender not to claim the gift")
+	}
+	if _, err = op.ClaimCreditGift(recipient.ID, gift.GiftNo); err != nil {
+		t.Fatalf("failed to claim gift: %+v", err)
+	}
+	if _, err = op.ClaimCreditGift(recipient.ID, gift.GiftNo); !errors.Is(err, errs.CreditGiftNotPending) {
+		t.Errorf("expected gift already claimed, got %v", err)
+	}
+	a, _ := op.GetUserCredits(sender.ID)
+	b, _ := op.GetUserCredits(recipient.ID)
+	if a.Balance != 20 || b.Balance != 30 {
+		t.Errorf("unexpected balances after gift: %d, %d", a.Balance, b.Balance)
+	}
+}
+
+func TestFilePreviewPricing(t *testing.T) {
+	user := &model.User{Username: "preview_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if err := op.AddCredits(user.ID, 20, "preview test", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	if err := op.SetFileCreditsConfig("/preview/movie.mkv", 10, 2, model.PricingFlat, false, 1); err != nil {
+		t.Fatalf("failed to set file config: %+v", err)
+	}
+	_, required, err := op.CheckFilePreviewPermission(user.ID, "/preview/movie.mkv")
+	if err != nil || required != 2 {
+		t.Fatalf("expected preview price 2, got %d, err: %+v", required, err)
+	}
+	if err = op.ProcessFilePreview(user.ID, "/preview/movie.mkv", nil); err != nil {
+		t.Fatalf("failed to preview: %+v", err)
+	}
+	if err = op.ProcessFileDownload(user.ID, "/preview/movie.mkv", nil); err != nil {
+		t.Fatalf("failed to download: %+v", err)
+	}
+	credits, _ := op.GetUserCredits(user.ID)
+	if credits.Balance != 8 {
+		t.Errorf("expected balance 8 after preview and download, got %d", credits.Balance)
+	}
+}
+
+func TestExportCreditTransactions(t *testing.T) {
+	user := &model.User{Username: "export_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := op.AddCredits(user.ID, 10, "export test", ""); err != nil {
+			t.Fatalf("failed to add credits: %+v", err)
+		}
+	}
+	if err := op.DeductCredits(user.ID, 5, "export test", "/export.zip"); err != nil {
+		t.Fatalf("failed to deduct credits: %+v", err)
+	}
+	var count int
+	filter := model.CreditTransactionFilter{UserID: user.ID, Source: "download"}
+	err := op.ExportCreditTransactions(filter, func(transactions []model.CreditTransaction) error {
+		count += len(transactions)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to export: %+v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 download transaction, got %d", count)
+	}
+}
+
+func TestGetCreditStats(t *testing.T) {
+	user := &model.User{Username: "stats_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if err := op.AddCredits(user.ID, 30, "stats test", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	if err := op.DeductCredits(user.ID, 12, "stats test", "/stats.zip"); err != nil {
+		t.Fatalf("failed to deduct credits: %+v", err)
+	}
+	for _, period := range []string{model.StatsPeriodDay, model.StatsPeriodWeek, model.StatsPeriodMonth} {
+		buckets, err := op.GetCreditStats(user.ID, period, 3)
+		if err != nil {
+			t.Fatalf("failed to get %s stats: %+v", period, err)
+		}
+		if len(buckets) != 3 {
+			t.Fatalf("expected 3 %s buckets, got %d", period, len(buckets))
+		}
+		last := buckets[2]
+		if last.Earn != 30 || last.Spend != 12 || last.Count != 2 {
+			t.Errorf("unexpected current %s bucket: %+v", period, last)
+		}
+		if buckets[0].Start >= buckets[1].Start || buckets[1].Start >= last.Start {
+			t.Errorf("%s buckets are not in ascending order: %+v", period, buckets)
+		}
+	}
+
+	// a transaction of yesterday falls in the previous day
+	if err := op.AddCredits(user.ID, 5, "stats test", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	db.GetDb().Model(&model.CreditTransaction{}).Where("user_id = ? AND amount = ?", user.ID, 5).
+		Update("created_at", time.Now().AddDate(0, 0, -1))
+	buckets, err := op.GetCreditStats(user.ID, model.StatsPeriodDay, 3)
+	if err != nil {
+		t.Fatalf("failed to get day stats: %+v", err)
+	}
+	if buckets[1].Earn != 5 || buckets[1].Count != 1 || buckets[2].Earn != 30 {
+		t.Errorf("unexpected day buckets: %+v", buckets)
+	}
+}
+
+func TestAuditCreditLedger(t *testing.T) {
+	user := &model.User{Username: "ledger_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if err := op.AddCredits(user.ID, 50, "ledger test", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	// corrupt the balance without a transaction
+	credits, _ := op.GetUserCredits(user.ID)
+	credits.Balance += 7
+	if err := db.UpdateUserCredits(credits); err != nil {
+		t.Fatalf("failed to update credits: %+v", err)
+	}
+	if _, err := op.AuditCreditLedger(); err != nil {
+		t.Fatalf("failed to audit: %+v", err)
+	}
+	issues, _, err := op.ListCreditLedgerIssues(false, 1, 100)
+	if err != nil {
+		t.Fatalf("failed to list issues: %+v", err)
+	}
+	found := false
+	for _, issue := range issues {
+		if issue.UserID == user.ID {
+			found = issue.Balance == 57 && issue.Expected == 50
+		}
+	}
+	if !found {
+		t.Fatalf("expected ledger issue for user %d, got %+v", user.ID, issues)
+	}
+	if err = op.RepairCreditLedger(1, user.ID); err != nil {
+		t.Fatalf("failed to repair: %+v", err)
+	}
+	credits, _ = op.GetUserCredits(user.ID)
+	if credits.Balance != 50 {
+		t.Errorf("expected repaired balance 50, got %d", credits.Balance)
+	}
+}
+
+func TestFilterCreditTransactions(t *testing.T) {
+	user := &model.User{Username: "filter_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if err := op.AddCredits(user.ID, 40, "filter test", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	for _, p := range []string{"/filter/a.zip", "/filter/b.iso"} {
+		if err := op.DeductCredits(user.ID, 5, "下载文件: "+p, p); err != nil {
+			t.Fatalf("failed to deduct credits: %+v", err)
+		}
+	}
+	for _, c := range []struct {
+		filter model.CreditTransactionFilter
+		want   int64
+	}{
+		{model.CreditTransactionFilter{}, 3},
+		{model.CreditTransactionFilter{Sign: model.AmountNegative}, 2},
+		{model.CreditTransactionFilter{Sign: model.AmountPositive}, 1},
+		{model.CreditTransactionFilter{Source: "download", Keyword: ".iso"}, 1},
+		{model.CreditTransactionFilter{Type: "spend", Keyword: "nothing"}, 0},
+		{model.CreditTransactionFilter{Path: "/filter/a.zip"}, 1},
+	} {
+		_, total, err := op.GetCreditTransactions(user.ID, c.filter, 1, 20)
+		if err != nil {
+			t.Fatalf("failed to get transactions: %+v", err)
+		}
+		if total != c.want {
+			t.Errorf("filter %+v: got %d transactions, want %d", c.filter, total, c.want)
+		}
+	}
+}
+
+func TestGetCreditRanking(t *testing.T) {
+	big := &model.User{Username: "rank_big", Role: model.GENERAL}
+	small := &model.User{Username: "rank_small", Role: model.GENERAL}
+	for _, u := range []*model.User{big, small} {
+		if err := op.CreateUser(u); err != nil {
+			t.Fatalf("failed to create user: %+v", err)
+		}
+	}
+	if err := op.AddCredits(big.ID, 100000, "rank test", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	if err := op.AddCredits(small.ID, 99999, "rank test", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	if err := op.DeductCredits(small.ID, 99990, "rank test", "/rank.zip"); err != nil {
+		t.Fatalf("failed to deduct credits: %+v", err)
+	}
+	for _, c := range []struct {
+		by   string
+		want string
+	}{
+		{model.RankByEarn, "rank_big"},
+		{model.RankBySpend, "rank_small"},
+		{model.RankByBalance, "rank_big"},
+	} {
+		entries, err := op.GetCreditRanking(c.by, 1, 1)
+		if err != nil {
+			t.Fatalf("failed to get ranking: %+v", err)
+		}
+		if len(entries) != 1 || entries[0].Username != c.want {
+			t.Errorf("%s ranking: got %+v, want %s first", c.by, entries, c.want)
+		}
+	}
+}
+
+func TestExternalReward(t *testing.T) {
+	user := &model.User{Username: "reward_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	source := &model.RewardSource{Name: "offerwall_test", Secret: "secret", UserDailyCap: 15, Enabled: true}
+	if err := op.SaveRewardSource(source); err != nil {
+		t.Fatalf("failed to save reward source: %+v", err)
+	}
+	signer := sign.NewHMACSign([]byte(source.Secret))
+	grant := func(amount int64, txID, nonce string) error {
+		data := model.RewardCallbackData(source.Name, user.ID, amount, txID, nonce)
+		_, err := op.GrantExternalReward(source.Name, user.ID, amount, txID, nonce,
+			signer.Sign(data, time.Now().Add(time.Minute).Unix()))
+		return err
+	}
+	if err := grant(10, "tx1", "n1"); err != nil {
+		t.Fatalf("failed to grant reward: %+v", err)
+	}
+	if err := grant(10, "tx1", "n2"); !errors.Is(err, errs.RewardReplayed) {
+		t.Errorf("expected replayed transaction id, got %v", err)
+	}
+	if err := grant(1, "tx2", "n1"); !errors.Is(err, errs.RewardReplayed) {
+		t.Errorf("expected replayed nonce, got %v", err)
+	}
+	if err := grant(10, "tx3", "n3"); !errors.Is(err, errs.RewardCapExceeded) {
+		t.Errorf("expected daily cap exceeded, got %v", err)
+	}
+	_, err := op.GrantExternalReward(source.Name, user.ID, 5, "tx4", "n4",
+		signer.Sign(model.RewardCallbackData(source.Name, user.ID, 50, "tx4", "n4"), time.Now().Add(time.Minute).Unix()))
+	if !errors.Is(err, errs.InvalidRewardSign) {
+		t.Errorf("expected invalid signature, got %v", err)
+	}
+	credits, _ := op.GetUserCredits(user.ID)
+	if credits.Balance != 10 {
+		t.Errorf("unexpected balance after rewards: %d", credits.Balance)
+	}
+}
+
+func TestTaskCredits(t *testing.T) {
+	user := &model.User{Username: "task_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	for _, item := range []*model.SettingItem{
+		{Key: conf.CreditsEnabled, Value: "true", Type: conf.TypeBool},
+		{Key: conf.CreditsTaskCopy, Value: "20", Type: conf.TypeNumber},
+	} {
+		if err := op.SaveSettingItem(item); err != nil {
+			t.Fatalf("failed to save setting: %+v", err)
+		}
+	}
+	defer op.SaveSettingItem(&model.SettingItem{Key: conf.CreditsEnabled, Value: "false", Type: conf.TypeBool})
+	if err := op.AddCredits(user.ID, 30, "test", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	failed, err := op.HoldTaskCredits(user, op.TaskCopy, "copy a to b")
+	if err != nil || failed == 0 {
+		t.Fatalf("failed to hold task credits: %d, %+v", failed, err)
+	}
+	if _, err = op.HoldTaskCredits(user, op.TaskCopy, "copy c to d"); !errors.Is(err, errs.InsufficientCredits) {
+		t.Errorf("expected insufficient credits, got %v", err)
+	}
+	op.SettleTaskCredits(failed, false)
+	succeeded, err := op.HoldTaskCredits(user, op.TaskCopy, "copy c to d")
+	if err != nil {
+		t.Fatalf("failed to hold task credits: %+v", err)
+	}
+	op.SettleTaskCredits(succeeded, true)
+	credits, _ := op.GetUserCredits(user.ID)
+	if credits.Balance != 10 || credits.Held != 0 {
+		t.Errorf("unexpected account after tasks: balance %d, held %d", credits.Balance, credits.Held)
+	}
+}
+
+func TestCreditAllowance(t *testing.T) {
+	user := &model.User{Username: "allowance_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if err := op.SaveSettingItem(&model.SettingItem{Key: conf.CreditsEnabled, Value: "true", Type: conf.TypeBool}); err != nil {
+		t.Fatalf("failed to save setting: %+v", err)
+	}
+	defer op.SaveSettingItem(&model.SettingItem{Key: conf.CreditsEnabled, Value: "false", Type: conf.TypeBool})
+	allowance := &model.CreditAllowance{Name: "monthly", Role: model.GENERAL, Amount: 10, Period: model.AllowanceMonthly, Enabled: true}
+	if err := op.SaveCreditAllowance(allowance); err != nil {
+		t.Fatalf("failed to save allowance: %+v", err)
+	}
+	defer op.DeleteCreditAllowance(allowance.ID)
+	for i := 0; i < 2; i++ {
+		if err := op.GrantCreditAllowances(); err != nil {
+			t.Fatalf("failed to grant allowances: %+v", err)
+		}
+	}
+	credits, _ := op.GetUserCredits(user.ID)
+	if credits.Balance != 10 {
+		t.Errorf("allowance should be granted once per period, balance %d", credits.Balance)
+	}
+	lots, err := db.GetActiveCreditLots(user.ID)
+	if err != nil || len(lots) != 1 || lots[0].ExpiresAt == nil {
+		t.Errorf("non-stacking allowance should expire at the end of the period: %+v, %v", lots, err)
+	}
+}
+
+func TestFreezeUserCredits(t *testing.T) {
+	user := &model.User{Username: "frozen_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if err := op.AddCredits(user.ID, 20, "test", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	if err := op.FreezeUserCredits(1, user.ID, true, "fraud investigation"); err != nil {
+		t.Fatalf("failed to freeze credits: %+v", err)
+	}
+	if err := op.AddCredits(user.ID, 5, "test", ""); !errors.Is(err, errs.CreditsFrozen) {
+		t.Errorf("expected earning to fail on a frozen account, got %v", err)
+	}
+	if err := op.DeductCredits(user.ID, 5, "download", "/frozen.zip"); !errors.Is(err, errs.CreditsFrozen) {
+		t.Errorf("expected deduction to fail on a frozen account, got %v", err)
+	}
+	if _, err := op.HoldCredits(user.ID, 5, "download", "/frozen.zip", time.Minute); !errors.Is(err, errs.CreditsFrozen) {
+		t.Errorf("expected hold to fail on a frozen account, got %v", err)
+	}
+	if err := op.FreezeUserCredits(1, user.ID, false, ""); err != nil {
+		t.Fatalf("failed to unfreeze credits: %+v", err)
+	}
+	if err := op.DeductCredits(user.ID, 5, "download", "/frozen.zip"); err != nil {
+		t.Errorf("failed to deduct credits after unfreezing: %+v", err)
+	}
+}
+
+func TestCreateUserProvisionsCredits(t *testing.T) {
+	if err := op.SaveSettingItem(&model.SettingItem{Key: conf.CreditsWelcomeBalance, Value: "15", Type: conf.TypeNumber}); err != nil {
+		t.Fatalf("failed to save setting: %+v", err)
+	}
+	defer op.SaveSettingItem(&model.SettingItem{Key: conf.CreditsWelcomeBalance, Value: "0", Type: conf.TypeNumber})
+	user := &model.User{Username: "welcome_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	credits, err := db.GetUserCreditsByUserID(user.ID)
+	if err != nil {
+		t.Fatalf("credits account should be created with the user: %+v", err)
+	}
+	if credits.Balance != 15 || credits.Bonus != 15 {
+		t.Errorf("unexpected welcome balance %d (bonus %d)", credits.Balance, credits.Bonus)
+	}
+}
+
+func TestPromoCreditsExpire(t *testing.T) {
+	for key, value := range map[string]string{conf.CreditsWelcomeBalance: "12", conf.CreditsPromoExpireDays: "30"} {
+		if err := op.SaveSettingItem(&model.SettingItem{Key: key, Value: value, Type: conf.TypeNumber}); err != nil {
+			t.Fatalf("failed to save setting: %+v", err)
+		}
+	}
+	defer op.SaveSettingItem(&model.SettingItem{Key: conf.CreditsWelcomeBalance, Value: "0", Type: conf.TypeNumber})
+	defer op.SaveSettingItem(&model.SettingItem{Key: conf.CreditsPromoExpireDays, Value: "0", Type: conf.TypeNumber})
+
+	user := &model.User{Username: "promo_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	lots, err := db.GetActiveCreditLots(user.ID)
+	if err != nil {
+		t.Fatalf("failed to get lots: %+v", err)
+	}
+	if len(lots) != 1 || lots[0].Amount != 12 || lots[0].ExpiresAt == nil {
+		t.Fatalf("expected the welcome credits to expire, got %+v", lots)
+	}
+	if days := time.Until(*lots[0].ExpiresAt).Hours() / 24; days < 29 || days > 30 {
+		t.Errorf("expected the welcome credits to expire in 30 days, got %.1f", days)
+	}
+}
+
+func TestWelcomeCreditsRequireEmail(t *testing.T) {
+	for key, value := range map[string]string{conf.CreditsWelcomeBalance: "10", conf.CreditsWelcomeRequireEmail: "true"} {
+		if err := op.SaveSettingItem(&model.SettingItem{Key: key, Value: value, Type: conf.TypeString}); err != nil {
+			t.Fatalf("failed to save setting: %+v", err)
+		}
+	}
+	defer op.SaveSettingItem(&model.SettingItem{Key: conf.CreditsWelcomeBalance, Value: "0", Type: conf.TypeNumber})
+	defer op.SaveSettingItem(&model.SettingItem{Key: conf.CreditsWelcomeRequireEmail, Value: "false", Type: conf.TypeBool})
+
+	user := &model.User{Username: "welcome_unverified", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	credits, err := db.GetUserCreditsByUserID(user.ID)
+	if err != nil {
+		t.Fatalf("credits account should be created with the user: %+v", err)
+	}
+	if credits.Balance != 0 {
+		t.Errorf("welcome credits should wait for a verified email, got %d", credits.Balance)
+	}
+	if err = op.RecordSSORegistration(user, "welcome_verified@example.com"); err != nil {
+		t.Fatalf("failed to record registration: %+v", err)
+	}
+	if credits, _ = db.GetUserCreditsByUserID(user.ID); credits.Balance != 10 {
+		t.Errorf("expected welcome credits after the email is known, got %d", credits.Balance)
+	}
+}
+
+func TestEstimateFileCredits(t *testing.T) {
+	owner := &model.User{Username: "estimate_owner", Role: model.GENERAL}
+	buyer := &model.User{Username: "estimate_buyer", Role: model.GENERAL}
+	for _, u := range []*model.User{owner, buyer} {
+		if err := op.CreateUser(u); err != nil {
+			t.Fatalf("failed to create user: %+v", err)
+		}
+	}
+	if err := op.SetFileCreditsConfig("/estimate", 4, 0, model.PricingFlat, true, 1); err != nil {
+		t.Fatalf("failed to set folder config: %+v", err)
+	}
+	config, err := op.FindFileCreditsConfig("/estimate")
+	if err != nil {
+		t.Fatalf("failed to get config: %+v", err)
+	}
+	if err = op.SetFileCreditsExemptions(config.ID, []uint{owner.ID}, nil); err != nil {
+		t.Fatalf("failed to set exemptions: %+v", err)
+	}
+	err = op.SaveSettingItem(&model.SettingItem{Key: conf.FreeDailyDownloads, Value: "5", Type: conf.TypeNumber})
+	if err != nil {
+		t.Fatalf("failed to save setting: %+v", err)
+	}
+	defer op.SaveSettingItem(&model.SettingItem{Key: conf.FreeDailyDownloads, Value: "0", Type: conf.TypeNumber})
+
+	// the daily free quota only applies to single downloads, an estimate always uses the full price
+	if _, required, err := op.CheckFileDownloadPermission(buyer.ID, "/estimate/sub/a.bin"); err != nil || required != 0 {
+		t.Fatalf("expected free download within quota, got %d, %+v", required, err)
+	}
+	for _, c := range []struct {
+		user *model.User
+		want int64
+	}{{owner, 0}, {buyer, 4}} {
+		required, err := op.EstimateFileCredits(c.user.ID, "/estimate/sub/a.bin", model.CreditsActionDownload, 0)
+		if err != nil {
+			t.Fatalf("failed to estimate credits: %+v", err)
+		}
+		if required != c.want {
+			t.Errorf("%s: estimated %d, want %d", c.user.Username, required, c.want)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package op
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/cmd/flags"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/mail"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const (
+	dataExportInterval = 24 * time.Hour     // 两次请求导出的最短间隔
+	dataExportExpire   = 7 * 24 * time.Hour // 压缩包的保留时间
+	dataExportDirName  = "exports"          // 数据目录下存放压缩包的目录
+	dataExportTimeout  = time.Hour          // 超过该时间仍未生成的导出视为失败
+)
+
+// RequestDataExport 请求导出用户的个人数据，压缩包在后台生成，完成后通过邮件通知用户。
+// 每个用户每天只能请求一次，上一次导出仍在进行时返回该导出
+func RequestDataExport(user *model.User) (*model.DataExport, error) {
+	if user.IsGuest() {
+		return nil, errors.New("游客不能导出数据")
+	}
+	last, err := db.GetLatestDataExport(user.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.Wrap(err, "获取数据导出失败")
+	}
+	if err == nil {
+		if last.Status == model.DataExportPending {
+			if time.Since(last.CreatedAt) < dataExportTimeout {
+				return last, nil
+			}
+			if err = failDataExport(last, "导出超时"); err != nil {
+				return nil, err
+			}
+		}
+		// 生成失败的导出不占用请求次数
+		if last.Status != model.DataExportFailed && time.Since(last.CreatedAt) < dataExportInterval {
+			return nil, errs.DataExportLimited
+		}
+	}
+	token, err := generateToken(32)
+	if err != nil {
+		return nil, errors.Wrap(err, "生成下载令牌失败")
+	}
+	export := &model.DataExport{
+		UserID:    user.ID,
+		Token:     token,
+		Status:    model.DataExportPending,
+		ExpiresAt: time.Now().Add(dataExportExpire),
+	}
+	if err = db.CreateDataExport(export); err != nil {
+		return nil, errors.Wrap(err, "创建数据导出失败")
+	}
+	snapshot := *export
+	go buildDataExport(*user, &snapshot)
+	return export, nil
+}
+
+// GetLatestDataExport 获取用户最近一次的数据导出，没有导出时返回 nil
+func GetLatestDataExport(userID uint) (*model.DataExport, error) {
+	export, err := db.GetLatestDataExport(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "获取数据导出失败")
+	}
+	return export, nil
+}
+
+// GetDataExportFile 根据下载令牌获取已生成的压缩包路径
+func GetDataExportFile(token string) (string, *model.DataExport, error) {
+	export, err := db.GetDataExportByToken(token)
+	if err != nil || export.Status != model.DataExportReady || time.Now().After(export.ExpiresAt) {
+		return "", nil, errs.InvalidExportToken
+	}
+	return dataExportPath(export.Token), export, nil
+}
+
+// GetUserDataExportFile 获取用户最近一次已生成且未过期的压缩包路径，供未设置邮箱的用户登录后下载
+func GetUserDataExportFile(userID uint) (string, *model.DataExport, error) {
+	export, err := db.GetLatestDataExport(userID)
+	if err != nil || export.Status != model.DataExportReady || time.Now().After(export.ExpiresAt) {
+		return "", nil, errs.InvalidExportToken
+	}
+	return dataExportPath(export.Token), export, nil
+}
+
+// FailStaleDataExports 将创建早于 before 仍未生成的导出标记为失败。
+// 导出在后台协程中生成，服务重启后未完成的导出不会再继续，启动时应以当前时间调用
+func FailStaleDataExports(before time.Time) error {
+	exports, err := db.GetPendingDataExports(before)
+	if err != nil {
+		return errors.Wrap(err, "获取未完成的数据导出失败")
+	}
+	for i := range exports {
+		if err = failDataExport(&exports[i], "导出超时"); err != nil {
+			utils.Log.Errorf("failed to fail stale data export %d: %+v", exports[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// failDataExport 将导出标记为失败
+func failDataExport(export *model.DataExport, reason string) error {
+	now := time.Now()
+	export.Status = model.DataExportFailed
+	export.Error = reason
+	export.CompletedAt = &now
+	if err := db.UpdateDataExport(export); err != nil {
+		return errors.Wrap(err, "更新数据导出失败")
+	}
+	return nil
+}
+
+// CleanExpiredDataExports 删除已到期的数据导出及其压缩包，并将超时未生成的导出标记为失败
+func CleanExpiredDataExports() error {
+	if err := FailStaleDataExports(time.Now().Add(-dataExportTimeout)); err != nil {
+		return err
+	}
+	exports, err := db.GetExpiredDataExports(time.Now())
+	if err != nil {
+		return errors.Wrap(err, "获取到期的数据导出失败")
+	}
+	for _, export := range exports {
+		if err = removeDataExport(&export); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildDataExport 生成压缩包并更新导出状态，完成后通知用户
+func buildDataExport(user model.User, export *model.DataExport) {
+	size, err := writeDataExport(&user, dataExportPath(export.Token))
+	now := time.Now()
+	export.CompletedAt = &now
+	if err != nil {
+		utils.Log.Errorf("failed to export data of user %d: %+v", user.ID, err)
+		export.Status = model.DataExportFailed
+		export.Error = err.Error()
+	} else {
+		export.Status = model.DataExportReady
+		export.Size = size
+	}
+	if err = db.UpdateDataExport(export); err != nil {
+		utils.Log.Errorf("failed to update data export %d: %+v", export.ID, err)
+		return
+	}
+	if export.Status != model.DataExportReady {
+		return
+	}
+	if email := userEmail(&user); email != "" {
+		if err = SendMail(email, mail.TemplateDataExport, map[string]any{
+			"Username":  user.Username,
+			"URL":       fmt.Sprintf("%s/api/me/export/download?token=%s", siteURL(), export.Token),
+			"ExpiresAt": export.ExpiresAt,
+		}); err != nil {
+			utils.Log.Warnf("failed to notify user %d of the data export: %+v", user.ID, err)
+		}
+	}
+}
+
+// writeDataExport 将用户的个人数据按类别写入压缩包中的 JSON 文件，返回压缩包大小
+func writeDataExport(user *model.User, path string) (int64, error) {
+	email := userEmail(user)
+	registrations, err := db.GetUserRegistrations(user.Username, email)
+	if err != nil {
+		return 0, errors.Wrap(err, "获取注册记录失败")
+	}
+	orders, err := db.GetAllPaymentOrdersByUserID(user.ID)
+	if err != nil {
+		return 0, errors.Wrap(err, "获取支付订单失败")
+	}
+	usages, err := db.GetRedeemCodeUsagesByUserID(user.ID)
+	if err != nil {
+		return 0, errors.Wrap(err, "获取兑换记录失败")
+	}
+	purchases, err := db.GetDownloadPurchasesByUserID(user.ID)
+	if err != nil {
+		return 0, errors.Wrap(err, "获取付费下载记录失败")
+	}
+	acceptances, err := db.GetAllTermsAcceptancesByUserID(user.ID)
+	if err != nil {
+		return 0, errors.Wrap(err, "获取同意条款记录失败")
+	}
+	identities, err := db.GetUserIdentities(user.ID)
+	if err != nil {
+		return 0, errors.Wrap(err, "获取外部身份失败")
+	}
+	var transactions []model.CreditTransaction
+	if err = db.EachCreditTransactions(model.CreditTransactionFilter{UserID: user.ID}, 500,
+		func(batch []model.CreditTransaction) error {
+			transactions = append(transactions, batch...)
+			return nil
+		}); err != nil {
+		return 0, errors.Wrap(err, "获取积分交易记录失败")
+	}
+	credits, _ := db.GetUserCreditsByUserID(user.ID)
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return 0, errors.Wrap(err, "创建导出目录失败")
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return 0, errors.Wrap(err, "创建压缩包失败")
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", map[string]any{
+			"id":             user.ID,
+			"username":       user.Username,
+			"email":          email,
+			"base_path":      user.BasePath,
+			"role":           user.Role,
+			"disabled":       user.Disabled,
+			"permission":     user.Permission,
+			"sso_id":         user.SsoID,
+			"vip_expires_at": user.VipExpiresAt,
+			"credits":        credits,
+			"exported_at":    time.Now(),
+		}},
+		{"registrations.json", registrations},
+		{"credit_transactions.json", transactions},
+		{"payment_orders.json", orders},
+		{"redeem_code_usages.json", usages},
+		{"download_purchases.json", purchases},
+		{"terms_acceptances.json", acceptances},
+		{"identities.json", identities},
+	}
+	for _, file := range files {
+		fw, err := w.Create(file.name)
+		if err != nil {
+			return 0, errors.Wrapf(err, "写入 %s 失败", file.name)
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err = enc.Encode(file.data); err != nil {
+			return 0, errors.Wrapf(err, "写入 %s 失败", file.name)
+		}
+	}
+	if err = w.Close(); err != nil {
+		return 0, errors.Wrap(err, "写入压缩包失败")
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return 0, errors.Wrap(err, "写入压缩包失败")
+	}
+	return info.Size(), nil
+}
+
+// removeDataExports 删除用户的所有数据导出，注销账户时调用
+func removeDataExports(userID uint) {
+	for {
+		export, err := db.GetLatestDataExport(userID)
+		if err != nil {
+			return
+		}
+		if err = removeDataExport(export); err != nil {
+			utils.Log.Errorf("failed to remove data export %d: %+v", export.ID, err)
+			return
+		}
+	}
+}
+
+func removeDataExport(export *model.DataExport) error {
+	if err := os.Remove(dataExportPath(export.Token)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "删除压缩包失败")
+	}
+	if err := db.DeleteDataExport(export.ID); err != nil {
+		return errors.Wrap(err, "删除数据导出失败")
+	}
+	return nil
+}
+
+func dataExportPath(token string) string {
+	return filepath.Join(flags.DataDir, dataExportDirName, token+".zip")
+}
@@ -0,0 +1,107 @@
+package op_test
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/cmd/flags"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/pkg/errors"
+)
+
+func TestDataExport(t *testing.T) {
+	dataDir := flags.DataDir
+	flags.DataDir = t.TempDir()
+	defer func() { flags.DataDir = dataDir }()
+
+	user := &model.User{Username: "data_export_user"}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if err := op.AddCredits(user.ID, 42, "export test", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	export, err := op.RequestDataExport(user)
+	if err != nil {
+		t.Fatalf("failed to request data export: %+v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for export.Status == model.DataExportPending && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		if export, err = op.GetLatestDataExport(user.ID); err != nil {
+			t.Fatalf("failed to get data export: %+v", err)
+		}
+	}
+	if export.Status != model.DataExportReady {
+		t.Fatalf("expected the export to be ready, got %s: %s", export.Status, export.Error)
+	}
+	if _, err = op.RequestDataExport(user); !errors.Is(err, errs.DataExportLimited) {
+		t.Errorf("expected a second export to be rate limited, got %v", err)
+	}
+	if _, _, err = op.GetDataExportFile("wrong"); !errors.Is(err, errs.InvalidExportToken) {
+		t.Errorf("expected an unknown token to be rejected, got %v", err)
+	}
+
+	path, _, err := op.GetDataExportFile(export.Token)
+	if err != nil {
+		t.Fatalf("failed to get export file: %+v", err)
+	}
+	if own, _, err := op.GetUserDataExportFile(user.ID); err != nil || own != path {
+		t.Errorf("expected the owner to download the export without a token, got %s, %v", own, err)
+	}
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("failed to open export: %+v", err)
+	}
+	defer r.Close()
+	f, err := r.Open("credit_transactions.json")
+	if err != nil {
+		t.Fatalf("expected credit transactions in the export: %+v", err)
+	}
+	defer f.Close()
+	var transactions []model.CreditTransaction
+	if err = json.NewDecoder(f).Decode(&transactions); err != nil {
+		t.Fatalf("failed to decode credit transactions: %+v", err)
+	}
+	if len(transactions) != 1 || transactions[0].Amount != 42 {
+		t.Errorf("expected the granted credits in the export, got %+v", transactions)
+	}
+	if _, err = r.Open("profile.json"); err != nil {
+		t.Errorf("expected a profile in the export: %+v", err)
+	}
+}
+
+func TestStaleDataExport(t *testing.T) {
+	user := &model.User{Username: "stale_export_user"}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	stale := &model.DataExport{
+		UserID:    user.ID,
+		Token:     "stale_export_token",
+		Status:    model.DataExportPending,
+		CreatedAt: time.Now().Add(-2 * time.Hour),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	if err := db.CreateDataExport(stale); err != nil {
+		t.Fatalf("failed to create data export: %+v", err)
+	}
+	if err := op.FailStaleDataExports(time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("failed to fail stale data exports: %+v", err)
+	}
+	export, err := op.GetLatestDataExport(user.ID)
+	if err != nil {
+		t.Fatalf("failed to get data export: %+v", err)
+	}
+	if export.Status != model.DataExportFailed {
+		t.Errorf("expected a stale export to be failed, got %s", export.Status)
+	}
+	if _, _, err = op.GetUserDataExportFile(user.ID); !errors.Is(err, errs.InvalidExportToken) {
+		t.Errorf("expected a failed export not to be downloadable, got %v", err)
+	}
+}
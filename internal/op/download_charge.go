@@ -0,0 +1,240 @@
+package op
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/go-cache"
+	"github.com/pkg/errors"
+)
+
+// DownloadCharge 一次下载请求冻结的积分或使用的免费额度，请求成功后扣除，失败后释放或退还
+type DownloadCharge struct {
+	userID   uint
+	path     string
+	hold     *model.CreditHold // 为 nil 表示本次下载不扣积分
+	quota    *quotaUse         // 本次下载使用的每日免费额度
+	session  *downloadSession  // 所属的计费会话，credits_charge_window 为 0 时为 nil
+	recharge func() error      // 冻结在传输期间过期被释放时按当前价格重新扣费
+}
+
+// downloadSession 同一用户对同一文件的计费会话，窗口期内的范围请求、断点续传和多线程分段下载共用一次扣费
+type downloadSession struct {
+	mu    sync.Mutex
+	hold  *model.CreditHold // 进行中的请求共用的积分冻结
+	quota *quotaUse         // 进行中的请求共用的免费额度
+	refs  int               // 使用 hold 或 quota 的进行中请求数
+	paid  bool              // 已扣费或本次下载免费，窗口期内的后续请求不再扣费
+}
+
+var (
+	downloadSessions   = cache.NewMemCache[*downloadSession]()
+	downloadSessionsMu sync.Mutex
+)
+
+// downloadChargeWindow 计费会话的窗口期，0 表示每个请求单独计费
+func downloadChargeWindow() time.Duration {
+	return time.Duration(getCreditsSettingInt(conf.CreditsChargeWindow, 60)) * time.Minute
+}
+
+// BeginFileDownload 下载请求开始时检查权限并冻结文件所需积分，积分不足时返回 errs.InsufficientCredits；
+// 免费文件、已购文件和使用每日免费额度的下载不冻结积分。credits_charge_window 内同一用户对同一文件的
+// 请求属于同一计费会话，并发的请求共用一次冻结，会话已扣费后的请求免费
+func BeginFileDownload(userID uint, path string) (*DownloadCharge, error) {
+	return beginCharge(userID, path, path, func() (*model.CreditHold, *quotaUse, error) {
+		return holdFileDownload(userID, path)
+	}, func() error {
+		return ProcessFileDownload(userID, path, nil)
+	})
+}
+
+// BeginFilePreview 在线预览开始时按预览价格冻结积分，预览成功后扣除，失败后释放。
+// 与下载相同，credits_charge_window 内同一用户重复预览同一文件只扣一次，有效期内已购的文件预览免费
+func BeginFilePreview(userID uint, path string, meta *model.TransactionMetadata) (*DownloadCharge, error) {
+	return beginCharge(userID, path, "preview:"+path, func() (*model.CreditHold, *quotaUse, error) {
+		ttl := time.Duration(getCreditsSettingInt(conf.CreditsHoldTimeout, 30)) * time.Minute
+		hold, err := holdFilePreview(userID, path, ttl)
+		return hold, nil, err
+	}, func() error {
+		return ProcessFilePreview(userID, path, meta)
+	})
+}
+
+// BeginArchiveExtract 解压下载压缩包内的文件时冻结积分。按大小计价的压缩包按内部文件的大小单独计价，
+// 不记为购买整个压缩包，同一内部文件在计费会话内只扣一次；固定价格的压缩包与下载整个压缩包相同，
+// 购买后有效期内解压其中的任何文件都不再扣费
+func BeginArchiveExtract(userID uint, archivePath, innerPath, password string) (*DownloadCharge, error) {
+	config, _, err := ResolveFileCreditsConfig(archivePath)
+	if err != nil || !config.IsSizeBased() {
+		return BeginFileDownload(userID, archivePath)
+	}
+	size, err := archiveInnerSize(archivePath, innerPath, password)
+	if err != nil {
+		return nil, err
+	}
+	reason := fmt.Sprintf("解压下载: %s%s", archivePath, innerPath)
+	return beginCharge(userID, archivePath, archivePath+":"+innerPath, func() (*model.CreditHold, *quotaUse, error) {
+		ttl := time.Duration(getCreditsSettingInt(conf.CreditsHoldTimeout, 30)) * time.Minute
+		return holdFileCharge(userID, archivePath, size, "archive", reason, ttl)
+	}, func() error {
+		return chargeArchiveExtract(userID, archivePath, size, reason)
+	})
+}
+
+// CheckArchiveExtractPermission 检查解压下载压缩包内文件的权限和所需积分，计价与 BeginArchiveExtract 相同
+func CheckArchiveExtractPermission(userID uint, archivePath, innerPath, password string) (bool, int64, error) {
+	config, _, err := ResolveFileCreditsConfig(archivePath)
+	if err != nil || !config.IsSizeBased() {
+		return CheckFileDownloadPermission(userID, archivePath)
+	}
+	size, err := archiveInnerSize(archivePath, innerPath, password)
+	if err != nil {
+		return false, 0, err
+	}
+	return CheckSizedFileAccessPermission(userID, archivePath, model.CreditsActionDownload, size)
+}
+
+// archiveInnerSize 获取压缩包内文件的大小
+func archiveInnerSize(archivePath, innerPath, password string) (int64, error) {
+	storage, actualPath, err := GetStorageAndActualPath(archivePath)
+	if err != nil {
+		return 0, err
+	}
+	_, obj, err := ArchiveGet(context.Background(), storage, actualPath, model.ArchiveListArgs{
+		ArchiveInnerArgs: model.ArchiveInnerArgs{
+			ArchiveArgs: model.ArchiveArgs{Password: password},
+			InnerPath:   innerPath,
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return obj.GetSize(), nil
+}
+
+// chargeArchiveExtract 按内部文件的大小直接扣除解压下载的积分
+func chargeArchiveExtract(userID uint, archivePath string, size int64, reason string) error {
+	check, err := checkFileCharge(userID, archivePath, model.CreditsActionDownload, size)
+	if err != nil {
+		return err
+	}
+	if !check.allowed {
+		return errs.InsufficientCredits
+	}
+	if used, err := useDownloadQuota(userID, check); err != nil || used != nil {
+		return err
+	}
+	if check.required <= 0 {
+		return nil
+	}
+	return deductCredits(userID, check.required, "archive", reason, archivePath, check.metadata(nil))
+}
+
+// beginCharge 在 key 对应的计费会话中冻结积分，hold 冻结本次下载所需积分或使用免费额度，免费时都返回 nil
+func beginCharge(userID uint, path, key string, hold func() (*model.CreditHold, *quotaUse, error), recharge func() error) (*DownloadCharge, error) {
+	window := downloadChargeWindow()
+	if window <= 0 {
+		h, q, err := hold()
+		if err != nil {
+			return nil, err
+		}
+		return &DownloadCharge{userID: userID, path: path, hold: h, quota: q, recharge: recharge}, nil
+	}
+
+	key = fmt.Sprintf("%d:%s", userID, key)
+	downloadSessionsMu.Lock()
+	session, ok := downloadSessions.Get(key)
+	if !ok {
+		session = &downloadSession{}
+		downloadSessions.Set(key, session, cache.WithEx[*downloadSession](window))
+	}
+	downloadSessionsMu.Unlock()
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	charge := &DownloadCharge{userID: userID, path: path, recharge: recharge}
+	if session.paid {
+		return charge, nil
+	}
+	if session.hold == nil && session.quota == nil {
+		h, q, err := hold()
+		if err != nil {
+			return nil, err
+		}
+		if h == nil && q == nil {
+			session.paid = true
+			return charge, nil
+		}
+		session.hold, session.quota = h, q
+	}
+	session.refs++
+	charge.hold, charge.quota, charge.session = session.hold, session.quota, session
+	return charge, nil
+}
+
+// holdFileDownload 按 credits_hold_timeout 冻结文件所需积分
+func holdFileDownload(userID uint, path string) (*model.CreditHold, *quotaUse, error) {
+	ttl := time.Duration(getCreditsSettingInt(conf.CreditsHoldTimeout, 30)) * time.Minute
+	return holdFileCharge(userID, path, -1, "download", fmt.Sprintf("下载文件: %s", path), ttl)
+}
+
+// Credits 返回本次下载冻结的积分
+func (d *DownloadCharge) Credits() int64 {
+	if d.hold == nil {
+		return 0
+	}
+	return d.hold.Amount
+}
+
+// Finish 下载请求结束时结算冻结的积分，ok 为 true 时扣除并记录已购文件，否则释放，使用的免费额度在失败时退还。
+// 属于计费会话的请求中第一个成功的请求扣除积分，所有请求都失败时才释放冻结
+func (d *DownloadCharge) Finish(ok bool) error {
+	if d.hold == nil && d.quota == nil {
+		return nil
+	}
+	hold, quota, session := d.hold, d.quota, d.session
+	d.hold, d.quota, d.session = nil, nil, nil
+	if session == nil {
+		return d.settle(hold, quota, ok)
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.refs--
+	if session.paid || (session.hold == nil && session.quota == nil) {
+		return nil
+	}
+	if ok {
+		session.paid, session.hold, session.quota = true, nil, nil
+		return d.settle(hold, quota, true)
+	}
+	if session.refs > 0 {
+		return nil
+	}
+	session.hold, session.quota = nil, nil
+	return d.settle(hold, quota, false)
+}
+
+// settle 扣除或释放冻结的积分、退还失败下载使用的免费额度，传输时间超过冻结时长导致冻结已被释放时按当前价格重新扣费
+func (d *DownloadCharge) settle(hold *model.CreditHold, quota *quotaUse, ok bool) error {
+	if quota != nil {
+		if ok {
+			return nil
+		}
+		return refundDownloadQuota(d.userID, quota)
+	}
+	if !ok {
+		return ReleaseCreditHold(hold.ID)
+	}
+	err := CaptureCreditHold(hold.ID)
+	if !errors.Is(err, errs.CreditHoldNotActive) {
+		return err
+	}
+	utils.Log.Warnf("credit hold %d of user %d expired during the download of %s, charging again", hold.ID, d.userID, d.path)
+	return d.recharge()
+}
@@ -0,0 +1,190 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/pkg/errors"
+)
+
+func TestBeginFileDownload(t *testing.T) {
+	user := &model.User{Username: "charge_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if err := op.AddCredits(user.ID, 15, "charge test", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	if err := op.SetFileCreditsConfig("/charge/file.zip", 10, 0, model.PricingFlat, false, 1); err != nil {
+		t.Fatalf("failed to set file config: %+v", err)
+	}
+	balance := func() int64 {
+		credits, err := op.GetUserCredits(user.ID)
+		if err != nil {
+			t.Fatalf("failed to get credits: %+v", err)
+		}
+		return credits.Available()
+	}
+
+	// a failed download releases the held credits
+	charge, err := op.BeginFileDownload(user.ID, "/charge/file.zip")
+	if err != nil {
+		t.Fatalf("failed to begin download: %+v", err)
+	}
+	if charge.Credits() != 10 || balance() != 5 {
+		t.Errorf("expected 10 credits to be held, held %d with %d available", charge.Credits(), balance())
+	}
+	if err = charge.Finish(false); err != nil {
+		t.Fatalf("failed to finish download: %+v", err)
+	}
+	if balance() != 15 {
+		t.Errorf("expected the held credits to be released, got %d", balance())
+	}
+
+	// a successful download is charged and purchased
+	if charge, err = op.BeginFileDownload(user.ID, "/charge/file.zip"); err != nil {
+		t.Fatalf("failed to begin download: %+v", err)
+	}
+	if err = charge.Finish(true); err != nil {
+		t.Fatalf("failed to finish download: %+v", err)
+	}
+	if balance() != 5 {
+		t.Errorf("expected the download to be charged, got %d", balance())
+	}
+	if purchased, _ := op.HasValidDownloadPurchase(user.ID, "/charge/file.zip"); !purchased {
+		t.Errorf("expected the download to be recorded as purchased")
+	}
+
+	// the purchased file is free, others can't be fetched without enough credits
+	if charge, err = op.BeginFileDownload(user.ID, "/charge/file.zip"); err != nil || charge.Credits() != 0 {
+		t.Errorf("expected a purchased file to be free, got %v", err)
+	}
+	if err = op.SetFileCreditsConfig("/charge/other.zip", 10, 0, model.PricingFlat, false, 1); err != nil {
+		t.Fatalf("failed to set file config: %+v", err)
+	}
+	if _, err = op.BeginFileDownload(user.ID, "/charge/other.zip"); !errors.Is(err, errs.InsufficientCredits) {
+		t.Errorf("expected insufficient credits, got %v", err)
+	}
+}
+
+func TestDownloadChargeWindow(t *testing.T) {
+	user := &model.User{Username: "segment_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if err := op.AddCredits(user.ID, 100, "segment test", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	if err := op.SetFileCreditsConfig("/segment/file.zip", 10, 0, model.PricingFlat, false, 1); err != nil {
+		t.Fatalf("failed to set file config: %+v", err)
+	}
+	// repeated downloads are only deduplicated by the charge window
+	if err := op.SaveSettingItem(&model.SettingItem{Key: conf.CreditsPurchaseValidHours, Value: "0", Type: conf.TypeNumber}); err != nil {
+		t.Fatalf("failed to save setting: %+v", err)
+	}
+	defer op.SaveSettingItem(&model.SettingItem{Key: conf.CreditsPurchaseValidHours, Value: "24", Type: conf.TypeNumber})
+	balance := func() int64 {
+		credits, err := op.GetUserCredits(user.ID)
+		if err != nil {
+			t.Fatalf("failed to get credits: %+v", err)
+		}
+		return credits.Balance
+	}
+
+	// two segments of a multi-threaded download share one hold, one of them fails
+	first, err := op.BeginFileDownload(user.ID, "/segment/file.zip")
+	if err != nil {
+		t.Fatalf("failed to begin download: %+v", err)
+	}
+	second, err := op.BeginFileDownload(user.ID, "/segment/file.zip")
+	if err != nil {
+		t.Fatalf("failed to begin download: %+v", err)
+	}
+	if err = first.Finish(false); err != nil {
+		t.Fatalf("failed to finish download: %+v", err)
+	}
+	if err = second.Finish(true); err != nil {
+		t.Fatalf("failed to finish download: %+v", err)
+	}
+	if balance() != 90 {
+		t.Errorf("expected the segmented download to be charged once, got %d", balance())
+	}
+
+	// a resumed download within the window is free
+	resumed, err := op.BeginFileDownload(user.ID, "/segment/file.zip")
+	if err != nil || resumed.Credits() != 0 {
+		t.Fatalf("expected a resumed download to be free, got %v", err)
+	}
+	_ = resumed.Finish(true)
+	if balance() != 90 {
+		t.Errorf("expected a resumed download not to be charged, got %d", balance())
+	}
+
+	// without the window every request is charged
+	if err = op.SaveSettingItem(&model.SettingItem{Key: conf.CreditsChargeWindow, Value: "0", Type: conf.TypeNumber}); err != nil {
+		t.Fatalf("failed to save setting: %+v", err)
+	}
+	defer op.SaveSettingItem(&model.SettingItem{Key: conf.CreditsChargeWindow, Value: "60", Type: conf.TypeNumber})
+	again, err := op.BeginFileDownload(user.ID, "/segment/file.zip")
+	if err != nil {
+		t.Fatalf("failed to begin download: %+v", err)
+	}
+	_ = again.Finish(true)
+	if balance() != 80 {
+		t.Errorf("expected every request to be charged without the window, got %d", balance())
+	}
+}
+
+func TestBeginFilePreview(t *testing.T) {
+	user := &model.User{Username: "preview_charge_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if err := op.AddCredits(user.ID, 20, "preview test", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	if err := op.SetFileCreditsConfig("/charge/video.mp4", 10, 3, model.PricingFlat, false, 1); err != nil {
+		t.Fatalf("failed to set file config: %+v", err)
+	}
+	balance := func() int64 {
+		credits, err := op.GetUserCredits(user.ID)
+		if err != nil {
+			t.Fatalf("failed to get credits: %+v", err)
+		}
+		return credits.Available()
+	}
+
+	// a failed preview is not charged
+	charge, err := op.BeginFilePreview(user.ID, "/charge/video.mp4", nil)
+	if err != nil {
+		t.Fatalf("failed to begin preview: %+v", err)
+	}
+	if charge.Credits() != 3 {
+		t.Errorf("expected the preview price to be held, got %d", charge.Credits())
+	}
+	if err = charge.Finish(false); err != nil {
+		t.Fatalf("failed to finish preview: %+v", err)
+	}
+	if balance() != 20 {
+		t.Errorf("expected a failed preview to be released, got %d", balance())
+	}
+
+	// repeated previews within the charge window are charged once
+	for i := 0; i < 2; i++ {
+		if charge, err = op.BeginFilePreview(user.ID, "/charge/video.mp4", nil); err != nil {
+			t.Fatalf("failed to begin preview: %+v", err)
+		}
+		if err = charge.Finish(true); err != nil {
+			t.Fatalf("failed to finish preview: %+v", err)
+		}
+	}
+	if balance() != 17 {
+		t.Errorf("expected the preview to be charged once, got %d", balance())
+	}
+	if purchased, _ := op.HasValidDownloadPurchase(user.ID, "/charge/video.mp4"); purchased {
+		t.Errorf("expected a preview not to purchase the download")
+	}
+}
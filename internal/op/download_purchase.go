@@ -0,0 +1,81 @@
+package op
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// purchaseValidWindow 已购文件免费重复下载的有效期，0 表示不启用，小于0表示永久有效
+func purchaseValidWindow() time.Duration {
+	hours := getCreditsSettingInt(conf.CreditsPurchaseValidHours, 24)
+	if hours < 0 {
+		return -1
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// HasValidDownloadPurchase 检查用户是否在有效期内已付费下载过该文件，或已通过兑换码永久解锁该文件
+func HasValidDownloadPurchase(userID uint, path string) (bool, error) {
+	window := purchaseValidWindow()
+	purchase, err := db.GetDownloadPurchase(userID, path)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, errors.Wrap(err, "获取购买记录失败")
+	}
+	return purchase.ValidAt(time.Now(), window), nil
+}
+
+// recordDownloadPurchase 记录付费下载，size 为计价时已知的文件大小，未知时为0或负数。
+// 积分已扣除，记录失败只打印日志
+func recordDownloadPurchase(userID uint, path string, credits, size int64) {
+	var mountPath string
+	if storage, _, err := GetStorageAndActualPath(path); err == nil {
+		mountPath = storage.GetStorage().MountPath
+	}
+	err := db.SaveDownloadPurchase(&model.DownloadPurchase{
+		UserID:      userID,
+		Path:        path,
+		Credits:     credits,
+		Size:        max(size, 0),
+		Storage:     mountPath,
+		PurchasedAt: time.Now(),
+	})
+	if err != nil {
+		utils.Log.Errorf("failed to record download purchase of user %d for %s: %+v", userID, path, err)
+	}
+}
+
+// PurchasedFile 用户已付费下载的文件，Valid 表示当前仍可免费重复下载
+type PurchasedFile struct {
+	model.DownloadPurchase
+	Valid      bool       `json:"valid"`
+	ValidUntil *time.Time `json:"valid_until,omitempty"` // 免费重复下载的截止时间，永久有效时为空
+}
+
+// ListDownloadPurchases 分页获取用户已付费下载的文件，按最近付费时间倒序
+func ListDownloadPurchases(userID uint, page, pageSize int) ([]PurchasedFile, int64, error) {
+	purchases, total, err := db.GetDownloadPurchases(userID, page, pageSize)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "获取购买记录失败")
+	}
+	window := purchaseValidWindow()
+	now := time.Now()
+	files := make([]PurchasedFile, 0, len(purchases))
+	for _, purchase := range purchases {
+		file := PurchasedFile{DownloadPurchase: purchase, Valid: purchase.ValidAt(now, window)}
+		if !purchase.Unlocked && window >= 0 {
+			validUntil := purchase.PurchasedAt.Add(window)
+			file.ValidUntil = &validUntil
+		}
+		files = append(files, file)
+	}
+	return files, total, nil
+}
@@ -0,0 +1,86 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestRepeatDownloadWithinWindow(t *testing.T) {
+	user := &model.User{Username: "repeat_purchase_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if err := op.AddCredits(user.ID, 100, "purchase test", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	if err := op.SetFileCreditsConfig("/purchase/file.zip", 10, 0, model.PricingFlat, false, 1); err != nil {
+		t.Fatalf("failed to set file config: %+v", err)
+	}
+	setWindow := func(hours string) {
+		err := op.SaveSettingItem(&model.SettingItem{Key: conf.CreditsPurchaseValidHours, Value: hours, Type: conf.TypeNumber})
+		if err != nil {
+			t.Fatalf("failed to save setting: %+v", err)
+		}
+	}
+	defer setWindow("24")
+	for _, c := range []struct {
+		hours string
+		want  int64
+	}{
+		{"24", 90}, // first download is charged
+		{"24", 90}, // repeat within the window is free
+		{"0", 80},  // window disabled, charged again
+		{"-1", 80}, // purchased forever
+	} {
+		setWindow(c.hours)
+		if err := op.ProcessFileDownload(user.ID, "/purchase/file.zip", nil); err != nil {
+			t.Fatalf("failed to download: %+v", err)
+		}
+		credits, err := op.GetUserCredits(user.ID)
+		if err != nil {
+			t.Fatalf("failed to get credits: %+v", err)
+		}
+		if credits.Balance != c.want {
+			t.Errorf("window %s: balance %d, want %d", c.hours, credits.Balance, c.want)
+		}
+	}
+}
+
+func TestListDownloadPurchases(t *testing.T) {
+	user := &model.User{Username: "purchase_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if err := op.AddCredits(user.ID, 20, "purchase test", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	for _, path := range []string{"/purchase/a.zip", "/purchase/b.zip"} {
+		if err := op.SetFileCreditsConfig(path, 5, 0, model.PricingFlat, false, 1); err != nil {
+			t.Fatalf("failed to set file config: %+v", err)
+		}
+		if err := op.ProcessFileDownload(user.ID, path, nil); err != nil {
+			t.Fatalf("failed to download %s: %+v", path, err)
+		}
+	}
+
+	purchases, total, err := op.ListDownloadPurchases(user.ID, 1, 1)
+	if err != nil {
+		t.Fatalf("failed to list purchases: %+v", err)
+	}
+	if total != 2 || len(purchases) != 1 {
+		t.Fatalf("expected 1 of 2 purchases on the first page, got %d of %d", len(purchases), total)
+	}
+	purchase := purchases[0]
+	if purchase.Path != "/purchase/b.zip" || purchase.Credits != 5 {
+		t.Errorf("expected the latest purchase first, got %+v", purchase)
+	}
+	if !purchase.Valid || purchase.ValidUntil == nil {
+		t.Errorf("expected the purchase to be valid for a limited time, got %+v", purchase)
+	}
+	if purchases, _, _ = op.ListDownloadPurchases(user.ID+1, 1, 10); len(purchases) != 0 {
+		t.Errorf("expected no purchases of other users, got %d", len(purchases))
+	}
+}
@@ -0,0 +1,112 @@
+package op
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const (
+	quotaDayLayout     = "2006-01-02"
+	quotaRetentionDays = 7
+	bytesPerGB         = 1024 * 1024 * 1024
+)
+
+// GetDailyDownloadQuota 获取用户的每日免费下载额度，定价组中设置的额度优先于全局设置
+func GetDailyDownloadQuota(userID uint) (model.DownloadQuota, error) {
+	quota := model.DownloadQuota{
+		Downloads: getCreditsSettingInt(conf.FreeDailyDownloads, 0),
+		Bytes:     getCreditsSettingInt(conf.FreeDailyDownloadGB, 0) * bytesPerGB,
+	}
+	group, err := db.GetUserPricingGroup(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return quota, nil
+		}
+		return quota, errors.Wrap(err, "获取用户定价组失败")
+	}
+	if group.FreeDailyDownloads > 0 {
+		quota.Downloads = group.FreeDailyDownloads
+	}
+	if group.FreeDailyGB > 0 {
+		quota.Bytes = group.FreeDailyGB * bytesPerGB
+	}
+	return quota, nil
+}
+
+// GetDownloadQuotaUsage 获取用户当日的免费额度使用量
+func GetDownloadQuotaUsage(userID uint) (*model.DownloadQuotaUsage, error) {
+	usage, err := db.GetDownloadQuotaUsage(userID, quotaDay(time.Now()))
+	if err != nil {
+		return nil, errors.Wrap(err, "获取免费额度使用量失败")
+	}
+	return usage, nil
+}
+
+// checkDownloadQuota 检查当日剩余免费额度是否足够下载该文件，size 小于0时按需获取文件大小
+func checkDownloadQuota(userID uint, filePath string, size int64) (*model.DownloadQuota, int64, error) {
+	quota, err := GetDailyDownloadQuota(userID)
+	if err != nil || !quota.Enabled() {
+		return nil, size, err
+	}
+	if size < 0 {
+		size = 0
+		if quota.Bytes > 0 {
+			if size, err = getFileSize(filePath); err != nil {
+				return nil, size, errors.WithMessage(err, "获取文件大小失败")
+			}
+		}
+	}
+	usage, err := GetDownloadQuotaUsage(userID)
+	if err != nil {
+		return nil, size, err
+	}
+	if !quota.Allows(usage, size) {
+		return nil, size, nil
+	}
+	return &quota, size, nil
+}
+
+// quotaUse 一次下载使用的免费额度，下载失败时退还
+type quotaUse struct {
+	day  string
+	size int64
+}
+
+// consumeDownloadQuota 使用一次当日免费额度，额度已被用完时返回 nil
+func consumeDownloadQuota(userID uint, size int64, quota *model.DownloadQuota) (*quotaUse, error) {
+	day := quotaDay(time.Now())
+	consumed, err := db.ConsumeDownloadQuota(userID, day, size, *quota)
+	if err != nil {
+		return nil, errors.Wrap(err, "使用免费额度失败")
+	}
+	if !consumed {
+		return nil, nil
+	}
+	return &quotaUse{day: day, size: size}, nil
+}
+
+// refundDownloadQuota 下载失败时退还使用的免费额度
+func refundDownloadQuota(userID uint, use *quotaUse) error {
+	if err := db.RefundDownloadQuota(userID, use.day, use.size); err != nil {
+		return errors.Wrap(err, "退还免费额度失败")
+	}
+	return nil
+}
+
+// CleanDownloadQuotaUsages 清理过期的免费额度使用记录
+func CleanDownloadQuotaUsages() error {
+	before := quotaDay(time.Now().AddDate(0, 0, -quotaRetentionDays))
+	if err := db.CleanDownloadQuotaUsages(before); err != nil {
+		return errors.Wrap(err, "清理免费额度使用记录失败")
+	}
+	return nil
+}
+
+func quotaDay(t time.Time) string {
+	return t.Format(quotaDayLayout)
+}
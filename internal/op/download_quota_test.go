@@ -0,0 +1,98 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestDailyDownloadQuota(t *testing.T) {
+	user := &model.User{Username: "quota_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if err := op.AddCredits(user.ID, 100, "quota test", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	if err := op.SetFileCreditsConfig("/quota/file.zip", 10, 0, model.PricingFlat, false, 1); err != nil {
+		t.Fatalf("failed to set file config: %+v", err)
+	}
+	group := &model.PricingGroup{Name: "quota", Multiplier: 1, FreeDailyDownloads: 2}
+	if err := op.SavePricingGroup(group); err != nil {
+		t.Fatalf("failed to save group: %+v", err)
+	}
+	if err := op.SetUserPricingGroup(user.ID, group.ID); err != nil {
+		t.Fatalf("failed to assign group: %+v", err)
+	}
+	// the first two downloads are free, the third one is charged
+	for i, want := range []int64{100, 100, 90} {
+		if err := op.ProcessFileDownload(user.ID, "/quota/file.zip", nil); err != nil {
+			t.Fatalf("download %d failed: %+v", i, err)
+		}
+		credits, err := op.GetUserCredits(user.ID)
+		if err != nil {
+			t.Fatalf("failed to get credits: %+v", err)
+		}
+		if credits.Balance != want {
+			t.Errorf("download %d: balance %d, want %d", i, credits.Balance, want)
+		}
+	}
+	usage, err := op.GetDownloadQuotaUsage(user.ID)
+	if err != nil {
+		t.Fatalf("failed to get usage: %+v", err)
+	}
+	if usage.Downloads != 2 {
+		t.Errorf("expected 2 free downloads used, got %d", usage.Downloads)
+	}
+}
+
+func TestDownloadQuotaRefund(t *testing.T) {
+	user := &model.User{Username: "quota_refund_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if err := op.SetFileCreditsConfig("/quota/refund.zip", 10, 0, model.PricingFlat, false, 1); err != nil {
+		t.Fatalf("failed to set file config: %+v", err)
+	}
+	group := &model.PricingGroup{Name: "quota_refund", Multiplier: 1, FreeDailyDownloads: 1}
+	if err := op.SavePricingGroup(group); err != nil {
+		t.Fatalf("failed to save group: %+v", err)
+	}
+	if err := op.SetUserPricingGroup(user.ID, group.ID); err != nil {
+		t.Fatalf("failed to assign group: %+v", err)
+	}
+	used := func() int64 {
+		usage, err := op.GetDownloadQuotaUsage(user.ID)
+		if err != nil {
+			t.Fatalf("failed to get usage: %+v", err)
+		}
+		return usage.Downloads
+	}
+
+	// a failed download gives the free download back
+	charge, err := op.BeginFileDownload(user.ID, "/quota/refund.zip")
+	if err != nil {
+		t.Fatalf("failed to begin download: %+v", err)
+	}
+	if used() != 1 {
+		t.Errorf("expected the free download to be used while downloading, got %d", used())
+	}
+	if err = charge.Finish(false); err != nil {
+		t.Fatalf("failed to finish download: %+v", err)
+	}
+	if used() != 0 {
+		t.Errorf("expected the free download to be refunded, got %d", used())
+	}
+
+	// so the next download is still free
+	if charge, err = op.BeginFileDownload(user.ID, "/quota/refund.zip"); err != nil {
+		t.Fatalf("expected the refunded free download to be usable without credits: %+v", err)
+	}
+	if err = charge.Finish(true); err != nil {
+		t.Fatalf("failed to finish download: %+v", err)
+	}
+	if used() != 1 {
+		t.Errorf("expected the free download to be used, got %d", used())
+	}
+}
@@ -0,0 +1,91 @@
+package op
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// IssueDownloadToken 检查权限并扣除下载积分后签发下载令牌，扣费和签发在同一次调用中完成，
+// 凭令牌下载时不再检查积分；令牌在 download_token_ttl 分钟内有效，可使用 download_token_max_uses 次
+func IssueDownloadToken(userID uint, path string, meta *model.TransactionMetadata) (*model.DownloadToken, error) {
+	credits, err := processFileDownload(userID, path, meta)
+	if err != nil {
+		return nil, err
+	}
+	token, err := generateToken(32)
+	if err != nil {
+		return nil, errors.Wrap(err, "生成下载令牌失败")
+	}
+	downloadToken := &model.DownloadToken{
+		UserID:    userID,
+		Token:     token,
+		Path:      path,
+		Credits:   credits,
+		MaxUses:   int(max(getCreditsSettingInt(conf.DownloadTokenMaxUses, 1), 1)),
+		ExpiresAt: time.Now().Add(time.Duration(max(getCreditsSettingInt(conf.DownloadTokenTTL, 5), 1)) * time.Minute),
+	}
+	// 积分已扣除并记录为已购文件，签发失败时用户仍可在有效期内免费下载
+	if err = db.CreateDownloadToken(downloadToken); err != nil {
+		return nil, errors.Wrap(err, "签发下载令牌失败")
+	}
+	return downloadToken, nil
+}
+
+// UseDownloadToken 校验下载令牌是否可用于下载 path，consume 为 true 时使用一次，
+// 令牌不存在、路径不符、已撤销、已用完或已过期时返回 errs.InvalidDownloadToken
+func UseDownloadToken(token, path string, consume bool) (*model.DownloadToken, error) {
+	if token == "" {
+		return nil, errs.InvalidDownloadToken
+	}
+	downloadToken, err := db.GetDownloadToken(token)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.InvalidDownloadToken
+		}
+		return nil, errors.Wrap(err, "获取下载令牌失败")
+	}
+	now := time.Now()
+	if downloadToken.Path != path || !downloadToken.Usable(now) {
+		return nil, errs.InvalidDownloadToken
+	}
+	if !consume {
+		return downloadToken, nil
+	}
+	ok, err := db.UseDownloadToken(downloadToken.ID, now)
+	if err != nil {
+		return nil, errors.Wrap(err, "使用下载令牌失败")
+	}
+	if !ok {
+		return nil, errs.InvalidDownloadToken
+	}
+	downloadToken.Uses++
+	return downloadToken, nil
+}
+
+// ListDownloadTokens 获取用户未过期的下载令牌
+func ListDownloadTokens(userID uint) ([]model.DownloadToken, error) {
+	return db.GetDownloadTokensByUserID(userID, time.Now())
+}
+
+// RevokeDownloadToken 撤销用户的下载令牌，已扣除的积分不退还
+func RevokeDownloadToken(userID, id uint) error {
+	ok, err := db.RevokeDownloadToken(userID, id)
+	if err != nil {
+		return errors.Wrap(err, "撤销下载令牌失败")
+	}
+	if !ok {
+		return errs.InvalidDownloadToken
+	}
+	return nil
+}
+
+// CleanExpiredDownloadTokens 删除已过期的下载令牌
+func CleanExpiredDownloadTokens() error {
+	return db.DeleteExpiredDownloadTokens(time.Now())
+}
@@ -0,0 +1,72 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/pkg/errors"
+)
+
+func TestDownloadToken(t *testing.T) {
+	user := &model.User{Username: "token_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if err := op.AddCredits(user.ID, 15, "token test", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	if err := op.SetFileCreditsConfig("/token/file.zip", 10, 0, model.PricingFlat, false, 1); err != nil {
+		t.Fatalf("failed to set file config: %+v", err)
+	}
+
+	token, err := op.IssueDownloadToken(user.ID, "/token/file.zip", nil)
+	if err != nil {
+		t.Fatalf("failed to issue download token: %+v", err)
+	}
+	if token.Credits != 10 || token.MaxUses != 1 || token.Token == "" {
+		t.Errorf("unexpected download token %+v", token)
+	}
+	if credits, _ := op.GetUserCredits(user.ID); credits.Available() != 5 {
+		t.Errorf("expected the credits to be deducted when the token is issued, got %d", credits.Available())
+	}
+
+	if _, err = op.UseDownloadToken(token.Token, "/token/other.zip", true); !errors.Is(err, errs.InvalidDownloadToken) {
+		t.Errorf("expected the token to be bound to its path, got %v", err)
+	}
+	if _, err = op.UseDownloadToken(token.Token, "/token/file.zip", false); err != nil {
+		t.Errorf("expected a HEAD request to be allowed: %+v", err)
+	}
+	used, err := op.UseDownloadToken(token.Token, "/token/file.zip", true)
+	if err != nil {
+		t.Fatalf("failed to use download token: %+v", err)
+	}
+	if used.UserID != user.ID || used.Uses != 1 {
+		t.Errorf("unexpected used download token %+v", used)
+	}
+	if _, err = op.UseDownloadToken(token.Token, "/token/file.zip", true); !errors.Is(err, errs.InvalidDownloadToken) {
+		t.Errorf("expected the token to be used only once, got %v", err)
+	}
+
+	// a purchased file costs nothing, a revoked token can't be used
+	token, err = op.IssueDownloadToken(user.ID, "/token/file.zip", nil)
+	if err != nil {
+		t.Fatalf("failed to issue download token: %+v", err)
+	}
+	if token.Credits != 0 {
+		t.Errorf("expected no charge for a purchased file, got %d", token.Credits)
+	}
+	if err = op.RevokeDownloadToken(user.ID+1, token.ID); !errors.Is(err, errs.InvalidDownloadToken) {
+		t.Errorf("expected tokens of other users not to be revoked, got %v", err)
+	}
+	if err = op.RevokeDownloadToken(user.ID, token.ID); err != nil {
+		t.Fatalf("failed to revoke download token: %+v", err)
+	}
+	if _, err = op.UseDownloadToken(token.Token, "/token/file.zip", true); !errors.Is(err, errs.InvalidDownloadToken) {
+		t.Errorf("expected a revoked token to be rejected, got %v", err)
+	}
+	if tokens, _ := op.ListDownloadTokens(user.ID); len(tokens) != 2 {
+		t.Errorf("expected 2 download tokens, got %d", len(tokens))
+	}
+}
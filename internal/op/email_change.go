@@ -0,0 +1,137 @@
+package op
+
+import (
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/go-cache"
+	"github.com/pkg/errors"
+)
+
+const (
+	emailChangeInterval    = time.Minute      // 两次发起更换的最短间隔
+	emailChangeExpire      = 30 * time.Minute // 发起更换后完成验证的期限
+	emailChangeMaxAttempts = 5                // 验证码允许的错误次数，超过后需重新发起
+)
+
+// pendingEmailChange 已发送验证码、等待确认的邮箱更换
+type pendingEmailChange struct {
+	OldEmail  string
+	NewEmail  string
+	Attempts  int
+	CreatedAt time.Time
+}
+
+var pendingEmailChanges = cache.NewMemCache[*pendingEmailChange]()
+
+// RequestEmailChange 发起邮箱更换，分别向原邮箱和新邮箱发送验证码；账户此前没有邮箱时只验证新邮箱
+func RequestEmailChange(user *model.User, newEmail string) error {
+	newEmail = normalizeEmail(newEmail)
+	key := user.Username
+	if pending, ok := pendingEmailChanges.Get(key); ok && time.Since(pending.CreatedAt) < emailChangeInterval {
+		return errs.EmailChangeLimited
+	}
+	oldEmail := userEmail(user)
+	if strings.EqualFold(oldEmail, newEmail) {
+		return errors.New("新邮箱与当前邮箱相同")
+	}
+	if _, err := db.GetUserRegistrationByEmail(newEmail); err == nil {
+		return errors.New("邮箱已被注册")
+	}
+	if err := checkEmailDomain(newEmail); err != nil {
+		return err
+	}
+
+	emails := []string{newEmail}
+	if oldEmail != "" {
+		emails = append(emails, oldEmail)
+	}
+	codes := make([]*model.VerificationCode, 0, len(emails))
+	for _, email := range emails {
+		code, err := CreateVerificationCode(email, model.VerificationCodeChangeEmail)
+		if err != nil {
+			return err
+		}
+		codes = append(codes, code)
+	}
+	pendingEmailChanges.Set(key, &pendingEmailChange{
+		OldEmail:  oldEmail,
+		NewEmail:  newEmail,
+		CreatedAt: time.Now(),
+	}, cache.WithEx[*pendingEmailChange](emailChangeExpire))
+	for _, code := range codes {
+		if err := SendVerificationCode(code); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConfirmEmailChange 校验原邮箱和新邮箱收到的验证码，通过后更新账户邮箱并写入审计记录
+func ConfirmEmailChange(user *model.User, oldCode, newCode, ip string) error {
+	key := user.Username
+	pending, ok := pendingEmailChanges.Get(key)
+	if !ok {
+		return errs.NoPendingEmailChange
+	}
+	if pending.Attempts >= emailChangeMaxAttempts {
+		pendingEmailChanges.Del(key)
+		return errs.NoPendingEmailChange
+	}
+	if !checkEmailChangeCode(pending.NewEmail, newCode) ||
+		(pending.OldEmail != "" && !checkEmailChangeCode(pending.OldEmail, oldCode)) {
+		pending.Attempts++
+		return errs.InvalidEmailChangeCode
+	}
+	pendingEmailChanges.Del(key)
+	for _, email := range []string{pending.NewEmail, pending.OldEmail} {
+		if email == "" {
+			continue
+		}
+		if err := db.InvalidateVerificationCodes(email, model.VerificationCodeChangeEmail, 0); err != nil {
+			return errors.Wrap(err, "更新验证码状态失败")
+		}
+	}
+
+	registration, err := db.GetUserRegistrationByUsername(user.Username)
+	if err != nil {
+		// 账户没有注册记录（如由管理员创建），新建一条已注册的记录保存邮箱
+		token, err := generateToken(32)
+		if err != nil {
+			return errors.Wrap(err, "生成验证令牌失败")
+		}
+		registration = &model.UserRegistration{
+			Username:  user.Username,
+			PwdHash:   user.PwdHash,
+			Salt:      user.Salt,
+			Status:    model.RegistrationRegistered,
+			Token:     token,
+			ExpiresAt: time.Now(),
+		}
+	}
+	registration.Email = pending.NewEmail
+	err = db.ChangeUserEmail(registration, &model.EmailChange{
+		UserID:   user.ID,
+		OldEmail: pending.OldEmail,
+		NewEmail: pending.NewEmail,
+		IP:       ip,
+	})
+	if err != nil {
+		return errors.Wrap(err, "更新邮箱失败")
+	}
+	return nil
+}
+
+// ListEmailChanges 获取邮箱更换的审计记录
+func ListEmailChanges(userID uint, page, pageSize int) ([]model.EmailChange, int64, error) {
+	return db.GetEmailChanges(userID, page, pageSize)
+}
+
+// checkEmailChangeCode 校验验证码但不标记为已使用，两个验证码都通过后才一并作废
+func checkEmailChangeCode(email, code string) bool {
+	_, err := findVerificationCode(email, model.VerificationCodeChangeEmail, code)
+	return err == nil
+}
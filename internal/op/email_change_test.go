@@ -0,0 +1,56 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/pkg/errors"
+)
+
+func TestEmailChange(t *testing.T) {
+	user := &model.User{Username: "email_user", Password: "password"}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if err := op.RecordSSORegistration(user, "old@example.com"); err != nil {
+		t.Fatalf("failed to record registration: %+v", err)
+	}
+
+	// sending fails without a mail provider, the change is pending anyway
+	_ = op.RequestEmailChange(user, "new@example.com")
+	if err := op.RequestEmailChange(user, "new@example.com"); !errors.Is(err, errs.EmailChangeLimited) {
+		t.Errorf("expected a second request to be rate limited, got %v", err)
+	}
+	oldCode, err := db.GetVerificationCode("old@example.com", model.VerificationCodeChangeEmail)
+	if err != nil {
+		t.Fatalf("expected a code for the old email: %+v", err)
+	}
+	newCode, err := db.GetVerificationCode("new@example.com", model.VerificationCodeChangeEmail)
+	if err != nil {
+		t.Fatalf("expected a code for the new email: %+v", err)
+	}
+	if err = op.ConfirmEmailChange(user, "wrong", newCode.Code, "127.0.0.1"); !errors.Is(err, errs.InvalidEmailChangeCode) {
+		t.Errorf("expected both codes to be required, got %v", err)
+	}
+	if err = op.ConfirmEmailChange(user, oldCode.Code, newCode.Code, "127.0.0.1"); err != nil {
+		t.Fatalf("failed to confirm email change: %+v", err)
+	}
+
+	registration, err := db.GetUserRegistrationByUsername("email_user")
+	if err != nil {
+		t.Fatalf("failed to get registration: %+v", err)
+	}
+	if registration.Email != "new@example.com" {
+		t.Errorf("expected the email to be changed, got %s", registration.Email)
+	}
+	changes, total, err := op.ListEmailChanges(user.ID, 1, 10)
+	if err != nil || total != 1 {
+		t.Fatalf("expected an audit record, got %d, %v", total, err)
+	}
+	if changes[0].OldEmail != "old@example.com" || changes[0].NewEmail != "new@example.com" {
+		t.Errorf("unexpected audit record: %+v", changes[0])
+	}
+}
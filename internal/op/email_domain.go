@@ -0,0 +1,43 @@
+package op
+
+import (
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+)
+
+// normalizeEmail 去除首尾空白并转为小写，邮箱在注册、登录和找回密码时均不区分大小写
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// checkEmailDomain 校验邮箱域名是否符合注册的域名白名单和黑名单，子域名同样匹配
+func checkEmailDomain(email string) error {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return errs.EmailDomainNotAllowed
+	}
+	domain := strings.ToLower(strings.TrimSpace(email[at+1:]))
+	if matchEmailDomain(domain, getSettingStr(conf.EmailBlockedDomains)) {
+		return errs.EmailDomainBlocked
+	}
+	allowed := getSettingStr(conf.EmailAllowedDomains)
+	if strings.TrimSpace(allowed) != "" && !matchEmailDomain(domain, allowed) {
+		return errs.EmailDomainNotAllowed
+	}
+	return nil
+}
+
+// matchEmailDomain 判断域名是否在逗号或换行分隔的域名列表中，或是其中某个域名的子域名
+func matchEmailDomain(domain, list string) bool {
+	for _, d := range strings.FieldsFunc(list, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r' || r == ' '
+	}) {
+		d = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(d), "@"))
+		if d != "" && (domain == d || strings.HasSuffix(domain, "."+d)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package op
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// 访客购买的下载令牌有效期，令牌在支付完成时签发，访客可能稍后才回到页面领取
+const shareDownloadTTL = 24 * time.Hour
+
+// CreateFileShare 创建文件分享链接，expireHours 为0表示不过期
+func CreateFileShare(userID uint, path string, expireHours int) (*model.FileShare, error) {
+	token, err := generateToken(16)
+	if err != nil {
+		return nil, errors.Wrap(err, "生成分享令牌失败")
+	}
+	share := &model.FileShare{
+		UserID: userID,
+		Token:  token,
+		Path:   path,
+	}
+	if expireHours > 0 {
+		expiresAt := time.Now().Add(time.Duration(expireHours) * time.Hour)
+		share.ExpiresAt = &expiresAt
+	}
+	if err = db.CreateFileShare(share); err != nil {
+		return nil, errors.Wrap(err, "创建分享链接失败")
+	}
+	return share, nil
+}
+
+// GetFileShare 根据分享令牌获取分享链接，不存在、已过期或分享者已被禁用、删除时返回 errs.ShareNotFound
+func GetFileShare(token string) (*model.FileShare, error) {
+	share, err := db.GetFileShareByToken(token, false)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.ShareNotFound
+		}
+		return nil, errors.Wrap(err, "获取分享链接失败")
+	}
+	if share.IsExpired() {
+		return nil, errs.ShareNotFound
+	}
+	owner, err := GetUserById(share.UserID)
+	if err != nil || owner.Disabled {
+		return nil, errs.ShareNotFound
+	}
+	return share, nil
+}
+
+// ListFileShares 获取用户创建的分享链接
+func ListFileShares(userID uint) ([]model.FileShare, error) {
+	return db.GetFileSharesByUserID(userID)
+}
+
+// DeleteFileShare 删除用户的分享链接，已售出的下载令牌仍可使用
+func DeleteFileShare(userID, id uint) error {
+	ok, err := db.DeleteFileShare(userID, id)
+	if err != nil {
+		return errors.Wrap(err, "删除分享链接失败")
+	}
+	if !ok {
+		return errs.ShareNotFound
+	}
+	return nil
+}
+
+// EstimateShareCredits 计算访客通过分享链接下载文件所需积分，按访客用户计价
+func EstimateShareCredits(share *model.FileShare) (int64, error) {
+	guest, err := GetGuest()
+	if err != nil {
+		return 0, err
+	}
+	return EstimateFileCredits(guest.ID, share.Path, model.CreditsActionDownload, -1)
+}
+
+// CreateShareOrder 为访客创建购买分享文件单次下载的订单，金额按文件所需积分折算。
+// 订单记在访客用户名下，支付后不增加积分，而是签发下载令牌，访客凭返回的 ClaimToken 领取
+func CreateShareOrder(token, paymentMethod string) (*model.PaymentOrder, error) {
+	share, err := GetFileShare(token)
+	if err != nil {
+		return nil, err
+	}
+	credits, err := EstimateShareCredits(share)
+	if err != nil {
+		return nil, err
+	}
+	if credits <= 0 {
+		return nil, errs.ShareFileFree
+	}
+	amount, err := CreditsPrice(credits)
+	if err != nil {
+		return nil, err
+	}
+	guest, err := GetGuest()
+	if err != nil {
+		return nil, err
+	}
+	claimToken, err := generateToken(32)
+	if err != nil {
+		return nil, errors.Wrap(err, "生成领取令牌失败")
+	}
+	order := &model.PaymentOrder{
+		OrderNo:       generateOrderID(),
+		UserID:        guest.ID,
+		Money:         amount,
+		Credits:       credits,
+		PaymentMethod: paymentMethod,
+		Status:        model.PaymentOrderPending,
+		ExpiresAt:     time.Now().Add(30 * time.Minute), // 30分钟过期
+		ShareToken:    share.Token,
+		ClaimToken:    claimToken,
+	}
+	if err = db.CreatePaymentOrder(order); err != nil {
+		return nil, errors.Wrap(err, "创建支付订单失败")
+	}
+	return order, nil
+}
+
+// fulfillShareOrder 为已支付的访客订单签发下载令牌，分享链接在支付后过期或被删除时仍按原路径签发
+func fulfillShareOrder(order *model.PaymentOrder) (*model.DownloadToken, error) {
+	share, err := db.GetFileShareByToken(order.ShareToken, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "获取分享链接失败")
+	}
+	token, err := generateToken(32)
+	if err != nil {
+		return nil, errors.Wrap(err, "生成下载令牌失败")
+	}
+	downloadToken := &model.DownloadToken{
+		UserID:    order.UserID,
+		Token:     token,
+		Path:      share.Path,
+		Credits:   order.Credits,
+		MaxUses:   int(max(getCreditsSettingInt(conf.DownloadTokenMaxUses, 1), 1)),
+		ExpiresAt: time.Now().Add(shareDownloadTTL),
+	}
+	if err = db.CreateDownloadToken(downloadToken); err != nil {
+		return nil, errors.Wrap(err, "签发下载令牌失败")
+	}
+	ok, err := db.SetPaymentOrderDownloadToken(order.ID, downloadToken.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "更新支付订单失败")
+	}
+	if !ok {
+		// 支付通知与访客领取同时签发，保留先关联的令牌
+		_, _ = db.RevokeDownloadToken(order.UserID, downloadToken.ID)
+		latest, err := db.GetPaymentOrderByOrderNo(order.OrderNo)
+		if err != nil {
+			return nil, errors.Wrap(err, "获取支付订单失败")
+		}
+		*order = *latest
+		return db.GetDownloadTokenByID(order.DownloadTokenID)
+	}
+	order.DownloadTokenID = downloadToken.ID
+	return downloadToken, nil
+}
+
+// ClaimShareDownload 访客凭分享令牌和领取令牌查询订单，订单已支付时返回下载令牌，订单不属于该分享时返回
+// errs.PaymentOrderNotFound。订单仍待支付时主动向支付渠道查询一次，支付完成时签发令牌失败的订单在此补签
+func ClaimShareDownload(shareToken, claimToken string) (*model.PaymentOrder, *model.DownloadToken, error) {
+	if shareToken == "" || claimToken == "" {
+		return nil, nil, errs.PaymentOrderNotFound
+	}
+	order, err := db.GetPaymentOrderByClaimToken(claimToken)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errs.PaymentOrderNotFound
+		}
+		return nil, nil, errors.Wrap(err, "获取支付订单失败")
+	}
+	if order.ShareToken != shareToken {
+		return nil, nil, errs.PaymentOrderNotFound
+	}
+	if order.Status == model.PaymentOrderPending && !order.IsExpired() {
+		if synced, err := SyncPaymentOrder(order.OrderNo); err == nil {
+			order = synced
+		}
+	}
+	if !order.IsPaid() {
+		return order, nil, nil
+	}
+	if order.DownloadTokenID == 0 {
+		downloadToken, err := fulfillShareOrder(order)
+		return order, downloadToken, err
+	}
+	downloadToken, err := db.GetDownloadTokenByID(order.DownloadTokenID)
+	if err != nil {
+		return order, nil, errors.Wrap(err, "获取下载令牌失败")
+	}
+	return order, downloadToken, nil
+}
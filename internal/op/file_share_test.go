@@ -0,0 +1,92 @@
+package op_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/pkg/errors"
+)
+
+func TestShareGuestCheckout(t *testing.T) {
+	if _, err := op.GetGuest(); err != nil {
+		if err = op.CreateUser(&model.User{Username: "share_guest", Role: model.GUEST, Disabled: true}); err != nil {
+			t.Fatalf("failed to create guest user: %+v", err)
+		}
+	}
+	owner := &model.User{Username: "share_owner", Role: model.GENERAL}
+	if err := op.CreateUser(owner); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if err := op.SetFileCreditsConfig("/share/file.zip", 10, 0, model.PricingFlat, false, 1); err != nil {
+		t.Fatalf("failed to set file config: %+v", err)
+	}
+
+	free, err := op.CreateFileShare(owner.ID, "/share/free.txt", 0)
+	if err != nil {
+		t.Fatalf("failed to create share: %+v", err)
+	}
+	if _, err = op.CreateShareOrder(free.Token, "alipay"); !errors.Is(err, errs.ShareFileFree) {
+		t.Errorf("expected no order for a free file, got %v", err)
+	}
+
+	share, err := op.CreateFileShare(owner.ID, "/share/file.zip", 1)
+	if err != nil {
+		t.Fatalf("failed to create share: %+v", err)
+	}
+	order, err := op.CreateShareOrder(share.Token, "alipay")
+	if err != nil {
+		t.Fatalf("failed to create share order: %+v", err)
+	}
+	price, _ := op.CreditsPrice(10)
+	if order.Credits != 10 || !order.Money.Equal(price) || order.ClaimToken == "" {
+		t.Errorf("unexpected share order %+v", order)
+	}
+	if _, token, err := op.ClaimShareDownload(share.Token, order.ClaimToken); err != nil || token != nil {
+		t.Errorf("expected no download token before payment, got %+v, %v", token, err)
+	}
+	if _, _, err := op.ClaimShareDownload(free.Token, order.ClaimToken); !errors.Is(err, errs.PaymentOrderNotFound) {
+		t.Errorf("expected an order of another share to be rejected, got %v", err)
+	}
+
+	// the link may be removed after the guest paid
+	if err = op.DeleteFileShare(owner.ID, share.ID); err != nil {
+		t.Fatalf("failed to delete share: %+v", err)
+	}
+	if _, err = op.GetFileShare(share.Token); !errors.Is(err, errs.ShareNotFound) {
+		t.Errorf("expected a deleted share not to be found, got %v", err)
+	}
+	if err = op.CompletePaymentOrder(order.OrderNo, "tx_share", order.Money, time.Now()); err != nil {
+		t.Fatalf("failed to complete share order: %+v", err)
+	}
+	paid, token, err := op.ClaimShareDownload(share.Token, order.ClaimToken)
+	if err != nil {
+		t.Fatalf("failed to claim share download: %+v", err)
+	}
+	if !paid.IsPaid() || token == nil || token.Path != "/share/file.zip" || token.Credits != 10 {
+		t.Fatalf("unexpected claimed download token %+v", token)
+	}
+	if _, again, _ := op.ClaimShareDownload(share.Token, order.ClaimToken); again == nil || again.ID != token.ID {
+		t.Errorf("expected the same download token to be returned, got %+v", again)
+	}
+	if _, err = op.UseDownloadToken(token.Token, "/share/file.zip", true); err != nil {
+		t.Errorf("failed to use the bought download token: %+v", err)
+	}
+	if credits, err := op.GetUserCredits(token.UserID); err == nil && credits.Balance != 0 {
+		t.Errorf("expected a guest order not to add credits, got %d", credits.Balance)
+	}
+	if _, _, err = op.ClaimShareDownload(share.Token, "unknown"); !errors.Is(err, errs.PaymentOrderNotFound) {
+		t.Errorf("expected an unknown claim token to be rejected, got %v", err)
+	}
+
+	// shares of disabled accounts can't be bought
+	owner.Disabled = true
+	if err = op.UpdateUser(owner); err != nil {
+		t.Fatalf("failed to disable user: %+v", err)
+	}
+	if _, err = op.CreateShareOrder(free.Token, "alipay"); !errors.Is(err, errs.ShareNotFound) {
+		t.Errorf("expected a share of a disabled user to be rejected, got %v", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/payment"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -82,6 +83,9 @@ var settingItemHooks = map[string]SettingItemHook{
 		conf.SlicesMap[conf.IgnoreDirectLinkParams] = strings.Split(item.Value, ",")
 		return nil
 	},
+	conf.PaymentProviders: func(item *model.SettingItem) error {
+		return payment.LoadProviders(item.Value)
+	},
 }
 
 func RegisterSettingItemHook(key string, hook SettingItemHook) {
@@ -109,3 +113,33 @@ func callStorageHooks(typ string, storage driver.Driver) {
 func RegisterStorageHook(hook StorageHook) {
 	storageHooks = append(storageHooks, hook)
 }
+
+// User
+type UserHook func(user *model.User)
+
+var userCreatedHooks = []UserHook{
+	provisionUserCredits,
+}
+
+func callUserCreatedHooks(user *model.User) {
+	for _, hook := range userCreatedHooks {
+		hook(user)
+	}
+}
+
+func RegisterUserCreatedHook(hook UserHook) {
+	userCreatedHooks = append(userCreatedHooks, hook)
+}
+
+var userApprovedHooks = make([]UserHook, 0)
+
+func callUserApprovedHooks(user *model.User) {
+	for _, hook := range userApprovedHooks {
+		hook(user)
+	}
+}
+
+// RegisterUserApprovedHook registers a hook called after a registration becomes a user
+func RegisterUserApprovedHook(hook UserHook) {
+	userApprovedHooks = append(userApprovedHooks, hook)
+}
@@ -0,0 +1,136 @@
+package op
+
+import (
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// inviteCodeFormat 邀请码格式，随机部分约 59 位熵
+var inviteCodeFormat = model.RedeemCodeFormat{
+	Prefix:    "INV",
+	Length:    12,
+	Charset:   model.RedeemCharsetUnambiguous,
+	GroupSize: 4,
+}
+
+// registrationMode 返回当前的注册模式，未设置时为管理员审核
+func registrationMode() string {
+	if mode := getSettingStr(conf.RegistrationMode); mode != "" {
+		return mode
+	}
+	return model.RegistrationModeApproval
+}
+
+// GenerateInviteCodes 生成 count 个邀请码，创建者即为邀请人
+func GenerateInviteCodes(createdBy uint, count, maxUses int, expiresAt *time.Time, note string) ([]model.InviteCode, error) {
+	if count <= 0 {
+		return nil, errors.New("邀请码数量必须大于0")
+	}
+	if maxUses <= 0 {
+		maxUses = 1
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, errors.New("邀请码过期时间必须晚于当前时间")
+	}
+	codes, err := generateUniqueRedeemCodes(inviteCodeFormat, count)
+	if err != nil {
+		return nil, err
+	}
+	inviteCodes := make([]model.InviteCode, 0, count)
+	for _, code := range codes {
+		inviteCodes = append(inviteCodes, model.InviteCode{
+			Code:      code,
+			CreatedBy: createdBy,
+			MaxUses:   maxUses,
+			Enabled:   true,
+			Note:      note,
+			ExpiresAt: expiresAt,
+		})
+	}
+	if err = db.CreateInviteCodes(inviteCodes); err != nil {
+		return nil, errors.Wrap(err, "创建邀请码失败")
+	}
+	return inviteCodes, nil
+}
+
+// CreateUserInviteCode 普通用户创建一个单次使用的邀请码，数量受 invite_codes_per_user 限制
+func CreateUserInviteCode(userID uint) (*model.InviteCode, error) {
+	quota := getCreditsSettingInt(conf.InviteCodesPerUser, 0)
+	count, err := db.CountInviteCodesByCreator(userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "获取邀请码失败")
+	}
+	if count >= quota {
+		return nil, errs.InviteCodeQuota
+	}
+	codes, err := GenerateInviteCodes(userID, 1, 1, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	return &codes[0], nil
+}
+
+// ListInviteCodes 分页获取邀请码，createdBy 为 0 时返回全部
+func ListInviteCodes(createdBy uint, page, pageSize int) ([]model.InviteCode, int64, error) {
+	codes, total, err := db.GetInviteCodes(createdBy, page, pageSize)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "获取邀请码失败")
+	}
+	return codes, total, nil
+}
+
+// SetInviteCodeEnabled 启用或停用邀请码
+func SetInviteCodeEnabled(id uint, enabled bool) error {
+	if err := db.SetInviteCodeEnabled(id, enabled); err != nil {
+		return errors.Wrap(err, "更新邀请码失败")
+	}
+	return nil
+}
+
+// useInviteCode 校验并占用邀请码的一次使用次数，注册申请被拒绝或过期时不会退回
+func useInviteCode(code string) (*model.InviteCode, error) {
+	inviteCode, err := db.GetInviteCodeByCode(strings.ToUpper(strings.TrimSpace(code)))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.InvalidInviteCode
+		}
+		return nil, errors.Wrap(err, "获取邀请码失败")
+	}
+	if !inviteCode.CanUse() {
+		return nil, errs.InvalidInviteCode
+	}
+	ok, err := db.UseInviteCode(inviteCode.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "更新邀请码失败")
+	}
+	if !ok {
+		return nil, errs.InvalidInviteCode
+	}
+	return inviteCode, nil
+}
+
+// bindInvitation 注册通过后记录邀请关系，失败不影响注册，仅记录日志
+func bindInvitation(inviteeID uint, code string) {
+	if code == "" {
+		return
+	}
+	inviteCode, err := db.GetInviteCodeByCode(code)
+	if err == nil {
+		err = db.CreateInvitation(&model.Invitation{
+			InviteCodeID: inviteCode.ID,
+			InviterID:    inviteCode.CreatedBy,
+			InviteeID:    inviteeID,
+		})
+	}
+	if err != nil {
+		utils.Log.Errorf("failed to record invitation of user %d with %s: %+v", inviteeID, code, err)
+	}
+}
@@ -0,0 +1,51 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/pkg/errors"
+)
+
+func TestInviteOnlyRegistration(t *testing.T) {
+	inviter := &model.User{Username: "invite_inviter", Role: model.GENERAL}
+	if err := op.CreateUser(inviter); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if err := op.SaveSettingItem(&model.SettingItem{Key: conf.RegistrationMode, Value: model.RegistrationModeInviteOnly, Type: conf.TypeSelect}); err != nil {
+		t.Fatalf("failed to save setting: %+v", err)
+	}
+	defer op.SaveSettingItem(&model.SettingItem{Key: conf.RegistrationMode, Value: model.RegistrationModeApproval, Type: conf.TypeSelect})
+
+	if _, err := op.CreateUserInviteCode(inviter.ID); !errors.Is(err, errs.InviteCodeQuota) {
+		t.Errorf("expected users to be unable to create invite codes by default, got %v", err)
+	}
+	if err := op.SaveSettingItem(&model.SettingItem{Key: conf.InviteCodesPerUser, Value: "1", Type: conf.TypeNumber}); err != nil {
+		t.Fatalf("failed to save setting: %+v", err)
+	}
+	defer op.SaveSettingItem(&model.SettingItem{Key: conf.InviteCodesPerUser, Value: "0", Type: conf.TypeNumber})
+	code, err := op.CreateUserInviteCode(inviter.ID)
+	if err != nil {
+		t.Fatalf("failed to create invite code: %+v", err)
+	}
+
+	if _, err = op.CreateUserRegistration("invitee@example.com", "invitee", "password", "", "", "", ""); !errors.Is(err, errs.InviteCodeRequired) {
+		t.Errorf("expected an invite code to be required, got %v", err)
+	}
+	if _, err = op.CreateUserRegistration("invitee@example.com", "invitee", "password", "", "", "INVNOTEXISTING", ""); !errors.Is(err, errs.InvalidInviteCode) {
+		t.Errorf("expected an unknown invite code to be rejected, got %v", err)
+	}
+	registration, err := op.CreateUserRegistration("invitee@example.com", "invitee", "password", "", "", code.Code, "")
+	if err != nil {
+		t.Fatalf("failed to register with invite code: %+v", err)
+	}
+	if registration.InviteCode != code.Code {
+		t.Errorf("expected the registration to keep invite code %s, got %s", code.Code, registration.InviteCode)
+	}
+	if _, err = op.CreateUserRegistration("invitee2@example.com", "invitee2", "password", "", "", code.Code, ""); !errors.Is(err, errs.InvalidInviteCode) {
+		t.Errorf("expected a used up invite code to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,268 @@
+package op
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/mail"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// mailSendTimeout 单次发送邮件的超时时间
+const mailSendTimeout = time.Minute
+
+// getSettingStr 读取字符串设置项，不存在时返回空字符串
+func getSettingStr(key string) string {
+	item, err := GetSettingItemByKey(key)
+	if err != nil {
+		return ""
+	}
+	return item.Value
+}
+
+// mailConfig 从设置项读取 SMTP 服务器配置，未配置服务器时返回 false
+func mailConfig() (mail.Config, bool) {
+	cfg := mail.Config{
+		Host:       strings.TrimSpace(getSettingStr(conf.SmtpHost)),
+		Username:   getSettingStr(conf.SmtpUsername),
+		Password:   getSettingStr(conf.SmtpPassword),
+		Encryption: getSettingStr(conf.SmtpEncryption),
+		From:       mailSender(),
+	}
+	cfg.Port, _ = strconv.Atoi(getSettingStr(conf.SmtpPort))
+	return cfg, cfg.Host != ""
+}
+
+// mailSender 返回发件人地址，未设置时使用 SMTP 用户名
+func mailSender() string {
+	if from := getSettingStr(conf.SmtpFrom); from != "" {
+		return from
+	}
+	return getSettingStr(conf.SmtpUsername)
+}
+
+// mailProvider 按设置创建邮件服务，使用 SMTP 但未配置服务器时返回 nil
+func mailProvider() (mail.Provider, string, error) {
+	name := getSettingStr(conf.MailProvider)
+	if name == "" || name == mail.ProviderSMTP {
+		cfg, ok := mailConfig()
+		if !ok {
+			return nil, mail.ProviderSMTP, nil
+		}
+		return mail.NewSMTPProvider(cfg), mail.ProviderSMTP, nil
+	}
+	provider, err := mail.NewProvider(name, getSettingStr(conf.MailProviderConfig))
+	return provider, name, err
+}
+
+// siteURL 返回站点地址，用于邮件中的链接
+func siteURL() string {
+	return strings.TrimSuffix(conf.Conf.SiteURL, "/")
+}
+
+// SendMail 按模板渲染并发送邮件，模板数据会自动加入 SiteTitle 和 SiteURL。
+// 每封邮件都会记录投递状态，发送失败的邮件由 RetryMailDeliveries 重试。
+// 未配置邮件服务时不发送，只记录日志
+func SendMail(to, template string, data map[string]any) error {
+	if data == nil {
+		data = map[string]any{}
+	}
+	data["SiteTitle"] = getSettingStr(conf.SiteTitle)
+	data["SiteURL"] = siteURL()
+	msg, err := mail.Render(template, data)
+	if err != nil {
+		return err
+	}
+	provider, name, err := mailProvider()
+	if err != nil {
+		return errors.Wrap(err, "创建邮件服务失败")
+	}
+	if provider == nil {
+		utils.Log.Infof("mail is not configured, mail [%s] to %s is not sent", msg.Subject, to)
+		return nil
+	}
+	delivery := &model.MailDelivery{
+		Provider:  name,
+		Template:  template,
+		Sender:    mailSender(),
+		Recipient: to,
+		Subject:   msg.Subject,
+		Text:      msg.Text,
+		HTML:      msg.HTML,
+		Status:    model.MailPending,
+	}
+	if err = db.CreateMailDelivery(delivery); err != nil {
+		return errors.Wrap(err, "创建邮件发送记录失败")
+	}
+	return deliverMail(provider, delivery)
+}
+
+// deliverMail 发送一条邮件记录并更新投递状态，失败次数达到 mail_max_attempts 后进入死信列表，
+// 否则按指数退避安排下次重试
+func deliverMail(provider mail.Provider, delivery *model.MailDelivery) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mailSendTimeout)
+	defer cancel()
+	messageID, sendErr := provider.Send(ctx, mail.Message{
+		From:    delivery.Sender,
+		To:      delivery.Recipient,
+		Subject: delivery.Subject,
+		Text:    delivery.Text,
+		HTML:    delivery.HTML,
+	})
+	now := time.Now()
+	delivery.Attempts++
+	delivery.NextRetryAt = nil
+	if sendErr == nil {
+		delivery.Status = model.MailSent
+		delivery.MessageID = messageID
+		delivery.LastError = ""
+		delivery.SentAt = &now
+	} else {
+		delivery.LastError = sendErr.Error()
+		if delivery.Attempts >= int(getCreditsSettingInt(conf.MailMaxAttempts, 3)) {
+			delivery.Status = model.MailDead
+		} else {
+			delivery.Status = model.MailFailed
+			next := now.Add(time.Duration(1<<delivery.Attempts) * time.Minute)
+			delivery.NextRetryAt = &next
+		}
+	}
+	if err := db.UpdateMailDelivery(delivery); err != nil {
+		utils.Log.Errorf("failed to update mail delivery %d: %+v", delivery.ID, err)
+	}
+	if sendErr != nil {
+		return errors.Wrapf(sendErr, "发送邮件到 %s 失败", delivery.Recipient)
+	}
+	return nil
+}
+
+// RetryMailDeliveries 重试到达重试时间的发送失败邮件
+func RetryMailDeliveries() error {
+	deliveries, err := db.GetDueMailDeliveries(time.Now(), 100)
+	if err != nil {
+		return errors.Wrap(err, "获取待重试邮件失败")
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	provider, _, err := mailProvider()
+	if err != nil {
+		return errors.Wrap(err, "创建邮件服务失败")
+	}
+	if provider == nil {
+		return nil
+	}
+	for i := range deliveries {
+		logMailError(deliverMail(provider, &deliveries[i]))
+	}
+	return nil
+}
+
+// ListMailDeliveries 分页获取邮件发送记录，status 为 dead 时即死信列表
+func ListMailDeliveries(status string, page, pageSize int) ([]model.MailDelivery, int64, error) {
+	deliveries, total, err := db.GetMailDeliveries(status, page, pageSize)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "获取邮件发送记录失败")
+	}
+	return deliveries, total, nil
+}
+
+// ResendMailDelivery 立即重新发送一条未成功的邮件，使用当前设置的邮件服务。
+// 死信列表中的邮件重新发送时会重新计算重试次数
+func ResendMailDelivery(id uint) (*model.MailDelivery, error) {
+	delivery, err := db.GetMailDelivery(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("邮件发送记录不存在")
+		}
+		return nil, errors.Wrap(err, "获取邮件发送记录失败")
+	}
+	if delivery.Status == model.MailSent {
+		return nil, errors.New("邮件已发送成功")
+	}
+	provider, name, err := mailProvider()
+	if err != nil {
+		return nil, errors.Wrap(err, "创建邮件服务失败")
+	}
+	if provider == nil {
+		return nil, errors.New("未配置邮件服务")
+	}
+	delivery.Provider = name
+	if delivery.Status == model.MailDead {
+		delivery.Attempts = 0
+	}
+	return delivery, deliverMail(provider, delivery)
+}
+
+// logMailError 通知类邮件发送失败不影响主流程，仅记录日志
+func logMailError(err error) {
+	if err != nil {
+		utils.Log.Errorf("mail: %+v", err)
+	}
+}
+
+// userEmail 获取用户注册时使用的邮箱，没有注册记录时返回空字符串
+func userEmail(user *model.User) string {
+	registration, err := db.GetUserRegistrationByUsername(user.Username)
+	if err != nil {
+		return ""
+	}
+	return registration.Email
+}
+
+// getUserByEmail 根据邮箱查找用户：先查已完成注册的注册邮箱，再查外部身份中由提供方验证过的邮箱，
+// 外部身份邮箱对应多个用户时视为找不到
+func getUserByEmail(email string) (*model.User, error) {
+	email = normalizeEmail(email)
+	if email == "" {
+		return nil, errors.WithStack(gorm.ErrRecordNotFound)
+	}
+	registration, err := db.GetUserRegistrationByEmail(email)
+	if err == nil && registration.Status == model.RegistrationRegistered {
+		return GetUserByName(registration.Username)
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	userIDs, err := db.GetIdentityUserIDsByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if len(userIDs) != 1 {
+		return nil, errors.WithStack(gorm.ErrRecordNotFound)
+	}
+	return GetUserById(userIDs[0])
+}
+
+// sendPaymentReceipt 向用户发送支付订单的收据邮件
+func sendPaymentReceipt(order *model.PaymentOrder) error {
+	user, err := GetUserById(order.UserID)
+	if err != nil {
+		return errors.Wrap(err, "获取用户失败")
+	}
+	email := userEmail(user)
+	if email == "" {
+		return nil
+	}
+	subject := strconv.FormatInt(order.Credits, 10) + " credits"
+	if order.PlanID != 0 {
+		if plan, err := db.GetSubscriptionPlanByID(order.PlanID); err == nil {
+			subject = plan.Name
+		}
+	}
+	return SendMail(email, mail.TemplatePaymentReceipt, map[string]any{
+		"Username": user.Username,
+		"OrderNo":  order.OrderNo,
+		"Subject":  subject,
+		"Amount":   order.MajorString(),
+		"Currency": order.Currency,
+		"PaidAt":   *order.PaidAt,
+	})
+}
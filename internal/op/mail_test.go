@@ -0,0 +1,64 @@
+package op_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/mail"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestMailDelivery(t *testing.T) {
+	fail := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"msg-1"}`))
+	}))
+	defer server.Close()
+
+	settings := []model.SettingItem{
+		{Key: conf.MailProvider, Value: "webhook", Type: conf.TypeSelect, Group: model.MAIL, Flag: model.PRIVATE},
+		{Key: conf.MailProviderConfig, Value: `{"url":"` + server.URL + `"}`, Type: conf.TypeText, Group: model.MAIL, Flag: model.PRIVATE},
+		{Key: conf.SmtpFrom, Value: "noreply@example.com", Type: conf.TypeString, Group: model.MAIL, Flag: model.PRIVATE},
+		{Key: conf.MailMaxAttempts, Value: "2", Type: conf.TypeNumber, Group: model.MAIL, Flag: model.PRIVATE},
+	}
+	for _, item := range settings {
+		if err := op.SaveSettingItem(&item); err != nil {
+			t.Fatalf("failed to save setting: %+v", err)
+		}
+	}
+	defer func() {
+		for _, item := range settings {
+			item.Value = ""
+			_ = op.SaveSettingItem(&item)
+		}
+	}()
+
+	data := map[string]any{"Username": "mail_user", "Reason": ""}
+	if err := op.SendMail("mail_user@example.com", mail.TemplateRegistrationRejected, data); err == nil {
+		t.Fatalf("expected the first attempt to fail")
+	}
+	deliveries, _, err := op.ListMailDeliveries(model.MailFailed, 1, 20)
+	if err != nil || len(deliveries) != 1 || deliveries[0].Attempts != 1 || deliveries[0].NextRetryAt == nil {
+		t.Fatalf("expected one failed delivery waiting for retry, got %+v, %v", deliveries, err)
+	}
+
+	delivery, err := op.ResendMailDelivery(deliveries[0].ID)
+	if err == nil || delivery.Status != model.MailDead {
+		t.Fatalf("expected the delivery to be dead after max attempts, got %+v, %v", delivery, err)
+	}
+
+	fail = false
+	if delivery, err = op.ResendMailDelivery(delivery.ID); err != nil {
+		t.Fatalf("failed to resend dead delivery: %+v", err)
+	}
+	if delivery.Status != model.MailSent || delivery.MessageID != "msg-1" || delivery.SentAt == nil {
+		t.Errorf("expected the delivery to be sent, got %+v", delivery)
+	}
+}
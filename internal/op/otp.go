@@ -0,0 +1,50 @@
+package op
+
+import (
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
+	"github.com/pquerna/otp/totp"
+)
+
+const otpBackupCodeCount = 10
+
+func hashOtpBackupCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	return utils.HashData(utils.SHA256, []byte(code))
+}
+
+// GenerateOtpBackupCodes replaces the backup codes of a user. The codes are
+// only returned here, just their hashes are stored.
+func GenerateOtpBackupCodes(u *model.User) ([]string, error) {
+	codes := make([]string, 0, otpBackupCodeCount)
+	hashes := make([]string, 0, otpBackupCodeCount)
+	for i := 0; i < otpBackupCodeCount; i++ {
+		code := strings.ToLower(random.String(5) + "-" + random.String(5))
+		codes = append(codes, code)
+		hashes = append(hashes, hashOtpBackupCode(code))
+	}
+	if err := db.ReplaceOtpBackupCodes(u.ID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// ValidateOtp checks a TOTP code of a user, falling back to consuming one of
+// the backup codes
+func ValidateOtp(u *model.User, code string) (bool, error) {
+	if u.OtpSecret == "" || code == "" {
+		return false, nil
+	}
+	if totp.Validate(code, u.OtpSecret) {
+		return true, nil
+	}
+	return db.UseOtpBackupCode(u.ID, hashOtpBackupCode(code))
+}
+
+func CountOtpBackupCodes(u *model.User) (int64, error) {
+	return db.CountOtpBackupCodes(u.ID)
+}
@@ -0,0 +1,42 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/pquerna/otp/totp"
+)
+
+func TestOtpBackupCodes(t *testing.T) {
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: "OpenList", AccountName: "otp_user"})
+	if err != nil {
+		t.Fatalf("failed to generate secret: %+v", err)
+	}
+	user := &model.User{Username: "otp_user", Password: "password", OtpSecret: key.Secret()}
+	if err = op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	codes, err := op.GenerateOtpBackupCodes(user)
+	if err != nil {
+		t.Fatalf("failed to generate backup codes: %+v", err)
+	}
+	if ok, err := op.ValidateOtp(user, codes[0]); err != nil || !ok {
+		t.Fatalf("expected the backup code to be accepted, got %v, %v", ok, err)
+	}
+	if ok, _ := op.ValidateOtp(user, codes[0]); ok {
+		t.Errorf("expected a backup code to be usable once")
+	}
+	if ok, _ := op.ValidateOtp(user, "wrong-code"); ok {
+		t.Errorf("expected an unknown code to be rejected")
+	}
+	if count, _ := op.CountOtpBackupCodes(user); count != int64(len(codes)-1) {
+		t.Errorf("expected %d unused backup codes, got %d", len(codes)-1, count)
+	}
+	if err = op.Cancel2FAByUser(user); err != nil {
+		t.Fatalf("failed to cancel 2fa: %+v", err)
+	}
+	if count, _ := op.CountOtpBackupCodes(user); count != 0 {
+		t.Errorf("expected backup codes to be dropped with 2fa, got %d", count)
+	}
+}
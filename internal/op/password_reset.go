@@ -0,0 +1,102 @@
+package op
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/mail"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/go-cache"
+	"github.com/pkg/errors"
+)
+
+const (
+	passwordResetInterval    = time.Minute      // 同一邮箱两次请求验证码的最短间隔
+	passwordResetTokenExpire = 15 * time.Minute // 验证通过后设置新密码的期限
+)
+
+var (
+	passwordResetRequests = cache.NewMemCache[time.Time]()
+	// 验证通过后签发的重置令牌，值为用户名
+	passwordResetTokens = cache.NewMemCache[string]()
+)
+
+// RequestPasswordReset 向用户的邮箱发送重置密码的验证码；邮箱未对应任何用户时静默返回，
+// 避免暴露账户是否存在
+func RequestPasswordReset(email string) error {
+	email = normalizeEmail(email)
+	if last, ok := passwordResetRequests.Get(email); ok && time.Since(last) < passwordResetInterval {
+		return errs.PasswordResetLimited
+	}
+	passwordResetRequests.Set(email, time.Now(), cache.WithEx[time.Time](passwordResetInterval))
+
+	user := passwordResetUser(email)
+	if user == nil {
+		return nil
+	}
+	code, err := CreateVerificationCode(email, model.VerificationCodeResetPassword)
+	if err != nil {
+		return err
+	}
+	return SendMail(email, mail.TemplatePasswordReset, map[string]any{
+		"Username":  user.Username,
+		"Code":      code.Code,
+		"ExpiresAt": code.ExpiresAt,
+	})
+}
+
+// VerifyPasswordResetCode 校验重置密码的验证码，通过后返回用于设置新密码的一次性令牌；
+// 错误次数达到 verification_code_max_attempts 时验证码锁定并返回 errs.VerificationCodeLocked，需要重新获取
+func VerifyPasswordResetCode(email, code string) (string, error) {
+	email = normalizeEmail(email)
+	verificationCode, err := findVerificationCode(email, model.VerificationCodeResetPassword, code)
+	if errors.Is(err, errs.VerificationCodeLocked) {
+		return "", err
+	}
+	if err != nil {
+		return "", errs.InvalidResetCode
+	}
+	verificationCode.Used = true
+	if err = db.UpdateVerificationCode(verificationCode); err != nil {
+		return "", errors.Wrap(err, "更新验证码状态失败")
+	}
+
+	user := passwordResetUser(email)
+	if user == nil {
+		return "", errs.InvalidResetCode
+	}
+	token, err := generateToken(32)
+	if err != nil {
+		return "", errors.Wrap(err, "生成重置令牌失败")
+	}
+	passwordResetTokens.Set(token, user.Username, cache.WithEx[string](passwordResetTokenExpire))
+	return token, nil
+}
+
+// ResetPassword 使用重置令牌设置新密码；密码时间戳随之更新，已签发的登录令牌全部失效
+func ResetPassword(token, password string) error {
+	username, ok := passwordResetTokens.Get(token)
+	if !ok {
+		return errs.InvalidResetToken
+	}
+	user, err := GetUserByName(username)
+	if err != nil {
+		return errors.Wrap(err, "获取用户失败")
+	}
+	user.SetPassword(password)
+	if err = UpdateUser(user); err != nil {
+		return errors.Wrap(err, "更新密码失败")
+	}
+	passwordResetTokens.Del(token)
+	return nil
+}
+
+// passwordResetUser 根据邮箱查找可以重置密码的用户，找不到时返回 nil
+func passwordResetUser(email string) *model.User {
+	user, err := getUserByEmail(email)
+	if err != nil || user.Disabled || user.IsGuest() {
+		return nil
+	}
+	return user
+}
@@ -0,0 +1,89 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/pkg/errors"
+)
+
+func TestPasswordReset(t *testing.T) {
+	user := &model.User{Username: "reset_user", Password: "password"}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if err := op.RecordSSORegistration(user, "reset@example.com"); err != nil {
+		t.Fatalf("failed to record registration: %+v", err)
+	}
+	user, _ = op.GetUserByName("reset_user")
+	oldPwdTS := user.PwdTS
+
+	// sending the mail fails without a provider, the code is created anyway
+	_ = op.RequestPasswordReset("reset@example.com")
+	if err := op.RequestPasswordReset("reset@example.com"); !errors.Is(err, errs.PasswordResetLimited) {
+		t.Errorf("expected a second request to be rate limited, got %v", err)
+	}
+	if err := op.RequestPasswordReset("nobody@example.com"); err != nil {
+		t.Errorf("expected unknown emails to be ignored silently, got %v", err)
+	}
+
+	code, err := db.GetVerificationCode("reset@example.com", model.VerificationCodeResetPassword)
+	if err != nil {
+		t.Fatalf("expected a reset code: %+v", err)
+	}
+	if _, err = op.VerifyPasswordResetCode("reset@example.com", "wrong"); !errors.Is(err, errs.InvalidResetCode) {
+		t.Errorf("expected a wrong code to be rejected, got %v", err)
+	}
+	token, err := op.VerifyPasswordResetCode("reset@example.com", code.Code)
+	if err != nil {
+		t.Fatalf("failed to verify code: %+v", err)
+	}
+	if _, err = op.VerifyPasswordResetCode("reset@example.com", code.Code); err == nil {
+		t.Errorf("expected the code to be usable once")
+	}
+
+	if err = op.ResetPassword(token, "new-password"); err != nil {
+		t.Fatalf("failed to reset password: %+v", err)
+	}
+	if err = op.ResetPassword(token, "other-password"); !errors.Is(err, errs.InvalidResetToken) {
+		t.Errorf("expected the token to be usable once, got %v", err)
+	}
+	user, _ = op.GetUserByName("reset_user")
+	if err = user.ValidateRawPassword("new-password"); err != nil {
+		t.Errorf("expected the new password to be set: %v", err)
+	}
+	if user.PwdTS == oldPwdTS {
+		t.Errorf("expected existing sessions to be invalidated")
+	}
+}
+
+func TestPasswordResetByIdentityEmail(t *testing.T) {
+	user := &model.User{Username: "reset_identity_user", Password: "password"}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if _, err := op.LinkUserIdentity(user, "github", "reset-identity", "Reset-Identity@example.com"); err != nil {
+		t.Fatalf("failed to link identity: %+v", err)
+	}
+
+	_ = op.RequestPasswordReset("reset-identity@example.com")
+	code, err := db.GetVerificationCode("reset-identity@example.com", model.VerificationCodeResetPassword)
+	if err != nil {
+		t.Fatalf("expected a reset code for the identity email: %+v", err)
+	}
+	// wrong codes share the lockout of the other verification flows
+	for i := 0; i < 4; i++ {
+		if _, err = op.VerifyPasswordResetCode("reset-identity@example.com", "wrong"); !errors.Is(err, errs.InvalidResetCode) {
+			t.Fatalf("expected a wrong code to be rejected, got %v", err)
+		}
+	}
+	if _, err = op.VerifyPasswordResetCode("reset-identity@example.com", "wrong"); !errors.Is(err, errs.VerificationCodeLocked) {
+		t.Errorf("expected the codes to be locked, got %v", err)
+	}
+	if _, err = op.VerifyPasswordResetCode("reset-identity@example.com", code.Code); err == nil {
+		t.Errorf("expected a locked code to be rejected")
+	}
+}
@@ -0,0 +1,74 @@
+package op
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// ListPricingGroups 获取定价组列表
+func ListPricingGroups() ([]model.PricingGroup, error) {
+	groups, err := db.GetPricingGroups()
+	if err != nil {
+		return nil, errors.Wrap(err, "获取定价组失败")
+	}
+	return groups, nil
+}
+
+// SavePricingGroup 创建或更新定价组
+func SavePricingGroup(group *model.PricingGroup) error {
+	if group.Multiplier < 0 {
+		return errors.New("价格倍率不能为负数")
+	}
+	if group.FreeDailyDownloads < 0 || group.FreeDailyGB < 0 {
+		return errors.New("每日免费额度不能为负数")
+	}
+	if err := db.SavePricingGroup(group); err != nil {
+		return errors.Wrap(err, "保存定价组失败")
+	}
+	return nil
+}
+
+// DeletePricingGroup 删除定价组，组内用户恢复为默认价格
+func DeletePricingGroup(id uint) error {
+	if err := db.DeletePricingGroup(id); err != nil {
+		return errors.Wrap(err, "删除定价组失败")
+	}
+	return nil
+}
+
+// SetUserPricingGroup 设置用户所属的定价组，groupID 为 0 时移出定价组
+func SetUserPricingGroup(userID, groupID uint) error {
+	if _, err := GetUserById(userID); err != nil {
+		return errors.WithMessage(err, "用户不存在")
+	}
+	if groupID == 0 {
+		if err := db.RemoveUserPricingGroup(userID); err != nil {
+			return errors.Wrap(err, "移出定价组失败")
+		}
+		return nil
+	}
+	if _, err := db.GetPricingGroupByID(groupID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("定价组不存在")
+		}
+		return errors.Wrap(err, "获取定价组失败")
+	}
+	if err := db.SetUserPricingGroup(userID, groupID); err != nil {
+		return errors.Wrap(err, "设置定价组失败")
+	}
+	return nil
+}
+
+// ApplyPricingGroup 按用户所属定价组计算实际所需积分，不属于任何组时价格不变
+func ApplyPricingGroup(userID uint, credits int64) (int64, error) {
+	group, err := db.GetUserPricingGroup(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return credits, nil
+		}
+		return credits, errors.Wrap(err, "获取用户定价组失败")
+	}
+	return group.Apply(credits), nil
+}
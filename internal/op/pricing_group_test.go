@@ -0,0 +1,109 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestPricingGroup(t *testing.T) {
+	member := &model.User{Username: "member", Role: model.GENERAL}
+	partner := &model.User{Username: "partner", Role: model.GENERAL}
+	for _, u := range []*model.User{member, partner} {
+		if err := op.CreateUser(u); err != nil {
+			t.Fatalf("failed to create user: %+v", err)
+		}
+	}
+	if err := op.SetFileCreditsConfig("/priced/file.zip", 15, 0, model.PricingFlat, false, 1); err != nil {
+		t.Fatalf("failed to set file config: %+v", err)
+	}
+	groups := []*model.PricingGroup{
+		{Name: "member", Multiplier: 0.5},
+		{Name: "partner", Exempt: true},
+	}
+	for i, g := range groups {
+		if err := op.SavePricingGroup(g); err != nil {
+			t.Fatalf("failed to save group: %+v", err)
+		}
+		if err := op.SetUserPricingGroup([]*model.User{member, partner}[i].ID, g.ID); err != nil {
+			t.Fatalf("failed to assign group: %+v", err)
+		}
+	}
+	for _, c := range []struct {
+		user *model.User
+		want int64
+	}{{member, 8}, {partner, 0}} {
+		_, required, err := op.CheckFileDownloadPermission(c.user.ID, "/priced/file.zip")
+		if err != nil {
+			t.Fatalf("failed to check permission: %+v", err)
+		}
+		if required != c.want {
+			t.Errorf("%s: required %d, want %d", c.user.Username, required, c.want)
+		}
+	}
+}
+
+func TestFileCreditsExemptions(t *testing.T) {
+	owner := &model.User{Username: "exempt_owner", Role: model.GENERAL}
+	colleague := &model.User{Username: "exempt_colleague", Role: model.GENERAL}
+	stranger := &model.User{Username: "exempt_stranger", Role: model.GENERAL}
+	for _, u := range []*model.User{owner, colleague, stranger} {
+		if err := op.CreateUser(u); err != nil {
+			t.Fatalf("failed to create user: %+v", err)
+		}
+	}
+	if err := op.SetFileCreditsConfig("/exempt/docs", 5, 0, model.PricingFlat, true, 1); err != nil {
+		t.Fatalf("failed to set folder config: %+v", err)
+	}
+	config, err := op.FindFileCreditsConfig("/exempt/docs")
+	if err != nil {
+		t.Fatalf("failed to get config: %+v", err)
+	}
+	group := &model.PricingGroup{Name: "collaborators", Multiplier: 1}
+	if err = op.SavePricingGroup(group); err != nil {
+		t.Fatalf("failed to save group: %+v", err)
+	}
+	if err = op.SetUserPricingGroup(colleague.ID, group.ID); err != nil {
+		t.Fatalf("failed to assign group: %+v", err)
+	}
+	if err = op.SetFileCreditsExemptions(config.ID, []uint{owner.ID}, []uint{group.ID}); err != nil {
+		t.Fatalf("failed to set exemptions: %+v", err)
+	}
+	for _, c := range []struct {
+		user *model.User
+		want int64
+	}{{owner, 0}, {colleague, 0}, {stranger, 5}} {
+		_, required, err := op.CheckFileDownloadPermission(c.user.ID, "/exempt/docs/report.pdf")
+		if err != nil {
+			t.Fatalf("failed to check permission: %+v", err)
+		}
+		if required != c.want {
+			t.Errorf("%s: required %d, want %d", c.user.Username, required, c.want)
+		}
+	}
+}
+
+func TestPromotion(t *testing.T) {
+	user := &model.User{Username: "promotion_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if err := op.SetFileCreditsConfig("/promo/file.zip", 10, 0, model.PricingFlat, false, 1); err != nil {
+		t.Fatalf("failed to set file config: %+v", err)
+	}
+	promotion := &model.Promotion{Name: "weekend", Paths: "/promo", Discount: 40, Enabled: true}
+	if err := op.SavePromotion(promotion); err != nil {
+		t.Fatalf("failed to save promotion: %+v", err)
+	}
+	_, required, err := op.CheckFileDownloadPermission(user.ID, "/promo/file.zip")
+	if err != nil || required != 6 {
+		t.Fatalf("expected discounted price 6, got %d, %+v", required, err)
+	}
+	if err = op.DeletePromotion(promotion.ID); err != nil {
+		t.Fatalf("failed to delete promotion: %+v", err)
+	}
+	if _, required, _ = op.CheckFileDownloadPermission(user.ID, "/promo/file.zip"); required != 10 {
+		t.Errorf("expected full price after promotion is deleted, got %d", required)
+	}
+}
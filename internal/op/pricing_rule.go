@@ -0,0 +1,155 @@
+package op
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/singleflight"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/go-cache"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const pricingRulesCacheKey = "pricing_rules"
+
+var pricingRuleCache = cache.NewMemCache(cache.WithShards[[]model.PricingRule](1))
+var pricingRuleG singleflight.Group[[]model.PricingRule]
+
+// ListPricingRules 获取全部路径计价规则
+func ListPricingRules() ([]model.PricingRule, error) {
+	rules, err := db.GetPricingRules(false)
+	if err != nil {
+		return nil, errors.Wrap(err, "获取计价规则失败")
+	}
+	return rules, nil
+}
+
+// SavePricingRule 创建或更新路径计价规则
+func SavePricingRule(rule *model.PricingRule) error {
+	if rule.MatchType == "" {
+		rule.MatchType = model.PricingRuleGlob
+	}
+	if rule.PricingMode == "" {
+		rule.PricingMode = model.PricingFlat
+	}
+	if rule.Credits < 0 {
+		return errors.New("积分数量不能为负数")
+	}
+	if err := rule.Compile(); err != nil {
+		return errors.WithMessage(err, "无效的路径模式")
+	}
+	if err := db.SavePricingRule(rule); err != nil {
+		return errors.Wrap(err, "保存计价规则失败")
+	}
+	pricingRuleCache.Del(pricingRulesCacheKey)
+	return nil
+}
+
+// DeletePricingRule 删除路径计价规则
+func DeletePricingRule(id uint) error {
+	if err := db.DeletePricingRule(id); err != nil {
+		return errors.Wrap(err, "删除计价规则失败")
+	}
+	pricingRuleCache.Del(pricingRulesCacheKey)
+	return nil
+}
+
+// getEnabledPricingRules 获取已启用且编译好的计价规则
+func getEnabledPricingRules() ([]model.PricingRule, error) {
+	if rules, ok := pricingRuleCache.Get(pricingRulesCacheKey); ok {
+		return rules, nil
+	}
+	rules, err, _ := pricingRuleG.Do(pricingRulesCacheKey, func() ([]model.PricingRule, error) {
+		rules, err := db.GetPricingRules(true)
+		if err != nil {
+			return nil, errors.Wrap(err, "获取计价规则失败")
+		}
+		compiled := rules[:0]
+		for _, rule := range rules {
+			if err := rule.Compile(); err != nil {
+				utils.Log.Warnf("skip invalid pricing rule %d: %+v", rule.ID, err)
+				continue
+			}
+			compiled = append(compiled, rule)
+		}
+		pricingRuleCache.Set(pricingRulesCacheKey, compiled, cache.WithEx[[]model.PricingRule](time.Hour))
+		return compiled, nil
+	})
+	return rules, err
+}
+
+// MatchPricingRule 按优先级查找第一条匹配路径的计价规则，没有匹配时返回 nil
+func MatchPricingRule(path string) (*model.PricingRule, error) {
+	rules, err := getEnabledPricingRules()
+	if err != nil {
+		return nil, err
+	}
+	for i := range rules {
+		if rules[i].Match(path) {
+			return &rules[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// ResolveFileCreditsConfig 获取文件实际生效的积分配置，依次匹配精确路径配置、路径计价规则和父目录继承配置，
+// 命中计价规则时同时返回该规则
+func ResolveFileCreditsConfig(path string) (*model.FileCreditsConfig, *model.PricingRule, error) {
+	config, err := db.GetFileCreditsConfigByPath(path)
+	if err == nil {
+		return config, nil, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, errors.Wrap(err, "获取文件积分配置失败")
+	}
+	rule, err := MatchPricingRule(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rule != nil {
+		return rule.ToConfig(), rule, nil
+	}
+	config, err = getInheritableCreditsConfig(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return config, nil, nil
+}
+
+// DryRunFilePricing 计算文件按当前配置和规则的基础价格，不考虑用户的会员、定价组和免费额度；
+// size 小于0时按需获取实际文件大小
+func DryRunFilePricing(path string, size int64) (*model.PricingDryRun, error) {
+	result := &model.PricingDryRun{Path: path, Source: model.PricingSourceNone, Size: size}
+	config, rule, err := ResolveFileCreditsConfig(path)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return result, nil
+		}
+		return nil, err
+	}
+	switch {
+	case rule != nil:
+		result.Source = model.PricingSourceRule
+		result.RuleID = rule.ID
+	case config.Path == path:
+		result.Source = model.PricingSourceConfig
+		result.ConfigID = config.ID
+	default:
+		result.Source = model.PricingSourceInherit
+		result.ConfigID = config.ID
+	}
+	result.Pattern = config.Path
+	result.PricingMode = config.PricingMode
+	result.Credits = config.Credits
+	if config.IsSizeBased() && size < 0 {
+		if size, err = getFileSize(path); err != nil {
+			return nil, errors.WithMessage(err, "获取文件大小失败")
+		}
+		result.Size = size
+	}
+	result.Cost = config.CostFor(model.CreditsActionDownload, size)
+	result.PreviewCost = config.CostFor(model.CreditsActionPreview, size)
+	return result, nil
+}
@@ -0,0 +1,47 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestPricingRules(t *testing.T) {
+	rules := []*model.PricingRule{
+		{Pattern: "/rules/**/*.mkv", Priority: 10, Credits: 50, Enabled: true},
+		{Pattern: "/rules/hd/**", Priority: 5, Credits: 80, Enabled: true},
+		{Pattern: `^/rules/.*\.iso$`, MatchType: model.PricingRuleRegex, Credits: 2, PricingMode: model.PricingPerGB, Enabled: true},
+		{Pattern: "/rules/**", Priority: 100, Credits: 1, Enabled: false},
+	}
+	for _, r := range rules {
+		if err := op.SavePricingRule(r); err != nil {
+			t.Fatalf("failed to save rule: %+v", err)
+		}
+	}
+	if err := op.SetFileCreditsConfig("/rules/hd/exact.mkv", 7, 0, model.PricingFlat, false, 1); err != nil {
+		t.Fatalf("failed to set file config: %+v", err)
+	}
+	for _, c := range []struct {
+		path   string
+		size   int64
+		source string
+		cost   int64
+	}{
+		{"/rules/a/b.mkv", 0, model.PricingSourceRule, 50},
+		{"/rules/hd/b.mkv", 0, model.PricingSourceRule, 80},
+		{"/rules/hd/exact.mkv", 0, model.PricingSourceConfig, 7},
+		{"/rules/x.iso", 3 << 30, model.PricingSourceRule, 6},
+	} {
+		result, err := op.DryRunFilePricing(c.path, c.size)
+		if err != nil {
+			t.Fatalf("failed to dry run %s: %+v", c.path, err)
+		}
+		if result.Source != c.source || result.Cost != c.cost {
+			t.Errorf("%s: got source %s cost %d, want %s %d", c.path, result.Source, result.Cost, c.source, c.cost)
+		}
+	}
+	if err := op.SavePricingRule(&model.PricingRule{Pattern: "[", MatchType: model.PricingRuleRegex}); err == nil {
+		t.Errorf("expected invalid regex to be rejected")
+	}
+}
@@ -0,0 +1,162 @@
+package op
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/OpenListTeam/OpenList/v4/cmd/flags"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+const (
+	avatarDirName    = "avatars" // 数据目录下存放上传头像的目录
+	MaxAvatarSize    = 1 << 20   // 上传头像的最大字节数
+	maxNicknameRunes = 64
+	maxBioRunes      = 500
+)
+
+// avatarTypes 允许上传的头像格式
+var avatarTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+// CheckProfile 校验用户资料，头像只能是 http(s) 地址或留空使用 gravatar
+func CheckProfile(nickname, avatar, bio string) error {
+	if utf8.RuneCountInString(nickname) > maxNicknameRunes {
+		return errors.Errorf("昵称不能超过%d个字符", maxNicknameRunes)
+	}
+	if utf8.RuneCountInString(bio) > maxBioRunes {
+		return errors.Errorf("个人简介不能超过%d个字符", maxBioRunes)
+	}
+	if avatar != "" {
+		u, err := url.Parse(avatar)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("头像必须是 http 或 https 地址")
+		}
+	}
+	return nil
+}
+
+// GetUserProfile 获取用户资料
+func GetUserProfile(user *model.User) *model.UserProfile {
+	return &model.UserProfile{
+		Nickname:  user.Nickname,
+		Avatar:    user.Avatar,
+		Bio:       user.Bio,
+		AvatarURL: avatarURL(user.Avatar, userEmail(user)),
+	}
+}
+
+// UpdateUserProfile 更新用户资料，avatar 为空时改用 gravatar 并删除上传的头像
+func UpdateUserProfile(user *model.User, nickname, avatar, bio string) error {
+	nickname, avatar, bio = strings.TrimSpace(nickname), strings.TrimSpace(avatar), strings.TrimSpace(bio)
+	check := avatar
+	if avatar == user.Avatar && isUploadedAvatar(avatar) {
+		// 保留已上传的头像
+		check = ""
+	}
+	if err := CheckProfile(nickname, check, bio); err != nil {
+		return err
+	}
+	if isUploadedAvatar(user.Avatar) && avatar != user.Avatar {
+		removeUserAvatar(user.ID)
+	}
+	user.Nickname, user.Avatar, user.Bio = nickname, avatar, bio
+	if err := UpdateUser(user); err != nil {
+		return errors.Wrap(err, "更新用户资料失败")
+	}
+	return nil
+}
+
+// SaveUserAvatar 保存用户上传的头像，只接受 png、jpeg、gif 和 webp 图片
+func SaveUserAvatar(user *model.User, data []byte) error {
+	if len(data) == 0 || len(data) > MaxAvatarSize {
+		return errors.Errorf("头像大小不能超过%dKB", MaxAvatarSize>>10)
+	}
+	contentType := http.DetectContentType(data)
+	supported := false
+	for _, t := range avatarTypes {
+		if contentType == t {
+			supported = true
+		}
+	}
+	if !supported {
+		return errors.New("头像只支持 png、jpeg、gif 和 webp 格式")
+	}
+	path := avatarPath(user.ID)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errors.Wrap(err, "创建头像目录失败")
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return errors.Wrap(err, "保存头像失败")
+	}
+	// 地址带上时间戳，更换头像后浏览器不会使用缓存
+	user.Avatar = fmt.Sprintf("/api/avatar/%d?t=%d", user.ID, time.Now().Unix())
+	if err := UpdateUser(user); err != nil {
+		return errors.Wrap(err, "更新用户资料失败")
+	}
+	return nil
+}
+
+// GetUserAvatarFile 获取用户上传的头像文件路径
+func GetUserAvatarFile(userID uint) (string, error) {
+	path := avatarPath(userID)
+	if _, err := os.Stat(path); err != nil {
+		return "", errors.New("头像不存在")
+	}
+	return path, nil
+}
+
+// applyRegistrationProfile 将注册时填写的资料写入新用户
+func applyRegistrationProfile(registration *model.UserRegistration, user *model.User) {
+	user.Nickname, user.Avatar, user.Bio = registration.Nickname, registration.Avatar, registration.Bio
+}
+
+// SaveRegistrationProfile 保存注册时填写的资料，资料需先经 CheckProfile 校验
+func SaveRegistrationProfile(registration *model.UserRegistration, nickname, avatar, bio string) error {
+	registration.Nickname, registration.Avatar, registration.Bio = strings.TrimSpace(nickname), strings.TrimSpace(avatar), strings.TrimSpace(bio)
+	if registration.Nickname == "" && registration.Avatar == "" && registration.Bio == "" {
+		return nil
+	}
+	if err := db.UpdateUserRegistration(registration); err != nil {
+		return errors.Wrap(err, "保存注册资料失败")
+	}
+	return nil
+}
+
+// avatarURL 返回显示的头像地址，未设置头像时根据邮箱生成 gravatar 地址，gravatar_url 为空时不使用 gravatar
+func avatarURL(avatar, email string) string {
+	if avatar != "" {
+		return avatar
+	}
+	base := getSettingStr(conf.GravatarURL)
+	if base == "" || email == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(normalizeEmail(email)))
+	return strings.TrimSuffix(base, "/") + "/" + hex.EncodeToString(sum[:]) + "?d=identicon"
+}
+
+func isUploadedAvatar(avatar string) bool {
+	return strings.HasPrefix(avatar, "/api/avatar/")
+}
+
+func removeUserAvatar(userID uint) {
+	if err := os.Remove(avatarPath(userID)); err != nil && !os.IsNotExist(err) {
+		utils.Log.Errorf("failed to remove the avatar of user %d: %+v", userID, err)
+	}
+}
+
+func avatarPath(userID uint) string {
+	return filepath.Join(flags.DataDir, avatarDirName, fmt.Sprintf("%d", userID))
+}
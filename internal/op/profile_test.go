@@ -0,0 +1,66 @@
+package op_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/cmd/flags"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestUserProfile(t *testing.T) {
+	dataDir := flags.DataDir
+	flags.DataDir = t.TempDir()
+	defer func() { flags.DataDir = dataDir }()
+	setRegistrationMode(t, model.RegistrationModeApproval)
+	if err := op.SaveSettingItem(&model.SettingItem{Key: conf.GravatarURL, Value: "https://www.gravatar.com/avatar/", Type: conf.TypeString}); err != nil {
+		t.Fatalf("failed to save setting: %+v", err)
+	}
+
+	if err := op.CheckProfile("nick", "javascript:alert(1)", ""); err == nil {
+		t.Errorf("expected a non http avatar to be rejected")
+	}
+	registration, err := op.CreateUserRegistration("profile@example.com", "profile_user", "password", "", "", "", "")
+	if err != nil {
+		t.Fatalf("failed to register: %+v", err)
+	}
+	if err = op.SaveRegistrationProfile(registration, " Nick ", "", "hello"); err != nil {
+		t.Fatalf("failed to save profile: %+v", err)
+	}
+	if _, err = op.VerifyUserRegistration(registration.Token); err != nil {
+		t.Fatalf("failed to verify registration: %+v", err)
+	}
+	user, err := op.ApproveUserRegistration(registration.ID, 1, false)
+	if err != nil {
+		t.Fatalf("failed to approve registration: %+v", err)
+	}
+	profile := op.GetUserProfile(user)
+	if profile.Nickname != "Nick" || profile.Bio != "hello" {
+		t.Errorf("expected the registration profile to be kept, got %+v", profile)
+	}
+	if !strings.Contains(profile.AvatarURL, "gravatar.com/avatar/") {
+		t.Errorf("expected a gravatar url, got %s", profile.AvatarURL)
+	}
+
+	png := []byte("\x89PNG\r\n\x1a\n0000000000000000")
+	if err = op.SaveUserAvatar(user, png); err != nil {
+		t.Fatalf("failed to save avatar: %+v", err)
+	}
+	if _, err = op.GetUserAvatarFile(user.ID); err != nil {
+		t.Errorf("expected the avatar file to exist: %v", err)
+	}
+	if err = op.SaveUserAvatar(user, []byte("not an image")); err == nil {
+		t.Errorf("expected a non image avatar to be rejected")
+	}
+	if err = op.UpdateUserProfile(user, "Nick", user.Avatar, "bio"); err != nil {
+		t.Errorf("expected the uploaded avatar to be kept, got %v", err)
+	}
+	if err = op.UpdateUserProfile(user, "Nick", "", "bio"); err != nil {
+		t.Fatalf("failed to update profile: %+v", err)
+	}
+	if _, err = op.GetUserAvatarFile(user.ID); err == nil {
+		t.Errorf("expected the uploaded avatar to be removed")
+	}
+}
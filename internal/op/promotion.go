@@ -0,0 +1,90 @@
+package op
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/singleflight"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/go-cache"
+	"github.com/pkg/errors"
+)
+
+const promotionsCacheKey = "promotions"
+
+var promotionCache = cache.NewMemCache(cache.WithShards[[]model.Promotion](1))
+var promotionG singleflight.Group[[]model.Promotion]
+
+// ListPromotions 获取全部促销活动
+func ListPromotions() ([]model.Promotion, error) {
+	promotions, err := db.GetPromotions(false)
+	if err != nil {
+		return nil, errors.Wrap(err, "获取促销活动失败")
+	}
+	return promotions, nil
+}
+
+// SavePromotion 创建或更新促销活动
+func SavePromotion(promotion *model.Promotion) error {
+	if promotion.Name == "" {
+		return errors.New("活动名称不能为空")
+	}
+	if err := promotion.Compile(); err != nil {
+		return err
+	}
+	if err := db.SavePromotion(promotion); err != nil {
+		return errors.Wrap(err, "保存促销活动失败")
+	}
+	promotionCache.Del(promotionsCacheKey)
+	return nil
+}
+
+// DeletePromotion 删除促销活动
+func DeletePromotion(id uint) error {
+	if err := db.DeletePromotion(id); err != nil {
+		return errors.Wrap(err, "删除促销活动失败")
+	}
+	promotionCache.Del(promotionsCacheKey)
+	return nil
+}
+
+// getEnabledPromotions 获取已启用且解析好的促销活动
+func getEnabledPromotions() ([]model.Promotion, error) {
+	if promotions, ok := promotionCache.Get(promotionsCacheKey); ok {
+		return promotions, nil
+	}
+	promotions, err, _ := promotionG.Do(promotionsCacheKey, func() ([]model.Promotion, error) {
+		promotions, err := db.GetPromotions(true)
+		if err != nil {
+			return nil, errors.Wrap(err, "获取促销活动失败")
+		}
+		compiled := promotions[:0]
+		for _, promotion := range promotions {
+			if err := promotion.Compile(); err != nil {
+				utils.Log.Warnf("skip invalid promotion %d: %+v", promotion.ID, err)
+				continue
+			}
+			compiled = append(compiled, promotion)
+		}
+		promotionCache.Set(promotionsCacheKey, compiled, cache.WithEx[[]model.Promotion](time.Hour))
+		return compiled, nil
+	})
+	return promotions, err
+}
+
+// ApplyPromotion 按当前生效且包含该路径的促销活动中折扣最大的一个计算所需积分，没有活动时价格不变
+func ApplyPromotion(path string, credits int64) (int64, error) {
+	promotions, err := getEnabledPromotions()
+	if err != nil {
+		return credits, err
+	}
+	now := time.Now()
+	required := credits
+	for i := range promotions {
+		if promotions[i].MatchPath(path) && promotions[i].ActiveAt(now) {
+			required = min(required, promotions[i].Apply(credits))
+		}
+	}
+	return required, nil
+}
@@ -0,0 +1,84 @@
+package op
+
+import (
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// ListProvisioningTemplates 获取用户模板列表
+func ListProvisioningTemplates() ([]model.ProvisioningTemplate, error) {
+	templates, err := db.GetProvisioningTemplates()
+	if err != nil {
+		return nil, errors.Wrap(err, "获取用户模板失败")
+	}
+	return templates, nil
+}
+
+// SaveProvisioningTemplate 创建或更新用户模板
+func SaveProvisioningTemplate(template *model.ProvisioningTemplate) error {
+	template.Name = strings.TrimSpace(template.Name)
+	if template.Name == "" {
+		return errors.New("模板名称不能为空")
+	}
+	if template.Role != model.GENERAL && template.Role != model.ADMIN {
+		return errors.New("模板角色只能是普通用户或管理员")
+	}
+	if template.WelcomeCredits < 0 {
+		return errors.New("新用户积分不能为负数")
+	}
+	if template.BasePath != "" {
+		template.BasePath = utils.FixAndCleanPath(template.BasePath)
+	}
+	if template.PricingGroupID != 0 {
+		if _, err := db.GetPricingGroupByID(template.PricingGroupID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("定价组不存在")
+			}
+			return errors.Wrap(err, "获取定价组失败")
+		}
+	}
+	if err := db.SaveProvisioningTemplate(template); err != nil {
+		return errors.Wrap(err, "保存用户模板失败")
+	}
+	return nil
+}
+
+// DeleteProvisioningTemplate 删除用户模板，删除默认模板后批准的用户使用内置默认值
+func DeleteProvisioningTemplate(id uint) error {
+	if err := db.DeleteProvisioningTemplate(id); err != nil {
+		return errors.Wrap(err, "删除用户模板失败")
+	}
+	return nil
+}
+
+// defaultProvisioningTemplate 获取批准注册申请时使用的模板，没有默认模板时返回内置默认值：
+// 普通用户、根目录、无权限、不加入定价组
+func defaultProvisioningTemplate() *model.ProvisioningTemplate {
+	template, err := db.GetDefaultProvisioningTemplate()
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.Log.Errorf("failed to get the default provisioning template: %+v", err)
+		}
+		return &model.ProvisioningTemplate{BasePath: "/"}
+	}
+	return template
+}
+
+// applyProvisioningTemplate 用户创建后按模板加入定价组并发放额外的新用户积分，失败只记录日志
+func applyProvisioningTemplate(template *model.ProvisioningTemplate, user *model.User) {
+	if template.PricingGroupID != 0 {
+		if err := db.SetUserPricingGroup(user.ID, template.PricingGroupID); err != nil {
+			utils.Log.Errorf("failed to assign pricing group %d to user %d: %+v", template.PricingGroupID, user.ID, err)
+		}
+	}
+	if template.WelcomeCredits > 0 {
+		if err := grantPromoCredits(user.ID, template.WelcomeCredits, "welcome"); err != nil {
+			utils.Log.Errorf("failed to grant template credits to user %d: %+v", user.ID, err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestProvisioningTemplate(t *testing.T) {
+	setRegistrationMode(t, model.RegistrationModeApproval)
+
+	group := &model.PricingGroup{Name: "provisioned", Multiplier: 1}
+	if err := op.SavePricingGroup(group); err != nil {
+		t.Fatalf("failed to save pricing group: %+v", err)
+	}
+	template := &model.ProvisioningTemplate{
+		Name:           "home",
+		BasePath:       "/home/{username}",
+		Permission:     3,
+		PricingGroupID: group.ID,
+		WelcomeCredits: 25,
+		IsDefault:      true,
+	}
+	if err := op.SaveProvisioningTemplate(template); err != nil {
+		t.Fatalf("failed to save template: %+v", err)
+	}
+	defer op.DeleteProvisioningTemplate(template.ID)
+	if err := op.SaveProvisioningTemplate(&model.ProvisioningTemplate{Name: "guest", Role: model.GUEST}); err == nil {
+		t.Errorf("expected a guest template to be rejected")
+	}
+	other := &model.ProvisioningTemplate{Name: "other", IsDefault: true}
+	if err := op.SaveProvisioningTemplate(other); err != nil {
+		t.Fatalf("failed to save template: %+v", err)
+	}
+	other.IsDefault = false
+	if err := op.SaveProvisioningTemplate(other); err != nil {
+		t.Fatalf("failed to save template: %+v", err)
+	}
+	template.IsDefault = true
+	if err := op.SaveProvisioningTemplate(template); err != nil {
+		t.Fatalf("failed to save template: %+v", err)
+	}
+
+	registration, err := op.CreateUserRegistration("provisioned@example.com", "provisioned", "password", "", "", "", "")
+	if err != nil {
+		t.Fatalf("failed to register: %+v", err)
+	}
+	if _, err = op.VerifyUserRegistration(registration.Token); err != nil {
+		t.Fatalf("failed to verify registration: %+v", err)
+	}
+	user, err := op.ApproveUserRegistration(registration.ID, 1, false)
+	if err != nil {
+		t.Fatalf("failed to approve registration: %+v", err)
+	}
+	if user.BasePath != "/home/provisioned" || user.Permission != 3 {
+		t.Errorf("expected the template to be applied, got base path %s and permission %d", user.BasePath, user.Permission)
+	}
+	if assigned, err := db.GetUserPricingGroup(user.ID); err != nil || assigned.ID != group.ID {
+		t.Errorf("expected the user to join the pricing group, got %v, %v", assigned, err)
+	}
+	credits, err := op.GetUserCredits(user.ID)
+	if err != nil {
+		t.Fatalf("failed to get credits: %+v", err)
+	}
+	if credits.Balance < 25 {
+		t.Errorf("expected the template credits to be granted, got %d", credits.Balance)
+	}
+}
@@ -0,0 +1,373 @@
+package op
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const (
+	// minRedeemCodeEntropy 兑换码随机部分的最小熵（位），保证兑换码无法被枚举猜中
+	minRedeemCodeEntropy = 48
+	// minRedeemCodeSpaceRatio 可能的兑换码数量与单批数量的最小比例，避免生成时频繁碰撞
+	minRedeemCodeSpaceRatio = 1 << 20
+	maxRedeemCodeLength     = 64
+	// maxRedeemCodeAttempts 生成不重复兑换码的最大尝试轮数
+	maxRedeemCodeAttempts = 5
+)
+
+var redeemPrefixRegexp = regexp.MustCompile(`^[A-Za-z0-9]{0,16}$`)
+
+// validateRedeemCodeFormat 检查兑换码格式是否合法，且随机部分足够长，生成 count 个兑换码时能保持唯一且难以被猜中
+func validateRedeemCodeFormat(format model.RedeemCodeFormat, count int) error {
+	if !redeemPrefixRegexp.MatchString(format.Prefix) {
+		return errors.New("兑换码前缀只能包含字母和数字，且不超过16个字符")
+	}
+	if format.Alphabet() == "" {
+		return errors.Errorf("不支持的兑换码字符集: %s", format.Charset)
+	}
+	if format.Length <= 0 || format.Length > maxRedeemCodeLength {
+		return errors.Errorf("兑换码长度必须在1到%d之间", maxRedeemCodeLength)
+	}
+	if format.GroupSize < 0 || format.GroupSize > format.Length {
+		return errors.New("兑换码分组长度无效")
+	}
+	entropy := format.EntropyBits()
+	if entropy < minRedeemCodeEntropy {
+		return errors.Errorf("兑换码过短，随机部分至少需要 %d 位熵，当前为 %.0f 位", minRedeemCodeEntropy, entropy)
+	}
+	if entropy < math.Log2(float64(count)*minRedeemCodeSpaceRatio) {
+		return errors.New("兑换码过短，无法保证本批兑换码唯一")
+	}
+	return nil
+}
+
+// generateUniqueRedeemCodes 按格式生成 count 个互不相同且与已有兑换码不重复的兑换码
+func generateUniqueRedeemCodes(format model.RedeemCodeFormat, count int) ([]string, error) {
+	codes := make([]string, 0, count)
+	seen := make(map[string]struct{}, count)
+	for attempt := 0; attempt < maxRedeemCodeAttempts && len(codes) < count; attempt++ {
+		var candidates []string
+		for len(codes)+len(candidates) < count {
+			code, err := generateRedeemCode(format)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := seen[code]; ok {
+				continue
+			}
+			seen[code] = struct{}{}
+			candidates = append(candidates, code)
+		}
+		existing, err := db.GetExistingRedeemCodes(candidates)
+		if err != nil {
+			return nil, errors.Wrap(err, "检查兑换码是否重复失败")
+		}
+		taken := make(map[string]struct{}, len(existing))
+		for _, code := range existing {
+			taken[code] = struct{}{}
+		}
+		for _, code := range candidates {
+			if _, ok := taken[code]; !ok {
+				codes = append(codes, code)
+			}
+		}
+	}
+	if len(codes) < count {
+		return nil, errors.New("生成不重复的兑换码失败，请使用更长的兑换码")
+	}
+	return codes, nil
+}
+
+// generateRedeemCode 按格式使用安全随机数生成一个兑换码
+func generateRedeemCode(format model.RedeemCodeFormat) (string, error) {
+	alphabet := format.Alphabet()
+	size := big.NewInt(int64(len(alphabet)))
+	b := make([]byte, format.Length)
+	for i := range b {
+		idx, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			return "", errors.Wrap(err, "生成兑换码失败")
+		}
+		b[i] = alphabet[idx.Int64()]
+	}
+	return format.Format(string(b)), nil
+}
+
+// ListRedeemBatches 分页获取兑换码批次
+func ListRedeemBatches(page, pageSize int) ([]model.RedeemBatch, int64, error) {
+	batches, total, err := db.GetRedeemBatches(page, pageSize)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "获取兑换码批次失败")
+	}
+	return batches, total, nil
+}
+
+// GetRedeemBatch 获取兑换码批次及其所有兑换码
+func GetRedeemBatch(batchNo string) (*model.RedeemBatch, []model.RedeemCode, error) {
+	batch, err := db.GetRedeemBatch(batchNo)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("兑换码批次不存在")
+		}
+		return nil, nil, errors.Wrap(err, "获取兑换码批次失败")
+	}
+	codes, err := db.GetRedeemCodesByBatch(batchNo)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "获取兑换码失败")
+	}
+	return batch, codes, nil
+}
+
+// redeemCodeDetailUsages 兑换码详情中返回的最近使用记录数
+const redeemCodeDetailUsages = 20
+
+// ListRedeemCodeUsages 按筛选条件分页获取兑换码使用记录
+func ListRedeemCodeUsages(filter model.RedeemCodeUsageFilter, page, pageSize int) ([]model.RedeemCodeUsage, int64, error) {
+	filter.Code = strings.TrimSpace(filter.Code)
+	usages, total, err := db.GetRedeemCodeUsages(filter, page, pageSize)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "获取兑换码使用记录失败")
+	}
+	return usages, total, nil
+}
+
+// GetRedeemCodeDetail 获取兑换码详情，包括已停用的兑换码、剩余次数和最近的使用记录
+func GetRedeemCodeDetail(code string) (*model.RedeemCodeDetail, error) {
+	redeemCode, err := db.FindRedeemCode(strings.TrimSpace(code))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.RedeemCodeNotFound
+		}
+		return nil, errors.Wrap(err, "获取兑换码失败")
+	}
+	usages, _, err := db.GetRedeemCodeUsages(model.RedeemCodeUsageFilter{RedeemCodeID: redeemCode.ID}, 1, redeemCodeDetailUsages)
+	if err != nil {
+		return nil, errors.Wrap(err, "获取兑换码使用记录失败")
+	}
+	return &model.RedeemCodeDetail{
+		RedeemCode:    redeemCode,
+		RemainingUses: max(redeemCode.MaxUses-redeemCode.UsedCount, 0),
+		Expired:       redeemCode.IsExpired(),
+		Usages:        usages,
+	}, nil
+}
+
+// ListRedeemCampaigns 获取全部兑换码活动
+func ListRedeemCampaigns() ([]model.RedeemCampaign, error) {
+	campaigns, err := db.GetRedeemCampaigns()
+	if err != nil {
+		return nil, errors.Wrap(err, "获取兑换码活动失败")
+	}
+	return campaigns, nil
+}
+
+// SaveRedeemCampaign 创建或更新兑换码活动，预算不能低于已生成批次的积分总数
+func SaveRedeemCampaign(campaign *model.RedeemCampaign) error {
+	if campaign.Name == "" {
+		return errors.New("活动名称不能为空")
+	}
+	if campaign.Budget < 0 {
+		return errors.New("活动预算不能为负数")
+	}
+	if campaign.ID != 0 && campaign.Budget > 0 {
+		committed, err := db.GetRedeemCampaignCommitted(campaign.ID)
+		if err != nil {
+			return errors.Wrap(err, "获取活动已发放积分失败")
+		}
+		if committed > campaign.Budget {
+			return errors.Errorf("活动预算不能低于已生成兑换码的积分总数 %d", committed)
+		}
+	}
+	if err := db.SaveRedeemCampaign(campaign); err != nil {
+		return errors.Wrap(err, "保存兑换码活动失败")
+	}
+	return nil
+}
+
+// DeleteRedeemCampaign 删除兑换码活动，活动下已有批次时不能删除
+func DeleteRedeemCampaign(id uint) error {
+	count, err := db.CountRedeemCampaignBatches(id)
+	if err != nil {
+		return errors.Wrap(err, "获取活动批次失败")
+	}
+	if count > 0 {
+		return errors.New("活动下已有兑换码批次，不能删除")
+	}
+	if err = db.DeleteRedeemCampaign(id); err != nil {
+		return errors.Wrap(err, "删除兑换码活动失败")
+	}
+	return nil
+}
+
+// GetRedeemCampaignStats 获取兑换码活动的兑换率、已发放积分和兑换用户数
+func GetRedeemCampaignStats(id uint) (*model.RedeemCampaignStats, error) {
+	if _, err := db.GetRedeemCampaign(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("兑换码活动不存在")
+		}
+		return nil, errors.Wrap(err, "获取兑换码活动失败")
+	}
+	stats, err := db.GetRedeemCampaignStats(id)
+	if err != nil {
+		return nil, errors.Wrap(err, "统计兑换码活动失败")
+	}
+	if stats.Capacity > 0 {
+		stats.RedemptionRate = float64(stats.Used) / float64(stats.Capacity)
+	}
+	return stats, nil
+}
+
+// checkRedeemCampaignBudget 检查批次所属的活动是否存在，以及生成该批次后是否超出活动预算
+func checkRedeemCampaignBudget(batch *model.RedeemBatch) error {
+	campaign, err := db.GetRedeemCampaign(batch.CampaignID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("兑换码活动不存在")
+		}
+		return errors.Wrap(err, "获取兑换码活动失败")
+	}
+	if campaign.Budget <= 0 {
+		return nil
+	}
+	committed, err := db.GetRedeemCampaignCommitted(campaign.ID)
+	if err != nil {
+		return errors.Wrap(err, "获取活动已发放积分失败")
+	}
+	if committed+batch.Potential() > campaign.Budget {
+		return errors.Errorf("超出活动预算，剩余可发放 %d 积分", max(campaign.Budget-committed, 0))
+	}
+	return nil
+}
+
+// RevokeRedeemCodes 立即停用兑换码并记录操作的管理员和原因。scope 为 code 时 target 为兑换码，
+// 为 batch 时 target 为批次号，为 filter 时停用符合 filter 的所有未使用兑换码；返回停用的数量
+func RevokeRedeemCodes(adminID uint, scope, target string, filter model.RedeemCodeFilter, reason string) (int64, error) {
+	if strings.TrimSpace(reason) == "" {
+		return 0, errors.New("必须填写作废原因")
+	}
+	switch scope {
+	case model.RevokeScopeCode:
+		if target == "" {
+			return 0, errors.New("兑换码不能为空")
+		}
+		filter = model.RedeemCodeFilter{Code: target}
+	case model.RevokeScopeBatch:
+		if _, err := db.GetRedeemBatch(target); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return 0, errors.New("兑换码批次不存在")
+			}
+			return 0, errors.Wrap(err, "获取兑换码批次失败")
+		}
+		filter = model.RedeemCodeFilter{BatchNo: target}
+	case model.RevokeScopeFilter:
+		filter.Unused = true
+		if filter == (model.RedeemCodeFilter{Unused: true}) {
+			return 0, errors.New("筛选条件不能为空")
+		}
+		b, err := json.Marshal(filter)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		target = string(b)
+	default:
+		return 0, errors.Errorf("不支持的作废范围: %s", scope)
+	}
+	revocation := &model.RedeemCodeRevocation{
+		AdminID: adminID,
+		Scope:   scope,
+		Target:  target,
+		Reason:  reason,
+	}
+	if err := db.RevokeRedeemCodes(filter, revocation); err != nil {
+		return 0, errors.Wrap(err, "作废兑换码失败")
+	}
+	utils.Log.Infof("admin %d revoked %d redeem codes (%s %s): %s", adminID, revocation.Count, scope, target, reason)
+	return revocation.Count, nil
+}
+
+// ListRedeemCodeRevocations 分页获取兑换码作废记录
+func ListRedeemCodeRevocations(page, pageSize int) ([]model.RedeemCodeRevocation, int64, error) {
+	revocations, total, err := db.GetRedeemCodeRevocations(page, pageSize)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "获取兑换码作废记录失败")
+	}
+	return revocations, total, nil
+}
+
+// maxRedeemVipDays 兑换码可发放的最大会员天数
+const maxRedeemVipDays = 3650
+
+// normalizeRedeemReward 检查批次的奖励类型和内容，非积分奖励的积分数量置为0
+func normalizeRedeemReward(batch *model.RedeemBatch) error {
+	if batch.RewardType == "" {
+		batch.RewardType = model.RedeemRewardCredits
+	}
+	switch batch.RewardType {
+	case model.RedeemRewardCredits:
+		if batch.Credits <= 0 {
+			return errs.InvalidCreditsAmount
+		}
+		batch.RewardPayload = ""
+		return nil
+	case model.RedeemRewardVipDays:
+		days, err := strconv.Atoi(batch.RewardPayload)
+		if err != nil || days <= 0 || days > maxRedeemVipDays {
+			return errors.Errorf("会员天数必须在1到%d之间", maxRedeemVipDays)
+		}
+		batch.RewardPayload = strconv.Itoa(days)
+	case model.RedeemRewardFileUnlock:
+		if strings.TrimSpace(batch.RewardPayload) == "" {
+			return errors.New("解锁的文件路径不能为空")
+		}
+		batch.RewardPayload = utils.FixAndCleanPath(batch.RewardPayload)
+	default:
+		return errors.Errorf("不支持的兑换码奖励类型: %s", batch.RewardType)
+	}
+	batch.Credits = 0
+	return nil
+}
+
+// redeemCodeReward 按兑换码的奖励类型构造兑换事务中发放的奖励
+func redeemCodeReward(userID uint, redeemCode *model.RedeemCode) (db.RedeemCodeReward, error) {
+	switch redeemCode.RewardType {
+	case model.RedeemRewardVipDays:
+		days, err := strconv.Atoi(redeemCode.RewardPayload)
+		if err != nil || days <= 0 {
+			return db.RedeemCodeReward{}, errors.Errorf("兑换码会员天数无效: %s", redeemCode.RewardPayload)
+		}
+		return db.RedeemCodeReward{VipDays: days}, nil
+	case model.RedeemRewardFileUnlock:
+		return db.RedeemCodeReward{Download: &model.DownloadPurchase{
+			UserID:      userID,
+			Path:        redeemCode.RewardPayload,
+			PurchasedAt: time.Now(),
+			Unlocked:    true,
+		}}, nil
+	default:
+		if redeemCode.Credits <= 0 {
+			return db.RedeemCodeReward{}, errs.InvalidCreditsAmount
+		}
+		reason := fmt.Sprintf("兑换码: %s", redeemCode.Code)
+		return db.RedeemCodeReward{Transaction: &model.CreditTransaction{
+			UserID:      userID,
+			Amount:      redeemCode.Credits,
+			Type:        "earn",
+			Source:      reason,
+			Description: reason,
+		}}, nil
+	}
+}
@@ -0,0 +1,384 @@
+package op_test
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/pkg/errors"
+)
+
+func TestRedeemBatch(t *testing.T) {
+	user := &model.User{Username: "redeem_batch_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	spec := &model.RedeemBatch{Count: 3, Credits: 15, Description: "flyer", CreatedBy: 1}
+	codes, err := op.GenerateRedeemCodes(spec)
+	if err != nil {
+		t.Fatalf("failed to generate redeem codes: %+v", err)
+	}
+	batch, batchCodes, err := op.GetRedeemBatch(spec.BatchNo)
+	if err != nil {
+		t.Fatalf("failed to get redeem batch: %+v", err)
+	}
+	if batch.Count != 3 || batch.Credits != 15 || batch.Description != "flyer" {
+		t.Errorf("unexpected batch: %+v", batch)
+	}
+	if len(batchCodes) != len(codes) {
+		t.Fatalf("expected %d codes in batch, got %d", len(codes), len(batchCodes))
+	}
+	for i, code := range batchCodes {
+		if code.Code != codes[i] {
+			t.Errorf("code %d: expected %s, got %s", i, codes[i], code.Code)
+		}
+	}
+	if err = op.RedeemCode(user.ID, codes[0]); err != nil {
+		t.Fatalf("failed to redeem code: %+v", err)
+	}
+	credits, err := op.GetUserCredits(user.ID)
+	if err != nil {
+		t.Fatalf("failed to get credits: %+v", err)
+	}
+	if credits.Balance != 15 {
+		t.Errorf("expected balance 15, got %d", credits.Balance)
+	}
+}
+
+func TestRedeemCodeFormat(t *testing.T) {
+	format := model.RedeemCodeFormat{Prefix: "GIFT", Length: 12, Charset: model.RedeemCharsetUnambiguous, GroupSize: 4}
+	codes, err := op.GenerateRedeemCodes(&model.RedeemBatch{Count: 20, Credits: 1, CreatedBy: 1, Format: format})
+	if err != nil {
+		t.Fatalf("failed to generate redeem codes: %+v", err)
+	}
+	pattern := regexp.MustCompile(`^GIFT[A-HJKMNP-Z2-9]{4}-[A-HJKMNP-Z2-9]{4}-[A-HJKMNP-Z2-9]{4}$`)
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if !pattern.MatchString(code) {
+			t.Errorf("code %s does not match the format", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicated code %s", code)
+		}
+		seen[code] = true
+	}
+
+	for _, invalid := range []model.RedeemCodeFormat{
+		{Prefix: "OL", Length: 6, Charset: model.RedeemCharsetUnambiguous},
+		{Prefix: "OL", Length: 12, Charset: model.RedeemCharsetDigits},
+		{Prefix: "A-B", Length: 16, Charset: model.RedeemCharsetUpper},
+		{Prefix: "OL", Length: 16, Charset: "emoji"},
+	} {
+		if _, err = op.GenerateRedeemCodes(&model.RedeemBatch{Count: 1, Credits: 1, CreatedBy: 1, Format: invalid}); err == nil {
+			t.Errorf("expected format %+v to be rejected", invalid)
+		}
+	}
+}
+
+func TestRedeemCodeMaxUsesPerUser(t *testing.T) {
+	first := &model.User{Username: "redeem_limit_first", Role: model.GENERAL}
+	second := &model.User{Username: "redeem_limit_second", Role: model.GENERAL}
+	for _, u := range []*model.User{first, second} {
+		if err := op.CreateUser(u); err != nil {
+			t.Fatalf("failed to create user: %+v", err)
+		}
+	}
+	codes, err := op.GenerateRedeemCodes(&model.RedeemBatch{Count: 1, Credits: 5, MaxUses: 10, MaxUsesPerUser: 2, CreatedBy: 1})
+	if err != nil {
+		t.Fatalf("failed to generate redeem codes: %+v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err = op.RedeemCode(first.ID, codes[0]); err != nil {
+			t.Fatalf("failed to redeem code: %+v", err)
+		}
+	}
+	if err = op.RedeemCode(first.ID, codes[0]); !errors.Is(err, errs.RedeemCodeUserLimit) {
+		t.Errorf("expected per user limit, got %v", err)
+	}
+	if err = op.RedeemCode(second.ID, codes[0]); err != nil {
+		t.Errorf("expected another user to redeem the code, got %+v", err)
+	}
+}
+
+func TestRedeemCampaign(t *testing.T) {
+	users := []*model.User{
+		{Username: "campaign_user_a", Role: model.GENERAL},
+		{Username: "campaign_user_b", Role: model.GENERAL},
+	}
+	for _, u := range users {
+		if err := op.CreateUser(u); err != nil {
+			t.Fatalf("failed to create user: %+v", err)
+		}
+	}
+	campaign := &model.RedeemCampaign{Name: "spring fair", Channel: "offline", Budget: 100}
+	if err := op.SaveRedeemCampaign(campaign); err != nil {
+		t.Fatalf("failed to save campaign: %+v", err)
+	}
+	codes, err := op.GenerateRedeemCodes(&model.RedeemBatch{CampaignID: campaign.ID, Count: 4, Credits: 10, CreatedBy: 1})
+	if err != nil {
+		t.Fatalf("failed to generate redeem codes: %+v", err)
+	}
+	if _, err = op.GenerateRedeemCodes(&model.RedeemBatch{CampaignID: campaign.ID, Count: 2, Credits: 40, CreatedBy: 1}); err == nil {
+		t.Errorf("expected the campaign budget to be enforced")
+	}
+	if _, err = op.GenerateRedeemCodes(&model.RedeemBatch{CampaignID: campaign.ID, Count: 1, Credits: 20, MaxUses: 3, CreatedBy: 1}); err != nil {
+		t.Fatalf("failed to generate redeem codes within budget: %+v", err)
+	}
+	for i, u := range users {
+		if err = op.RedeemCode(u.ID, codes[i]); err != nil {
+			t.Fatalf("failed to redeem code: %+v", err)
+		}
+	}
+	if err = op.RedeemCode(users[0].ID, codes[2]); err != nil {
+		t.Fatalf("failed to redeem code: %+v", err)
+	}
+
+	stats, err := op.GetRedeemCampaignStats(campaign.ID)
+	if err != nil {
+		t.Fatalf("failed to get campaign stats: %+v", err)
+	}
+	expected := model.RedeemCampaignStats{
+		CampaignID: campaign.ID, Batches: 2, Codes: 5, Capacity: 7, Used: 3,
+		RedemptionRate: 3.0 / 7, Committed: 100, CreditsIssued: 30, UniqueUsers: 2,
+	}
+	if *stats != expected {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if err = op.DeleteRedeemCampaign(campaign.ID); err == nil {
+		t.Errorf("expected a campaign with batches not to be deleted")
+	}
+}
+
+func TestRevokeRedeemCodes(t *testing.T) {
+	user := &model.User{Username: "revoke_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	campaign := &model.RedeemCampaign{Name: "leaked campaign"}
+	if err := op.SaveRedeemCampaign(campaign); err != nil {
+		t.Fatalf("failed to save campaign: %+v", err)
+	}
+	batch := &model.RedeemBatch{CampaignID: campaign.ID, Count: 3, Credits: 1, CreatedBy: 1}
+	codes, err := op.GenerateRedeemCodes(batch)
+	if err != nil {
+		t.Fatalf("failed to generate redeem codes: %+v", err)
+	}
+	if err = op.RedeemCode(user.ID, codes[0]); err != nil {
+		t.Fatalf("failed to redeem code: %+v", err)
+	}
+
+	if _, err = op.RevokeRedeemCodes(1, model.RevokeScopeCode, codes[1], model.RedeemCodeFilter{}, ""); err == nil {
+		t.Errorf("expected a reason to be required")
+	}
+	count, err := op.RevokeRedeemCodes(1, model.RevokeScopeCode, codes[1], model.RedeemCodeFilter{}, "sold online")
+	if err != nil || count != 1 {
+		t.Fatalf("expected 1 code revoked, got %d, %+v", count, err)
+	}
+	if err = op.RedeemCode(user.ID, codes[1]); err == nil {
+		t.Errorf("expected a revoked code to be rejected")
+	}
+	// the used code is kept, only the remaining unused code is revoked
+	count, err = op.RevokeRedeemCodes(1, model.RevokeScopeFilter, "", model.RedeemCodeFilter{CampaignID: campaign.ID}, "campaign leaked")
+	if err != nil || count != 1 {
+		t.Fatalf("expected 1 code revoked, got %d, %+v", count, err)
+	}
+	_, batchCodes, err := op.GetRedeemBatch(batch.BatchNo)
+	if err != nil {
+		t.Fatalf("failed to get redeem batch: %+v", err)
+	}
+	for _, code := range batchCodes {
+		if code.Enabled != (code.Code == codes[0]) {
+			t.Errorf("unexpected enabled state of code %s: %v", code.Code, code.Enabled)
+		}
+	}
+	revocations, total, err := op.ListRedeemCodeRevocations(1, 10)
+	if err != nil || total < 2 {
+		t.Fatalf("expected revocation records, got %d, %+v", total, err)
+	}
+	if revocations[0].Scope != model.RevokeScopeFilter || revocations[0].Reason != "campaign leaked" || revocations[0].AdminID != 1 {
+		t.Errorf("unexpected revocation record: %+v", revocations[0])
+	}
+}
+
+func TestGetRedeemCodeInfo(t *testing.T) {
+	user := &model.User{Username: "redeem_info_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	codes, err := op.GenerateRedeemCodes(&model.RedeemBatch{Count: 1, Credits: 8, MaxUses: 3, MaxUsesPerUser: 1, CreatedBy: 1})
+	if err != nil {
+		t.Fatalf("failed to generate redeem codes: %+v", err)
+	}
+	info, err := op.GetRedeemCodeInfo(user.ID, " "+codes[0]+" ")
+	if err != nil {
+		t.Fatalf("failed to get redeem code info: %+v", err)
+	}
+	if info.Credits != 8 || info.RemainingUses != 3 || !info.Available {
+		t.Errorf("unexpected info before redeeming: %+v", info)
+	}
+	if err = op.RedeemCode(user.ID, codes[0]); err != nil {
+		t.Fatalf("failed to redeem code: %+v", err)
+	}
+	if info, err = op.GetRedeemCodeInfo(user.ID, codes[0]); err != nil {
+		t.Fatalf("failed to get redeem code info: %+v", err)
+	}
+	if info.RemainingUses != 2 || info.Available {
+		t.Errorf("unexpected info after redeeming: %+v", info)
+	}
+	if _, err = op.GetRedeemCodeInfo(user.ID, "OLNOTEXISTING"); !errors.Is(err, errs.RedeemCodeNotFound) {
+		t.Errorf("expected redeem code not found, got %v", err)
+	}
+}
+
+func TestRedeemRewardTypes(t *testing.T) {
+	user := &model.User{Username: "redeem_reward_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	vipCodes, err := op.GenerateRedeemCodes(&model.RedeemBatch{Count: 1, RewardType: model.RedeemRewardVipDays, RewardPayload: "7", CreatedBy: 1})
+	if err != nil {
+		t.Fatalf("failed to generate vip codes: %+v", err)
+	}
+	if err = op.SetFileCreditsConfig("/redeem_unlock/movie.mkv", 50, 0, model.PricingFlat, false, 1); err != nil {
+		t.Fatalf("failed to set file config: %+v", err)
+	}
+	unlockCodes, err := op.GenerateRedeemCodes(&model.RedeemBatch{Count: 1, RewardType: model.RedeemRewardFileUnlock, RewardPayload: "redeem_unlock/movie.mkv", CreatedBy: 1})
+	if err != nil {
+		t.Fatalf("failed to generate unlock codes: %+v", err)
+	}
+	if _, err = op.GenerateRedeemCodes(&model.RedeemBatch{Count: 1, RewardType: model.RedeemRewardVipDays, RewardPayload: "forever", CreatedBy: 1}); err == nil {
+		t.Errorf("expected an invalid vip days payload to be rejected")
+	}
+
+	if err = op.RedeemCode(user.ID, vipCodes[0]); err != nil {
+		t.Fatalf("failed to redeem vip code: %+v", err)
+	}
+	updated, err := op.GetUserById(user.ID)
+	if err != nil {
+		t.Fatalf("failed to get user: %+v", err)
+	}
+	if !updated.IsVip() || updated.VipExpiresAt.Before(time.Now().AddDate(0, 0, 6)) {
+		t.Errorf("expected 7 days of vip, got %v", updated.VipExpiresAt)
+	}
+
+	if err = op.RedeemCode(user.ID, unlockCodes[0]); err != nil {
+		t.Fatalf("failed to redeem unlock code: %+v", err)
+	}
+	_, required, err := op.CheckFileDownloadPermission(user.ID, "/redeem_unlock/movie.mkv")
+	if err != nil || required != 0 {
+		t.Errorf("expected the unlocked file to be free, got %d, %+v", required, err)
+	}
+	credits, err := op.GetUserCredits(user.ID)
+	if err != nil {
+		t.Fatalf("failed to get credits: %+v", err)
+	}
+	if credits.Balance != 0 {
+		t.Errorf("expected no credits from non-credit rewards, got %d", credits.Balance)
+	}
+}
+
+func TestListRedeemCodeUsages(t *testing.T) {
+	user := &model.User{Username: "redeem_usage_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	batch := &model.RedeemBatch{Count: 2, Credits: 5, MaxUses: 2, CreatedBy: 1}
+	codes, err := op.GenerateRedeemCodes(batch)
+	if err != nil {
+		t.Fatalf("failed to generate redeem codes: %+v", err)
+	}
+	for _, code := range codes {
+		if err = op.RedeemCode(user.ID, code); err != nil {
+			t.Fatalf("failed to redeem code: %+v", err)
+		}
+	}
+
+	usages, total, err := op.ListRedeemCodeUsages(model.RedeemCodeUsageFilter{BatchNo: batch.BatchNo}, 1, 20)
+	if err != nil {
+		t.Fatalf("failed to list usages: %+v", err)
+	}
+	if total != 2 || len(usages) != 2 || usages[0].User == nil || usages[0].RedeemCode == nil {
+		t.Errorf("expected 2 usages with user and code preloaded, got %d: %+v", total, usages)
+	}
+	if _, total, _ = op.ListRedeemCodeUsages(model.RedeemCodeUsageFilter{Code: codes[0], UserID: user.ID}, 1, 20); total != 1 {
+		t.Errorf("expected 1 usage of %s, got %d", codes[0], total)
+	}
+	future := time.Now().Add(time.Hour)
+	if _, total, _ = op.ListRedeemCodeUsages(model.RedeemCodeUsageFilter{BatchNo: batch.BatchNo, Start: &future}, 1, 20); total != 0 {
+		t.Errorf("expected no usages after %v, got %d", future, total)
+	}
+
+	detail, err := op.GetRedeemCodeDetail(codes[0])
+	if err != nil {
+		t.Fatalf("failed to get redeem code detail: %+v", err)
+	}
+	if detail.RemainingUses != 1 || detail.Expired || len(detail.Usages) != 1 {
+		t.Errorf("unexpected redeem code detail: %+v", detail)
+	}
+	if _, err = op.GetRedeemCodeDetail("OLNOTEXISTING"); !errors.Is(err, errs.RedeemCodeNotFound) {
+		t.Errorf("expected redeem code not found, got %v", err)
+	}
+}
+
+func TestGenerateRedeemCodesExpiry(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	if _, err := op.GenerateRedeemCodes(&model.RedeemBatch{Count: 1, Credits: 5, ExpiresAt: &past, CreatedBy: 1}); err == nil {
+		t.Errorf("expected an expiry in the past to be rejected")
+	}
+	future := time.Now().Add(time.Hour).Truncate(time.Second)
+	codes, err := op.GenerateRedeemCodes(&model.RedeemBatch{Count: 1, Credits: 5, MaxUses: 3, ExpiresAt: &future, CreatedBy: 1})
+	if err != nil {
+		t.Fatalf("failed to generate redeem codes: %+v", err)
+	}
+	redeemCode, err := op.GetRedeemCode(codes[0])
+	if err != nil {
+		t.Fatalf("failed to get redeem code: %+v", err)
+	}
+	if redeemCode.MaxUses != 3 || redeemCode.ExpiresAt == nil || !redeemCode.ExpiresAt.Equal(future) {
+		t.Errorf("expected max uses 3 and expiry %v, got %d and %v", future, redeemCode.MaxUses, redeemCode.ExpiresAt)
+	}
+}
+
+func TestRedeemCodeConcurrent(t *testing.T) {
+	users := make([]*model.User, 5)
+	for i := range users {
+		users[i] = &model.User{Username: fmt.Sprintf("redeem_concurrent_user_%d", i), Role: model.GENERAL}
+		if err := op.CreateUser(users[i]); err != nil {
+			t.Fatalf("failed to create user: %+v", err)
+		}
+	}
+	codes, err := op.GenerateRedeemCodes(&model.RedeemBatch{Count: 1, Credits: 10, MaxUses: 1, CreatedBy: 1})
+	if err != nil {
+		t.Fatalf("failed to generate redeem codes: %+v", err)
+	}
+
+	var wg sync.WaitGroup
+	var redeemed atomic.Int32
+	for _, user := range users {
+		wg.Add(1)
+		go func(userID uint) {
+			defer wg.Done()
+			if err := op.RedeemCode(userID, codes[0]); err == nil {
+				redeemed.Add(1)
+			}
+		}(user.ID)
+	}
+	wg.Wait()
+
+	if redeemed.Load() != 1 {
+		t.Errorf("expected exactly one redemption of a single-use code, got %d", redeemed.Load())
+	}
+	detail, err := op.GetRedeemCodeDetail(codes[0])
+	if err != nil {
+		t.Fatalf("failed to get redeem code detail: %+v", err)
+	}
+	if detail.UsedCount != 1 || len(detail.Usages) != 1 {
+		t.Errorf("expected one usage, got used count %d and %d usages", detail.UsedCount, len(detail.Usages))
+	}
+}
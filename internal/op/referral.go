@@ -0,0 +1,172 @@
+package op
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// GetReferralCode 获取用户的推荐码，不存在时自动生成
+func GetReferralCode(userID uint) (*model.ReferralCode, error) {
+	code, err := db.GetReferralCodeByUserID(userID)
+	if err == nil {
+		return code, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.Wrap(err, "获取推荐码失败")
+	}
+
+	code = &model.ReferralCode{
+		UserID: userID,
+		Code:   strings.ToUpper(random.String(8)),
+	}
+	err = db.CreateReferralCode(code)
+	if err != nil {
+		// 并发创建时唯一索引冲突，重新读取
+		if existing, e := db.GetReferralCodeByUserID(userID); e == nil {
+			return existing, nil
+		}
+		return nil, errors.Wrap(err, "创建推荐码失败")
+	}
+	return code, nil
+}
+
+// CheckReferralCode 检查推荐码是否有效
+func CheckReferralCode(code string) error {
+	if _, err := db.GetReferralCodeByCode(strings.ToUpper(code)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errs.InvalidReferralCode
+		}
+		return errors.Wrap(err, "获取推荐码失败")
+	}
+	return nil
+}
+
+// BindReferral 在被推荐用户注册通过后建立推荐关系，并按设置发放注册奖励
+func BindReferral(refereeID uint, code string) error {
+	if code == "" {
+		return nil
+	}
+	referralCode, err := db.GetReferralCodeByCode(strings.ToUpper(code))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errs.InvalidReferralCode
+		}
+		return errors.Wrap(err, "获取推荐码失败")
+	}
+	if referralCode.UserID == refereeID {
+		return errs.SelfReferral
+	}
+
+	referral := &model.Referral{
+		ReferrerID: referralCode.UserID,
+		RefereeID:  refereeID,
+		Code:       referralCode.Code,
+	}
+	err = db.CreateReferral(referral)
+	if err != nil {
+		return errors.Wrap(err, "创建推荐关系失败")
+	}
+
+	return rewardReferral(referral, "register",
+		getCreditsSettingInt(conf.ReferralRegisterReferrerCredits, 0),
+		getCreditsSettingInt(conf.ReferralRegisterRefereeCredits, 0))
+}
+
+// RewardReferralFirstPurchase 被推荐用户首次购买后按设置发放奖励，每个被推荐用户只发放一次
+func RewardReferralFirstPurchase(refereeID uint) error {
+	referral, err := db.GetReferralByRefereeID(refereeID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return errors.Wrap(err, "获取推荐关系失败")
+	}
+	if referral.FirstPurchaseAt != nil {
+		return nil
+	}
+
+	first, err := db.MarkReferralFirstPurchase(referral.ID, time.Now())
+	if err != nil {
+		return errors.Wrap(err, "更新推荐关系失败")
+	}
+	if !first {
+		return nil
+	}
+
+	return rewardReferral(referral, "first_purchase",
+		getCreditsSettingInt(conf.ReferralPurchaseReferrerCredits, 0),
+		getCreditsSettingInt(conf.ReferralPurchaseRefereeCredits, 0))
+}
+
+// rewardReferral 发放推荐奖励，积分交易的来源为 referral，来源ID为推荐关系ID
+func rewardReferral(referral *model.Referral, event string, referrerCredits, refereeCredits int64) error {
+	referrerCredits, refereeCredits = max(referrerCredits, 0), max(refereeCredits, 0)
+	if referrerCredits == 0 && refereeCredits == 0 {
+		return nil
+	}
+
+	sourceID := strconv.FormatUint(uint64(referral.ID), 10)
+	for _, r := range []struct {
+		userID  uint
+		credits int64
+	}{{referral.ReferrerID, referrerCredits}, {referral.RefereeID, refereeCredits}} {
+		if r.credits == 0 {
+			continue
+		}
+		err := changeUserCredits(&model.CreditTransaction{
+			UserID:      r.userID,
+			Amount:      r.credits,
+			Type:        "earn",
+			Source:      "referral",
+			SourceID:    sourceID,
+			Description: fmt.Sprintf("推荐奖励: %s", event),
+			ExpiresAt:   promoCreditsExpiresAt(),
+		})
+		if err != nil {
+			return errors.Wrap(err, "发放推荐奖励失败")
+		}
+	}
+
+	err := db.AddReferralRewards(referral.ID, referrerCredits, refereeCredits)
+	if err != nil {
+		return errors.Wrap(err, "更新推荐奖励失败")
+	}
+	return nil
+}
+
+// GetReferralStats 获取用户的推荐码及推荐统计
+func GetReferralStats(userID uint) (*model.ReferralStats, error) {
+	code, err := GetReferralCode(userID)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := db.GetReferralStats(userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "获取推荐统计失败")
+	}
+	stats.Code = code.Code
+	return stats, nil
+}
+
+// ListReferrals 获取用户推荐的用户列表
+func ListReferrals(userID uint, page, pageSize int) ([]model.ReferralItem, int64, error) {
+	return db.GetReferralsByReferrerID(userID, page, pageSize)
+}
+
+// logReferralError 推荐奖励失败不影响主流程，仅记录日志
+func logReferralError(err error) {
+	if err != nil {
+		utils.Log.Errorf("referral: %+v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestReferral(t *testing.T) {
+	referrer := &model.User{Username: "referrer", Role: model.GENERAL}
+	referee := &model.User{Username: "referee", Role: model.GENERAL}
+	for _, u := range []*model.User{referrer, referee} {
+		if err := op.CreateUser(u); err != nil {
+			t.Fatalf("failed to create user: %+v", err)
+		}
+	}
+	err := op.SaveSettingItem(&model.SettingItem{Key: conf.ReferralPurchaseReferrerCredits, Value: "5", Type: conf.TypeNumber})
+	if err != nil {
+		t.Fatalf("failed to save setting: %+v", err)
+	}
+	code, err := op.GetReferralCode(referrer.ID)
+	if err != nil {
+		t.Fatalf("failed to get referral code: %+v", err)
+	}
+	if err = op.BindReferral(referrer.ID, code.Code); err == nil {
+		t.Error("own referral code should be rejected")
+	}
+	if err = op.BindReferral(referee.ID, code.Code); err != nil {
+		t.Fatalf("failed to bind referral: %+v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err = op.RewardReferralFirstPurchase(referee.ID); err != nil {
+			t.Fatalf("failed to reward first purchase: %+v", err)
+		}
+	}
+	stats, err := op.GetReferralStats(referrer.ID)
+	if err != nil {
+		t.Fatalf("failed to get referral stats: %+v", err)
+	}
+	if stats.Invited != 1 || stats.Purchased != 1 || stats.CreditsEarned != 5 {
+		t.Errorf("unexpected referral stats: %+v", stats)
+	}
+	referrals, total, err := op.ListReferrals(referrer.ID, 1, 20)
+	if err != nil {
+		t.Fatalf("failed to list referrals: %+v", err)
+	}
+	if total != 1 || len(referrals) != 1 || referrals[0].Username != "referee" || referrals[0].CreatedAt.IsZero() {
+		t.Errorf("unexpected referrals: %+v", referrals)
+	}
+}
@@ -0,0 +1,109 @@
+package op
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+// maxRegistrationBatch 单次批量审核的最大申请数
+const maxRegistrationBatch = 100
+
+// BatchApproveRegistrations 批量批准注册申请，返回每个申请的处理结果。先校验整批申请，
+// 同一来源批准数的限制会计入本批已批准的申请，force 为 true 时忽略该限制；
+// atomic 为 true 时任一申请校验失败则整批都不处理
+func BatchApproveRegistrations(ids []uint, adminID uint, force, atomic bool) ([]model.RegistrationReviewResult, error) {
+	return reviewRegistrationBatch(ids, model.RegistrationRegistered, force, atomic, func(registration *model.UserRegistration, result *model.RegistrationReviewResult) error {
+		user, err := activateRegistration(registration, adminID)
+		if err != nil {
+			return err
+		}
+		result.Status, result.UserID = model.ReviewResultApproved, user.ID
+		return nil
+	})
+}
+
+// BatchRejectRegistrations 批量拒绝注册申请，返回每个申请的处理结果；atomic 为 true 时任一申请校验失败则整批都不处理
+func BatchRejectRegistrations(ids []uint, adminID uint, atomic bool) ([]model.RegistrationReviewResult, error) {
+	return reviewRegistrationBatch(ids, model.RegistrationRejected, true, atomic, func(registration *model.UserRegistration, result *model.RegistrationReviewResult) error {
+		if err := rejectRegistration(registration, adminID); err != nil {
+			return err
+		}
+		result.Status = model.ReviewResultRejected
+		return nil
+	})
+}
+
+// reviewRegistrationBatch 校验整批申请能否转换到 status 后逐个处理。每个申请的状态转换都是有条件的更新，
+// 并发处理或创建用户失败时该申请保持原状态，不影响其他申请
+func reviewRegistrationBatch(ids []uint, status int, force, atomic bool,
+	review func(*model.UserRegistration, *model.RegistrationReviewResult) error) ([]model.RegistrationReviewResult, error) {
+	if len(ids) == 0 {
+		return nil, errors.New("注册申请不能为空")
+	}
+	if len(ids) > maxRegistrationBatch {
+		return nil, errors.Errorf("单次最多处理 %d 个注册申请", maxRegistrationBatch)
+	}
+
+	seen := make(map[uint]struct{}, len(ids))
+	results := make([]model.RegistrationReviewResult, 0, len(ids))
+	registrations := make([]*model.UserRegistration, 0, len(ids))
+	// remaining 记录每个来源网段在窗口期内还能批准的申请数
+	remaining := make(map[string]int64)
+	limit := getCreditsSettingInt(conf.RegistrationSourceApprovals, 0)
+	failed := false
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		registration, err := getUserRegistration(id)
+		if err == nil && !registration.CanTransitionTo(status) {
+			err = errs.InvalidRegistrationStatus
+		}
+		if err == nil && status == model.RegistrationRegistered && !force && limit > 0 && registration.Source != "" {
+			left, ok := remaining[registration.Source]
+			if !ok {
+				var count int64
+				if count, err = db.CountApprovedRegistrationsBySource(registration.Source, registrationSourceSince()); err != nil {
+					err = errors.Wrap(err, "统计同一来源的注册申请失败")
+				}
+				left = limit - count
+			}
+			if err == nil && left <= 0 {
+				err = errs.RegistrationSourceLimited
+			}
+			remaining[registration.Source] = left - 1
+		}
+		result := model.RegistrationReviewResult{ID: id}
+		if err != nil {
+			failed = true
+			setReviewError(&result, err)
+			registration = nil
+		}
+		results = append(results, result)
+		registrations = append(registrations, registration)
+	}
+
+	for i, registration := range registrations {
+		if registration == nil {
+			continue
+		}
+		if atomic && failed {
+			results[i].Status = model.ReviewResultSkipped
+			continue
+		}
+		if err := review(registration, &results[i]); err != nil {
+			setReviewError(&results[i], err)
+		}
+	}
+	return results, nil
+}
+
+func setReviewError(result *model.RegistrationReviewResult, err error) {
+	result.Status = model.ReviewResultFailed
+	result.Code = errs.Code(err)
+	result.Error = err.Error()
+}
@@ -0,0 +1,92 @@
+package op
+
+import (
+	stderrors "errors"
+	"strconv"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/mail"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/notify"
+	"github.com/pkg/errors"
+)
+
+// AdminEventRegistrationsWaiting 已验证的注册申请等待审核超过 registration_escalate_after 时通知管理员的事件
+const AdminEventRegistrationsWaiting = "registrations_waiting"
+
+// SendRegistrationReminders 在待验证注册申请的令牌过期前 registration_reminder_before 小时内，
+// 重新生成令牌并发送带有新验证链接的提醒邮件，有效期重新计算为 24 小时；每个申请只提醒一次
+func SendRegistrationReminders() error {
+	hours := getCreditsSettingInt(conf.RegistrationReminderBefore, 2)
+	if hours <= 0 {
+		return nil
+	}
+	registrations, err := db.GetRegistrationsToRemind(time.Now().Add(time.Duration(hours) * time.Hour))
+	if err != nil {
+		return errors.Wrap(err, "获取待提醒的注册申请失败")
+	}
+	var errs []error
+	for i := range registrations {
+		if err = remindRegistration(&registrations[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return stderrors.Join(errs...)
+}
+
+// remindRegistration 为注册申请生成新的验证令牌并发送提醒邮件
+func remindRegistration(registration *model.UserRegistration) error {
+	token, err := generateToken(32)
+	if err != nil {
+		return errors.Wrap(err, "生成验证令牌失败")
+	}
+	now := time.Now()
+	registration.Token = token
+	registration.ExpiresAt = now.Add(24 * time.Hour)
+	registration.SentAt = &now
+	registration.RemindedAt = &now
+	if err = db.UpdateUserRegistration(registration); err != nil {
+		return errors.Wrapf(err, "更新注册申请 %d 失败", registration.ID)
+	}
+	return SendMail(registration.Email, mail.TemplateVerificationReminder, map[string]any{
+		"Username":  registration.Username,
+		"URL":       verificationURL(registration),
+		"ExpiresAt": registration.ExpiresAt,
+	})
+}
+
+// NotifyWaitingRegistrations 将验证邮箱后等待审核超过 registration_escalate_after 小时的注册申请汇总通知管理员，
+// 每个申请只通知一次
+func NotifyWaitingRegistrations() error {
+	hours := getCreditsSettingInt(conf.RegistrationEscalateAfter, 48)
+	if hours <= 0 {
+		return nil
+	}
+	registrations, err := db.GetStaleVerifiedRegistrations(time.Now().Add(-time.Duration(hours) * time.Hour))
+	if err != nil {
+		return errors.Wrap(err, "获取等待审核的注册申请失败")
+	}
+	if len(registrations) == 0 {
+		return nil
+	}
+	fields := make(map[string]string, len(registrations))
+	ids := make([]uint, 0, len(registrations))
+	for _, r := range registrations {
+		fields[r.Username] = r.Email
+		ids = append(ids, r.ID)
+	}
+	msg := notify.Message{
+		Event:  AdminEventRegistrationsWaiting,
+		Title:  "Registrations waiting for approval",
+		Text:   strconv.Itoa(len(registrations)) + " registrations have been waiting for approval for more than " + strconv.FormatInt(hours, 10) + " hours.",
+		Fields: fields,
+		Time:   time.Now(),
+	}
+	// 先记录再发送，避免某个渠道持续失败时每次都重复通知
+	if err = db.MarkRegistrationsEscalated(ids, time.Now()); err != nil {
+		return errors.Wrap(err, "记录注册申请通知时间失败")
+	}
+	return deliverAdminNotification(msg)
+}
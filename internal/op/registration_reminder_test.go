@@ -0,0 +1,109 @@
+package op_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/notify"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestSendRegistrationReminders(t *testing.T) {
+	setRegistrationMode(t, model.RegistrationModeApproval)
+
+	registration, err := op.CreateUserRegistration("remind@example.com", "reg_remind", "password", "", "", "", "")
+	if err != nil {
+		t.Fatalf("failed to register: %+v", err)
+	}
+	// sending fails without a mail provider, the token is renewed anyway
+	_ = op.SendRegistrationReminders()
+	if reminded, _ := db.GetUserRegistrationByID(registration.ID); reminded.RemindedAt != nil {
+		t.Errorf("expected no reminder long before the link expires")
+	}
+
+	registration.ExpiresAt = time.Now().Add(time.Hour)
+	if err = db.UpdateUserRegistration(registration); err != nil {
+		t.Fatalf("failed to update registration: %+v", err)
+	}
+	_ = op.SendRegistrationReminders()
+	reminded, err := db.GetUserRegistrationByID(registration.ID)
+	if err != nil {
+		t.Fatalf("failed to get registration: %+v", err)
+	}
+	if reminded.RemindedAt == nil || reminded.Token == registration.Token {
+		t.Fatalf("expected a reminder with a fresh link, got %+v", reminded)
+	}
+	if time.Until(reminded.ExpiresAt) < 23*time.Hour {
+		t.Errorf("expected the fresh link to be valid for 24 hours, expires at %v", reminded.ExpiresAt)
+	}
+
+	reminded.ExpiresAt = time.Now().Add(time.Hour)
+	if err = db.UpdateUserRegistration(reminded); err != nil {
+		t.Fatalf("failed to update registration: %+v", err)
+	}
+	_ = op.SendRegistrationReminders()
+	if again, _ := db.GetUserRegistrationByID(registration.ID); again.Token != reminded.Token {
+		t.Errorf("expected a registration to be reminded only once")
+	}
+	if _, err = op.VerifyUserRegistration(reminded.Token); err != nil {
+		t.Errorf("expected the fresh link to work: %+v", err)
+	}
+}
+
+func TestNotifyWaitingRegistrations(t *testing.T) {
+	var received []notify.Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg notify.Message
+		_ = json.NewDecoder(r.Body).Decode(&msg)
+		received = append(received, msg)
+	}))
+	defer server.Close()
+	if err := op.SaveSettingItem(&model.SettingItem{Key: conf.NotifyWebhookURL, Value: server.URL, Type: conf.TypeString}); err != nil {
+		t.Fatalf("failed to save setting: %+v", err)
+	}
+	defer op.SaveSettingItem(&model.SettingItem{Key: conf.NotifyWebhookURL, Value: "", Type: conf.TypeString})
+	setRegistrationMode(t, model.RegistrationModeApproval)
+
+	registration, err := op.CreateUserRegistration("waiting@example.com", "reg_waiting", "password", "", "", "", "")
+	if err != nil {
+		t.Fatalf("failed to register: %+v", err)
+	}
+	if registration, err = op.VerifyUserRegistration(registration.Token); err != nil {
+		t.Fatalf("failed to verify registration: %+v", err)
+	}
+	if registration.VerifiedAt == nil {
+		t.Fatalf("expected the verification time to be recorded")
+	}
+	_ = op.NotifyWaitingRegistrations()
+	for _, msg := range received {
+		if _, ok := msg.Fields["reg_waiting"]; ok {
+			t.Errorf("expected no notification for a registration verified just now")
+		}
+	}
+
+	verifiedAt := time.Now().Add(-72 * time.Hour)
+	registration.VerifiedAt = &verifiedAt
+	if err = db.UpdateUserRegistration(registration); err != nil {
+		t.Fatalf("failed to update registration: %+v", err)
+	}
+	received = nil
+	if err = op.NotifyWaitingRegistrations(); err != nil {
+		t.Fatalf("failed to notify admins: %+v", err)
+	}
+	if len(received) != 1 || received[0].Event != op.AdminEventRegistrationsWaiting || received[0].Fields["reg_waiting"] != "waiting@example.com" {
+		t.Fatalf("expected admins to be notified of the waiting registration, got %+v", received)
+	}
+	received = nil
+	if err = op.NotifyWaitingRegistrations(); err != nil {
+		t.Fatalf("failed to notify admins: %+v", err)
+	}
+	if len(received) != 0 {
+		t.Errorf("expected a registration to be escalated only once, got %+v", received)
+	}
+}
@@ -0,0 +1,105 @@
+package op
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+// registrationSource 返回 IP 所在的来源网段，IPv4 按 /24、IPv6 按 /64 归并，
+// 同一网段的地址通常属于同一用户或同一台主机；无法解析时原样返回
+func registrationSource(ip string) string {
+	parsed := net.ParseIP(strings.TrimSpace(ip))
+	if parsed == nil {
+		return ip
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: parsed.Mask(net.CIDRMask(64, 128)), Mask: net.CIDRMask(64, 128)}).String()
+}
+
+// registrationSourceSince 返回统计同一来源注册申请的窗口起点
+func registrationSourceSince() time.Time {
+	hours := getCreditsSettingInt(conf.RegistrationSourceWindow, 24)
+	return time.Now().Add(-time.Duration(hours) * time.Hour)
+}
+
+// checkRegistrationSourceLimit 检查同一来源网段在窗口期内批准的注册申请是否已达到 registration_source_approvals
+func checkRegistrationSourceLimit(registration *model.UserRegistration) error {
+	limit := getCreditsSettingInt(conf.RegistrationSourceApprovals, 0)
+	if limit <= 0 || registration.Source == "" {
+		return nil
+	}
+	count, err := db.CountApprovedRegistrationsBySource(registration.Source, registrationSourceSince())
+	if err != nil {
+		return errors.Wrap(err, "统计同一来源的注册申请失败")
+	}
+	if count >= limit {
+		return errs.RegistrationSourceLimited
+	}
+	return nil
+}
+
+// flagPendingRegistrations 为待审核的注册申请标记可疑的来源：同一网段在窗口期内提交了大量申请，
+// 或同一网段存在仅数字后缀不同的用户名
+func flagPendingRegistrations(registrations []model.UserRegistration) ([]model.PendingRegistration, error) {
+	threshold := int(getCreditsSettingInt(conf.RegistrationSourceSuspicious, 3))
+	since := registrationSourceSince()
+	sources := make(map[string][]model.UserRegistration)
+	pending := make([]model.PendingRegistration, len(registrations))
+	for i, registration := range registrations {
+		pending[i] = model.PendingRegistration{UserRegistration: registration, Flags: []string{}}
+		if registration.Source == "" {
+			continue
+		}
+		same, ok := sources[registration.Source]
+		if !ok {
+			var err error
+			if same, err = db.GetRegistrationsBySource(registration.Source, since); err != nil {
+				return nil, errors.Wrap(err, "获取同一来源的注册申请失败")
+			}
+			sources[registration.Source] = same
+		}
+		pending[i].SourceCount = len(same)
+		if threshold > 0 && len(same) >= threshold {
+			pending[i].Flags = append(pending[i].Flags, model.RegistrationFlagSameSource)
+		}
+		if hasSequentialUsername(registration, same) {
+			pending[i].Flags = append(pending[i].Flags, model.RegistrationFlagSequentialUsernames)
+		}
+	}
+	return pending, nil
+}
+
+// hasSequentialUsername 判断其他申请中是否有与该申请的用户名仅数字后缀不同的用户名，如 bot1 和 bot2
+func hasSequentialUsername(registration model.UserRegistration, others []model.UserRegistration) bool {
+	stem, ok := usernameStem(registration.Username)
+	if !ok {
+		return false
+	}
+	for _, other := range others {
+		if other.ID == registration.ID {
+			continue
+		}
+		if s, ok := usernameStem(other.Username); ok && s == stem {
+			return true
+		}
+	}
+	return false
+}
+
+// usernameStem 去掉用户名末尾的数字并转为小写，用户名不以数字结尾时返回 false
+func usernameStem(username string) (string, bool) {
+	stem := strings.TrimRight(username, "0123456789")
+	if stem == username || stem == "" {
+		return "", false
+	}
+	return strings.ToLower(stem), true
+}
@@ -0,0 +1,92 @@
+package op
+
+import (
+	"context"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/notify"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+// 注册生命周期的 Webhook 事件
+const (
+	RegistrationEventSubmitted = "registration.submitted"
+	RegistrationEventVerified  = "registration.verified"
+	RegistrationEventApproved  = "registration.approved"
+	RegistrationEventRejected  = "registration.rejected"
+)
+
+// RegistrationWebhookPayload 注册生命周期 Webhook 的请求体
+type RegistrationWebhookPayload struct {
+	Event        string                  `json:"event"`
+	Time         time.Time               `json:"time"`
+	Registration RegistrationWebhookData `json:"registration"`
+}
+
+// RegistrationWebhookData 注册申请中发送给外部系统的字段，不含密码和验证令牌
+type RegistrationWebhookData struct {
+	ID         uint      `json:"id"`
+	Username   string    `json:"username"`
+	Email      string    `json:"email"`
+	Status     int       `json:"status"`
+	InviteCode string    `json:"invite_code,omitempty"`
+	SSO        bool      `json:"sso"`
+	ReviewedBy uint      `json:"reviewed_by,omitempty"`
+	UserID     uint      `json:"user_id,omitempty"` // 仅 registration.approved 事件，为创建的用户 ID
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// emitRegistrationWebhook 向 registration_webhook_urls 中的每个地址异步发送注册事件，
+// 事件不在 registration_webhook_events 中时跳过；userID 为批准后创建的用户，其他事件为 0
+func emitRegistrationWebhook(event string, registration *model.UserRegistration, userID uint) {
+	urls := registrationWebhookURLs()
+	if len(urls) == 0 || !registrationWebhookEnabled(event) {
+		return
+	}
+	payload := RegistrationWebhookPayload{
+		Event: event,
+		Time:  time.Now(),
+		Registration: RegistrationWebhookData{
+			ID:         registration.ID,
+			Username:   registration.Username,
+			Email:      registration.Email,
+			Status:     registration.Status,
+			InviteCode: registration.InviteCode,
+			SSO:        registration.SsoID != "",
+			ReviewedBy: registration.ReviewedBy,
+			UserID:     userID,
+			CreatedAt:  registration.CreatedAt,
+		},
+	}
+	secret := getSettingStr(conf.RegistrationWebhookSecret)
+	for _, url := range urls {
+		go func(url string) {
+			ctx, cancel := context.WithTimeout(context.Background(), notifySendTimeout)
+			defer cancel()
+			if err := notify.PostJSON(ctx, url, secret, payload); err != nil {
+				utils.Log.Errorf("failed to post %s of registration %d to %s: %+v", event, registration.ID, url, err)
+			}
+		}(url)
+	}
+}
+
+// registrationWebhookURLs 返回接收注册事件的地址列表
+func registrationWebhookURLs() []string {
+	return splitSettingList(getSettingStr(conf.RegistrationWebhookURLs))
+}
+
+// registrationWebhookEnabled 判断事件是否需要发送，未配置事件列表时发送全部事件
+func registrationWebhookEnabled(event string) bool {
+	events := splitSettingList(getSettingStr(conf.RegistrationWebhookEvents))
+	if len(events) == 0 {
+		return true
+	}
+	for _, e := range events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package op_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/notify"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestRegistrationWebhooks(t *testing.T) {
+	received := make(chan op.RegistrationWebhookPayload, 8)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(notify.SignatureHeader) != notify.Sign("secret", body) {
+			t.Errorf("webhook body is not signed with the secret")
+		}
+		var payload op.RegistrationWebhookPayload
+		_ = json.Unmarshal(body, &payload)
+		received <- payload
+	}))
+	defer server.Close()
+
+	save := func(key, value string) {
+		if err := op.SaveSettingItem(&model.SettingItem{Key: key, Value: value, Type: conf.TypeString}); err != nil {
+			t.Fatalf("failed to save setting: %+v", err)
+		}
+	}
+	save(conf.RegistrationWebhookURLs, server.URL)
+	save(conf.RegistrationWebhookSecret, "secret")
+	defer save(conf.RegistrationWebhookURLs, "")
+	setRegistrationMode(t, model.RegistrationModeApproval)
+
+	expect := func(event string) op.RegistrationWebhookPayload {
+		t.Helper()
+		select {
+		case payload := <-received:
+			if payload.Event != event {
+				t.Errorf("expected event %s, got %s", event, payload.Event)
+			}
+			return payload
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected event %s to be posted", event)
+		}
+		return op.RegistrationWebhookPayload{}
+	}
+
+	registration, err := op.CreateUserRegistration("hook@example.com", "reg_hook", "password", "", "", "", "")
+	if err != nil {
+		t.Fatalf("failed to register: %+v", err)
+	}
+	if payload := expect(op.RegistrationEventSubmitted); payload.Registration.Username != "reg_hook" || payload.Registration.Email != "hook@example.com" {
+		t.Errorf("expected the registration in the payload, got %+v", payload.Registration)
+	}
+	if _, err = op.VerifyUserRegistration(registration.Token); err != nil {
+		t.Fatalf("failed to verify registration: %+v", err)
+	}
+	expect(op.RegistrationEventVerified)
+	user, err := op.ApproveUserRegistration(registration.ID, 1, false)
+	if err != nil {
+		t.Fatalf("failed to approve registration: %+v", err)
+	}
+	if payload := expect(op.RegistrationEventApproved); payload.Registration.UserID != user.ID || payload.Registration.ReviewedBy != 1 {
+		t.Errorf("expected the created user and the reviewer in the payload, got %+v", payload.Registration)
+	}
+
+	save(conf.RegistrationWebhookEvents, op.RegistrationEventRejected)
+	defer save(conf.RegistrationWebhookEvents, "")
+	registration, err = op.CreateUserRegistration("hook_rejected@example.com", "reg_hook_rejected", "password", "", "", "", "")
+	if err != nil {
+		t.Fatalf("failed to register: %+v", err)
+	}
+	if err = op.RejectUserRegistration(registration.ID, 1); err != nil {
+		t.Fatalf("failed to reject registration: %+v", err)
+	}
+	if payload := expect(op.RegistrationEventRejected); payload.Registration.ID != registration.ID {
+		t.Errorf("expected the rejected registration, got %+v", payload.Registration)
+	}
+}
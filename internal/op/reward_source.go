@@ -0,0 +1,117 @@
+package op
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/sign"
+	"github.com/pkg/errors"
+)
+
+// MaxRewardSignTTL 回调签名有效期的上限，防止外部平台签发长期有效的回调
+const MaxRewardSignTTL = 10 * time.Minute
+
+// ListRewardSources 获取外部奖励来源
+func ListRewardSources() ([]model.RewardSource, error) {
+	sources, err := db.GetRewardSources()
+	if err != nil {
+		return nil, errors.Wrap(err, "获取奖励来源失败")
+	}
+	return sources, nil
+}
+
+// SaveRewardSource 创建或更新外部奖励来源
+func SaveRewardSource(source *model.RewardSource) error {
+	source.Name = strings.TrimSpace(source.Name)
+	if source.Name == "" {
+		return errors.New("来源名称不能为空")
+	}
+	if source.Secret == "" {
+		return errors.New("签名密钥不能为空")
+	}
+	if source.MaxAmount < 0 || source.UserDailyCap < 0 || source.DailyCap < 0 {
+		return errors.New("积分上限不能为负数")
+	}
+	if err := db.SaveRewardSource(source); err != nil {
+		return errors.Wrap(err, "保存奖励来源失败")
+	}
+	return nil
+}
+
+// DeleteRewardSource 删除外部奖励来源
+func DeleteRewardSource(id uint) error {
+	if err := db.DeleteRewardSource(id); err != nil {
+		return errors.Wrap(err, "删除奖励来源失败")
+	}
+	return nil
+}
+
+// GrantExternalReward 校验外部平台的回调签名并为用户发放积分。
+// signature 为 sign.HMACSign 对 model.RewardCallbackData 的签名，包含过期时间，
+// 外部交易ID和随机数在同一来源内只能使用一次，签名错误时返回 errs.InvalidRewardSign
+func GrantExternalReward(sourceName string, userID uint, amount int64, externalID, nonce, signature string) (*model.ExternalReward, error) {
+	source, err := db.GetRewardSourceByName(sourceName)
+	if err != nil || !source.Enabled {
+		return nil, errors.New("奖励来源不存在或未启用")
+	}
+	if externalID == "" || nonce == "" {
+		return nil, errors.New("交易ID和随机数不能为空")
+	}
+	data := model.RewardCallbackData(source.Name, userID, amount, externalID, nonce)
+	if err = sign.NewHMACSign([]byte(source.Secret)).Verify(data, signature); err != nil {
+		return nil, errors.WithMessage(errs.InvalidRewardSign, err.Error())
+	}
+	// Verify accepts an expiry of 0 as "never", which would make the nonce the only replay protection
+	expire, _ := strconv.ParseInt(signature[strings.LastIndex(signature, ":")+1:], 10, 64)
+	if expire == 0 || time.Unix(expire, 0).After(time.Now().Add(MaxRewardSignTTL)) {
+		return nil, errors.WithMessage(errs.InvalidRewardSign, "签名有效期过长")
+	}
+	if amount <= 0 {
+		return nil, errs.InvalidCreditsAmount
+	}
+	if source.MaxAmount > 0 && amount > source.MaxAmount {
+		return nil, errs.RewardCapExceeded
+	}
+	if _, err = db.GetUserById(userID); err != nil {
+		return nil, errors.New("用户不存在")
+	}
+
+	reward := &model.ExternalReward{
+		SourceID:   source.ID,
+		ExternalID: externalID,
+		Nonce:      nonce,
+		UserID:     userID,
+		Amount:     amount,
+	}
+	now := time.Now()
+	since := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	err = retryOnCreditsConflict(func() error {
+		reward.ID = 0
+		transaction := &model.CreditTransaction{
+			UserID:      userID,
+			Amount:      amount,
+			Type:        "earn",
+			Source:      "offerwall",
+			SourceID:    externalID,
+			Description: source.Name,
+			ExpiresAt:   promoCreditsExpiresAt(),
+		}
+		if err := transaction.SetMetadata(&model.TransactionMetadata{
+			Extra: map[string]string{"reward_source": source.Name},
+		}); err != nil {
+			return err
+		}
+		return db.GrantExternalReward(source, reward, transaction, since)
+	})
+	if err != nil {
+		if errors.Is(err, errs.RewardReplayed) || errors.Is(err, errs.RewardCapExceeded) {
+			return nil, err
+		}
+		return nil, errors.Wrap(err, "发放奖励失败")
+	}
+	return reward, nil
+}
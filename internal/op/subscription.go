@@ -0,0 +1,92 @@
+package op
+
+import (
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// ListSubscriptionPlans 获取会员套餐列表
+func ListSubscriptionPlans(enabledOnly bool) ([]model.SubscriptionPlan, error) {
+	plans, err := db.GetSubscriptionPlans(enabledOnly)
+	if err != nil {
+		return nil, errors.Wrap(err, "获取会员套餐失败")
+	}
+	return plans, nil
+}
+
+// SaveSubscriptionPlan 创建或更新会员套餐
+func SaveSubscriptionPlan(plan *model.SubscriptionPlan) error {
+	if plan.Months <= 0 {
+		return errors.New("有效月数必须大于0")
+	}
+	if plan.Money.Amount <= 0 {
+		return errors.New("套餐价格必须大于0")
+	}
+	plan.Money = model.NewMoney(plan.Money.Amount, plan.Money.Currency)
+	if err := db.SaveSubscriptionPlan(plan); err != nil {
+		return errors.Wrap(err, "保存会员套餐失败")
+	}
+	return nil
+}
+
+// DeleteSubscriptionPlan 删除会员套餐
+func DeleteSubscriptionPlan(id uint) error {
+	if err := db.DeleteSubscriptionPlan(id); err != nil {
+		return errors.Wrap(err, "删除会员套餐失败")
+	}
+	return nil
+}
+
+// CreateSubscriptionOrder 创建购买会员套餐的支付订单
+func CreateSubscriptionOrder(userID, planID uint, paymentMethod string) (*model.PaymentOrder, error) {
+	plan, err := db.GetSubscriptionPlanByID(planID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.VipPlanNotFound
+		}
+		return nil, errors.Wrap(err, "获取会员套餐失败")
+	}
+	if !plan.Enabled {
+		return nil, errs.VipPlanUnavailable
+	}
+
+	order := &model.PaymentOrder{
+		OrderNo:       generateOrderID(),
+		UserID:        userID,
+		PlanID:        plan.ID,
+		Money:         plan.Money,
+		PaymentMethod: paymentMethod,
+		Status:        model.PaymentOrderPending,
+		ExpiresAt:     time.Now().Add(30 * time.Minute), // 30分钟过期
+	}
+
+	err = db.CreatePaymentOrder(order)
+	if err != nil {
+		return nil, errors.Wrap(err, "创建支付订单失败")
+	}
+
+	return order, nil
+}
+
+// IsVipFreePath 检查路径是否在会员免积分下载的目录下
+func IsVipFreePath(path string) bool {
+	item, err := GetSettingItemByKey(conf.VipFreePaths)
+	if err != nil {
+		return false
+	}
+	for _, p := range strings.Split(item.Value, "\n") {
+		p = strings.TrimSpace(p)
+		if p != "" && utils.IsSubPath(p, path) {
+			return true
+		}
+	}
+	return false
+}
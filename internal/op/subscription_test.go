@@ -0,0 +1,40 @@
+package op_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestSubscriptionOrder(t *testing.T) {
+	user := &model.User{Username: "vip", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	plan := &model.SubscriptionPlan{Name: "monthly", Months: 1, Money: model.NewMoney(1500, ""), Enabled: true}
+	if err := op.SaveSubscriptionPlan(plan); err != nil {
+		t.Fatalf("failed to save plan: %+v", err)
+	}
+	for i := 0; i < 2; i++ {
+		order, err := op.CreateSubscriptionOrder(user.ID, plan.ID, "alipay")
+		if err != nil {
+			t.Fatalf("failed to create order: %+v", err)
+		}
+		if err = op.CompletePaymentOrder(order.OrderNo, "tx", plan.Money, time.Now()); err != nil {
+			t.Fatalf("failed to complete order: %+v", err)
+		}
+	}
+	u, err := op.GetUserById(user.ID)
+	if err != nil {
+		t.Fatalf("failed to get user: %+v", err)
+	}
+	if !u.IsVip() || u.VipExpiresAt.Before(time.Now().AddDate(0, 2, -1)) {
+		t.Errorf("renewal should stack on the current expiry, got %v", u.VipExpiresAt)
+	}
+	credits, _ := op.GetUserCredits(user.ID)
+	if credits.Balance != 0 {
+		t.Errorf("subscription orders should not add credits, balance %d", credits.Balance)
+	}
+}
@@ -0,0 +1,156 @@
+package op
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// PublishTermsDocument 发布新版本的服务条款或隐私政策，发布后用户需在下次登录时重新同意
+func PublishTermsDocument(doc *model.TermsDocument) error {
+	doc.Version = strings.TrimSpace(doc.Version)
+	if !slices.Contains(model.TermsKinds, doc.Kind) {
+		return errors.Errorf("不支持的条款类型: %s", doc.Kind)
+	}
+	if doc.Version == "" {
+		return errors.New("版本号不能为空")
+	}
+	if strings.TrimSpace(doc.Content) == "" {
+		return errors.New("条款内容不能为空")
+	}
+	if _, err := db.GetTermsDocumentByVersion(doc.Kind, doc.Version); err == nil {
+		return errors.New("该版本已发布")
+	}
+	doc.ID = 0
+	if err := db.CreateTermsDocument(doc); err != nil {
+		return errors.Wrap(err, "发布条款失败")
+	}
+	return nil
+}
+
+// GetCurrentTerms 获取每类条款当前生效的版本，尚未发布的类型不返回
+func GetCurrentTerms() ([]model.TermsDocument, error) {
+	var docs []model.TermsDocument
+	for _, kind := range model.TermsKinds {
+		doc, err := db.GetLatestTermsDocument(kind)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "获取条款失败")
+		}
+		docs = append(docs, *doc)
+	}
+	return docs, nil
+}
+
+// ListTermsDocuments 分页获取条款的历史版本
+func ListTermsDocuments(kind string, page, pageSize int) ([]model.TermsDocument, int64, error) {
+	docs, total, err := db.GetTermsDocuments(kind, page, pageSize)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "获取条款失败")
+	}
+	return docs, total, nil
+}
+
+// ListTermsAcceptances 分页获取同意条款的记录，userID 为 0 时返回全部
+func ListTermsAcceptances(userID uint, page, pageSize int) ([]model.TermsAcceptance, int64, error) {
+	acceptances, total, err := db.GetTermsAcceptances(userID, page, pageSize)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "获取同意条款记录失败")
+	}
+	return acceptances, total, nil
+}
+
+// CheckTermsAccepted 检查 accepted（条款类型到版本号）是否包含所有当前版本的条款，
+// 未包含时返回 errs.TermsNotAccepted；返回需要记录的当前条款
+func CheckTermsAccepted(accepted map[string]string) ([]model.TermsDocument, error) {
+	docs, err := GetCurrentTerms()
+	if err != nil {
+		return nil, err
+	}
+	for _, doc := range docs {
+		if accepted[doc.Kind] != doc.Version {
+			return nil, errs.TermsNotAccepted
+		}
+	}
+	return docs, nil
+}
+
+// RecordRegistrationTerms 记录注册申请同意的条款版本，申请批准后关联到用户
+func RecordRegistrationTerms(registration *model.UserRegistration, docs []model.TermsDocument, ip string) error {
+	if err := db.CreateTermsAcceptances(termsAcceptances(docs, 0, registration.ID, ip)); err != nil {
+		return errors.Wrap(err, "保存同意条款记录失败")
+	}
+	return nil
+}
+
+// PendingTerms 获取用户尚未同意的当前版本条款，管理员和游客无需同意
+func PendingTerms(user *model.User) ([]model.TermsDocument, error) {
+	if user.IsAdmin() || user.IsGuest() {
+		return nil, nil
+	}
+	docs, err := GetCurrentTerms()
+	if err != nil {
+		return nil, err
+	}
+	var pending []model.TermsDocument
+	for _, doc := range docs {
+		ok, err := db.HasAcceptedTerms(user.ID, doc.Kind, doc.Version)
+		if err != nil {
+			return nil, errors.Wrap(err, "获取同意条款记录失败")
+		}
+		if !ok {
+			pending = append(pending, doc)
+		}
+	}
+	return pending, nil
+}
+
+// AcceptPendingTerms 登录时检查用户是否已同意当前版本的条款，accepted 包含所有未同意的版本时记录同意，
+// 否则返回 errs.TermsNotAccepted 和需要同意的条款
+func AcceptPendingTerms(user *model.User, accepted map[string]string, ip string) ([]model.TermsDocument, error) {
+	pending, err := PendingTerms(user)
+	if err != nil || len(pending) == 0 {
+		return nil, err
+	}
+	for _, doc := range pending {
+		if accepted[doc.Kind] != doc.Version {
+			return pending, errs.TermsNotAccepted
+		}
+	}
+	if err = db.CreateTermsAcceptances(termsAcceptances(pending, user.ID, 0, ip)); err != nil {
+		return nil, errors.Wrap(err, "保存同意条款记录失败")
+	}
+	return nil, nil
+}
+
+// bindRegistrationTerms 注册申请批准后将注册时同意条款的记录关联到用户
+func bindRegistrationTerms(registration *model.UserRegistration, user *model.User) {
+	if err := db.BindRegistrationTermsAcceptances(registration.ID, user.ID); err != nil {
+		utils.Log.Errorf("failed to bind terms acceptances of registration %d: %+v", registration.ID, err)
+	}
+}
+
+func termsAcceptances(docs []model.TermsDocument, userID, registrationID uint, ip string) []model.TermsAcceptance {
+	now := time.Now()
+	acceptances := make([]model.TermsAcceptance, 0, len(docs))
+	for _, doc := range docs {
+		acceptances = append(acceptances, model.TermsAcceptance{
+			UserID:         userID,
+			RegistrationID: registrationID,
+			Kind:           doc.Kind,
+			Version:        doc.Version,
+			IP:             ip,
+			AcceptedAt:     now,
+		})
+	}
+	return acceptances
+}
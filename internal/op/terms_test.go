@@ -0,0 +1,45 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/pkg/errors"
+)
+
+func TestTermsAcceptance(t *testing.T) {
+	publish := func(version string) {
+		if err := op.PublishTermsDocument(&model.TermsDocument{Kind: model.TermsOfService, Version: version, Content: "terms " + version}); err != nil {
+			t.Fatalf("failed to publish terms: %+v", err)
+		}
+	}
+	publish("2025-01")
+	if err := op.PublishTermsDocument(&model.TermsDocument{Kind: model.TermsOfService, Version: "2025-01", Content: "again"}); err == nil {
+		t.Errorf("expected a published version not to be published again")
+	}
+	if _, err := op.CheckTermsAccepted(map[string]string{model.TermsOfService: "2024-12"}); !errors.Is(err, errs.TermsNotAccepted) {
+		t.Errorf("expected an old version to be refused, got %v", err)
+	}
+
+	user := &model.User{Username: "terms_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	pending, err := op.AcceptPendingTerms(user, nil, "127.0.0.1")
+	if !errors.Is(err, errs.TermsNotAccepted) || len(pending) != 1 || pending[0].Version != "2025-01" {
+		t.Fatalf("expected the current terms to be pending, got %v, %v", pending, err)
+	}
+	if _, err = op.AcceptPendingTerms(user, map[string]string{model.TermsOfService: "2025-01"}, "127.0.0.1"); err != nil {
+		t.Fatalf("failed to accept terms: %+v", err)
+	}
+	if pending, err = op.PendingTerms(user); err != nil || len(pending) != 0 {
+		t.Errorf("expected no pending terms after accepting, got %v, %v", pending, err)
+	}
+
+	publish("2025-02")
+	if pending, err = op.PendingTerms(user); err != nil || len(pending) != 1 || pending[0].Version != "2025-02" {
+		t.Errorf("expected a new version to be accepted again, got %v, %v", pending, err)
+	}
+}
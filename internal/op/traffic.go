@@ -0,0 +1,101 @@
+package op
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const trafficBillingUnit = bytesPerGB // 按GB计费
+
+var (
+	trafficMu      sync.Mutex
+	pendingTraffic = map[uint]int64{}
+)
+
+// RecordTraffic 记录用户代理下载的流量，先在内存中累加，由 FlushTraffic 定期写入数据库，
+// 未开启流量计费时不记录
+func RecordTraffic(user *model.User, bytes int64) {
+	if user == nil || user.IsGuest() || bytes <= 0 || getCreditsSettingInt(conf.TrafficCreditsPerGB, 0) <= 0 {
+		return
+	}
+	trafficMu.Lock()
+	pendingTraffic[user.ID] += bytes
+	trafficMu.Unlock()
+}
+
+// FlushTraffic 将内存中累计的流量写入流量账户
+func FlushTraffic() error {
+	trafficMu.Lock()
+	pending := pendingTraffic
+	pendingTraffic = map[uint]int64{}
+	trafficMu.Unlock()
+
+	var errList []error
+	for userID, bytes := range pending {
+		if err := db.AddTraffic(userID, bytes); err != nil {
+			// 写入失败的流量放回，下次重试
+			trafficMu.Lock()
+			pendingTraffic[userID] += bytes
+			trafficMu.Unlock()
+			errList = append(errList, err)
+		}
+	}
+	if len(errList) > 0 {
+		return errors.Wrapf(errList[0], "写入流量失败 %d 个用户", len(errList))
+	}
+	return nil
+}
+
+// BillTraffic 将流量账户中满1GB的未计费流量按 traffic_credits_per_gb 转换为积分扣费，
+// 每个用户每次只生成一条交易记录，不足1GB的部分留到下次
+func BillTraffic() error {
+	rate := getCreditsSettingInt(conf.TrafficCreditsPerGB, 0)
+	if rate <= 0 {
+		return nil
+	}
+	accounts, err := db.GetUnbilledTrafficAccounts(trafficBillingUnit)
+	if err != nil {
+		return errors.Wrap(err, "获取流量账户失败")
+	}
+	for _, account := range accounts {
+		units := account.Unbilled() / trafficBillingUnit
+		err = retryOnCreditsConflict(func() error {
+			return db.BillTraffic(account.UserID, units*trafficBillingUnit, &model.CreditTransaction{
+				UserID:      account.UserID,
+				Amount:      -units * rate,
+				Type:        "spend",
+				Source:      "traffic",
+				Description: fmt.Sprintf("流量计费: %d GB", units),
+			})
+		})
+		if errors.Is(err, errs.InsufficientCredits) {
+			// 积分不足时保留未计费流量，等待充值后再扣
+			utils.Log.Warnf("insufficient credits to bill %d GB traffic of user %d", units, account.UserID)
+			continue
+		}
+		if err != nil {
+			return errors.Wrap(err, "流量计费失败")
+		}
+	}
+	return nil
+}
+
+// GetTrafficAccount 获取用户的流量账户，没有流量时返回空账户
+func GetTrafficAccount(userID uint) (*model.TrafficAccount, error) {
+	account, err := db.GetTrafficAccount(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &model.TrafficAccount{UserID: userID}, nil
+		}
+		return nil, errors.Wrap(err, "获取流量账户失败")
+	}
+	return account, nil
+}
@@ -0,0 +1,48 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestBillTraffic(t *testing.T) {
+	user := &model.User{Username: "traffic_user", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	err := op.SaveSettingItem(&model.SettingItem{Key: conf.TrafficCreditsPerGB, Value: "3", Type: conf.TypeNumber})
+	if err != nil {
+		t.Fatalf("failed to save setting: %+v", err)
+	}
+	defer op.SaveSettingItem(&model.SettingItem{Key: conf.TrafficCreditsPerGB, Value: "0", Type: conf.TypeNumber})
+	if err = op.AddCredits(user.ID, 100, "traffic test", ""); err != nil {
+		t.Fatalf("failed to add credits: %+v", err)
+	}
+	// 2.5GB in several requests, only whole GBs are billed
+	for i := 0; i < 5; i++ {
+		op.RecordTraffic(user, 512<<20)
+	}
+	if err = op.FlushTraffic(); err != nil {
+		t.Fatalf("failed to flush traffic: %+v", err)
+	}
+	if err = op.BillTraffic(); err != nil {
+		t.Fatalf("failed to bill traffic: %+v", err)
+	}
+	credits, err := op.GetUserCredits(user.ID)
+	if err != nil {
+		t.Fatalf("failed to get credits: %+v", err)
+	}
+	if credits.Balance != 94 {
+		t.Errorf("expected balance 94, got %d", credits.Balance)
+	}
+	account, err := op.GetTrafficAccount(user.ID)
+	if err != nil {
+		t.Fatalf("failed to get traffic account: %+v", err)
+	}
+	if account.TotalBytes != 5*512<<20 || account.Unbilled() != 512<<20 {
+		t.Errorf("unexpected traffic account: %+v", account)
+	}
+}
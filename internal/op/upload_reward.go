@@ -0,0 +1,124 @@
+package op
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// GetUploadEarnRules 获取上传奖励规则列表
+func GetUploadEarnRules() ([]model.UploadEarnRule, error) {
+	rules, err := db.GetUploadEarnRules()
+	if err != nil {
+		return nil, errors.Wrap(err, "获取上传奖励规则失败")
+	}
+	return rules, nil
+}
+
+// SaveUploadEarnRule 创建或更新上传奖励规则
+func SaveUploadEarnRule(rule *model.UploadEarnRule) error {
+	rule.Path = utils.FixAndCleanPath(rule.Path)
+	if rule.Mode != model.UploadRewardPerFile && rule.Mode != model.UploadRewardPerMB {
+		return errors.Errorf("无效的计算方式: %s", rule.Mode)
+	}
+	if rule.Credits <= 0 {
+		return errs.InvalidCreditsAmount
+	}
+	if err := db.SaveUploadEarnRule(rule); err != nil {
+		return errors.Wrap(err, "保存上传奖励规则失败")
+	}
+	return nil
+}
+
+// DeleteUploadEarnRule 删除上传奖励规则
+func DeleteUploadEarnRule(id uint) error {
+	if err := db.DeleteUploadEarnRule(id); err != nil {
+		return errors.Wrap(err, "删除上传奖励规则失败")
+	}
+	return nil
+}
+
+// matchUploadEarnRule 返回路径最长的匹配规则
+func matchUploadEarnRule(rules []model.UploadEarnRule, path string) *model.UploadEarnRule {
+	var matched *model.UploadEarnRule
+	for i := range rules {
+		if utils.IsSubPath(rules[i].Path, path) && (matched == nil || len(rules[i].Path) > len(matched.Path)) {
+			matched = &rules[i]
+		}
+	}
+	return matched
+}
+
+// uploadRewardRule 返回用户上传到 path 时命中的上传奖励规则，没有时返回 nil
+func uploadRewardRule(user *model.User, path string) (*model.UploadEarnRule, error) {
+	if user == nil || user.IsGuest() || !getCreditsSettingBool(conf.CreditsEnabled) {
+		return nil, nil
+	}
+	rules, err := db.GetEnabledUploadEarnRules()
+	if err != nil {
+		return nil, errors.Wrap(err, "获取上传奖励规则失败")
+	}
+	return matchUploadEarnRule(rules, utils.FixAndCleanPath(path)), nil
+}
+
+// UploadRewardApplies 检查用户上传到 path 时是否可能获得上传奖励，
+// 上传前据此决定是否需要在服务端计算文件哈希
+func UploadRewardApplies(user *model.User, path string) bool {
+	rule, err := uploadRewardRule(user, path)
+	return err == nil && rule != nil
+}
+
+// RewardUpload 用户上传文件成功后，按匹配的上传奖励规则发放积分，返回实际发放的积分。
+// sha256 为服务端在上传时计算的文件哈希，客户端提供的哈希不可信，没有哈希时不发放奖励；
+// 相同内容或相同路径只奖励一次
+func RewardUpload(user *model.User, path string, size int64, sha256 string) (int64, error) {
+	if sha256 == "" {
+		return 0, nil
+	}
+	rule, err := uploadRewardRule(user, path)
+	if err != nil || rule == nil {
+		return 0, err
+	}
+	path = utils.FixAndCleanPath(path)
+	credits := rule.Calculate(size)
+	if credits <= 0 {
+		return 0, nil
+	}
+
+	reward := &model.UploadReward{
+		UserID:     user.ID,
+		RuleID:     rule.ID,
+		Path:       path,
+		Size:       size,
+		ContentKey: fmt.Sprintf("%s:%s:%d", utils.SHA256.Name, strings.ToLower(sha256), size),
+		Credits:    credits,
+	}
+	var created bool
+	err = retryOnCreditsConflict(func() error {
+		reward.ID = 0
+		created, err = db.CreateUploadReward(reward, &model.CreditTransaction{
+			UserID:      user.ID,
+			Amount:      credits,
+			Type:        "earn",
+			Source:      "upload",
+			SourceID:    strconv.FormatUint(uint64(rule.ID), 10),
+			Description: fmt.Sprintf("上传文件: %s", path),
+			ExpiresAt:   promoCreditsExpiresAt(),
+		})
+		return err
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "发放上传奖励失败")
+	}
+	if !created {
+		return 0, nil
+	}
+	return credits, nil
+}
@@ -0,0 +1,57 @@
+package op_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestRewardUpload(t *testing.T) {
+	user := &model.User{Username: "uploader", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	err := op.SaveSettingItem(&model.SettingItem{Key: conf.CreditsEnabled, Value: "true", Type: conf.TypeBool})
+	if err != nil {
+		t.Fatalf("failed to save setting: %+v", err)
+	}
+	rules := []*model.UploadEarnRule{
+		{Path: "/share", Mode: model.UploadRewardPerFile, Credits: 2, Enabled: true},
+		{Path: "/share/video", Mode: model.UploadRewardPerMB, Credits: 1, MaxCredits: 10, Enabled: true},
+	}
+	for _, r := range rules {
+		if err = op.SaveUploadEarnRule(r); err != nil {
+			t.Fatalf("failed to save rule: %+v", err)
+		}
+	}
+	hash := "0123456789ABCDEF0123456789abcdef0123456789abcdef0123456789abcdef"
+	cases := []struct {
+		path string
+		size int64
+		hash string
+		want int64
+	}{
+		{"/share/a.txt", 10, "aa", 2},
+		{"/share/a.txt", 10, "bb", 0},                                  // same path
+		{"/share/b.txt", 10, "", 0},                                    // not hashed on the server
+		{"/share/video/b.mp4", 50 << 20, hash, 10},                     // per MB, capped
+		{"/share/video/copy.mp4", 50 << 20, hash, 0},                   // same content
+		{"/share/video/lower.mp4", 50 << 20, strings.ToLower(hash), 0}, // same content
+		{"/other/c.txt", 10, "cc", 0},                                  // no rule
+	}
+	if !op.UploadRewardApplies(user, "/share/d.txt") || op.UploadRewardApplies(user, "/other/d.txt") {
+		t.Errorf("expected upload rewards to apply only under the rule paths")
+	}
+	for _, c := range cases {
+		got, err := op.RewardUpload(user, c.path, c.size, c.hash)
+		if err != nil {
+			t.Fatalf("failed to reward upload %s: %+v", c.path, err)
+		}
+		if got != c.want {
+			t.Errorf("reward for %s: got %d, want %d", c.path, got, c.want)
+		}
+	}
+}
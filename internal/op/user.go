@@ -1,6 +1,7 @@
 package op
 
 import (
+	"strings"
 	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/db"
@@ -60,6 +61,17 @@ func GetUserByName(username string) (*model.User, error) {
 	return user, err
 }
 
+// GetUserByLogin gets the user by the username or the email of a registered account, emails are case-insensitive
+func GetUserByLogin(login string) (*model.User, error) {
+	if strings.Contains(login, "@") {
+		registration, err := db.GetUserRegistrationByEmail(normalizeEmail(login))
+		if err == nil && registration.Status == model.RegistrationRegistered {
+			return GetUserByName(registration.Username)
+		}
+	}
+	return GetUserByName(login)
+}
+
 func GetUserById(id uint) (*model.User, error) {
 	return db.GetUserById(id)
 }
@@ -70,7 +82,11 @@ func GetUsers(pageIndex, pageSize int) (users []model.User, count int64, err err
 
 func CreateUser(u *model.User) error {
 	u.BasePath = utils.FixAndCleanPath(u.BasePath)
-	return db.CreateUser(u)
+	if err := db.CreateUser(u); err != nil {
+		return err
+	}
+	callUserCreatedHooks(u)
+	return nil
 }
 
 func DeleteUserById(id uint) error {
@@ -101,8 +117,44 @@ func UpdateUser(u *model.User) error {
 	return db.UpdateUser(u)
 }
 
+// SyncSSORole applies the role asserted by the identity provider on login. Only accounts
+// created through the provider are synced, local accounts that linked the identity keep
+// their role. The guest user is never touched and the last admin is never demoted.
+func SyncSSORole(u *model.User, provider string, admin bool) error {
+	if u.IsGuest() {
+		return nil
+	}
+	role := model.GENERAL
+	if admin {
+		role = model.ADMIN
+	}
+	if u.Role == role {
+		return nil
+	}
+	provisioned, err := IsProvisionedBy(u.ID, provider)
+	if err != nil || !provisioned {
+		return err
+	}
+	if u.IsAdmin() {
+		admins, err := db.CountUsersByRole(model.ADMIN)
+		if err != nil {
+			return err
+		}
+		if admins <= 1 {
+			utils.Log.Warnf("not demoting %s, the last admin", u.Username)
+			return nil
+		}
+	}
+	u.Role = role
+	adminUser = nil
+	return UpdateUser(u)
+}
+
 func Cancel2FAByUser(u *model.User) error {
 	u.OtpSecret = ""
+	if err := db.ReplaceOtpBackupCodes(u.ID, nil); err != nil {
+		return err
+	}
 	return UpdateUser(u)
 }
 
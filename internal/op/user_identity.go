@@ -0,0 +1,172 @@
+package op
+
+import (
+	"crypto/subtle"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/go-cache"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const identityLinkTokenExpire = 10 * time.Minute // 关联令牌的有效期
+
+// pendingIdentityLink 待关联的外部身份及发起关联的浏览器会话
+type pendingIdentityLink struct {
+	identity *model.UserIdentity
+	session  string
+}
+
+// identityLinkTokens 第三方登录回调签发的关联令牌，登录用户凭令牌关联该身份
+var identityLinkTokens = cache.NewMemCache[*pendingIdentityLink]()
+
+// ListUserIdentities 获取用户关联的外部身份
+func ListUserIdentities(userID uint) ([]model.UserIdentity, error) {
+	identities, err := db.GetUserIdentities(userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "获取外部身份失败")
+	}
+	return identities, nil
+}
+
+// GetUserByIdentity 根据外部身份获取用户，未关联时返回 gorm.ErrRecordNotFound
+func GetUserByIdentity(provider, subject string) (*model.User, error) {
+	if subject == "" {
+		return nil, errors.WithStack(gorm.ErrRecordNotFound)
+	}
+	identity, err := db.GetUserIdentity(provider, subject)
+	if err != nil {
+		return nil, err
+	}
+	return GetUserById(identity.UserID)
+}
+
+// MigrateLegacySSOIDs 将旧的 sso_id 绑定迁移为当前第三方登录平台的身份关联，已关联的跳过；
+// 只在升级时执行一次，之后 sso_id 不再用于登录
+func MigrateLegacySSOIDs(provider string) error {
+	users, err := db.GetUsersWithSSOID()
+	if err != nil {
+		return errors.Wrap(err, "获取用户失败")
+	}
+	for _, user := range users {
+		_, err = db.GetUserIdentity(provider, user.SsoID)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.Wrap(err, "获取外部身份失败")
+		}
+		if err = db.CreateUserIdentity(&model.UserIdentity{UserID: user.ID, Provider: provider, Subject: user.SsoID}); err != nil {
+			return errors.Wrapf(err, "迁移用户 %s 的 sso_id 失败", user.Username)
+		}
+	}
+	return nil
+}
+
+// LinkUserIdentity 将外部身份关联到用户，已关联到其他用户时返回 errs.IdentityLinked
+func LinkUserIdentity(user *model.User, provider, subject, email string) (*model.UserIdentity, error) {
+	if user.IsGuest() {
+		return nil, errors.New("游客不能关联外部身份")
+	}
+	if subject == "" {
+		return nil, errors.New("外部身份标识不能为空")
+	}
+	if existing, err := GetUserByIdentity(provider, subject); err == nil {
+		if existing.ID != user.ID {
+			return nil, errs.IdentityLinked
+		}
+		if identity, err := db.GetUserIdentity(provider, subject); err == nil {
+			return identity, nil
+		}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.Wrap(err, "获取外部身份失败")
+	}
+	identity := &model.UserIdentity{UserID: user.ID, Provider: provider, Subject: subject, Email: normalizeEmail(email)}
+	if err := db.CreateUserIdentity(identity); err != nil {
+		return nil, errors.Wrap(err, "关联外部身份失败")
+	}
+	return identity, nil
+}
+
+// ProvisionUserIdentity 关联刚由外部身份登录创建的用户，该用户的角色由提供方管理
+func ProvisionUserIdentity(user *model.User, provider, subject, email string) error {
+	identity := &model.UserIdentity{UserID: user.ID, Provider: provider, Subject: subject, Email: normalizeEmail(email), Provisioned: true}
+	return errors.Wrap(db.CreateUserIdentity(identity), "关联外部身份失败")
+}
+
+// IsProvisionedBy 检查用户是否由提供方的外部身份登录时创建
+func IsProvisionedBy(userID uint, provider string) (bool, error) {
+	identities, err := db.GetUserIdentities(userID)
+	if err != nil {
+		return false, errors.Wrap(err, "获取外部身份失败")
+	}
+	for _, identity := range identities {
+		if identity.Provider == provider && identity.Provisioned {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// IssueIdentityLinkToken 在第三方登录回调中为已验证的身份签发关联令牌，
+// session 为发起关联的浏览器会话，令牌只能在同一会话中使用
+func IssueIdentityLinkToken(provider, subject, email, session string) (string, error) {
+	if session == "" {
+		return "", errs.InvalidIdentityLinkToken
+	}
+	token, err := generateToken(32)
+	if err != nil {
+		return "", errors.Wrap(err, "生成关联令牌失败")
+	}
+	identityLinkTokens.Set(token, &pendingIdentityLink{
+		identity: &model.UserIdentity{Provider: provider, Subject: subject, Email: email},
+		session:  session,
+	}, cache.WithEx[*pendingIdentityLink](identityLinkTokenExpire))
+	return token, nil
+}
+
+// LinkUserIdentityByToken 使用关联令牌将第三方登录的身份关联到用户，令牌只能在发起关联的会话中使用一次
+func LinkUserIdentityByToken(user *model.User, token, session string) (*model.UserIdentity, error) {
+	pending, ok := identityLinkTokens.Get(token)
+	if !ok || subtle.ConstantTimeCompare([]byte(pending.session), []byte(session)) != 1 {
+		return nil, errs.InvalidIdentityLinkToken
+	}
+	identityLinkTokens.Del(token)
+	return LinkUserIdentity(user, pending.identity.Provider, pending.identity.Subject, pending.identity.Email)
+}
+
+// UnlinkUserIdentity 解除用户的外部身份关联；没有密码和通行密钥时不能解除最后一个外部身份
+func UnlinkUserIdentity(user *model.User, id uint) error {
+	identities, err := db.GetUserIdentities(user.ID)
+	if err != nil {
+		return errors.Wrap(err, "获取外部身份失败")
+	}
+	var identity *model.UserIdentity
+	for i := range identities {
+		if identities[i].ID == id {
+			identity = &identities[i]
+		}
+	}
+	if identity == nil {
+		return errs.IdentityNotFound
+	}
+	if user.PwdHash == "" && len(identities) == 1 {
+		if passkeys, err := db.CountWebAuthnCredentials(user.ID); err != nil || passkeys == 0 {
+			return errs.LastLoginMethod
+		}
+	}
+	if _, err = db.DeleteUserIdentity(user.ID, id); err != nil {
+		return errors.Wrap(err, "解除外部身份失败")
+	}
+	// 同时解除旧的 sso_id 绑定
+	if identity.Provider != model.IdentityProviderLDAP && user.SsoID == identity.Subject {
+		user.SsoID = ""
+		if err = UpdateUser(user); err != nil {
+			return errors.Wrap(err, "解除外部身份失败")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func TestUserIdentities(t *testing.T) {
+	owner := &model.User{Username: "identity_owner"}
+	other := &model.User{Username: "identity_other", SsoID: "legacy-id"}
+	for _, u := range []*model.User{owner, other} {
+		u.SetPassword("password")
+		if err := op.CreateUser(u); err != nil {
+			t.Fatalf("failed to create user: %+v", err)
+		}
+	}
+
+	token, err := op.IssueIdentityLinkToken("github", "gh-1", "Owner@Example.com", "session-1")
+	if err != nil {
+		t.Fatalf("failed to issue token: %+v", err)
+	}
+	if _, err = op.LinkUserIdentityByToken(other, token, "session-2"); !errors.Is(err, errs.InvalidIdentityLinkToken) {
+		t.Errorf("expected the token to be bound to the session that started the link, got %v", err)
+	}
+	identity, err := op.LinkUserIdentityByToken(owner, token, "session-1")
+	if err != nil {
+		t.Fatalf("failed to link identity: %+v", err)
+	}
+	if identity.Email != "owner@example.com" {
+		t.Errorf("expected the email to be normalized, got %s", identity.Email)
+	}
+	if _, err = op.LinkUserIdentityByToken(owner, token, "session-1"); !errors.Is(err, errs.InvalidIdentityLinkToken) {
+		t.Errorf("expected the token to be usable once, got %v", err)
+	}
+	if user, err := op.GetUserByIdentity("github", "gh-1"); err != nil || user.ID != owner.ID {
+		t.Errorf("expected the identity to resolve to the owner, got %v, %v", user, err)
+	}
+
+	// the legacy sso id is only resolved once migrated
+	if _, err = op.GetUserByIdentity("github", "legacy-id"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("expected the legacy sso id not to resolve before the migration, got %v", err)
+	}
+	if err = op.MigrateLegacySSOIDs("github"); err != nil {
+		t.Fatalf("failed to migrate legacy sso ids: %+v", err)
+	}
+	if user, err := op.GetUserByIdentity("github", "legacy-id"); err != nil || user.ID != other.ID {
+		t.Errorf("expected the legacy sso id to resolve to the other user, got %v, %v", user, err)
+	}
+	if _, err = op.LinkUserIdentity(owner, "github", "legacy-id", ""); !errors.Is(err, errs.IdentityLinked) {
+		t.Errorf("expected an identity of another user to be refused, got %v", err)
+	}
+	if _, err = op.GetUserByIdentity(model.IdentityProviderLDAP, ""); err == nil {
+		t.Errorf("expected an empty subject to resolve to no user")
+	}
+
+	if err = op.UnlinkUserIdentity(other, identity.ID); !errors.Is(err, errs.IdentityNotFound) {
+		t.Errorf("expected an identity of another user to be refused, got %v", err)
+	}
+	if err = op.UnlinkUserIdentity(owner, identity.ID); err != nil {
+		t.Fatalf("failed to unlink identity: %+v", err)
+	}
+	if _, err = op.GetUserByIdentity("github", "gh-1"); err == nil {
+		t.Errorf("expected the unlinked identity to resolve to no user")
+	}
+}
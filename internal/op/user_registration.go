@@ -4,9 +4,14 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	netmail "net/mail"
+	"strings"
 	"time"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/mail"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
@@ -14,26 +19,72 @@ import (
 	"gorm.io/gorm"
 )
 
-// CreateUserRegistration 创建用户注册申请
-func CreateUserRegistration(email, username, password string) (*model.UserRegistration, error) {
+// CreateUserRegistration 创建用户注册申请，关闭注册时返回 errs.RegistrationClosed，邀请注册模式下必须提供有效的邀请码；
+// 密码为空时创建仅使用通行密钥登录的账户，需在注册完成后通过 GetPasskeyEnrollmentUser 登记通行密钥；
+// reason 为申请理由，供管理员审核时参考；ip 为提交申请的客户端地址，用于统计同一来源的注册申请
+func CreateUserRegistration(email, username, password, reason, referralCode, inviteCode, ip string) (*model.UserRegistration, error) {
+	email = normalizeEmail(email)
+	if addr, err := netmail.ParseAddress(email); err != nil || addr.Address != email {
+		return nil, errors.New("邮箱格式不正确")
+	}
+	if strings.Contains(username, "@") {
+		return nil, errors.New("用户名不能包含 @")
+	}
 	// 检查邮箱是否已存在
 	if _, err := db.GetUserByName(email); err == nil {
 		return nil, errors.New("邮箱已被注册")
 	}
 	
-	// 检查用户名是否已存在
-	if _, err := db.GetUserByName(username); err == nil {
-		return nil, errors.New("用户名已被使用")
+	// 检查用户名是否符合用户名策略且未被使用
+	if err := CheckUsername(username, 0); err != nil {
+		return nil, err
 	}
 	
-	// 检查是否已有待处理的注册申请
-	if existing, err := db.GetUserRegistrationByEmail(email); err == nil && !existing.IsExpired() {
-		return nil, errors.New("已有待处理的注册申请，请稍后再试")
+	// 检查邮箱是否已注册或已有待处理的注册申请
+	if existing, err := db.GetUserRegistrationByEmail(email); err == nil {
+		if existing.Status == model.RegistrationRegistered {
+			return nil, errors.New("邮箱已被注册")
+		}
+		if !existing.IsExpired() {
+			return nil, errors.New("已有待处理的注册申请，请稍后再试")
+		}
 	}
 	
-	// 生成密码哈希和盐值
+	// 检查推荐码
+	if referralCode != "" {
+		if err := CheckReferralCode(referralCode); err != nil {
+			return nil, err
+		}
+	}
+
+	mode := registrationMode()
+	if mode == model.RegistrationModeClosed {
+		return nil, errs.RegistrationClosed
+	}
+	if err := checkEmailDomain(email); err != nil {
+		return nil, err
+	}
+
+	// 邀请注册模式下校验并占用邀请码
+	if mode == model.RegistrationModeInviteOnly {
+		if inviteCode == "" {
+			return nil, errs.InviteCodeRequired
+		}
+		code, err := useInviteCode(inviteCode)
+		if err != nil {
+			return nil, err
+		}
+		inviteCode = code.Code
+	} else {
+		inviteCode = ""
+	}
+
+	// 生成密码哈希和盐值，仅通行密钥的账户不设置密码哈希，无法使用密码登录
 	salt := random.String(8)
-	pwdHash := model.TwoHashPwd(password, salt)
+	pwdHash := ""
+	if password != "" {
+		pwdHash = model.TwoHashPwd(password, salt)
+	}
 	
 	// 生成验证令牌
 	token, err := generateToken(32)
@@ -41,14 +92,21 @@ func CreateUserRegistration(email, username, password string) (*model.UserRegist
 		return nil, errors.Wrap(err, "生成验证令牌失败")
 	}
 	
+	now := time.Now()
 	registration := &model.UserRegistration{
+		SentAt:    &now,
 		Email:     email,
 		Username:  username,
 		Password:  password, // 临时存储明文密码用于验证
 		PwdHash:   pwdHash,
 		Salt:      salt,
-		Status:    0, // 待验证
+		Status:    model.RegistrationPending,
 		Token:     token,
+		ReferralCode: strings.ToUpper(referralCode),
+		InviteCode:   inviteCode,
+		Reason:       strings.TrimSpace(reason),
+		IP:           ip,
+		Source:       registrationSource(ip),
 		ExpiresAt: time.Now().Add(24 * time.Hour), // 24小时过期
 	}
 	
@@ -56,134 +114,488 @@ func CreateUserRegistration(email, username, password string) (*model.UserRegist
 	if err != nil {
 		return nil, errors.Wrap(err, "创建注册申请失败")
 	}
+	emitRegistrationWebhook(RegistrationEventSubmitted, registration, 0)
+	logMailError(SendVerificationEmail(registration))
 	
 	return registration, nil
 }
 
-// VerifyUserRegistration 验证用户注册
+// VerifyUserRegistration 验证用户注册，开放注册模式下验证后直接完成注册
 func VerifyUserRegistration(token string) (*model.UserRegistration, error) {
 	registration, err := db.GetUserRegistrationByToken(token)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("无效的验证链接")
+			return nil, errs.InvalidVerificationLink
 		}
 		return nil, errors.Wrap(err, "获取注册信息失败")
 	}
 	
 	if registration.IsExpired() {
-		return nil, errors.New("验证链接已过期")
+		return nil, errs.VerificationLinkExpired
 	}
 	
 	// 更新状态为已验证
-	registration.Status = 1
-	err = db.UpdateUserRegistration(registration)
-	if err != nil {
-		return nil, errors.Wrap(err, "更新注册状态失败")
+	if err = transitionRegistration(registration, model.RegistrationVerified, 0); err != nil {
+		return nil, err
+	}
+	emitRegistrationWebhook(RegistrationEventVerified, registration, 0)
+	
+	// 开放注册模式下验证邮箱后直接创建用户，否则进入待审核队列
+	if err = autoActivateRegistration(registration); err != nil {
+		return nil, err
 	}
 	
 	return registration, nil
 }
 
-// ApproveUserRegistration 批准用户注册
-func ApproveUserRegistration(registrationID uint) (*model.User, error) {
-	registration, err := db.GetUserRegistrationByToken("")
+const (
+	verificationResendCooldown = 2 * time.Minute // 同一注册申请两次发送验证邮件的最短间隔
+	verificationMaxResends     = 5               // 每个注册申请最多重新发送的次数
+)
+
+// ResendVerificationEmail 为待验证的注册申请重新生成验证令牌并发送验证邮件，有效期重新计算；
+// 处于冷却期时返回 errs.VerificationResendCooldown 和需要等待的时间
+func ResendVerificationEmail(email string) (time.Duration, error) {
+	registration, err := db.GetUserRegistrationByEmail(normalizeEmail(email))
+	if err != nil || registration.Status != model.RegistrationPending {
+		return 0, errors.New("没有待验证的注册申请")
+	}
+	if registration.ResendCount >= verificationMaxResends {
+		return 0, errs.VerificationResendLimit
+	}
+	if registration.SentAt != nil {
+		if wait := verificationResendCooldown - time.Since(*registration.SentAt); wait > 0 {
+			return wait, errs.VerificationResendCooldown
+		}
+	}
+
+	token, err := generateToken(32)
+	if err != nil {
+		return 0, errors.Wrap(err, "生成验证令牌失败")
+	}
+	now := time.Now()
+	registration.Token = token
+	registration.ExpiresAt = now.Add(24 * time.Hour)
+	registration.SentAt = &now
+	registration.ResendCount++
+	if err = db.UpdateUserRegistration(registration); err != nil {
+		return 0, errors.Wrap(err, "更新注册申请失败")
+	}
+	return 0, SendVerificationEmail(registration)
+}
+
+// ApproveUserRegistration 批准已验证邮箱的注册申请，记录执行审核的管理员；
+// 同一来源批准的申请超出 registration_source_approvals 时返回 errs.RegistrationSourceLimited，force 为 true 时忽略该限制
+func ApproveUserRegistration(registrationID, adminID uint, force bool) (*model.User, error) {
+	registration, err := getUserRegistration(registrationID)
 	if err != nil {
-		return nil, errors.Wrap(err, "获取注册信息失败")
+		return nil, err
 	}
-	
-	if registration.Status != 1 {
-		return nil, errors.New("注册申请未验证或已处理")
+	if !force {
+		if err = checkRegistrationSourceLimit(registration); err != nil {
+			return nil, err
+		}
 	}
 	
-	// 创建用户
+	return activateRegistration(registration, adminID)
+}
+
+// autoActivateRegistration 开放注册模式下自动批准已验证的注册申请；其他模式或同一来源批准的申请过多时
+// 申请留在待审核队列并通知管理员
+func autoActivateRegistration(registration *model.UserRegistration) error {
+	if registrationMode() == model.RegistrationModeOpen {
+		err := checkRegistrationSourceLimit(registration)
+		if err == nil {
+			_, err = activateRegistration(registration, 0)
+			return err
+		}
+		if !errors.Is(err, errs.RegistrationSourceLimited) {
+			return err
+		}
+	}
+	notifyRegistrationPending(registration)
+	return nil
+}
+
+// getUserRegistration 根据 ID 获取注册申请
+func getUserRegistration(registrationID uint) (*model.UserRegistration, error) {
+	registration, err := db.GetUserRegistrationByID(registrationID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("注册申请不存在")
+		}
+		return nil, errors.Wrap(err, "获取注册信息失败")
+	}
+	return registration, nil
+}
+
+// transitionRegistration 将注册申请转换到目标状态，校验转换是否合法并防止并发重复处理；
+// reviewer 为执行审核的管理员，0 表示由系统自动处理
+func transitionRegistration(registration *model.UserRegistration, status int, reviewer uint) error {
+	if !registration.CanTransitionTo(status) {
+		return errs.InvalidRegistrationStatus
+	}
+	from, verifiedAt := registration.Status, registration.VerifiedAt
+	registration.Status = status
+	if status == model.RegistrationVerified {
+		now := time.Now()
+		registration.VerifiedAt = &now
+	}
+	// 批准和拒绝总是记录处理时间，用于统计同一来源批准的申请数
+	if reviewer != 0 || status == model.RegistrationRegistered || status == model.RegistrationRejected {
+		now := time.Now()
+		registration.ReviewedBy = reviewer
+		registration.ReviewedAt = &now
+	}
+	ok, err := db.TransitionUserRegistration(registration, from)
+	if err == nil && !ok {
+		err = errs.InvalidRegistrationStatus
+	}
+	if err != nil {
+		registration.Status, registration.ReviewedBy, registration.ReviewedAt = from, 0, nil
+		registration.VerifiedAt = verifiedAt
+		return errors.Wrap(err, "更新注册状态失败")
+	}
+	return nil
+}
+
+// activateRegistration 为已验证的注册申请创建用户，建立推荐和邀请关系并通知用户；
+// 先占用状态再创建用户，避免同一申请被重复批准
+func activateRegistration(registration *model.UserRegistration, reviewer uint) (*model.User, error) {
+	from := registration.Status
+	if err := transitionRegistration(registration, model.RegistrationRegistered, reviewer); err != nil {
+		return nil, err
+	}
+
+	// 按默认用户模板创建用户
+	template := defaultProvisioningTemplate()
 	user := &model.User{
 		Username:   registration.Username,
 		PwdHash:    registration.PwdHash,
 		Salt:       registration.Salt,
-		BasePath:   "/",
-		Role:       model.GENERAL, // 普通用户
+		BasePath:   template.UserBasePath(registration.Username),
+		Role:       template.Role,
 		Disabled:   false,
-		Permission: 0, // 默认权限
+		Permission: template.Permission,
+		SsoID:      registration.SsoID,
 	}
+	applyRegistrationProfile(registration, user)
 	
-	err = CreateUser(user)
+	err := CreateUser(user)
 	if err != nil {
+		// 恢复申请状态以便重新处理
+		registration.Status, registration.ReviewedBy, registration.ReviewedAt = from, 0, nil
+		if _, rerr := db.TransitionUserRegistration(registration, model.RegistrationRegistered); rerr != nil {
+			utils.Log.Errorf("failed to restore status of registration %d: %+v", registration.ID, rerr)
+		}
 		return nil, errors.Wrap(err, "创建用户失败")
 	}
 	
-	// 创建用户积分账户
-	credits := &model.UserCredits{
-		UserID:  user.ID,
-		Balance: 0, // 初始积分为0
+	applyProvisioningTemplate(template, user)
+	// 第三方登录发起的注册只来自 OIDC，角色由提供方管理
+	if registration.SsoID != "" {
+		if err = ProvisionUserIdentity(user, model.IdentityProviderOIDC, registration.SsoID, registration.Email); err != nil {
+			utils.Log.Warnf("failed to link the identity of sso user %s: %+v", user.Username, err)
+		}
 	}
-	err = db.CreateUserCredits(credits)
-	if err != nil {
-		return nil, errors.Wrap(err, "创建积分账户失败")
+	callUserApprovedHooks(user)
+	// 注册申请的邮箱均已验证
+	grantVerifiedWelcomeCredits(user)
+	bindRegistrationTerms(registration, user)
+
+	// 建立推荐关系并发放推荐奖励
+	logReferralError(BindReferral(user.ID, registration.ReferralCode))
+	bindInvitation(user.ID, registration.InviteCode)
+
+	// 仅通行密钥的账户需要登记令牌才能设置唯一的登录凭证
+	if user.PwdHash == "" {
+		if err = issuePasskeyEnrollment(registration); err != nil {
+			utils.Log.Errorf("failed to issue passkey enrollment of registration %d: %+v", registration.ID, err)
+		}
 	}
+
+	emitRegistrationWebhook(RegistrationEventApproved, registration, user.ID)
+	logMailError(SendMail(registration.Email, mail.TemplateRegistrationApproved, map[string]any{
+		"Username":     registration.Username,
+		"PasskeyToken": registration.EnrollToken,
+	}))
 	
-	// 更新注册状态为已注册
-	registration.Status = 2
-	err = db.UpdateUserRegistration(registration)
+	return user, nil
+}
+
+// CreateSSORegistration 让第三方登录的新用户走注册流程：身份已由提供方验证，
+// 开放注册模式下直接创建用户并返回已注册的记录，审核模式下等待管理员批准
+func CreateSSORegistration(ssoID, username, email, ip string) (*model.UserRegistration, error) {
+	switch registrationMode() {
+	case model.RegistrationModeClosed:
+		return nil, errs.RegistrationClosed
+	case model.RegistrationModeInviteOnly:
+		return nil, errs.InviteCodeRequired
+	}
+	email = normalizeEmail(email)
+	if email == "" {
+		return nil, errors.New("无法从第三方获取邮箱")
+	}
+	if err := checkEmailDomain(email); err != nil {
+		return nil, err
+	}
+	if existing, err := db.GetUserRegistrationByEmail(email); err == nil {
+		if existing.SsoID != ssoID {
+			return nil, errors.New("邮箱已被注册")
+		}
+		return existing, nil
+	}
+	if _, err := db.GetUserByName(username); err == nil {
+		username = username + "_" + ssoID
+	}
+	token, err := generateToken(32)
 	if err != nil {
-		return nil, errors.Wrap(err, "更新注册状态失败")
+		return nil, errors.Wrap(err, "生成验证令牌失败")
 	}
-	
-	return user, nil
+	salt := random.String(8)
+	now := time.Now()
+	registration := &model.UserRegistration{
+		Email:      email,
+		Username:   username,
+		PwdHash:    model.TwoHashPwd(random.String(16), salt),
+		Salt:       salt,
+		Status:     model.RegistrationVerified, // 第三方已验证邮箱
+		VerifiedAt: &now,
+		Token:      token,
+		SsoID:      ssoID,
+		IP:         ip,
+		Source:     registrationSource(ip),
+		ExpiresAt:  now,
+	}
+	if err = db.CreateUserRegistration(registration); err != nil {
+		return nil, errors.Wrap(err, "创建注册申请失败")
+	}
+	emitRegistrationWebhook(RegistrationEventSubmitted, registration, 0)
+	emitRegistrationWebhook(RegistrationEventVerified, registration, 0)
+	if err = autoActivateRegistration(registration); err != nil {
+		return nil, err
+	}
+	return registration, nil
 }
 
-// RejectUserRegistration 拒绝用户注册
-func RejectUserRegistration(registrationID uint) error {
-	registration, err := db.GetUserRegistrationByToken("")
+// RecordSSORegistration 为通过第三方登录自动创建的用户记录一条已注册状态的注册记录，
+// 使其邮箱可用于收据等通知，并视为已验证邮箱发放新用户积分；邮箱为空或已被其他注册记录占用时跳过
+func RecordSSORegistration(user *model.User, email string) error {
+	email = normalizeEmail(email)
+	if email == "" {
+		return nil
+	}
+	if _, err := db.GetUserRegistrationByEmail(email); err == nil {
+		return nil
+	}
+	token, err := generateToken(32)
 	if err != nil {
-		return errors.Wrap(err, "获取注册信息失败")
+		return errors.Wrap(err, "生成验证令牌失败")
 	}
-	
-	registration.Status = -1 // 已拒绝
-	err = db.UpdateUserRegistration(registration)
+	registration := &model.UserRegistration{
+		Email:     email,
+		Username:  user.Username,
+		PwdHash:   user.PwdHash,
+		Salt:      user.Salt,
+		Status:    model.RegistrationRegistered, // 第三方已验证身份
+		Token:     token,
+		ExpiresAt: time.Now(),
+	}
+	if err := db.CreateUserRegistration(registration); err != nil {
+		return errors.Wrap(err, "创建注册记录失败")
+	}
+	grantVerifiedWelcomeCredits(user)
+	return nil
+}
+
+// issuePasskeyEnrollment 为仅通行密钥的账户签发登记首个通行密钥的一次性令牌，24 小时内有效；
+// 令牌只随注册完成的响应和批准邮件发给申请人，不复用出现在验证链接中的验证令牌
+func issuePasskeyEnrollment(registration *model.UserRegistration) error {
+	token, err := generateToken(32)
 	if err != nil {
-		return errors.Wrap(err, "更新注册状态失败")
+		return errors.Wrap(err, "生成登记令牌失败")
+	}
+	expiresAt := time.Now().Add(24 * time.Hour)
+	registration.EnrollToken, registration.EnrollExpiresAt = token, &expiresAt
+	if err = db.UpdateUserRegistration(registration); err != nil {
+		registration.EnrollToken, registration.EnrollExpiresAt = "", nil
+		return errors.Wrap(err, "保存登记令牌失败")
+	}
+	return nil
+}
+
+// GetPasskeyEnrollmentUser 根据登记令牌获取可以登记首个通行密钥的用户，
+// 仅对没有密码且尚无通行密钥的账户在令牌有效期内有效
+func GetPasskeyEnrollmentUser(token string) (*model.User, error) {
+	if token == "" {
+		return nil, errors.New("无效的登记令牌")
+	}
+	registration, err := db.GetUserRegistrationByEnrollToken(token)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("无效的登记令牌")
+		}
+		return nil, errors.Wrap(err, "获取注册信息失败")
+	}
+	if registration.EnrollExpiresAt == nil || time.Now().After(*registration.EnrollExpiresAt) {
+		return nil, errors.New("登记通行密钥的期限已过，请登录后在账户设置中添加")
+	}
+	user, err := GetUserByName(registration.Username)
+	if err != nil {
+		return nil, errors.Wrap(err, "获取用户失败")
+	}
+	if user.PwdHash != "" {
+		return nil, errors.New("账户已设置密码，请登录后在账户设置中添加通行密钥")
+	}
+	count, err := db.CountWebAuthnCredentials(user.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "获取通行密钥失败")
+	}
+	if count > 0 {
+		return nil, errors.New("账户已登记通行密钥，请登录后在账户设置中添加")
+	}
+	return user, nil
+}
+
+// FinishPasskeyEnrollment 首个通行密钥保存后使登记令牌失效
+func FinishPasskeyEnrollment(token string) error {
+	if _, err := db.ClearRegistrationEnrollToken(token); err != nil {
+		return errors.Wrap(err, "使登记令牌失效失败")
+	}
+	return nil
+}
+
+// ImportDirectoryUser 创建由目录服务（LDAP）管理的用户，dn 为用户在目录中的标识。
+// 已关联该 dn 的用户或同名的本地用户直接返回，同名的本地管理员不会返回，返回 errs.DirectoryAccountProtected；
+// 目录用户跳过邮箱验证，其邮箱直接记录为已注册
+func ImportDirectoryUser(u *model.User, dn, email string) (*model.User, bool, error) {
+	if existing, err := GetUserByIdentity(model.IdentityProviderLDAP, dn); err == nil {
+		return existing, false, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+	if existing, err := GetUserByName(u.Username); err == nil {
+		if existing.IsAdmin() {
+			return nil, false, errs.DirectoryAccountProtected
+		}
+		return existing, false, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+	if err := CreateUser(u); err != nil {
+		return nil, false, errors.Wrap(err, "创建用户失败")
+	}
+	if dn != "" {
+		if err := ProvisionUserIdentity(u, model.IdentityProviderLDAP, dn, email); err != nil {
+			utils.Log.Warnf("failed to link the identity of directory user %s: %+v", u.Username, err)
+		}
+	}
+	if err := RecordSSORegistration(u, email); err != nil {
+		utils.Log.Warnf("failed to record registration of directory user %s: %+v", u.Username, err)
+	}
+	return u, true, nil
+}
+
+// RejectUserRegistration 拒绝尚未完成的注册申请，记录执行审核的管理员
+func RejectUserRegistration(registrationID, adminID uint) error {
+	registration, err := getUserRegistration(registrationID)
+	if err != nil {
+		return err
 	}
 	
+	return rejectRegistration(registration, adminID)
+}
+
+// rejectRegistration 拒绝注册申请并通知申请人
+func rejectRegistration(registration *model.UserRegistration, adminID uint) error {
+	if err := transitionRegistration(registration, model.RegistrationRejected, adminID); err != nil {
+		return err
+	}
+	emitRegistrationWebhook(RegistrationEventRejected, registration, 0)
+	logMailError(SendMail(registration.Email, mail.TemplateRegistrationRejected, map[string]any{
+		"Username": registration.Username,
+	}))
 	return nil
 }
 
-// CreateVerificationCode 创建验证码
+// verificationCodeFormat 按设置返回验证码的字符集和长度，默认为6位数字
+func verificationCodeFormat() model.RedeemCodeFormat {
+	format := model.RedeemCodeFormat{
+		Length:  int(getCreditsSettingInt(conf.VerificationCodeLength, 6)),
+		Charset: getSettingStr(conf.VerificationCodeCharset),
+	}
+	if format.Charset == model.RedeemCharsetAlnum || format.Alphabet() == "" {
+		// 验证码不区分大小写，不使用大小写混合的字符集
+		format.Charset = model.RedeemCharsetDigits
+	}
+	format.Length = min(max(format.Length, 4), 16)
+	return format
+}
+
+// CreateVerificationCode 按设置的字符集、长度和有效期创建验证码，
+// 同一邮箱和类型只保留最新的 verification_code_max_active 个有效验证码，更早的随之失效
 func CreateVerificationCode(email, codeType string) (*model.VerificationCode, error) {
-	// 生成6位数字验证码
-	code := random.String(6)
+	email = normalizeEmail(email)
+	code, err := generateRedeemCode(verificationCodeFormat())
+	if err != nil {
+		return nil, errors.Wrap(err, "生成验证码失败")
+	}
 	
 	verificationCode := &model.VerificationCode{
 		Email:     email,
 		Code:      code,
 		Type:      codeType,
 		Used:      false,
-		ExpiresAt: time.Now().Add(10 * time.Minute), // 10分钟过期
+		ExpiresAt: time.Now().Add(time.Duration(max(getCreditsSettingInt(conf.VerificationCodeTTL, 10), 1)) * time.Minute),
 	}
 	
-	err := db.CreateVerificationCode(verificationCode)
+	err = db.CreateVerificationCode(verificationCode)
 	if err != nil {
 		return nil, errors.Wrap(err, "创建验证码失败")
 	}
+	maxActive := max(int(getCreditsSettingInt(conf.VerificationCodeMaxActive, 1)), 1)
+	if err = db.InvalidateVerificationCodes(email, codeType, maxActive); err != nil {
+		return nil, errors.Wrap(err, "作废旧验证码失败")
+	}
 	
 	return verificationCode, nil
 }
 
-// VerifyCode 验证验证码
-func VerifyCode(email, code, codeType string) error {
-	verificationCode, err := db.GetVerificationCode(email, codeType)
+// findVerificationCode 在邮箱的有效验证码中查找与 code 相同的一个，忽略大小写；
+// 不匹配时返回 errs.InvalidVerificationCode 并记录一次失败，失败次数达到 verification_code_max_attempts 时
+// 邮箱的有效验证码全部锁定并返回 errs.VerificationCodeLocked，需要重新获取验证码
+func findVerificationCode(email, codeType, code string) (*model.VerificationCode, error) {
+	email, code = normalizeEmail(email), strings.TrimSpace(code)
+	if email == "" {
+		return nil, errs.InvalidVerificationCode
+	}
+	codes, err := db.GetActiveVerificationCodes(email, codeType)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("验证码不存在或已过期")
+		return nil, errors.Wrap(err, "获取验证码失败")
+	}
+	if len(codes) == 0 {
+		return nil, errs.InvalidVerificationCode
+	}
+	for i := range codes {
+		if code != "" && strings.EqualFold(codes[i].Code, code) {
+			return &codes[i], nil
 		}
-		return errors.Wrap(err, "获取验证码失败")
 	}
-	
-	if !verificationCode.CanUse() {
-		return errors.New("验证码已使用或已过期")
+	locked, err := db.RecordVerificationCodeFailure(email, codeType, int(getCreditsSettingInt(conf.VerificationCodeMaxAttempts, 5)))
+	if err != nil {
+		utils.Log.Errorf("failed to record the failed verification of %s: %+v", email, err)
 	}
-	
-	if verificationCode.Code != code {
-		return errors.New("验证码错误")
+	if locked {
+		return nil, errs.VerificationCodeLocked
+	}
+	return nil, errs.InvalidVerificationCode
+}
+
+// VerifyCode 验证验证码
+func VerifyCode(email, code, codeType string) error {
+	verificationCode, err := findVerificationCode(email, codeType, code)
+	if err != nil {
+		return err
 	}
 	
 	// 标记为已使用
@@ -196,9 +608,17 @@ func VerifyCode(email, code, codeType string) error {
 	return nil
 }
 
-// GetPendingRegistrations 获取待处理的注册申请
-func GetPendingRegistrations(page, pageSize int) ([]model.UserRegistration, int64, error) {
-	return db.GetPendingRegistrations(page, pageSize)
+// GetPendingRegistrations 获取待处理的注册申请，并标记来源可疑的申请
+func GetPendingRegistrations(page, pageSize int) ([]model.PendingRegistration, int64, error) {
+	registrations, total, err := db.GetPendingRegistrations(page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	pending, err := flagPendingRegistrations(registrations)
+	if err != nil {
+		return nil, 0, err
+	}
+	return pending, total, nil
 }
 
 // CleanExpiredData 清理过期数据
@@ -226,17 +646,24 @@ func generateToken(length int) (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
-// SendVerificationEmail 发送验证邮件（占位函数，需要实现邮件发送逻辑）
-func SendVerificationEmail(email, token string) error {
-	// TODO: 实现邮件发送逻辑
-	verifyURL := fmt.Sprintf("http://localhost:5244/api/auth/verify?token=%s", token)
-	utils.Log.Infof("发送验证邮件到 %s，验证链接: %s", email, verifyURL)
-	return nil
+// SendVerificationEmail 发送注册验证邮件
+func SendVerificationEmail(registration *model.UserRegistration) error {
+	return SendMail(registration.Email, mail.TemplateVerification, map[string]any{
+		"Username":  registration.Username,
+		"URL":       verificationURL(registration),
+		"ExpiresAt": registration.ExpiresAt,
+	})
 }
 
-// SendVerificationCode 发送验证码（占位函数，需要实现邮件发送逻辑）
-func SendVerificationCode(email, code string) error {
-	// TODO: 实现邮件发送逻辑
-	utils.Log.Infof("发送验证码到 %s，验证码: %s", email, code)
-	return nil
-}
\ No newline at end of file
+// verificationURL 返回注册申请的邮箱验证链接
+func verificationURL(registration *model.UserRegistration) string {
+	return fmt.Sprintf("%s/api/register/verify?token=%s", siteURL(), registration.Token)
+}
+
+// SendVerificationCode 发送邮箱验证码
+func SendVerificationCode(code *model.VerificationCode) error {
+	return SendMail(code.Email, mail.TemplateVerificationCode, map[string]any{
+		"Code":      code.Code,
+		"ExpiresAt": code.ExpiresAt,
+	})
+}
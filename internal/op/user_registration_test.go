@@ -0,0 +1,526 @@
+package op_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/pkg/errors"
+)
+
+func setRegistrationMode(t *testing.T, mode string) {
+	if err := op.SaveSettingItem(&model.SettingItem{Key: conf.RegistrationMode, Value: mode, Type: conf.TypeSelect}); err != nil {
+		t.Fatalf("failed to save setting: %+v", err)
+	}
+}
+
+func TestRegistrationModes(t *testing.T) {
+	defer setRegistrationMode(t, model.RegistrationModeApproval)
+
+	setRegistrationMode(t, model.RegistrationModeClosed)
+	if _, err := op.CreateUserRegistration("closed@example.com", "reg_closed", "password", "", "", "", ""); !errors.Is(err, errs.RegistrationClosed) {
+		t.Errorf("expected registration to be closed, got %v", err)
+	}
+
+	setRegistrationMode(t, model.RegistrationModeApproval)
+	registration, err := op.CreateUserRegistration("approval@example.com", "reg_approval", "password", "", "", "", "")
+	if err != nil {
+		t.Fatalf("failed to register: %+v", err)
+	}
+	if registration, err = op.VerifyUserRegistration(registration.Token); err != nil {
+		t.Fatalf("failed to verify registration: %+v", err)
+	}
+	if registration.Status != model.RegistrationVerified {
+		t.Errorf("expected the registration to wait for approval, got status %d", registration.Status)
+	}
+	if _, err = op.GetUserByName("reg_approval"); err == nil {
+		t.Errorf("expected no user before approval")
+	}
+
+	setRegistrationMode(t, model.RegistrationModeOpen)
+	registration, err = op.CreateUserRegistration("open@example.com", "reg_open", "password", "", "", "", "")
+	if err != nil {
+		t.Fatalf("failed to register: %+v", err)
+	}
+	if registration, err = op.VerifyUserRegistration(registration.Token); err != nil {
+		t.Fatalf("failed to verify registration: %+v", err)
+	}
+	if registration.Status != model.RegistrationRegistered {
+		t.Errorf("expected the registration to be completed, got status %d", registration.Status)
+	}
+	if _, err = op.GetUserByName("reg_open"); err != nil {
+		t.Errorf("expected the user to be created once verified: %v", err)
+	}
+}
+
+func TestRecordSSORegistration(t *testing.T) {
+	user := &model.User{Username: "sso_user", Password: "password", SsoID: "sso-4357"}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if err := op.RecordSSORegistration(user, "sso@example.com"); err != nil {
+		t.Fatalf("failed to record registration: %+v", err)
+	}
+	// an email that is already recorded is skipped
+	if err := op.RecordSSORegistration(user, "sso@example.com"); err != nil {
+		t.Fatalf("expected duplicate email to be skipped, got %+v", err)
+	}
+	registration, err := db.GetUserRegistrationByUsername("sso_user")
+	if err != nil {
+		t.Fatalf("failed to get registration: %+v", err)
+	}
+	if registration.Email != "sso@example.com" || registration.Status != model.RegistrationRegistered {
+		t.Errorf("unexpected registration: %+v", registration)
+	}
+}
+
+func TestCreateSSORegistration(t *testing.T) {
+	defer setRegistrationMode(t, model.RegistrationModeApproval)
+
+	setRegistrationMode(t, model.RegistrationModeClosed)
+	if _, err := op.CreateSSORegistration("oidc-closed", "oidc_closed", "oidc_closed@example.com", ""); !errors.Is(err, errs.RegistrationClosed) {
+		t.Errorf("expected registration to be closed, got %v", err)
+	}
+
+	setRegistrationMode(t, model.RegistrationModeApproval)
+	registration, err := op.CreateSSORegistration("oidc-approval", "oidc_approval", "oidc_approval@example.com", "")
+	if err != nil {
+		t.Fatalf("failed to register: %+v", err)
+	}
+	if registration.Status != model.RegistrationVerified {
+		t.Errorf("expected the registration to wait for approval, got status %d", registration.Status)
+	}
+	// logging in again returns the same pending registration
+	again, err := op.CreateSSORegistration("oidc-approval", "oidc_approval", "oidc_approval@example.com", "")
+	if err != nil || again.ID != registration.ID {
+		t.Errorf("expected the pending registration, got %+v, %v", again, err)
+	}
+
+	setRegistrationMode(t, model.RegistrationModeOpen)
+	registration, err = op.CreateSSORegistration("oidc-open", "oidc_open", "oidc_open@example.com", "")
+	if err != nil {
+		t.Fatalf("failed to register: %+v", err)
+	}
+	if registration.Status != model.RegistrationRegistered {
+		t.Errorf("expected the registration to be activated, got status %d", registration.Status)
+	}
+	user, err := op.GetUserByName("oidc_open")
+	if err != nil {
+		t.Fatalf("expected the user to be created: %+v", err)
+	}
+	if user.SsoID != "oidc-open" {
+		t.Errorf("expected the sso identity to be linked, got %q", user.SsoID)
+	}
+
+	if err = op.SyncSSORole(user, model.IdentityProviderLDAP, true); err != nil {
+		t.Fatalf("failed to sync role: %+v", err)
+	}
+	if user, _ = op.GetUserByName("oidc_open"); user.IsAdmin() {
+		t.Errorf("expected the role not to be synced from another provider")
+	}
+	if err = op.SyncSSORole(user, model.IdentityProviderOIDC, true); err != nil {
+		t.Fatalf("failed to sync role: %+v", err)
+	}
+	if user, _ = op.GetUserByName("oidc_open"); !user.IsAdmin() {
+		t.Errorf("expected the user to be promoted")
+	}
+	if admins, _ := db.CountUsersByRole(model.ADMIN); admins == 1 {
+		if err = op.SyncSSORole(user, model.IdentityProviderOIDC, false); err != nil {
+			t.Fatalf("failed to sync role: %+v", err)
+		}
+		if user, _ = op.GetUserByName("oidc_open"); !user.IsAdmin() {
+			t.Errorf("expected the last admin not to be demoted")
+		}
+	}
+	other := &model.User{Username: "oidc_other_admin", Role: model.ADMIN}
+	if err = op.CreateUser(other); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if err = op.SyncSSORole(user, model.IdentityProviderOIDC, false); err != nil {
+		t.Fatalf("failed to sync role: %+v", err)
+	}
+	if user, _ = op.GetUserByName("oidc_open"); user.IsAdmin() {
+		t.Errorf("expected the user to be demoted")
+	}
+
+	// a local account that linked the identity keeps its role
+	local := &model.User{Username: "oidc_local", Role: model.GENERAL}
+	if err = op.CreateUser(local); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if _, err = op.LinkUserIdentity(local, model.IdentityProviderOIDC, "oidc-local", ""); err != nil {
+		t.Fatalf("failed to link identity: %+v", err)
+	}
+	if err = op.SyncSSORole(local, model.IdentityProviderOIDC, true); err != nil {
+		t.Fatalf("failed to sync role: %+v", err)
+	}
+	if local, _ = op.GetUserByName("oidc_local"); local.IsAdmin() {
+		t.Errorf("expected a linked local account not to be promoted")
+	}
+}
+
+func TestImportDirectoryUser(t *testing.T) {
+	user, created, err := op.ImportDirectoryUser(&model.User{Username: "ldap_user", Password: "password"}, "uid=ldap_user,dc=example", "ldap@example.com")
+	if err != nil || !created {
+		t.Fatalf("expected the user to be created, got %v, %v", created, err)
+	}
+	if _, created, err = op.ImportDirectoryUser(&model.User{Username: "ldap_user", Password: "password"}, "uid=ldap_user,dc=example", "ldap@example.com"); err != nil || created {
+		t.Errorf("expected the existing user to be kept, got %v, %v", created, err)
+	}
+	if managed, _ := op.IsProvisionedBy(user.ID, model.IdentityProviderLDAP); !managed {
+		t.Errorf("expected the imported user to be managed by the directory")
+	}
+
+	// local accounts of the same name aren't managed by the directory, admins aren't returned at all
+	local := &model.User{Username: "ldap_local", Password: "password"}
+	if err = op.CreateUser(local); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	existing, created, err := op.ImportDirectoryUser(&model.User{Username: "ldap_local"}, "uid=ldap_local,dc=example", "")
+	if err != nil || created || existing.ID != local.ID {
+		t.Errorf("expected the local user to be returned, got %v, %v", created, err)
+	}
+	if managed, _ := op.IsProvisionedBy(local.ID, model.IdentityProviderLDAP); managed {
+		t.Errorf("expected the local user not to be managed by the directory")
+	}
+	admin := &model.User{Username: "ldap_admin", Password: "password", Role: model.ADMIN}
+	if err = op.CreateUser(admin); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if _, _, err = op.ImportDirectoryUser(&model.User{Username: "ldap_admin"}, "uid=ldap_admin,dc=example", ""); !errors.Is(err, errs.DirectoryAccountProtected) {
+		t.Errorf("expected the admin account to be protected, got %v", err)
+	}
+	registration, err := db.GetUserRegistrationByUsername(user.Username)
+	if err != nil {
+		t.Fatalf("failed to get registration: %+v", err)
+	}
+	if registration.Status != model.RegistrationRegistered {
+		t.Errorf("expected directory users to skip verification, got status %d", registration.Status)
+	}
+}
+
+func TestReviewRegistration(t *testing.T) {
+	setRegistrationMode(t, model.RegistrationModeApproval)
+
+	registration, err := op.CreateUserRegistration("review@example.com", "reg_review", "password", "", "", "", "")
+	if err != nil {
+		t.Fatalf("failed to register: %+v", err)
+	}
+	if _, err = op.ApproveUserRegistration(registration.ID, 1, false); !errors.Is(err, errs.InvalidRegistrationStatus) {
+		t.Errorf("expected an unverified registration not to be approved, got %v", err)
+	}
+	if _, err = op.VerifyUserRegistration(registration.Token); err != nil {
+		t.Fatalf("failed to verify registration: %+v", err)
+	}
+	user, err := op.ApproveUserRegistration(registration.ID, 1, false)
+	if err != nil {
+		t.Fatalf("failed to approve registration: %+v", err)
+	}
+	if user.Username != "reg_review" {
+		t.Errorf("expected the user of the registration, got %s", user.Username)
+	}
+	if _, err = op.ApproveUserRegistration(registration.ID, 1, false); !errors.Is(err, errs.InvalidRegistrationStatus) {
+		t.Errorf("expected a registration to be approved once, got %v", err)
+	}
+	if err = op.RejectUserRegistration(registration.ID, 1); !errors.Is(err, errs.InvalidRegistrationStatus) {
+		t.Errorf("expected a completed registration not to be rejected, got %v", err)
+	}
+	approved, err := db.GetUserRegistrationByID(registration.ID)
+	if err != nil {
+		t.Fatalf("failed to get registration: %+v", err)
+	}
+	if approved.ReviewedBy != 1 || approved.ReviewedAt == nil {
+		t.Errorf("expected the reviewer to be recorded, got %+v", approved)
+	}
+
+	registration, err = op.CreateUserRegistration("rejected@example.com", "reg_rejected", "password", "", "", "", "")
+	if err != nil {
+		t.Fatalf("failed to register: %+v", err)
+	}
+	if err = op.RejectUserRegistration(registration.ID, 1); err != nil {
+		t.Fatalf("failed to reject registration: %+v", err)
+	}
+	if _, err = op.ApproveUserRegistration(registration.ID, 1, false); !errors.Is(err, errs.InvalidRegistrationStatus) {
+		t.Errorf("expected a rejected registration not to be approved, got %v", err)
+	}
+}
+
+func TestRegistrationEmailDomains(t *testing.T) {
+	save := func(key, value string) {
+		if err := op.SaveSettingItem(&model.SettingItem{Key: key, Value: value, Type: conf.TypeText}); err != nil {
+			t.Fatalf("failed to save setting: %+v", err)
+		}
+	}
+	defer save(conf.EmailAllowedDomains, "")
+	defer save(conf.EmailBlockedDomains, "")
+
+	save(conf.EmailAllowedDomains, "example.edu")
+	save(conf.EmailBlockedDomains, "spam.example.edu")
+	if _, err := op.CreateUserRegistration("student@gmail.com", "domain_other", "password", "", "", "", ""); !errors.Is(err, errs.EmailDomainNotAllowed) {
+		t.Errorf("expected other domains to be refused, got %v", err)
+	}
+	if _, err := op.CreateUserRegistration("bot@spam.example.edu", "domain_blocked", "password", "", "", "", ""); !errors.Is(err, errs.EmailDomainBlocked) {
+		t.Errorf("expected the blocked subdomain to be refused, got %v", err)
+	}
+	if _, err := op.CreateUserRegistration("student@cs.Example.edu", "domain_allowed", "password", "", "", "", ""); err != nil {
+		t.Errorf("expected a subdomain of an allowed domain to register, got %v", err)
+	}
+}
+
+func TestResendVerificationEmail(t *testing.T) {
+	setRegistrationMode(t, model.RegistrationModeApproval)
+
+	registration, err := op.CreateUserRegistration("resend@example.com", "reg_resend", "password", "", "", "", "")
+	if err != nil {
+		t.Fatalf("failed to register: %+v", err)
+	}
+	if wait, err := op.ResendVerificationEmail("resend@example.com"); !errors.Is(err, errs.VerificationResendCooldown) || wait <= 0 {
+		t.Errorf("expected the cooldown to apply right after registering, got %v, %v", wait, err)
+	}
+
+	// move the last mail out of the cooldown
+	sentAt := registration.SentAt.Add(-time.Hour)
+	registration.SentAt = &sentAt
+	if err = db.UpdateUserRegistration(registration); err != nil {
+		t.Fatalf("failed to update registration: %+v", err)
+	}
+	// sending fails without a mail provider, the token is renewed anyway
+	_, _ = op.ResendVerificationEmail("resend@example.com")
+	if _, err = op.VerifyUserRegistration(registration.Token); err == nil {
+		t.Errorf("expected the old verification link to stop working")
+	}
+	resent, err := db.GetUserRegistrationByEmail("resend@example.com")
+	if err != nil {
+		t.Fatalf("failed to get registration: %+v", err)
+	}
+	if resent.ResendCount != 1 {
+		t.Errorf("expected the resend to be counted, got %d", resent.ResendCount)
+	}
+	if _, err = op.VerifyUserRegistration(resent.Token); err != nil {
+		t.Errorf("expected the new verification link to work: %+v", err)
+	}
+}
+
+func TestRegistrationSourceLimit(t *testing.T) {
+	setRegistrationMode(t, model.RegistrationModeOpen)
+	defer setRegistrationMode(t, model.RegistrationModeApproval)
+	if err := op.SaveSettingItem(&model.SettingItem{Key: conf.RegistrationSourceApprovals, Value: "1", Type: conf.TypeNumber}); err != nil {
+		t.Fatalf("failed to save setting: %+v", err)
+	}
+	defer op.SaveSettingItem(&model.SettingItem{Key: conf.RegistrationSourceApprovals, Value: "0", Type: conf.TypeNumber})
+
+	var registrations []*model.UserRegistration
+	for i, ip := range []string{"203.0.113.5", "203.0.113.9", "203.0.113.20"} {
+		registration, err := op.CreateUserRegistration(fmt.Sprintf("source%d@example.com", i+1), fmt.Sprintf("source_bot%d", i+1), "password", "", "", "", ip)
+		if err != nil {
+			t.Fatalf("failed to register: %+v", err)
+		}
+		if registration.Source != "203.0.113.0/24" {
+			t.Errorf("unexpected source %s", registration.Source)
+		}
+		if registration, err = op.VerifyUserRegistration(registration.Token); err != nil {
+			t.Fatalf("failed to verify registration: %+v", err)
+		}
+		registrations = append(registrations, registration)
+	}
+	if registrations[0].Status != model.RegistrationRegistered || registrations[1].Status != model.RegistrationVerified {
+		t.Fatalf("expected only the first registration of the subnet to be approved automatically")
+	}
+	if _, err := op.ApproveUserRegistration(registrations[1].ID, 1, false); !errors.Is(err, errs.RegistrationSourceLimited) {
+		t.Errorf("expected the approval to be limited, got %v", err)
+	}
+	if _, err := op.ApproveUserRegistration(registrations[1].ID, 1, true); err != nil {
+		t.Errorf("expected a forced approval to succeed: %+v", err)
+	}
+
+	pending, _, err := op.GetPendingRegistrations(1, 100)
+	if err != nil {
+		t.Fatalf("failed to get pending registrations: %+v", err)
+	}
+	for _, p := range pending {
+		if p.ID != registrations[2].ID {
+			continue
+		}
+		if p.SourceCount != 3 || len(p.Flags) != 2 {
+			t.Errorf("expected the registration to be flagged, got %d registrations and flags %v", p.SourceCount, p.Flags)
+		}
+		return
+	}
+	t.Errorf("expected the limited registration to wait for approval")
+}
+
+func TestCreateUserRegistrationFields(t *testing.T) {
+	setRegistrationMode(t, model.RegistrationModeApproval)
+
+	// the username and the email passed in the wrong order are refused
+	if _, err := op.CreateUserRegistration("reg_swapped", "swapped@example.com", "password", "", "", "", ""); err == nil {
+		t.Errorf("expected a username in place of the email to be refused")
+	}
+	registration, err := op.CreateUserRegistration("fields@example.com", "reg_fields", "password", " I run the lab's file server ", "", "", "")
+	if err != nil {
+		t.Fatalf("failed to register: %+v", err)
+	}
+	if registration.Email != "fields@example.com" || registration.Username != "reg_fields" {
+		t.Errorf("unexpected email %s and username %s", registration.Email, registration.Username)
+	}
+	if _, err = op.VerifyUserRegistration(registration.Token); err != nil {
+		t.Fatalf("failed to verify registration: %+v", err)
+	}
+	pending, _, err := op.GetPendingRegistrations(1, 100)
+	if err != nil {
+		t.Fatalf("failed to get pending registrations: %+v", err)
+	}
+	for _, p := range pending {
+		if p.ID == registration.ID {
+			if p.Reason != "I run the lab's file server" {
+				t.Errorf("expected the reason to be shown in the pending list, got %q", p.Reason)
+			}
+			return
+		}
+	}
+	t.Errorf("expected the registration in the pending list")
+}
+
+func TestBatchReviewRegistrations(t *testing.T) {
+	setRegistrationMode(t, model.RegistrationModeApproval)
+
+	var ids []uint
+	for i := 1; i <= 3; i++ {
+		registration, err := op.CreateUserRegistration(fmt.Sprintf("batch%d@example.com", i), fmt.Sprintf("reg_batch%d", i), "password", "", "", "", "")
+		if err != nil {
+			t.Fatalf("failed to register: %+v", err)
+		}
+		// leave the last registration unverified so that it can't be approved
+		if i < 3 {
+			if _, err = op.VerifyUserRegistration(registration.Token); err != nil {
+				t.Fatalf("failed to verify registration: %+v", err)
+			}
+		}
+		ids = append(ids, registration.ID)
+	}
+
+	results, err := op.BatchApproveRegistrations(ids, 1, false, true)
+	if err != nil {
+		t.Fatalf("failed to approve registrations: %+v", err)
+	}
+	for i, status := range []string{model.ReviewResultSkipped, model.ReviewResultSkipped, model.ReviewResultFailed} {
+		if results[i].Status != status {
+			t.Errorf("atomic batch: expected registration %d to be %s, got %s", i+1, status, results[i].Status)
+		}
+	}
+
+	if results, err = op.BatchApproveRegistrations(ids, 1, false, false); err != nil {
+		t.Fatalf("failed to approve registrations: %+v", err)
+	}
+	for i, status := range []string{model.ReviewResultApproved, model.ReviewResultApproved, model.ReviewResultFailed} {
+		if results[i].Status != status {
+			t.Errorf("expected registration %d to be %s, got %s", i+1, status, results[i].Status)
+		}
+	}
+	if results[2].Code != "invalid_registration_status" || results[0].UserID == 0 {
+		t.Errorf("unexpected results %+v", results)
+	}
+
+	if results, err = op.BatchRejectRegistrations(ids[2:], 1, false); err != nil || results[0].Status != model.ReviewResultRejected {
+		t.Errorf("expected the registration to be rejected, got %+v, %v", results, err)
+	}
+}
+
+func TestVerificationCodes(t *testing.T) {
+	settings := []model.SettingItem{
+		{Key: conf.VerificationCodeCharset, Value: model.RedeemCharsetUnambiguous, Type: conf.TypeSelect},
+		{Key: conf.VerificationCodeLength, Value: "8", Type: conf.TypeNumber},
+		{Key: conf.VerificationCodeMaxActive, Value: "2", Type: conf.TypeNumber},
+	}
+	for i := range settings {
+		if err := op.SaveSettingItem(&settings[i]); err != nil {
+			t.Fatalf("failed to save setting: %+v", err)
+		}
+	}
+	defer func() {
+		for _, item := range []model.SettingItem{
+			{Key: conf.VerificationCodeCharset, Value: model.RedeemCharsetDigits, Type: conf.TypeSelect},
+			{Key: conf.VerificationCodeLength, Value: "6", Type: conf.TypeNumber},
+			{Key: conf.VerificationCodeMaxActive, Value: "1", Type: conf.TypeNumber},
+		} {
+			_ = op.SaveSettingItem(&item)
+		}
+	}()
+
+	var codes []*model.VerificationCode
+	for i := 0; i < 3; i++ {
+		code, err := op.CreateVerificationCode("codes@example.com", model.VerificationCodeRegister)
+		if err != nil {
+			t.Fatalf("failed to create code: %+v", err)
+		}
+		if len(code.Code) != 8 {
+			t.Errorf("expected a code of 8 characters, got %s", code.Code)
+		}
+		codes = append(codes, code)
+	}
+	if err := op.VerifyCode("codes@example.com", codes[0].Code, model.VerificationCodeRegister); err == nil {
+		t.Errorf("expected the oldest code to be invalidated")
+	}
+	if err := op.VerifyCode("codes@example.com", strings.ToLower(codes[1].Code), model.VerificationCodeRegister); err != nil {
+		t.Errorf("expected an older active code to be accepted case-insensitively, got %v", err)
+	}
+	if err := op.VerifyCode("codes@example.com", codes[1].Code, model.VerificationCodeRegister); err == nil {
+		t.Errorf("expected a used code to be rejected")
+	}
+	if err := op.VerifyCode("codes@example.com", codes[2].Code, model.VerificationCodeRegister); err != nil {
+		t.Errorf("expected the newest code to be accepted, got %v", err)
+	}
+}
+
+func TestGetUserByLogin(t *testing.T) {
+	user := &model.User{Username: "login_user", Password: "password"}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if err := op.RecordSSORegistration(user, " Login.User@Example.com "); err != nil {
+		t.Fatalf("failed to record registration: %+v", err)
+	}
+	for _, login := range []string{"login_user", "login.user@example.com", "LOGIN.USER@EXAMPLE.COM"} {
+		if found, err := op.GetUserByLogin(login); err != nil || found.Username != "login_user" {
+			t.Errorf("expected %s to resolve to login_user, got %v, %v", login, found, err)
+		}
+	}
+	if _, err := op.GetUserByLogin("nobody@example.com"); err == nil {
+		t.Errorf("expected an unknown email to fail")
+	}
+	if _, err := op.CreateUserRegistration("LOGIN.USER@example.com", "login_other", "password", "", "", "", ""); err == nil || err.Error() != "邮箱已被注册" {
+		t.Errorf("expected an email differing only in case to be taken, got %v", err)
+	}
+}
+
+func TestVerificationCodeLockout(t *testing.T) {
+	code, err := op.CreateVerificationCode("lockout@example.com", model.VerificationCodeRegister)
+	if err != nil {
+		t.Fatalf("failed to create code: %+v", err)
+	}
+	for i := 1; i < 5; i++ {
+		if err = op.VerifyCode("lockout@example.com", "wrong", model.VerificationCodeRegister); !errors.Is(err, errs.InvalidVerificationCode) {
+			t.Fatalf("attempt %d: expected an invalid code, got %v", i, err)
+		}
+	}
+	if err = op.VerifyCode("lockout@example.com", "wrong", model.VerificationCodeRegister); !errors.Is(err, errs.VerificationCodeLocked) {
+		t.Fatalf("expected the code to be locked, got %v", err)
+	}
+	if err = op.VerifyCode("lockout@example.com", code.Code, model.VerificationCodeRegister); err == nil {
+		t.Errorf("expected the locked code to be rejected")
+	}
+
+	code, err = op.CreateVerificationCode("lockout@example.com", model.VerificationCodeRegister)
+	if err != nil {
+		t.Fatalf("failed to create code: %+v", err)
+	}
+	if err = op.VerifyCode("lockout@example.com", code.Code, model.VerificationCodeRegister); err != nil {
+		t.Errorf("expected a new code to be accepted, got %v", err)
+	}
+}
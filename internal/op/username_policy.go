@@ -0,0 +1,45 @@
+package op
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// CheckUsername 按用户名策略检查注册或由管理员创建的新用户名：长度、username_pattern、保留用户名以及唯一性。
+// userID 为改名的用户，新用户为 0
+func CheckUsername(username string, userID uint) error {
+	if n := utf8.RuneCountInString(username); n < int(getCreditsSettingInt(conf.UsernameMinLength, 3)) ||
+		n > int(getCreditsSettingInt(conf.UsernameMaxLength, 50)) {
+		return errs.UsernameLength
+	}
+	if pattern := strings.TrimSpace(getSettingStr(conf.UsernamePattern)); pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			utils.Log.Warnf("invalid %s: %+v", conf.UsernamePattern, err)
+		} else if !re.MatchString(username) {
+			return errs.UsernameInvalid
+		}
+	}
+	for _, reserved := range strings.FieldsFunc(getSettingStr(conf.ReservedUsernames), func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r' || r == ' '
+	}) {
+		if strings.EqualFold(reserved, username) {
+			return errs.UsernameReserved
+		}
+	}
+	taken, err := db.UsernameTaken(username, userID, getCreditsSettingBool(conf.UsernameCaseInsensitive))
+	if err != nil {
+		return errors.Wrap(err, "检查用户名失败")
+	}
+	if taken {
+		return errs.UsernameTaken
+	}
+	return nil
+}
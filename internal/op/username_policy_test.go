@@ -0,0 +1,52 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/pkg/errors"
+)
+
+func TestCheckUsername(t *testing.T) {
+	settings := map[string]string{
+		conf.UsernamePattern:         `^[a-zA-Z0-9_]+$`,
+		conf.ReservedUsernames:       "admin\nroot, api",
+		conf.UsernameCaseInsensitive: "true",
+	}
+	for key, value := range settings {
+		if err := op.SaveSettingItem(&model.SettingItem{Key: key, Value: value, Type: conf.TypeString}); err != nil {
+			t.Fatalf("failed to save setting: %+v", err)
+		}
+	}
+	defer op.SaveSettingItem(&model.SettingItem{Key: conf.UsernamePattern, Value: "", Type: conf.TypeString})
+
+	user := &model.User{Username: "Policy_Taken", Role: model.GENERAL}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	cases := []struct {
+		username string
+		err      error
+	}{
+		{"ab", errs.UsernameLength},
+		{"policy user", errs.UsernameInvalid},
+		{"Root", errs.UsernameReserved},
+		{"API", errs.UsernameReserved},
+		{"policy_taken", errs.UsernameTaken},
+		{"policy_free", nil},
+	}
+	for _, c := range cases {
+		if err := op.CheckUsername(c.username, 0); !errors.Is(err, c.err) {
+			t.Errorf("username %q: expected %v, got %v", c.username, c.err, err)
+		}
+	}
+	if err := op.CheckUsername("POLICY_TAKEN", user.ID); err != nil {
+		t.Errorf("expected a user to change the case of its own username: %v", err)
+	}
+	if _, err := op.CreateUserRegistration("policy@example.com", "Policy_Taken", "password", "", "", "", ""); !errors.Is(err, errs.UsernameTaken) {
+		t.Errorf("expected the registration to apply the username policy, got %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package op
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/pkg/errors"
+)
+
+// GetAuthnUser loads the passkeys of a user for a webauthn ceremony
+func GetAuthnUser(u *model.User) (*model.AuthnUser, error) {
+	stored, err := db.GetWebAuthnCredentials(u.ID)
+	if err != nil {
+		return nil, err
+	}
+	credentials := make([]webauthn.Credential, 0, len(stored))
+	for _, s := range stored {
+		var credential webauthn.Credential
+		if err = utils.Json.Unmarshal([]byte(s.Data), &credential); err != nil {
+			return nil, errors.Wrapf(err, "failed decode passkey %d", s.ID)
+		}
+		credentials = append(credentials, credential)
+	}
+	return &model.AuthnUser{User: u, Credentials: credentials}, nil
+}
+
+func RegisterAuthn(u *model.User, credential *webauthn.Credential) error {
+	return db.CreateWebAuthnCredential(u.ID, credential)
+}
+
+// RemoveAuthn removes a passkey of a user. The last passkey of an account
+// without a password can't be removed, as it is the only way to sign in.
+func RemoveAuthn(u *model.User, credentialID string) error {
+	if u.PwdHash == "" {
+		count, err := db.CountWebAuthnCredentials(u.ID)
+		if err != nil {
+			return err
+		}
+		if count <= 1 {
+			return errors.New("can't remove the last passkey of an account without a password")
+		}
+	}
+	return db.DeleteWebAuthnCredential(u.ID, credentialID)
+}
@@ -0,0 +1,81 @@
+package op_test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+func TestPasskeyOnlyRegistration(t *testing.T) {
+	defer setRegistrationMode(t, model.RegistrationModeApproval)
+	setRegistrationMode(t, model.RegistrationModeOpen)
+
+	registration, err := op.CreateUserRegistration("passkey@example.com", "passkey_user", "", "", "", "", "")
+	if err != nil {
+		t.Fatalf("failed to register: %+v", err)
+	}
+	if _, err = op.GetPasskeyEnrollmentUser(registration.Token); err == nil {
+		t.Errorf("expected enrollment to wait for the registration to complete")
+	}
+	verified, err := op.VerifyUserRegistration(registration.Token)
+	if err != nil {
+		t.Fatalf("failed to verify registration: %+v", err)
+	}
+	if verified.EnrollToken == "" || verified.EnrollToken == registration.Token {
+		t.Fatalf("expected a separate enrollment token once the registration completes")
+	}
+	if _, err = op.GetPasskeyEnrollmentUser(registration.Token); err == nil {
+		t.Errorf("expected the verification token not to enroll passkeys")
+	}
+	user, err := op.GetPasskeyEnrollmentUser(verified.EnrollToken)
+	if err != nil {
+		t.Fatalf("failed to get enrollment user: %+v", err)
+	}
+	if user.ValidateRawPassword("") == nil {
+		t.Errorf("expected a passkey only account to reject password login")
+	}
+
+	credential := &webauthn.Credential{ID: []byte("passkey-1")}
+	if err = op.RegisterAuthn(user, credential); err != nil {
+		t.Fatalf("failed to register passkey: %+v", err)
+	}
+	if err = op.FinishPasskeyEnrollment(verified.EnrollToken); err != nil {
+		t.Fatalf("failed to finish enrollment: %+v", err)
+	}
+	if _, err = op.GetPasskeyEnrollmentUser(verified.EnrollToken); err == nil {
+		t.Errorf("expected the enrollment token to be single use")
+	}
+	authnUser, err := op.GetAuthnUser(user)
+	if err != nil {
+		t.Fatalf("failed to load passkeys: %+v", err)
+	}
+	if len(authnUser.WebAuthnCredentials()) != 1 {
+		t.Errorf("expected 1 passkey, got %d", len(authnUser.WebAuthnCredentials()))
+	}
+	if err = op.RemoveAuthn(user, base64.StdEncoding.EncodeToString(credential.ID)); err == nil {
+		t.Errorf("expected the last passkey of a passkey only account to be kept")
+	}
+}
+
+func TestPasskeyEnrollmentRequiresPasskeyOnlyAccount(t *testing.T) {
+	defer setRegistrationMode(t, model.RegistrationModeApproval)
+	setRegistrationMode(t, model.RegistrationModeOpen)
+
+	registration, err := op.CreateUserRegistration("password_enroll@example.com", "password_enroll", "password", "", "", "", "")
+	if err != nil {
+		t.Fatalf("failed to register: %+v", err)
+	}
+	verified, err := op.VerifyUserRegistration(registration.Token)
+	if err != nil {
+		t.Fatalf("failed to verify registration: %+v", err)
+	}
+	if verified.EnrollToken != "" {
+		t.Errorf("expected no enrollment token for an account with a password")
+	}
+	if _, err = op.GetPasskeyEnrollmentUser(registration.Token); err == nil {
+		t.Errorf("expected the verification link not to enroll passkeys on a password account")
+	}
+}
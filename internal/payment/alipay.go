@@ -31,14 +31,26 @@ type AlipayProvider struct {
 
 // AlipayConfig holds Alipay configuration
 type AlipayConfig struct {
-	AppID          string `json:"app_id"`
-	PrivateKeyPath string `json:"private_key_path"`
-	PublicKeyPath  string `json:"public_key_path"`
-	Gateway        string `json:"gateway"`
-	NotifyURL      string `json:"notify_url"`
+	AppID          string `json:"app_id" required:"true"`
+	PrivateKeyPath string `json:"private_key_path" required:"true" help:"path of the application private key (PEM)"`
+	PublicKeyPath  string `json:"public_key_path" required:"true" help:"path of the Alipay public key (PEM)"`
+	Gateway        string `json:"gateway" default:"https://openapi.alipay.com/gateway.do"`
+	NotifyURL      string `json:"notify_url" required:"true" help:"e.g. https://example.com/api/payment/notify/alipay"`
 	ReturnURL      string `json:"return_url"`
 }
 
+func init() {
+	RegisterProviderDriver(ProviderDriver{
+		Name: "alipay",
+		NewConfig: func() interface{} {
+			return &AlipayConfig{}
+		},
+		New: func(config interface{}) (PaymentProvider, error) {
+			return NewAlipayProvider(*config.(*AlipayConfig))
+		},
+	})
+}
+
 // NewAlipayProvider creates a new Alipay payment provider
 func NewAlipayProvider(config AlipayConfig) (*AlipayProvider, error) {
 	privateKey, err := loadRSAPrivateKey(config.PrivateKeyPath)
@@ -82,7 +94,7 @@ func (ap *AlipayProvider) CreateOrder(order *model.PaymentOrder) (*PaymentRespon
 	// Build business parameters
 	bizContent := map[string]interface{}{
 		"out_trade_no": order.OrderNo,
-		"total_amount": fmt.Sprintf("%.2f", float64(order.Amount)/100),
+		"total_amount": order.Money.MajorString(),
 		"subject":      fmt.Sprintf("OpenList Credits Purchase - %d credits", order.Credits),
 		"body":         fmt.Sprintf("Purchase %d credits for OpenList", order.Credits),
 		"timeout_express": "30m",
@@ -141,6 +153,25 @@ func (ap *AlipayProvider) CreateOrder(order *model.PaymentOrder) (*PaymentRespon
 	}, nil
 }
 
+// ParseNotification reads an Alipay notification, which is posted as a form
+func (ap *AlipayProvider) ParseNotification(r *http.Request) (map[string]interface{}, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, errors.Wrap(err, "failed to parse notification")
+	}
+	paymentData := make(map[string]interface{}, len(r.PostForm))
+	for key := range r.PostForm {
+		paymentData[key] = r.PostForm.Get(key)
+	}
+	return paymentData, nil
+}
+
+// AckNotification answers an Alipay notification
+func (ap *AlipayProvider) AckNotification(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("success"))
+}
+
 // VerifyPayment verifies an Alipay payment notification
 func (ap *AlipayProvider) VerifyPayment(orderNo string, paymentData map[string]interface{}) (*PaymentVerification, error) {
 	// Extract notification parameters
@@ -163,9 +194,9 @@ func (ap *AlipayProvider) VerifyPayment(orderNo string, paymentData map[string]i
 	}
 
 	// Parse amount
-	var amount float64
-	if amountStr, exists := notifyParams["total_amount"]; exists {
-		fmt.Sscanf(amountStr, "%f", &amount)
+	amount, err := model.ParseMoney(notifyParams["total_amount"], model.DefaultCurrency)
+	if err != nil {
+		return &PaymentVerification{Success: false}, errors.Wrap(err, "invalid total_amount")
 	}
 
 	// Parse paid time
@@ -186,8 +217,88 @@ func (ap *AlipayProvider) VerifyPayment(orderNo string, paymentData map[string]i
 	}, nil
 }
 
+// QueryOrder queries an Alipay order by out_trade_no via alipay.trade.query
+func (ap *AlipayProvider) QueryOrder(orderNo string) (*PaymentVerification, error) {
+	params := map[string]string{
+		"app_id":    ap.AppID,
+		"method":    "alipay.trade.query",
+		"charset":   "utf-8",
+		"sign_type": "RSA2",
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+		"version":   "1.0",
+	}
+
+	bizContentJSON, err := json.Marshal(map[string]interface{}{
+		"out_trade_no": orderNo,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal biz_content")
+	}
+	params["biz_content"] = string(bizContentJSON)
+
+	sign, err := ap.generateSign(params)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate signature")
+	}
+	params["sign"] = sign
+
+	resp, err := ap.makeAPIRequest(params)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make API request")
+	}
+
+	var alipayResp struct {
+		AlipayTradeQueryResponse struct {
+			Code        string `json:"code"`
+			Msg         string `json:"msg"`
+			SubCode     string `json:"sub_code"`
+			SubMsg      string `json:"sub_msg"`
+			TradeNo     string `json:"trade_no"`
+			OutTradeNo  string `json:"out_trade_no"`
+			TradeStatus string `json:"trade_status"`
+			TotalAmount string `json:"total_amount"`
+			SendPayDate string `json:"send_pay_date"`
+		} `json:"alipay_trade_query_response"`
+	}
+	if err := json.Unmarshal(resp, &alipayResp); err != nil {
+		return nil, errors.Wrap(err, "failed to parse response")
+	}
+
+	result := alipayResp.AlipayTradeQueryResponse
+	if result.Code != "10000" {
+		return nil, errors.Errorf("alipay error: %s - %s %s", result.Code, result.Msg, result.SubMsg)
+	}
+	if result.OutTradeNo != orderNo {
+		return nil, errors.Errorf("alipay returned mismatched order: %s", result.OutTradeNo)
+	}
+	if result.TradeStatus != "TRADE_SUCCESS" && result.TradeStatus != "TRADE_FINISHED" {
+		return &PaymentVerification{Success: false, OrderNo: orderNo}, nil
+	}
+
+	amount, err := model.ParseMoney(result.TotalAmount, model.DefaultCurrency)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid total_amount")
+	}
+	paidAt := time.Now()
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", result.SendPayDate, time.Local); err == nil {
+		paidAt = t
+	}
+
+	return &PaymentVerification{
+		Success:       true,
+		OrderNo:       result.OutTradeNo,
+		TransactionID: result.TradeNo,
+		Amount:        amount,
+		PaidAt:        paidAt,
+		PaymentData: map[string]interface{}{
+			"provider":     "alipay",
+			"trade_status": result.TradeStatus,
+		},
+	}, nil
+}
+
 // Refund processes a refund for Alipay payment
-func (ap *AlipayProvider) Refund(orderNo string, amount float64) (*RefundResponse, error) {
+func (ap *AlipayProvider) Refund(orderNo string, amount model.Money) (*RefundResponse, error) {
 	// Build request parameters
 	params := map[string]string{
 		"app_id":    ap.AppID,
@@ -201,9 +312,9 @@ func (ap *AlipayProvider) Refund(orderNo string, amount float64) (*RefundRespons
 	// Build business parameters
 	bizContent := map[string]interface{}{
 		"out_trade_no":   orderNo,
-		"refund_amount":  fmt.Sprintf("%.2f", amount),
+		"refund_amount":  amount.MajorString(),
 		"refund_reason":  "User requested refund",
-		"out_request_no": fmt.Sprintf("%s_refund_%d", orderNo, time.Now().Unix()),
+		"out_request_no": GenerateRefundNo(),
 	}
 
 	bizContentJSON, err := json.Marshal(bizContent)
@@ -2,10 +2,12 @@ package payment
 
 import (
 	"encoding/json"
-	"fmt"
+	"net/http"
+	"sync"
 	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/pkg/errors"
 )
 
@@ -13,7 +15,19 @@ import (
 type PaymentProvider interface {
 	CreateOrder(order *model.PaymentOrder) (*PaymentResponse, error)
 	VerifyPayment(orderNo string, paymentData map[string]interface{}) (*PaymentVerification, error)
-	Refund(orderNo string, amount float64) (*RefundResponse, error)
+	// QueryOrder actively asks the gateway for the state of an order,
+	// Success is only true when the gateway reports the order as paid
+	QueryOrder(orderNo string) (*PaymentVerification, error)
+	Refund(orderNo string, amount model.Money) (*RefundResponse, error)
+}
+
+// NotificationHandler is implemented by providers that accept asynchronous payment
+// notifications, which are parsed and answered in a gateway specific format
+type NotificationHandler interface {
+	// ParseNotification reads the notification data passed to VerifyPayment from the request
+	ParseNotification(r *http.Request) (map[string]interface{}, error)
+	// AckNotification tells the gateway the notification was handled so it stops retrying
+	AckNotification(w http.ResponseWriter)
 }
 
 // PaymentResponse represents the response from payment provider
@@ -29,7 +43,7 @@ type PaymentVerification struct {
 	Success       bool                   `json:"success"`
 	OrderNo       string                 `json:"order_no"`
 	TransactionID string                 `json:"transaction_id"`
-	Amount        float64                `json:"amount"`
+	Amount        model.Money            `json:"amount"`
 	PaidAt        time.Time              `json:"paid_at"`
 	PaymentData   map[string]interface{} `json:"payment_data"`
 }
@@ -85,8 +99,17 @@ func (pm *PaymentManager) VerifyPayment(providerName, orderNo string, paymentDat
 	return provider.VerifyPayment(orderNo, paymentData)
 }
 
+// QueryPayment queries the state of an order using specified provider
+func (pm *PaymentManager) QueryPayment(providerName, orderNo string) (*PaymentVerification, error) {
+	provider, err := pm.GetProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+	return provider.QueryOrder(orderNo)
+}
+
 // ProcessRefund processes a refund using specified provider
-func (pm *PaymentManager) ProcessRefund(providerName, orderNo string, amount float64) (*RefundResponse, error) {
+func (pm *PaymentManager) ProcessRefund(providerName, orderNo string, amount model.Money) (*RefundResponse, error) {
 	provider, err := pm.GetProvider(providerName)
 	if err != nil {
 		return nil, err
@@ -123,31 +146,37 @@ func UnmarshalPaymentData(data string) (map[string]interface{}, error) {
 
 // GenerateOrderNo generates a unique order number
 func GenerateOrderNo() string {
-	return fmt.Sprintf("OL%d", time.Now().UnixNano())
+	return "OL" + utils.NextSnowflakeString()
+}
+
+// GenerateRefundNo generates a unique refund request number
+func GenerateRefundNo() string {
+	return "RF" + utils.NextSnowflakeString()
 }
 
 // Global payment manager instance
-var globalPaymentManager *PaymentManager
+var (
+	globalPaymentManager *PaymentManager
+	managerMu            sync.RWMutex
+)
 
-// InitPaymentManager initializes the global payment manager
+// InitPaymentManager initializes the global payment manager with no provider.
+// Providers are registered declaratively through the payment_providers setting,
+// see LoadProviders and RegisterProviderDriver.
 func InitPaymentManager() {
+	managerMu.Lock()
 	globalPaymentManager = NewPaymentManager()
-	
-	// Register payment providers here
-	// Example:
-	// alipayConfig := AlipayConfig{...}
-	// alipayProvider, _ := NewAlipayProvider(alipayConfig)
-	// globalPaymentManager.RegisterProvider("alipay", alipayProvider)
-	
-	// wechatConfig := WechatConfig{...}
-	// wechatProvider := NewWechatProvider(wechatConfig)
-	// globalPaymentManager.RegisterProvider("wechat", wechatProvider)
+	managerMu.Unlock()
 }
 
 // GetPaymentManager returns the global payment manager instance
 func GetPaymentManager() *PaymentManager {
-	if globalPaymentManager == nil {
+	managerMu.RLock()
+	pm := globalPaymentManager
+	managerMu.RUnlock()
+	if pm == nil {
 		InitPaymentManager()
+		return GetPaymentManager()
 	}
-	return globalPaymentManager
-}
\ No newline at end of file
+	return pm
+}
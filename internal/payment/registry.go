@@ -0,0 +1,221 @@
+package payment
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// ProviderDriver describes a payment gateway implementation that can be
+// instantiated from a JSON config blob, the same way storage drivers are
+// created from their Addition.
+type ProviderDriver struct {
+	// Name is the unique driver name referenced by ProviderEntry.Driver
+	Name string
+	// NewConfig returns a pointer to a zero value of the driver's config struct.
+	// Fields use the same `json`, `default`, `required`, `help` tags as storage drivers.
+	NewConfig func() interface{}
+	// New creates the provider from a filled and validated config
+	New func(config interface{}) (PaymentProvider, error)
+}
+
+// ProviderEntry is one element of the payment_providers setting
+type ProviderEntry struct {
+	Name    string                 `json:"name"`    // name used as payment_method, defaults to Driver
+	Driver  string                 `json:"driver"`  // registered driver name
+	Enabled bool                   `json:"enabled"` // disabled entries are validated but not registered
+	Config  map[string]interface{} `json:"config"`  // driver specific config
+}
+
+var (
+	providerDrivers     = map[string]ProviderDriver{}
+	providerDriverItems = map[string][]driver.Item{}
+)
+
+// RegisterProviderDriver registers a payment provider driver, it should be called in init()
+func RegisterProviderDriver(d ProviderDriver) {
+	if _, ok := providerDrivers[d.Name]; ok {
+		panic("payment provider driver registered twice: " + d.Name)
+	}
+	providerDrivers[d.Name] = d
+	providerDriverItems[d.Name] = getConfigItems(reflect.TypeOf(d.NewConfig()))
+}
+
+// GetProviderDriverNames returns the names of all registered drivers
+func GetProviderDriverNames() []string {
+	names := make([]string, 0, len(providerDrivers))
+	for name := range providerDrivers {
+		names = append(names, name)
+	}
+	return names
+}
+
+// GetProviderDriverItems returns the config schema of all registered drivers
+func GetProviderDriverItems() map[string][]driver.Item {
+	return providerDriverItems
+}
+
+// NewProvider creates a provider of the named driver from a raw config map,
+// applying defaults and checking required fields.
+func NewProvider(driverName string, raw map[string]interface{}) (PaymentProvider, error) {
+	d, ok := providerDrivers[driverName]
+	if !ok {
+		return nil, errors.Errorf("no payment provider driver named: %s", driverName)
+	}
+	config := d.NewConfig()
+	items := providerDriverItems[driverName]
+	filled := make(map[string]interface{}, len(items))
+	for k, v := range raw {
+		filled[k] = v
+	}
+	for _, item := range items {
+		v, exists := filled[item.Name]
+		if (!exists || v == "" || v == nil) && item.Default != "" {
+			filled[item.Name] = defaultValue(item)
+		}
+	}
+	bytes, err := utils.Json.Marshal(filled)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err = utils.Json.Unmarshal(bytes, config); err != nil {
+		return nil, errors.Wrapf(err, "invalid config for payment provider driver %s", driverName)
+	}
+	if err = validateConfig(config, items); err != nil {
+		return nil, errors.Wrapf(err, "invalid config for payment provider driver %s", driverName)
+	}
+	return d.New(config)
+}
+
+// ParseProviderEntries parses and validates the payment_providers setting value
+func ParseProviderEntries(value string) ([]ProviderEntry, error) {
+	var entries []ProviderEntry
+	if strings.TrimSpace(value) == "" {
+		return entries, nil
+	}
+	if err := utils.Json.UnmarshalFromString(value, &entries); err != nil {
+		return nil, errors.Wrap(err, "failed to parse payment providers")
+	}
+	names := make(map[string]struct{}, len(entries))
+	for i := range entries {
+		e := &entries[i]
+		if e.Name == "" {
+			e.Name = e.Driver
+		}
+		if _, ok := names[e.Name]; ok {
+			return nil, errors.Errorf("duplicate payment provider name: %s", e.Name)
+		}
+		names[e.Name] = struct{}{}
+	}
+	return entries, nil
+}
+
+// LoadProviders builds a new payment manager from the payment_providers setting
+// and swaps it in, so providers can be changed at runtime without a restart.
+// Every entry is validated, nothing is swapped if any entry is invalid.
+func LoadProviders(value string) error {
+	entries, err := ParseProviderEntries(value)
+	if err != nil {
+		return err
+	}
+	pm := NewPaymentManager()
+	for _, e := range entries {
+		provider, err := NewProvider(e.Driver, e.Config)
+		if err != nil {
+			return errors.WithMessagef(err, "payment provider [%s]", e.Name)
+		}
+		if e.Enabled {
+			pm.RegisterProvider(e.Name, provider)
+		}
+	}
+	managerMu.Lock()
+	globalPaymentManager = pm
+	managerMu.Unlock()
+	return nil
+}
+
+func getConfigItems(t reflect.Type) []driver.Item {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var items []driver.Item
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			items = append(items, getConfigItems(field.Type)...)
+			continue
+		}
+		name, ok := field.Tag.Lookup("json")
+		if !ok || name == "-" {
+			continue
+		}
+		item := driver.Item{
+			Name:     strings.Split(name, ",")[0],
+			Type:     strings.ToLower(field.Type.Name()),
+			Default:  field.Tag.Get("default"),
+			Options:  field.Tag.Get("options"),
+			Required: field.Tag.Get("required") == "true",
+			Help:     field.Tag.Get("help"),
+		}
+		if typ := field.Tag.Get("type"); typ != "" {
+			item.Type = typ
+		}
+		if item.Type == "" {
+			item.Type = "string"
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func defaultValue(item driver.Item) interface{} {
+	switch item.Type {
+	case "bool":
+		return item.Default == "true"
+	case "int", "int64", "number":
+		if i, err := strconv.ParseInt(item.Default, 10, 64); err == nil {
+			return i
+		}
+	}
+	return item.Default
+}
+
+func validateConfig(config interface{}, items []driver.Item) error {
+	v := reflect.ValueOf(config)
+	for v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	values := make(map[string]reflect.Value, len(items))
+	collectFields(v, values)
+	for _, item := range items {
+		if !item.Required {
+			continue
+		}
+		if f, ok := values[item.Name]; !ok || f.IsZero() {
+			return errors.Errorf("%s is required", item.Name)
+		}
+		if item.Options != "" && values[item.Name].Kind() == reflect.String &&
+			!utils.SliceContains(strings.Split(item.Options, ","), values[item.Name].String()) {
+			return errors.Errorf("%s must be one of %s", item.Name, item.Options)
+		}
+	}
+	return nil
+}
+
+func collectFields(v reflect.Value, values map[string]reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			collectFields(v.Field(i), values)
+			continue
+		}
+		if name, ok := field.Tag.Lookup("json"); ok {
+			values[strings.Split(name, ",")[0]] = v.Field(i)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package payment
+
+import "testing"
+
+func TestLoadProviders(t *testing.T) {
+	valid := `[{"name":"wx","driver":"wechat","enabled":true,"config":{"app_id":"a","mch_id":"m","api_key":"k","notify_url":"https://example.com/n"}}]`
+	if err := LoadProviders(valid); err != nil {
+		t.Fatalf("load valid providers: %+v", err)
+	}
+	p, err := GetPaymentManager().GetProvider("wx")
+	if err != nil {
+		t.Fatalf("provider not registered: %+v", err)
+	}
+	if gw := p.(*WechatProvider).Gateway; gw != "https://api.mch.weixin.qq.com/pay/unifiedorder" {
+		t.Errorf("default gateway not applied, got %s", gw)
+	}
+	if _, ok := p.(NotificationHandler); !ok {
+		t.Error("expected a provider registered under another name to accept notifications")
+	}
+
+	invalid := []string{
+		`[{"driver":"wechat","enabled":true,"config":{"app_id":"a"}}]`,
+		`[{"driver":"unknown","enabled":true,"config":{}}]`,
+		`[{"driver":"wechat","config":{}},{"driver":"wechat","config":{}}]`,
+		`{`,
+	}
+	for _, v := range invalid {
+		if err := LoadProviders(v); err == nil {
+			t.Errorf("expected error for %s", v)
+		}
+	}
+	if _, err := GetPaymentManager().GetProvider("wx"); err != nil {
+		t.Error("invalid config should not replace the current providers")
+	}
+}
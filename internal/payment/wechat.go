@@ -18,20 +18,35 @@ import (
 
 // WechatProvider implements PaymentProvider for WeChat Pay
 type WechatProvider struct {
-	AppID     string
-	MchID     string
-	APIKey    string
-	NotifyURL string
-	Gateway   string
+	AppID        string
+	MchID        string
+	APIKey       string
+	NotifyURL    string
+	Gateway      string
+	QueryGateway string
 }
 
 // WechatConfig holds WeChat Pay configuration
 type WechatConfig struct {
-	AppID     string `json:"app_id"`
-	MchID     string `json:"mch_id"`
-	APIKey    string `json:"api_key"`
-	NotifyURL string `json:"notify_url"`
-	Gateway   string `json:"gateway"`
+	AppID     string `json:"app_id" required:"true"`
+	MchID     string `json:"mch_id" required:"true"`
+	APIKey    string `json:"api_key" required:"true"`
+	NotifyURL string `json:"notify_url" required:"true" help:"e.g. https://example.com/api/payment/notify/wechat"`
+	Gateway   string `json:"gateway" default:"https://api.mch.weixin.qq.com/pay/unifiedorder"`
+	// QueryGateway is the orderquery endpoint
+	QueryGateway string `json:"query_gateway" default:"https://api.mch.weixin.qq.com/pay/orderquery"`
+}
+
+func init() {
+	RegisterProviderDriver(ProviderDriver{
+		Name: "wechat",
+		NewConfig: func() interface{} {
+			return &WechatConfig{}
+		},
+		New: func(config interface{}) (PaymentProvider, error) {
+			return NewWechatProvider(*config.(*WechatConfig)), nil
+		},
+	})
 }
 
 // WechatUnifiedOrderRequest represents WeChat unified order request
@@ -90,13 +105,17 @@ func NewWechatProvider(config WechatConfig) *WechatProvider {
 	if config.Gateway == "" {
 		config.Gateway = "https://api.mch.weixin.qq.com/pay/unifiedorder"
 	}
+	if config.QueryGateway == "" {
+		config.QueryGateway = "https://api.mch.weixin.qq.com/pay/orderquery"
+	}
 
 	return &WechatProvider{
-		AppID:     config.AppID,
-		MchID:     config.MchID,
-		APIKey:    config.APIKey,
-		NotifyURL: config.NotifyURL,
-		Gateway:   config.Gateway,
+		AppID:        config.AppID,
+		MchID:        config.MchID,
+		APIKey:       config.APIKey,
+		NotifyURL:    config.NotifyURL,
+		Gateway:      config.Gateway,
+		QueryGateway: config.QueryGateway,
 	}
 }
 
@@ -112,7 +131,7 @@ func (wp *WechatProvider) CreateOrder(order *model.PaymentOrder) (*PaymentRespon
 		NonceStr:       nonceStr,
 		Body:           fmt.Sprintf("OpenList Credits Purchase - %d credits", order.Credits),
 		OutTradeNo:     order.OrderNo,
-		TotalFee:       int(order.Amount * 100), // Convert to cents
+		TotalFee:       int(order.Money.Minor()), // total_fee is in cents
 		SpbillCreateIP: "127.0.0.1",
 		NotifyURL:      wp.NotifyURL,
 		TradeType:      "NATIVE", // QR code payment
@@ -167,6 +186,24 @@ func (wp *WechatProvider) CreateOrder(order *model.PaymentOrder) (*PaymentRespon
 	}, nil
 }
 
+// ParseNotification reads a WeChat Pay notification, which is posted as XML
+func (wp *WechatProvider) ParseNotification(r *http.Request) (map[string]interface{}, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read notification")
+	}
+	return map[string]interface{}{
+		"xml": string(body),
+	}, nil
+}
+
+// AckNotification answers a WeChat Pay notification
+func (wp *WechatProvider) AckNotification(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(buildXML(map[string]string{"return_code": "SUCCESS", "return_msg": "OK"})))
+}
+
 // VerifyPayment verifies a WeChat Pay notification
 func (wp *WechatProvider) VerifyPayment(orderNo string, paymentData map[string]interface{}) (*PaymentVerification, error) {
 	// Parse notification data
@@ -202,14 +239,77 @@ func (wp *WechatProvider) VerifyPayment(orderNo string, paymentData map[string]i
 		Success:       true,
 		OrderNo:       notification.OutTradeNo,
 		TransactionID: notification.TransactionID,
-		Amount:        float64(notification.TotalFee) / 100, // Convert from cents
+		Amount:        model.NewMoney(int64(notification.TotalFee), model.DefaultCurrency),
 		PaidAt:        paidAt,
 		PaymentData:   paymentData,
 	}, nil
 }
 
+// QueryOrder queries a WeChat Pay order by out_trade_no via orderquery
+func (wp *WechatProvider) QueryOrder(orderNo string) (*PaymentVerification, error) {
+	params := map[string]string{
+		"appid":        wp.AppID,
+		"mch_id":       wp.MchID,
+		"out_trade_no": orderNo,
+		"nonce_str":    wp.generateNonceStr(),
+	}
+	params["sign"] = wp.signParams(params)
+
+	resp, err := http.Post(wp.QueryGateway, "application/xml", strings.NewReader(buildXML(params)))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make API request")
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read response")
+	}
+
+	result, err := parseXMLMap(respBody)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse response")
+	}
+	if result["return_code"] != "SUCCESS" {
+		return nil, errors.Errorf("wechat error: %s", result["return_msg"])
+	}
+	// the response is signed over every returned field except sign itself
+	sign := result["sign"]
+	delete(result, "sign")
+	if wp.signParams(result) != sign {
+		return nil, errors.New("invalid signature")
+	}
+	if result["result_code"] != "SUCCESS" {
+		return nil, errors.Errorf("wechat error: %s - %s", result["err_code"], result["err_code_des"])
+	}
+	if result["out_trade_no"] != orderNo {
+		return nil, errors.Errorf("wechat returned mismatched order: %s", result["out_trade_no"])
+	}
+	if result["trade_state"] != "SUCCESS" {
+		return &PaymentVerification{Success: false, OrderNo: orderNo}, nil
+	}
+
+	var totalFee int64
+	fmt.Sscanf(result["total_fee"], "%d", &totalFee)
+	paidAt := time.Now()
+	if t, err := time.ParseInLocation("20060102150405", result["time_end"], time.Local); err == nil {
+		paidAt = t
+	}
+
+	return &PaymentVerification{
+		Success:       true,
+		OrderNo:       orderNo,
+		TransactionID: result["transaction_id"],
+		Amount:        model.NewMoney(totalFee, model.DefaultCurrency),
+		PaidAt:        paidAt,
+		PaymentData: map[string]interface{}{
+			"provider":    "wechat",
+			"trade_state": result["trade_state"],
+		},
+	}, nil
+}
+
 // Refund processes a refund for WeChat Pay
-func (wp *WechatProvider) Refund(orderNo string, amount float64) (*RefundResponse, error) {
+func (wp *WechatProvider) Refund(orderNo string, amount model.Money) (*RefundResponse, error) {
 	// WeChat Pay refund implementation would go here
 	// This is a simplified placeholder
 	return &RefundResponse{
@@ -288,4 +388,48 @@ func (wp *WechatProvider) signParams(params map[string]string) string {
 	// Generate MD5 hash
 	hash := md5.Sum([]byte(queryString))
 	return strings.ToUpper(hex.EncodeToString(hash[:]))
-}
\ No newline at end of file
+}
+
+// buildXML encodes flat params as a WeChat Pay <xml> request body
+func buildXML(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString("<xml>")
+	for _, key := range keys {
+		b.WriteString("<" + key + ">")
+		_ = xml.EscapeText(&b, []byte(params[key]))
+		b.WriteString("</" + key + ">")
+	}
+	b.WriteString("</xml>")
+	return b.String()
+}
+
+// parseXMLMap decodes a flat WeChat Pay <xml> response into a map
+func parseXMLMap(data []byte) (map[string]string, error) {
+	result := make(map[string]string)
+	decoder := xml.NewDecoder(strings.NewReader(string(data)))
+	var key string
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			key = t.Name.Local
+		case xml.CharData:
+			if key != "" && key != "xml" {
+				result[key] += string(t)
+			}
+		case xml.EndElement:
+			key = ""
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package sign
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/pkg/sign"
+)
+
+// SignUser signs a download link on behalf of a user, who is charged and metered for the
+// download. The sign is "<user id>.<sign>" and expires like other signed links.
+func SignUser(data string, userID uint) string {
+	id := strconv.FormatUint(uint64(userID), 10)
+	return id + "." + Sign(userData(data, id))
+}
+
+// IsUserSign reports whether a sign is made by SignUser
+func IsUserSign(s string) bool {
+	return strings.Contains(s, ".")
+}
+
+// VerifyUser verifies a sign made by SignUser and returns the user it is made for
+func VerifyUser(data string, s string) (uint, error) {
+	id, s, ok := strings.Cut(s, ".")
+	if !ok {
+		return 0, sign.ErrSignInvalid
+	}
+	userID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return 0, sign.ErrSignInvalid
+	}
+	if err = Verify(userData(data, id), s); err != nil {
+		return 0, err
+	}
+	return uint(userID), nil
+}
+
+func userData(data, id string) string {
+	return data + "\x00user:" + id
+}
@@ -16,6 +16,9 @@ type TaskExtension struct {
 	endTime    *time.Time
 	totalBytes int64
 	ApiUrl     string
+	// CreditHoldID is the credit hold created when the task was submitted,
+	// captured when the task succeeds and released when it fails
+	CreditHoldID uint
 }
 
 func (t *TaskExtension) SetCtx(ctx context.Context) {
@@ -47,6 +47,11 @@ func RefreshAndRemove(dstPath string, payloads ...any) {
 			err = verifyAndRemove(ctx, srcStorage, dstStorage, srcActualPath, dstActualPath, dstNeedRefresh)
 			if err != nil {
 				log.Error(err)
+				continue
+			}
+			// keep the credits configs attached to the moved path
+			if _, err = op.MoveFileCreditsConfigs(string(p), path.Join(dstPath, path.Base(string(p)))); err != nil {
+				log.Error(err)
 			}
 		}
 	}
@@ -0,0 +1,47 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule(t *testing.T) {
+	cases := []struct {
+		expr string
+		at   string
+		want bool
+	}{
+		{"0 20 * * 5", "2025-01-03 20:00", true}, // Friday
+		{"0 20 * * 5", "2025-01-04 20:00", false},
+		{"*/15 9-17 * * 1-5", "2025-01-06 09:45", true},
+		{"*/15 9-17 * * 1-5", "2025-01-06 09:50", false},
+		{"0 0 1 * *", "2025-02-01 00:00", true},
+		{"0 0 1 * 0", "2025-01-05 00:00", true}, // Sunday, either day field matches
+		{"0 0 * * 7", "2025-01-05 00:00", true},
+		{"30 12 25 12 *", "2025-12-25 12:30", true},
+	}
+	for _, c := range cases {
+		s, err := ParseSchedule(c.expr)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %+v", c.expr, err)
+		}
+		at, _ := time.Parse("2006-01-02 15:04", c.at)
+		if got := s.Match(at); got != c.want {
+			t.Errorf("%s at %s: got %v, want %v", c.expr, c.at, got, c.want)
+		}
+	}
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "5-1 * * * *", "*/0 * * * *"} {
+		if _, err := ParseSchedule(expr); err == nil {
+			t.Errorf("expected %q to be invalid", expr)
+		}
+	}
+
+	s, _ := ParseSchedule("0 20 * * 5")
+	now, _ := time.Parse("2006-01-02 15:04", "2025-01-03 21:30")
+	if prev, ok := s.Prev(now, 2*time.Hour); !ok || prev.Hour() != 20 {
+		t.Errorf("expected previous fire at 20:00, got %v, %v", prev, ok)
+	}
+	if _, ok := s.Prev(now, time.Hour); ok {
+		t.Errorf("expected no fire time within an hour")
+	}
+}
@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	snowflakeEpoch    int64 = 1704067200000 // 2024-01-01 00:00:00 UTC in milliseconds
+	snowflakeNodeBits       = 10
+	snowflakeSeqBits        = 12
+	snowflakeMaxNode  int64 = -1 ^ (-1 << snowflakeNodeBits)
+	snowflakeMaxSeq   int64 = -1 ^ (-1 << snowflakeSeqBits)
+)
+
+// Snowflake generates 63-bit ids composed of a millisecond timestamp,
+// a node id and a per-millisecond sequence, so ids generated by different
+// instances never collide as long as their node ids differ.
+type Snowflake struct {
+	mu       sync.Mutex
+	node     int64
+	lastTime int64
+	seq      int64
+}
+
+// NewSnowflake creates a generator for the given node id (0-1023).
+// Out-of-range node ids are folded into the valid range.
+func NewSnowflake(node int64) *Snowflake {
+	return &Snowflake{node: node & snowflakeMaxNode}
+}
+
+// Node returns the node id of the generator
+func (s *Snowflake) Node() int64 {
+	return s.node
+}
+
+// NextID returns the next unique id. It waits for the next millisecond when
+// the sequence is exhausted and never goes backwards if the clock does.
+func (s *Snowflake) NextID() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UnixMilli()
+	if now < s.lastTime {
+		now = s.lastTime
+	}
+	if now == s.lastTime {
+		s.seq = (s.seq + 1) & snowflakeMaxSeq
+		if s.seq == 0 {
+			for now <= s.lastTime {
+				time.Sleep(100 * time.Microsecond)
+				now = time.Now().UnixMilli()
+			}
+		}
+	} else {
+		s.seq = 0
+	}
+	s.lastTime = now
+	return (now-snowflakeEpoch)<<(snowflakeNodeBits+snowflakeSeqBits) |
+		s.node<<snowflakeSeqBits |
+		s.seq
+}
+
+// NextIDString returns NextID formatted as a decimal string
+func (s *Snowflake) NextIDString() string {
+	return strconv.FormatInt(s.NextID(), 10)
+}
+
+var defaultSnowflake = NewSnowflake(0)
+
+// SetSnowflakeNode replaces the default generator with one bound to node.
+// It should be called once during startup, before any id is generated.
+func SetSnowflakeNode(node int64) {
+	defaultSnowflake = NewSnowflake(node)
+}
+
+// NextSnowflakeID returns the next id of the default generator
+func NextSnowflakeID() int64 {
+	return defaultSnowflake.NextID()
+}
+
+// NextSnowflakeString returns the next id of the default generator as a string
+func NextSnowflakeString() string {
+	return defaultSnowflake.NextIDString()
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSnowflakeUnique(t *testing.T) {
+	s := NewSnowflake(1)
+	const goroutines, perG = 8, 5000
+	var mu sync.Mutex
+	seen := make(map[int64]struct{}, goroutines*perG)
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ids := make([]int64, 0, perG)
+			for j := 0; j < perG; j++ {
+				ids = append(ids, s.NextID())
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, id := range ids {
+				if _, ok := seen[id]; ok {
+					t.Errorf("duplicate id %d", id)
+				}
+				seen[id] = struct{}{}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSnowflakeNodes(t *testing.T) {
+	a, b := NewSnowflake(1), NewSnowflake(2)
+	for i := 0; i < 1000; i++ {
+		if a.NextID() == b.NextID() {
+			t.Fatal("ids from different nodes collided")
+		}
+	}
+	if NewSnowflake(1024+3).Node() != 3 {
+		t.Error("node id should be folded into range")
+	}
+}
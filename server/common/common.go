@@ -46,9 +46,11 @@ func ErrorWithDataResp(c *gin.Context, err error, code int, data interface{}, l
 			log.Errorf("%v", err)
 		}
 	}
+	errCode, msg := localizeError(c, err)
 	c.JSON(200, Resp[interface{}]{
 		Code:    code,
-		Message: hidePrivacy(err.Error()),
+		Message: hidePrivacy(msg),
+		ErrCode: errCode,
 		Data:    data,
 	})
 	c.Abort()
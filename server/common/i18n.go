@@ -0,0 +1,133 @@
+package common
+
+import (
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/gin-gonic/gin"
+)
+
+const defaultLang = "en"
+
+// errorMessages maps error codes to their localized messages by language
+var errorMessages = map[string]map[string]string{
+	"insufficient_credits":         {"en": "insufficient credits", "zh": "积分不足"},
+	"credits_conflict":             {"en": "credits account was modified concurrently", "zh": "积分账户正在被修改，请稍后重试"},
+	"credit_hold_not_active":       {"en": "credit hold is already captured or released", "zh": "预扣积分已结算或已释放"},
+	"transfer_limit_exceeded":      {"en": "daily credits transfer limit exceeded", "zh": "超出每日转账限额"},
+	"credit_gift_not_pending":      {"en": "credit gift is already claimed or returned", "zh": "礼物已被领取或已退回"},
+	"reward_replayed":              {"en": "reward transaction or nonce has already been used", "zh": "该奖励交易或随机数已被使用"},
+	"reward_cap_exceeded":          {"en": "reward cap exceeded", "zh": "超出奖励积分上限"},
+	"invalid_reward_sign":          {"en": "invalid reward callback signature", "zh": "奖励回调签名无效"},
+	"credits_frozen":               {"en": "credits account is frozen", "zh": "积分账户已被冻结"},
+	"invalid_credits_amount":       {"en": "credits amount must be greater than 0", "zh": "积分数量必须大于0"},
+	"transfer_to_self":             {"en": "cannot send credits to yourself", "zh": "不能向自己转账"},
+	"recipient_not_found":          {"en": "recipient not found", "zh": "接收用户不存在"},
+	"recipient_unavailable":        {"en": "recipient is unavailable", "zh": "接收用户不可用"},
+	"invalid_download_token":       {"en": "download token is invalid, used up or expired", "zh": "下载令牌无效、已用完或已过期"},
+	"credit_hold_not_found":        {"en": "credit hold not found", "zh": "预扣记录不存在"},
+	"credit_gift_not_found":        {"en": "credit gift not found", "zh": "礼物不存在"},
+	"credit_gift_expired":          {"en": "credit gift has expired", "zh": "礼物已过期"},
+	"redeem_code_not_found":        {"en": "redeem code not found", "zh": "兑换码不存在"},
+	"redeem_code_unavailable":      {"en": "redeem code is already used or expired", "zh": "兑换码已使用或已过期"},
+	"redeem_code_user_limit":       {"en": "you have reached the usage limit of this redeem code", "zh": "已达到该兑换码的使用次数上限"},
+	"invalid_referral_code":        {"en": "invalid referral code", "zh": "推荐码无效"},
+	"self_referral":                {"en": "cannot use your own referral code", "zh": "不能使用自己的推荐码"},
+	"payment_order_not_found":      {"en": "payment order not found", "zh": "订单不存在"},
+	"payment_order_invalid_status": {"en": "payment order is not pending", "zh": "订单状态异常"},
+	"payment_order_expired":        {"en": "payment order has expired", "zh": "订单已过期"},
+	"share_not_found":              {"en": "share link not found or expired", "zh": "分享链接不存在或已过期"},
+	"share_file_free":              {"en": "the shared file is free to download", "zh": "分享的文件可免费下载"},
+	"credit_package_not_found":     {"en": "credit package not found", "zh": "充值套餐不存在"},
+	"credit_package_unavailable":   {"en": "credit package is no longer available", "zh": "充值套餐已下架"},
+	"vip_plan_not_found":           {"en": "subscription plan not found", "zh": "会员套餐不存在"},
+	"vip_plan_unavailable":         {"en": "subscription plan is no longer available", "zh": "会员套餐已下架"},
+	"coupon_required":              {"en": "coupon code is required", "zh": "优惠码不能为空"},
+	"coupon_not_found":             {"en": "coupon not found", "zh": "优惠码不存在"},
+	"coupon_inactive":              {"en": "coupon is not active or has expired", "zh": "优惠码未生效或已过期"},
+	"coupon_min_amount":            {"en": "order amount does not reach the coupon minimum", "zh": "订单金额未达到优惠券的最低金额"},
+	"coupon_not_applicable":        {"en": "coupon does not apply to this order", "zh": "优惠码不适用于该订单"},
+	"coupon_too_large":             {"en": "order amount after discount must be greater than 0", "zh": "优惠后的订单金额必须大于0"},
+	"coupon_exhausted":             {"en": "coupon has reached its usage limit", "zh": "优惠码已达到使用次数上限"},
+	"coupon_user_limit":            {"en": "you have reached the usage limit of this coupon", "zh": "已达到该优惠码的使用次数上限"},
+	"registration_closed":          {"en": "registration is closed", "zh": "暂不开放注册"},
+	"invite_code_required":         {"en": "an invite code is required to register", "zh": "注册需要填写邀请码"},
+	"invalid_invite_code":          {"en": "invite code is invalid, used up or expired", "zh": "邀请码无效、已用完或已过期"},
+	"invite_code_quota":            {"en": "you have reached the number of invite codes you can create", "zh": "可创建的邀请码数量已达上限"},
+	"two_factor_required":          {"en": "two factor authentication must be enabled for this operation", "zh": "此操作需要先启用两步验证"},
+	"invalid_two_factor_code":      {"en": "a valid 2FA code or backup code is required for this operation", "zh": "此操作需要有效的两步验证码或备用码"},
+	"password_reset_limited":       {"en": "too many password reset attempts, try again later", "zh": "重置密码尝试次数过多，请稍后再试"},
+	"invalid_reset_code":           {"en": "the reset code is wrong, used or expired", "zh": "重置验证码错误、已使用或已过期"},
+	"invalid_reset_token":          {"en": "the reset link is invalid or expired, request a new code", "zh": "重置凭证无效或已过期，请重新获取验证码"},
+	"email_change_limited":         {"en": "an email change was requested recently, try again later", "zh": "刚刚已发起过邮箱更换，请稍后再试"},
+	"no_pending_email_change":      {"en": "there is no pending email change, request a new one", "zh": "没有待确认的邮箱更换，请重新发起"},
+	"invalid_email_change_code":    {"en": "the verification codes are wrong or expired", "zh": "验证码错误或已过期"},
+	"account_deletion_limited":     {"en": "too many account deletion attempts, try again later", "zh": "注销账户尝试次数过多，请稍后再试"},
+	"invalid_deletion_code":        {"en": "the password or the verification code is wrong", "zh": "密码或验证码错误"},
+	"data_export_limited":          {"en": "a data export was requested recently, try again later", "zh": "最近已请求过数据导出，请稍后再试"},
+	"invalid_export_token":         {"en": "the export link is invalid or expired, request a new export", "zh": "导出链接无效或已过期，请重新请求导出"},
+	"terms_not_accepted":           {"en": "the current terms of service and privacy policy must be accepted", "zh": "需要同意当前版本的服务条款和隐私政策"},
+	"registration_pending":         {"en": "your registration is waiting for approval", "zh": "注册申请正在等待审核"},
+	"email_domain_not_allowed":     {"en": "registration is limited to emails of specific domains", "zh": "仅允许使用指定域名的邮箱注册"},
+	"email_domain_blocked":         {"en": "emails of this domain can't be used to register", "zh": "不允许使用该域名的邮箱注册"},
+	"verification_resend_cooldown": {"en": "the verification email was sent recently, try again later", "zh": "验证邮件刚刚已发送，请稍后再试"},
+	"verification_resend_limit":    {"en": "the verification email can't be sent again, register anew after the registration expires", "zh": "验证邮件重发次数已达上限，请在申请过期后重新注册"},
+	"invalid_registration_status":  {"en": "the registration can't be processed in its current status", "zh": "注册申请当前状态不允许此操作"},
+	"registration_source_limited":  {"en": "too many registrations from the same network were approved recently", "zh": "同一网络近期批准的注册申请过多"},
+	"username_invalid":             {"en": "the username contains characters that are not allowed", "zh": "用户名包含不允许的字符"},
+	"username_length":              {"en": "the username is too short or too long", "zh": "用户名过短或过长"},
+	"username_reserved":            {"en": "this username is reserved", "zh": "该用户名为保留用户名"},
+	"username_taken":               {"en": "this username is already taken", "zh": "用户名已被使用"},
+	"invalid_verification_link":    {"en": "the verification link is invalid or has been replaced by a newer one", "zh": "验证链接无效或已被新的链接取代"},
+	"verification_link_expired":    {"en": "the verification link has expired, register again", "zh": "验证链接已过期，请重新注册"},
+	"invalid_verification_code":    {"en": "the verification code is wrong or expired", "zh": "验证码错误或已过期"},
+	"verification_code_locked":     {"en": "too many wrong verification codes, request a new code", "zh": "验证码错误次数过多，请重新获取验证码"},
+	"identity_linked":              {"en": "this identity is already linked to another account", "zh": "该外部身份已关联到其他账户"},
+	"identity_not_found":           {"en": "the linked identity doesn't exist", "zh": "关联的外部身份不存在"},
+	"invalid_identity_link_token":  {"en": "the sign in with the provider has expired, sign in again to link it", "zh": "第三方登录已过期，请重新登录后再关联"},
+	"last_login_method":            {"en": "the only way to sign in can't be unlinked, set a password first", "zh": "不能解除唯一的登录方式，请先设置密码"},
+	"directory_account_protected":  {"en": "an admin account with this name exists and can't be taken over by the directory user", "zh": "存在同名的管理员账户，目录用户不能接管"},
+}
+
+// requestLang picks the first supported language from the Accept-Language header
+func requestLang(c *gin.Context) string {
+	for _, part := range strings.Split(c.GetHeader("Accept-Language"), ",") {
+		tag := strings.ToLower(strings.TrimSpace(strings.SplitN(part, ";", 2)[0]))
+		lang := strings.SplitN(tag, "-", 2)[0]
+		if lang == "zh" || lang == "en" {
+			return lang
+		}
+	}
+	return defaultLang
+}
+
+// Localize returns the message in the language of the request, messages maps languages to messages
+func Localize(c *gin.Context, messages map[string]string) string {
+	if msg, ok := messages[requestLang(c)]; ok {
+		return msg
+	}
+	return messages[defaultLang]
+}
+
+// ErrorMessage returns the message of err in the language of the request
+func ErrorMessage(c *gin.Context, err error) string {
+	_, msg := localizeError(c, err)
+	return msg
+}
+
+// localizeError returns the error code of err and its message in the language of the request.
+// Errors without a code keep their original message
+func localizeError(c *gin.Context, err error) (string, string) {
+	code := errs.Code(err)
+	if code == "" {
+		return "", err.Error()
+	}
+	messages, ok := errorMessages[code]
+	if !ok {
+		return code, err.Error()
+	}
+	if msg, ok := messages[requestLang(c)]; ok {
+		return code, msg
+	}
+	return code, messages[defaultLang]
+}
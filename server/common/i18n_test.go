@@ -0,0 +1,33 @@
+package common
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+func TestLocalizeError(t *testing.T) {
+	tests := []struct {
+		lang    string
+		err     error
+		code    string
+		message string
+	}{
+		{"", errs.InsufficientCredits, "insufficient_credits", "insufficient credits"},
+		{"zh-CN,zh;q=0.9,en;q=0.8", errs.InsufficientCredits, "insufficient_credits", "积分不足"},
+		{"fr-FR, en;q=0.5", errors.WithMessage(errs.CreditsFrozen, "failed to transfer"), "credits_frozen", "credits account is frozen"},
+		{"zh", errors.New("plain error"), "", "plain error"},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/", nil)
+		c.Request.Header.Set("Accept-Language", tt.lang)
+		code, message := localizeError(c, tt.err)
+		if code != tt.code || message != tt.message {
+			t.Errorf("localizeError(%q, %v) = %q, %q, want %q, %q", tt.lang, tt.err, code, message, tt.code, tt.message)
+		}
+	}
+}
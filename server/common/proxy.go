@@ -128,6 +128,7 @@ func (iw *InterceptResponseWriter) Write(p []byte) (int, error) {
 type WrittenResponseWriter struct {
 	http.ResponseWriter
 	written bool
+	size    int64
 }
 
 func (ww *WrittenResponseWriter) Write(p []byte) (int, error) {
@@ -135,6 +136,7 @@ func (ww *WrittenResponseWriter) Write(p []byte) (int, error) {
 	if !ww.written && n > 0 {
 		ww.written = true
 	}
+	ww.size += int64(n)
 	return n, err
 }
 
@@ -142,6 +144,11 @@ func (ww *WrittenResponseWriter) IsWritten() bool {
 	return ww.written
 }
 
+// WrittenBytes returns the number of body bytes written so far
+func (ww *WrittenResponseWriter) WrittenBytes() int64 {
+	return ww.size
+}
+
 func GenerateDownProxyURL(storage *model.Storage, reqPath string) string {
 	if storage.DownProxyURL == "" {
 		return ""
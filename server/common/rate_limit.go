@@ -0,0 +1,74 @@
+package common
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SlidingWindowLimiter limits how many events a key may have within any
+// window of time, keeping the time of every event still inside the window
+type SlidingWindowLimiter struct {
+	mu     sync.Mutex
+	events map[string][]time.Time
+}
+
+func NewSlidingWindowLimiter() *SlidingWindowLimiter {
+	return &SlidingWindowLimiter{events: make(map[string][]time.Time)}
+}
+
+// sweepThreshold is the number of keys above which idle keys are dropped
+const sweepThreshold = 10000
+
+// Allow records an event for key if fewer than limit events happened within
+// window, otherwise it reports how long to wait until the next one is allowed.
+// A limit of zero or less disables the check.
+func (l *SlidingWindowLimiter) Allow(key string, limit int, window time.Duration) (bool, time.Duration) {
+	if limit <= 0 {
+		return true, 0
+	}
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.events) > sweepThreshold {
+		l.sweep(now, window)
+	}
+	events := prune(l.events[key], now, window)
+	if len(events) >= limit {
+		l.events[key] = events
+		return false, events[len(events)-limit].Add(window).Sub(now)
+	}
+	l.events[key] = append(events, now)
+	return true, 0
+}
+
+func (l *SlidingWindowLimiter) sweep(now time.Time, window time.Duration) {
+	for key, events := range l.events {
+		if events = prune(events, now, window); len(events) == 0 {
+			delete(l.events, key)
+		} else {
+			l.events[key] = events
+		}
+	}
+}
+
+// prune drops the events that left the window
+func prune(events []time.Time, now time.Time, window time.Duration) []time.Time {
+	i := 0
+	for i < len(events) && now.Sub(events[i]) >= window {
+		i++
+	}
+	return events[i:]
+}
+
+// TooManyRequestsResp answers 429 with a Retry-After header in seconds
+func TooManyRequestsResp(c *gin.Context, retryAfter time.Duration) {
+	seconds := int(retryAfter.Seconds())
+	if retryAfter > time.Duration(seconds)*time.Second {
+		seconds++
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	ErrorStrResp(c, "Too many requests, try again in "+strconv.Itoa(seconds)+" seconds", 429)
+}
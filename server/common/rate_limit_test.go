@@ -0,0 +1,33 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSlidingWindowLimiter(t *testing.T) {
+	l := NewSlidingWindowLimiter()
+	window := 50 * time.Millisecond
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("a", 3, window); !ok {
+			t.Fatalf("expected event %d to be allowed", i)
+		}
+	}
+	ok, wait := l.Allow("a", 3, window)
+	if ok {
+		t.Fatalf("expected the fourth event to be limited")
+	}
+	if wait <= 0 || wait > window {
+		t.Errorf("unexpected wait %v", wait)
+	}
+	if ok, _ := l.Allow("b", 3, window); !ok {
+		t.Errorf("expected keys to be limited separately")
+	}
+	if ok, _ := l.Allow("a", 0, window); !ok {
+		t.Errorf("expected a zero limit to disable the check")
+	}
+	time.Sleep(wait)
+	if ok, _ := l.Allow("a", 3, window); !ok {
+		t.Errorf("expected an event to be allowed once the window moved on")
+	}
+}
@@ -3,6 +3,7 @@ package common
 type Resp[T any] struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
+	ErrCode string `json:"err_code,omitempty"`
 	Data    T      `json:"data"`
 }
 
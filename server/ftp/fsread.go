@@ -6,6 +6,8 @@ import (
 	fs2 "io/fs"
 	"net/http"
 	"os"
+	stdpath "path"
+	"sync/atomic"
 	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
@@ -13,7 +15,9 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/fs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/pkg/errors"
 )
@@ -21,7 +25,10 @@ import (
 type FileDownloadProxy struct {
 	model.File
 	io.Closer
-	ctx context.Context
+	ctx    context.Context
+	charge *op.DownloadCharge // credits held for a paid file, settled on Close
+	read   atomic.Int64
+	size   int64 // bytes from the requested offset to the end of the file
 }
 
 func OpenDownload(ctx context.Context, reqPath string, offset int64) (*FileDownloadProxy, error) {
@@ -36,12 +43,17 @@ func OpenDownload(ctx context.Context, reqPath string, offset int64) (*FileDownl
 	if !common.CanAccess(user, meta, reqPath, ctx.Value(conf.MetaPassKey).(string)) {
 		return nil, errs.PermissionDenied
 	}
+	charge, err := beginDownloadCharge(user, reqPath)
+	if err != nil {
+		return nil, err
+	}
 
 	// directly use proxy
 	header, _ := ctx.Value(conf.ProxyHeaderKey).(http.Header)
 	ip, _ := ctx.Value(conf.ClientIPKey).(string)
 	link, obj, err := fs.Link(ctx, reqPath, model.LinkArgs{IP: ip, Header: header})
 	if err != nil {
+		settleDownloadCharge(charge, false)
 		return nil, err
 	}
 	ss, err := stream.NewSeekableStream(&stream.FileStream{
@@ -50,18 +62,45 @@ func OpenDownload(ctx context.Context, reqPath string, offset int64) (*FileDownl
 	}, link)
 	if err != nil {
 		_ = link.Close()
+		settleDownloadCharge(charge, false)
 		return nil, err
 	}
 	reader, err := stream.NewReadAtSeeker(ss, offset)
 	if err != nil {
 		_ = ss.Close()
+		settleDownloadCharge(charge, false)
 		return nil, err
 	}
-	return &FileDownloadProxy{File: reader, Closer: ss, ctx: ctx}, nil
+	return &FileDownloadProxy{File: reader, Closer: ss, ctx: ctx, charge: charge, size: obj.GetSize() - offset}, nil
+}
+
+// beginDownloadCharge holds the credits of a paid file before the transfer starts,
+// returns nil when credits are disabled
+func beginDownloadCharge(user *model.User, reqPath string) (*op.DownloadCharge, error) {
+	if !setting.GetBool(conf.CreditsEnabled) {
+		return nil, nil
+	}
+	charge, err := op.BeginFileDownload(user.ID, reqPath)
+	if errors.Is(err, errs.InsufficientCredits) {
+		_, required, _ := op.CheckFileDownloadPermission(user.ID, reqPath)
+		return nil, errors.Wrapf(err, "%d credits are required to download %s", required, stdpath.Base(reqPath))
+	}
+	return charge, err
+}
+
+// settleDownloadCharge deducts the held credits when the file was transferred, or releases them
+func settleDownloadCharge(charge *op.DownloadCharge, transferred bool) {
+	if charge == nil {
+		return
+	}
+	if err := charge.Finish(transferred); err != nil {
+		utils.Log.Errorf("failed to settle the ftp download credits: %+v", err)
+	}
 }
 
 func (f *FileDownloadProxy) Read(p []byte) (n int, err error) {
 	n, err = f.File.Read(p)
+	f.read.Add(int64(n))
 	if err != nil {
 		return
 	}
@@ -71,6 +110,7 @@ func (f *FileDownloadProxy) Read(p []byte) (n int, err error) {
 
 func (f *FileDownloadProxy) ReadAt(p []byte, off int64) (n int, err error) {
 	n, err = f.File.ReadAt(p, off)
+	f.read.Add(int64(n))
 	if err != nil {
 		return
 	}
@@ -78,6 +118,15 @@ func (f *FileDownloadProxy) ReadAt(p []byte, off int64) (n int, err error) {
 	return
 }
 
+// Close settles the credits of a paid file once the transfer ends: they are deducted
+// when the file was read to the end and released when the transfer was aborted
+func (f *FileDownloadProxy) Close() error {
+	err := f.Closer.Close()
+	settleDownloadCharge(f.charge, f.read.Load() >= f.size)
+	f.charge = nil
+	return err
+}
+
 func (f *FileDownloadProxy) Write(p []byte) (n int, err error) {
 	return 0, errs.NotSupport
 }
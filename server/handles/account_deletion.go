@@ -0,0 +1,47 @@
+package handles
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestAccountDeletion 向注册邮箱发送注销账户的验证码
+func RequestAccountDeletion(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+
+	if err := op.RequestAccountDeletion(user); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"message": "A verification code has been sent to your email.",
+	})
+}
+
+// DeleteAccountReq 注销账户请求，仅使用通行密钥登录的账户不需要密码
+type DeleteAccountReq struct {
+	Password string `json:"password"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// DeleteAccount 校验密码和验证码后注销当前账户，所有会话随之失效
+func DeleteAccount(c *gin.Context) {
+	var req DeleteAccountReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.MustGet("user").(*model.User)
+
+	if err := op.DeleteAccount(user, req.Password, req.Code); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"message": "Your account has been deleted.",
+	})
+}
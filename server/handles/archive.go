@@ -119,7 +119,10 @@ func FsArchiveMeta(c *gin.Context) {
 		return
 	}
 	s := ""
-	if isEncrypt(meta, reqPath) || setting.GetBool(conf.SignAll) {
+	if isPaidDownload(user, reqPath, -1) {
+		// extracting from a paid archive is charged to the user the link is signed for
+		s = sign.SignUser(reqPath, user.ID)
+	} else if isEncrypt(meta, reqPath) || setting.GetBool(conf.SignAll) {
 		s = sign.SignArchive(reqPath)
 	}
 	api := "/ae"
@@ -3,9 +3,11 @@ package handles
 import (
 	"bytes"
 	"encoding/base64"
+	"errors"
 	"image/png"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
@@ -17,6 +19,8 @@ type LoginReq struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password"`
 	OtpCode  string `json:"otp_code"`
+	// AcceptedTerms maps the kind of each terms document to the version the user accepted
+	AcceptedTerms map[string]string `json:"accepted_terms"`
 }
 
 // Login Deprecated
@@ -49,8 +53,8 @@ func loginHash(c *gin.Context, req *LoginReq) {
 		model.LoginCache.Expire(ip, model.DefaultLockDuration)
 		return
 	}
-	// check username
-	user, err := op.GetUserByName(req.Username)
+	// check username, an email of a registered account is accepted as well
+	user, err := op.GetUserByLogin(req.Username)
 	if err != nil {
 		common.ErrorResp(c, err, 400)
 		model.LoginCache.Set(ip, count+1)
@@ -62,14 +66,28 @@ func loginHash(c *gin.Context, req *LoginReq) {
 		model.LoginCache.Set(ip, count+1)
 		return
 	}
-	// check 2FA
+	// check 2FA, a backup code is accepted in place of the current code
 	if user.OtpSecret != "" {
-		if !totp.Validate(req.OtpCode, user.OtpSecret) {
+		ok, err := op.ValidateOtp(user, req.OtpCode)
+		if err != nil {
+			common.ErrorResp(c, err, 500)
+			return
+		}
+		if !ok {
 			common.ErrorStrResp(c, "Invalid 2FA code", 402)
 			model.LoginCache.Set(ip, count+1)
 			return
 		}
 	}
+	// the current terms must be accepted, the pending documents are returned so that they can be shown
+	if pending, err := op.AcceptPendingTerms(user, req.AcceptedTerms, ip); err != nil {
+		if errors.Is(err, errs.TermsNotAccepted) {
+			common.ErrorWithDataResp(c, err, 403, gin.H{"terms": pending})
+		} else {
+			common.ErrorResp(c, err, 500)
+		}
+		return
+	}
 	// generate token
 	token, err := common.GenerateToken(user)
 	if err != nil {
@@ -110,11 +128,16 @@ func UpdateCurrent(c *gin.Context) {
 		common.ErrorStrResp(c, "Guest user can not update profile", 403)
 		return
 	}
+	if req.Username != user.Username {
+		if err := op.CheckUsername(req.Username, user.ID); err != nil {
+			common.ErrorResp(c, err, 400)
+			return
+		}
+	}
 	user.Username = req.Username
 	if req.Password != "" {
 		user.SetPassword(req.Password)
 	}
-	user.SsoID = req.SsoID
 	if err := op.UpdateUser(user); err != nil {
 		common.ErrorResp(c, err, 500)
 	} else {
@@ -174,9 +197,54 @@ func Verify2FA(c *gin.Context) {
 	user.OtpSecret = req.Secret
 	if err := op.UpdateUser(user); err != nil {
 		common.ErrorResp(c, err, 500)
-	} else {
-		common.SuccessResp(c)
+		return
+	}
+	codes, err := op.GenerateOtpBackupCodes(user)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, gin.H{"backup_codes": codes})
+}
+
+type Regenerate2FABackupCodesReq struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// Regenerate2FABackupCodes replaces the backup codes of the current user,
+// the current TOTP code is required
+func Regenerate2FABackupCodes(c *gin.Context) {
+	var req Regenerate2FABackupCodesReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if user.OtpSecret == "" {
+		common.ErrorStrResp(c, "2FA is not enabled", 400)
+		return
+	}
+	if !totp.Validate(req.Code, user.OtpSecret) {
+		common.ErrorStrResp(c, "Invalid 2FA code", 400)
+		return
+	}
+	codes, err := op.GenerateOtpBackupCodes(user)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, gin.H{"backup_codes": codes})
+}
+
+// Get2FABackupCodes returns the number of unused backup codes of the current user
+func Get2FABackupCodes(c *gin.Context) {
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	count, err := op.CountOtpBackupCodes(user)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
 	}
+	common.SuccessResp(c, gin.H{"remaining": count})
 }
 
 func LogOut(c *gin.Context) {
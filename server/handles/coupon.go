@@ -0,0 +1,101 @@
+package handles
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// ListCoupons 获取优惠券列表（管理员）
+func ListCoupons(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	coupons, total, err := op.ListCoupons(page, pageSize)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"coupons":   coupons,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// SaveCouponReq 保存优惠券请求
+type SaveCouponReq struct {
+	ID           uint       `json:"id"`
+	Code         string     `json:"code" binding:"required,max=64"`
+	Type         string     `json:"type" binding:"required,oneof=percent fixed"`
+	Value        int64      `json:"value" binding:"required,min=1"`
+	Currency     string     `json:"currency" binding:"max=10"`
+	MinAmount    int64      `json:"min_amount" binding:"min=0"`
+	MaxUses      int        `json:"max_uses" binding:"min=0"`
+	PerUserLimit int        `json:"per_user_limit" binding:"min=0"`
+	StartsAt     *time.Time `json:"starts_at"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	Enabled      bool       `json:"enabled"`
+	Description  string     `json:"description" binding:"max=500"`
+}
+
+// SaveCoupon 创建或更新优惠券（管理员）
+func SaveCoupon(c *gin.Context) {
+	var req SaveCouponReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	coupon := &model.Coupon{
+		ID:           req.ID,
+		Code:         req.Code,
+		Type:         req.Type,
+		Value:        req.Value,
+		Currency:     req.Currency,
+		MinAmount:    req.MinAmount,
+		MaxUses:      req.MaxUses,
+		PerUserLimit: req.PerUserLimit,
+		StartsAt:     req.StartsAt,
+		ExpiresAt:    req.ExpiresAt,
+		Enabled:      req.Enabled,
+		Description:  req.Description,
+	}
+	if err := op.SaveCoupon(coupon); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, coupon)
+}
+
+// DeleteCoupon 删除优惠券（管理员）
+func DeleteCoupon(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	if err = op.DeleteCoupon(uint(id)); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"message": "Coupon deleted successfully",
+	})
+}
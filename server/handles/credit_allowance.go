@@ -0,0 +1,76 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// ListCreditAllowances 获取积分津贴列表（管理员）
+func ListCreditAllowances(c *gin.Context) {
+	allowances, err := op.ListCreditAllowances()
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, allowances)
+}
+
+// SaveCreditAllowanceReq 保存积分津贴请求
+type SaveCreditAllowanceReq struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name" binding:"required,max=100"`
+	Role        int    `json:"role" binding:"min=0"`
+	Amount      int64  `json:"amount" binding:"required,min=1"`
+	Period      string `json:"period" binding:"required,oneof=monthly weekly"`
+	Stacking    bool   `json:"stacking"`
+	Enabled     bool   `json:"enabled"`
+	Description string `json:"description" binding:"max=500"`
+}
+
+// SaveCreditAllowance 创建或更新积分津贴（管理员）
+func SaveCreditAllowance(c *gin.Context) {
+	var req SaveCreditAllowanceReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	allowance := &model.CreditAllowance{
+		ID:          req.ID,
+		Name:        req.Name,
+		Role:        req.Role,
+		Amount:      req.Amount,
+		Period:      req.Period,
+		Stacking:    req.Stacking,
+		Enabled:     req.Enabled,
+		Description: req.Description,
+	}
+	if err := op.SaveCreditAllowance(allowance); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, allowance)
+}
+
+// DeleteCreditAllowance 删除积分津贴（管理员）
+func DeleteCreditAllowance(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	if err = op.DeleteCreditAllowance(uint(id)); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"message": "Credit allowance deleted successfully",
+	})
+}
@@ -0,0 +1,86 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// SendCreditGiftReq 赠送积分请求
+type SendCreditGiftReq struct {
+	Recipient string `json:"recipient" binding:"required,max=255"` // 接收方用户名或邮箱
+	Amount    int64  `json:"amount" binding:"required,min=1"`
+	Message   string `json:"message" binding:"max=200"`
+}
+
+// SendCreditGift 向其他用户赠送积分
+func SendCreditGift(c *gin.Context) {
+	var req SendCreditGiftReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	user := c.MustGet("user").(*model.User)
+	gift, err := op.SendCreditGift(user.ID, req.Recipient, req.Amount, req.Message)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, gift)
+}
+
+// ClaimCreditGiftReq 领取礼物请求
+type ClaimCreditGiftReq struct {
+	GiftNo string `json:"gift_no" binding:"required"`
+}
+
+// ClaimCreditGift 领取收到的积分礼物
+func ClaimCreditGift(c *gin.Context) {
+	var req ClaimCreditGiftReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	user := c.MustGet("user").(*model.User)
+	gift, err := op.ClaimCreditGift(user.ID, req.GiftNo)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, gift)
+}
+
+// ListCreditGifts 获取当前用户收到的礼物，box=sent 时获取赠送的礼物
+func ListCreditGifts(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	sent := c.Query("box") == "sent"
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	user := c.MustGet("user").(*model.User)
+	gifts, total, err := op.ListCreditGifts(user.ID, sent, page, pageSize)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"gifts":     gifts,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
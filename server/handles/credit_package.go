@@ -0,0 +1,91 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// ListCreditPackages 获取可购买的积分充值套餐及不使用套餐时每100积分的价格
+func ListCreditPackages(c *gin.Context) {
+	packages, err := op.ListCreditPackages(true)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	price, err := op.CreditsPrice(100)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, gin.H{
+		"packages":      packages,
+		"price_per_100": price,
+	})
+}
+
+// ListAllCreditPackages 获取所有积分充值套餐（管理员）
+func ListAllCreditPackages(c *gin.Context) {
+	packages, err := op.ListCreditPackages(false)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, packages)
+}
+
+// SaveCreditPackageReq 保存积分充值套餐请求
+type SaveCreditPackageReq struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name" binding:"required,max=100"`
+	Credits     int64  `json:"credits" binding:"required,min=1"`
+	Amount      int64  `json:"amount" binding:"required,min=1"` // 价格（最小货币单位）
+	Currency    string `json:"currency"`
+	Enabled     bool   `json:"enabled"`
+	Description string `json:"description" binding:"max=500"`
+}
+
+// SaveCreditPackage 创建或更新积分充值套餐（管理员）
+func SaveCreditPackage(c *gin.Context) {
+	var req SaveCreditPackageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	pkg := &model.CreditPackage{
+		ID:          req.ID,
+		Name:        req.Name,
+		Credits:     req.Credits,
+		Money:       model.NewMoney(req.Amount, req.Currency),
+		Enabled:     req.Enabled,
+		Description: req.Description,
+	}
+	if err := op.SaveCreditPackage(pkg); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, pkg)
+}
+
+// DeleteCreditPackage 删除积分充值套餐（管理员）
+func DeleteCreditPackage(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	if err = op.DeleteCreditPackage(uint(id)); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"message": "Credit package deleted successfully",
+	})
+}
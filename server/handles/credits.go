@@ -4,10 +4,14 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/payment"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
 )
 
 // GetUserCredits 获取用户积分信息
@@ -16,14 +20,14 @@ func GetUserCredits(c *gin.Context) {
 
 	credits, err := op.GetUserCredits(user.ID)
 	if err != nil {
-		common.ErrorStrResp(c, err.Error(), 500)
+		common.ErrorResp(c, err, 500)
 		return
 	}
 
 	common.SuccessResp(c, credits)
 }
 
-// GetCreditTransactions 获取用户积分交易记录
+// GetCreditTransactions 获取用户积分交易记录，支持按类型、来源、时间范围、金额正负和描述筛选
 func GetCreditTransactions(c *gin.Context) {
 	user := c.MustGet("user").(*model.User)
 
@@ -38,9 +42,100 @@ func GetCreditTransactions(c *gin.Context) {
 		pageSize = 20
 	}
 
-	transactions, total, err := op.GetCreditTransactions(user.ID, page, pageSize)
+	filter, err := parseCreditTransactionFilter(c)
 	if err != nil {
-		common.ErrorStrResp(c, err.Error(), 500)
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	transactions, total, err := op.GetCreditTransactions(user.ID, filter, page, pageSize)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"transactions": transactions,
+		"total":        total,
+		"page":         page,
+		"page_size":    pageSize,
+	})
+}
+
+// parseCreditTransactionFilter 从查询参数解析积分交易记录筛选条件，时间支持 RFC3339 或 2006-01-02 格式
+func parseCreditTransactionFilter(c *gin.Context) (model.CreditTransactionFilter, error) {
+	filter := model.CreditTransactionFilter{
+		Type:    c.Query("type"),
+		Source:  c.Query("source"),
+		Sign:    c.Query("sign"),
+		Keyword: c.Query("keyword"),
+		Path:    c.Query("path"),
+	}
+	if filter.Sign != "" && filter.Sign != model.AmountPositive && filter.Sign != model.AmountNegative {
+		return filter, errors.Errorf("invalid sign: %s", filter.Sign)
+	}
+	var err error
+	if filter.Start, err = parseTimeQuery(c, "start"); err != nil {
+		return filter, err
+	}
+	if filter.End, err = parseTimeQuery(c, "end"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// parseTimeQuery 解析 RFC3339 或 2006-01-02 格式的时间查询参数，参数为空时返回 nil
+func parseTimeQuery(c *gin.Context, key string) (*time.Time, error) {
+	v := c.Query(key)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.ParseInLocation(time.RFC3339, v, time.Local)
+	if err != nil {
+		if t, err = time.ParseInLocation(time.DateOnly, v, time.Local); err != nil {
+			return nil, errors.Errorf("invalid %s: %s", key, v)
+		}
+	}
+	return &t, nil
+}
+
+// clientMetadata 收集请求的客户端信息，记录到积分交易元数据中
+func clientMetadata(c *gin.Context) *model.TransactionMetadata {
+	return &model.TransactionMetadata{
+		ClientIP:  c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// ListAllCreditTransactions 按筛选条件获取所有用户的积分交易记录（管理员），可按 user_id 和 path 筛选
+func ListAllCreditTransactions(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	filter, err := parseCreditTransactionFilter(c)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if v := c.Query("user_id"); v != "" {
+		userID, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			common.ErrorStrResp(c, "invalid user_id", 400)
+			return
+		}
+		filter.UserID = uint(userID)
+	}
+
+	transactions, total, err := op.ListAllCreditTransactions(filter, page, pageSize)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
 		return
 	}
 
@@ -52,13 +147,195 @@ func GetCreditTransactions(c *gin.Context) {
 	})
 }
 
-// SetFileCreditsConfigReq 设置文件积分配置请求
+// GetCreditLots 获取用户未过期的积分批次
+func GetCreditLots(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+
+	lots, err := op.GetCreditLots(user.ID)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, lots)
+}
+
+// TransferCreditsReq 积分转账请求
+type TransferCreditsReq struct {
+	Username string `json:"username" binding:"required"`
+	Amount   int64  `json:"amount" binding:"required,min=1"`
+	Note     string `json:"note" binding:"max=200"`
+}
+
+// TransferCredits 向其他用户转账积分
+func TransferCredits(c *gin.Context) {
+	var req TransferCreditsReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	user := c.MustGet("user").(*model.User)
+
+	to, err := op.GetUserByName(req.Username)
+	if err != nil {
+		common.ErrorStrResp(c, "user not found", 404)
+		return
+	}
+
+	err = op.TransferCredits(user.ID, to.ID, req.Amount, req.Note)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"message": "Credits transferred successfully",
+	})
+}
+
+// AdjustCreditsReq 管理员调整积分请求
+type AdjustCreditsReq struct {
+	UserID uint   `json:"user_id" binding:"required"`
+	Amount int64  `json:"amount" binding:"required"`
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// AdjustCredits 为用户增加或扣除积分（管理员）
+func AdjustCredits(c *gin.Context) {
+	var req AdjustCreditsReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	user := c.MustGet("user").(*model.User)
+
+	err := op.AdjustCredits(user.ID, req.UserID, req.Amount, req.Reason)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"message": "Credits adjusted successfully",
+	})
+}
+
+// FreezeCreditsReq 冻结或解冻积分账户请求
+type FreezeCreditsReq struct {
+	UserID uint   `json:"user_id" binding:"required"`
+	Frozen bool   `json:"frozen"`
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// FreezeCredits 冻结或解冻用户的积分账户（管理员）
+func FreezeCredits(c *gin.Context) {
+	var req FreezeCreditsReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	user := c.MustGet("user").(*model.User)
+
+	if err := op.FreezeUserCredits(user.ID, req.UserID, req.Frozen, req.Reason); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"message": "Credits account updated successfully",
+	})
+}
+
+// ListCreditAdjustments 获取积分调整审计记录（管理员）
+func ListCreditAdjustments(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	transactions, total, err := op.ListCreditAdjustments(page, pageSize)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"transactions": transactions,
+		"total":        total,
+		"page":         page,
+		"page_size":    pageSize,
+	})
+}
+
+// GetTrafficUsage 获取当前用户的代理流量及计费情况
+func GetTrafficUsage(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+
+	account, err := op.GetTrafficAccount(user.ID)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, account)
+}
+
+// GetApiUsage 获取当前用户当日各类接口的调用次数及计费情况
+func GetApiUsage(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+
+	usages, err := op.GetApiUsages(user.ID)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"free_calls": setting.GetInt(conf.ApiFreeDailyCalls, 0),
+		"usages":     usages,
+	})
+}
+
+// GetDownloadQuota 获取当前用户的每日免费下载额度及当日使用量
+func GetDownloadQuota(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+
+	quota, err := op.GetDailyDownloadQuota(user.ID)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	usage, err := op.GetDownloadQuotaUsage(user.ID)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"quota": quota,
+		"usage": usage,
+	})
+}
+
+// SetFileCreditsConfigReq 设置文件积分配置请求，ID 不为0时按ID更新配置，否则按路径创建或覆盖配置。
+// Inheritable 和 Enabled 未指定时保留原有值，新建配置默认为 true
 type SetFileCreditsConfigReq struct {
-	Path        string `json:"path" binding:"required"`
-	IsFolder    bool   `json:"is_folder"`
-	Credits     int64  `json:"credits" binding:"min=0"`
-	Inheritable bool   `json:"inheritable"`
-	Enabled     bool   `json:"enabled"`
+	ID             uint   `json:"id"`
+	Path           string `json:"path" binding:"required"`
+	IsFolder       bool   `json:"is_folder"`
+	Credits        int64  `json:"credits" binding:"min=0"`
+	PreviewCredits int64  `json:"preview_credits" binding:"min=0"`
+	PricingMode    string `json:"pricing_mode" binding:"omitempty,oneof=flat per_mb per_gb"`
+	Inheritable    *bool  `json:"inheritable"`
+	Enabled        *bool  `json:"enabled"`
 }
 
 // SetFileCreditsConfig 设置文件积分配置（管理员）
@@ -71,14 +348,90 @@ func SetFileCreditsConfig(c *gin.Context) {
 
 	user := c.MustGet("user").(*model.User)
 
-	err := op.SetFileCreditsConfig(req.Path, req.Credits, req.IsFolder, user.ID)
+	config := &model.FileCreditsConfig{
+		ID:             req.ID,
+		Path:           req.Path,
+		IsFolder:       req.IsFolder,
+		Credits:        req.Credits,
+		PreviewCredits: req.PreviewCredits,
+		PricingMode:    req.PricingMode,
+		Inheritable:    true,
+		Enabled:        true,
+		CreatedBy:      user.ID,
+	}
+	if req.Inheritable == nil || req.Enabled == nil {
+		if existing, err := op.FindFileCreditsConfig(req.Path); err == nil {
+			config.Inheritable, config.Enabled = existing.Inheritable, existing.Enabled
+		}
+	}
+	if req.Inheritable != nil {
+		config.Inheritable = *req.Inheritable
+	}
+	if req.Enabled != nil {
+		config.Enabled = *req.Enabled
+	}
+
+	var err error
+	if req.ID != 0 {
+		err = op.UpdateFileCreditsConfig(config)
+	} else {
+		err = op.SaveFileCreditsConfig(config)
+	}
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, config)
+}
+
+// ListFileCreditsConfigs 获取文件积分配置列表（管理员）
+func ListFileCreditsConfigs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	configs, total, err := op.ListFileCreditsConfigs(page, pageSize)
 	if err != nil {
-		common.ErrorStrResp(c, err.Error(), 400)
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"configs":   configs,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// ToggleFileCreditsConfigReq 启用或停用文件积分配置请求
+type ToggleFileCreditsConfigReq struct {
+	ID      uint `json:"id" binding:"required"`
+	Enabled bool `json:"enabled"`
+}
+
+// ToggleFileCreditsConfig 启用或停用文件积分配置（管理员）
+func ToggleFileCreditsConfig(c *gin.Context) {
+	var req ToggleFileCreditsConfigReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	if err := op.SetFileCreditsConfigEnabled(req.ID, req.Enabled); err != nil {
+		common.ErrorResp(c, err, 400)
 		return
 	}
 
 	common.SuccessResp(c, gin.H{
-		"message": "File credits config set successfully",
+		"message": "File credits config updated successfully",
 	})
 }
 
@@ -90,9 +443,9 @@ func GetFileCreditsConfig(c *gin.Context) {
 		return
 	}
 
-	config, err := op.GetFileCreditsConfig(path)
+	config, _, err := op.ResolveFileCreditsConfig(path)
 	if err != nil {
-		common.ErrorStrResp(c, err.Error(), 500)
+		common.ErrorResp(c, err, 500)
 		return
 	}
 
@@ -107,16 +460,16 @@ func DeleteFileCreditsConfig(c *gin.Context) {
 		return
 	}
 
-	// 首先获取配置以获得ID
-	config, err := op.GetFileCreditsConfig(path)
+	// 首先获取配置以获得ID，已停用的配置也可以删除
+	config, err := op.FindFileCreditsConfig(path)
 	if err != nil {
-		common.ErrorStrResp(c, err.Error(), 404)
+		common.ErrorResp(c, err, 404)
 		return
 	}
 
 	err = op.DeleteFileCreditsConfig(config.ID)
 	if err != nil {
-		common.ErrorStrResp(c, err.Error(), 400)
+		common.ErrorResp(c, err, 400)
 		return
 	}
 
@@ -127,10 +480,22 @@ func DeleteFileCreditsConfig(c *gin.Context) {
 
 // GenerateRedeemCodesReq 生成兑换码请求
 type GenerateRedeemCodesReq struct {
-	Credits     int64  `json:"credits" binding:"required,min=1"`
+	Credits     int64  `json:"credits" binding:"min=0"`
 	Count       int    `json:"count" binding:"required,min=1,max=1000"`
 	MaxUses     int    `json:"max_uses" binding:"min=1"`
 	Description string `json:"description" binding:"max=500"`
+	// 过期时间，为空表示永不过期
+	ExpiresAt *time.Time `json:"expires_at"`
+	// 每个用户对同一兑换码的最大使用次数，0 表示不限制
+	MaxUsesPerUser int `json:"max_uses_per_user" binding:"min=0"`
+	// 所属兑换码活动，0 表示不属于任何活动
+	CampaignID uint `json:"campaign_id"`
+	// 奖励类型: credits（默认）, vip_days, file_unlock
+	RewardType string `json:"reward_type"`
+	// 奖励内容: vip_days 为天数，file_unlock 为文件路径
+	RewardPayload string `json:"reward_payload"`
+	// 兑换码格式，为空时使用默认格式
+	Format model.RedeemCodeFormat `json:"format"`
 }
 
 // GenerateRedeemCodes 生成兑换码（管理员）
@@ -143,18 +508,51 @@ func GenerateRedeemCodes(c *gin.Context) {
 
 	user := c.MustGet("user").(*model.User)
 
-	codes, err := op.GenerateRedeemCodes(req.Count, req.Credits, req.Description, user.ID, nil)
+	batch := &model.RedeemBatch{
+		CampaignID:     req.CampaignID,
+		Count:          req.Count,
+		Credits:        req.Credits,
+		RewardType:     req.RewardType,
+		RewardPayload:  req.RewardPayload,
+		MaxUses:        req.MaxUses,
+		MaxUsesPerUser: req.MaxUsesPerUser,
+		ExpiresAt:      req.ExpiresAt,
+		Description:    req.Description,
+		CreatedBy:      user.ID,
+		Format:         req.Format,
+	}
+	codes, err := op.GenerateRedeemCodes(batch)
 	if err != nil {
-		common.ErrorStrResp(c, err.Error(), 400)
+		common.ErrorResp(c, err, 400)
 		return
 	}
 
 	common.SuccessResp(c, gin.H{
-		"codes":   codes,
+		"batch_no": batch.BatchNo,
+		"codes":    codes,
 		"message": "Redeem codes generated successfully",
 	})
 }
 
+// GetRedeemCodeInfo 获取兑换码的面值和有效期，供兑换链接打开后展示确认页，不会使用兑换码
+func GetRedeemCodeInfo(c *gin.Context) {
+	code := c.Query("code")
+	if code == "" {
+		common.ErrorStrResp(c, "code is required", 400)
+		return
+	}
+
+	user := c.MustGet("user").(*model.User)
+
+	info, err := op.GetRedeemCodeInfo(user.ID, code)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, info)
+}
+
 // RedeemCodeReq 兑换码兑换请求
 type RedeemCodeReq struct {
 	Code string `json:"code" binding:"required"`
@@ -172,7 +570,7 @@ func RedeemCode(c *gin.Context) {
 
 	err := op.RedeemCode(user.ID, req.Code)
 	if err != nil {
-		common.ErrorStrResp(c, err.Error(), 400)
+		common.ErrorResp(c, err, 400)
 		return
 	}
 
@@ -183,8 +581,10 @@ func RedeemCode(c *gin.Context) {
 
 // CreatePaymentOrderReq 创建支付订单请求
 type CreatePaymentOrderReq struct {
-	Credits       int64  `json:"credits" binding:"required,min=1"`
+	Credits       int64  `json:"credits" binding:"min=0"`
+	PackageID     uint   `json:"package_id"` // 不为 0 时按充值套餐下单，忽略 credits
 	PaymentMethod string `json:"payment_method" binding:"required"`
+	Coupon        string `json:"coupon" binding:"max=64"`
 }
 
 // CreatePaymentOrder 创建支付订单
@@ -197,12 +597,9 @@ func CreatePaymentOrder(c *gin.Context) {
 
 	user := c.MustGet("user").(*model.User)
 
-	// 计算金额（这里假设1积分=1分钱，实际应根据业务需求调整）
-	amount := req.Credits
-
-	order, err := op.CreatePaymentOrder(user.ID, amount, req.Credits, req.PaymentMethod)
+	order, err := op.CreateCreditsOrder(user.ID, req.Credits, req.PackageID, req.PaymentMethod, req.Coupon)
 	if err != nil {
-		common.ErrorStrResp(c, err.Error(), 400)
+		common.ErrorResp(c, err, 400)
 		return
 	}
 
@@ -211,11 +608,11 @@ func CreatePaymentOrder(c *gin.Context) {
 
 // CompletePaymentOrderReq 完成支付订单请求
 type CompletePaymentOrderReq struct {
-	OrderNo       string `json:"order_no" binding:"required"`
-	TransactionID string `json:"transaction_id" binding:"required"`
+	OrderNo string `json:"order_no" binding:"required"`
 }
 
-// CompletePaymentOrder 完成支付订单（支付回调）
+// CompletePaymentOrder 完成支付订单
+// 客户端只能触发对支付渠道的主动查询，是否入账以渠道返回的结果为准
 func CompletePaymentOrder(c *gin.Context) {
 	var req CompletePaymentOrderReq
 	if err := c.ShouldBind(&req); err != nil {
@@ -223,9 +620,20 @@ func CompletePaymentOrder(c *gin.Context) {
 		return
 	}
 
-	err := op.CompletePaymentOrder(req.OrderNo, req.TransactionID, 0, time.Now())
+	user := c.MustGet("user").(*model.User)
+	order, err := op.GetPaymentOrderByNo(req.OrderNo)
+	if err != nil || order.UserID != user.ID {
+		common.ErrorStrResp(c, "order not found", 404)
+		return
+	}
+
+	order, err = op.SyncPaymentOrder(req.OrderNo)
 	if err != nil {
-		common.ErrorStrResp(c, err.Error(), 400)
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if !order.IsPaid() {
+		common.ErrorStrResp(c, "Payment not confirmed by the provider yet", 400)
 		return
 	}
 
@@ -245,7 +653,7 @@ func CancelPaymentOrder(c *gin.Context) {
 	user := c.MustGet("user").(*model.User)
 	err := op.CancelPaymentOrder(orderNo, user.ID)
 	if err != nil {
-		common.ErrorStrResp(c, err.Error(), 400)
+		common.ErrorResp(c, err, 400)
 		return
 	}
 
@@ -254,83 +662,96 @@ func CancelPaymentOrder(c *gin.Context) {
 	})
 }
 
-// PaymentNotification 处理支付通知
+// PaymentNotification 处理支付通知，由 payment_providers 中注册的同名支付渠道解析、校验并应答通知
 func PaymentNotification(c *gin.Context) {
-	provider := c.Param("provider")
-	if provider == "" {
+	name := c.Param("provider")
+	if name == "" {
 		common.ErrorStrResp(c, "Provider is required", 400)
 		return
 	}
-
-	// 解析通知数据
-	var paymentData map[string]interface{}
-	var orderNo string
-
-	switch provider {
-	case "alipay":
-		// 解析支付宝通知
-		if err := c.ShouldBindJSON(&paymentData); err != nil {
-			common.ErrorResp(c, err, 400)
-			return
-		}
-		if outTradeNo, ok := paymentData["out_trade_no"].(string); ok {
-			orderNo = outTradeNo
-		}
-	case "wechat":
-		// 解析微信通知 (XML格式)
-		body, err := c.GetRawData()
-		if err != nil {
-			common.ErrorResp(c, err, 400)
-			return
-		}
-		paymentData = map[string]interface{}{
-			"xml": string(body),
-		}
-	default:
+	provider, err := payment.GetPaymentManager().GetProvider(name)
+	if err != nil {
 		common.ErrorStrResp(c, "Unsupported payment provider", 400)
 		return
 	}
+	notifier, ok := provider.(payment.NotificationHandler)
+	if !ok {
+		common.ErrorStrResp(c, "Payment provider does not accept notifications", 400)
+		return
+	}
 
-	// 这里应该调用支付验证逻辑
-	// 由于支付验证比较复杂，这里简化处理
-	// 实际项目中需要根据具体的支付提供商API进行验证
+	// 解析通知数据
+	paymentData, err := notifier.ParseNotification(c.Request)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
 
-	// 模拟支付验证成功，完成订单
-	if orderNo != "" {
-		err := op.CompletePaymentOrder(orderNo, "mock_transaction_id", 0, time.Now())
+	// 校验通知签名，以渠道确认的订单号与实付金额完成订单
+	verification, err := provider.VerifyPayment("", paymentData)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if verification.Success {
+		err = op.CompletePaymentOrder(verification.OrderNo, verification.TransactionID, verification.Amount, verification.PaidAt)
 		if err != nil {
-			common.ErrorStrResp(c, err.Error(), 400)
-			return
+			// 重复通知时订单已完成，仍需应答成功以停止渠道重试
+			if order, e := op.GetPaymentOrderByNo(verification.OrderNo); e != nil || !order.IsPaid() {
+				common.ErrorResp(c, err, 400)
+				return
+			}
 		}
 	}
 
-	// 根据支付提供商返回相应格式的成功响应
-	switch provider {
-	case "alipay":
-		c.String(200, "success")
-	case "wechat":
-		c.XML(200, gin.H{
-			"return_code": "SUCCESS",
-			"return_msg":  "OK",
-		})
-	default:
-		c.JSON(200, gin.H{"status": "success"})
+	// 按支付渠道要求的格式应答
+	notifier.AckNotification(c.Writer)
+}
+
+// PaymentReturn 支付同步返回（return_url）
+// 仅使用订单号，向支付渠道主动查询确认支付结果，不信任客户端带回的其它参数
+func PaymentReturn(c *gin.Context) {
+	orderNo := c.Query("out_trade_no")
+	if orderNo == "" {
+		orderNo = c.Query("order_no")
 	}
+	if orderNo == "" {
+		common.ErrorStrResp(c, "order_no is required", 400)
+		return
+	}
+
+	order, err := op.SyncPaymentOrder(orderNo)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"order_no": order.OrderNo,
+		"status":   order.Status,
+		"credits":  order.Credits,
+		"paid_at":  order.PaidAt,
+	})
 }
 
-// CheckDownloadPermission 检查文件下载权限
+// CheckDownloadPermission 检查文件下载或预览权限，action 为 preview 时按预览价格计算
 func CheckDownloadPermission(c *gin.Context) {
 	path := c.Query("path")
 	if path == "" {
 		common.ErrorStrResp(c, "path is required", 400)
 		return
 	}
+	action := c.DefaultQuery("action", model.CreditsActionDownload)
+	if action != model.CreditsActionDownload && action != model.CreditsActionPreview {
+		common.ErrorStrResp(c, "invalid action", 400)
+		return
+	}
 
 	user := c.MustGet("user").(*model.User)
 
-	canDownload, requiredCredits, err := op.CheckFileDownloadPermission(user.ID, path)
+	canDownload, requiredCredits, err := op.CheckFileAccessPermission(user.ID, path, action)
 	if err != nil {
-		common.ErrorStrResp(c, err.Error(), 500)
+		common.ErrorResp(c, err, 500)
 		return
 	}
 
@@ -340,7 +761,36 @@ func CheckDownloadPermission(c *gin.Context) {
 	})
 }
 
-// DeductCreditsForDownload 扣除下载积分
+// ListMyPurchases 分页获取当前用户已付费下载的文件，供前端标记已购文件
+func ListMyPurchases(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	purchases, total, err := op.ListDownloadPurchases(user.ID, page, pageSize)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"purchases": purchases,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// DeductCreditsForDownload 扣除下载积分，用于下载前预先购买；通过下载链接获取文件时已在同一请求中扣费，
+// 已购文件在有效期内下载不再扣费
 func DeductCreditsForDownload(c *gin.Context) {
 	path := c.Query("path")
 	if path == "" {
@@ -350,13 +800,76 @@ func DeductCreditsForDownload(c *gin.Context) {
 
 	user := c.MustGet("user").(*model.User)
 
-	err := op.ProcessFileDownload(user.ID, path)
+	err := op.ProcessFileDownload(user.ID, path, clientMetadata(c))
 	if err != nil {
-		common.ErrorStrResp(c, err.Error(), 400)
+		common.ErrorResp(c, err, 400)
 		return
 	}
 
 	common.SuccessResp(c, gin.H{
 		"message": "Credits deducted successfully",
 	})
-}
\ No newline at end of file
+}
+
+// CreditHoldReq 扣除或释放积分冻结请求
+type CreditHoldReq struct {
+	HoldID uint `json:"hold_id" binding:"required"`
+}
+
+// CaptureDownloadCredits 扣除下载的积分冻结（管理员），下载请求的冻结由服务端按传输结果结算，
+// 用于处理结算异常遗留的冻结
+func CaptureDownloadCredits(c *gin.Context) {
+	hold, ok := getDownloadCreditHold(c)
+	if !ok {
+		return
+	}
+
+	err := op.CaptureCreditHold(hold.ID)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"message": "Credits deducted successfully",
+	})
+}
+
+// ReleaseDownloadCredits 释放下载的积分冻结（管理员）
+func ReleaseDownloadCredits(c *gin.Context) {
+	hold, ok := getDownloadCreditHold(c)
+	if !ok {
+		return
+	}
+
+	err := op.ReleaseCreditHold(hold.ID)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"message": "Credits released successfully",
+	})
+}
+
+func getDownloadCreditHold(c *gin.Context) (*model.CreditHold, bool) {
+	var req CreditHoldReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return nil, false
+	}
+
+	// holds of server-side tasks are settled by the task itself
+	hold, err := op.GetCreditHold(req.HoldID)
+	if err != nil || hold.Source != "download" {
+		common.ErrorStrResp(c, "credit hold not found", 404)
+		return nil, false
+	}
+	return hold, true
+}
+
+// ListPaymentDrivers 获取可用的支付驱动及其配置项（管理员）
+func ListPaymentDrivers(c *gin.Context) {
+	common.SuccessResp(c, payment.GetProviderDriverItems())
+}
@@ -0,0 +1,193 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// BulkFileCreditsConfigReq 批量设置或清除文件积分配置请求，Root 不为空时作用于整个目录树，否则作用于 Paths
+type BulkFileCreditsConfigReq struct {
+	Action         string   `json:"action" binding:"required,oneof=apply clear"`
+	Paths          []string `json:"paths"`
+	Root           string   `json:"root"`
+	IncludeFolders bool     `json:"include_folders"` // 对目录树设置时是否同时为其中的文件夹设置配置
+	IsFolder       bool     `json:"is_folder"`       // Paths 是否为文件夹
+	Credits        int64    `json:"credits" binding:"min=0"`
+	PreviewCredits int64    `json:"preview_credits" binding:"min=0"`
+	PricingMode    string   `json:"pricing_mode" binding:"omitempty,oneof=flat per_mb per_gb"`
+	Inheritable    bool     `json:"inheritable"`
+	Enabled        bool     `json:"enabled"`
+}
+
+// BulkFileCreditsConfig 批量设置或清除文件积分配置（管理员），所有路径在一个事务中完成
+func BulkFileCreditsConfig(c *gin.Context) {
+	var req BulkFileCreditsConfigReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if req.Root == "" && len(req.Paths) == 0 {
+		common.ErrorStrResp(c, "root or paths is required", 400)
+		return
+	}
+
+	if req.Action == "clear" {
+		var count int64
+		var err error
+		if req.Root != "" {
+			count, err = op.ClearFileCreditsConfigTree(req.Root)
+		} else {
+			count, err = op.ClearFileCreditsConfigs(req.Paths)
+		}
+		if err != nil {
+			common.ErrorResp(c, err, 500)
+			return
+		}
+		common.SuccessResp(c, gin.H{"count": count})
+		return
+	}
+
+	paths := make(map[string]bool, len(req.Paths))
+	if req.Root != "" {
+		var err error
+		paths, err = collectCreditsConfigTree(c, req.Root, req.IncludeFolders)
+		if err != nil {
+			common.ErrorResp(c, err, 400)
+			return
+		}
+	} else {
+		for _, path := range req.Paths {
+			paths[path] = req.IsFolder
+		}
+	}
+
+	user := c.MustGet("user").(*model.User)
+	count, err := op.BulkSetFileCreditsConfig(paths, model.FileCreditsConfig{
+		Credits:        req.Credits,
+		PreviewCredits: req.PreviewCredits,
+		PricingMode:    req.PricingMode,
+		Inheritable:    req.Inheritable,
+		Enabled:        req.Enabled,
+		CreatedBy:      user.ID,
+	})
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{"count": count})
+}
+
+// collectCreditsConfigTree 遍历目录树收集需要设置积分配置的路径，值表示是否为文件夹
+func collectCreditsConfigTree(c *gin.Context, root string, includeFolders bool) (map[string]bool, error) {
+	root = utils.FixAndCleanPath(root)
+	obj, err := fs.Get(c.Request.Context(), root, &fs.GetArgs{})
+	if err != nil {
+		return nil, err
+	}
+	paths := make(map[string]bool)
+	err = fs.WalkFS(c.Request.Context(), -1, root, obj, func(reqPath string, info model.Obj) error {
+		if info.IsDir() && !includeFolders {
+			return nil
+		}
+		if len(paths) >= op.MaxBulkCreditsConfigs {
+			return errors.Errorf("more than %d paths under %s", op.MaxBulkCreditsConfigs, root)
+		}
+		paths[reqPath] = info.IsDir()
+		return nil
+	})
+	return paths, err
+}
+
+// ListOrphanedFileCreditsConfigs 获取路径已被删除的积分配置（管理员）
+func ListOrphanedFileCreditsConfigs(c *gin.Context) {
+	configs, err := op.ListOrphanedFileCreditsConfigs()
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, configs)
+}
+
+// ScanFileCreditsConfigOrphans 检查所有积分配置的路径是否存在，不存在的标记为孤立并返回孤立配置报告（管理员）
+func ScanFileCreditsConfigOrphans(c *gin.Context) {
+	checked, err := op.ScanFileCreditsConfigOrphans(func(path string) (bool, error) {
+		_, err := fs.Get(c.Request.Context(), path, &fs.GetArgs{NoLog: true})
+		if err == nil {
+			return true, nil
+		}
+		if errs.IsNotFoundError(err) {
+			return false, nil
+		}
+		return false, err
+	})
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	configs, err := op.ListOrphanedFileCreditsConfigs()
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, gin.H{
+		"checked": checked,
+		"orphans": configs,
+	})
+}
+
+// CleanOrphanedFileCreditsConfigs 删除所有孤立的积分配置（管理员）
+func CleanOrphanedFileCreditsConfigs(c *gin.Context) {
+	count, err := op.CleanOrphanedFileCreditsConfigs()
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, gin.H{"count": count})
+}
+
+// ListFileCreditsExemptions 获取文件积分配置的豁免名单（管理员）
+func ListFileCreditsExemptions(c *gin.Context) {
+	configID, err := strconv.Atoi(c.Query("config_id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	exemptions, err := op.ListFileCreditsExemptions(uint(configID))
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, exemptions)
+}
+
+// SetFileCreditsExemptionsReq 设置豁免名单请求，名单会被整体替换
+type SetFileCreditsExemptionsReq struct {
+	ConfigID uint   `json:"config_id" binding:"required"`
+	UserIDs  []uint `json:"user_ids"`
+	GroupIDs []uint `json:"group_ids"`
+}
+
+// SetFileCreditsExemptions 设置文件积分配置的豁免名单（管理员）
+func SetFileCreditsExemptions(c *gin.Context) {
+	var req SetFileCreditsExemptionsReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := op.SetFileCreditsExemptions(req.ConfigID, req.UserIDs, req.GroupIDs); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, gin.H{
+		"message": "Exemptions updated successfully",
+	})
+}
@@ -0,0 +1,52 @@
+package handles
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// ExportFileCreditsConfigs 将所有文件积分配置导出为 JSON 文档（管理员）
+func ExportFileCreditsConfigs(c *gin.Context) {
+	doc, err := op.ExportFileCreditsConfigs()
+	if err != nil {
+		common.ErrorStrResp(c, err.Error(), 500)
+		return
+	}
+	filename := fmt.Sprintf("credits_configs_%s.json", time.Now().Format("20060102150405"))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.IndentedJSON(200, doc)
+}
+
+// ImportFileCreditsConfigsReq 导入文件积分配置请求
+type ImportFileCreditsConfigsReq struct {
+	Document model.CreditsConfigDocument `json:"document" binding:"required"`
+	Strategy string                      `json:"strategy" binding:"omitempty,oneof=skip overwrite fail"`
+	DryRun   bool                        `json:"dry_run"`
+}
+
+// ImportFileCreditsConfigs 从 JSON 文档导入文件积分配置（管理员）
+func ImportFileCreditsConfigs(c *gin.Context) {
+	var req ImportFileCreditsConfigsReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	user := c.MustGet("user").(*model.User)
+	result, err := op.ImportFileCreditsConfigs(&req.Document, req.Strategy, req.DryRun, user.ID)
+	if err != nil {
+		if result != nil {
+			common.ErrorWithDataResp(c, err, 409, result)
+		} else {
+			common.ErrorStrResp(c, err.Error(), 400)
+		}
+		return
+	}
+
+	common.SuccessResp(c, result)
+}
@@ -0,0 +1,75 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// ListCreditLedgerIssues 获取积分账本不一致记录（管理员），resolved=true 时返回已修复的记录
+func ListCreditLedgerIssues(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	resolved := c.Query("resolved") == "true"
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	issues, total, err := op.ListCreditLedgerIssues(resolved, page, pageSize)
+	if err != nil {
+		common.ErrorStrResp(c, err.Error(), 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"issues":    issues,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// AuditCreditLedger 立即校验所有积分账户（管理员）
+func AuditCreditLedger(c *gin.Context) {
+	count, err := op.AuditCreditLedger()
+	if err != nil {
+		common.ErrorStrResp(c, err.Error(), 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"inconsistent": count,
+	})
+}
+
+// RepairCreditLedgerReq 修复积分账户请求
+type RepairCreditLedgerReq struct {
+	UserID uint `json:"user_id" binding:"required"`
+}
+
+// RepairCreditLedger 将用户积分余额修正为交易记录的合计（管理员）
+func RepairCreditLedger(c *gin.Context) {
+	var req RepairCreditLedgerReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	admin := c.MustGet("user").(*model.User)
+
+	if err := op.RepairCreditLedger(admin.ID, req.UserID); err != nil {
+		common.ErrorStrResp(c, err.Error(), 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"message": "Credit ledger repaired successfully",
+	})
+}
@@ -0,0 +1,77 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultStatsCount 各统计粒度默认返回的周期数
+var defaultStatsCount = map[string]int{
+	model.StatsPeriodDay:   30,
+	model.StatsPeriodWeek:  12,
+	model.StatsPeriodMonth: 12,
+}
+
+// GetMyCreditStats 获取当前用户按周期汇总的积分收支
+func GetMyCreditStats(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+	creditStats(c, user.ID)
+}
+
+// GetCreditStats 获取全站按周期汇总的积分收支（管理员）
+func GetCreditStats(c *gin.Context) {
+	creditStats(c, 0)
+}
+
+func creditStats(c *gin.Context, userID uint) {
+	period := c.DefaultQuery("period", model.StatsPeriodDay)
+	def, ok := defaultStatsCount[period]
+	if !ok {
+		common.ErrorStrResp(c, "period must be one of day, week, month", 400)
+		return
+	}
+	count, _ := strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(def)))
+	if count < 1 || count > 366 {
+		count = def
+	}
+
+	buckets, err := op.GetCreditStats(userID, period, count)
+	if err != nil {
+		common.ErrorStrResp(c, err.Error(), 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"period":  period,
+		"buckets": buckets,
+	})
+}
+
+// GetCreditRanking 获取积分排行（管理员），by 为 spend、earn 或 balance，days 为统计天数，0 表示全部
+func GetCreditRanking(c *gin.Context) {
+	by := c.DefaultQuery("by", model.RankBySpend)
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if days < 0 {
+		days = 30
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	entries, err := op.GetCreditRanking(by, days, limit)
+	if err != nil {
+		common.ErrorStrResp(c, err.Error(), 400)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"by":      by,
+		"days":    days,
+		"entries": entries,
+	})
+}
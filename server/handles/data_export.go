@@ -0,0 +1,55 @@
+package handles
+
+import (
+	"fmt"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestDataExport 请求导出当前用户的个人数据，压缩包在后台生成
+func RequestDataExport(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+	export, err := op.RequestDataExport(user)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, export)
+}
+
+// GetDataExport 获取当前用户最近一次数据导出的状态
+func GetDataExport(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+	export, err := op.GetLatestDataExport(user.ID)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, gin.H{
+		"export": export,
+	})
+}
+
+// DownloadDataExport 通过邮件中的下载令牌下载数据导出的压缩包
+func DownloadDataExport(c *gin.Context) {
+	path, export, err := op.GetDataExportFile(c.Query("token"))
+	if err != nil {
+		common.ErrorResp(c, err, 404)
+		return
+	}
+	c.FileAttachment(path, fmt.Sprintf("data-export-%s.zip", export.CreatedAt.Format("20060102")))
+}
+
+// DownloadMyDataExport 登录用户直接下载自己最近一次生成的压缩包，未设置邮箱的用户通过此接口获取导出
+func DownloadMyDataExport(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+	path, export, err := op.GetUserDataExportFile(user.ID)
+	if err != nil {
+		common.ErrorResp(c, err, 404)
+		return
+	}
+	c.FileAttachment(path, fmt.Sprintf("data-export-%s.zip", export.CreatedAt.Format("20060102")))
+}
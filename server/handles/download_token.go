@@ -0,0 +1,99 @@
+package handles
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+type IssueDownloadTokenReq struct {
+	Path     string `json:"path" form:"path" binding:"required"`
+	Password string `json:"password" form:"password"`
+}
+
+type IssueDownloadTokenResp struct {
+	ID        uint      `json:"id"`
+	Token     string    `json:"token"`
+	URL       string    `json:"url"` // 凭令牌下载的链接，无需登录和签名
+	Credits   int64     `json:"credits"`
+	MaxUses   int       `json:"max_uses"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// IssueDownloadToken 预先扣除文件的下载积分并签发下载令牌，供下载器等无法携带登录凭证的客户端使用
+func IssueDownloadToken(c *gin.Context) {
+	var req IssueDownloadTokenReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	reqPath, err := user.JoinPath(req.Path)
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	if err = checkCreditsPreviewAccess(user, reqPath, req.Password); err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	obj, err := fs.Get(c.Request.Context(), reqPath, &fs.GetArgs{})
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	if obj.IsDir() {
+		common.ErrorStrResp(c, "path is a folder", 400)
+		return
+	}
+	token, err := op.IssueDownloadToken(user.ID, reqPath, clientMetadata(c))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, IssueDownloadTokenResp{
+		ID:        token.ID,
+		Token:     token.Token,
+		URL:       fmt.Sprintf("%s/d%s?token=%s", common.GetApiUrl(c), utils.EncodePath(reqPath, true), token.Token),
+		Credits:   token.Credits,
+		MaxUses:   token.MaxUses,
+		ExpiresAt: token.ExpiresAt,
+	})
+}
+
+// ListMyDownloadTokens 获取当前用户未过期的下载令牌
+func ListMyDownloadTokens(c *gin.Context) {
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	tokens, err := op.ListDownloadTokens(user.ID)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, tokens)
+}
+
+type RevokeDownloadTokenReq struct {
+	ID uint `json:"id" binding:"required"`
+}
+
+// RevokeMyDownloadToken 撤销当前用户的下载令牌
+func RevokeMyDownloadToken(c *gin.Context) {
+	var req RevokeDownloadTokenReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if err := op.RevokeDownloadToken(user.ID, req.ID); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c)
+}
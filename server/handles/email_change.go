@@ -0,0 +1,95 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestEmailChangeReq 发起邮箱更换请求
+type RequestEmailChangeReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// RequestEmailChange 发起邮箱更换，向原邮箱和新邮箱分别发送验证码
+func RequestEmailChange(c *gin.Context) {
+	var req RequestEmailChangeReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.MustGet("user").(*model.User)
+	if user.IsGuest() {
+		common.ErrorStrResp(c, "Guest user can not change email", 403)
+		return
+	}
+
+	if err := op.RequestEmailChange(user, req.Email); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"message": "Verification codes have been sent to the current and the new email.",
+	})
+}
+
+// ConfirmEmailChangeReq 确认邮箱更换请求，账户此前没有邮箱时 old_code 留空
+type ConfirmEmailChangeReq struct {
+	OldCode string `json:"old_code"`
+	NewCode string `json:"new_code" binding:"required"`
+}
+
+// ConfirmEmailChange 校验两个验证码并更新账户邮箱
+func ConfirmEmailChange(c *gin.Context) {
+	var req ConfirmEmailChangeReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.MustGet("user").(*model.User)
+
+	if err := op.ConfirmEmailChange(user, req.OldCode, req.NewCode, c.ClientIP()); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c)
+}
+
+// ListEmailChanges 获取邮箱更换的审计记录（管理员），可按 user_id 筛选
+func ListEmailChanges(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	var userID uint
+	if v := c.Query("user_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			common.ErrorStrResp(c, "invalid user_id", 400)
+			return
+		}
+		userID = uint(id)
+	}
+
+	changes, total, err := op.ListEmailChanges(userID, page, pageSize)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"changes":   changes,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
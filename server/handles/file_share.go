@@ -0,0 +1,187 @@
+package handles
+
+import (
+	"fmt"
+	stdpath "path"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/sign"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+type CreateFileShareReq struct {
+	Path        string `json:"path" form:"path" binding:"required"`
+	Password    string `json:"password" form:"password"`
+	ExpireHours int    `json:"expire_hours" form:"expire_hours"` // 0 表示不过期
+}
+
+// CreateFileShare 为文件创建分享链接，访客打开链接可查看文件，付费文件可由访客付款购买一次下载
+func CreateFileShare(c *gin.Context) {
+	var req CreateFileShareReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if user.IsGuest() {
+		common.ErrorStrResp(c, "Guest user can not create share links", 403)
+		return
+	}
+	reqPath, err := user.JoinPath(req.Path)
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	if err = checkCreditsPreviewAccess(user, reqPath, req.Password); err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	obj, err := fs.Get(c.Request.Context(), reqPath, &fs.GetArgs{})
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	if obj.IsDir() {
+		common.ErrorStrResp(c, "path is a folder", 400)
+		return
+	}
+	share, err := op.CreateFileShare(user.ID, reqPath, max(req.ExpireHours, 0))
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, share)
+}
+
+// ListMyFileShares 获取当前用户创建的分享链接
+func ListMyFileShares(c *gin.Context) {
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	shares, err := op.ListFileShares(user.ID)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, shares)
+}
+
+type DeleteFileShareReq struct {
+	ID uint `json:"id" binding:"required"`
+}
+
+// DeleteMyFileShare 删除当前用户的分享链接
+func DeleteMyFileShare(c *gin.Context) {
+	var req DeleteFileShareReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if err := op.DeleteFileShare(user.ID, req.ID); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+type FileShareResp struct {
+	Name    string      `json:"name"`
+	Size    int64       `json:"size"`
+	Credits int64       `json:"credits"`       // 下载所需积分，0 表示免费
+	Price   model.Money `json:"price"`         // 访客购买一次下载的金额
+	URL     string      `json:"url,omitempty"` // 免费文件的下载链接
+}
+
+// GetFileShare 访客查看分享的文件及购买下载的价格
+func GetFileShare(c *gin.Context) {
+	share, err := op.GetFileShare(c.Param("token"))
+	if err != nil {
+		common.ErrorResp(c, err, 404)
+		return
+	}
+	obj, err := fs.Get(c.Request.Context(), share.Path, &fs.GetArgs{})
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	credits, err := op.EstimateShareCredits(share)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	resp := FileShareResp{
+		Name:    obj.GetName(),
+		Size:    obj.GetSize(),
+		Credits: credits,
+	}
+	if credits > 0 {
+		if resp.Price, err = op.CreditsPrice(credits); err != nil {
+			common.ErrorResp(c, err, 500)
+			return
+		}
+	} else {
+		resp.URL = fmt.Sprintf("%s/d%s?sign=%s", common.GetApiUrl(c), utils.EncodePath(share.Path, true), sign.Sign(share.Path))
+	}
+	common.SuccessResp(c, resp)
+}
+
+type CreateShareOrderReq struct {
+	PaymentMethod string `json:"payment_method" binding:"required"`
+}
+
+type CreateShareOrderResp struct {
+	OrderNo    string      `json:"order_no"`
+	Amount     model.Money `json:"amount"`
+	ClaimToken string      `json:"claim_token"` // 凭此令牌查询订单并领取下载链接
+	ExpiresAt  time.Time   `json:"expires_at"`
+}
+
+// CreateShareOrder 访客无需注册，为分享的付费文件下单购买一次下载
+func CreateShareOrder(c *gin.Context) {
+	var req CreateShareOrderReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	order, err := op.CreateShareOrder(c.Param("token"), req.PaymentMethod)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, CreateShareOrderResp{
+		OrderNo:    order.OrderNo,
+		Amount:     order.Money,
+		ClaimToken: order.ClaimToken,
+		ExpiresAt:  order.ExpiresAt,
+	})
+}
+
+type ShareOrderResp struct {
+	Status    string     `json:"status"`
+	URL       string     `json:"url,omitempty"` // 订单支付后凭下载令牌下载的链接
+	Name      string     `json:"name,omitempty"`
+	MaxUses   int        `json:"max_uses,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// GetShareOrder 访客凭领取令牌查询订单，支付完成后返回下载链接
+func GetShareOrder(c *gin.Context) {
+	order, downloadToken, err := op.ClaimShareDownload(c.Param("token"), c.Query("claim"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	resp := ShareOrderResp{Status: order.Status}
+	if downloadToken != nil {
+		resp.URL = fmt.Sprintf("%s/d%s?token=%s", common.GetApiUrl(c), utils.EncodePath(downloadToken.Path, true), downloadToken.Token)
+		resp.Name = stdpath.Base(downloadToken.Path)
+		resp.MaxUses = downloadToken.MaxUses
+		resp.ExpiresAt = &downloadToken.ExpiresAt
+	}
+	common.SuccessResp(c, resp)
+}
@@ -0,0 +1,218 @@
+package handles
+
+import (
+	stdpath "path"
+	"path/filepath"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// maxCreditsPreviewPaths 单次按路径查询价格的最大数量
+const maxCreditsPreviewPaths = 500
+
+type FsCreditsPreviewReq struct {
+	Path     string   `json:"path" form:"path"`   // 目录路径，返回目录下所有条目的价格
+	Paths    []string `json:"paths" form:"paths"` // 文件路径列表，未指定目录时使用
+	Password string   `json:"password" form:"password"`
+	Action   string   `json:"action" form:"action"` // download 或 preview，默认 download
+}
+
+type FsCreditsPreviewItem struct {
+	Path            string `json:"path"`
+	IsDir           bool   `json:"is_dir"`
+	RequiredCredits int64  `json:"required_credits"`
+	CanDownload     bool   `json:"can_download"`
+	Error           string `json:"error,omitempty"`
+}
+
+// FsCreditsPreview 批量查询文件所需积分，供文件列表一次性标记付费文件
+func FsCreditsPreview(c *gin.Context) {
+	var req FsCreditsPreviewReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if req.Action == "" {
+		req.Action = model.CreditsActionDownload
+	}
+	if req.Action != model.CreditsActionDownload && req.Action != model.CreditsActionPreview {
+		common.ErrorStrResp(c, "invalid action", 400)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+
+	if req.Path == "" {
+		if len(req.Paths) == 0 {
+			common.ErrorStrResp(c, "path or paths is required", 400)
+			return
+		}
+		if len(req.Paths) > maxCreditsPreviewPaths {
+			common.ErrorStrResp(c, "too many paths", 400)
+			return
+		}
+		items := make([]FsCreditsPreviewItem, 0, len(req.Paths))
+		for _, p := range req.Paths {
+			item := FsCreditsPreviewItem{Path: p}
+			reqPath, err := user.JoinPath(p)
+			if err == nil {
+				err = checkCreditsPreviewAccess(user, reqPath, req.Password)
+			}
+			if err != nil {
+				item.Error = err.Error()
+			} else {
+				fillCreditsPreview(&item, user.ID, reqPath, req.Action, -1)
+			}
+			items = append(items, item)
+		}
+		common.SuccessResp(c, items)
+		return
+	}
+
+	reqPath, err := user.JoinPath(req.Path)
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	if err = checkCreditsPreviewAccess(user, reqPath, req.Password); err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	objs, err := fs.List(c.Request.Context(), reqPath, &fs.ListArgs{})
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	items := make([]FsCreditsPreviewItem, 0, len(objs))
+	for _, obj := range objs {
+		item := FsCreditsPreviewItem{Path: stdpath.Join(req.Path, obj.GetName()), IsDir: obj.IsDir()}
+		if obj.IsDir() {
+			item.CanDownload = true
+		} else {
+			fillCreditsPreview(&item, user.ID, stdpath.Join(reqPath, obj.GetName()), req.Action, obj.GetSize())
+		}
+		items = append(items, item)
+	}
+	common.SuccessResp(c, items)
+}
+
+// maxCreditsEstimateFiles 单次估算目录费用时最多统计的文件数量
+const maxCreditsEstimateFiles = 10000
+
+type FsCreditsEstimateReq struct {
+	Path     string `json:"path" form:"path" binding:"required"` // 目录路径
+	Password string `json:"password" form:"password"`
+	Action   string `json:"action" form:"action"` // download 或 preview，默认 download
+}
+
+type FsCreditsEstimateResp struct {
+	Path         string `json:"path"`
+	Files        int    `json:"files"`         // 文件总数
+	PaidFiles    int    `json:"paid_files"`    // 需要积分的文件数
+	SkippedDirs  int    `json:"skipped_dirs"`  // 无权访问而未统计的子目录数
+	TotalSize    int64  `json:"total_size"`    // 文件总大小
+	TotalCredits int64  `json:"total_credits"` // 所需积分合计
+	Available    int64  `json:"available"`     // 当前可用积分
+	Enough       bool   `json:"enough"`        // 可用积分是否足够
+}
+
+// FsCreditsEstimate 递归统计目录下所有文件的积分合计，供打包下载等目录下载前确认费用，
+// 计价与单个文件下载一致，但不计入每日免费额度
+func FsCreditsEstimate(c *gin.Context) {
+	var req FsCreditsEstimateReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if req.Action == "" {
+		req.Action = model.CreditsActionDownload
+	}
+	if req.Action != model.CreditsActionDownload && req.Action != model.CreditsActionPreview {
+		common.ErrorStrResp(c, "invalid action", 400)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	reqPath, err := user.JoinPath(req.Path)
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	if err = checkCreditsPreviewAccess(user, reqPath, req.Password); err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	obj, err := fs.Get(c.Request.Context(), reqPath, &fs.GetArgs{})
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	if !obj.IsDir() {
+		common.ErrorStrResp(c, "path is not a folder", 400)
+		return
+	}
+
+	resp := FsCreditsEstimateResp{Path: req.Path}
+	err = fs.WalkFS(c.Request.Context(), -1, reqPath, obj, func(filePath string, info model.Obj) error {
+		if info.IsDir() {
+			if filePath != reqPath && checkCreditsPreviewAccess(user, filePath, req.Password) != nil {
+				resp.SkippedDirs++
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if resp.Files >= maxCreditsEstimateFiles {
+			return errors.Errorf("more than %d files under %s", maxCreditsEstimateFiles, req.Path)
+		}
+		required, err := op.EstimateFileCredits(user.ID, filePath, req.Action, info.GetSize())
+		if err != nil {
+			return errors.WithMessagef(err, "failed to estimate credits of %s", filePath)
+		}
+		resp.Files++
+		resp.TotalSize += info.GetSize()
+		if required > 0 {
+			resp.PaidFiles++
+			resp.TotalCredits += required
+		}
+		return nil
+	})
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	credits, err := op.GetUserCredits(user.ID)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	resp.Available = credits.Available()
+	resp.Enough = resp.Available >= resp.TotalCredits
+	common.SuccessResp(c, resp)
+}
+
+// checkCreditsPreviewAccess 检查用户能否访问路径，与列目录的权限一致
+func checkCreditsPreviewAccess(user *model.User, reqPath, password string) error {
+	meta, err := op.GetNearestMeta(reqPath)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		return err
+	}
+	if !common.CanAccess(user, meta, reqPath, password) {
+		return errors.New("password is incorrect or you have no permission")
+	}
+	return nil
+}
+
+func fillCreditsPreview(item *FsCreditsPreviewItem, userID uint, reqPath, action string, size int64) {
+	allowed, required, err := op.CheckSizedFileAccessPermission(userID, reqPath, action, size)
+	if err != nil {
+		item.Error = err.Error()
+		return
+	}
+	item.CanDownload = allowed
+	item.RequiredCredits = required
+}
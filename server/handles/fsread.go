@@ -90,6 +90,7 @@ func FsList(c *gin.Context) {
 		common.ErrorResp(c, err, 500)
 		return
 	}
+	op.RecordApiCall(user, op.ApiList)
 	total, objs := pagination(objs, &req.PageReq)
 	provider := "unknown"
 	storage, err := fs.GetStorage(reqPath, &fs.GetStoragesArgs{})
@@ -279,23 +280,36 @@ func FsGet(c *gin.Context) {
 	if err == nil {
 		provider = storage.Config().Name
 	}
+	objSign := common.Sign(obj, stdpath.Dir(reqPath), isEncrypt(meta, reqPath))
 	if !obj.IsDir() {
 		if err != nil {
 			common.ErrorResp(c, err, 500)
 			return
 		}
+		paid := isPaidDownload(user, reqPath, obj.GetSize())
+		if paid {
+			// links of paid files are signed for the user, who is charged when a browser or player
+			// navigates to them without the login token
+			objSign = sign.SignUser(reqPath, user.ID)
+		}
 		if storage.Config().MustProxy() || storage.GetStorage().WebProxy {
 			rawURL = common.GenerateDownProxyURL(storage.GetStorage(), reqPath)
 			if rawURL == "" {
 				query := ""
-				if isEncrypt(meta, reqPath) || setting.GetBool(conf.SignAll) {
-					query = "?sign=" + sign.Sign(reqPath)
+				if paid || isEncrypt(meta, reqPath) || setting.GetBool(conf.SignAll) {
+					query = "?sign=" + objSign
 				}
 				rawURL = fmt.Sprintf("%s/p%s%s",
 					common.GetApiUrl(c),
 					utils.EncodePath(reqPath, true),
 					query)
 			}
+		} else if paid {
+			// paid files are only served by /d, which charges the download
+			rawURL = fmt.Sprintf("%s/d%s?sign=%s",
+				common.GetApiUrl(c),
+				utils.EncodePath(reqPath, true),
+				objSign)
 		} else {
 			// file have raw url
 			if url, ok := model.GetUrl(obj); ok {
@@ -335,7 +349,7 @@ func FsGet(c *gin.Context) {
 			Created:     obj.CreateTime(),
 			HashInfoStr: obj.GetHash().String(),
 			HashInfo:    obj.GetHash().Export(),
-			Sign:        common.Sign(obj, parentPath, isEncrypt(meta, reqPath)),
+			Sign:        objSign,
 			Type:        utils.GetFileType(obj.GetName()),
 			Thumb:       thumb,
 		},
@@ -347,6 +361,16 @@ func FsGet(c *gin.Context) {
 	})
 }
 
+// isPaidDownload reports whether downloading the file costs the user credits,
+// in which case the raw url of the storage must not be exposed
+func isPaidDownload(user *model.User, path string, size int64) bool {
+	if !setting.GetBool(conf.CreditsEnabled) {
+		return false
+	}
+	credits, err := op.EstimateFileCredits(user.ID, path, model.CreditsActionDownload, size)
+	return err != nil || credits > 0
+}
+
 func filterRelated(objs []model.Obj, obj model.Obj) []model.Obj {
 	var related []model.Obj
 	nameWithoutExt := strings.TrimSuffix(obj.GetName(), stdpath.Ext(obj.GetName()))
@@ -391,7 +415,21 @@ func FsOther(c *gin.Context) {
 		common.ErrorStrResp(c, "password is incorrect or you have no permission", 403)
 		return
 	}
+	// online preview is charged at the preview price of the file, the credits are held
+	// until the preview succeeds and released if it fails
+	var charge *op.DownloadCharge
+	if strings.HasSuffix(req.Method, "_preview") && setting.GetBool(conf.CreditsEnabled) {
+		if charge, err = op.BeginFilePreview(user.ID, req.Path, clientMetadata(c)); err != nil {
+			common.ErrorResp(c, err, 403)
+			return
+		}
+	}
 	res, err := fs.Other(c.Request.Context(), req.FsOtherArgs)
+	if charge != nil {
+		if ferr := charge.Finish(err == nil); ferr != nil {
+			utils.Log.Errorf("failed to settle the preview credits of user %d for %s: %+v", user.ID, req.Path, ferr)
+		}
+	}
 	if err != nil {
 		common.ErrorResp(c, err, 500)
 		return
@@ -0,0 +1,125 @@
+package handles
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// GenerateInviteCodesReq 生成邀请码请求
+type GenerateInviteCodesReq struct {
+	Count     int        `json:"count" binding:"required,min=1,max=1000"`
+	MaxUses   int        `json:"max_uses" binding:"min=0"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Note      string     `json:"note" binding:"max=200"`
+}
+
+// GenerateInviteCodes 生成邀请码（管理员）
+func GenerateInviteCodes(c *gin.Context) {
+	var req GenerateInviteCodesReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	user := c.MustGet("user").(*model.User)
+
+	codes, err := op.GenerateInviteCodes(user.ID, req.Count, req.MaxUses, req.ExpiresAt, req.Note)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, codes)
+}
+
+// ListInviteCodes 获取邀请码列表（管理员），可按 created_by 筛选
+func ListInviteCodes(c *gin.Context) {
+	page, pageSize := inviteCodesPage(c)
+	createdBy, _ := strconv.ParseUint(c.Query("created_by"), 10, 64)
+
+	codes, total, err := op.ListInviteCodes(uint(createdBy), page, pageSize)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"codes":     codes,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// ToggleInviteCodeReq 启用或停用邀请码请求
+type ToggleInviteCodeReq struct {
+	ID      uint `json:"id" binding:"required"`
+	Enabled bool `json:"enabled"`
+}
+
+// ToggleInviteCode 启用或停用邀请码（管理员）
+func ToggleInviteCode(c *gin.Context) {
+	var req ToggleInviteCodeReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	if err := op.SetInviteCodeEnabled(req.ID, req.Enabled); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"message": "Invite code updated successfully",
+	})
+}
+
+// CreateMyInviteCode 当前用户创建一个邀请码
+func CreateMyInviteCode(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+
+	code, err := op.CreateUserInviteCode(user.ID)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, code)
+}
+
+// ListMyInviteCodes 获取当前用户创建的邀请码
+func ListMyInviteCodes(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+	page, pageSize := inviteCodesPage(c)
+
+	codes, total, err := op.ListInviteCodes(user.ID, page, pageSize)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"codes":     codes,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+func inviteCodesPage(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	return page, pageSize
+}
@@ -0,0 +1,91 @@
+package handles
+
+import (
+	"errors"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/ldap.v3"
+)
+
+const ldapImportPageSize = 500
+
+type LdapImportResp struct {
+	Created int      `json:"created"`
+	Updated int      `json:"updated"`
+	Skipped []string `json:"skipped"` // local accounts of the same name, which are not managed by the directory
+	Failed  []string `json:"failed"`
+}
+
+// ImportLdapUsers creates accounts for every directory entry matched by the
+// import filter and applies the group to role mapping to the accounts created
+// from the directory. Local accounts of the same name are left alone.
+func ImportLdapUsers(c *gin.Context) {
+	if !setting.GetBool(conf.LdapLoginEnabled) {
+		common.ErrorStrResp(c, "ldap is not enabled", 403)
+		return
+	}
+	usernameAttribute := setting.GetStr(conf.LdapUsernameAttribute, "uid")
+	emailAttribute := setting.GetStr(conf.LdapEmailAttribute, "mail")
+
+	l, err := ldapConnect()
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	defer l.Close()
+
+	searchRequest := ldap.NewSearchRequest(
+		setting.GetStr(conf.LdapUserSearchBase),
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		setting.GetStr(conf.LdapImportFilter, "(objectClass=person)"),
+		[]string{"dn", usernameAttribute, emailAttribute, "memberOf"},
+		nil,
+	)
+	sr, err := l.SearchWithPaging(searchRequest, ldapImportPageSize)
+	if err != nil {
+		utils.Log.Errorf("LDAP search failed: %v", err)
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	resp := LdapImportResp{Skipped: []string{}, Failed: []string{}}
+	for _, entry := range sr.Entries {
+		username := entry.GetAttributeValue(usernameAttribute)
+		if username == "" {
+			continue
+		}
+		user, created, err := op.ImportDirectoryUser(newLdapUser(username), entry.DN, entry.GetAttributeValue(emailAttribute))
+		if errors.Is(err, errs.DirectoryAccountProtected) {
+			resp.Skipped = append(resp.Skipped, username)
+			continue
+		}
+		if err == nil && !created {
+			var managed bool
+			if managed, err = op.IsProvisionedBy(user.ID, model.IdentityProviderLDAP); err == nil && !managed {
+				resp.Skipped = append(resp.Skipped, username)
+				continue
+			}
+		}
+		if err == nil {
+			err = syncLdapRole(user, entry)
+		}
+		if err != nil {
+			utils.Log.Errorf("failed to import ldap user %s: %+v", username, err)
+			resp.Failed = append(resp.Failed, username)
+			continue
+		}
+		if created {
+			resp.Created++
+		} else {
+			resp.Updated++
+		}
+	}
+	common.SuccessResp(c, resp)
+}
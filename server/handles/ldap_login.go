@@ -7,7 +7,6 @@ import (
 	"strings"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
-	"github.com/OpenListTeam/OpenList/v4/internal/db"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
@@ -43,89 +42,82 @@ func loginLdap(c *gin.Context, req *LoginReq) {
 		return
 	}
 
-	// Auth start
-	ldapServer := setting.GetStr(conf.LdapServer)
-	ldapManagerDN := setting.GetStr(conf.LdapManagerDN)
-	ldapManagerPassword := setting.GetStr(conf.LdapManagerPassword)
-	ldapUserSearchBase := setting.GetStr(conf.LdapUserSearchBase)
-	ldapUserSearchFilter := setting.GetStr(conf.LdapUserSearchFilter) // (uid=%s)
+	entry, err := ldapAuthenticate(req.Username, req.Password)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		model.LoginCache.Set(ip, count+1)
+		return
+	}
+	emailAttribute := setting.GetStr(conf.LdapEmailAttribute, "mail")
 
-	// Connect to LdapServer
-	l, err := dial(ldapServer)
+	// an identity linked to an account takes precedence over the account of the same name
+	user, err := op.GetUserByIdentity(model.IdentityProviderLDAP, entry.DN)
 	if err != nil {
-		utils.Log.Errorf("failed to connect to LDAP: %v", err)
+		user, _, err = op.ImportDirectoryUser(newLdapUser(req.Username), entry.DN, entry.GetAttributeValue(emailAttribute))
+	}
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		model.LoginCache.Set(ip, count+1)
+		return
+	}
+	if err = syncLdapRole(user, entry); err != nil {
 		common.ErrorResp(c, err, 500)
 		return
 	}
 
-	// First bind with a read only user
-	if ldapManagerDN != "" && ldapManagerPassword != "" {
-		err = l.Bind(ldapManagerDN, ldapManagerPassword)
-		if err != nil {
-			utils.Log.Errorf("Failed to bind to LDAP: %v", err)
-			common.ErrorResp(c, err, 500)
-			return
-		}
+	// generate token
+	token, err := common.GenerateToken(user)
+	if err != nil {
+		common.ErrorResp(c, err, 400, true)
+		return
+	}
+	common.SuccessResp(c, gin.H{"token": token})
+	model.LoginCache.Del(ip)
+}
+
+// ldapAuthenticate searches the user in the directory and binds as the user to verify the password
+func ldapAuthenticate(username, password string) (*ldap.Entry, error) {
+	ldapUserSearchBase := setting.GetStr(conf.LdapUserSearchBase)
+	ldapUserSearchFilter := setting.GetStr(conf.LdapUserSearchFilter) // (uid=%s)
+	emailAttribute := setting.GetStr(conf.LdapEmailAttribute, "mail")
+
+	// Connect to LdapServer and bind with a read only user
+	l, err := ldapConnect()
+	if err != nil {
+		return nil, err
 	}
+	defer l.Close()
 
 	// Search for the given username
 	searchRequest := ldap.NewSearchRequest(
 		ldapUserSearchBase,
 		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
-		fmt.Sprintf(ldapUserSearchFilter, req.Username),
-		[]string{"dn"},
+		fmt.Sprintf(ldapUserSearchFilter, ldap.EscapeFilter(username)),
+		[]string{"dn", emailAttribute, "memberOf"},
 		nil,
 	)
 	sr, err := l.Search(searchRequest)
 	if err != nil {
 		utils.Log.Errorf("LDAP search failed: %v", err)
-		common.ErrorResp(c, err, 500)
-		return
+		return nil, err
 	}
 	if len(sr.Entries) != 1 {
 		utils.Log.Errorf("User does not exist or too many entries returned")
-		common.ErrorResp(c, err, 500)
-		return
+		return nil, errors.New("user does not exist or too many entries returned")
 	}
-	userDN := sr.Entries[0].DN
+	entry := sr.Entries[0]
 
 	// Bind as the user to verify their password
-	err = l.Bind(userDN, req.Password)
-	if err != nil {
+	if err = l.Bind(entry.DN, password); err != nil {
 		utils.Log.Errorf("Failed to auth. %v", err)
-		common.ErrorResp(c, err, 400)
-		model.LoginCache.Set(ip, count+1)
-		return
-	} else {
-		utils.Log.Infof("Auth successful username:%s", req.Username)
-	}
-	// Auth finished
-
-	user, err := op.GetUserByName(req.Username)
-	if err != nil {
-		user, err = ladpRegister(req.Username)
-		if err != nil {
-			common.ErrorResp(c, err, 400)
-			model.LoginCache.Set(ip, count+1)
-			return
-		}
-	}
-
-	// generate token
-	token, err := common.GenerateToken(user)
-	if err != nil {
-		common.ErrorResp(c, err, 400, true)
-		return
+		return nil, err
 	}
-	common.SuccessResp(c, gin.H{"token": token})
-	model.LoginCache.Del(ip)
+	utils.Log.Infof("Auth successful username:%s", username)
+	return entry, nil
 }
 
-func ladpRegister(username string) (*model.User, error) {
-	if username == "" {
-		return nil, errors.New("cannot get username from ldap provider")
-	}
-	user := &model.User{
+func newLdapUser(username string) *model.User {
+	return &model.User{
 		ID:         0,
 		Username:   username,
 		Password:   random.String(16),
@@ -134,10 +126,45 @@ func ladpRegister(username string) (*model.User, error) {
 		Role:       0,
 		Disabled:   false,
 	}
-	if err := db.CreateUser(user); err != nil {
+}
+
+// syncLdapRole grants the admin role to members of the configured admin
+// groups and revokes it from everyone else. Only accounts created from the
+// directory are synced, local accounts of the same name keep their role.
+func syncLdapRole(user *model.User, entry *ldap.Entry) error {
+	adminGroups := setting.GetStr(conf.LdapAdminGroups)
+	if adminGroups == "" {
+		return nil
+	}
+	admin := false
+	for _, group := range strings.Split(adminGroups, ",") {
+		group = strings.TrimSpace(group)
+		for _, memberOf := range entry.GetAttributeValues("memberOf") {
+			if group != "" && strings.EqualFold(group, memberOf) {
+				admin = true
+			}
+		}
+	}
+	return op.SyncSSORole(user, model.IdentityProviderLDAP, admin)
+}
+
+// ldapConnect dials the LDAP server and binds with the manager account if one is configured
+func ldapConnect() (*ldap.Conn, error) {
+	l, err := dial(setting.GetStr(conf.LdapServer))
+	if err != nil {
+		utils.Log.Errorf("failed to connect to LDAP: %v", err)
 		return nil, err
 	}
-	return user, nil
+	ldapManagerDN := setting.GetStr(conf.LdapManagerDN)
+	ldapManagerPassword := setting.GetStr(conf.LdapManagerPassword)
+	if ldapManagerDN != "" && ldapManagerPassword != "" {
+		if err = l.Bind(ldapManagerDN, ldapManagerPassword); err != nil {
+			utils.Log.Errorf("Failed to bind to LDAP: %v", err)
+			l.Close()
+			return nil, err
+		}
+	}
+	return l, nil
 }
 
 func dial(ldapServer string) (*ldap.Conn, error) {
@@ -0,0 +1,66 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/mail"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// ListMailDeliveries 获取邮件发送记录（管理员），status=dead 时为死信列表
+func ListMailDeliveries(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	deliveries, total, err := op.ListMailDeliveries(c.Query("status"), page, pageSize)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"deliveries": deliveries,
+		"total":      total,
+		"page":       page,
+		"page_size":  pageSize,
+	})
+}
+
+// ResendMailDelivery 立即重新发送一封失败或进入死信列表的邮件（管理员）
+func ResendMailDelivery(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	delivery, err := op.ResendMailDelivery(uint(id))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, delivery)
+}
+
+// ListMailProviders 获取支持的邮件服务（管理员）
+func ListMailProviders(c *gin.Context) {
+	common.SuccessResp(c, mail.GetProviderDriverNames())
+}
+
+// TestAdminNotification 向已配置的管理员通知渠道发送一条测试通知（管理员）
+func TestAdminNotification(c *gin.Context) {
+	if err := op.SendTestAdminNotification(); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c)
+}
@@ -0,0 +1,88 @@
+package handles
+
+import (
+	"errors"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/go-cache"
+	"github.com/gin-gonic/gin"
+)
+
+// passwordResetIPCache counts the password reset requests of each client ip
+var passwordResetIPCache = cache.NewMemCache[int]()
+
+const passwordResetMaxRequestsPerIP = 10
+
+type RequestPasswordResetReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// RequestPasswordReset mails a reset code to the email of a registered user.
+// It answers the same whether or not the email belongs to an account.
+func RequestPasswordReset(c *gin.Context) {
+	var req RequestPasswordResetReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	ip := c.ClientIP()
+	count, _ := passwordResetIPCache.Get(ip)
+	if count >= passwordResetMaxRequestsPerIP {
+		common.ErrorResp(c, errs.PasswordResetLimited, 429)
+		return
+	}
+	passwordResetIPCache.Set(ip, count+1, cache.WithEx[int](model.DefaultLockDuration))
+	if err := op.RequestPasswordReset(req.Email); err != nil {
+		if errors.Is(err, errs.PasswordResetLimited) {
+			common.ErrorResp(c, err, 429)
+		} else {
+			common.ErrorResp(c, err, 500, true)
+		}
+		return
+	}
+	common.SuccessResp(c, gin.H{
+		"message": "If the email belongs to an account, a reset code has been sent to it.",
+	})
+}
+
+type VerifyPasswordResetReq struct {
+	Email string `json:"email" binding:"required,email"`
+	Code  string `json:"code" binding:"required"`
+}
+
+// VerifyPasswordReset checks a reset code and returns the token used to set the new password
+func VerifyPasswordReset(c *gin.Context) {
+	var req VerifyPasswordResetReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	token, err := op.VerifyPasswordResetCode(req.Email, req.Code)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, gin.H{"token": token})
+}
+
+type ResetPasswordReq struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// ResetPassword sets a new password, signing the user out everywhere
+func ResetPassword(c *gin.Context) {
+	var req ResetPasswordReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := op.ResetPassword(req.Token, req.Password); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c)
+}
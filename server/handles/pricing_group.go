@@ -0,0 +1,98 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// ListPricingGroups 获取定价组列表（管理员）
+func ListPricingGroups(c *gin.Context) {
+	groups, err := op.ListPricingGroups()
+	if err != nil {
+		common.ErrorStrResp(c, err.Error(), 500)
+		return
+	}
+	common.SuccessResp(c, groups)
+}
+
+// SavePricingGroupReq 保存定价组请求
+type SavePricingGroupReq struct {
+	ID                 uint    `json:"id"`
+	Name               string  `json:"name" binding:"required,max=50"`
+	Multiplier         float64 `json:"multiplier" binding:"min=0"`
+	Exempt             bool    `json:"exempt"`
+	FreeDailyDownloads int64   `json:"free_daily_downloads" binding:"min=0"`
+	FreeDailyGB        int64   `json:"free_daily_gb" binding:"min=0"`
+	Description        string  `json:"description" binding:"max=500"`
+}
+
+// SavePricingGroup 创建或更新定价组（管理员）
+func SavePricingGroup(c *gin.Context) {
+	var req SavePricingGroupReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	group := &model.PricingGroup{
+		ID:                 req.ID,
+		Name:               req.Name,
+		Multiplier:         req.Multiplier,
+		Exempt:             req.Exempt,
+		FreeDailyDownloads: req.FreeDailyDownloads,
+		FreeDailyGB:        req.FreeDailyGB,
+		Description:        req.Description,
+	}
+	if err := op.SavePricingGroup(group); err != nil {
+		common.ErrorStrResp(c, err.Error(), 400)
+		return
+	}
+
+	common.SuccessResp(c, group)
+}
+
+// DeletePricingGroup 删除定价组（管理员）
+func DeletePricingGroup(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	if err = op.DeletePricingGroup(uint(id)); err != nil {
+		common.ErrorStrResp(c, err.Error(), 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"message": "Pricing group deleted successfully",
+	})
+}
+
+// AssignPricingGroupReq 设置用户定价组请求
+type AssignPricingGroupReq struct {
+	UserID  uint `json:"user_id" binding:"required"`
+	GroupID uint `json:"group_id"` // 为0时移出定价组
+}
+
+// AssignPricingGroup 设置用户所属的定价组（管理员）
+func AssignPricingGroup(c *gin.Context) {
+	var req AssignPricingGroupReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	if err := op.SetUserPricingGroup(req.UserID, req.GroupID); err != nil {
+		common.ErrorStrResp(c, err.Error(), 400)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"message": "Pricing group assigned successfully",
+	})
+}
@@ -0,0 +1,103 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// ListPricingRules 获取路径计价规则列表（管理员）
+func ListPricingRules(c *gin.Context) {
+	rules, err := op.ListPricingRules()
+	if err != nil {
+		common.ErrorStrResp(c, err.Error(), 500)
+		return
+	}
+	common.SuccessResp(c, rules)
+}
+
+// SavePricingRuleReq 保存路径计价规则请求
+type SavePricingRuleReq struct {
+	ID             uint   `json:"id"`
+	Pattern        string `json:"pattern" binding:"required,max=500"`
+	MatchType      string `json:"match_type" binding:"omitempty,oneof=glob regex"`
+	Priority       int    `json:"priority"`
+	Credits        int64  `json:"credits" binding:"min=0"`
+	PreviewCredits int64  `json:"preview_credits" binding:"min=0"`
+	PricingMode    string `json:"pricing_mode" binding:"omitempty,oneof=flat per_mb per_gb"`
+	Enabled        bool   `json:"enabled"`
+	Description    string `json:"description" binding:"max=500"`
+}
+
+// SavePricingRule 创建或更新路径计价规则（管理员）
+func SavePricingRule(c *gin.Context) {
+	var req SavePricingRuleReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	rule := &model.PricingRule{
+		ID:             req.ID,
+		Pattern:        req.Pattern,
+		MatchType:      req.MatchType,
+		Priority:       req.Priority,
+		Credits:        req.Credits,
+		PreviewCredits: req.PreviewCredits,
+		PricingMode:    req.PricingMode,
+		Enabled:        req.Enabled,
+		Description:    req.Description,
+	}
+	if err := op.SavePricingRule(rule); err != nil {
+		common.ErrorStrResp(c, err.Error(), 400)
+		return
+	}
+
+	common.SuccessResp(c, rule)
+}
+
+// DeletePricingRule 删除路径计价规则（管理员）
+func DeletePricingRule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	if err = op.DeletePricingRule(uint(id)); err != nil {
+		common.ErrorStrResp(c, err.Error(), 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"message": "Pricing rule deleted successfully",
+	})
+}
+
+// DryRunFilePricing 试算文件按当前配置和规则的价格（管理员），未指定 size 时使用实际文件大小
+func DryRunFilePricing(c *gin.Context) {
+	path := c.Query("path")
+	if path == "" {
+		common.ErrorStrResp(c, "path is required", 400)
+		return
+	}
+	size := int64(-1)
+	if s := c.Query("size"); s != "" {
+		var err error
+		if size, err = strconv.ParseInt(s, 10, 64); err != nil || size < 0 {
+			common.ErrorStrResp(c, "invalid size", 400)
+			return
+		}
+	}
+
+	result, err := op.DryRunFilePricing(path, size)
+	if err != nil {
+		common.ErrorStrResp(c, err.Error(), 400)
+		return
+	}
+
+	common.SuccessResp(c, result)
+}
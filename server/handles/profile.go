@@ -0,0 +1,98 @@
+package handles
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// GetProfile 获取当前用户的资料
+func GetProfile(c *gin.Context) {
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	common.SuccessResp(c, op.GetUserProfile(user))
+}
+
+// UpdateProfileReq 更新用户资料请求
+type UpdateProfileReq struct {
+	Nickname string `json:"nickname"`
+	Avatar   string `json:"avatar"` // 头像地址，为空时使用 gravatar
+	Bio      string `json:"bio"`
+}
+
+// UpdateProfile 更新当前用户的资料
+func UpdateProfile(c *gin.Context) {
+	var req UpdateProfileReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if user.IsGuest() {
+		common.ErrorStrResp(c, "Guest user can not update profile", 403)
+		return
+	}
+
+	if err := op.UpdateUserProfile(user, req.Nickname, req.Avatar, req.Bio); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, op.GetUserProfile(user))
+}
+
+// UploadAvatar 上传当前用户的头像，表单字段为 file
+func UploadAvatar(c *gin.Context) {
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if user.IsGuest() {
+		common.ErrorStrResp(c, "Guest user can not update profile", 403)
+		return
+	}
+	file, err := c.FormFile("file")
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if file.Size > op.MaxAvatarSize {
+		common.ErrorStrResp(c, "avatar is too large", 400)
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, op.MaxAvatarSize+1))
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	if err = op.SaveUserAvatar(user, data); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, op.GetUserProfile(user))
+}
+
+// GetAvatar 获取用户上传的头像
+func GetAvatar(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	path, err := op.GetUserAvatarFile(uint(id))
+	if err != nil {
+		common.ErrorResp(c, err, 404)
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.File(path)
+}
@@ -0,0 +1,81 @@
+package handles
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// ListPromotions 获取促销活动列表（管理员）
+func ListPromotions(c *gin.Context) {
+	promotions, err := op.ListPromotions()
+	if err != nil {
+		common.ErrorStrResp(c, err.Error(), 500)
+		return
+	}
+	common.SuccessResp(c, promotions)
+}
+
+// SavePromotionReq 保存促销活动请求
+type SavePromotionReq struct {
+	ID          uint       `json:"id"`
+	Name        string     `json:"name" binding:"required,max=100"`
+	Paths       string     `json:"paths" binding:"required"`
+	Discount    int        `json:"discount" binding:"required,min=1,max=100"`
+	StartsAt    *time.Time `json:"starts_at"`
+	EndsAt      *time.Time `json:"ends_at"`
+	Cron        string     `json:"cron" binding:"max=100"`
+	Duration    int        `json:"duration" binding:"min=0"`
+	Enabled     bool       `json:"enabled"`
+	Description string     `json:"description" binding:"max=500"`
+}
+
+// SavePromotion 创建或更新促销活动（管理员）
+func SavePromotion(c *gin.Context) {
+	var req SavePromotionReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	promotion := &model.Promotion{
+		ID:          req.ID,
+		Name:        req.Name,
+		Paths:       req.Paths,
+		Discount:    req.Discount,
+		StartsAt:    req.StartsAt,
+		EndsAt:      req.EndsAt,
+		Cron:        req.Cron,
+		Duration:    req.Duration,
+		Enabled:     req.Enabled,
+		Description: req.Description,
+	}
+	if err := op.SavePromotion(promotion); err != nil {
+		common.ErrorStrResp(c, err.Error(), 400)
+		return
+	}
+
+	common.SuccessResp(c, promotion)
+}
+
+// DeletePromotion 删除促销活动（管理员）
+func DeletePromotion(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	if err = op.DeletePromotion(uint(id)); err != nil {
+		common.ErrorStrResp(c, err.Error(), 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"message": "Promotion deleted successfully",
+	})
+}
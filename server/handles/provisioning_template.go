@@ -0,0 +1,78 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// ListProvisioningTemplates 获取用户模板列表（管理员）
+func ListProvisioningTemplates(c *gin.Context) {
+	templates, err := op.ListProvisioningTemplates()
+	if err != nil {
+		common.ErrorStrResp(c, err.Error(), 500)
+		return
+	}
+	common.SuccessResp(c, templates)
+}
+
+// SaveProvisioningTemplateReq 保存用户模板请求
+type SaveProvisioningTemplateReq struct {
+	ID             uint   `json:"id"`
+	Name           string `json:"name" binding:"required,max=50"`
+	Role           int    `json:"role"`
+	BasePath       string `json:"base_path"` // 支持 {username} 占位符
+	Permission     int32  `json:"permission" binding:"min=0"`
+	PricingGroupID uint   `json:"pricing_group_id"`
+	WelcomeCredits int64  `json:"welcome_credits" binding:"min=0"`
+	IsDefault      bool   `json:"is_default"`
+	Description    string `json:"description" binding:"max=500"`
+}
+
+// SaveProvisioningTemplate 创建或更新用户模板（管理员）
+func SaveProvisioningTemplate(c *gin.Context) {
+	var req SaveProvisioningTemplateReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	template := &model.ProvisioningTemplate{
+		ID:             req.ID,
+		Name:           req.Name,
+		Role:           req.Role,
+		BasePath:       req.BasePath,
+		Permission:     req.Permission,
+		PricingGroupID: req.PricingGroupID,
+		WelcomeCredits: req.WelcomeCredits,
+		IsDefault:      req.IsDefault,
+		Description:    req.Description,
+	}
+	if err := op.SaveProvisioningTemplate(template); err != nil {
+		common.ErrorStrResp(c, err.Error(), 400)
+		return
+	}
+
+	common.SuccessResp(c, template)
+}
+
+// DeleteProvisioningTemplate 删除用户模板（管理员）
+func DeleteProvisioningTemplate(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	if err = op.DeleteProvisioningTemplate(uint(id)); err != nil {
+		common.ErrorStrResp(c, err.Error(), 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"message": "Provisioning template deleted successfully",
+	})
+}
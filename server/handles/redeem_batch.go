@@ -0,0 +1,414 @@
+package handles
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/skip2/go-qrcode"
+)
+
+// ListRedeemBatches 获取兑换码批次列表（管理员）
+func ListRedeemBatches(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	batches, total, err := op.ListRedeemBatches(page, pageSize)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"batches":   batches,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+var redeemCodeCSVHeader = []string{"code", "credits", "reward_type", "reward_payload", "max_uses", "used_count", "enabled", "expires_at", "url"}
+
+// redeemURL 兑换码的兑换链接，登录后前端会自动填入并提交兑换码
+func redeemURL(c *gin.Context, code string) string {
+	return common.GetApiUrl(c.Request.Context()) + "/redeem?code=" + url.QueryEscape(code)
+}
+
+// ExportRedeemBatch 导出兑换码批次（管理员），format=csv 导出表格，format=html 导出可打印的卡片页，
+// 卡片页包含兑换码、二维码和面值，可在浏览器中直接打印或另存为 PDF
+func ExportRedeemBatch(c *gin.Context) {
+	batchNo := c.Query("batch_no")
+	if batchNo == "" {
+		common.ErrorStrResp(c, "batch_no is required", 400)
+		return
+	}
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "html" {
+		common.ErrorStrResp(c, "invalid format", 400)
+		return
+	}
+
+	batch, codes, err := op.GetRedeemBatch(batchNo)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	if format == "html" {
+		exportRedeemCards(c, batch, codes)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="redeem_codes_%s.csv"`, batch.BatchNo))
+	c.Status(200)
+	// BOM so that spreadsheet software detects UTF-8
+	_, _ = c.Writer.WriteString("\xEF\xBB\xBF")
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write(redeemCodeCSVHeader)
+	for _, code := range codes {
+		expiresAt := ""
+		if code.ExpiresAt != nil {
+			expiresAt = code.ExpiresAt.Format(time.RFC3339)
+		}
+		_ = w.Write([]string{
+			code.Code,
+			strconv.FormatInt(code.Credits, 10),
+			code.RewardType,
+			code.RewardPayload,
+			strconv.Itoa(code.MaxUses),
+			strconv.Itoa(code.UsedCount),
+			strconv.FormatBool(code.Enabled),
+			expiresAt,
+			redeemURL(c, code.Code),
+		})
+	}
+	w.Flush()
+	if err = w.Error(); err != nil {
+		utils.Log.Errorf("failed to export redeem codes: %+v", err)
+	}
+}
+
+const (
+	defaultRedeemQRSize = 256
+	maxRedeemQRSize     = 1024
+)
+
+// RedeemCodeQR 将兑换码渲染为二维码 PNG（管理员），content=url（默认）时内容为兑换链接，content=code 时为兑换码本身
+func RedeemCodeQR(c *gin.Context) {
+	code, err := op.GetRedeemCode(c.Query("code"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	size, err := strconv.Atoi(c.DefaultQuery("size", strconv.Itoa(defaultRedeemQRSize)))
+	if err != nil || size < 64 || size > maxRedeemQRSize {
+		common.ErrorStrResp(c, fmt.Sprintf("size must be between 64 and %d", maxRedeemQRSize), 400)
+		return
+	}
+	content := redeemURL(c, code.Code)
+	switch c.DefaultQuery("content", "url") {
+	case "url":
+	case "code":
+		content = code.Code
+	default:
+		common.ErrorStrResp(c, "invalid content", 400)
+		return
+	}
+
+	png, err := qrcode.Encode(content, qrcode.Medium, size)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s.png"`, code.Code))
+	c.Data(200, "image/png", png)
+}
+
+type redeemCard struct {
+	Code      string
+	Value     string
+	ExpiresAt *time.Time
+	QRCode    template.URL
+}
+
+var redeemCardsTemplate = template.Must(template.New("redeem_cards").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Batch.BatchNo}}</title>
+<style>
+body { font-family: sans-serif; margin: 0; }
+.sheet { display: flex; flex-wrap: wrap; gap: 4mm; padding: 8mm; }
+.card { width: 85mm; height: 54mm; box-sizing: border-box; border: 1px dashed #999; padding: 4mm;
+  display: flex; align-items: center; gap: 4mm; page-break-inside: avoid; break-inside: avoid; }
+.card img { width: 30mm; height: 30mm; }
+.value { font-size: 20pt; font-weight: bold; }
+.code { font-family: monospace; font-size: 12pt; margin-top: 2mm; word-break: break-all; }
+.meta { font-size: 8pt; color: #666; margin-top: 2mm; }
+@media print { .card { border-color: #ccc; } }
+</style>
+</head>
+<body>
+<div class="sheet">
+{{range .Cards}}<div class="card">
+<img src="{{.QRCode}}" alt="">
+<div>
+<div class="value">{{.Value}}</div>
+<div class="code">{{.Code}}</div>
+{{if .ExpiresAt}}<div class="meta">Valid until {{.ExpiresAt.Format "2006-01-02"}}</div>{{end}}
+{{if $.Batch.Description}}<div class="meta">{{$.Batch.Description}}</div>{{end}}
+</div>
+</div>
+{{end}}</div>
+</body>
+</html>
+`))
+
+// redeemCardValue 卡片上展示的奖励
+func redeemCardValue(code model.RedeemCode) string {
+	switch code.RewardType {
+	case model.RedeemRewardVipDays:
+		return code.RewardPayload + " days VIP"
+	case model.RedeemRewardFileUnlock:
+		return "Unlock " + path.Base(code.RewardPayload)
+	default:
+		return strconv.FormatInt(code.Credits, 10) + " credits"
+	}
+}
+
+// exportRedeemCards 输出兑换码卡片页，每张卡片的二维码内容为兑换链接
+func exportRedeemCards(c *gin.Context, batch *model.RedeemBatch, codes []model.RedeemCode) {
+	cards := make([]redeemCard, 0, len(codes))
+	for _, code := range codes {
+		png, err := qrcode.Encode(redeemURL(c, code.Code), qrcode.Medium, 256)
+		if err != nil {
+			common.ErrorResp(c, err, 500)
+			return
+		}
+		cards = append(cards, redeemCard{
+			Code:      code.Code,
+			Value:     redeemCardValue(code),
+			ExpiresAt: code.ExpiresAt,
+			QRCode:    template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(png)),
+		})
+	}
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="redeem_cards_%s.html"`, batch.BatchNo))
+	c.Status(200)
+	err := redeemCardsTemplate.Execute(c.Writer, gin.H{"Batch": batch, "Cards": cards})
+	if err != nil {
+		utils.Log.Errorf("failed to export redeem cards: %+v", err)
+	}
+}
+
+// ListRedeemCampaigns 获取兑换码活动列表（管理员）
+func ListRedeemCampaigns(c *gin.Context) {
+	campaigns, err := op.ListRedeemCampaigns()
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, campaigns)
+}
+
+// SaveRedeemCampaignReq 保存兑换码活动请求
+type SaveRedeemCampaignReq struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name" binding:"required,max=100"`
+	Channel     string `json:"channel" binding:"max=100"`
+	Budget      int64  `json:"budget" binding:"min=0"`
+	Description string `json:"description" binding:"max=500"`
+}
+
+// SaveRedeemCampaign 创建或更新兑换码活动（管理员）
+func SaveRedeemCampaign(c *gin.Context) {
+	var req SaveRedeemCampaignReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	campaign := &model.RedeemCampaign{
+		ID:          req.ID,
+		Name:        req.Name,
+		Channel:     req.Channel,
+		Budget:      req.Budget,
+		Description: req.Description,
+	}
+	if err := op.SaveRedeemCampaign(campaign); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, campaign)
+}
+
+// DeleteRedeemCampaign 删除兑换码活动（管理员）
+func DeleteRedeemCampaign(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	if err = op.DeleteRedeemCampaign(uint(id)); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"message": "Redeem campaign deleted successfully",
+	})
+}
+
+// GetRedeemCampaignStats 获取兑换码活动的兑换统计（管理员）
+func GetRedeemCampaignStats(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	stats, err := op.GetRedeemCampaignStats(uint(id))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, stats)
+}
+
+// RevokeRedeemCodesReq 作废兑换码请求
+type RevokeRedeemCodesReq struct {
+	Scope  string                 `json:"scope" binding:"required,oneof=code batch filter"`
+	Target string                 `json:"target"` // scope 为 code 时为兑换码，为 batch 时为批次号
+	Filter model.RedeemCodeFilter `json:"filter"` // scope 为 filter 时的筛选条件，只作废未使用的兑换码
+	Reason string                 `json:"reason" binding:"required,max=500"`
+}
+
+// RevokeRedeemCodes 立即作废单个兑换码、整个批次或符合条件的未使用兑换码（管理员）
+func RevokeRedeemCodes(c *gin.Context) {
+	var req RevokeRedeemCodesReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	admin := c.MustGet("user").(*model.User)
+
+	count, err := op.RevokeRedeemCodes(admin.ID, req.Scope, req.Target, req.Filter, req.Reason)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"revoked": count,
+	})
+}
+
+// ListRedeemCodeRevocations 获取兑换码作废记录（管理员）
+func ListRedeemCodeRevocations(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	revocations, total, err := op.ListRedeemCodeRevocations(page, pageSize)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"revocations": revocations,
+		"total":       total,
+		"page":        page,
+		"page_size":   pageSize,
+	})
+}
+
+// ListRedeemCodeUsages 按兑换码、批次、用户和使用时间筛选兑换码使用记录（管理员）
+func ListRedeemCodeUsages(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	filter := model.RedeemCodeUsageFilter{
+		Code:    c.Query("code"),
+		BatchNo: c.Query("batch_no"),
+	}
+	if v := c.Query("user_id"); v != "" {
+		userID, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			common.ErrorStrResp(c, "invalid user_id", 400)
+			return
+		}
+		filter.UserID = uint(userID)
+	}
+	var err error
+	if filter.Start, err = parseTimeQuery(c, "start"); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if filter.End, err = parseTimeQuery(c, "end"); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	usages, total, err := op.ListRedeemCodeUsages(filter, page, pageSize)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"usages":    usages,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetRedeemCodeDetail 获取兑换码详情及剩余次数，用于排查兑换失败（管理员）
+func GetRedeemCodeDetail(c *gin.Context) {
+	code := c.Query("code")
+	if code == "" {
+		common.ErrorStrResp(c, "code is required", 400)
+		return
+	}
+
+	detail, err := op.GetRedeemCodeDetail(code)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, detail)
+}
@@ -0,0 +1,51 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// GetReferralStats 获取当前用户的推荐码及推荐统计
+func GetReferralStats(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+
+	stats, err := op.GetReferralStats(user.ID)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, stats)
+}
+
+// ListReferrals 获取当前用户推荐的用户列表
+func ListReferrals(c *gin.Context) {
+	user := c.MustGet("user").(*model.User)
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	referrals, total, err := op.ListReferrals(user.ID, page, pageSize)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"referrals": referrals,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
@@ -0,0 +1,113 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// RewardCallbackReq 外部奖励回调请求，可通过查询参数或请求体传递
+type RewardCallbackReq struct {
+	UserID        uint   `json:"user_id" form:"user_id" binding:"required"`
+	Amount        int64  `json:"amount" form:"amount" binding:"required"`
+	TransactionID string `json:"transaction_id" form:"transaction_id" binding:"required,max=128"`
+	Nonce         string `json:"nonce" form:"nonce" binding:"required,max=64"`
+	Sign          string `json:"sign" form:"sign" binding:"required"`
+}
+
+// RewardCallback 外部广告、任务墙平台的服务端回调，校验签名后为用户发放积分
+func RewardCallback(c *gin.Context) {
+	var req RewardCallbackReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	reward, err := op.GrantExternalReward(c.Param("source"), req.UserID, req.Amount, req.TransactionID, req.Nonce, req.Sign)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.InvalidRewardSign):
+			common.ErrorResp(c, err, 401)
+		case errors.Is(err, errs.RewardReplayed):
+			common.ErrorResp(c, err, 409)
+		case errors.Is(err, errs.RewardCapExceeded):
+			common.ErrorResp(c, err, 429)
+		default:
+			common.ErrorResp(c, err, 400)
+		}
+		return
+	}
+
+	common.SuccessResp(c, reward)
+}
+
+// ListRewardSources 获取外部奖励来源列表（管理员）
+func ListRewardSources(c *gin.Context) {
+	sources, err := op.ListRewardSources()
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, sources)
+}
+
+// SaveRewardSourceReq 保存外部奖励来源请求
+type SaveRewardSourceReq struct {
+	ID           uint   `json:"id"`
+	Name         string `json:"name" binding:"required,max=64"`
+	Secret       string `json:"secret" binding:"required,max=256"`
+	MaxAmount    int64  `json:"max_amount" binding:"min=0"`
+	UserDailyCap int64  `json:"user_daily_cap" binding:"min=0"`
+	DailyCap     int64  `json:"daily_cap" binding:"min=0"`
+	Enabled      bool   `json:"enabled"`
+	Description  string `json:"description" binding:"max=500"`
+}
+
+// SaveRewardSource 创建或更新外部奖励来源（管理员）
+func SaveRewardSource(c *gin.Context) {
+	var req SaveRewardSourceReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	source := &model.RewardSource{
+		ID:           req.ID,
+		Name:         req.Name,
+		Secret:       req.Secret,
+		MaxAmount:    req.MaxAmount,
+		UserDailyCap: req.UserDailyCap,
+		DailyCap:     req.DailyCap,
+		Enabled:      req.Enabled,
+		Description:  req.Description,
+	}
+	if err := op.SaveRewardSource(source); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, source)
+}
+
+// DeleteRewardSource 删除外部奖励来源（管理员）
+func DeleteRewardSource(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	if err = op.DeleteRewardSource(uint(id)); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"message": "Reward source deleted successfully",
+	})
+}
@@ -49,6 +49,7 @@ func Search(c *gin.Context) {
 		common.ErrorResp(c, err, 500)
 		return
 	}
+	op.RecordApiCall(user, op.ApiSearch)
 	var filteredNodes []model.SearchNode
 	for _, node := range nodes {
 		if !strings.HasPrefix(node.Parent, user.BasePath) {
@@ -13,8 +13,9 @@ import (
 	"github.com/OpenListTeam/go-cache"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
-	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
@@ -70,6 +71,9 @@ func SSOLoginRedirect(c *gin.Context) {
 		common.ErrorStrResp(c, "no method provided", 400)
 		return
 	}
+	if method == "get_sso_id" {
+		startSSOLinkSession(c)
+	}
 	redirectUri := ssoRedirectUri(c, useCompatibility, method)
 	urlValues.Add("response_type", "code")
 	urlValues.Add("redirect_uri", redirectUri)
@@ -77,14 +81,14 @@ func SSOLoginRedirect(c *gin.Context) {
 	switch platform {
 	case "Github":
 		rUrl = "https://github.com/login/oauth/authorize?"
-		urlValues.Add("scope", "read:user")
+		urlValues.Add("scope", "read:user user:email")
 	case "Microsoft":
 		rUrl = "https://login.microsoftonline.com/common/oauth2/v2.0/authorize?"
 		urlValues.Add("scope", "user.read")
 		urlValues.Add("response_mode", "query")
 	case "Google":
 		rUrl = "https://accounts.google.com/o/oauth2/v2/auth?"
-		urlValues.Add("scope", "https://www.googleapis.com/auth/userinfo.profile")
+		urlValues.Add("scope", "https://www.googleapis.com/auth/userinfo.profile https://www.googleapis.com/auth/userinfo.email")
 	case "Dingtalk":
 		rUrl = "https://login.dingtalk.com/oauth2/auth?"
 		urlValues.Add("scope", "openid")
@@ -141,7 +145,120 @@ func GetOIDCClient(c *gin.Context, useCompatibility bool, redirectUri, method st
 	}, nil
 }
 
-func autoRegister(username, userID string, err error) (*model.User, error) {
+// ssoProvider returns the identity provider name of the configured sso platform
+func ssoProvider() string {
+	return strings.ToLower(setting.GetStr(conf.SSOLoginPlatform))
+}
+
+// ssoLinkCookie holds the browser session that started linking an identity,
+// the link token is only accepted from the same session
+const ssoLinkCookie = "sso_link_session"
+const ssoLinkExpire = time.Minute * 10
+
+// startSSOLinkSession binds the identity link flow to the browser that starts it
+func startSSOLinkSession(c *gin.Context) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(ssoLinkCookie, random.String(32), int(ssoLinkExpire.Seconds()), "/api",
+		"", strings.HasPrefix(common.GetApiUrl(c), "https://"), true)
+}
+
+// ssoLinkSession returns the browser session that started linking an identity
+func ssoLinkSession(c *gin.Context) string {
+	session, _ := c.Cookie(ssoLinkCookie)
+	return session
+}
+
+// ssoIdentityLinkToken issues a token with which the signed in user links the identity to the account.
+// The token is bound to the browser session that started the flow.
+func ssoIdentityLinkToken(c *gin.Context, userID, email string) (string, error) {
+	token, err := op.IssueIdentityLinkToken(ssoProvider(), userID, email, ssoLinkSession(c))
+	if err != nil {
+		return "", errors.New("the identity link was not started in this browser, please try again")
+	}
+	return token, nil
+}
+
+// ssoOrigin returns the origin of the site, the only window the sso popup reports to
+func ssoOrigin(c *gin.Context) string {
+	u, err := url.Parse(common.GetApiUrl(c))
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// ssoPostMessage answers the sso popup with a page that posts msg to the site window which opened it
+func ssoPostMessage(c *gin.Context, msg map[string]string) {
+	data, _ := utils.Json.Marshal(msg)
+	origin, _ := utils.Json.Marshal(ssoOrigin(c))
+	html := fmt.Sprintf(`<!DOCTYPE html>
+				<head></head>
+				<body>
+				<script>
+				window.opener.postMessage(%s, %s)
+				window.close()
+				</script>
+				</body>`, data, origin)
+	c.Data(200, "text/html; charset=utf-8", []byte(html))
+}
+
+// ssoVerifiedEmail returns the email reported by the provider, or an empty string
+// unless the provider marks it as verified. GitHub leaves the email out of the
+// profile unless it is public, so the verified emails are fetched separately.
+// Providers that don't report whether the email is verified are not trusted.
+func ssoVerifiedEmail(platform, accessToken string, profile []byte, emailField string) string {
+	switch platform {
+	case "Github":
+		resp, err := ssoClient.R().SetHeader("Authorization", "Bearer "+accessToken).
+			SetHeader("Accept", "application/json").Get("https://api.github.com/user/emails")
+		if err != nil || resp.IsError() {
+			return ""
+		}
+		var emails []struct {
+			Email    string `json:"email"`
+			Primary  bool   `json:"primary"`
+			Verified bool   `json:"verified"`
+		}
+		if err = utils.Json.Unmarshal(resp.Body(), &emails); err != nil {
+			return ""
+		}
+		var email string
+		for _, e := range emails {
+			if !e.Verified {
+				continue
+			}
+			if e.Primary {
+				return e.Email
+			}
+			if email == "" {
+				email = e.Email
+			}
+		}
+		return email
+	case "Google":
+		if utils.Json.Get(profile, "verified_email").ToBool() {
+			return utils.Json.Get(profile, emailField).ToString()
+		}
+	case "Casdoor":
+		if utils.Json.Get(profile, "email_verified").ToBool() {
+			return utils.Json.Get(profile, emailField).ToString()
+		}
+	}
+	return ""
+}
+
+// oidcVerifiedEmail returns the email claim of an OIDC token if the
+// email_verified claim is true, or an empty string
+func oidcVerifiedEmail(payload []byte) string {
+	if !utils.Json.Get(payload, "email_verified").ToBool() {
+		return ""
+	}
+	return utils.Json.Get(payload, setting.GetStr(conf.SSOOIDCEmailKey, "email")).ToString()
+}
+
+// autoRegister creates a user for a new SSO identity and records a
+// pre-verified registration carrying the verified email of the provider
+func autoRegister(username, userID, email string, err error) (*model.User, error) {
 	if !errors.Is(err, gorm.ErrRecordNotFound) || !setting.GetBool(conf.SSOAutoRegister) {
 		return nil, err
 	}
@@ -158,19 +275,75 @@ func autoRegister(username, userID string, err error) (*model.User, error) {
 		Disabled:   false,
 		SsoID:      userID,
 	}
-	if err = db.CreateUser(user); err != nil {
+	if err = op.CreateUser(user); err != nil {
 		if strings.HasPrefix(err.Error(), "UNIQUE constraint failed") && strings.HasSuffix(err.Error(), "username") {
 			user.Username = user.Username + "_" + userID
-			if err = db.CreateUser(user); err != nil {
+			if err = op.CreateUser(user); err != nil {
 				return nil, err
 			}
 		} else {
 			return nil, err
 		}
 	}
+	if err = op.RecordSSORegistration(user, email); err != nil {
+		utils.Log.Warnf("failed to record registration of sso user %s: %+v", user.Username, err)
+	}
+	if err = op.ProvisionUserIdentity(user, ssoProvider(), userID, email); err != nil {
+		utils.Log.Warnf("failed to link the identity of sso user %s: %+v", user.Username, err)
+	}
 	return user, nil
 }
 
+// oidcRegister provisions a new OIDC user. Without auto register the user can
+// go through the registration pipeline, in which case a login only succeeds
+// once the registration has been activated.
+func oidcRegister(userID, email, ip string, err error) (*model.User, error) {
+	if !errors.Is(err, gorm.ErrRecordNotFound) || setting.GetBool(conf.SSOAutoRegister) || !setting.GetBool(conf.SSOOIDCRegister) {
+		return autoRegister(userID, userID, email, err)
+	}
+	registration, err := op.CreateSSORegistration(userID, userID, email, ip)
+	if err != nil {
+		return nil, err
+	}
+	if registration.Status != model.RegistrationRegistered {
+		return nil, errs.RegistrationPending
+	}
+	return op.GetUserByName(registration.Username)
+}
+
+// syncOIDCRole updates the role of the user from the configured role claim,
+// which may be a string or a list of groups. Nested claims use dotted keys.
+// The role is left alone when the token doesn't carry the claim.
+func syncOIDCRole(user *model.User, payload []byte) error {
+	key := setting.GetStr(conf.SSOOIDCRoleKey)
+	if key == "" {
+		return nil
+	}
+	var path []interface{}
+	for _, k := range strings.Split(key, ".") {
+		path = append(path, k)
+	}
+	var values []string
+	switch claim := utils.Json.Get(payload, path...).GetInterface().(type) {
+	case string:
+		values = append(values, claim)
+	case []interface{}:
+		for _, v := range claim {
+			values = append(values, fmt.Sprint(v))
+		}
+	default:
+		return nil
+	}
+	admin := false
+	for _, v := range strings.Split(setting.GetStr(conf.SSOOIDCAdminValues), ",") {
+		if v = strings.TrimSpace(v); v != "" && utils.SliceContains(values, v) {
+			admin = true
+			break
+		}
+	}
+	return op.SyncSSORole(user, model.IdentityProviderOIDC, admin)
+}
+
 func parseJWT(p string) ([]byte, error) {
 	parts := strings.Split(p, ".")
 	if len(parts) < 2 {
@@ -235,29 +408,32 @@ func OIDCLoginCallback(c *gin.Context) {
 		return
 	}
 	if method == "get_sso_id" {
+		linkToken, err := ssoIdentityLinkToken(c, userID, oidcVerifiedEmail(payload))
+		if err != nil {
+			common.ErrorResp(c, err, 400)
+			return
+		}
 		if useCompatibility {
-			c.Redirect(302, common.GetApiUrl(c)+"/@manage?sso_id="+userID)
+			c.Redirect(302, common.GetApiUrl(c)+"/@manage?"+url.Values{"sso_id": {userID}, "link_token": {linkToken}}.Encode())
 			return
 		}
-		html := fmt.Sprintf(`<!DOCTYPE html>
-				<head></head>
-				<body>
-				<script>
-				window.opener.postMessage({"sso_id": "%s"}, "*")
-				window.close()
-				</script>
-				</body>`, userID)
-		c.Data(200, "text/html; charset=utf-8", []byte(html))
+		ssoPostMessage(c, map[string]string{"sso_id": userID, "link_token": linkToken})
 		return
 	}
 	if method == "sso_get_token" {
-		user, err := db.GetUserBySSOID(userID)
+		email := oidcVerifiedEmail(payload)
+		user, err := op.GetUserByIdentity(model.IdentityProviderOIDC, userID)
 		if err != nil {
-			user, err = autoRegister(userID, userID, err)
+			user, err = oidcRegister(userID, email, c.ClientIP(), err)
 			if err != nil {
 				common.ErrorResp(c, err, 400)
+				return
 			}
 		}
+		if err = syncOIDCRole(user, payload); err != nil {
+			common.ErrorResp(c, err, 400)
+			return
+		}
 		token, err := common.GenerateToken(user)
 		if err != nil {
 			common.ErrorResp(c, err, 400)
@@ -266,15 +442,7 @@ func OIDCLoginCallback(c *gin.Context) {
 			c.Redirect(302, common.GetApiUrl(c)+"/@login?token="+token)
 			return
 		}
-		html := fmt.Sprintf(`<!DOCTYPE html>
-				<head></head>
-				<body>
-				<script>
-				window.opener.postMessage({"token":"%s"}, "*")
-				window.close()
-				</script>
-				</body>`, token)
-		c.Data(200, "text/html; charset=utf-8", []byte(html))
+		ssoPostMessage(c, map[string]string{"token": token})
 		return
 	}
 }
@@ -297,16 +465,17 @@ func SSOLoginCallback(c *gin.Context) {
 	clientId := setting.GetStr(conf.SSOClientId)
 	platform := setting.GetStr(conf.SSOLoginPlatform)
 	clientSecret := setting.GetStr(conf.SSOClientSecret)
-	var tokenUrl, userUrl, scope, authField, idField, usernameField string
+	var tokenUrl, userUrl, scope, authField, idField, usernameField, emailField string
 	additionalForm := make(map[string]string)
 	switch platform {
 	case "Github":
 		tokenUrl = "https://github.com/login/oauth/access_token"
 		userUrl = "https://api.github.com/user"
 		authField = "code"
-		scope = "read:user"
+		scope = "read:user user:email"
 		idField = "id"
 		usernameField = "login"
+		emailField = "email"
 	case "Microsoft":
 		tokenUrl = "https://login.microsoftonline.com/common/oauth2/v2.0/token"
 		userUrl = "https://graph.microsoft.com/v1.0/me"
@@ -315,20 +484,23 @@ func SSOLoginCallback(c *gin.Context) {
 		authField = "code"
 		idField = "id"
 		usernameField = "displayName"
+		emailField = "mail"
 	case "Google":
 		tokenUrl = "https://oauth2.googleapis.com/token"
 		userUrl = "https://www.googleapis.com/oauth2/v1/userinfo"
 		additionalForm["grant_type"] = "authorization_code"
-		scope = "https://www.googleapis.com/auth/userinfo.profile"
+		scope = "https://www.googleapis.com/auth/userinfo.profile https://www.googleapis.com/auth/userinfo.email"
 		authField = "code"
 		idField = "id"
 		usernameField = "name"
+		emailField = "email"
 	case "Dingtalk":
 		tokenUrl = "https://api.dingtalk.com/v1.0/oauth2/userAccessToken"
 		userUrl = "https://api.dingtalk.com/v1.0/contact/users/me"
 		authField = "authCode"
 		idField = "unionId"
 		usernameField = "nick"
+		emailField = "email"
 	case "Casdoor":
 		endpoint := strings.TrimSuffix(setting.GetStr(conf.SSOEndpointName), "/")
 		tokenUrl = endpoint + "/api/login/oauth/access_token"
@@ -338,6 +510,7 @@ func SSOLoginCallback(c *gin.Context) {
 		authField = "code"
 		idField = "sub"
 		usernameField = "preferred_username"
+		emailField = "email"
 	case "OIDC":
 		OIDCLoginCallback(c)
 		return
@@ -381,12 +554,13 @@ func SSOLoginCallback(c *gin.Context) {
 		common.ErrorResp(c, err, 400)
 		return
 	}
+	var accessToken string
 	if platform == "Dingtalk" {
-		accessToken := utils.Json.Get(resp.Body(), "accessToken").ToString()
+		accessToken = utils.Json.Get(resp.Body(), "accessToken").ToString()
 		resp, err = ssoClient.R().SetHeader("x-acs-dingtalk-access-token", accessToken).
 			Get(userUrl)
 	} else {
-		accessToken := utils.Json.Get(resp.Body(), "access_token").ToString()
+		accessToken = utils.Json.Get(resp.Body(), "access_token").ToString()
 		resp, err = ssoClient.R().SetHeader("Authorization", "Bearer "+accessToken).
 			Get(userUrl)
 	}
@@ -399,26 +573,24 @@ func SSOLoginCallback(c *gin.Context) {
 		common.ErrorResp(c, errors.New("error occurred"), 400)
 		return
 	}
+	email := ssoVerifiedEmail(platform, accessToken, resp.Body(), emailField)
 	if argument == "get_sso_id" {
+		linkToken, err := ssoIdentityLinkToken(c, userID, email)
+		if err != nil {
+			common.ErrorResp(c, err, 400)
+			return
+		}
 		if usecompatibility {
-			c.Redirect(302, common.GetApiUrl(c)+"/@manage?sso_id="+userID)
+			c.Redirect(302, common.GetApiUrl(c)+"/@manage?"+url.Values{"sso_id": {userID}, "link_token": {linkToken}}.Encode())
 			return
 		}
-		html := fmt.Sprintf(`<!DOCTYPE html>
-				<head></head>
-				<body>
-				<script>
-				window.opener.postMessage({"sso_id": "%s"}, "*")
-				window.close()
-				</script>
-				</body>`, userID)
-		c.Data(200, "text/html; charset=utf-8", []byte(html))
+		ssoPostMessage(c, map[string]string{"sso_id": userID, "link_token": linkToken})
 		return
 	}
 	username := utils.Json.Get(resp.Body(), usernameField).ToString()
-	user, err := db.GetUserBySSOID(userID)
+	user, err := op.GetUserByIdentity(ssoProvider(), userID)
 	if err != nil {
-		user, err = autoRegister(username, userID, err)
+		user, err = autoRegister(username, userID, email, err)
 		if err != nil {
 			common.ErrorResp(c, err, 400)
 			return
@@ -432,13 +604,5 @@ func SSOLoginCallback(c *gin.Context) {
 		c.Redirect(302, common.GetApiUrl(c)+"/@login?token="+token)
 		return
 	}
-	html := fmt.Sprintf(`<!DOCTYPE html>
-							<head></head>
-							<body>
-							<script>
-							window.opener.postMessage({"token":"%s"}, "*")
-							window.close()
-							</script>
-							</body>`, token)
-	c.Data(200, "text/html; charset=utf-8", []byte(html))
+	ssoPostMessage(c, map[string]string{"token": token})
 }
@@ -0,0 +1,108 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// ListSubscriptionPlans 获取可购买的会员套餐
+func ListSubscriptionPlans(c *gin.Context) {
+	plans, err := op.ListSubscriptionPlans(true)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, plans)
+}
+
+// SubscribeReq 购买会员套餐请求
+type SubscribeReq struct {
+	PlanID        uint   `json:"plan_id" binding:"required"`
+	PaymentMethod string `json:"payment_method" binding:"required"`
+}
+
+// Subscribe 创建购买会员套餐的支付订单
+func Subscribe(c *gin.Context) {
+	var req SubscribeReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	user := c.MustGet("user").(*model.User)
+
+	order, err := op.CreateSubscriptionOrder(user.ID, req.PlanID, req.PaymentMethod)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, order)
+}
+
+// ListAllSubscriptionPlans 获取所有会员套餐（管理员）
+func ListAllSubscriptionPlans(c *gin.Context) {
+	plans, err := op.ListSubscriptionPlans(false)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, plans)
+}
+
+// SaveSubscriptionPlanReq 保存会员套餐请求
+type SaveSubscriptionPlanReq struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name" binding:"required,max=100"`
+	Months      int    `json:"months" binding:"required,min=1"`
+	Amount      int64  `json:"amount" binding:"required,min=1"` // 价格（最小货币单位）
+	Currency    string `json:"currency"`
+	Enabled     bool   `json:"enabled"`
+	Description string `json:"description" binding:"max=500"`
+}
+
+// SaveSubscriptionPlan 创建或更新会员套餐（管理员）
+func SaveSubscriptionPlan(c *gin.Context) {
+	var req SaveSubscriptionPlanReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	plan := &model.SubscriptionPlan{
+		ID:          req.ID,
+		Name:        req.Name,
+		Months:      req.Months,
+		Money:       model.NewMoney(req.Amount, req.Currency),
+		Enabled:     req.Enabled,
+		Description: req.Description,
+	}
+	if err := op.SaveSubscriptionPlan(plan); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, plan)
+}
+
+// DeleteSubscriptionPlan 删除会员套餐（管理员）
+func DeleteSubscriptionPlan(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	if err = op.DeleteSubscriptionPlan(uint(id)); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"message": "Subscription plan deleted successfully",
+	})
+}
@@ -0,0 +1,103 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// GetCurrentTerms 获取当前版本的服务条款和隐私政策，注册和登录时需同意其中的版本
+func GetCurrentTerms(c *gin.Context) {
+	docs, err := op.GetCurrentTerms()
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, docs)
+}
+
+// PublishTermsReq 发布条款请求
+type PublishTermsReq struct {
+	Kind    string `json:"kind" binding:"required"`
+	Version string `json:"version" binding:"required,max=32"`
+	Title   string `json:"title" binding:"max=200"`
+	Content string `json:"content" binding:"required"`
+}
+
+// PublishTerms 发布新版本的条款（管理员），用户下次登录时需重新同意
+func PublishTerms(c *gin.Context) {
+	var req PublishTermsReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	admin := c.MustGet("user").(*model.User)
+	doc := &model.TermsDocument{
+		Kind:      req.Kind,
+		Version:   req.Version,
+		Title:     req.Title,
+		Content:   req.Content,
+		CreatedBy: admin.ID,
+	}
+	if err := op.PublishTermsDocument(doc); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, doc)
+}
+
+// ListTerms 获取条款的历史版本（管理员）
+func ListTerms(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	docs, total, err := op.ListTermsDocuments(c.Query("kind"), page, pageSize)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"documents": docs,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// ListTermsAcceptances 获取用户同意条款的记录（管理员），可按 user_id 筛选
+func ListTermsAcceptances(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	userID, _ := strconv.ParseUint(c.Query("user_id"), 10, 64)
+
+	acceptances, total, err := op.ListTermsAcceptances(uint(userID), page, pageSize)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"acceptances": acceptances,
+		"total":       total,
+		"page":        page,
+		"page_size":   pageSize,
+	})
+}
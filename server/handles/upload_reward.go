@@ -0,0 +1,77 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// ListUploadEarnRules 获取上传奖励规则列表（管理员）
+func ListUploadEarnRules(c *gin.Context) {
+	rules, err := op.GetUploadEarnRules()
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, rules)
+}
+
+// SaveUploadEarnRuleReq 保存上传奖励规则请求
+type SaveUploadEarnRuleReq struct {
+	ID         uint   `json:"id"`
+	Path       string `json:"path" binding:"required"`
+	Mode       string `json:"mode" binding:"required,oneof=per_file per_mb"`
+	Credits    int64  `json:"credits" binding:"required,min=1"`
+	MaxCredits int64  `json:"max_credits" binding:"min=0"`
+	MinSize    int64  `json:"min_size" binding:"min=0"`
+	Enabled    bool   `json:"enabled"`
+}
+
+// SaveUploadEarnRule 创建或更新上传奖励规则（管理员）
+func SaveUploadEarnRule(c *gin.Context) {
+	var req SaveUploadEarnRuleReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	user := c.MustGet("user").(*model.User)
+
+	rule := &model.UploadEarnRule{
+		ID:         req.ID,
+		Path:       req.Path,
+		Mode:       req.Mode,
+		Credits:    req.Credits,
+		MaxCredits: req.MaxCredits,
+		MinSize:    req.MinSize,
+		Enabled:    req.Enabled,
+		CreatedBy:  user.ID,
+	}
+	if err := op.SaveUploadEarnRule(rule); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, rule)
+}
+
+// DeleteUploadEarnRule 删除上传奖励规则（管理员）
+func DeleteUploadEarnRule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	if err = op.DeleteUploadEarnRule(uint(id)); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"message": "Upload earn rule deleted successfully",
+	})
+}
@@ -39,6 +39,10 @@ func CreateUser(c *gin.Context) {
 		common.ErrorStrResp(c, "admin or guest user can not be created", 400, true)
 		return
 	}
+	if err := op.CheckUsername(req.Username, 0); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
 	req.SetPassword(req.Password)
 	req.Password = ""
 	req.Authn = "[]"
@@ -64,6 +68,12 @@ func UpdateUser(c *gin.Context) {
 		common.ErrorStrResp(c, "role can not be changed", 400)
 		return
 	}
+	if req.Username != user.Username {
+		if err := op.CheckUsername(req.Username, user.ID); err != nil {
+			common.ErrorResp(c, err, 400)
+			return
+		}
+	}
 	if req.Password == "" {
 		req.PwdHash = user.PwdHash
 		req.Salt = user.Salt
@@ -74,6 +84,11 @@ func UpdateUser(c *gin.Context) {
 	if req.OtpSecret == "" {
 		req.OtpSecret = user.OtpSecret
 	}
+	if req.VipExpiresAt == nil {
+		req.VipExpiresAt = user.VipExpiresAt
+	}
+	// the profile is edited by the user
+	req.Nickname, req.Avatar, req.Bio = user.Nickname, user.Avatar, user.Bio
 	if req.Disabled && req.IsAdmin() {
 		common.ErrorStrResp(c, "admin user can not be disabled", 400)
 		return
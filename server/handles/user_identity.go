@@ -0,0 +1,111 @@
+package handles
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// ListMyIdentities 获取当前用户关联的外部身份
+func ListMyIdentities(c *gin.Context) {
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	identities, err := op.ListUserIdentities(user.ID)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, identities)
+}
+
+// LinkIdentityReq 关联第三方登录身份请求
+type LinkIdentityReq struct {
+	Token string `json:"token" binding:"required"` // 以 get_sso_id 方式登录后回调返回的 link_token
+}
+
+// LinkIdentity 将第三方登录或 OIDC 的身份关联到当前用户
+func LinkIdentity(c *gin.Context) {
+	var req LinkIdentityReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+
+	identity, err := op.LinkUserIdentityByToken(user, req.Token, ssoLinkSession(c))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, identity)
+}
+
+// LinkLdapIdentityReq 关联 LDAP 身份请求
+type LinkLdapIdentityReq struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// LinkLdapIdentity 验证 LDAP 账号和密码后将其关联到当前用户
+func LinkLdapIdentity(c *gin.Context) {
+	var req LinkLdapIdentityReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if !setting.GetBool(conf.LdapLoginEnabled) {
+		common.ErrorStrResp(c, "ldap is not enabled", 403)
+		return
+	}
+	ip := c.ClientIP()
+	count, ok := model.LoginCache.Get(ip)
+	if ok && count >= model.DefaultMaxAuthRetries {
+		common.ErrorStrResp(c, "Too many unsuccessful sign-in attempts have been made using an incorrect username or password, Try again later.", 429)
+		model.LoginCache.Expire(ip, model.DefaultLockDuration)
+		return
+	}
+	entry, err := ldapAuthenticate(req.Username, req.Password)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		model.LoginCache.Set(ip, count+1)
+		return
+	}
+	model.LoginCache.Del(ip)
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+
+	email := entry.GetAttributeValue(setting.GetStr(conf.LdapEmailAttribute, "mail"))
+	identity, err := op.LinkUserIdentity(user, model.IdentityProviderLDAP, entry.DN, email)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, identity)
+}
+
+// UnlinkIdentityReq 解除外部身份请求
+type UnlinkIdentityReq struct {
+	ID uint `json:"id" binding:"required"`
+}
+
+// UnlinkIdentity 解除当前用户的外部身份关联
+func UnlinkIdentity(c *gin.Context) {
+	var req UnlinkIdentityReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+
+	if err := op.UnlinkUserIdentity(user, req.ID); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"message": "Identity unlinked successfully",
+	})
+}
@@ -1,19 +1,53 @@
 package handles
 
 import (
+	"errors"
+	"html/template"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/gin-gonic/gin"
 )
 
 // CreateRegistrationReq 创建用户注册申请请求
 type CreateRegistrationReq struct {
-	Username string `json:"username" binding:"required,min=3,max=50"`
+	Username string `json:"username" binding:"required,max=255"` // 长度和字符由用户名策略检查
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required_without=Passkey,omitempty,min=6"`
+	Passkey  bool   `json:"passkey"` // 不设置密码，注册完成后登记通行密钥作为唯一凭证
 	Reason   string `json:"reason" binding:"max=500"` // 申请理由
+	ReferralCode string `json:"referral_code" binding:"max=32"` // 推荐码
+	InviteCode   string `json:"invite_code" binding:"max=32"` // 邀请码，邀请注册模式下必填
+	AcceptedTerms map[string]string `json:"accepted_terms"` // 同意的条款类型及版本号，须与当前版本一致
+	Nickname string `json:"nickname"` // 以下为可选的用户资料
+	Avatar   string `json:"avatar"`   // 头像地址，为空时使用 gravatar
+	Bio      string `json:"bio"`
+}
+
+// registrationLimiter 注册和验证接口的限流器，按接口分别统计客户端 IP 和邮箱的请求
+var registrationLimiter = common.NewSlidingWindowLimiter()
+
+// registrationRateLimited 检查接口的 IP 和邮箱限流，超出时返回 429 并带上 Retry-After
+func registrationRateLimited(c *gin.Context, endpoint, email string) bool {
+	window := time.Duration(setting.GetInt(conf.RegistrationRateLimitWindow, 60)) * time.Minute
+	if ok, wait := registrationLimiter.Allow(endpoint+":ip:"+c.ClientIP(), setting.GetInt(conf.RegistrationRateLimitIP, 20), window); !ok {
+		common.TooManyRequestsResp(c, wait)
+		return true
+	}
+	if ok, wait := registrationLimiter.Allow(endpoint+":email:"+strings.ToLower(email), setting.GetInt(conf.RegistrationRateLimitEmail, 5), window); !ok {
+		common.TooManyRequestsResp(c, wait)
+		return true
+	}
+	return false
 }
 
 // CreateRegistration 创建用户注册申请
@@ -23,23 +57,80 @@ func CreateRegistration(c *gin.Context) {
 		common.ErrorResp(c, err, 400)
 		return
 	}
+	if registrationRateLimited(c, "register", req.Email) {
+		return
+	}
+
+	if req.Passkey {
+		req.Password = ""
+	}
+
+	terms, err := op.CheckTermsAccepted(req.AcceptedTerms)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err = op.CheckProfile(req.Nickname, req.Avatar, req.Bio); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
 
 	// 创建注册申请
-	registration, err := op.CreateUserRegistration(req.Username, req.Email, req.Password)
+	registration, err := op.CreateUserRegistration(req.Email, req.Username, req.Password, req.Reason, req.ReferralCode, req.InviteCode, c.ClientIP())
 	if err != nil {
-		common.ErrorStrResp(c, err.Error(), 400)
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err = op.RecordRegistrationTerms(registration, terms, c.ClientIP()); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	if err = op.SaveRegistrationProfile(registration, req.Nickname, req.Avatar, req.Bio); err != nil {
+		common.ErrorResp(c, err, 500, true)
 		return
 	}
 
 	common.SuccessResp(c, gin.H{
 		"id":      registration.ID,
-		"message": "Registration application submitted successfully. Please wait for admin approval.",
+		"message": "Registration application submitted successfully. Please verify your email.",
+	})
+}
+
+// ResendVerificationReq 重新发送验证邮件请求
+type ResendVerificationReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResendVerification 为待验证的注册申请重新发送验证邮件，旧的验证链接随之失效
+func ResendVerification(c *gin.Context) {
+	var req ResendVerificationReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if registrationRateLimited(c, "resend", req.Email) {
+		return
+	}
+
+	wait, err := op.ResendVerificationEmail(req.Email)
+	if errors.Is(err, errs.VerificationResendCooldown) {
+		c.Header("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		common.ErrorResp(c, err, 429)
+		return
+	}
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"message": "Verification email sent again. Please check your inbox.",
 	})
 }
 
 // VerifyRegistrationReq 验证注册申请请求
 type VerifyRegistrationReq struct {
-	Token string `json:"token" binding:"required"`
+	Token string `json:"token" form:"token" binding:"required"`
 }
 
 // VerifyRegistration 验证用户注册申请
@@ -51,20 +142,111 @@ func VerifyRegistration(c *gin.Context) {
 	}
 
 	// 验证注册申请
-	_, err := op.VerifyUserRegistration(req.Token)
+	registration, err := op.VerifyUserRegistration(req.Token)
 	if err != nil {
 		common.ErrorStrResp(c, err.Error(), 400)
 		return
 	}
 
-	common.SuccessResp(c, gin.H{
-		"message": "Registration verified successfully.",
+	message := "Registration verified successfully. Please wait for admin approval."
+	if registration.Status == model.RegistrationRegistered {
+		message = "Registration completed successfully. You can sign in now."
+	}
+	resp := gin.H{
+		"message": message,
+		"status":  registration.Status,
+	}
+	// 仅通行密钥的账户凭登记令牌设置首个通行密钥
+	if registration.EnrollToken != "" {
+		resp["passkey_token"] = registration.EnrollToken
+	}
+	common.SuccessResp(c, resp)
+}
+
+// verifyPageTexts 验证结果页的文本，按语言区分
+var verifyPageTexts = map[string]map[string]string{
+	"verified":   {"en": "Your email is verified. You can sign in once an admin approves your registration.", "zh": "邮箱已验证，管理员批准注册申请后即可登录。"},
+	"registered": {"en": "Your email is verified and your account is ready.", "zh": "邮箱已验证，账号已创建。"},
+	"failed":     {"en": "Email verification failed", "zh": "邮箱验证失败"},
+	"success":    {"en": "Email verified", "zh": "邮箱验证成功"},
+	"login":      {"en": "Continue to login", "zh": "前往登录"},
+}
+
+var verifyPageTemplate = template.Must(template.New("verify").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; display: flex; justify-content: center; align-items: center; min-height: 100vh; margin: 0; background: #f5f5f5; }
+.box { max-width: 420px; padding: 32px; background: #fff; border-radius: 8px; box-shadow: 0 2px 8px rgba(0,0,0,.1); text-align: center; }
+h1 { font-size: 20px; }
+h1.failed { color: #d03050; }
+a { display: inline-block; margin-top: 16px; padding: 8px 20px; color: #fff; background: #1890ff; border-radius: 4px; text-decoration: none; }
+</style>
+</head>
+<body>
+<div class="box">
+<h1{{if .Failed}} class="failed"{{end}}>{{.Title}}</h1>
+<p>{{.Message}}</p>
+<a href="{{.LoginURL}}">{{.Login}}</a>
+</div>
+</body>
+</html>
+`))
+
+// VerifyRegistrationPage 处理验证邮件中的链接，设置了 verify_redirect_url 时带上结果跳转到前端页面，
+// 否则显示按请求语言渲染的结果页
+func VerifyRegistrationPage(c *gin.Context) {
+	status, message, errCode := "failed", "", ""
+	registration, err := op.VerifyUserRegistration(c.Query("token"))
+	if err != nil {
+		errCode, message = errs.Code(err), common.ErrorMessage(c, err)
+	} else {
+		status = "verified"
+		if registration.Status == model.RegistrationRegistered {
+			status = "registered"
+		}
+		message = common.Localize(c, verifyPageTexts[status])
+	}
+
+	if redirect := setting.GetStr(conf.VerifyRedirectURL); redirect != "" {
+		query := url.Values{"status": {status}}
+		if errCode != "" {
+			query.Set("code", errCode)
+		}
+		sep := "?"
+		if strings.Contains(redirect, "?") {
+			sep = "&"
+		}
+		c.Redirect(302, redirect+sep+query.Encode())
+		return
+	}
+
+	failed := err != nil
+	title := verifyPageTexts["success"]
+	if failed {
+		title = verifyPageTexts["failed"]
+	}
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(200)
+	err = verifyPageTemplate.Execute(c.Writer, gin.H{
+		"Title":    common.Localize(c, title),
+		"Message":  message,
+		"Failed":   failed,
+		"Login":    common.Localize(c, verifyPageTexts["login"]),
+		"LoginURL": common.GetApiUrl(c.Request.Context()) + "/@login",
 	})
+	if err != nil {
+		utils.Log.Errorf("failed to render the verification page: %+v", err)
+	}
 }
 
 // ApproveRegistrationReq 批准注册申请请求
 type ApproveRegistrationReq struct {
-	ID uint `json:"id" binding:"required"`
+	ID    uint `json:"id" binding:"required"`
+	Force bool `json:"force"` // 忽略同一来源批准申请数的限制
 }
 
 // ApproveRegistration 批准用户注册申请（管理员）
@@ -75,10 +257,12 @@ func ApproveRegistration(c *gin.Context) {
 		return
 	}
 
+	admin := c.MustGet("user").(*model.User)
+
 	// 批准注册申请
-	user, err := op.ApproveUserRegistration(req.ID)
+	user, err := op.ApproveUserRegistration(req.ID, admin.ID, req.Force)
 	if err != nil {
-		common.ErrorStrResp(c, err.Error(), 400)
+		common.ErrorResp(c, err, 400)
 		return
 	}
 
@@ -102,10 +286,12 @@ func RejectRegistration(c *gin.Context) {
 		return
 	}
 
+	admin := c.MustGet("user").(*model.User)
+
 	// 拒绝注册申请
-	err := op.RejectUserRegistration(req.ID)
+	err := op.RejectUserRegistration(req.ID, admin.ID)
 	if err != nil {
-		common.ErrorStrResp(c, err.Error(), 400)
+		common.ErrorResp(c, err, 400)
 		return
 	}
 
@@ -114,6 +300,53 @@ func RejectRegistration(c *gin.Context) {
 	})
 }
 
+// BatchReviewRegistrationReq 批量审核注册申请请求
+type BatchReviewRegistrationReq struct {
+	IDs    []uint `json:"ids" binding:"required,min=1,max=100"`
+	Force  bool   `json:"force"`  // 批准时忽略同一来源批准申请数的限制
+	Atomic bool   `json:"atomic"` // 任一申请校验失败时整批都不处理
+}
+
+// BatchApproveRegistrations 批量批准注册申请（管理员），返回每个申请的处理结果
+func BatchApproveRegistrations(c *gin.Context) {
+	var req BatchReviewRegistrationReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	admin := c.MustGet("user").(*model.User)
+	results, err := op.BatchApproveRegistrations(req.IDs, admin.ID, req.Force, req.Atomic)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"results": results,
+	})
+}
+
+// BatchRejectRegistrations 批量拒绝注册申请（管理员），返回每个申请的处理结果
+func BatchRejectRegistrations(c *gin.Context) {
+	var req BatchReviewRegistrationReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	admin := c.MustGet("user").(*model.User)
+	results, err := op.BatchRejectRegistrations(req.IDs, admin.ID, req.Atomic)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+
+	common.SuccessResp(c, gin.H{
+		"results": results,
+	})
+}
+
 // ListPendingRegistrations 获取待处理的注册申请列表（管理员）
 func ListPendingRegistrations(c *gin.Context) {
 	// 获取分页参数
@@ -155,6 +388,9 @@ func SendVerificationCode(c *gin.Context) {
 		common.ErrorResp(c, err, 400)
 		return
 	}
+	if registrationRateLimited(c, "send_code", req.Email) {
+		return
+	}
 
 	// 创建验证码
 	code, err := op.CreateVerificationCode(req.Email, req.Type)
@@ -162,6 +398,10 @@ func SendVerificationCode(c *gin.Context) {
 		common.ErrorStrResp(c, err.Error(), 400)
 		return
 	}
+	if err = op.SendVerificationCode(code); err != nil {
+		common.ErrorStrResp(c, err.Error(), 500)
+		return
+	}
 
 	common.SuccessResp(c, gin.H{
 		"message":   "Verification code sent successfully.",
@@ -184,11 +424,14 @@ func VerifyCode(c *gin.Context) {
 		common.ErrorResp(c, err, 400)
 		return
 	}
+	if registrationRateLimited(c, "verify_code", req.Email) {
+		return
+	}
 
 	// 验证验证码
 	err := op.VerifyCode(req.Email, req.Code, req.Type)
 	if err != nil {
-		common.ErrorStrResp(c, err.Error(), 400)
+		common.ErrorResp(c, err, 400)
 		return
 	}
 
@@ -12,6 +12,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/gin-gonic/gin"
 	"github.com/go-webauthn/webauthn/protocol"
@@ -38,7 +39,10 @@ func BeginAuthnLogin(c *gin.Context) {
 		var user *model.User
 		user, err = db.GetUserByName(username)
 		if err == nil {
-			options, sessionData, err = authnInstance.BeginLogin(user)
+			var authnUser *model.AuthnUser
+			if authnUser, err = op.GetAuthnUser(user); err == nil {
+				options, sessionData, err = authnInstance.BeginLogin(authnUser)
+			}
 		}
 	} else { // client-side discoverable login
 		options, sessionData, err = authnInstance.BeginDiscoverableLogin()
@@ -91,7 +95,12 @@ func FinishAuthnLogin(c *gin.Context) {
 			common.ErrorResp(c, err, 400)
 			return
 		}
-		_, err = authnInstance.FinishLogin(user, sessionData, c.Request)
+		authnUser, err := op.GetAuthnUser(user)
+		if err != nil {
+			common.ErrorResp(c, err, 400)
+			return
+		}
+		_, err = authnInstance.FinishLogin(authnUser, sessionData, c.Request)
 	} else { // client-side discoverable login
 		_, err = authnInstance.FinishDiscoverableLogin(func(_, userHandle []byte) (webauthn.User, error) {
 			// first param `rawID` in this callback function is equal to ID in webauthn.Credential,
@@ -103,7 +112,7 @@ func FinishAuthnLogin(c *gin.Context) {
 				return nil, err
 			}
 
-			return user, nil
+			return op.GetAuthnUser(user)
 		}, sessionData, c.Request)
 	}
 	if err != nil {
@@ -120,27 +129,72 @@ func FinishAuthnLogin(c *gin.Context) {
 }
 
 func BeginAuthnRegistration(c *gin.Context) {
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	beginAuthnRegistration(c, user)
+}
+
+func FinishAuthnRegistration(c *gin.Context) {
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	finishAuthnRegistration(c, user)
+}
+
+// BeginRegistrationPasskey starts enrolling the first passkey of a newly
+// registered passkey only account, authorized by its one-time enrollment token
+func BeginRegistrationPasskey(c *gin.Context) {
+	user, err := op.GetPasskeyEnrollmentUser(c.Query("token"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	beginAuthnRegistration(c, user)
+}
+
+func FinishRegistrationPasskey(c *gin.Context) {
+	token := c.Query("token")
+	user, err := op.GetPasskeyEnrollmentUser(token)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	finishAuthnRegistration(c, user)
+	if c.IsAborted() {
+		return
+	}
+	// the enrollment token is single use
+	if err = op.FinishPasskeyEnrollment(token); err != nil {
+		utils.Log.Errorf("failed to invalidate the passkey enrollment token of %s: %+v", user.Username, err)
+	}
+}
+
+func beginAuthnRegistration(c *gin.Context, user *model.User) {
 	enabled := setting.GetBool(conf.WebauthnLoginEnabled)
 	if !enabled {
 		common.ErrorStrResp(c, "WebAuthn is not enabled", 403)
 		return
 	}
-	user := c.Request.Context().Value(conf.UserKey).(*model.User)
 
 	authnInstance, err := authn.NewAuthnInstance(c)
 	if err != nil {
 		common.ErrorResp(c, err, 400)
+		return
+	}
+	authnUser, err := op.GetAuthnUser(user)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
 	}
 
-	options, sessionData, err := authnInstance.BeginRegistration(user)
+	options, sessionData, err := authnInstance.BeginRegistration(authnUser)
 
 	if err != nil {
 		common.ErrorResp(c, err, 400)
+		return
 	}
 
 	val, err := json.Marshal(sessionData)
 	if err != nil {
 		common.ErrorResp(c, err, 400)
+		return
 	}
 
 	common.SuccessResp(c, gin.H{
@@ -149,13 +203,12 @@ func BeginAuthnRegistration(c *gin.Context) {
 	})
 }
 
-func FinishAuthnRegistration(c *gin.Context) {
+func finishAuthnRegistration(c *gin.Context, user *model.User) {
 	enabled := setting.GetBool(conf.WebauthnLoginEnabled)
 	if !enabled {
 		common.ErrorStrResp(c, "WebAuthn is not enabled", 403)
 		return
 	}
-	user := c.Request.Context().Value(conf.UserKey).(*model.User)
 	sessionDataString := c.GetHeader("Session")
 
 	authnInstance, err := authn.NewAuthnInstance(c)
@@ -176,18 +229,18 @@ func FinishAuthnRegistration(c *gin.Context) {
 		return
 	}
 
-	credential, err := authnInstance.FinishRegistration(user, sessionData, c.Request)
-
+	authnUser, err := op.GetAuthnUser(user)
 	if err != nil {
 		common.ErrorResp(c, err, 400)
 		return
 	}
-	err = db.RegisterAuthn(user, credential)
+	credential, err := authnInstance.FinishRegistration(authnUser, sessionData, c.Request)
+
 	if err != nil {
 		common.ErrorResp(c, err, 400)
 		return
 	}
-	err = op.DelUserCache(user.Username)
+	err = op.RegisterAuthn(user, credential)
 	if err != nil {
 		common.ErrorResp(c, err, 400)
 		return
@@ -206,12 +259,7 @@ func DeleteAuthnLogin(c *gin.Context) {
 		common.ErrorResp(c, err, 400)
 		return
 	}
-	err = db.RemoveAuthn(user, req.ID)
-	if err != nil {
-		common.ErrorResp(c, err, 400)
-		return
-	}
-	err = op.DelUserCache(user.Username)
+	err = op.RemoveAuthn(user, req.ID)
 	if err != nil {
 		common.ErrorResp(c, err, 400)
 		return
@@ -225,7 +273,12 @@ func GetAuthnCredentials(c *gin.Context) {
 		FingerPrint string `json:"fingerprint"`
 	}
 	user := c.Request.Context().Value(conf.UserKey).(*model.User)
-	credentials := user.WebAuthnCredentials()
+	authnUser, err := op.GetAuthnUser(user)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	credentials := authnUser.WebAuthnCredentials()
 	res := make([]WebAuthnCredentials, 0, len(credentials))
 	for _, v := range credentials {
 		credential := WebAuthnCredentials{
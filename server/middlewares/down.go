@@ -1,10 +1,14 @@
 package middlewares
 
 import (
+	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/internal/sign"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
@@ -27,10 +31,28 @@ func Down(verifyFunc func(string, string) error) func(c *gin.Context) {
 			}
 		}
 		common.GinWithValue(c, conf.MetaKey, meta)
+		// a prepaid download token stands in for both the sign and the login token
+		if token := c.Query("token"); token != "" {
+			downToken(c, token, rawPath)
+			return
+		}
+		// identify the user when a token is given, used for traffic accounting
+		if user := tryParseUser(c.GetHeader("Authorization")); user != nil {
+			common.GinWithValue(c, conf.UserKey, user)
+		}
 		// verify sign
-		if needSign(meta, rawPath) {
-			s := c.Query("sign")
-			err = verifyFunc(rawPath, strings.TrimSuffix(s, "/"))
+		s := strings.TrimSuffix(c.Query("sign"), "/")
+		if sign.IsUserSign(s) {
+			// a link signed for a user stands in for the sign, and the download is charged to that user
+			user, err := verifyUserSign(rawPath, s)
+			if err != nil {
+				common.ErrorResp(c, err, 401)
+				c.Abort()
+				return
+			}
+			common.GinWithValue(c, conf.UserKey, user)
+		} else if needSign(meta, rawPath) {
+			err = verifyFunc(rawPath, s)
 			if err != nil {
 				common.ErrorResp(c, err, 401)
 				c.Abort()
@@ -41,6 +63,181 @@ func Down(verifyFunc func(string, string) error) func(c *gin.Context) {
 	}
 }
 
+// verifyUserSign returns the user a download link is signed for
+func verifyUserSign(rawPath, s string) (*model.User, error) {
+	userID, err := sign.VerifyUser(rawPath, s)
+	if err != nil {
+		return nil, err
+	}
+	user, err := op.GetUserById(userID)
+	if err != nil || user.Disabled {
+		return nil, errors.New("the user of the signed link is not available")
+	}
+	return user, nil
+}
+
+// downToken authorizes a download by a prepaid download token. The credits were deducted
+// when the token was issued, so the request is marked as paid. HEAD requests don't use up the token.
+func downToken(c *gin.Context, token, rawPath string) {
+	downloadToken, err := op.UseDownloadToken(token, rawPath, c.Request.Method != http.MethodHead)
+	if err != nil {
+		if errors.Is(err, errs.InvalidDownloadToken) {
+			common.ErrorResp(c, err, 403)
+		} else {
+			common.ErrorResp(c, err, 500, true)
+		}
+		c.Abort()
+		return
+	}
+	user, err := op.GetUserById(downloadToken.UserID)
+	// tokens bought through a share link belong to the guest user, which may be disabled
+	if err != nil || (user.Disabled && !user.IsGuest()) {
+		common.ErrorResp(c, errs.InvalidDownloadToken, 403)
+		c.Abort()
+		return
+	}
+	common.GinWithValue(c, conf.UserKey, user)
+	common.GinWithValue(c, conf.DownloadTokenKey, downloadToken)
+	c.Next()
+}
+
+// DownloadCredits charges paid files in the download request itself, so they can't be fetched
+// by hitting the download URL without paying. The credits are held before the file is served,
+// deducted when the request succeeds and released when it fails. The user is identified by the
+// login token, or by the link signed for the user which is what browsers and players navigate to;
+// other requests are charged as the guest user, who usually has no credits. HEAD requests are
+// never charged, for paid files that aren't purchased yet they are answered without a link to the storage.
+func DownloadCredits(c *gin.Context) {
+	if skipDownloadCredits(c) {
+		c.Next()
+		return
+	}
+	rawPath := c.Request.Context().Value(conf.PathKey).(string)
+	user, ok := downloadUser(c)
+	if !ok {
+		return
+	}
+	if c.Request.Method == http.MethodHead {
+		obj, err := fs.Get(c.Request.Context(), rawPath, &fs.GetArgs{})
+		if err != nil || obj.IsDir() {
+			// the download handler reports the error
+			c.Next()
+			return
+		}
+		allowed, required, err := op.CheckSizedFileAccessPermission(user.ID, rawPath, model.CreditsActionDownload, obj.GetSize())
+		headDownload(c, allowed, required, err, func() {
+			c.Header("Content-Length", strconv.FormatInt(obj.GetSize(), 10))
+			c.Header("Last-Modified", obj.ModTime().UTC().Format(http.TimeFormat))
+		})
+		return
+	}
+	chargeDownload(c, user, rawPath, func() (*op.DownloadCharge, error) {
+		return op.BeginFileDownload(user.ID, rawPath)
+	})
+}
+
+// ArchiveCredits charges extracting a file from a paid archive like DownloadCredits does,
+// the extraction is priced by op.BeginArchiveExtract rather than as a download of the whole archive
+func ArchiveCredits(c *gin.Context) {
+	if skipDownloadCredits(c) {
+		c.Next()
+		return
+	}
+	rawPath := c.Request.Context().Value(conf.PathKey).(string)
+	innerPath := utils.FixAndCleanPath(c.Query("inner"))
+	password := c.Query("pass")
+	user, ok := downloadUser(c)
+	if !ok {
+		return
+	}
+	if c.Request.Method == http.MethodHead {
+		allowed, required, err := op.CheckArchiveExtractPermission(user.ID, rawPath, innerPath, password)
+		headDownload(c, allowed, required, err, func() {})
+		return
+	}
+	chargeDownload(c, user, rawPath, func() (*op.DownloadCharge, error) {
+		return op.BeginArchiveExtract(user.ID, rawPath, innerPath, password)
+	})
+}
+
+// skipDownloadCredits reports whether the request isn't charged: credits are disabled,
+// or the download is prepaid by a download token
+func skipDownloadCredits(c *gin.Context) bool {
+	_, prepaid := c.Request.Context().Value(conf.DownloadTokenKey).(*model.DownloadToken)
+	return prepaid || !setting.GetBool(conf.CreditsEnabled)
+}
+
+// downloadUser returns the user identified by Down, or the guest user
+func downloadUser(c *gin.Context) (*model.User, bool) {
+	if user, ok := c.Request.Context().Value(conf.UserKey).(*model.User); ok {
+		return user, true
+	}
+	guest, err := op.GetGuest()
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		c.Abort()
+		return nil, false
+	}
+	return guest, true
+}
+
+// headDownload answers a HEAD request without charging it. Paid files that aren't purchased
+// yet are answered here with the headers set by header, as the handler would redirect to the storage.
+func headDownload(c *gin.Context, allowed bool, required int64, err error, header func()) {
+	if err != nil {
+		// the download handler reports the error
+		c.Next()
+		return
+	}
+	if !allowed {
+		common.ErrorResp(c, errs.InsufficientCredits, 402)
+		c.Abort()
+		return
+	}
+	if required > 0 {
+		header()
+		c.Status(http.StatusOK)
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// chargeDownload holds the credits of a download by begin, serves it and settles the held
+// credits by the result of the transfer
+func chargeDownload(c *gin.Context, user *model.User, rawPath string, begin func() (*op.DownloadCharge, error)) {
+	charge, err := begin()
+	if err != nil {
+		if errors.Is(err, errs.InsufficientCredits) {
+			common.ErrorResp(c, err, 402)
+		} else {
+			common.ErrorResp(c, err, 500, true)
+		}
+		c.Abort()
+		return
+	}
+	c.Next()
+	if err = charge.Finish(!c.IsAborted() && c.Writer.Status() < 400); err != nil {
+		utils.Log.Errorf("failed to settle the download credits of user %d for %s: %+v", user.ID, rawPath, err)
+	}
+}
+
+// tryParseUser returns the user of a login token, or nil if the token is absent or invalid
+func tryParseUser(token string) *model.User {
+	if token == "" {
+		return nil
+	}
+	userClaims, err := common.ParseToken(token)
+	if err != nil {
+		return nil
+	}
+	user, err := op.GetUserByName(userClaims.Username)
+	if err != nil || userClaims.PwdTS != user.PwdTS || user.Disabled {
+		return nil
+	}
+	return user
+}
+
 // TODO: implement
 // path maybe contains # ? etc.
 func parsePath(path string) string {
@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// Require2FA guards sensitive operations. Users with 2FA enabled must send a
+// current code or a backup code in the X-OTP-Code header, and when 2FA is
+// enforced users without it are refused. Requests made with the admin token
+// are not checked.
+func Require2FA(c *gin.Context) {
+	token := c.GetHeader("Authorization")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(setting.GetStr(conf.Token))) == 1 {
+		c.Next()
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if user.OtpSecret == "" {
+		if setting.GetBool(conf.RequireTwoFactor) {
+			common.ErrorResp(c, errs.TwoFactorRequired, 403)
+			c.Abort()
+			return
+		}
+		c.Next()
+		return
+	}
+	ok, err := op.ValidateOtp(user, c.GetHeader("X-OTP-Code"))
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		c.Abort()
+		return
+	}
+	if !ok {
+		common.ErrorResp(c, errs.InvalidTwoFactorCode, 403)
+		c.Abort()
+		return
+	}
+	c.Next()
+}
@@ -44,17 +44,17 @@ func Init(e *gin.Engine) {
 
 	downloadLimiter := middlewares.DownloadRateLimiter(stream.ClientDownloadLimit)
 	signCheck := middlewares.Down(sign.Verify)
-	g.GET("/d/*path", signCheck, downloadLimiter, handles.Down)
-	g.GET("/p/*path", signCheck, downloadLimiter, handles.Proxy)
-	g.HEAD("/d/*path", signCheck, handles.Down)
-	g.HEAD("/p/*path", signCheck, handles.Proxy)
+	g.GET("/d/*path", signCheck, downloadLimiter, middlewares.DownloadCredits, handles.Down)
+	g.GET("/p/*path", signCheck, downloadLimiter, middlewares.DownloadCredits, handles.Proxy)
+	g.HEAD("/d/*path", signCheck, middlewares.DownloadCredits, handles.Down)
+	g.HEAD("/p/*path", signCheck, middlewares.DownloadCredits, handles.Proxy)
 	archiveSignCheck := middlewares.Down(sign.VerifyArchive)
-	g.GET("/ad/*path", archiveSignCheck, downloadLimiter, handles.ArchiveDown)
-	g.GET("/ap/*path", archiveSignCheck, downloadLimiter, handles.ArchiveProxy)
-	g.GET("/ae/*path", archiveSignCheck, downloadLimiter, handles.ArchiveInternalExtract)
-	g.HEAD("/ad/*path", archiveSignCheck, handles.ArchiveDown)
-	g.HEAD("/ap/*path", archiveSignCheck, handles.ArchiveProxy)
-	g.HEAD("/ae/*path", archiveSignCheck, handles.ArchiveInternalExtract)
+	g.GET("/ad/*path", archiveSignCheck, downloadLimiter, middlewares.ArchiveCredits, handles.ArchiveDown)
+	g.GET("/ap/*path", archiveSignCheck, downloadLimiter, middlewares.ArchiveCredits, handles.ArchiveProxy)
+	g.GET("/ae/*path", archiveSignCheck, downloadLimiter, middlewares.ArchiveCredits, handles.ArchiveInternalExtract)
+	g.HEAD("/ad/*path", archiveSignCheck, middlewares.ArchiveCredits, handles.ArchiveDown)
+	g.HEAD("/ap/*path", archiveSignCheck, middlewares.ArchiveCredits, handles.ArchiveProxy)
+	g.HEAD("/ae/*path", archiveSignCheck, middlewares.ArchiveCredits, handles.ArchiveInternalExtract)
 
 	api := g.Group("/api")
 	auth := api.Group("", middlewares.Auth)
@@ -68,8 +68,40 @@ func Init(e *gin.Engine) {
 	auth.GET("/me/sshkey/list", handles.ListMyPublicKey)
 	auth.POST("/me/sshkey/add", handles.AddMyPublicKey)
 	auth.POST("/me/sshkey/delete", handles.DeleteMyPublicKey)
+	auth.POST("/me/credits/transfer", middlewares.Require2FA, handles.TransferCredits)
+	auth.GET("/me/credits/gifts", handles.ListCreditGifts)
+	auth.POST("/me/credits/gifts/send", middlewares.Require2FA, handles.SendCreditGift)
+	auth.POST("/me/credits/gifts/claim", handles.ClaimCreditGift)
+	auth.GET("/me/credits/transactions/export", handles.ExportMyCreditTransactions)
+	auth.GET("/me/purchases", handles.ListMyPurchases)
+	auth.GET("/me/download_tokens", handles.ListMyDownloadTokens)
+	auth.POST("/me/download_tokens/revoke", handles.RevokeMyDownloadToken)
+	auth.GET("/me/shares", handles.ListMyFileShares)
+	auth.POST("/me/shares/delete", handles.DeleteMyFileShare)
+	auth.GET("/me/referral", handles.GetReferralStats)
+	auth.GET("/me/referral/list", handles.ListReferrals)
+	auth.POST("/me/invite/create", handles.CreateMyInviteCode)
+	auth.GET("/me/invite/list", handles.ListMyInviteCodes)
+	auth.POST("/me/email/change", handles.RequestEmailChange)
+	auth.POST("/me/email/confirm", handles.ConfirmEmailChange)
+	auth.POST("/me/delete/code", handles.RequestAccountDeletion)
+	auth.POST("/me/delete", handles.DeleteAccount)
+	auth.GET("/me/profile", handles.GetProfile)
+	auth.POST("/me/profile", handles.UpdateProfile)
+	auth.POST("/me/avatar", handles.UploadAvatar)
+	api.GET("/avatar/:id", handles.GetAvatar)
+	auth.GET("/me/identities", handles.ListMyIdentities)
+	auth.POST("/me/identities/link", handles.LinkIdentity)
+	auth.POST("/me/identities/link/ldap", handles.LinkLdapIdentity)
+	auth.POST("/me/identities/unlink", handles.UnlinkIdentity)
+	auth.POST("/me/export", handles.RequestDataExport)
+	auth.GET("/me/export", handles.GetDataExport)
+	auth.GET("/me/export/file", handles.DownloadMyDataExport)
+	api.GET("/me/export/download", handles.DownloadDataExport)
 	auth.POST("/auth/2fa/generate", handles.Generate2FA)
 	auth.POST("/auth/2fa/verify", handles.Verify2FA)
+	auth.GET("/auth/2fa/backup_codes", handles.Get2FABackupCodes)
+	auth.POST("/auth/2fa/backup_codes", handles.Regenerate2FABackupCodes)
 	auth.GET("/auth/logout", handles.LogOut)
 
 	// auth
@@ -88,35 +120,62 @@ func Init(e *gin.Engine) {
 
 	// user registration (no auth required)
 	api.POST("/register", handles.CreateRegistration)
+	api.GET("/register/verify", handles.VerifyRegistrationPage)
 	api.POST("/register/verify", handles.VerifyRegistration)
+	api.POST("/register/resend", handles.ResendVerification)
+	api.POST("/auth/password/reset/request", handles.RequestPasswordReset)
+	api.POST("/auth/password/reset/verify", handles.VerifyPasswordReset)
+	api.POST("/auth/password/reset", handles.ResetPassword)
+	api.GET("/register/passkey/begin", handles.BeginRegistrationPasskey)
+	api.POST("/register/passkey/finish", handles.FinishRegistrationPasskey)
 	api.POST("/verification/send", handles.SendVerificationCode)
 	api.POST("/verification/verify", handles.VerifyCode)
 
 	// credits system
 	auth.GET("/credits", handles.GetUserCredits)
 	auth.GET("/credits/transactions", handles.GetCreditTransactions)
+	auth.GET("/credits/lots", handles.GetCreditLots)
+	auth.GET("/credits/traffic", handles.GetTrafficUsage)
+	auth.GET("/credits/api_usage", handles.GetApiUsage)
+	auth.GET("/credits/quota", handles.GetDownloadQuota)
+	auth.GET("/credits/stats", handles.GetMyCreditStats)
 	auth.GET("/credits/config", handles.GetFileCreditsConfig)
 	auth.GET("/credits/download/check", handles.CheckDownloadPermission)
 	auth.POST("/credits/download/deduct", handles.DeductCreditsForDownload)
 	auth.POST("/credits/redeem", handles.RedeemCode)
-	auth.POST("/credits/payment/create", handles.CreatePaymentOrder)
+	auth.GET("/credits/redeem/info", handles.GetRedeemCodeInfo)
+	auth.POST("/credits/payment/create", middlewares.Require2FA, handles.CreatePaymentOrder)
 	auth.POST("/credits/payment/complete", handles.CompletePaymentOrder)
 	auth.DELETE("/credits/payment/:order_no", handles.CancelPaymentOrder)
+	auth.GET("/credits/vip/plans", handles.ListSubscriptionPlans)
+	auth.POST("/credits/vip/subscribe", middlewares.Require2FA, handles.Subscribe)
+	auth.GET("/credits/packages", handles.ListCreditPackages)
 
 	// no need auth
 	public := api.Group("/public")
 	public.Any("/settings", handles.PublicSettings)
 	public.Any("/offline_download_tools", handles.OfflineDownloadTools)
 	public.Any("/archive_extensions", handles.ArchiveExtensions)
+	public.GET("/terms", handles.GetCurrentTerms)
 	
 	// payment notifications (webhook endpoints)
 	api.POST("/payment/notify/:provider", handles.PaymentNotification)
+	api.GET("/payment/return", handles.PaymentReturn)
+
+	// guest checkout of shared paid files
+	api.GET("/share/:token", handles.GetFileShare)
+	api.POST("/share/:token/order", handles.CreateShareOrder)
+	api.GET("/share/:token/order", handles.GetShareOrder)
+
+	// external reward callbacks (offerwall / ad platforms)
+	api.Any("/credits/reward/callback/:source", handles.RewardCallback)
 
 	_fs(auth.Group("/fs"))
 	_task(auth.Group("/task", middlewares.AuthNotGuest))
 	admin(auth.Group("/admin", middlewares.AuthAdmin))
 	_userRegistration(auth.Group("/admin", middlewares.AuthAdmin))
 	_credits(auth.Group("/admin", middlewares.AuthAdmin))
+	_mail(auth.Group("/admin", middlewares.AuthAdmin))
 	if flags.Debug || flags.Dev {
 		debug(g.Group("/debug"))
 	}
@@ -141,6 +200,8 @@ func admin(g *gin.RouterGroup) {
 	user.POST("/cancel_2fa", handles.Cancel2FAById)
 	user.POST("/delete", handles.DeleteUser)
 	user.POST("/del_cache", handles.DelUserCache)
+	user.POST("/ldap/import", handles.ImportLdapUsers)
+	user.GET("/email_changes", handles.ListEmailChanges)
 	user.GET("/sshkey/list", handles.ListPublicKeys)
 	user.POST("/sshkey/delete", handles.DeletePublicKey)
 
@@ -175,6 +236,7 @@ func admin(g *gin.RouterGroup) {
 	setting.POST("/set_thunder", handles.SetThunder)
 	setting.POST("/set_thunderx", handles.SetThunderX)
 	setting.POST("/set_thunder_browser", handles.SetThunderBrowser)
+	setting.POST("/test_notification", handles.TestAdminNotification)
 
 	// retain /admin/task API to ensure compatibility with legacy automation scripts
 	_task(g.Group("/task"))
@@ -196,6 +258,10 @@ func _fs(g *gin.RouterGroup) {
 	g.Any("/search", middlewares.SearchIndex, handles.Search)
 	g.Any("/get", handles.FsGet)
 	g.Any("/other", handles.FsOther)
+	g.POST("/credits/preview", handles.FsCreditsPreview)
+	g.POST("/credits/estimate", handles.FsCreditsEstimate)
+	g.POST("/download_token", handles.IssueDownloadToken)
+	g.POST("/share", handles.CreateFileShare)
 	g.Any("/dirs", handles.FsDirs)
 	g.POST("/mkdir", handles.FsMkdir)
 	g.POST("/rename", handles.FsRename)
@@ -225,13 +291,94 @@ func _userRegistration(g *gin.RouterGroup) {
 	reg.GET("/list", handles.ListPendingRegistrations)
 	reg.POST("/approve", handles.ApproveRegistration)
 	reg.POST("/reject", handles.RejectRegistration)
+	reg.POST("/batch_approve", handles.BatchApproveRegistrations)
+	reg.POST("/batch_reject", handles.BatchRejectRegistrations)
+	reg.POST("/invite/generate", handles.GenerateInviteCodes)
+	reg.GET("/invite/list", handles.ListInviteCodes)
+	reg.POST("/invite/toggle", handles.ToggleInviteCode)
+	reg.GET("/terms/list", handles.ListTerms)
+	reg.POST("/terms/publish", handles.PublishTerms)
+	reg.GET("/terms/acceptances", handles.ListTermsAcceptances)
+	reg.GET("/templates", handles.ListProvisioningTemplates)
+	reg.POST("/templates/save", handles.SaveProvisioningTemplate)
+	reg.POST("/templates/delete", handles.DeleteProvisioningTemplate)
+}
+
+func _mail(g *gin.RouterGroup) {
+	m := g.Group("/mail")
+	m.GET("/providers", handles.ListMailProviders)
+	m.GET("/deliveries", handles.ListMailDeliveries)
+	m.POST("/deliveries/resend", handles.ResendMailDelivery)
 }
 
 func _credits(g *gin.RouterGroup) {
 	credits := g.Group("/credits")
+	credits.GET("/configs", handles.ListFileCreditsConfigs)
 	credits.POST("/config/set", handles.SetFileCreditsConfig)
+	credits.POST("/config/toggle", handles.ToggleFileCreditsConfig)
+	credits.GET("/config/exemptions", handles.ListFileCreditsExemptions)
+	credits.POST("/config/exemptions/set", handles.SetFileCreditsExemptions)
 	credits.DELETE("/config/delete", handles.DeleteFileCreditsConfig)
+	credits.POST("/config/bulk", handles.BulkFileCreditsConfig)
+	credits.GET("/config/export", handles.ExportFileCreditsConfigs)
+	credits.POST("/config/import", handles.ImportFileCreditsConfigs)
+	credits.GET("/config/orphans", handles.ListOrphanedFileCreditsConfigs)
+	credits.POST("/config/orphans/scan", handles.ScanFileCreditsConfigOrphans)
+	credits.POST("/config/orphans/clean", handles.CleanOrphanedFileCreditsConfigs)
 	credits.POST("/redeem/generate", handles.GenerateRedeemCodes)
+	credits.GET("/redeem/batches", handles.ListRedeemBatches)
+	credits.GET("/redeem/batches/export", handles.ExportRedeemBatch)
+	credits.GET("/redeem/qr", handles.RedeemCodeQR)
+	credits.POST("/redeem/revoke", handles.RevokeRedeemCodes)
+	credits.GET("/redeem/revocations", handles.ListRedeemCodeRevocations)
+	credits.GET("/redeem/usages", handles.ListRedeemCodeUsages)
+	credits.GET("/redeem/code", handles.GetRedeemCodeDetail)
+	credits.GET("/redeem/campaigns", handles.ListRedeemCampaigns)
+	credits.POST("/redeem/campaigns/save", handles.SaveRedeemCampaign)
+	credits.POST("/redeem/campaigns/delete", handles.DeleteRedeemCampaign)
+	credits.GET("/redeem/campaigns/stats", handles.GetRedeemCampaignStats)
+	credits.GET("/payment/drivers", handles.ListPaymentDrivers)
+	credits.POST("/adjust", handles.AdjustCredits)
+	credits.GET("/adjust/audit", handles.ListCreditAdjustments)
+	credits.POST("/freeze", handles.FreezeCredits)
+	credits.POST("/holds/capture", handles.CaptureDownloadCredits)
+	credits.POST("/holds/release", handles.ReleaseDownloadCredits)
+	credits.GET("/transactions", handles.ListAllCreditTransactions)
+	credits.GET("/transactions/export", handles.ExportCreditTransactions)
+	credits.GET("/stats", handles.GetCreditStats)
+	credits.GET("/ranking", handles.GetCreditRanking)
+	credits.GET("/ledger/issues", handles.ListCreditLedgerIssues)
+	credits.POST("/ledger/audit", handles.AuditCreditLedger)
+	credits.POST("/ledger/repair", handles.RepairCreditLedger)
+	credits.GET("/upload_rules", handles.ListUploadEarnRules)
+	credits.POST("/upload_rules/save", handles.SaveUploadEarnRule)
+	credits.POST("/upload_rules/delete", handles.DeleteUploadEarnRule)
+	credits.GET("/vip/plans", handles.ListAllSubscriptionPlans)
+	credits.POST("/vip/plans/save", handles.SaveSubscriptionPlan)
+	credits.POST("/vip/plans/delete", handles.DeleteSubscriptionPlan)
+	credits.GET("/packages", handles.ListAllCreditPackages)
+	credits.POST("/packages/save", handles.SaveCreditPackage)
+	credits.POST("/packages/delete", handles.DeleteCreditPackage)
+	credits.GET("/allowances", handles.ListCreditAllowances)
+	credits.POST("/allowances/save", handles.SaveCreditAllowance)
+	credits.POST("/allowances/delete", handles.DeleteCreditAllowance)
+	credits.GET("/pricing_groups", handles.ListPricingGroups)
+	credits.POST("/pricing_groups/save", handles.SavePricingGroup)
+	credits.POST("/pricing_groups/delete", handles.DeletePricingGroup)
+	credits.POST("/pricing_groups/assign", handles.AssignPricingGroup)
+	credits.GET("/pricing_rules", handles.ListPricingRules)
+	credits.POST("/pricing_rules/save", handles.SavePricingRule)
+	credits.POST("/pricing_rules/delete", handles.DeletePricingRule)
+	credits.GET("/pricing_rules/dry_run", handles.DryRunFilePricing)
+	credits.GET("/promotions", handles.ListPromotions)
+	credits.POST("/promotions/save", handles.SavePromotion)
+	credits.POST("/promotions/delete", handles.DeletePromotion)
+	credits.GET("/reward_sources", handles.ListRewardSources)
+	credits.POST("/reward_sources/save", handles.SaveRewardSource)
+	credits.POST("/reward_sources/delete", handles.DeleteRewardSource)
+	credits.GET("/coupons", handles.ListCoupons)
+	credits.POST("/coupons/save", handles.SaveCoupon)
+	credits.POST("/coupons/delete", handles.DeleteCoupon)
 }
 
 func _task(g *gin.RouterGroup) {
@@ -0,0 +1,136 @@
+package s3
+
+import (
+	"encoding/xml"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/itsHenry35/gofakes3/signature"
+	"github.com/pkg/errors"
+)
+
+// creditsError is an S3 error response with the machine-readable reason a download was denied
+type creditsError struct {
+	XMLName         xml.Name `xml:"Error"`
+	Code            string   `xml:"Code"`
+	Message         string   `xml:"Message"`
+	Reason          string   `xml:"Reason"`
+	RequiredCredits int64    `xml:"RequiredCredits,omitempty"`
+	Resource        string   `xml:"Resource"`
+}
+
+// statusRecorder remembers the status code written by the s3 server
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// creditsMiddleware charges paid files downloaded by GetObject. The credits are held before
+// the object is served, deducted when the request succeeds and released when it fails.
+// Requests whose signature is invalid are passed on untouched for the s3 server to reject.
+func creditsMiddleware(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || !setting.GetBool(conf.CreditsEnabled) || !authenticated(r) {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		fp, ok := objectPath(r)
+		if !ok {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		user, err := creditsUser()
+		if err != nil {
+			utils.Log.Errorf("failed to get the s3 credits user: %+v", err)
+			writeCreditsError(w, "InternalError", "internal_error", "failed to get the user of the access key", 0, r.URL.Path, http.StatusInternalServerError)
+			return
+		}
+		charge, err := op.BeginFileDownload(user.ID, fp)
+		if err != nil {
+			if errors.Is(err, errs.InsufficientCredits) {
+				_, required, _ := op.CheckFileDownloadPermission(user.ID, fp)
+				writeCreditsError(w, "AccessDenied", errs.Code(err), err.Error(), required, r.URL.Path, http.StatusForbidden)
+				return
+			}
+			if errs.IsObjectNotFound(err) {
+				handler.ServeHTTP(w, r)
+				return
+			}
+			utils.Log.Errorf("failed to charge s3 download of %s: %+v", fp, err)
+			writeCreditsError(w, "InternalError", "internal_error", "failed to charge the download", 0, r.URL.Path, http.StatusInternalServerError)
+			return
+		}
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(recorder, r)
+		if err = charge.Finish(recorder.status < http.StatusBadRequest); err != nil {
+			utils.Log.Errorf("failed to settle the s3 download credits of user %d for %s: %+v", user.ID, fp, err)
+		}
+	})
+}
+
+// authenticated reports whether the request is signed with a configured access key,
+// requests are always authenticated when no access key is configured
+func authenticated(r *http.Request) bool {
+	if authlistResolver() == nil {
+		return true
+	}
+	result := signature.V4SignVerify(r)
+	if result == signature.ErrUnsupportAlgorithm {
+		result = signature.V2SignVerify(r)
+	}
+	return result == signature.ErrNone
+}
+
+// objectPath returns the path of the object requested by a GetObject request
+func objectPath(r *http.Request) (string, bool) {
+	query := r.URL.Query()
+	for _, sub := range []string{"uploadId", "uploads", "acl", "tagging", "list-type", "versioning", "location"} {
+		if query.Has(sub) {
+			return "", false
+		}
+	}
+	bucketName, objectName, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if !ok || objectName == "" || strings.HasSuffix(objectName, "/") {
+		return "", false
+	}
+	bucket, err := getBucketByName(bucketName)
+	if err != nil {
+		return "", false
+	}
+	return path.Join(bucket.Path, objectName), true
+}
+
+// creditsUser returns the user the access key acts as, which is set by s3_credits_user
+// and defaults to the guest user
+func creditsUser() (*model.User, error) {
+	if username := setting.GetStr(conf.S3CreditsUser); username != "" {
+		return op.GetUserByName(username)
+	}
+	return op.GetGuest()
+}
+
+func writeCreditsError(w http.ResponseWriter, code, reason, message string, required int64, resource string, status int) {
+	body, _ := xml.Marshal(creditsError{
+		Code:            code,
+		Message:         message,
+		Reason:          reason,
+		RequiredCredits: required,
+		Resource:        resource,
+	})
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(xml.Header))
+	_, _ = w.Write(body)
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/subtle"
 	"net/http"
 	"path"
+	"strconv"
 	"strings"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
@@ -12,10 +13,13 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/server/middlewares"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
 	"github.com/OpenListTeam/OpenList/v4/server/webdav"
 	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -32,8 +36,8 @@ func WebDav(dav *gin.RouterGroup) {
 	dav.Use(WebDAVAuth)
 	uploadLimiter := middlewares.UploadRateLimiter(stream.ClientUploadLimit)
 	downloadLimiter := middlewares.DownloadRateLimiter(stream.ClientDownloadLimit)
-	dav.Any("/*path", uploadLimiter, downloadLimiter, ServeWebDAV)
-	dav.Any("", uploadLimiter, downloadLimiter, ServeWebDAV)
+	dav.Any("/*path", uploadLimiter, downloadLimiter, WebDAVCredits, ServeWebDAV)
+	dav.Any("", uploadLimiter, downloadLimiter, WebDAVCredits, ServeWebDAV)
 	dav.Handle("PROPFIND", "/*path", ServeWebDAV)
 	dav.Handle("PROPFIND", "", ServeWebDAV)
 	dav.Handle("MKCOL", "/*path", ServeWebDAV)
@@ -48,6 +52,66 @@ func ServeWebDAV(c *gin.Context) {
 	handler.ServeHTTP(c.Writer, c.Request)
 }
 
+// WebDAVCredits charges paid files downloaded through WebDAV like the download handlers do:
+// the credits are held before the file is served, deducted when the request succeeds and
+// released when it fails. Insufficient credits are answered with 402 Payment Required.
+// HEAD requests of paid files that are not purchased yet are answered without a link to the storage.
+func WebDAVCredits(c *gin.Context) {
+	method := c.Request.Method
+	if (method != http.MethodGet && method != http.MethodHead && method != http.MethodPost) || !setting.GetBool(conf.CreditsEnabled) {
+		c.Next()
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	reqPath, err := user.JoinPath(strings.TrimPrefix(c.Request.URL.Path, handler.Prefix))
+	if err != nil {
+		// the webdav handler reports the invalid path
+		c.Next()
+		return
+	}
+	obj, err := fs.Get(c.Request.Context(), reqPath, &fs.GetArgs{})
+	if err != nil || obj.IsDir() {
+		c.Next()
+		return
+	}
+	if method == http.MethodHead {
+		allowed, required, err := op.CheckSizedFileAccessPermission(user.ID, reqPath, model.CreditsActionDownload, obj.GetSize())
+		if err != nil {
+			c.String(http.StatusInternalServerError, common.ErrorMessage(c, err))
+			c.Abort()
+			return
+		}
+		if !allowed {
+			c.String(http.StatusPaymentRequired, common.ErrorMessage(c, errs.InsufficientCredits))
+			c.Abort()
+			return
+		}
+		if required > 0 {
+			c.Header("Content-Length", strconv.FormatInt(obj.GetSize(), 10))
+			c.Header("Last-Modified", obj.ModTime().UTC().Format(http.TimeFormat))
+			c.Status(http.StatusOK)
+			c.Abort()
+			return
+		}
+		c.Next()
+		return
+	}
+	charge, err := op.BeginFileDownload(user.ID, reqPath)
+	if err != nil {
+		if errors.Is(err, errs.InsufficientCredits) {
+			c.String(http.StatusPaymentRequired, common.ErrorMessage(c, err))
+		} else {
+			c.String(http.StatusInternalServerError, common.ErrorMessage(c, err))
+		}
+		c.Abort()
+		return
+	}
+	c.Next()
+	if err = charge.Finish(c.Writer.Status() < http.StatusBadRequest); err != nil {
+		log.Errorf("failed to settle the webdav download credits of user %d for %s: %+v", user.ID, reqPath, err)
+	}
+}
+
 func WebDAVAuth(c *gin.Context) {
 	// check count of login
 	ip := c.ClientIP()
@@ -24,6 +24,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/fs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 )
@@ -269,7 +270,10 @@ func (h *Handler) handleGetHeadPost(w http.ResponseWriter, r *http.Request) (sta
 	if storage.GetStorage().ProxyRange {
 		link = common.ProxyRange(ctx, link, fi.GetSize())
 	}
-	err = common.Proxy(w, r, link, fi)
+	// proxied traffic is metered to the user like downloads through /p
+	written := &common.WrittenResponseWriter{ResponseWriter: w}
+	defer func() { op.RecordTraffic(user, written.WrittenBytes()) }()
+	err = common.Proxy(written, r, link, fi)
 	if err != nil {
 		if statusCode, ok := errors.Unwrap(err).(net.ErrorHttpStatusCode); ok {
 			return int(statusCode), err
@@ -648,6 +652,7 @@ func (h *Handler) handlePropfind(w http.ResponseWriter, r *http.Request) (status
 		}
 		return http.StatusMethodNotAllowed, err
 	}
+	op.RecordApiCall(user, op.ApiPropfind)
 	depth := infiniteDepth
 	if hdr := r.Header.Get("Depth"); hdr != "" {
 		depth = parseDepth(hdr)